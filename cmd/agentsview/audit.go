@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/secrets"
+)
+
+// auditBatchSize is the number of messages scanned per
+// transaction.
+const auditBatchSize = 500
+
+// AuditConfig holds parsed CLI options for the audit command.
+type AuditConfig struct {
+	Format  string
+	Output  string
+	Full    bool
+	NewOnly bool
+}
+
+func parseAuditFlags(args []string) (AuditConfig, error) {
+	fs := flag.NewFlagSet("audit secrets", flag.ContinueOnError)
+	format := fs.String(
+		"format", "table",
+		"Output format: table, json or sarif",
+	)
+	output := fs.String(
+		"o", "",
+		"Write the report to this file instead of stdout",
+	)
+	full := fs.Bool(
+		"full", false,
+		"Forget earlier results and scan every message",
+	)
+	newOnly := fs.Bool(
+		"new", false,
+		"Report only findings from this run",
+	)
+	if err := fs.Parse(args); err != nil {
+		return AuditConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return AuditConfig{}, fmt.Errorf(
+			"unexpected arguments: %s", strings.Join(fs.Args(), " "),
+		)
+	}
+	switch *format {
+	case "table", "json", "sarif":
+	default:
+		return AuditConfig{}, fmt.Errorf(
+			"unknown format %q (want table, json or sarif)", *format,
+		)
+	}
+	return AuditConfig{
+		Format:  *format,
+		Output:  *output,
+		Full:    *full,
+		NewOnly: *newOnly,
+	}, nil
+}
+
+func runAudit(args []string) {
+	if len(args) == 0 || args[0] != "secrets" {
+		fmt.Fprintln(os.Stderr,
+			"Usage: agentsview audit secrets [--format table|json|sarif] "+
+				"[--full] [--new] [-o file]")
+		os.Exit(2)
+	}
+	cfg, err := parseAuditFlags(args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	appCfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+	scanner, err := appCfg.SecretScanner()
+	if err != nil {
+		log.Fatalf("invalid secret_patterns: %v", err)
+	}
+	database, err := db.Open(appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	report, err := auditSecrets(
+		context.Background(), database, scanner, cfg,
+	)
+	if err != nil {
+		log.Fatalf("audit: %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if cfg.Output != "" {
+		f, err := os.Create(cfg.Output)
+		if err != nil {
+			log.Fatalf("creating report: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writeAuditReport(out, cfg.Format, report); err != nil {
+		log.Fatalf("writing report: %v", err)
+	}
+}
+
+// auditReport is the result of an audit run. Findings holds
+// every stored finding, or with --new only those of this run.
+type auditReport struct {
+	Scanned   int                `json:"messages_scanned"`
+	Watermark int64              `json:"watermark"`
+	ByRule    []auditRuleSummary `json:"by_rule"`
+	Findings  []db.SecretFinding `json:"findings"`
+}
+
+// auditRuleSummary lists where one credential type leaked.
+type auditRuleSummary struct {
+	Rule     string   `json:"rule"`
+	Findings int      `json:"findings"`
+	Sessions []string `json:"sessions"`
+	Projects []string `json:"projects"`
+	Machines []string `json:"machines"`
+}
+
+// auditSecrets scans the messages added since the last audit,
+// stores what it finds and returns the report.
+func auditSecrets(
+	ctx context.Context, database *db.DB,
+	scanner *secrets.Scanner, cfg AuditConfig,
+) (auditReport, error) {
+	var report auditReport
+	lastID, err := database.LastSecretFindingID()
+	if err != nil {
+		return report, err
+	}
+	if cfg.Full {
+		if err := database.ResetSecretAudit(); err != nil {
+			return report, err
+		}
+	}
+
+	for {
+		from, err := database.SecretAuditWatermark()
+		if err != nil {
+			return report, err
+		}
+		msgs, err := database.MessagesAfter(ctx, from, auditBatchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(msgs) == 0 {
+			report.Watermark = from
+			break
+		}
+		findings := db.ScanMessages(scanner, msgs)
+		saved, err := database.SaveSecretAudit(
+			from, msgs[len(msgs)-1].ID, findings,
+		)
+		if err != nil {
+			return report, err
+		}
+		// A sync moved the watermark back; scan again from it.
+		if saved {
+			report.Scanned += len(msgs)
+		}
+	}
+
+	if !cfg.NewOnly {
+		lastID = 0
+	}
+	findings, err := database.ListSecretFindings(ctx, lastID)
+	if err != nil {
+		return report, err
+	}
+	report.Findings = findings
+	report.ByRule = summarizeFindings(findings)
+	return report, nil
+}
+
+func summarizeFindings(findings []db.SecretFinding) []auditRuleSummary {
+	type sets struct {
+		count                        int
+		sessions, projects, machines map[string]bool
+	}
+	byRule := map[string]*sets{}
+	for _, f := range findings {
+		s := byRule[f.Rule]
+		if s == nil {
+			s = &sets{
+				sessions: map[string]bool{},
+				projects: map[string]bool{},
+				machines: map[string]bool{},
+			}
+			byRule[f.Rule] = s
+		}
+		s.count++
+		s.sessions[f.SessionID] = true
+		s.projects[f.Project] = true
+		s.machines[f.Machine] = true
+	}
+
+	keys := func(m map[string]bool) []string {
+		out := make([]string, 0, len(m))
+		for k := range m {
+			out = append(out, k)
+		}
+		sort.Strings(out)
+		return out
+	}
+	out := make([]auditRuleSummary, 0, len(byRule))
+	for rule, s := range byRule {
+		out = append(out, auditRuleSummary{
+			Rule:     rule,
+			Findings: s.count,
+			Sessions: keys(s.sessions),
+			Projects: keys(s.projects),
+			Machines: keys(s.machines),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Findings != out[j].Findings {
+			return out[i].Findings > out[j].Findings
+		}
+		return out[i].Rule < out[j].Rule
+	})
+	return out
+}
+
+func writeAuditReport(
+	w io.Writer, format string, report auditReport,
+) error {
+	switch format {
+	case "json":
+		if report.Findings == nil {
+			report.Findings = []db.SecretFinding{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "sarif":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(auditSARIF(report))
+	default:
+		return writeAuditTable(w, report)
+	}
+}
+
+func writeAuditTable(w io.Writer, report auditReport) error {
+	fmt.Fprintf(w, "Scanned %d new messages (watermark %d).\n",
+		report.Scanned, report.Watermark)
+	if len(report.Findings) == 0 {
+		fmt.Fprintln(w, "No secrets found.")
+		return nil
+	}
+
+	fmt.Fprintf(w, "\n%d secrets found:\n\n", len(report.Findings))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tFINDINGS\tSESSIONS\tPROJECTS\tMACHINES")
+	for _, s := range report.ByRule {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n",
+			s.Rule, s.Findings, len(s.Sessions),
+			strings.Join(s.Projects, ", "),
+			strings.Join(s.Machines, ", "))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "SESSION\tPROJECT\tMACHINE\tMESSAGE\tFIELD\tRULE\tPREVIEW")
+	for _, f := range report.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			f.SessionID, f.Project, f.Machine, f.Ordinal,
+			findingField(f), f.Rule, f.Preview)
+	}
+	return tw.Flush()
+}
+
+// findingField names the field of a finding, with the tool call
+// index for tool fields.
+func findingField(f db.SecretFinding) string {
+	if f.ToolCall == nil {
+		return f.Field
+	}
+	return fmt.Sprintf("%s[%d]", f.Field, *f.ToolCall)
+}
+
+// SARIF 2.1.0 output, for code scanning dashboards.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+func auditSARIF(report auditReport) sarifLog {
+	rules := make([]sarifRule, 0, len(report.ByRule))
+	for _, s := range report.ByRule {
+		rules = append(rules, sarifRule{
+			ID: s.Rule,
+			ShortDescription: sarifMessage{
+				Text: "Secret detected: " + s.Rule,
+			},
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+
+	results := make([]sarifResult, 0, len(report.Findings))
+	for _, f := range report.Findings {
+		uri := "agentsview:session/" + f.SessionID
+		if f.FilePath != nil && *f.FilePath != "" {
+			uri = "file://" + filepath.ToSlash(*f.FilePath)
+		}
+		field := findingField(f)
+		results = append(results, sarifResult{
+			RuleID: f.Rule,
+			Level:  "error",
+			Message: sarifMessage{Text: fmt.Sprintf(
+				"%s in %s of message %d in session %s",
+				f.Rule, field, f.Ordinal, f.SessionID,
+			)},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri},
+				},
+				LogicalLocations: []sarifLogicalLocation{{
+					Name: fmt.Sprintf("message %d", f.Ordinal),
+					FullyQualifiedName: fmt.Sprintf(
+						"%s/%d/%s", f.SessionID, f.Ordinal, field,
+					),
+					Kind: "member",
+				}},
+			}},
+			PartialFingerprints: map[string]string{
+				"agentsview/v1": fmt.Sprintf("%s:%d:%s:%s:%d",
+					f.SessionID, f.Ordinal, field, f.Rule, f.Start),
+			},
+			Properties: map[string]any{
+				"project": f.Project,
+				"machine": f.Machine,
+				"agent":   f.Agent,
+				"start":   f.Start,
+				"end":     f.End,
+				"preview": f.Preview,
+			},
+		})
+	}
+
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "agentsview",
+				Version:        version,
+				InformationURI: "https://github.com/wesm/agentsview",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/secrets"
+)
+
+func TestParseAuditFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    AuditConfig
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			args: nil,
+			want: AuditConfig{Format: "table"},
+		},
+		{
+			name: "all flags",
+			args: []string{
+				"--format", "sarif", "-o", "audit.sarif", "--full", "--new",
+			},
+			want: AuditConfig{
+				Format: "sarif", Output: "audit.sarif",
+				Full: true, NewOnly: true,
+			},
+		},
+		{
+			name:    "unknown format",
+			args:    []string{"--format", "csv"},
+			wantErr: "unknown format",
+		},
+		{
+			name:    "extra arguments",
+			args:    []string{"everything"},
+			wantErr: "unexpected arguments",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAuditFlags(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("config = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func seedAuditSession(
+	t *testing.T, d *db.DB, id, machine string, content string,
+) {
+	t.Helper()
+	if err := d.UpsertSession(db.Session{
+		ID: id, Project: "my-app", Machine: machine,
+		Agent: "claude", MessageCount: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.InsertMessages([]db.Message{{
+		SessionID: id, Role: "user",
+		Content: content, ContentLength: len(content),
+	}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditSecrets(t *testing.T) {
+	d, err := db.Open(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	sc := secrets.Default()
+	key := "AKIA" + "IOSFODNN7EXAMPLE"
+
+	seedAuditSession(t, d, "s1", "laptop", "my key is "+key)
+	report, err := auditSecrets(ctx, d, sc, AuditConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 1 || len(report.Findings) != 1 {
+		t.Fatalf("first audit = %+v", report)
+	}
+
+	// Only the new session is scanned on the next run.
+	seedAuditSession(t, d, "s2", "server", "again "+key)
+	report, err = auditSecrets(ctx, d, sc, AuditConfig{NewOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 1 || len(report.Findings) != 1 ||
+		report.Findings[0].SessionID != "s2" {
+		t.Fatalf("incremental audit = %+v", report)
+	}
+
+	report, err = auditSecrets(ctx, d, sc, AuditConfig{Full: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 2 || len(report.Findings) != 2 {
+		t.Fatalf("full audit = %+v", report)
+	}
+	if len(report.ByRule) != 1 {
+		t.Fatalf("by rule = %+v", report.ByRule)
+	}
+	s := report.ByRule[0]
+	if s.Rule != "aws_access_key_id" || s.Findings != 2 ||
+		strings.Join(s.Machines, ",") != "laptop,server" ||
+		strings.Join(s.Projects, ",") != "my-app" {
+		t.Errorf("summary = %+v", s)
+	}
+
+	for _, format := range []string{"table", "json", "sarif"} {
+		var buf bytes.Buffer
+		if err := writeAuditReport(&buf, format, report); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(buf.String(), key) {
+			t.Errorf("%s report leaks the secret:\n%s", format, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeAuditReport(&buf, "sarif", report); err != nil {
+		t.Fatal(err)
+	}
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					LogicalLocations []struct {
+						FullyQualifiedName string `json:"fullyQualifiedName"`
+					} `json:"logicalLocations"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &sarif); err != nil {
+		t.Fatalf("decoding SARIF: %v\n%s", err, buf.String())
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 {
+		t.Fatalf("SARIF = %s", buf.String())
+	}
+	run := sarif.Runs[0]
+	if len(run.Tool.Driver.Rules) != 1 || len(run.Results) != 2 ||
+		run.Results[0].RuleID != "aws_access_key_id" ||
+		run.Results[0].Locations[0].LogicalLocations[0].
+			FullyQualifiedName != "s1/0/content" {
+		t.Errorf("SARIF = %s", buf.String())
+	}
+}
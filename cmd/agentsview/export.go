@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/secrets"
+	"github.com/wesm/agentsview/internal/server"
+)
+
+// ExportConfig holds parsed CLI options for the export command.
+type ExportConfig struct {
+	SessionID string
+	Format    string
+	Output    string
+	Redact    bool
+}
+
+func parseExportFlags(args []string) (ExportConfig, error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String(
+		"format", "md",
+		"Output format: md, json or html",
+	)
+	output := fs.String(
+		"o", "",
+		"Write to this file instead of stdout",
+	)
+	redact := fs.Bool(
+		"redact", true,
+		"Replace detected secrets with redaction markers",
+	)
+
+	// Allow the session ID before the flags, as in
+	// "export <id> --format json".
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return ExportConfig{}, err
+	}
+	if id == "" && fs.NArg() > 0 {
+		id = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return ExportConfig{}, err
+		}
+	}
+	if fs.NArg() > 0 {
+		return ExportConfig{}, fmt.Errorf(
+			"unexpected arguments: %s", strings.Join(fs.Args(), " "),
+		)
+	}
+	if id == "" {
+		return ExportConfig{}, fmt.Errorf("session ID is required")
+	}
+	return ExportConfig{
+		SessionID: id,
+		Format:    *format,
+		Output:    *output,
+		Redact:    *redact,
+	}, nil
+}
+
+func runExport(args []string) {
+	cfg, err := parseExportFlags(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr,
+			"Usage: agentsview export <session-id> "+
+				"[--format md|json|html] [-o file] [--redact=false]")
+		os.Exit(2)
+	}
+
+	appCfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+	database, err := db.OpenReadOnly(appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	var scanner *secrets.Scanner
+	if cfg.Redact {
+		if scanner, err = appCfg.SecretScanner(); err != nil {
+			log.Fatalf("invalid secret_patterns: %v", err)
+		}
+	}
+	content, err := exportSession(database, cfg, scanner)
+	if err != nil {
+		log.Fatalf("export: %v", err)
+	}
+	if cfg.Output == "" {
+		_, err = os.Stdout.Write(content)
+	} else {
+		err = os.WriteFile(cfg.Output, content, 0o644)
+	}
+	if err != nil {
+		log.Fatalf("writing export: %v", err)
+	}
+}
+
+// exportSession renders the session selected by cfg, redacting
+// the secrets found by scanner unless it is nil.
+func exportSession(
+	database *db.DB, cfg ExportConfig, scanner *secrets.Scanner,
+) ([]byte, error) {
+	content, _, err := server.ExportSession(
+		context.Background(), database,
+		cfg.SessionID, cfg.Format, scanner,
+	)
+	if errors.Is(err, server.ErrSessionNotFound) {
+		return nil, fmt.Errorf("session %s not found", cfg.SessionID)
+	}
+	return content, err
+}
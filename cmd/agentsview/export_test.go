@@ -0,0 +1,129 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/secrets"
+)
+
+func TestParseExportFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    ExportConfig
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			args: []string{"s1"},
+			want: ExportConfig{SessionID: "s1", Format: "md", Redact: true},
+		},
+		{
+			name: "id before flags",
+			args: []string{"s1", "--format", "json", "-o", "out.json"},
+			want: ExportConfig{
+				SessionID: "s1", Format: "json", Output: "out.json",
+				Redact: true,
+			},
+		},
+		{
+			name: "id after flags",
+			args: []string{"--format", "html", "s1"},
+			want: ExportConfig{SessionID: "s1", Format: "html", Redact: true},
+		},
+		{
+			name: "flags on both sides",
+			args: []string{"-o", "x.md", "s1", "--format", "md"},
+			want: ExportConfig{
+				SessionID: "s1", Format: "md", Output: "x.md",
+				Redact: true,
+			},
+		},
+		{
+			name: "no redaction",
+			args: []string{"s1", "--redact=false"},
+			want: ExportConfig{SessionID: "s1", Format: "md"},
+		},
+		{
+			name:    "missing id",
+			args:    []string{"--format", "json"},
+			wantErr: "session ID is required",
+		},
+		{
+			name:    "extra arguments",
+			args:    []string{"s1", "s2"},
+			wantErr: "unexpected arguments",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExportFlags(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("config = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportSessionCommand(t *testing.T) {
+	d, err := db.Open(filepath.Join(t.TempDir(), "export.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if err := d.UpsertSession(db.Session{
+		ID: "s1", Project: "my-app", Machine: "local",
+		Agent: "claude", MessageCount: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.InsertMessages([]db.Message{{
+		SessionID: "s1", Role: "user",
+		Content:       "hello there, my key is AKIA" + "IOSFODNN7EXAMPLE",
+		ContentLength: 42,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ExportConfig{SessionID: "s1", Format: "md"}
+	out, err := exportSession(d, cfg, secrets.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "# my-app") ||
+		!strings.Contains(string(out), "my key is [REDACTED:aws_access_key_id]") {
+		t.Errorf("markdown export = %s", out)
+	}
+	out, err = exportSession(d, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "AKIA"+"IOSFODNN7EXAMPLE") {
+		t.Errorf("unredacted export = %s", out)
+	}
+
+	_, err = exportSession(d, ExportConfig{
+		SessionID: "nope", Format: "md",
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "nope not found") {
+		t.Errorf("missing session err = %v", err)
+	}
+	_, err = exportSession(d, ExportConfig{
+		SessionID: "s1", Format: "pdf",
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown export format") {
+		t.Errorf("bad format err = %v", err)
+	}
+}
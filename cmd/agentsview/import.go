@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/parser"
+	"github.com/wesm/agentsview/internal/sync"
+)
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(),
+			"Usage: agentsview import <file>... (\"-\" reads stdin)")
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("parsing flags: %v", err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		log.Fatalf("creating data dir: %v", err)
+	}
+	database := mustOpenDB(cfg)
+	defer database.Close()
+
+	engine := sync.NewEngine(
+		database, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+		"local",
+	)
+	engine.SetOTelDir(cfg.OTelDir())
+
+	for _, name := range fs.Args() {
+		ids, err := importTraceFile(engine, name, os.Stdin)
+		if err != nil {
+			log.Fatalf("import %s: %v", name, err)
+		}
+		fmt.Printf("%s: %d session(s)\n", name, len(ids))
+	}
+}
+
+// importTraceFile imports the GenAI spans of an OTLP/JSON trace
+// file, or of stdin if name is "-", and returns the IDs of the
+// sessions they went to.
+func importTraceFile(
+	engine *sync.Engine, name string, stdin io.Reader,
+) ([]string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if name == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	batches, err := parser.SplitOTelTraces(data)
+	if err != nil {
+		return nil, err
+	}
+	return engine.ImportOTel(batches)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/sync"
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+func TestImportTraceFile(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.Open(filepath.Join(dir, "sessions.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	defer database.Close()
+	engine := sync.NewEngine(
+		database, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+		"local",
+	)
+	engine.SetOTelDir(filepath.Join(dir, "otel"))
+
+	chat := func(trace, prompt string) string {
+		return testjsonl.OTLPRequestJSON("bot", "", testjsonl.OTelSpan{
+			TraceID: trace, SpanID: "s", Name: "chat",
+			Start: 1718000000000000000, End: 1718000001000000000,
+			Attrs: map[string]any{
+				"gen_ai.operation.name":   "chat",
+				"gen_ai.prompt.0.role":    "user",
+				"gen_ai.prompt.0.content": prompt,
+			},
+		})
+	}
+
+	// The collector's file exporter writes a request per line.
+	path := filepath.Join(dir, "traces.jsonl")
+	content := chat("t1", "first") + "\n" + chat("t2", "second") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := importTraceFile(engine, path, nil)
+	if err != nil {
+		t.Fatalf("importTraceFile: %v", err)
+	}
+	if strings.Join(ids, ",") != "otel:t1,otel:t2" {
+		t.Errorf("ids = %v", ids)
+	}
+
+	ids, err = importTraceFile(engine, "-", strings.NewReader(chat("t3", "third")))
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("stdin import = %v, %v", ids, err)
+	}
+	sess, err := database.GetSession(context.Background(), "otel:t3")
+	if err != nil || sess == nil {
+		t.Fatalf("GetSession = %v, %v", sess, err)
+	}
+	if sess.FirstMessage == nil || *sess.FirstMessage != "third" {
+		t.Errorf("first message = %v", sess.FirstMessage)
+	}
+
+	if _, err := importTraceFile(engine, filepath.Join(dir, "none.json"), nil); err == nil {
+		t.Error("missing file imported")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -29,8 +28,6 @@ const (
 	periodicSyncInterval  = 15 * time.Minute
 	unwatchedPollInterval = 2 * time.Minute
 	watcherDebounce       = 500 * time.Millisecond
-	browserPollInterval   = 100 * time.Millisecond
-	browserPollAttempts   = 60
 )
 
 func main() {
@@ -45,6 +42,27 @@ func main() {
 		case "serve":
 			runServe(os.Args[2:])
 			return
+		case "mcp":
+			runMCP(os.Args[2:])
+			return
+		case "db":
+			runDB(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "audit":
+			runAudit(os.Args[2:])
+			return
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "push":
+			runPush(os.Args[2:])
+			return
+		case "token":
+			runToken(os.Args[2:])
+			return
 		case "version", "--version", "-v":
 			fmt.Printf("agentsview %s (commit %s, built %s)\n",
 				version, commit, buildDate)
@@ -61,15 +79,23 @@ func main() {
 func printUsage() {
 	fmt.Printf(`agentsview %s - local web viewer for AI agent sessions
 
-Syncs Claude Code, Codex, Copilot CLI, Gemini CLI, and OpenCode session
-data into SQLite, serves an analytics dashboard and session browser via
-a local web UI.
+Syncs Claude Code, Codex, Copilot CLI, Gemini CLI, OpenCode, Aider,
+Cline, Roo Code, Cursor, Goose and Amp session data, and OpenTelemetry
+GenAI traces, into SQLite, serves an analytics dashboard and session
+browser via a local web UI.
 
 Usage:
   agentsview [flags]          Start the server (default command)
   agentsview serve [flags]    Start the server (explicit)
   agentsview prune [flags]    Delete sessions matching filters
   agentsview update [flags]   Check for and install updates
+  agentsview mcp              Serve sessions to MCP clients over stdio
+  agentsview db migrate       Apply pending database schema migrations
+  agentsview export <id>      Export a session as Markdown, JSON or HTML
+  agentsview audit secrets    Scan stored sessions for leaked credentials
+  agentsview import <file>    Import OTLP/JSON trace files
+  agentsview push [flags]     Upload local sessions to a remote server
+  agentsview token <cmd>      Create, list or revoke API tokens
   agentsview version          Show version information
   agentsview help             Show this help
 
@@ -77,6 +103,11 @@ Server flags:
   -host string        Host to bind to (default "127.0.0.1")
   -port int           Port to listen on (default 8080)
   -no-browser         Don't open browser on startup
+  -socket string      Listen on this Unix domain socket instead
+  -socket-mode string Socket file permissions in octal (default "0600")
+  -tls-cert string    Certificate file to serve HTTPS with
+  -tls-key string     Key file to serve HTTPS with
+  -tls-self-signed    Serve HTTPS with a generated self-signed certificate
 
 Prune flags:
   -project string     Sessions whose project contains this substring
@@ -86,6 +117,34 @@ Prune flags:
   -dry-run            Show what would be pruned without deleting
   -yes                Skip confirmation prompt
 
+DB migrate flags:
+  -dry-run            Show pending migrations without applying them
+
+Export flags:
+  -format string      Output format: md, json or html (default "md")
+  -o string           Write to this file instead of stdout
+  -redact             Redact detected secrets (default true)
+
+Audit secrets flags:
+  -format string      Output format: table, json or sarif (default "table")
+  -o string           Write the report to this file instead of stdout
+  -full               Forget earlier results and scan every message
+  -new                Report only findings from this run
+
+Push flags:
+  -server string      URL of the agentsview server to push to (required)
+  -machine string     Machine name to record (default: hostname)
+  -watch              Keep running and push sessions as they change
+  -token string       API token with the upload scope
+                      (default: $AGENTSVIEW_TOKEN)
+
+Token commands:
+  create -name NAME   Create a token and print it once
+    -scopes string    Comma-separated: read, write, upload, admin
+                      (default "read")
+  list                List tokens
+  revoke <id|name>    Revoke a token
+
 Update flags:
   -check              Check for updates without installing
   -yes                Install without confirmation prompt
@@ -97,7 +156,10 @@ Environment variables:
   COPILOT_DIR             Copilot CLI directory
   GEMINI_DIR              Gemini CLI directory
   OPENCODE_DIR            OpenCode data directory
+  GOOSE_DIR               Goose data directory
+  AMP_DIR                 Amp data directory
   AGENT_VIEWER_DATA_DIR   Data directory (database, config)
+  AGENTSVIEW_TOKEN        API token used by push
 
 Multiple directories:
   Add arrays to ~/.agentsview/config.json to scan multiple locations:
@@ -108,6 +170,51 @@ Multiple directories:
   When set, these override the default directory. Environment variables
   override config file arrays.
 
+Aider:
+  Aider keeps its chat history in the repository it runs in. List the
+  repositories to sync in config.json:
+  {
+    "aider_repos": ["/path/to/repo"]
+  }
+
+Cline and Roo Code:
+  Tasks are read from the extensions' VS Code global storage. Set
+  "cline_dirs" in config.json to sync other storage directories, such as
+  those of VS Code Insiders or VSCodium:
+  {
+    "cline_dirs": ["/path/to/globalStorage/saoudrizwan.claude-dev"]
+  }
+
+Cursor:
+  Composer and agent chats are read, read-only, from the state.vscdb
+  databases in Cursor's user data directory. Set "cursor_dirs" in
+  config.json to sync other user data directories:
+  {
+    "cursor_dirs": ["/path/to/Cursor/User"]
+  }
+
+OpenTelemetry:
+  Agents that emit spans following the OpenTelemetry GenAI semantic
+  conventions are imported as "otel" sessions. Point an OTLP/HTTP
+  exporter at the server, using the JSON encoding:
+    OTEL_EXPORTER_OTLP_TRACES_ENDPOINT=http://127.0.0.1:8080/v1/traces
+    OTEL_EXPORTER_OTLP_TRACES_PROTOCOL=http/json
+  or import trace files written by the collector's file exporter with
+  "agentsview import". Spans sharing a gen_ai.conversation.id form one
+  session; otherwise each trace is a session.
+
+Archive mode:
+  Set "archive": true in config.json to keep a compressed copy of every
+  synced session file in ~/.agentsview/archive. Sessions whose original
+  files are deleted are then restored from the archive on sync and
+  resync.
+
+Commit links:
+  Set "correlate_commits": true in config.json to link sessions to the
+  commits of the local git repositories they edited. After each sync,
+  commits authored during a session or up to two hours after it that
+  change files the session edited are read from the local git log.
+
 Data is stored in ~/.agentsview/ by default.
 `, version)
 }
@@ -133,6 +240,11 @@ func runServe(args []string) {
 	warnMissingDirs(cfg.ResolveCopilotDirs(), "copilot")
 	warnMissingDirs(cfg.ResolveGeminiDirs(), "gemini")
 	warnMissingDirs(cfg.ResolveOpenCodeDirs(), "opencode")
+	warnMissingDirs(cfg.AiderRepos, "aider")
+	warnMissingDirs(cfg.ResolveClineDirs(), "cline")
+	warnMissingDirs(cfg.ResolveCursorDirs(), "cursor")
+	warnMissingDirs(cfg.ResolveGooseDirs(), "goose")
+	warnMissingDirs(cfg.ResolveAmpDirs(), "amp")
 
 	engine := sync.NewEngine(
 		database,
@@ -141,8 +253,18 @@ func runServe(args []string) {
 		cfg.ResolveCopilotDirs(),
 		cfg.ResolveGeminiDirs(),
 		cfg.ResolveOpenCodeDirs(),
+		cfg.AiderRepos,
+		cfg.ResolveClineDirs(),
+		cfg.ResolveCursorDirs(),
+		cfg.ResolveGooseDirs(),
+		cfg.ResolveAmpDirs(),
 		"local",
 	)
+	if cfg.Archive {
+		engine.SetArchive(sync.NewArchive(cfg.ArchiveDir()))
+	}
+	engine.SetCommitCorrelation(cfg.CorrelateCommits)
+	engine.SetOTelDir(cfg.OTelDir())
 
 	runInitialSync(engine)
 
@@ -154,33 +276,44 @@ func runServe(args []string) {
 		go startUnwatchedPoll(engine)
 	}
 
-	port := server.FindAvailablePort(cfg.Host, cfg.Port)
-	if port != cfg.Port {
-		fmt.Printf("Port %d in use, using %d\n", cfg.Port, port)
+	// A Unix domain socket has no port to move off of.
+	if cfg.Socket == "" {
+		port := server.FindAvailablePort(cfg.Host, cfg.Port)
+		if port != cfg.Port {
+			fmt.Printf("Port %d in use, using %d\n", cfg.Port, port)
+		}
+		cfg.Port = port
 	}
-	cfg.Port = port
 
-	srv := server.New(cfg, database, engine,
+	srv, err := server.New(cfg, database, engine,
 		server.WithVersion(server.VersionInfo{
 			Version:   version,
 			Commit:    commit,
 			BuildDate: buildDate,
 		}),
 	)
+	if err != nil {
+		log.Fatalf("starting server: %v", err)
+	}
 
-	url := fmt.Sprintf("http://%s:%d", cfg.Host, cfg.Port)
+	// Bind before announcing the address, so the browser
+	// never finds nothing listening.
+	ln, err := srv.Listen()
+	if err != nil {
+		log.Fatalf("listening: %v", err)
+	}
 	fmt.Printf(
 		"agentsview %s listening at %s (started in %s)\n",
-		version, url,
+		version, srv.Addr(),
 		time.Since(start).Round(time.Millisecond),
 	)
 
-	if !cfg.NoBrowser {
+	// Browsers can't open Unix domain sockets.
+	if url := srv.URL(); url != "" && !cfg.NoBrowser {
 		go openBrowser(url)
 	}
 
-	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
-	if err := http.ListenAndServe(addr, srv.Handler()); err != nil {
+	if err := srv.Serve(ln); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
 }
@@ -222,6 +355,12 @@ func mustOpenDB(cfg config.Config) *db.DB {
 		database.SetCursorSecret(secret)
 	}
 
+	prices, err := cfg.PriceTable()
+	if err != nil {
+		log.Fatalf("invalid pricing: %v", err)
+	}
+	database.SetPriceTable(prices)
+
 	return database
 }
 
@@ -263,11 +402,56 @@ func startFileWatcher(
 		return func() {}, []string{"all"}
 	}
 
-	type watchRoot struct {
-		dir  string
-		root string // actual path passed to WatchRecursive
+	roots := sessionWatchRoots(cfg)
+
+	var totalWatched int
+	// Aider histories sit in repository roots, so only the
+	// roots themselves are watched.
+	for _, d := range cfg.AiderRepos {
+		if _, err := os.Stat(d); err != nil {
+			continue
+		}
+		if err := watcher.WatchDir(d); err != nil {
+			unwatchedDirs = append(unwatchedDirs, d)
+			log.Printf(
+				"Couldn't watch %s, will poll every %s",
+				d, unwatchedPollInterval,
+			)
+			continue
+		}
+		totalWatched++
+	}
+	for _, r := range roots {
+		watched, uw, _ := watcher.WatchRecursive(r.root)
+		totalWatched += watched
+		if uw > 0 {
+			unwatchedDirs = append(unwatchedDirs, r.dir)
+			log.Printf(
+				"Couldn't watch %d directories under %s, will poll every %s",
+				uw, r.dir, unwatchedPollInterval,
+			)
+		}
 	}
 
+	fmt.Printf(
+		"Watching %d directories for changes (%s)\n",
+		totalWatched, time.Since(t).Round(time.Millisecond),
+	)
+	watcher.Start()
+	return watcher.Stop, unwatchedDirs
+}
+
+// watchRoot is a session directory and the tree under it the
+// file watcher watches.
+type watchRoot struct {
+	dir  string
+	root string // actual path passed to WatchRecursive
+}
+
+// sessionWatchRoots returns the existing session directories of
+// the configured agents to watch recursively. Aider repositories
+// are not included; only their roots are watched.
+func sessionWatchRoots(cfg config.Config) []watchRoot {
 	var roots []watchRoot
 	for _, d := range cfg.ResolveClaudeDirs() {
 		if _, err := os.Stat(d); err == nil {
@@ -291,26 +475,25 @@ func startFileWatcher(
 			roots = append(roots, watchRoot{d, geminiTmp})
 		}
 	}
-
-	var totalWatched int
-	for _, r := range roots {
-		watched, uw, _ := watcher.WatchRecursive(r.root)
-		totalWatched += watched
-		if uw > 0 {
-			unwatchedDirs = append(unwatchedDirs, r.dir)
-			log.Printf(
-				"Couldn't watch %d directories under %s, will poll every %s",
-				uw, r.dir, unwatchedPollInterval,
-			)
+	for _, d := range cfg.ResolveClineDirs() {
+		clineTasks := filepath.Join(d, "tasks")
+		if _, err := os.Stat(clineTasks); err == nil {
+			roots = append(roots, watchRoot{d, clineTasks})
 		}
 	}
-
-	fmt.Printf(
-		"Watching %d directories for changes (%s)\n",
-		totalWatched, time.Since(t).Round(time.Millisecond),
-	)
-	watcher.Start()
-	return watcher.Stop, unwatchedDirs
+	for _, d := range cfg.ResolveGooseDirs() {
+		gooseSessions := filepath.Join(d, "sessions")
+		if _, err := os.Stat(gooseSessions); err == nil {
+			roots = append(roots, watchRoot{d, gooseSessions})
+		}
+	}
+	for _, d := range cfg.ResolveAmpDirs() {
+		ampThreads := filepath.Join(d, "threads")
+		if _, err := os.Stat(ampThreads); err == nil {
+			roots = append(roots, watchRoot{d, ampThreads})
+		}
+	}
+	return roots
 }
 
 func startPeriodicSync(engine *sync.Engine) {
@@ -332,15 +515,6 @@ func startUnwatchedPoll(engine *sync.Engine) {
 }
 
 func openBrowser(url string) {
-	for range browserPollAttempts {
-		time.Sleep(browserPollInterval)
-		resp, err := http.Get(url + "/api/v1/stats")
-		if err == nil {
-			resp.Body.Close()
-			break
-		}
-	}
-
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
 	case "darwin":
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/mcp"
+)
+
+func runMCP(args []string) {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(),
+			"Usage: agentsview mcp\n\n"+
+				"Serve the session database to MCP clients over stdio.\n"+
+				"The database is opened read-only, so this can run\n"+
+				"alongside agentsview serve.")
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("parsing flags: %v", err)
+	}
+
+	// stdout carries the protocol; keep diagnostics on stderr.
+	log.SetOutput(os.Stderr)
+
+	cfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+
+	database, err := db.OpenReadOnly(cfg.DBPath)
+	if err != nil {
+		log.Fatalf(
+			"opening database: %v (run agentsview serve once "+
+				"to create it)", err,
+		)
+	}
+	defer database.Close()
+
+	if cfg.CursorSecret != "" {
+		secret, err := base64.StdEncoding.DecodeString(cfg.CursorSecret)
+		if err != nil {
+			log.Fatalf("invalid cursor secret: %v", err)
+		}
+		database.SetCursorSecret(secret)
+	}
+
+	prices, err := cfg.PriceTable()
+	if err != nil {
+		log.Fatalf("invalid pricing: %v", err)
+	}
+	database.SetPriceTable(prices)
+
+	srv := mcp.New(database, "agentsview", version)
+	err = srv.Serve(context.Background(), os.Stdin, os.Stdout)
+	if err != nil {
+		log.Fatalf("mcp: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+)
+
+func runDB(args []string) {
+	if len(args) == 0 || args[0] != "migrate" {
+		fmt.Fprintln(os.Stderr,
+			"Usage: agentsview db migrate [--dry-run]")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("db migrate", flag.ExitOnError)
+	dryRun := fs.Bool(
+		"dry-run", false,
+		"Show pending migrations without applying them",
+	)
+	if err := fs.Parse(args[1:]); err != nil {
+		log.Fatalf("parsing flags: %v", err)
+	}
+
+	cfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+	if err := migrateDB(cfg.DBPath, *dryRun, os.Stdout); err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+}
+
+// migrateDB brings the database at path up to the current
+// schema, or with dryRun only describes what would change.
+func migrateDB(path string, dryRun bool, out io.Writer) error {
+	plan, err := db.PlanMigrations(path)
+	if err != nil {
+		return err
+	}
+	if plan.New {
+		fmt.Fprintf(out,
+			"No database at %s; it will be created at schema "+
+				"version %d on first run.\n", path, plan.To)
+		return nil
+	}
+	if !plan.Pending() {
+		fmt.Fprintf(out,
+			"Database is up to date (schema version %d).\n",
+			plan.From)
+		return nil
+	}
+
+	writeMigrationPlan(out, plan)
+	if dryRun {
+		fmt.Fprintln(out, "\nDry run: no changes made.")
+		return nil
+	}
+
+	database, err := db.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := database.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nMigrated to schema version %d.\n", plan.To)
+	return nil
+}
+
+func writeMigrationPlan(w io.Writer, plan db.MigrationPlan) {
+	if plan.Rebuild {
+		fmt.Fprintf(w,
+			"Schema version %d predates migrations: the database "+
+				"will be rebuilt\nand sessions re-synced from their "+
+				"source files. Tags, stars and notes\nare kept.\n",
+			plan.From)
+	} else {
+		fmt.Fprintf(w, "Schema version %d -> %d:\n", plan.From, plan.To)
+		for _, s := range plan.Steps {
+			note := ""
+			if s.Reparse {
+				note = " (reparse)"
+			}
+			fmt.Fprintf(w, "  %3d  %s%s\n", s.Version, s.Name, note)
+		}
+		if plan.Reparse() {
+			fmt.Fprintln(w,
+				"\nSession files will be parsed again on the next sync.")
+		}
+	}
+	fmt.Fprintf(w, "\nBackup: %s\n", plan.Backup)
+}
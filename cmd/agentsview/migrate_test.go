@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// writeLegacyDB creates a database whose schema predates
+// versioned migrations.
+func writeLegacyDB(t *testing.T, path string) {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(`
+		CREATE TABLE sessions (id TEXT PRIMARY KEY, project TEXT);
+		INSERT INTO sessions VALUES ('old', 'proj');
+	`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateDB(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("NoDatabase", func(t *testing.T) {
+		var out bytes.Buffer
+		path := filepath.Join(dir, "missing.db")
+		if err := migrateDB(path, false, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "No database") {
+			t.Errorf("output = %q", out.String())
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("database created: %v", err)
+		}
+	})
+
+	t.Run("UpToDate", func(t *testing.T) {
+		path := filepath.Join(dir, "current.db")
+		d, err := db.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Close()
+
+		var out bytes.Buffer
+		if err := migrateDB(path, false, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "up to date") {
+			t.Errorf("output = %q", out.String())
+		}
+	})
+
+	t.Run("LegacyDryRun", func(t *testing.T) {
+		path := filepath.Join(dir, "legacy-dry.db")
+		writeLegacyDB(t, path)
+
+		var out bytes.Buffer
+		if err := migrateDB(path, true, &out); err != nil {
+			t.Fatal(err)
+		}
+		got := out.String()
+		for _, want := range []string{
+			"will be rebuilt", "Dry run", path + ".v0.bak",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("output missing %q:\n%s", want, got)
+			}
+		}
+		plan, err := db.PlanMigrations(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !plan.Rebuild {
+			t.Error("dry run changed the database")
+		}
+		if _, err := os.Stat(plan.Backup); !os.IsNotExist(err) {
+			t.Errorf("dry run wrote a backup: %v", err)
+		}
+	})
+
+	t.Run("Legacy", func(t *testing.T) {
+		path := filepath.Join(dir, "legacy.db")
+		writeLegacyDB(t, path)
+
+		var out bytes.Buffer
+		if err := migrateDB(path, false, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "Migrated to schema") {
+			t.Errorf("output = %q", out.String())
+		}
+		plan, err := db.PlanMigrations(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plan.Pending() {
+			t.Errorf("still pending after migrate: %+v", plan)
+		}
+		if _, err := os.Stat(path + ".v0.bak"); err != nil {
+			t.Errorf("backup missing: %v", err)
+		}
+	})
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/parser"
+	"github.com/wesm/agentsview/internal/sync"
+)
+
+const (
+	pushRetries       = 5
+	pushBackoff       = time.Second
+	pushMaxBackoff    = 30 * time.Second
+	pushTimeout       = 2 * time.Minute
+	pushRescanEvery   = 5 * time.Minute
+	pushDebounce      = 5 * time.Second
+	pushManifestName  = "push-manifest.json"
+	pushManifestFlush = 50
+)
+
+// pushManifest records, per server, the files pushed to it and
+// the content they had, so later pushes send only new or changed
+// files. Size and mtime let unchanged files skip hashing.
+type pushManifest struct {
+	path    string
+	Servers map[string]map[string]pushedFile `json:"servers"`
+}
+
+type pushedFile struct {
+	Hash  string `json:"hash"`
+	Size  int64  `json:"size"`
+	Mtime int64  `json:"mtime"`
+}
+
+// loadPushManifest reads the manifest at path, starting an
+// empty one if it doesn't exist.
+func loadPushManifest(path string) (*pushManifest, error) {
+	m := &pushManifest{
+		path:    path,
+		Servers: make(map[string]map[string]pushedFile),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if m.Servers == nil {
+		m.Servers = make(map[string]map[string]pushedFile)
+	}
+	return m, nil
+}
+
+// save writes the manifest atomically.
+func (m *pushManifest) save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+func (m *pushManifest) files(server string) map[string]pushedFile {
+	files, ok := m.Servers[server]
+	if !ok {
+		files = make(map[string]pushedFile)
+		m.Servers[server] = files
+	}
+	return files
+}
+
+// pushStats counts the outcome of a push pass.
+type pushStats struct {
+	Pushed    int
+	Unchanged int
+	Rejected  int
+	Failed    int
+}
+
+// pusher uploads session files to a remote agentsview server.
+type pusher struct {
+	server   string
+	machine  string
+	token    string
+	client   *http.Client
+	manifest *pushManifest
+	retries  int
+	backoff  time.Duration
+	sleep    func(time.Duration)
+}
+
+// pushableAgents are the agents whose discovered files the
+// upload endpoint accepts as they are. Sessions of the other
+// agents live in databases or span several files; see
+// unpushableSessions.
+var pushableAgents = map[parser.AgentType]bool{
+	parser.AgentClaude:  true,
+	parser.AgentCodex:   true,
+	parser.AgentCopilot: true,
+	parser.AgentGemini:  true,
+	parser.AgentGoose:   true,
+	parser.AgentAmp:     true,
+	parser.AgentOTel:    true,
+}
+
+// discoverPushFiles finds the session files of the configured
+// agents that can be pushed.
+func discoverPushFiles(cfg config.Config) []sync.DiscoveredFile {
+	var files []sync.DiscoveredFile
+	for _, d := range cfg.ResolveClaudeDirs() {
+		files = append(files, sync.DiscoverClaudeProjects(d)...)
+	}
+	for _, d := range cfg.ResolveCodexDirs() {
+		files = append(files, sync.DiscoverCodexSessions(d)...)
+	}
+	for _, d := range cfg.ResolveCopilotDirs() {
+		files = append(files, sync.DiscoverCopilotSessions(d)...)
+	}
+	for _, d := range cfg.ResolveGeminiDirs() {
+		files = append(files, sync.DiscoverGeminiSessions(d)...)
+	}
+	for _, d := range cfg.ResolveGooseDirs() {
+		files = append(files, sync.DiscoverGooseSessions(d)...)
+	}
+	for _, d := range cfg.ResolveAmpDirs() {
+		files = append(files, sync.DiscoverAmpThreads(d)...)
+	}
+	files = append(files, sync.DiscoverOTelSessions(cfg.OTelDir())...)
+	return files
+}
+
+// unpushableSessions counts the local sessions of the configured
+// agents push can't upload, by agent.
+func unpushableSessions(cfg config.Config) map[parser.AgentType]int {
+	counts := make(map[parser.AgentType]int)
+	for _, d := range cfg.AiderRepos {
+		for _, f := range sync.DiscoverAiderSessions(d) {
+			results, err := parser.ParseAiderHistory(f.Path, d, "")
+			if err == nil {
+				counts[parser.AgentAider] += len(results)
+			}
+		}
+	}
+	for _, d := range cfg.ResolveClineDirs() {
+		counts[parser.AgentCline] += len(sync.DiscoverClineTasks(d))
+	}
+	for _, d := range cfg.ResolveOpenCodeDirs() {
+		metas, err := parser.ListOpenCodeSessionMeta(
+			filepath.Join(d, "opencode.db"),
+		)
+		if err == nil {
+			counts[parser.AgentOpenCode] += len(metas)
+		}
+	}
+	for _, d := range cfg.ResolveCursorDirs() {
+		metas, err := parser.ListCursorComposerMeta(
+			parser.CursorGlobalDB(d),
+		)
+		if err == nil {
+			counts[parser.AgentCursor] += len(metas)
+		}
+	}
+	for agent, n := range counts {
+		if n == 0 {
+			delete(counts, agent)
+		}
+	}
+	return counts
+}
+
+// printSkippedSessions reports the sessions push leaves behind,
+// one line per agent.
+func printSkippedSessions(w io.Writer, counts map[parser.AgentType]int) {
+	agents := make([]string, 0, len(counts))
+	for agent := range counts {
+		agents = append(agents, string(agent))
+	}
+	sort.Strings(agents)
+	for _, agent := range agents {
+		fmt.Fprintf(
+			w, "%s: skipped %d session(s) (unsupported)\n",
+			agent, counts[parser.AgentType(agent)],
+		)
+	}
+}
+
+// pushAll pushes every new or changed file, saving the manifest
+// as it goes.
+func (p *pusher) pushAll(files []sync.DiscoveredFile) pushStats {
+	var stats pushStats
+	dirty := 0
+	for _, f := range files {
+		if !pushableAgents[f.Agent] {
+			continue
+		}
+		pushed, err := p.pushFile(f)
+		if errors.Is(err, errPushUnauthorized) {
+			// Every other file would be refused too.
+			stats.Failed++
+			log.Printf("push: %v", err)
+			break
+		}
+		switch {
+		case errors.Is(err, errUploadRejected):
+			stats.Rejected++
+			dirty++
+			log.Printf("push %s: %v", f.Path, err)
+		case err != nil:
+			stats.Failed++
+			log.Printf("push %s: %v", f.Path, err)
+		case pushed:
+			stats.Pushed++
+			dirty++
+		default:
+			stats.Unchanged++
+		}
+		if dirty >= pushManifestFlush {
+			p.saveManifest()
+			dirty = 0
+		}
+	}
+	if dirty > 0 {
+		p.saveManifest()
+	}
+	return stats
+}
+
+func (p *pusher) saveManifest() {
+	if err := p.manifest.save(); err != nil {
+		log.Printf("saving push manifest: %v", err)
+	}
+}
+
+// errUploadRejected marks files the server refused for good.
+// They are recorded like pushed files so unchanged content is
+// not sent again.
+var errUploadRejected = errors.New("rejected")
+
+// errPushUnauthorized marks uploads refused for a missing or
+// insufficient token. They are retried on the next push.
+var errPushUnauthorized = errors.New("not authorized")
+
+// pushFile uploads f unless the manifest shows the server already
+// has its current content. Reports whether it was uploaded.
+func (p *pusher) pushFile(f sync.DiscoveredFile) (bool, error) {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return false, err
+	}
+	files := p.manifest.files(p.server)
+	prev, seen := files[f.Path]
+	mtime := info.ModTime().UnixNano()
+	if seen && prev.Size == info.Size() && prev.Mtime == mtime {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return false, err
+	}
+	hash, err := sync.ComputeHash(bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+	entry := pushedFile{Hash: hash, Size: int64(len(data)), Mtime: mtime}
+	if seen && prev.Hash == hash {
+		files[f.Path] = entry
+		return false, nil
+	}
+
+	err = p.upload(f, data)
+	if err == nil || errors.Is(err, errUploadRejected) {
+		files[f.Path] = entry
+	}
+	return err == nil, err
+}
+
+// upload posts a session file to the server's upload endpoint,
+// retrying with exponential backoff while the server is
+// unreachable or failing.
+func (p *pusher) upload(f sync.DiscoveredFile, data []byte) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", pushFileName(f.Path))
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	q.Set("agent", string(f.Agent))
+	q.Set("machine", p.machine)
+	if project := pushProject(f); project != "" {
+		q.Set("project", project)
+	}
+	target := strings.TrimSuffix(p.server, "/") +
+		"/api/v1/sessions/upload?" + q.Encode()
+
+	backoff := p.backoff
+	for attempt := 1; ; attempt++ {
+		err = p.post(target, mw.FormDataContentType(), body.Bytes())
+		var retry *retryableError
+		if !errors.As(err, &retry) || attempt >= p.retries {
+			return err
+		}
+		log.Printf(
+			"push %s: %v; retrying in %s", f.Path, err, backoff,
+		)
+		p.sleep(backoff)
+		backoff = min(backoff*2, pushMaxBackoff)
+	}
+}
+
+// retryableError is an upload failure worth retrying: a network
+// error, a server error or rate limiting.
+type retryableError struct{ err error }
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (p *pusher) post(target, contentType string, body []byte) error {
+	req, err := http.NewRequest(
+		http.MethodPost, target, bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return &retryableError{err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	msg := resp.Status
+	var e struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &e) == nil && e.Error != "" {
+		msg = fmt.Sprintf("%s: %s", resp.Status, e.Error)
+	}
+	if resp.StatusCode == http.StatusUnauthorized ||
+		resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: %s", errPushUnauthorized, msg)
+	}
+	if resp.StatusCode >= 500 ||
+		resp.StatusCode == http.StatusTooManyRequests {
+		return &retryableError{errors.New(msg)}
+	}
+	return fmt.Errorf("%w: %s", errUploadRejected, msg)
+}
+
+// pushFileName is the name a file is uploaded under. A Copilot
+// events.jsonl is named after its session directory, which the
+// server takes its session ID from.
+func pushFileName(path string) string {
+	if filepath.Base(path) == "events.jsonl" {
+		return filepath.Base(filepath.Dir(path)) + ".jsonl"
+	}
+	return filepath.Base(path)
+}
+
+// pushProject returns the project to upload f under, or "" to
+// let the server take it from the session. Claude sessions get
+// the name a local sync would give them.
+func pushProject(f sync.DiscoveredFile) string {
+	project := f.Project
+	if f.Agent == parser.AgentClaude {
+		if p := sync.ClaudeProject(f); p != "" {
+			project = p
+		}
+	}
+	if project == "" || strings.ContainsAny(project, "/\\") ||
+		strings.HasPrefix(project, ".") {
+		if f.Agent == parser.AgentClaude {
+			return "unknown"
+		}
+		return ""
+	}
+	return project
+}
+
+func runPush(args []string) {
+	fs := flag.NewFlagSet("push", flag.ExitOnError)
+	server := fs.String("server", "",
+		"URL of the agentsview server to push to")
+	machine := fs.String("machine", "",
+		"Machine name to record (default: hostname)")
+	watch := fs.Bool("watch", false,
+		"Keep running and push sessions as they change")
+	token := fs.String("token", os.Getenv("AGENTSVIEW_TOKEN"),
+		"API token with the upload scope (default: $AGENTSVIEW_TOKEN)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(),
+			"Usage: agentsview push -server URL [flags]\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("parsing flags: %v", err)
+	}
+	if *server == "" || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if u, err := url.Parse(*server); err != nil ||
+		(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Fatalf("invalid server URL: %s", *server)
+	}
+	if *machine == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "remote"
+		}
+		*machine = host
+	}
+
+	cfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		log.Fatalf("creating data dir: %v", err)
+	}
+	manifest, err := loadPushManifest(
+		filepath.Join(cfg.DataDir, pushManifestName),
+	)
+	if err != nil {
+		log.Fatalf("loading push manifest: %v", err)
+	}
+
+	p := &pusher{
+		server:   strings.TrimSuffix(*server, "/"),
+		machine:  *machine,
+		token:    *token,
+		client:   &http.Client{Timeout: pushTimeout},
+		manifest: manifest,
+		retries:  pushRetries,
+		backoff:  pushBackoff,
+		sleep:    time.Sleep,
+	}
+
+	printSkippedSessions(os.Stdout, unpushableSessions(cfg))
+	stats := p.pushAll(discoverPushFiles(cfg))
+	printPushStats(stats)
+	if !*watch {
+		if stats.Failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	watchAndPush(cfg, p)
+}
+
+func printPushStats(s pushStats) {
+	fmt.Printf(
+		"Pushed %d file(s), %d unchanged, %d rejected, %d failed\n",
+		s.Pushed, s.Unchanged, s.Rejected, s.Failed,
+	)
+}
+
+// watchAndPush pushes sessions as their files change until
+// interrupted. Changes trigger a push pass over all discovered
+// files, which the manifest keeps cheap; a periodic pass picks
+// up failed uploads and directories that could not be watched.
+func watchAndPush(cfg config.Config, p *pusher) {
+	changed := make(chan struct{}, 1)
+	watcher, err := sync.NewWatcher(pushDebounce, func([]string) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		log.Printf(
+			"warning: file watcher unavailable: %v"+
+				"; will rescan every %s",
+			err, pushRescanEvery,
+		)
+	} else {
+		for _, r := range sessionWatchRoots(cfg) {
+			if _, uw, _ := watcher.WatchRecursive(r.root); uw > 0 {
+				log.Printf(
+					"Couldn't watch %d directories under %s, will rescan every %s",
+					uw, r.dir, pushRescanEvery,
+				)
+			}
+		}
+		watcher.Start()
+		defer watcher.Stop()
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	ticker := time.NewTicker(pushRescanEvery)
+	defer ticker.Stop()
+
+	fmt.Printf("Watching for session changes, pushing to %s\n", p.server)
+	for {
+		select {
+		case <-changed:
+		case <-ticker.C:
+		case <-interrupt:
+			return
+		}
+		if s := p.pushAll(discoverPushFiles(cfg)); s.Pushed+s.Rejected+s.Failed > 0 {
+			printPushStats(s)
+		}
+	}
+}
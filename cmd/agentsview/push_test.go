@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/parser"
+	"github.com/wesm/agentsview/internal/sync"
+)
+
+// pushRecorder is a fake upload endpoint that answers with the
+// queued statuses, then 200, recording each upload.
+type pushRecorder struct {
+	statuses []int
+	uploads  []*http.Request
+	names    []string
+}
+
+func (r *pushRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	io.Copy(io.Discard, file)
+	r.uploads = append(r.uploads, req)
+	r.names = append(r.names, header.Filename)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	io.WriteString(w, `{"error":"nope"}`)
+}
+
+func newTestPusher(
+	t *testing.T, rec *pushRecorder,
+) (*pusher, *[]time.Duration) {
+	t.Helper()
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+	manifest, err := loadPushManifest(
+		filepath.Join(t.TempDir(), pushManifestName),
+	)
+	if err != nil {
+		t.Fatalf("loadPushManifest: %v", err)
+	}
+	var sleeps []time.Duration
+	return &pusher{
+		server:   srv.URL,
+		machine:  "laptop",
+		client:   srv.Client(),
+		manifest: manifest,
+		retries:  3,
+		backoff:  time.Second,
+		sleep:    func(d time.Duration) { sleeps = append(sleeps, d) },
+	}, &sleeps
+}
+
+func writePushFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushAll_OnlyNewOrChanged(t *testing.T) {
+	dir := t.TempDir()
+	claude := filepath.Join(dir, "-home-u-code-app", "s1.jsonl")
+	copilot := filepath.Join(dir, "session-state", "cp-1", "events.jsonl")
+	writePushFile(t, claude, `{"type":"user"}`+"\n")
+	writePushFile(t, copilot, `{"type":"session.start"}`+"\n")
+	files := []sync.DiscoveredFile{
+		{Path: claude, Project: "-home-u-code-app", Agent: parser.AgentClaude},
+		{Path: copilot, Agent: parser.AgentCopilot},
+		{Path: filepath.Join(dir, "x.db"), Agent: parser.AgentOpenCode},
+	}
+
+	rec := &pushRecorder{}
+	p, _ := newTestPusher(t, rec)
+
+	stats := p.pushAll(files)
+	if stats.Pushed != 2 || stats.Failed != 0 {
+		t.Fatalf("first push = %+v", stats)
+	}
+	if rec.names[1] != "cp-1.jsonl" {
+		t.Errorf("copilot uploaded as %q", rec.names[1])
+	}
+	q := rec.uploads[0].URL.Query()
+	if q.Get("agent") != "claude" || q.Get("project") != "app" ||
+		q.Get("machine") != "laptop" {
+		t.Errorf("claude query = %v", q)
+	}
+
+	stats = p.pushAll(files)
+	if stats.Pushed != 0 || stats.Unchanged != 2 {
+		t.Errorf("second push = %+v", stats)
+	}
+
+	// A touched but identical file is not sent again.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(claude, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if stats = p.pushAll(files); stats.Pushed != 0 {
+		t.Errorf("push after touch = %+v", stats)
+	}
+
+	writePushFile(t, claude, `{"type":"user"}`+"\n"+`{"type":"assistant"}`+"\n")
+	if stats = p.pushAll(files); stats.Pushed != 1 {
+		t.Errorf("push after change = %+v", stats)
+	}
+	if len(rec.uploads) != 3 {
+		t.Errorf("got %d uploads, want 3", len(rec.uploads))
+	}
+
+	// The manifest survives a restart.
+	m, err := loadPushManifest(p.manifest.path)
+	if err != nil {
+		t.Fatalf("reloading manifest: %v", err)
+	}
+	if got := len(m.Servers[p.server]); got != 2 {
+		t.Errorf("manifest has %d files, want 2", got)
+	}
+}
+
+func TestPushFile_RetriesWithBackoff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rollout.jsonl")
+	writePushFile(t, path, `{"type":"session_meta"}`+"\n")
+	f := sync.DiscoveredFile{Path: path, Agent: parser.AgentCodex}
+
+	rec := &pushRecorder{statuses: []int{503, 429}}
+	p, sleeps := newTestPusher(t, rec)
+	pushed, err := p.pushFile(f)
+	if err != nil || !pushed {
+		t.Fatalf("pushFile = %v, %v", pushed, err)
+	}
+	if len(rec.uploads) != 3 {
+		t.Errorf("got %d attempts, want 3", len(rec.uploads))
+	}
+	if len(*sleeps) != 2 || (*sleeps)[0] != time.Second ||
+		(*sleeps)[1] != 2*time.Second {
+		t.Errorf("sleeps = %v", *sleeps)
+	}
+
+	// Failures past the retry limit leave the file unrecorded.
+	writePushFile(t, path, `{"type":"session_meta","x":1}`+"\n")
+	rec.statuses = []int{500, 500, 500}
+	if _, err := p.pushFile(f); err == nil {
+		t.Fatal("expected error after retries")
+	}
+	if p.manifest.files(p.server)[path].Hash == "" {
+		t.Fatal("earlier push should still be recorded")
+	}
+	if _, err := p.pushFile(f); err != nil {
+		t.Errorf("push after recovery: %v", err)
+	}
+}
+
+func TestPushFile_RejectedNotRetried(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.jsonl")
+	writePushFile(t, path, "\n")
+	f := sync.DiscoveredFile{Path: path, Agent: parser.AgentCodex}
+
+	rec := &pushRecorder{statuses: []int{400}}
+	p, sleeps := newTestPusher(t, rec)
+	stats := p.pushAll([]sync.DiscoveredFile{f})
+	if stats.Rejected != 1 || len(*sleeps) != 0 {
+		t.Fatalf("stats = %+v, sleeps = %v", stats, *sleeps)
+	}
+	if stats = p.pushAll([]sync.DiscoveredFile{f}); stats.Unchanged != 1 {
+		t.Errorf("rejected file pushed again: %+v", stats)
+	}
+}
+
+func TestPushAll_UnauthorizedStops(t *testing.T) {
+	dir := t.TempDir()
+	var files []sync.DiscoveredFile
+	for _, name := range []string{"a.jsonl", "b.jsonl"} {
+		path := filepath.Join(dir, name)
+		writePushFile(t, path, `{"type":"session_meta"}`+"\n")
+		files = append(files, sync.DiscoveredFile{
+			Path: path, Agent: parser.AgentCodex,
+		})
+	}
+
+	rec := &pushRecorder{statuses: []int{401}}
+	p, sleeps := newTestPusher(t, rec)
+	p.token = "avt_secret"
+	stats := p.pushAll(files)
+	if stats.Failed != 1 || len(rec.uploads) != 1 || len(*sleeps) != 0 {
+		t.Fatalf("stats = %+v, uploads = %d", stats, len(rec.uploads))
+	}
+	if got := rec.uploads[0].Header.Get("Authorization"); got != "Bearer avt_secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	// Nothing was recorded, so a later push sends both.
+	if stats = p.pushAll(files); stats.Pushed != 2 {
+		t.Errorf("push after auth fixed = %+v", stats)
+	}
+}
+
+func TestDiscoverPushFiles_SkipsUnsupported(t *testing.T) {
+	dataDir := t.TempDir()
+	clineDir := t.TempDir()
+	for _, id := range []string{"1700000000000", "1700000001000"} {
+		writePushFile(t, filepath.Join(
+			clineDir, "tasks", id, parser.ClineHistoryFile,
+		), "[]")
+	}
+	writePushFile(t, filepath.Join(dataDir, "otel", "conv-1.jsonl"), "{}\n")
+	cfg := config.Config{
+		DataDir:    dataDir,
+		ClineDirs:  []string{clineDir},
+		CursorDirs: []string{t.TempDir()},
+	}
+
+	files := discoverPushFiles(cfg)
+	if len(files) != 1 || files[0].Agent != parser.AgentOTel {
+		t.Errorf("discovered %+v, want the OTel session", files)
+	}
+
+	var out bytes.Buffer
+	printSkippedSessions(&out, unpushableSessions(cfg))
+	if got, want := out.String(),
+		"cline: skipped 2 session(s) (unsupported)\n"; got != want {
+		t.Errorf("skipped report = %q, want %q", got, want)
+	}
+}
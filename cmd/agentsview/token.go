@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/wesm/agentsview/internal/config"
+)
+
+const tokenUsage = `Usage:
+  agentsview token create -name NAME [-scopes read,write,upload,admin]
+  agentsview token list
+  agentsview token revoke <id|name>`
+
+func runToken(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, tokenUsage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ExitOnError)
+		name := fs.String("name", "", "Name describing the token's use")
+		scopes := fs.String("scopes", config.ScopeRead,
+			"Comma-separated scopes: read, write, upload, admin")
+		if err := fs.Parse(args[1:]); err != nil {
+			log.Fatalf("parsing flags: %v", err)
+		}
+		if *name == "" || fs.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, tokenUsage)
+			os.Exit(2)
+		}
+		err = createToken(&cfg, *name, *scopes, os.Stdout)
+	case "list":
+		err = listTokens(cfg, os.Stdout)
+	case "revoke":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, tokenUsage)
+			os.Exit(2)
+		}
+		err = revokeToken(&cfg, args[1], os.Stdout)
+	default:
+		fmt.Fprintln(os.Stderr, tokenUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("token %s: %v", args[0], err)
+	}
+}
+
+// createToken adds a token to the config and prints it. The
+// token is not stored anywhere, so this is the only time it is
+// shown.
+func createToken(
+	cfg *config.Config, name, scopes string, out io.Writer,
+) error {
+	for _, t := range cfg.Auth.Tokens {
+		if t.Name == name {
+			return fmt.Errorf("a token named %q already exists", name)
+		}
+	}
+	var list []string
+	for s := range strings.SplitSeq(scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	tok, secret, err := config.NewAuthToken(name, list)
+	if err != nil {
+		return err
+	}
+	tokens := append(slices.Clone(cfg.Auth.Tokens), tok)
+	if err := cfg.SaveAuthTokens(tokens); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Created token %s (%s) with scopes %s:\n\n  %s\n\n",
+		tok.ID, tok.Name, strings.Join(tok.Scopes, ","), secret)
+	fmt.Fprintln(out,
+		"Store it now; it cannot be shown again. A server that "+
+			"already requires authentication accepts it within seconds; "+
+			"otherwise restart the server.")
+	return nil
+}
+
+// listTokens prints the configured tokens, without secrets.
+func listTokens(cfg config.Config, out io.Writer) error {
+	if len(cfg.Auth.Tokens) == 0 {
+		fmt.Fprintln(out, "No tokens.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSCOPES\tCREATED")
+	for _, t := range cfg.Auth.Tokens {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			t.ID, t.Name, strings.Join(t.Scopes, ","), t.CreatedAt)
+	}
+	return tw.Flush()
+}
+
+// revokeToken removes the token with the given ID or name.
+func revokeToken(cfg *config.Config, ref string, out io.Writer) error {
+	var (
+		kept    []config.AuthToken
+		revoked *config.AuthToken
+	)
+	for _, t := range cfg.Auth.Tokens {
+		if revoked == nil && (t.ID == ref || t.Name == ref) {
+			revoked = &t
+			continue
+		}
+		kept = append(kept, t)
+	}
+	if revoked == nil {
+		return fmt.Errorf("no token with ID or name %q", ref)
+	}
+	if err := cfg.SaveAuthTokens(kept); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Revoked token %s (%s). "+
+		"Running servers refuse it within seconds.\n",
+		revoked.ID, revoked.Name)
+	return nil
+}
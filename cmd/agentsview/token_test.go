@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/config"
+)
+
+func TestTokenCommands(t *testing.T) {
+	cfg := config.Config{DataDir: t.TempDir()}
+
+	var out bytes.Buffer
+	if err := createToken(&cfg, "laptop", "read, upload", &out); err != nil {
+		t.Fatal(err)
+	}
+	secret := regexp.MustCompile(`avt_\S+`).FindString(out.String())
+	if secret == "" {
+		t.Fatalf("no token printed:\n%s", out.String())
+	}
+	if len(cfg.Auth.Tokens) != 1 || !cfg.Auth.Required ||
+		cfg.Auth.Tokens[0].Hash != config.HashToken(secret) {
+		t.Fatalf("auth = %+v", cfg.Auth)
+	}
+	if err := createToken(&cfg, "laptop", "read", &out); err == nil {
+		t.Error("expected error for duplicate name")
+	}
+	if err := createToken(&cfg, "ci", "delete", &out); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+
+	out.Reset()
+	if err := listTokens(cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "laptop") ||
+		!strings.Contains(out.String(), "read,upload") ||
+		strings.Contains(out.String(), secret) {
+		t.Errorf("list output:\n%s", out.String())
+	}
+
+	if err := revokeToken(&cfg, "nope", &out); err == nil {
+		t.Error("expected error for unknown token")
+	}
+	if err := revokeToken(&cfg, cfg.Auth.Tokens[0].ID, &out); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := listTokens(cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "No tokens") {
+		t.Errorf("list after revoke:\n%s", out.String())
+	}
+}
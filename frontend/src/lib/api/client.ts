@@ -18,17 +18,30 @@ import type {
   ActivityResponse,
   HeatmapResponse,
   ProjectsAnalyticsResponse,
+  BranchesAnalyticsResponse,
   HourOfWeekResponse,
   SessionShapeResponse,
   VelocityResponse,
   ToolsAnalyticsResponse,
   TopSessionsResponse,
+  CostAnalyticsResponse,
   Granularity,
   HeatmapMetric,
   TopSessionsMetric,
   Insight,
   InsightsResponse,
   GenerateInsightRequest,
+  Tag,
+  Star,
+  Note,
+  TagsResponse,
+  StarsResponse,
+  NotesResponse,
+  SecretsResponse,
+  ChangesResponse,
+  FileSessionsResponse,
+  FileHotspotsResponse,
+  CommitSessionsResponse,
 } from "./types.js";
 
 const BASE = "/api/v1";
@@ -90,6 +103,8 @@ function buildQuery(
 export interface ListSessionsParams {
   project?: string;
   exclude_project?: string;
+  branch?: string;
+  repo?: string;
   machine?: string;
   agent?: string;
   date?: string;
@@ -99,6 +114,8 @@ export interface ListSessionsParams {
   min_messages?: number;
   max_messages?: number;
   min_user_messages?: number;
+  tag?: string;
+  starred?: boolean;
   cursor?: string;
   limit?: number;
 }
@@ -116,6 +133,116 @@ export function getSession(
   return fetchJSON(`/sessions/${id}`, init);
 }
 
+/* Annotations */
+
+function sendJSON<T>(
+  method: string,
+  path: string,
+  body?: unknown,
+): Promise<T> {
+  return fetchJSON(path, {
+    method,
+    headers: { "Content-Type": "application/json" },
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+}
+
+async function deleteResource(path: string): Promise<void> {
+  const res = await fetch(`${BASE}${path}`, {
+    method: "DELETE",
+  });
+  if (!res.ok) {
+    const body = await res.text();
+    throw new ApiError(
+      res.status,
+      apiErrorMessage(res.status, body),
+    );
+  }
+}
+
+export function listTags(sessionId: string): Promise<TagsResponse> {
+  return fetchJSON(`/sessions/${sessionId}/tags`);
+}
+
+export function addTag(
+  sessionId: string,
+  tag: string,
+  ordinal?: number,
+): Promise<Tag> {
+  return sendJSON("POST", `/sessions/${sessionId}/tags`, {
+    tag,
+    ordinal,
+  });
+}
+
+export function removeTag(
+  sessionId: string,
+  tag: string,
+  ordinal?: number,
+): Promise<void> {
+  return deleteResource(
+    `/sessions/${sessionId}/tags/${encodeURIComponent(tag)}` +
+      buildQuery({ ordinal }),
+  );
+}
+
+export function listStars(sessionId: string): Promise<StarsResponse> {
+  return fetchJSON(`/sessions/${sessionId}/stars`);
+}
+
+export function addStar(
+  sessionId: string,
+  ordinal?: number,
+): Promise<Star> {
+  return sendJSON(
+    "PUT",
+    `/sessions/${sessionId}/star${buildQuery({ ordinal })}`,
+  );
+}
+
+export function removeStar(
+  sessionId: string,
+  ordinal?: number,
+): Promise<void> {
+  return deleteResource(
+    `/sessions/${sessionId}/star${buildQuery({ ordinal })}`,
+  );
+}
+
+export function listNotes(sessionId: string): Promise<NotesResponse> {
+  return fetchJSON(`/sessions/${sessionId}/notes`);
+}
+
+export function addNote(
+  sessionId: string,
+  body: string,
+  ordinal?: number,
+): Promise<Note> {
+  return sendJSON("POST", `/sessions/${sessionId}/notes`, {
+    body,
+    ordinal,
+  });
+}
+
+export function updateNote(
+  sessionId: string,
+  noteId: number,
+  body: string,
+): Promise<Note> {
+  return sendJSON(
+    "PUT",
+    `/sessions/${sessionId}/notes/${noteId}`,
+    { body },
+  );
+}
+
+export function deleteNote(
+  sessionId: string,
+  noteId: number,
+): Promise<void> {
+  return deleteResource(`/sessions/${sessionId}/notes/${noteId}`);
+}
+
 /* Messages */
 
 export interface GetMessagesParams {
@@ -155,6 +282,8 @@ export function search(
   query: string,
   params: {
     project?: string;
+    tag?: string;
+    starred?: boolean;
     limit?: number;
     cursor?: number;
   } = {},
@@ -333,9 +462,54 @@ export function watchSession(
   return es;
 }
 
-/** Get the export URL for a session */
-export function getExportUrl(sessionId: string): string {
-  return `${BASE}/sessions/${sessionId}/export`;
+export type ExportFormat = "html" | "markdown" | "json";
+
+/** Get the export URL for a session (HTML unless a format is given) */
+export function getExportUrl(
+  sessionId: string,
+  format?: ExportFormat,
+): string {
+  const url = `${BASE}/sessions/${sessionId}/export`;
+  return format ? `${url}?format=${format}` : url;
+}
+
+/** List secrets detected in a session's messages */
+export function listSecrets(sessionId: string): Promise<SecretsResponse> {
+  return fetchJSON(`/sessions/${sessionId}/secrets`);
+}
+
+/** List the files a session changed, with unified diffs */
+export function getSessionChanges(
+  sessionId: string,
+  params: { diff?: boolean } = {},
+): Promise<ChangesResponse> {
+  return fetchJSON(
+    `/sessions/${sessionId}/changes${buildQuery({ ...params })}`,
+  );
+}
+
+/* Files */
+
+/** List the sessions that read or changed a file */
+export function getFileSessions(
+  path: string,
+  params: { project?: string; limit?: number } = {},
+): Promise<FileSessionsResponse> {
+  return fetchJSON(`/files${buildQuery({ path, ...params })}`);
+}
+
+/** Most changed files per project */
+export function getFileHotspots(
+  params: { project?: string; limit?: number } = {},
+): Promise<FileHotspotsResponse> {
+  return fetchJSON(`/files/hotspots${buildQuery({ ...params })}`);
+}
+
+/** Sessions linked to the commits whose SHA starts with sha */
+export function getCommitSessions(
+  sha: string,
+): Promise<CommitSessionsResponse> {
+  return fetchJSON(`/commits/${encodeURIComponent(sha)}`);
 }
 
 /* Publish / GitHub config */
@@ -370,6 +544,8 @@ export interface AnalyticsParams {
   timezone?: string;
   machine?: string;
   project?: string;
+  branch?: string;
+  repo?: string;
   agent?: string;
   dow?: number;
   hour?: number;
@@ -413,6 +589,22 @@ export function getAnalyticsProjects(
   );
 }
 
+export function getAnalyticsBranches(
+  params: AnalyticsParams,
+): Promise<BranchesAnalyticsResponse> {
+  return fetchJSON(
+    `/analytics/branches${buildQuery({ ...params })}`,
+  );
+}
+
+export function getAnalyticsCost(
+  params: AnalyticsParams,
+): Promise<CostAnalyticsResponse> {
+  return fetchJSON(
+    `/analytics/cost${buildQuery({ ...params })}`,
+  );
+}
+
 export function getAnalyticsHourOfWeek(
   params: AnalyticsParams,
 ): Promise<HourOfWeekResponse> {
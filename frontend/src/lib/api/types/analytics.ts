@@ -31,6 +31,10 @@ export interface ActivityEntry {
   tool_calls: number;
   thinking_messages: number;
   by_agent: Record<string, number>;
+  input_tokens: number;
+  output_tokens: number;
+  cache_creation_input_tokens: number;
+  cache_read_input_tokens: number;
 }
 
 export interface ActivityResponse {
@@ -73,6 +77,25 @@ export interface ProjectsAnalyticsResponse {
   projects: ProjectAnalytics[];
 }
 
+export interface BranchAnalytics {
+  project: string;
+  repo_root: string;
+  branch: string;
+  sessions: number;
+  messages: number;
+  user_messages: number;
+  input_tokens: number;
+  output_tokens: number;
+  cost_usd: number;
+  first_session: string;
+  last_session: string;
+  agents: Record<string, number>;
+}
+
+export interface BranchesAnalyticsResponse {
+  branches: BranchAnalytics[];
+}
+
 export interface HourOfWeekCell {
   day_of_week: number;
   hour: number;
@@ -150,10 +173,41 @@ export interface ToolTrendEntry {
   by_category: Record<string, number>;
 }
 
+export interface ToolErrorRate {
+  name: string;
+  calls: number;
+  errors: number;
+  rate: number;
+}
+
 export interface ToolsAnalyticsResponse {
   total_calls: number;
   unblocked_calls: number;
+  error_calls: number;
   by_category: ToolCategoryCount[];
   by_agent: ToolAgentBreakdown[];
   trend: ToolTrendEntry[];
+  errors_by_category: ToolErrorRate[];
+  errors_by_tool: ToolErrorRate[];
+  errors_by_project: ToolErrorRate[];
+}
+
+export interface DailyCost {
+  date: string;
+  cost_usd: number;
+}
+
+export interface CostBreakdown {
+  name: string;
+  cost_usd: number;
+  sessions: number;
+}
+
+export interface CostAnalyticsResponse {
+  total_usd: number;
+  daily: DailyCost[];
+  by_project: CostBreakdown[];
+  by_agent: CostBreakdown[];
+  by_model: CostBreakdown[];
+  unpriced_models: string[];
 }
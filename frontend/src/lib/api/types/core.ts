@@ -28,6 +28,157 @@ export interface Session {
   file_size?: number;
   file_mtime?: number;
   created_at: string;
+  cost_usd?: number;
+  cost_by_model?: Record<string, number>;
+  tags?: string[];
+  starred?: boolean;
+  cwd?: string;
+  git_branch?: string;
+  repo_root?: string;
+  /** Linked git commits; only set by the single-session endpoint */
+  commits?: SessionCommit[];
+}
+
+/** Matches Go Tag struct; ordinal is unset for session tags */
+export interface Tag {
+  session_id: string;
+  ordinal?: number;
+  tag: string;
+  created_at: string;
+}
+
+/** Matches Go Star struct */
+export interface Star {
+  session_id: string;
+  ordinal?: number;
+  created_at: string;
+}
+
+/** Matches Go Note struct */
+export interface Note {
+  id: number;
+  session_id: string;
+  ordinal?: number;
+  body: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface TagsResponse {
+  tags: Tag[];
+}
+
+export interface StarsResponse {
+  stars: Star[];
+}
+
+export interface NotesResponse {
+  notes: Note[];
+}
+
+/** Matches Go secretFinding struct in internal/server/secrets.go */
+export interface SecretFinding {
+  session_id: string;
+  ordinal: number;
+  field: "content" | "tool_input" | "tool_result";
+  tool_call?: number;
+  rule: string;
+  start: number;
+  end: number;
+  preview: string;
+}
+
+export interface SecretsResponse {
+  findings: SecretFinding[];
+  count: number;
+}
+
+/** Matches Go changes.File struct */
+export interface FileChange {
+  path: string;
+  old_path?: string;
+  status: "added" | "modified" | "deleted" | "renamed";
+  added: number;
+  removed: number;
+  edits: number;
+  ordinals: number[];
+  diff: string;
+}
+
+export interface ChangesResponse {
+  files: FileChange[];
+  added: number;
+  removed: number;
+}
+
+/** Matches Go db.FileSession struct */
+export interface FileSession {
+  session_id: string;
+  project: string;
+  machine: string;
+  agent: string;
+  first_message: string | null;
+  started_at: string | null;
+  ended_at: string | null;
+  path: string;
+  repo_root?: string;
+  reads: number;
+  edits: number;
+  writes: number;
+}
+
+export interface FileSessionsResponse {
+  path: string;
+  sessions: FileSession[];
+  count: number;
+}
+
+/** Matches Go db.FileHotspot struct */
+export interface FileHotspot {
+  project: string;
+  path: string;
+  repo_root?: string;
+  changes: number;
+  sessions: number;
+  last_session_id: string;
+  last_active: string | null;
+}
+
+export interface FileHotspotsResponse {
+  hotspots: FileHotspot[];
+}
+
+/** Matches Go db.SessionCommit struct */
+export interface SessionCommit {
+  sha: string;
+  repo_root: string;
+  author: string;
+  email: string;
+  authored_at: string;
+  subject: string;
+  files: string[];
+}
+
+/** Matches Go db.CommitSession struct */
+export interface CommitSession {
+  sha: string;
+  repo_root: string;
+  subject: string;
+  authored_at: string;
+  files: string[];
+  session_id: string;
+  project: string;
+  machine: string;
+  agent: string;
+  first_message: string | null;
+  started_at: string | null;
+  ended_at: string | null;
+}
+
+export interface CommitSessionsResponse {
+  sha: string;
+  sessions: CommitSession[];
+  count: number;
 }
 
 /** Matches Go SessionPage struct */
@@ -61,6 +212,8 @@ export interface ToolCall {
   result_content_length?: number;
   result_content?: string;
   subagent_session_id?: string;
+  is_error?: boolean;
+  exit_code?: number;
 }
 
 /** Matches Go Message struct in internal/db/messages.go */
@@ -75,6 +228,11 @@ export interface Message {
   has_tool_use: boolean;
   content_length: number;
   tool_calls?: ToolCall[];
+  model?: string;
+  input_tokens?: number;
+  output_tokens?: number;
+  cache_creation_input_tokens?: number;
+  cache_read_input_tokens?: number;
 }
 
 /** Matches Go MinimapEntry struct */
@@ -93,9 +93,14 @@ function makeVelocity(): VelocityResponse {
 function makeTools(): ToolsAnalyticsResponse {
   return {
     total_calls: 0,
+    unblocked_calls: 0,
+    error_calls: 0,
     by_category: [],
     by_agent: [],
     trend: [],
+    errors_by_category: [],
+    errors_by_tool: [],
+    errors_by_project: [],
   };
 }
 
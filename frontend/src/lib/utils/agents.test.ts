@@ -13,6 +13,12 @@ describe("KNOWN_AGENTS", () => {
       "copilot",
       "gemini",
       "opencode",
+      "aider",
+      "cline",
+      "cursor",
+      "goose",
+      "amp",
+      "otel",
     ]);
   });
 
@@ -40,6 +46,24 @@ describe("agentColor", () => {
     expect(agentColor("opencode")).toBe(
       "var(--accent-purple)",
     );
+    expect(agentColor("aider")).toBe(
+      "var(--accent-teal)",
+    );
+    expect(agentColor("cline")).toBe(
+      "var(--accent-indigo)",
+    );
+    expect(agentColor("cursor")).toBe(
+      "var(--accent-slate)",
+    );
+    expect(agentColor("goose")).toBe(
+      "var(--accent-orange)",
+    );
+    expect(agentColor("amp")).toBe(
+      "var(--accent-cyan)",
+    );
+    expect(agentColor("otel")).toBe(
+      "var(--accent-lime)",
+    );
   });
 
   it("falls back to blue for unknown agents", () => {
@@ -9,6 +9,12 @@ export const KNOWN_AGENTS: readonly AgentMeta[] = [
   { name: "copilot", color: "var(--accent-amber)" },
   { name: "gemini", color: "var(--accent-rose)" },
   { name: "opencode", color: "var(--accent-purple)" },
+  { name: "aider", color: "var(--accent-teal)" },
+  { name: "cline", color: "var(--accent-indigo)" },
+  { name: "cursor", color: "var(--accent-slate)" },
+  { name: "goose", color: "var(--accent-orange)" },
+  { name: "amp", color: "var(--accent-cyan)" },
+  { name: "otel", color: "var(--accent-lime)" },
 ];
 
 const agentColorMap = new Map(
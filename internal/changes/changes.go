@@ -0,0 +1,335 @@
+// Package changes reconstructs the file changes made in a
+// session from its edit tool calls: Claude Code Edit, MultiEdit
+// and Write, their OpenCode equivalents, and Codex apply_patch.
+package changes
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Call is a tool call as recorded in a session.
+type Call struct {
+	Ordinal   int
+	ToolName  string
+	InputJSON string
+	// Failed calls changed nothing and are skipped.
+	Failed bool
+}
+
+// File statuses.
+const (
+	StatusAdded    = "added"
+	StatusModified = "modified"
+	StatusDeleted  = "deleted"
+	StatusRenamed  = "renamed"
+)
+
+// File is the net change to one file. Diff is in unified
+// format. When the file's contents before the session are not
+// known, each edit becomes its own hunks, numbered from the
+// start of the edited text.
+type File struct {
+	Path     string `json:"path"`
+	OldPath  string `json:"old_path,omitempty"`
+	Status   string `json:"status"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+	Edits    int    `json:"edits"`
+	Ordinals []int  `json:"ordinals"`
+	Diff     string `json:"diff"`
+}
+
+// editKind is what a single edit does to a file.
+type editKind int
+
+const (
+	editReplace editKind = iota // replace oldText with newText
+	editWrite                   // set the whole file to newText
+	editPatch                   // apply patch ops
+	editDelete                  // delete the file
+)
+
+// edit is one normalized change to a file.
+type edit struct {
+	kind       editKind
+	path       string
+	moveTo     string
+	oldText    string
+	newText    string
+	replaceAll bool
+	created    bool
+	chunks     [][]op
+}
+
+// fileState tracks a file across the edits of a session.
+type fileState struct {
+	file    File
+	created bool
+	deleted bool
+	// whole is set while the file's diff is its full content,
+	// written once in finish.
+	whole bool
+	// content is the current file content, when the session
+	// wrote all of it.
+	content *string
+	diff    strings.Builder
+}
+
+// Compute returns the files changed by calls, in the order they
+// were first changed.
+func Compute(calls []Call) []File {
+	var order []*fileState
+	files := map[string]*fileState{}
+	for _, c := range calls {
+		if c.Failed {
+			continue
+		}
+		for _, e := range parseCall(c.ToolName, c.InputJSON) {
+			st := files[e.path]
+			if st == nil {
+				st = &fileState{file: File{Path: e.path}}
+				files[e.path] = st
+				order = append(order, st)
+			}
+			st.apply(e)
+			st.file.Edits++
+			if n := len(st.file.Ordinals); n == 0 ||
+				st.file.Ordinals[n-1] != c.Ordinal {
+				st.file.Ordinals = append(st.file.Ordinals, c.Ordinal)
+			}
+			if e.moveTo != "" && e.moveTo != e.path {
+				delete(files, e.path)
+				if st.file.OldPath == "" {
+					st.file.OldPath = e.path
+				}
+				st.file.Path = e.moveTo
+				if other := files[e.moveTo]; other != nil {
+					other.file.Path = ""
+				}
+				files[e.moveTo] = st
+			}
+		}
+	}
+
+	out := make([]File, 0, len(order))
+	for _, st := range order {
+		if st.file.Path == "" {
+			// Overwritten by a rename.
+			continue
+		}
+		out = append(out, st.finish())
+	}
+	return out
+}
+
+// Paths returns the files an edit tool call changes, without
+// computing diffs.
+func Paths(toolName, inputJSON string) []string {
+	var out []string
+	for _, e := range parseCall(toolName, inputJSON) {
+		out = append(out, e.path)
+		if e.moveTo != "" {
+			out = append(out, e.moveTo)
+		}
+	}
+	return out
+}
+
+func (st *fileState) apply(e edit) {
+	if e.kind != editDelete {
+		st.deleted = false
+	}
+	switch e.kind {
+	case editWrite:
+		if st.file.Edits == 0 && st.content == nil {
+			// A file written before any other change is
+			// treated as new; Write does not say whether it
+			// existed.
+			st.created, st.whole = true, true
+			st.content = new(string)
+		}
+		st.setContent(e.newText)
+	case editReplace:
+		if st.content != nil {
+			if !strings.Contains(*st.content, e.oldText) {
+				st.forget()
+			} else {
+				n := 1
+				if e.replaceAll {
+					n = -1
+				}
+				st.setContent(strings.Replace(
+					*st.content, e.oldText, e.newText, n,
+				))
+				return
+			}
+		}
+		st.writeFragment(
+			diffLines(splitLines(e.oldText), splitLines(e.newText)),
+		)
+	case editPatch:
+		if e.created {
+			if st.file.Edits == 0 && st.content == nil {
+				st.created, st.whole = true, true
+				st.content = new(string)
+			}
+			st.setContent(e.newText)
+			return
+		}
+		if st.content != nil {
+			if next, ok := applyChunks(*st.content, e.chunks); ok {
+				st.setContent(next)
+				return
+			}
+			st.forget()
+		}
+		for _, ops := range e.chunks {
+			st.writeFragment(ops)
+		}
+	case editDelete:
+		if st.content != nil {
+			st.setContent("")
+		}
+		st.deleted = true
+	}
+}
+
+// setContent records a change to a file whose content is known.
+func (st *fileState) setContent(next string) {
+	if st.content == nil {
+		// Overwriting a file of unknown content.
+		st.content = new(string)
+	}
+	if !st.whole {
+		st.writeFragment(diffLines(
+			splitLines(*st.content), splitLines(next),
+		))
+	}
+	*st.content = next
+}
+
+// forget drops the known content after an edit that could not
+// be applied to it.
+func (st *fileState) forget() {
+	if st.whole {
+		st.writeFragment(diffLines(nil, splitLines(*st.content)))
+		st.whole = false
+	}
+	st.content = nil
+}
+
+func (st *fileState) writeFragment(ops []op) {
+	a, r := writeHunks(&st.diff, ops, 1, 1)
+	st.file.Added += a
+	st.file.Removed += r
+}
+
+func (st *fileState) finish() File {
+	f := st.file
+	if st.whole {
+		f.Added, f.Removed = 0, 0
+		st.diff.Reset()
+		if !st.deleted {
+			st.writeFragment(diffLines(nil, splitLines(*st.content)))
+			f.Added, f.Removed = st.file.Added, st.file.Removed
+		}
+	}
+
+	oldName, newName := diffName("a", f.Path), diffName("b", f.Path)
+	if f.OldPath != "" {
+		oldName = diffName("a", f.OldPath)
+	}
+	switch {
+	case st.created && st.deleted:
+		f.Status = StatusDeleted
+	case st.created:
+		f.Status = StatusAdded
+		oldName = "/dev/null"
+	case st.deleted:
+		f.Status = StatusDeleted
+		newName = "/dev/null"
+	case f.OldPath != "":
+		f.Status = StatusRenamed
+	default:
+		f.Status = StatusModified
+	}
+	if st.diff.Len() > 0 {
+		f.Diff = "--- " + oldName + "\n+++ " + newName + "\n" +
+			st.diff.String()
+	}
+	return f
+}
+
+// diffName prefixes path for a diff header as git does.
+func diffName(prefix, path string) string {
+	return prefix + "/" + strings.TrimPrefix(path, "/")
+}
+
+// parseCall normalizes an edit tool call into file edits. Other
+// tools yield none.
+func parseCall(toolName, inputJSON string) []edit {
+	in := gjson.Parse(inputJSON)
+	str := func(keys ...string) string {
+		for _, k := range keys {
+			if v := in.Get(k); v.Exists() {
+				return v.String()
+			}
+		}
+		return ""
+	}
+
+	switch toolName {
+	case "Edit", "edit":
+		path := str("file_path", "filePath")
+		if path == "" {
+			return nil
+		}
+		return []edit{{
+			kind:    editReplace,
+			path:    path,
+			oldText: str("old_string", "oldString"),
+			newText: str("new_string", "newString"),
+			replaceAll: in.Get("replace_all").Bool() ||
+				in.Get("replaceAll").Bool(),
+		}}
+	case "MultiEdit":
+		path := str("file_path")
+		if path == "" {
+			return nil
+		}
+		var edits []edit
+		for _, e := range in.Get("edits").Array() {
+			edits = append(edits, edit{
+				kind:       editReplace,
+				path:       path,
+				oldText:    e.Get("old_string").String(),
+				newText:    e.Get("new_string").String(),
+				replaceAll: e.Get("replace_all").Bool(),
+			})
+		}
+		return edits
+	case "Write", "write":
+		path := str("file_path", "filePath")
+		if path == "" {
+			return nil
+		}
+		return []edit{{
+			kind: editWrite, path: path, newText: str("content"),
+		}}
+	case "apply_patch":
+		patch := str("patch", "input")
+		if !in.IsObject() {
+			patch = inputJSON
+		}
+		return parsePatch(patch)
+	case "shell", "exec_command", "shell_command":
+		// Older Codex versions run apply_patch as a command.
+		cmd := in.Get("command").Array()
+		if len(cmd) == 2 && cmd[0].String() == "apply_patch" {
+			return parsePatch(cmd[1].String())
+		}
+	}
+	return nil
+}
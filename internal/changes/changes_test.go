@@ -0,0 +1,222 @@
+package changes
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func inputJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestUnifiedHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\nfifteen\n16\n"
+	var sb strings.Builder
+	added, removed := writeHunks(
+		&sb, diffLines(splitLines(a), splitLines(b)), 1, 1,
+	)
+	want := "@@ -1,6 +1,6 @@\n" +
+		" 1\n 2\n-3\n+three\n 4\n 5\n 6\n" +
+		"@@ -12,4 +12,5 @@\n" +
+		" 12\n 13\n 14\n-15\n+fifteen\n+16\n"
+	if sb.String() != want {
+		t.Errorf("hunks =\n%s\nwant\n%s", sb.String(), want)
+	}
+	if added != 3 || removed != 2 {
+		t.Errorf("added, removed = %d, %d; want 3, 2", added, removed)
+	}
+}
+
+func TestUnifiedHunksMerge(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\n"
+	b := "A\nb\nc\nd\ne\nf\nG\n"
+	var sb strings.Builder
+	writeHunks(&sb, diffLines(splitLines(a), splitLines(b)), 1, 1)
+	want := "@@ -1,7 +1,7 @@\n" +
+		"-a\n+A\n b\n c\n d\n e\n f\n-g\n+G\n"
+	if sb.String() != want {
+		t.Errorf("hunks =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestComputeClaudeEdits(t *testing.T) {
+	calls := []Call{
+		{Ordinal: 1, ToolName: "Edit", InputJSON: inputJSON(t, map[string]any{
+			"file_path":  "/repo/main.go",
+			"old_string": "func main() {\n}",
+			"new_string": "func main() {\n\trun()\n}",
+		})},
+		{Ordinal: 2, ToolName: "Write", InputJSON: inputJSON(t, map[string]any{
+			"file_path": "/repo/new.go",
+			"content":   "package main\n",
+		})},
+		{Ordinal: 3, ToolName: "MultiEdit", InputJSON: inputJSON(t, map[string]any{
+			"file_path": "/repo/new.go",
+			"edits": []map[string]any{
+				{"old_string": "main", "new_string": "app"},
+				{"old_string": "app\n", "new_string": "app\n\nvar x = 1\n"},
+			},
+		})},
+		{Ordinal: 4, ToolName: "Edit", Failed: true, InputJSON: inputJSON(t, map[string]any{
+			"file_path":  "/repo/other.go",
+			"old_string": "a",
+			"new_string": "b",
+		})},
+		{Ordinal: 5, ToolName: "Read", InputJSON: `{"file_path":"/repo/main.go"}`},
+	}
+
+	files := Compute(calls)
+	if len(files) != 2 {
+		t.Fatalf("files = %+v, want 2", files)
+	}
+
+	f := files[0]
+	if f.Path != "/repo/main.go" || f.Status != StatusModified ||
+		f.Added != 1 || f.Removed != 0 || f.Edits != 1 {
+		t.Errorf("main.go = %+v", f)
+	}
+	wantDiff := "--- a/repo/main.go\n+++ b/repo/main.go\n" +
+		"@@ -1,2 +1,3 @@\n func main() {\n+\trun()\n }\n"
+	if f.Diff != wantDiff {
+		t.Errorf("main.go diff =\n%s\nwant\n%s", f.Diff, wantDiff)
+	}
+
+	f = files[1]
+	if f.Status != StatusAdded || f.Added != 3 || f.Removed != 0 ||
+		f.Edits != 3 || len(f.Ordinals) != 2 {
+		t.Errorf("new.go = %+v", f)
+	}
+	wantDiff = "--- /dev/null\n+++ b/repo/new.go\n" +
+		"@@ -0,0 +1,3 @@\n+package app\n+\n+var x = 1\n"
+	if f.Diff != wantDiff {
+		t.Errorf("new.go diff =\n%s\nwant\n%s", f.Diff, wantDiff)
+	}
+}
+
+func TestComputeKnownContentLineNumbers(t *testing.T) {
+	var content strings.Builder
+	for i := range 20 {
+		content.WriteString(strings.Repeat("x", i) + "\n")
+	}
+	files := Compute([]Call{
+		{ToolName: "Write", InputJSON: inputJSON(t, map[string]any{
+			"file_path": "f.txt", "content": content.String(),
+		})},
+		{ToolName: "Edit", InputJSON: inputJSON(t, map[string]any{
+			"file_path": "f.txt", "old_string": "xxxxxxxxxx\n",
+			"new_string": "ten\n",
+		})},
+		// Not in the known content: the file changed outside
+		// the session, so the content is dropped.
+		{ToolName: "Edit", InputJSON: inputJSON(t, map[string]any{
+			"file_path": "f.txt", "old_string": "missing",
+			"new_string": "found",
+		})},
+	})
+	if len(files) != 1 {
+		t.Fatalf("files = %+v", files)
+	}
+	f := files[0]
+	if f.Status != StatusAdded || f.Added != 21 || f.Removed != 1 {
+		t.Errorf("file = %+v", f)
+	}
+	if !strings.Contains(f.Diff, "@@ -0,0 +1,20 @@\n") ||
+		!strings.Contains(f.Diff, "@@ -1 +1 @@\n-missing\n+found\n") {
+		t.Errorf("diff =\n%s", f.Diff)
+	}
+}
+
+func TestComputeApplyPatch(t *testing.T) {
+	patch := strings.Join([]string{
+		"*** Begin Patch",
+		"*** Add File: docs/new.md",
+		"+# Title",
+		"+",
+		"+Body",
+		"*** Update File: src/app.py",
+		"*** Move to: src/main.py",
+		"@@ def main():",
+		"     setup()",
+		"-    run()",
+		"+    run(fast=True)",
+		"+    report()",
+		"*** End of File",
+		"*** Delete File: old.txt",
+		"*** End Patch",
+	}, "\n")
+	files := Compute([]Call{{
+		Ordinal: 7, ToolName: "apply_patch",
+		InputJSON: inputJSON(t, map[string]any{"input": patch}),
+	}})
+	if len(files) != 3 {
+		t.Fatalf("files = %+v, want 3", files)
+	}
+	if f := files[0]; f.Path != "docs/new.md" ||
+		f.Status != StatusAdded || f.Added != 3 {
+		t.Errorf("added file = %+v", f)
+	}
+
+	f := files[1]
+	if f.Path != "src/main.py" || f.OldPath != "src/app.py" ||
+		f.Status != StatusRenamed || f.Added != 2 || f.Removed != 1 {
+		t.Errorf("renamed file = %+v", f)
+	}
+	wantDiff := "--- a/src/app.py\n+++ b/src/main.py\n" +
+		"@@ -1,2 +1,3 @@\n     setup()\n-    run()\n" +
+		"+    run(fast=True)\n+    report()\n"
+	if f.Diff != wantDiff {
+		t.Errorf("renamed diff =\n%s\nwant\n%s", f.Diff, wantDiff)
+	}
+
+	if f := files[2]; f.Path != "old.txt" ||
+		f.Status != StatusDeleted || f.Diff != "" {
+		t.Errorf("deleted file = %+v", f)
+	}
+
+	// Older Codex versions pass the patch as a command.
+	shell := Compute([]Call{{
+		ToolName: "shell",
+		InputJSON: inputJSON(t, map[string]any{
+			"command": []string{"apply_patch", patch},
+		}),
+	}})
+	if len(shell) != 3 {
+		t.Errorf("shell apply_patch files = %+v", shell)
+	}
+}
+
+func TestComputePatchOnWrittenFile(t *testing.T) {
+	files := Compute([]Call{
+		{ToolName: "apply_patch", InputJSON: "*** Begin Patch\n" +
+			"*** Add File: a.txt\n+one\n+two\n+three\n*** End Patch"},
+		{ToolName: "apply_patch", InputJSON: "*** Begin Patch\n" +
+			"*** Update File: a.txt\n@@\n one\n-two\n+2\n*** End Patch"},
+	})
+	if len(files) != 1 {
+		t.Fatalf("files = %+v", files)
+	}
+	want := "--- /dev/null\n+++ b/a.txt\n" +
+		"@@ -0,0 +1,3 @@\n+one\n+2\n+three\n"
+	if files[0].Diff != want || files[0].Status != StatusAdded {
+		t.Errorf("file = %+v, want diff\n%s", files[0], want)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	got := Paths("apply_patch", `{"patch":"*** Begin Patch\n`+
+		`*** Update File: a.go\n*** Move to: b.go\n@@\n-x\n+y\n`+
+		`*** End Patch"}`)
+	if strings.Join(got, ",") != "a.go,b.go" {
+		t.Errorf("Paths = %v", got)
+	}
+	if got := Paths("Bash", `{"command":"ls"}`); got != nil {
+		t.Errorf("Paths(Bash) = %v", got)
+	}
+}
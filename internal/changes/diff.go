@@ -0,0 +1,212 @@
+package changes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around
+// each change in a hunk.
+const contextLines = 3
+
+// maxDiffCells bounds the size of the table used to diff the
+// part of two texts that differs. Larger inputs are shown as a
+// whole-block replacement.
+const maxDiffCells = 4_000_000
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// splitLines splits text into lines without their terminators.
+// An empty text has no lines.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the edit script turning a into b.
+func diffLines(a, b []string) []op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		ops = append(ops, op{opEqual, l})
+	}
+	ops = append(ops, diffMiddle(
+		a[prefix:len(a)-suffix], b[prefix:len(b)-suffix],
+	)...)
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, op{opEqual, l})
+	}
+	return ops
+}
+
+// diffMiddle diffs a and b by longest common subsequence.
+func diffMiddle(a, b []string) []op {
+	var ops []op
+	if len(a)*len(b) == 0 || len(a)*len(b) > maxDiffCells {
+		for _, l := range a {
+			ops = append(ops, op{opDelete, l})
+		}
+		for _, l := range b {
+			ops = append(ops, op{opInsert, l})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	w := len(b) + 1
+	lcs := make([]int32, (len(a)+1)*w)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+			} else {
+				lcs[i*w+j] = max(lcs[(i+1)*w+j], lcs[i*w+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{opEqual, a[i]})
+			i++
+			j++
+		case lcs[(i+1)*w+j] >= lcs[i*w+j+1]:
+			ops = append(ops, op{opDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, op{opInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{opDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{opInsert, b[j]})
+	}
+	return ops
+}
+
+// hunk is a run of changes with their surrounding context.
+type hunk struct {
+	oldStart, newStart int // 1-based
+	ops                []op
+}
+
+// writeHunks writes the hunks of ops in unified diff format,
+// numbering lines from oldLine and newLine, and returns the
+// number of added and removed lines.
+func writeHunks(
+	sb *strings.Builder, ops []op, oldLine, newLine int,
+) (added, removed int) {
+	for _, h := range hunks(ops, oldLine, newLine) {
+		var oldN, newN int
+		for _, o := range h.ops {
+			if o.kind != opInsert {
+				oldN++
+			}
+			if o.kind != opDelete {
+				newN++
+			}
+		}
+		fmt.Fprintf(sb, "@@ -%s +%s @@\n",
+			hunkRange(h.oldStart, oldN), hunkRange(h.newStart, newN))
+		for _, o := range h.ops {
+			sb.WriteByte(byte(o.kind))
+			sb.WriteString(o.line)
+			sb.WriteByte('\n')
+			switch o.kind {
+			case opInsert:
+				added++
+			case opDelete:
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
+
+// hunkRange formats a hunk range as diff(1) does: an empty range
+// starts at the line before it.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		start--
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// hunks groups the changes in ops, keeping contextLines of
+// unchanged lines around each and merging groups whose context
+// would overlap.
+func hunks(ops []op, oldLine, newLine int) []hunk {
+	var out []hunk
+	var cur *hunk
+	lastChange := -1
+	oldAt, newAt := oldLine, newLine
+	for i, o := range ops {
+		if o.kind != opEqual {
+			if cur == nil || i-lastChange > 2*contextLines {
+				start := max(i-contextLines, lastChange+1, 0)
+				if cur != nil {
+					// Close the previous hunk with trailing
+					// context.
+					cur.ops = append(cur.ops,
+						ops[lastChange+1:lastChange+1+contextLines]...)
+					start = max(start, lastChange+1+contextLines)
+				}
+				out = append(out, hunk{
+					oldStart: oldAt - (i - start),
+					newStart: newAt - (i - start),
+				})
+				cur = &out[len(out)-1]
+				cur.ops = append(cur.ops, ops[start:i]...)
+			} else {
+				cur.ops = append(cur.ops, ops[lastChange+1:i]...)
+			}
+			cur.ops = append(cur.ops, o)
+			lastChange = i
+		}
+		if o.kind != opInsert {
+			oldAt++
+		}
+		if o.kind != opDelete {
+			newAt++
+		}
+	}
+	if cur != nil {
+		end := min(lastChange+1+contextLines, len(ops))
+		cur.ops = append(cur.ops, ops[lastChange+1:end]...)
+	}
+	return out
+}
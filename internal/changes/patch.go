@@ -0,0 +1,131 @@
+package changes
+
+import (
+	"slices"
+	"strings"
+)
+
+// Codex apply_patch file headers.
+const (
+	patchAdd    = "*** Add File: "
+	patchUpdate = "*** Update File: "
+	patchDelete = "*** Delete File: "
+	patchMove   = "*** Move to: "
+	patchEOF    = "*** End of File"
+)
+
+// parsePatch normalizes a Codex apply_patch body into edits.
+// Update hunks keep their lines as edit ops; added files carry
+// their content.
+func parsePatch(patch string) []edit {
+	var edits []edit
+	var cur *edit
+	var chunk []op
+	flushChunk := func() {
+		if cur != nil && len(chunk) > 0 {
+			cur.chunks = append(cur.chunks, chunk)
+		}
+		chunk = nil
+	}
+	flush := func() {
+		flushChunk()
+		if cur != nil {
+			edits = append(edits, *cur)
+		}
+		cur = nil
+	}
+	header := func(line, prefix string) string {
+		return strings.TrimSpace(strings.TrimPrefix(line, prefix))
+	}
+
+	patch = strings.TrimRight(patch, "\n")
+	for line := range strings.SplitSeq(patch, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, patchAdd):
+			flush()
+			cur = &edit{
+				kind: editPatch, created: true,
+				path: header(line, patchAdd),
+			}
+		case strings.HasPrefix(line, patchUpdate):
+			flush()
+			cur = &edit{kind: editPatch, path: header(line, patchUpdate)}
+		case strings.HasPrefix(line, patchDelete):
+			flush()
+			edits = append(edits, edit{
+				kind: editDelete, path: header(line, patchDelete),
+			})
+		case strings.HasPrefix(line, patchMove):
+			if cur != nil {
+				cur.moveTo = header(line, patchMove)
+			}
+		case line == patchEOF:
+		case strings.HasPrefix(line, "*** "):
+			// *** Begin Patch, *** End Patch
+			flush()
+		case cur == nil:
+		case cur.created:
+			if text, ok := strings.CutPrefix(line, "+"); ok {
+				cur.newText += text + "\n"
+			}
+		case strings.HasPrefix(line, "@@"):
+			flushChunk()
+		case line == "":
+			chunk = append(chunk, op{opEqual, ""})
+		default:
+			switch opKind(line[0]) {
+			case opEqual, opDelete, opInsert:
+				chunk = append(chunk, op{opKind(line[0]), line[1:]})
+			}
+		}
+	}
+	flush()
+
+	out := edits[:0]
+	for _, e := range edits {
+		if e.path != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// applyChunks applies patch chunks to content in order. It
+// reports false if a chunk's old lines are not found.
+func applyChunks(content string, chunks [][]op) (string, bool) {
+	lines := splitLines(content)
+	cursor := 0
+	for _, ops := range chunks {
+		var oldLines, newLines []string
+		for _, o := range ops {
+			if o.kind != opInsert {
+				oldLines = append(oldLines, o.line)
+			}
+			if o.kind != opDelete {
+				newLines = append(newLines, o.line)
+			}
+		}
+
+		at := -1
+		if len(oldLines) == 0 {
+			at = len(lines)
+		}
+		for i := cursor; at < 0 && i+len(oldLines) <= len(lines); i++ {
+			if slices.Equal(lines[i:i+len(oldLines)], oldLines) {
+				at = i
+			}
+		}
+		if at < 0 {
+			return "", false
+		}
+		lines = slices.Concat(
+			lines[:at], newLines, lines[at+len(oldLines):],
+		)
+		cursor = at + len(newLines)
+	}
+	if len(lines) == 0 {
+		return "", true
+	}
+	return strings.Join(lines, "\n") + "\n", true
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"time"
+)
+
+// Scopes a token or proxy user can be granted. Write covers
+// annotating sessions with tags, stars and notes. Admin implies
+// the others.
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeUpload = "upload"
+	ScopeAdmin  = "admin"
+)
+
+// tokenPrefix starts every generated API token, so they are
+// recognizable in configs and secret scans.
+const tokenPrefix = "avt_"
+
+// AuthConfig configures how API requests are authenticated.
+type AuthConfig struct {
+	// Required turns authentication on. It is set when the
+	// first token is created and stays set when tokens are
+	// revoked, so revoking the last one doesn't open the API.
+	Required bool `json:"required,omitempty"`
+
+	// Tokens are the static API tokens, accepted as bearer
+	// tokens or as the password of HTTP basic auth.
+	Tokens []AuthToken `json:"tokens,omitempty"`
+
+	// Proxy trusts a reverse proxy that authenticates users
+	// itself and names them in a request header.
+	Proxy *ProxyAuth `json:"proxy,omitempty"`
+}
+
+// Enabled reports whether requests must be authenticated.
+func (a AuthConfig) Enabled() bool {
+	return a.Required || len(a.Tokens) > 0 || a.Proxy != nil
+}
+
+// AuthToken is a static API token. Only the SHA-256 hash of
+// the token is stored.
+type AuthToken struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Hash      string   `json:"hash"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"created_at,omitempty"`
+}
+
+// ProxyAuth configures trust in a reverse proxy's user header.
+type ProxyAuth struct {
+	// Header names the user, e.g. "X-Forwarded-User".
+	Header string `json:"header"`
+	// Trusted lists the CIDRs requests must come from for the
+	// header to count. Defaults to loopback addresses. Requests
+	// over the Unix domain socket are always trusted.
+	Trusted []string `json:"trusted,omitempty"`
+	// Scopes are granted to every user the proxy names.
+	// Defaults to read.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// HashToken returns the stored form of an API token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// NewAuthToken generates an API token with the given name and
+// scopes. It returns the record to store and the token itself,
+// which is not kept anywhere else.
+func NewAuthToken(
+	name string, scopes []string,
+) (AuthToken, string, error) {
+	if err := validateScopes(scopes); err != nil {
+		return AuthToken{}, "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return AuthToken{}, "", fmt.Errorf("generating token: %w", err)
+	}
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return AuthToken{}, "", fmt.Errorf("generating token: %w", err)
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return AuthToken{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Hash:      HashToken(token),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}, token, nil
+}
+
+// validateScopes rejects empty or unknown scope lists.
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("no scopes given")
+	}
+	for _, s := range scopes {
+		switch s {
+		case ScopeRead, ScopeWrite, ScopeUpload, ScopeAdmin:
+		default:
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+	return nil
+}
+
+// validate checks the scopes of the tokens and proxy.
+func (a AuthConfig) validate() error {
+	for _, t := range a.Tokens {
+		if t.Hash == "" {
+			return fmt.Errorf("token %q has no hash", t.ID)
+		}
+		if err := validateScopes(t.Scopes); err != nil {
+			return fmt.Errorf("token %q: %w", t.ID, err)
+		}
+	}
+	if a.Proxy != nil {
+		if a.Proxy.Header == "" {
+			return fmt.Errorf("proxy header not set")
+		}
+		for _, cidr := range a.Proxy.Trusted {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("proxy: %w", err)
+			}
+		}
+		if len(a.Proxy.Scopes) > 0 {
+			if err := validateScopes(a.Proxy.Scopes); err != nil {
+				return fmt.Errorf("proxy: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// HasScope reports whether scopes grant scope. Admin grants
+// every scope.
+func HasScope(scopes []string, scope string) bool {
+	return slices.Contains(scopes, scope) ||
+		slices.Contains(scopes, ScopeAdmin)
+}
+
+// ReadAuthTokens reads the API tokens from the config file as it
+// is now, so a running server can pick up tokens created or
+// revoked since it started.
+func (c *Config) ReadAuthTokens() ([]AuthToken, error) {
+	data, err := os.ReadFile(c.ConfigPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var file struct {
+		Auth AuthConfig `json:"auth"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+	if err := file.Auth.validate(); err != nil {
+		return nil, fmt.Errorf("auth config: %w", err)
+	}
+	return file.Auth.Tokens, nil
+}
+
+// SaveAuthTokens persists the API tokens to the config file and
+// marks authentication as required, keeping the rest of the
+// file's auth settings.
+func (c *Config) SaveAuthTokens(tokens []AuthToken) error {
+	if err := os.MkdirAll(c.DataDir, 0o700); err != nil {
+		return fmt.Errorf("creating data dir: %w", err)
+	}
+
+	existing := make(map[string]any)
+	data, err := os.ReadFile(c.ConfigPath())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &existing); err != nil {
+			return fmt.Errorf(
+				"existing config is invalid, cannot update: %w",
+				err,
+			)
+		}
+	}
+
+	auth, _ := existing["auth"].(map[string]any)
+	if auth == nil {
+		auth = make(map[string]any)
+	}
+	if tokens == nil {
+		tokens = []AuthToken{}
+	}
+	auth["tokens"] = tokens
+	auth["required"] = true
+	existing["auth"] = auth
+
+	out, err := json.MarshalIndent(existing, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling config: %w", err)
+	}
+	if err := os.WriteFile(c.ConfigPath(), out, 0o600); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	c.Auth.Tokens = tokens
+	c.Auth.Required = true
+	return nil
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewAuthToken(t *testing.T) {
+	tok, secret, err := NewAuthToken("ci", []string{ScopeUpload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		t.Errorf("secret = %q, want %s prefix", secret, tokenPrefix)
+	}
+	if tok.Hash != HashToken(secret) || strings.Contains(tok.Hash, secret) {
+		t.Errorf("hash = %q", tok.Hash)
+	}
+	if tok.ID == "" || tok.Name != "ci" {
+		t.Errorf("token = %+v", tok)
+	}
+
+	if _, _, err := NewAuthToken("x", []string{"delete"}); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+	if _, _, err := NewAuthToken("x", nil); err == nil {
+		t.Error("expected error for no scopes")
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	if !HasScope([]string{ScopeRead}, ScopeRead) {
+		t.Error("read should grant read")
+	}
+	if HasScope([]string{ScopeRead}, ScopeUpload) {
+		t.Error("read should not grant upload")
+	}
+	if !HasScope([]string{ScopeAdmin}, ScopeUpload) {
+		t.Error("admin should grant upload")
+	}
+	if !HasScope([]string{ScopeAdmin}, ScopeWrite) {
+		t.Error("admin should grant write")
+	}
+}
+
+func TestSaveAuthTokens_RoundTrip(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"github_token": "gh",
+		"auth": map[string]any{
+			"proxy": map[string]any{"header": "X-Forwarded-User"},
+		},
+	})
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, _, err := NewAuthToken("laptop", []string{ScopeRead, ScopeUpload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SaveAuthTokens([]AuthToken{tok}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Auth.Required || len(got.Auth.Tokens) != 1 ||
+		got.Auth.Tokens[0].Hash != tok.Hash {
+		t.Errorf("auth = %+v", got.Auth)
+	}
+	if got.Auth.Proxy == nil || got.GithubToken != "gh" {
+		t.Errorf("other settings lost: %+v", got)
+	}
+
+	// Revoking every token keeps auth required.
+	if err := got.SaveAuthTokens(nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err = LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Auth.Enabled() || len(got.Auth.Tokens) != 0 {
+		t.Errorf("auth after revoke = %+v", got.Auth)
+	}
+}
+
+func TestLoadFile_ReadsCORSOrigins(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"cors_origins": []string{"https://dash.example.com"},
+	})
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.CORSOrigins) != 1 ||
+		cfg.CORSOrigins[0] != "https://dash.example.com" {
+		t.Errorf("CORSOrigins = %v", cfg.CORSOrigins)
+	}
+	if cfg.Auth.Enabled() {
+		t.Error("auth should be off by default")
+	}
+}
+
+func TestLoadFile_RejectsInvalidAuth(t *testing.T) {
+	tests := []struct {
+		name string
+		auth map[string]any
+		want string
+	}{
+		{
+			"UnknownScope",
+			map[string]any{"tokens": []map[string]any{
+				{"id": "a1", "hash": "sha256:00", "scopes": []string{"root"}},
+			}},
+			"unknown scope",
+		},
+		{
+			"MissingHash",
+			map[string]any{"tokens": []map[string]any{
+				{"id": "a1", "scopes": []string{"read"}},
+			}},
+			"no hash",
+		},
+		{
+			"BadCIDR",
+			map[string]any{"proxy": map[string]any{
+				"header": "X-Forwarded-User", "trusted": []string{"10.0.0.1"},
+			}},
+			"proxy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupTestEnv(t)
+			writeConfig(t, dir, map[string]any{"auth": tt.auth})
+			_, err := LoadMinimal()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"time"
+
+	"github.com/wesm/agentsview/internal/pricing"
+	"github.com/wesm/agentsview/internal/secrets"
 )
 
 // Config holds all application configuration.
@@ -22,12 +25,73 @@ type Config struct {
 	CopilotDir       string        `json:"copilot_dir"`
 	GeminiDir        string        `json:"gemini_dir"`
 	OpenCodeDir      string        `json:"opencode_dir"`
+	GooseDir         string        `json:"goose_dir"`
+	AmpDir           string        `json:"amp_dir"`
 	DataDir          string        `json:"data_dir"`
 	DBPath           string        `json:"-"`
 	CursorSecret     string        `json:"cursor_secret"`
 	GithubToken      string        `json:"github_token,omitempty"`
 	WriteTimeout     time.Duration `json:"-"`
 
+	// Archive enables archive mode: synced source files are
+	// copied into ArchiveDir so sessions survive the agents
+	// deleting their own transcripts.
+	Archive bool `json:"archive,omitempty"`
+
+	// CorrelateCommits links sessions to the commits of the
+	// local git repositories whose files they edited.
+	CorrelateCommits bool `json:"correlate_commits,omitempty"`
+
+	// AiderRepos lists the repositories whose Aider chat
+	// histories are synced. Aider keeps its history in the
+	// repository it runs in, so there is no default.
+	AiderRepos []string `json:"aider_repos,omitempty"`
+
+	// ClineDirs lists the VS Code global storage directories of
+	// Cline, Roo Code and similar extensions whose tasks are
+	// synced. When unset, the default storage directories of
+	// Cline and Roo Code are used if they exist.
+	ClineDirs []string `json:"cline_dirs,omitempty"`
+
+	// CursorDirs lists the Cursor user data directories (those
+	// holding globalStorage and workspaceStorage) whose chats are
+	// synced. When unset, Cursor's default directory is used if
+	// it exists.
+	CursorDirs []string `json:"cursor_dirs,omitempty"`
+
+	// Pricing overrides or extends the built-in model price
+	// table, keyed by model name.
+	Pricing map[string][]pricing.Price `json:"pricing,omitempty"`
+
+	// SecretPatterns adds detection rules to the built-in secret
+	// scanner used to redact exports.
+	SecretPatterns []secrets.Pattern `json:"secret_patterns,omitempty"`
+
+	// Socket is the path of a Unix domain socket to listen on
+	// instead of Host and Port. SocketMode holds the socket
+	// file's permissions in octal, 0600 by default.
+	Socket     string `json:"socket,omitempty"`
+	SocketMode string `json:"socket_mode,omitempty"`
+
+	// TLSCert and TLSKey are the PEM certificate and key files
+	// to serve HTTPS with. They are reloaded when they change
+	// on disk.
+	TLSCert string `json:"tls_cert,omitempty"`
+	TLSKey  string `json:"tls_key,omitempty"`
+
+	// TLSSelfSigned serves HTTPS with a self-signed certificate
+	// generated in TLSDir when no certificate is configured.
+	TLSSelfSigned bool `json:"tls_self_signed,omitempty"`
+
+	// Auth configures authentication of API requests. Requests
+	// are not authenticated unless it is enabled.
+	Auth AuthConfig `json:"auth,omitempty"`
+
+	// CORSOrigins lists the origins other sites may call the API
+	// from. Cross-origin requests from any other origin get no
+	// CORS headers.
+	CORSOrigins []string `json:"cors_origins,omitempty"`
+
 	// Multi-directory support (from config.json).
 	// When set, these take precedence over the single-dir
 	// fields above. Env vars override these with a
@@ -37,6 +101,8 @@ type Config struct {
 	CopilotDirs       []string `json:"copilot_dirs,omitempty"`
 	GeminiDirs        []string `json:"gemini_dirs,omitempty"`
 	OpenCodeDirs      []string `json:"opencode_dirs,omitempty"`
+	GooseDirs         []string `json:"goose_dirs,omitempty"`
+	AmpDirs           []string `json:"amp_dirs,omitempty"`
 }
 
 // Default returns a Config with default values.
@@ -56,6 +122,8 @@ func Default() (Config, error) {
 		CopilotDir:       filepath.Join(home, ".copilot"),
 		GeminiDir:        filepath.Join(home, ".gemini"),
 		OpenCodeDir:      filepath.Join(home, ".local", "share", "opencode"),
+		GooseDir:         filepath.Join(home, ".local", "share", "goose"),
+		AmpDir:           filepath.Join(home, ".local", "share", "amp"),
 		DataDir:          dataDir,
 		DBPath:           filepath.Join(dataDir, "sessions.db"),
 		WriteTimeout:     30 * time.Second,
@@ -71,6 +139,9 @@ func Load(fs *flag.FlagSet) (Config, error) {
 		return cfg, err
 	}
 	applyFlags(&cfg, fs)
+	if err := cfg.validateListener(); err != nil {
+		return cfg, err
+	}
 	return cfg, nil
 }
 
@@ -94,12 +165,13 @@ func LoadMinimal() (Config, error) {
 	return cfg, nil
 }
 
-func (c *Config) configPath() string {
+// ConfigPath returns the path of the config file.
+func (c *Config) ConfigPath() string {
 	return filepath.Join(c.DataDir, "config.json")
 }
 
 func (c *Config) loadFile() error {
-	data, err := os.ReadFile(c.configPath())
+	data, err := os.ReadFile(c.ConfigPath())
 	if os.IsNotExist(err) {
 		return nil
 	}
@@ -115,6 +187,24 @@ func (c *Config) loadFile() error {
 		CopilotDirs       []string `json:"copilot_dirs"`
 		GeminiDirs        []string `json:"gemini_dirs"`
 		OpenCodeDirs      []string `json:"opencode_dirs"`
+		GooseDirs         []string `json:"goose_dirs"`
+		AmpDirs           []string `json:"amp_dirs"`
+		Archive           bool     `json:"archive"`
+		CorrelateCommits  bool     `json:"correlate_commits"`
+		AiderRepos        []string `json:"aider_repos"`
+		ClineDirs         []string `json:"cline_dirs"`
+		CursorDirs        []string `json:"cursor_dirs"`
+
+		Pricing        map[string][]pricing.Price `json:"pricing"`
+		SecretPatterns []secrets.Pattern          `json:"secret_patterns"`
+		Auth           AuthConfig                 `json:"auth"`
+		CORSOrigins    []string                   `json:"cors_origins"`
+
+		Socket        string `json:"socket"`
+		SocketMode    string `json:"socket_mode"`
+		TLSCert       string `json:"tls_cert"`
+		TLSKey        string `json:"tls_key"`
+		TLSSelfSigned bool   `json:"tls_self_signed"`
 	}
 	if err := json.Unmarshal(data, &file); err != nil {
 		return fmt.Errorf("parsing config: %w", err)
@@ -125,6 +215,55 @@ func (c *Config) loadFile() error {
 	if file.CursorSecret != "" {
 		c.CursorSecret = file.CursorSecret
 	}
+	if file.Archive {
+		c.Archive = true
+	}
+	if file.CorrelateCommits {
+		c.CorrelateCommits = true
+	}
+	if len(file.AiderRepos) > 0 {
+		c.AiderRepos = file.AiderRepos
+	}
+	if len(file.ClineDirs) > 0 {
+		c.ClineDirs = file.ClineDirs
+	}
+	if len(file.CursorDirs) > 0 {
+		c.CursorDirs = file.CursorDirs
+	}
+	if len(file.Pricing) > 0 {
+		if _, err := pricing.Default().With(file.Pricing); err != nil {
+			return fmt.Errorf("invalid pricing: %w", err)
+		}
+		c.Pricing = file.Pricing
+	}
+	if len(file.SecretPatterns) > 0 {
+		if _, err := secrets.Default().With(file.SecretPatterns); err != nil {
+			return fmt.Errorf("invalid secret_patterns: %w", err)
+		}
+		c.SecretPatterns = file.SecretPatterns
+	}
+	if err := file.Auth.validate(); err != nil {
+		return fmt.Errorf("invalid auth: %w", err)
+	}
+	c.Auth = file.Auth
+	if len(file.CORSOrigins) > 0 {
+		c.CORSOrigins = file.CORSOrigins
+	}
+	if file.Socket != "" {
+		c.Socket = file.Socket
+	}
+	if file.SocketMode != "" {
+		c.SocketMode = file.SocketMode
+	}
+	if file.TLSCert != "" {
+		c.TLSCert = file.TLSCert
+	}
+	if file.TLSKey != "" {
+		c.TLSKey = file.TLSKey
+	}
+	if file.TLSSelfSigned {
+		c.TLSSelfSigned = true
+	}
 	// Only apply config-file arrays when not already set by
 	// env var. loadEnv runs before loadFile, so a non-nil
 	// slice here means the env var won.
@@ -143,6 +282,12 @@ func (c *Config) loadFile() error {
 	if len(file.OpenCodeDirs) > 0 && c.OpenCodeDirs == nil {
 		c.OpenCodeDirs = file.OpenCodeDirs
 	}
+	if len(file.GooseDirs) > 0 && c.GooseDirs == nil {
+		c.GooseDirs = file.GooseDirs
+	}
+	if len(file.AmpDirs) > 0 && c.AmpDirs == nil {
+		c.AmpDirs = file.AmpDirs
+	}
 	return nil
 }
 
@@ -163,7 +308,7 @@ func (c *Config) ensureCursorSecret() error {
 	}
 
 	existing := make(map[string]any)
-	data, err := os.ReadFile(c.configPath())
+	data, err := os.ReadFile(c.ConfigPath())
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("reading config: %w", err)
 	}
@@ -179,7 +324,7 @@ func (c *Config) ensureCursorSecret() error {
 		return fmt.Errorf("marshaling config: %w", err)
 	}
 
-	if err := os.WriteFile(c.configPath(), out, 0o600); err != nil {
+	if err := os.WriteFile(c.ConfigPath(), out, 0o600); err != nil {
 		return fmt.Errorf("writing config: %w", err)
 	}
 	return nil
@@ -206,11 +351,77 @@ func (c *Config) loadEnv() {
 		c.OpenCodeDir = v
 		c.OpenCodeDirs = []string{v}
 	}
+	if v := os.Getenv("GOOSE_DIR"); v != "" {
+		c.GooseDir = v
+		c.GooseDirs = []string{v}
+	}
+	if v := os.Getenv("AMP_DIR"); v != "" {
+		c.AmpDir = v
+		c.AmpDirs = []string{v}
+	}
 	if v := os.Getenv("AGENT_VIEWER_DATA_DIR"); v != "" {
 		c.DataDir = v
 	}
 }
 
+// ArchiveDir returns the directory holding archived session
+// source files.
+func (c *Config) ArchiveDir() string {
+	return filepath.Join(c.DataDir, "archive")
+}
+
+// OTelDir returns the directory holding sessions imported from
+// OpenTelemetry traces.
+func (c *Config) OTelDir() string {
+	return filepath.Join(c.DataDir, "otel")
+}
+
+// TLSDir returns the directory the self-signed certificate is
+// kept in.
+func (c *Config) TLSDir() string {
+	return filepath.Join(c.DataDir, "tls")
+}
+
+// TLSEnabled reports whether the server serves HTTPS.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCert != "" || c.TLSSelfSigned
+}
+
+// SocketPerm returns the permissions of the Unix domain socket.
+func (c *Config) SocketPerm() (os.FileMode, error) {
+	if c.SocketMode == "" {
+		return 0o600, nil
+	}
+	mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid socket mode %q", c.SocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// validateListener checks the socket and TLS settings.
+func (c *Config) validateListener() error {
+	if _, err := c.SocketPerm(); err != nil {
+		return err
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("tls_cert and tls_key must be set together")
+	}
+	return nil
+}
+
+// PriceTable returns the built-in price table with the config
+// file's pricing overrides applied.
+func (c *Config) PriceTable() (*pricing.Table, error) {
+	return pricing.Default().With(c.Pricing)
+}
+
+// SecretScanner returns the built-in secret scanner with the
+// config file's secret_patterns added.
+func (c *Config) SecretScanner() (*secrets.Scanner, error) {
+	return secrets.Default().With(c.SecretPatterns)
+}
+
 // ResolveClaudeDirs returns the effective list of Claude
 // project directories. Precedence: env var (single) >
 // config file array > default (single).
@@ -234,6 +445,61 @@ func (c *Config) ResolveOpenCodeDirs() []string {
 	return c.resolveDirs(c.OpenCodeDirs, c.OpenCodeDir)
 }
 
+func (c *Config) ResolveGooseDirs() []string {
+	return c.resolveDirs(c.GooseDirs, c.GooseDir)
+}
+
+func (c *Config) ResolveAmpDirs() []string {
+	return c.resolveDirs(c.AmpDirs, c.AmpDir)
+}
+
+// clineExtensions are the VS Code extension IDs of Cline and Roo
+// Code, which name their global storage directories.
+var clineExtensions = []string{
+	"saoudrizwan.claude-dev",
+	"rooveterinaryinc.roo-cline",
+}
+
+// ResolveClineDirs returns the Cline and Roo Code storage
+// directories to sync: the config file's cline_dirs, or else
+// the extensions' default VS Code storage directories that
+// exist.
+func (c *Config) ResolveClineDirs() []string {
+	if len(c.ClineDirs) > 0 {
+		return c.ClineDirs
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, ext := range clineExtensions {
+		d := filepath.Join(base, "Code", "User", "globalStorage", ext)
+		if _, err := os.Stat(d); err == nil {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// ResolveCursorDirs returns the Cursor user data directories to
+// sync: the config file's cursor_dirs, or else Cursor's default
+// user data directory if it exists.
+func (c *Config) ResolveCursorDirs() []string {
+	if len(c.CursorDirs) > 0 {
+		return c.CursorDirs
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	d := filepath.Join(base, "Cursor", "User")
+	if _, err := os.Stat(d); err != nil {
+		return nil
+	}
+	return []string{d}
+}
+
 func (c *Config) resolveDirs(multi []string, single string) []string {
 	if len(multi) > 0 {
 		return multi
@@ -253,6 +519,16 @@ func RegisterServeFlags(fs *flag.FlagSet) {
 		"no-browser", false,
 		"Don't open browser on startup",
 	)
+	fs.String("socket", "",
+		"Listen on this Unix domain socket instead of host and port")
+	fs.String("socket-mode", "0600",
+		"Permissions of the Unix domain socket, in octal")
+	fs.String("tls-cert", "", "Certificate file to serve HTTPS with")
+	fs.String("tls-key", "", "Key file to serve HTTPS with")
+	fs.Bool(
+		"tls-self-signed", false,
+		"Serve HTTPS with a generated self-signed certificate",
+	)
 }
 
 // applyFlags copies explicitly-set flags from fs into cfg.
@@ -269,6 +545,16 @@ func applyFlags(cfg *Config, fs *flag.FlagSet) {
 			cfg.Port, _ = strconv.Atoi(f.Value.String())
 		case "no-browser":
 			cfg.NoBrowser = f.Value.String() == "true"
+		case "socket":
+			cfg.Socket = f.Value.String()
+		case "socket-mode":
+			cfg.SocketMode = f.Value.String()
+		case "tls-cert":
+			cfg.TLSCert = f.Value.String()
+		case "tls-key":
+			cfg.TLSKey = f.Value.String()
+		case "tls-self-signed":
+			cfg.TLSSelfSigned = f.Value.String() == "true"
 		}
 	})
 }
@@ -295,7 +581,7 @@ func (c *Config) SaveGithubToken(token string) error {
 	}
 
 	existing := make(map[string]any)
-	data, err := os.ReadFile(c.configPath())
+	data, err := os.ReadFile(c.ConfigPath())
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("reading config file: %w", err)
 	}
@@ -314,7 +600,7 @@ func (c *Config) SaveGithubToken(token string) error {
 		return fmt.Errorf("marshaling config: %w", err)
 	}
 
-	if err := os.WriteFile(c.configPath(), out, 0o600); err != nil {
+	if err := os.WriteFile(c.ConfigPath(), out, 0o600); err != nil {
 		return fmt.Errorf("writing config: %w", err)
 	}
 	c.GithubToken = token
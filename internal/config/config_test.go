@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 const configFileName = "config.json"
@@ -104,6 +105,36 @@ func TestLoad_DefaultsWithoutFlags(t *testing.T) {
 	}
 }
 
+func TestLoad_ListenerFlags(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"socket":          "/run/agentsview.sock",
+		"tls_self_signed": true,
+	})
+
+	cfg, err := loadConfigFromFlags(t, "-socket-mode", "0660")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Socket != "/run/agentsview.sock" || !cfg.TLSEnabled() {
+		t.Errorf("Socket = %q, TLSEnabled = %v",
+			cfg.Socket, cfg.TLSEnabled())
+	}
+	if perm, _ := cfg.SocketPerm(); perm != 0o660 {
+		t.Errorf("SocketPerm = %o, want 660", perm)
+	}
+	if got := cfg.TLSDir(); got != filepath.Join(dir, "tls") {
+		t.Errorf("TLSDir = %q", got)
+	}
+
+	if _, err := loadConfigFromFlags(t, "-socket-mode", "rw"); err == nil {
+		t.Error("expected error for invalid socket mode")
+	}
+	if _, err := loadConfigFromFlags(t, "-tls-cert", "c.pem"); err == nil {
+		t.Error("expected error for cert without key")
+	}
+}
+
 func TestLoad_NilFlagSet(t *testing.T) {
 	cfg, err := Load(nil)
 	if err != nil {
@@ -194,6 +225,8 @@ func TestLoadFile_ReadsDirArrays(t *testing.T) {
 	writeConfig(t, dir, map[string]any{
 		"claude_project_dirs": []string{"/path/one", "/path/two"},
 		"codex_sessions_dirs": []string{"/codex/a"},
+		"goose_dirs":          []string{"/goose/a"},
+		"amp_dirs":            []string{"/amp/a", "/amp/b"},
 	})
 
 	cfg, err := LoadMinimal()
@@ -210,6 +243,168 @@ func TestLoadFile_ReadsDirArrays(t *testing.T) {
 	if len(cfg.CodexSessionsDirs) != 1 || cfg.CodexSessionsDirs[0] != "/codex/a" {
 		t.Errorf("CodexSessionsDirs = %v", cfg.CodexSessionsDirs)
 	}
+	if got := cfg.ResolveGooseDirs(); len(got) != 1 || got[0] != "/goose/a" {
+		t.Errorf("ResolveGooseDirs = %v", got)
+	}
+	if got := cfg.ResolveAmpDirs(); len(got) != 2 || got[1] != "/amp/b" {
+		t.Errorf("ResolveAmpDirs = %v", got)
+	}
+}
+
+func TestLoadFile_ReadsArchive(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{"archive": true})
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Archive {
+		t.Error("Archive = false, want true")
+	}
+	if want := filepath.Join(dir, "archive"); cfg.ArchiveDir() != want {
+		t.Errorf("ArchiveDir = %q, want %q", cfg.ArchiveDir(), want)
+	}
+}
+
+func TestLoadFile_ReadsCorrelateCommits(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{"correlate_commits": true})
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.CorrelateCommits {
+		t.Error("CorrelateCommits = false, want true")
+	}
+}
+
+func TestLoadFile_ReadsAiderRepos(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"aider_repos": []string{"/src/app", "/src/lib"},
+	})
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.AiderRepos) != 2 || cfg.AiderRepos[1] != "/src/lib" {
+		t.Errorf("AiderRepos = %v", cfg.AiderRepos)
+	}
+}
+
+func TestLoadFile_ReadsClineDirs(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"cline_dirs": []string{"/storage/cline"},
+	})
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.ResolveClineDirs()
+	if len(got) != 1 || got[0] != "/storage/cline" {
+		t.Errorf("ResolveClineDirs = %v", got)
+	}
+}
+
+func TestLoadFile_ReadsCursorDirs(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"cursor_dirs": []string{"/cursor/User"},
+	})
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.ResolveCursorDirs()
+	if len(got) != 1 || got[0] != "/cursor/User" {
+		t.Errorf("ResolveCursorDirs = %v", got)
+	}
+}
+
+func TestLoadFile_ReadsPricing(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"pricing": map[string]any{
+			"claude-sonnet-4-5": []map[string]any{
+				{"input": 2, "output": 10},
+			},
+		},
+	})
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := cfg.PriceTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := table.Lookup("claude-sonnet-4-5-20250929", time.Time{})
+	if !ok || p.Input != 2 || p.Output != 10 {
+		t.Errorf("override price = %+v, %v; want input 2, output 10",
+			p, ok)
+	}
+	if _, ok := table.Lookup("claude-opus-4-1", time.Time{}); !ok {
+		t.Error("built-in price missing after override")
+	}
+}
+
+func TestLoadFile_RejectsInvalidPricing(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"pricing": map[string]any{
+			"my-model": []map[string]any{
+				{"since": "last week", "input": 1},
+			},
+		},
+	})
+
+	_, err := LoadMinimal()
+	if err == nil || !strings.Contains(err.Error(), "my-model") {
+		t.Fatalf("err = %v, want invalid pricing error", err)
+	}
+}
+
+func TestLoadFile_ReadsSecretPatterns(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"secret_patterns": []map[string]any{
+			{"name": "internal_token", "regex": `itk_[a-z0-9]{12}`},
+		},
+	})
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err := cfg.SecretScanner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := sc.Redact("token itk_abcdef123456")
+	if got != "token [REDACTED:internal_token]" {
+		t.Errorf("Redact = %q", got)
+	}
+}
+
+func TestLoadFile_RejectsInvalidSecretPatterns(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"secret_patterns": []map[string]any{
+			{"name": "broken", "regex": "("},
+		},
+	})
+
+	_, err := LoadMinimal()
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("err = %v, want invalid secret pattern error", err)
+	}
 }
 
 func TestResolveDirs(t *testing.T) {
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"math"
 	"sort"
@@ -47,6 +48,8 @@ type AnalyticsFilter struct {
 	Machine         string // optional machine filter
 	Project         string // optional project filter
 	Agent           string // optional agent filter
+	Branch          string // optional git branch filter
+	Repo            string // optional repository root or name
 	Timezone        string // IANA timezone for day bucketing
 	DayOfWeek       *int   // nil = all, 0=Mon, 6=Sun (ISO)
 	Hour            *int   // nil = all, 0-23
@@ -117,6 +120,17 @@ func (f AnalyticsFilter) buildWhere(
 		args = append(args, f.Agent)
 	}
 
+	if f.Branch != "" {
+		preds = append(preds, "git_branch = ?")
+		args = append(args, f.Branch)
+	}
+
+	if f.Repo != "" {
+		pred, repoArgs := repoPredicate(f.Repo)
+		preds = append(preds, pred)
+		args = append(args, repoArgs...)
+	}
+
 	if f.MinUserMessages > 0 {
 		preds = append(preds, "user_message_count >= ?")
 		args = append(args, f.MinUserMessages)
@@ -439,6 +453,11 @@ type ActivityEntry struct {
 	ToolCalls         int            `json:"tool_calls"`
 	ThinkingMessages  int            `json:"thinking_messages"`
 	ByAgent           map[string]int `json:"by_agent"`
+	// Token usage recorded on the bucket's messages.
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
 }
 
 // ActivityResponse wraps the activity series.
@@ -492,7 +511,11 @@ func (db *DB) GetAnalyticsActivity(
 	}
 
 	query := `SELECT ` + dateCol + `, s.agent, s.id,
-		m.role, m.has_thinking, COUNT(*)
+		m.role, m.has_thinking, COUNT(*),
+		COALESCE(SUM(m.input_tokens), 0),
+		COALESCE(SUM(m.output_tokens), 0),
+		COALESCE(SUM(m.cache_creation_input_tokens), 0),
+		COALESCE(SUM(m.cache_read_input_tokens), 0)
 		FROM sessions s
 		LEFT JOIN messages m ON m.session_id = s.id
 		WHERE ` + where + `
@@ -514,9 +537,11 @@ func (db *DB) GetAnalyticsActivity(
 		var role *string
 		var hasThinking *bool
 		var count int
+		var input, output, cacheCreation, cacheRead int64
 		if err := rows.Scan(
 			&ts, &agent, &sid, &role,
 			&hasThinking, &count,
+			&input, &output, &cacheCreation, &cacheRead,
 		); err != nil {
 			return ActivityResponse{},
 				fmt.Errorf("scanning activity row: %w", err)
@@ -559,6 +584,10 @@ func (db *DB) GetAnalyticsActivity(
 			if hasThinking != nil && *hasThinking {
 				entry.ThinkingMessages += count
 			}
+			entry.InputTokens += input
+			entry.OutputTokens += output
+			entry.CacheCreationInputTokens += cacheCreation
+			entry.CacheReadInputTokens += cacheRead
 		}
 	}
 	if err := rows.Err(); err != nil {
@@ -1291,13 +1320,28 @@ type ToolTrendEntry struct {
 	ByCat map[string]int `json:"by_category"`
 }
 
+// ToolErrorRate holds failure counts for one tool category,
+// tool or project. Calls counts only calls with a recorded
+// result, so agents that do not report one do not dilute the
+// rate.
+type ToolErrorRate struct {
+	Name   string  `json:"name"`
+	Calls  int     `json:"calls"`
+	Errors int     `json:"errors"`
+	Rate   float64 `json:"rate"`
+}
+
 // ToolsAnalyticsResponse wraps tool usage analytics.
 type ToolsAnalyticsResponse struct {
-	TotalCalls     int                  `json:"total_calls"`
-	UnblockedCalls int                  `json:"unblocked_calls"`
-	ByCategory     []ToolCategoryCount  `json:"by_category"`
-	ByAgent        []ToolAgentBreakdown `json:"by_agent"`
-	Trend          []ToolTrendEntry     `json:"trend"`
+	TotalCalls       int                  `json:"total_calls"`
+	UnblockedCalls   int                  `json:"unblocked_calls"`
+	ErrorCalls       int                  `json:"error_calls"`
+	ByCategory       []ToolCategoryCount  `json:"by_category"`
+	ByAgent          []ToolAgentBreakdown `json:"by_agent"`
+	Trend            []ToolTrendEntry     `json:"trend"`
+	ErrorsByCategory []ToolErrorRate      `json:"errors_by_category"`
+	ErrorsByTool     []ToolErrorRate      `json:"errors_by_tool"`
+	ErrorsByProject  []ToolErrorRate      `json:"errors_by_project"`
 }
 
 // GetAnalyticsTools returns tool usage analytics aggregated
@@ -1319,7 +1363,7 @@ func (db *DB) GetAnalyticsTools(
 	}
 
 	// Fetch filtered session IDs and their metadata.
-	sessQ := `SELECT id, ` + dateCol + `, agent
+	sessQ := `SELECT id, ` + dateCol + `, agent, project
 		FROM sessions WHERE ` + where
 
 	sessRows, err := db.reader.QueryContext(ctx, sessQ, args...)
@@ -1330,15 +1374,18 @@ func (db *DB) GetAnalyticsTools(
 	defer sessRows.Close()
 
 	type sessInfo struct {
-		date  string
-		agent string
+		date    string
+		agent   string
+		project string
 	}
 	sessionMap := make(map[string]sessInfo)
 	var sessionIDs []string
 
 	for sessRows.Next() {
-		var id, ts, agent string
-		if err := sessRows.Scan(&id, &ts, &agent); err != nil {
+		var id, ts, agent, project string
+		if err := sessRows.Scan(
+			&id, &ts, &agent, &project,
+		); err != nil {
 			return ToolsAnalyticsResponse{},
 				fmt.Errorf("scanning tool session: %w", err)
 		}
@@ -1349,7 +1396,9 @@ func (db *DB) GetAnalyticsTools(
 		if timeIDs != nil && !timeIDs[id] {
 			continue
 		}
-		sessionMap[id] = sessInfo{date: date, agent: agent}
+		sessionMap[id] = sessInfo{
+			date: date, agent: agent, project: project,
+		}
 		sessionIDs = append(sessionIDs, id)
 	}
 	if err := sessRows.Err(); err != nil {
@@ -1358,9 +1407,12 @@ func (db *DB) GetAnalyticsTools(
 	}
 
 	resp := ToolsAnalyticsResponse{
-		ByCategory: []ToolCategoryCount{},
-		ByAgent:    []ToolAgentBreakdown{},
-		Trend:      []ToolTrendEntry{},
+		ByCategory:       []ToolCategoryCount{},
+		ByAgent:          []ToolAgentBreakdown{},
+		Trend:            []ToolTrendEntry{},
+		ErrorsByCategory: []ToolErrorRate{},
+		ErrorsByTool:     []ToolErrorRate{},
+		ErrorsByProject:  []ToolErrorRate{},
 	}
 
 	if len(sessionIDs) == 0 {
@@ -1372,13 +1424,15 @@ func (db *DB) GetAnalyticsTools(
 		sessionID string
 		category  string
 		toolName  string
+		isError   sql.NullBool
 	}
 	var toolRows []toolRow
 
 	err = queryChunked(sessionIDs,
 		func(chunk []string) error {
 			ph, chunkArgs := inPlaceholders(chunk)
-			q := `SELECT session_id, category, tool_name
+			q := `SELECT session_id, category, tool_name,
+					is_error
 				FROM tool_calls
 				WHERE session_id IN ` + ph
 			rows, qErr := db.reader.QueryContext(
@@ -1392,8 +1446,9 @@ func (db *DB) GetAnalyticsTools(
 			defer rows.Close()
 			for rows.Next() {
 				var sid, cat, name string
+				var isError sql.NullBool
 				if err := rows.Scan(
-					&sid, &cat, &name,
+					&sid, &cat, &name, &isError,
 				); err != nil {
 					return fmt.Errorf(
 						"scanning tool_call: %w", err,
@@ -1403,6 +1458,7 @@ func (db *DB) GetAnalyticsTools(
 					sessionID: sid,
 					category:  cat,
 					toolName:  name,
+					isError:   isError,
 				})
 			}
 			return rows.Err()
@@ -1420,6 +1476,9 @@ func (db *DB) GetAnalyticsTools(
 	agentCats := make(map[string]map[string]int)    // agent → cat → count
 	trendBuckets := make(map[string]map[string]int) // week → cat → count
 	unblockedCalls := 0
+	catErrors := make(map[string]*ToolErrorRate)
+	toolErrors := make(map[string]*ToolErrorRate)
+	projectErrors := make(map[string]*ToolErrorRate)
 
 	for _, tr := range toolRows {
 		info := sessionMap[tr.sessionID]
@@ -1439,10 +1498,23 @@ func (db *DB) GetAnalyticsTools(
 		if strings.HasPrefix(tr.toolName, "mcp__unblocked") {
 			unblockedCalls++
 		}
+
+		if tr.isError.Valid {
+			failed := tr.isError.Bool
+			if failed {
+				resp.ErrorCalls++
+			}
+			countToolError(catErrors, tr.category, failed)
+			countToolError(toolErrors, tr.toolName, failed)
+			countToolError(projectErrors, info.project, failed)
+		}
 	}
 
 	resp.TotalCalls = len(toolRows)
 	resp.UnblockedCalls = unblockedCalls
+	resp.ErrorsByCategory = sortedToolErrorRates(catErrors)
+	resp.ErrorsByTool = sortedToolErrorRates(toolErrors)
+	resp.ErrorsByProject = sortedToolErrorRates(projectErrors)
 
 	// Build ByCategory sorted by count desc.
 	resp.ByCategory = make(
@@ -1520,6 +1592,44 @@ func (db *DB) GetAnalyticsTools(
 	return resp, nil
 }
 
+func countToolError(
+	m map[string]*ToolErrorRate, name string, failed bool,
+) {
+	e := m[name]
+	if e == nil {
+		e = &ToolErrorRate{Name: name}
+		m[name] = e
+	}
+	e.Calls++
+	if failed {
+		e.Errors++
+	}
+}
+
+// sortedToolErrorRates computes rates (percent, one decimal)
+// and orders entries by error count, then rate, then name.
+func sortedToolErrorRates(
+	m map[string]*ToolErrorRate,
+) []ToolErrorRate {
+	out := make([]ToolErrorRate, 0, len(m))
+	for _, e := range m {
+		e.Rate = math.Round(
+			float64(e.Errors)/float64(e.Calls)*1000,
+		) / 10
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Errors != out[j].Errors {
+			return out[i].Errors > out[j].Errors
+		}
+		if out[i].Rate != out[j].Rate {
+			return out[i].Rate > out[j].Rate
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
 // --- Velocity ---
 
 // velocityMsg holds per-message data needed for velocity
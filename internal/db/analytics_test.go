@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -1419,13 +1420,13 @@ func TestGetAnalyticsTools(t *testing.T) {
 	m1 := asstMsg("t1", 0, "[Read: a.go]")
 	m1.HasToolUse = true
 	m1.ToolCalls = []ToolCall{
-		{SessionID: "t1", ToolName: "Read", Category: "Read"},
-		{SessionID: "t1", ToolName: "Read", Category: "Read"},
+		{SessionID: "t1", ToolName: "Read", Category: "Read", IsError: Ptr(false)},
+		{SessionID: "t1", ToolName: "Read", Category: "Read", IsError: Ptr(false)},
 	}
 	m2 := asstMsg("t1", 1, "[Bash: ls]")
 	m2.HasToolUse = true
 	m2.ToolCalls = []ToolCall{
-		{SessionID: "t1", ToolName: "Bash", Category: "Bash"},
+		{SessionID: "t1", ToolName: "Bash", Category: "Bash", IsError: Ptr(true), ExitCode: Ptr(1)},
 	}
 	m3 := asstMsg("t1", 2, "[Edit: b.go]")
 	m3.HasToolUse = true
@@ -1442,8 +1443,8 @@ func TestGetAnalyticsTools(t *testing.T) {
 	m4 := asstMsg("t2", 0, "[Read: c.go]")
 	m4.HasToolUse = true
 	m4.ToolCalls = []ToolCall{
-		{SessionID: "t2", ToolName: "Read", Category: "Read"},
-		{SessionID: "t2", ToolName: "Grep", Category: "Grep"},
+		{SessionID: "t2", ToolName: "Read", Category: "Read", IsError: Ptr(false)},
+		{SessionID: "t2", ToolName: "Grep", Category: "Grep", IsError: Ptr(true)},
 	}
 	insertMessages(t, d, m4)
 
@@ -1549,6 +1550,38 @@ func TestGetAnalyticsTools(t *testing.T) {
 		}
 	})
 
+	t.Run("ErrorRates", func(t *testing.T) {
+		resp, err := d.GetAnalyticsTools(ctx, baseFilter())
+		if err != nil {
+			t.Fatalf("GetAnalyticsTools: %v", err)
+		}
+		if resp.ErrorCalls != 2 {
+			t.Errorf("ErrorCalls = %d, want 2", resp.ErrorCalls)
+		}
+		// Edit has no recorded result and is left out.
+		wantCats := []ToolErrorRate{
+			{Name: "Bash", Calls: 1, Errors: 1, Rate: 100},
+			{Name: "Grep", Calls: 1, Errors: 1, Rate: 100},
+			{Name: "Read", Calls: 3, Errors: 0, Rate: 0},
+		}
+		if !reflect.DeepEqual(resp.ErrorsByCategory, wantCats) {
+			t.Errorf("ErrorsByCategory = %+v, want %+v",
+				resp.ErrorsByCategory, wantCats)
+		}
+		if len(resp.ErrorsByTool) != 3 {
+			t.Errorf("ErrorsByTool = %+v, want 3 tools",
+				resp.ErrorsByTool)
+		}
+		wantProjects := []ToolErrorRate{
+			{Name: "beta", Calls: 2, Errors: 1, Rate: 50},
+			{Name: "alpha", Calls: 3, Errors: 1, Rate: 33.3},
+		}
+		if !reflect.DeepEqual(resp.ErrorsByProject, wantProjects) {
+			t.Errorf("ErrorsByProject = %+v, want %+v",
+				resp.ErrorsByProject, wantProjects)
+		}
+	})
+
 	t.Run("ProjectFilter", func(t *testing.T) {
 		f := baseFilter()
 		f.Project = "alpha"
@@ -1621,6 +1654,38 @@ func TestActivityToolAndThinkingCounts(t *testing.T) {
 	}
 }
 
+func TestActivityTokenCounts(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"tk1", "tk2"} {
+		insertSession(t, d, id, "proj", func(s *Session) {
+			s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+			s.MessageCount = 2
+		})
+		a := asstMsg(id, 1, "done")
+		a.Model = "claude-sonnet-4"
+		a.InputTokens = 10
+		a.OutputTokens = 20
+		a.CacheCreationInputTokens = 30
+		a.CacheReadInputTokens = 40
+		insertMessages(t, d, userMsg(id, 0, "go"), a)
+	}
+
+	resp := mustActivity(t, d, ctx, baseFilter(), "day")
+	if len(resp.Series) != 1 {
+		t.Fatalf("len(Series) = %d, want 1", len(resp.Series))
+	}
+	e := resp.Series[0]
+	if e.InputTokens != 20 || e.OutputTokens != 40 ||
+		e.CacheCreationInputTokens != 60 ||
+		e.CacheReadInputTokens != 80 {
+		t.Errorf("token sums = %d/%d/%d/%d, want 20/40/60/80",
+			e.InputTokens, e.OutputTokens,
+			e.CacheCreationInputTokens, e.CacheReadInputTokens)
+	}
+}
+
 func TestGetAnalyticsTopSessions(t *testing.T) {
 	d := testDB(t)
 	ctx := context.Background()
@@ -0,0 +1,516 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTag is returned for tags that are empty, too long or
+// contain whitespace.
+var ErrInvalidTag = errors.New("invalid tag")
+
+const maxTagLength = 64
+
+// Tag is a user-assigned label on a session or, when Ordinal is
+// set, on one of its messages.
+type Tag struct {
+	SessionID string `json:"session_id"`
+	Ordinal   *int   `json:"ordinal,omitempty"`
+	Tag       string `json:"tag"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Star marks a session or, when Ordinal is set, one of its
+// messages.
+type Star struct {
+	SessionID string `json:"session_id"`
+	Ordinal   *int   `json:"ordinal,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Note is a free-text note on a session or, when Ordinal is set,
+// on one of its messages.
+type Note struct {
+	ID        int64  `json:"id"`
+	SessionID string `json:"session_id"`
+	Ordinal   *int   `json:"ordinal,omitempty"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// NormalizeTag trims and lowercases a tag, returning
+// ErrInvalidTag if the result is not a usable tag.
+func NormalizeTag(tag string) (string, error) {
+	tag = tagKey(tag)
+	if tag == "" || len(tag) > maxTagLength ||
+		strings.ContainsAny(tag, " \t\r\n") {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// tagKey is the stored form of a tag.
+func tagKey(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// querier is satisfied by *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(
+		ctx context.Context, query string, args ...any,
+	) (*sql.Rows, error)
+}
+
+func ordinalPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+const tagCols = "session_id, ordinal, tag, created_at"
+
+func queryTags(
+	ctx context.Context, q querier, where string, args ...any,
+) ([]Tag, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT "+tagCols+" FROM session_tags WHERE "+where+
+			" ORDER BY session_id, ifnull(ordinal, -1), tag",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var t Tag
+		var ord sql.NullInt64
+		if err := rows.Scan(
+			&t.SessionID, &ord, &t.Tag, &t.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning tag: %w", err)
+		}
+		t.Ordinal = ordinalPtr(ord)
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+const starCols = "session_id, ordinal, created_at"
+
+func queryStars(
+	ctx context.Context, q querier, where string, args ...any,
+) ([]Star, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT "+starCols+" FROM session_stars WHERE "+where+
+			" ORDER BY session_id, ifnull(ordinal, -1)",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying stars: %w", err)
+	}
+	defer rows.Close()
+
+	stars := []Star{}
+	for rows.Next() {
+		var s Star
+		var ord sql.NullInt64
+		if err := rows.Scan(
+			&s.SessionID, &ord, &s.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning star: %w", err)
+		}
+		s.Ordinal = ordinalPtr(ord)
+		stars = append(stars, s)
+	}
+	return stars, rows.Err()
+}
+
+const noteCols = "id, session_id, ordinal, body, created_at, updated_at"
+
+func queryNotes(
+	ctx context.Context, q querier, where string, args ...any,
+) ([]Note, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT "+noteCols+" FROM session_notes WHERE "+where+
+			" ORDER BY session_id, ifnull(ordinal, -1), id",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []Note{}
+	for rows.Next() {
+		var n Note
+		var ord sql.NullInt64
+		if err := rows.Scan(
+			&n.ID, &n.SessionID, &ord, &n.Body,
+			&n.CreatedAt, &n.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning note: %w", err)
+		}
+		n.Ordinal = ordinalPtr(ord)
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// ListTags returns the tags on a session and its messages,
+// session tags first.
+func (db *DB) ListTags(
+	ctx context.Context, sessionID string,
+) ([]Tag, error) {
+	return queryTags(ctx, db.reader, "session_id = ?", sessionID)
+}
+
+// AddTag tags a session, or the message at ordinal when it is
+// non-nil. Adding an existing tag is a no-op that returns it.
+func (db *DB) AddTag(
+	sessionID string, ordinal *int, tag string,
+) (Tag, error) {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return Tag{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, err := db.writer.Exec(`
+		INSERT OR IGNORE INTO session_tags (session_id, ordinal, tag)
+		VALUES (?, ?, ?)`,
+		sessionID, ordinal, tag,
+	); err != nil {
+		return Tag{}, fmt.Errorf("adding tag: %w", err)
+	}
+	tags, err := queryTags(context.Background(), db.writer,
+		"session_id = ? AND ordinal IS ? AND tag = ?",
+		sessionID, ordinal, tag,
+	)
+	if err != nil {
+		return Tag{}, err
+	}
+	if len(tags) == 0 {
+		return Tag{}, fmt.Errorf("adding tag: row not found")
+	}
+	return tags[0], nil
+}
+
+// RemoveTag removes a tag from a session, or from the message at
+// ordinal when it is non-nil. It reports whether the tag existed.
+func (db *DB) RemoveTag(
+	sessionID string, ordinal *int, tag string,
+) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	res, err := db.writer.Exec(`
+		DELETE FROM session_tags
+		WHERE session_id = ? AND ordinal IS ? AND tag = ?`,
+		sessionID, ordinal, tagKey(tag),
+	)
+	if err != nil {
+		return false, fmt.Errorf("removing tag: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListStars returns the stars on a session and its messages.
+func (db *DB) ListStars(
+	ctx context.Context, sessionID string,
+) ([]Star, error) {
+	return queryStars(ctx, db.reader, "session_id = ?", sessionID)
+}
+
+// AddStar stars a session, or the message at ordinal when it is
+// non-nil. Starring twice is a no-op that returns the star.
+func (db *DB) AddStar(sessionID string, ordinal *int) (Star, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, err := db.writer.Exec(`
+		INSERT OR IGNORE INTO session_stars (session_id, ordinal)
+		VALUES (?, ?)`,
+		sessionID, ordinal,
+	); err != nil {
+		return Star{}, fmt.Errorf("adding star: %w", err)
+	}
+	stars, err := queryStars(context.Background(), db.writer,
+		"session_id = ? AND ordinal IS ?", sessionID, ordinal,
+	)
+	if err != nil {
+		return Star{}, err
+	}
+	if len(stars) == 0 {
+		return Star{}, fmt.Errorf("adding star: row not found")
+	}
+	return stars[0], nil
+}
+
+// RemoveStar unstars a session, or the message at ordinal when
+// it is non-nil. It reports whether the star existed.
+func (db *DB) RemoveStar(
+	sessionID string, ordinal *int,
+) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	res, err := db.writer.Exec(`
+		DELETE FROM session_stars
+		WHERE session_id = ? AND ordinal IS ?`,
+		sessionID, ordinal,
+	)
+	if err != nil {
+		return false, fmt.Errorf("removing star: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListNotes returns the notes on a session and its messages,
+// session notes first, each group oldest first.
+func (db *DB) ListNotes(
+	ctx context.Context, sessionID string,
+) ([]Note, error) {
+	return queryNotes(ctx, db.reader, "session_id = ?", sessionID)
+}
+
+// AddNote adds a note to a session, or to the message at ordinal
+// when it is non-nil.
+func (db *DB) AddNote(
+	sessionID string, ordinal *int, body string,
+) (Note, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	res, err := db.writer.Exec(`
+		INSERT INTO session_notes (session_id, ordinal, body)
+		VALUES (?, ?, ?)`,
+		sessionID, ordinal, body,
+	)
+	if err != nil {
+		return Note{}, fmt.Errorf("adding note: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Note{}, fmt.Errorf("adding note: %w", err)
+	}
+	notes, err := queryNotes(
+		context.Background(), db.writer, "id = ?", id,
+	)
+	if err != nil {
+		return Note{}, err
+	}
+	if len(notes) == 0 {
+		return Note{}, fmt.Errorf("adding note: row not found")
+	}
+	return notes[0], nil
+}
+
+// UpdateNote replaces the body of a session's note. Returns nil,
+// nil if the session has no note with that ID.
+func (db *DB) UpdateNote(
+	sessionID string, id int64, body string,
+) (*Note, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	res, err := db.writer.Exec(`
+		UPDATE session_notes
+		SET body = ?,
+			updated_at = strftime('%Y-%m-%dT%H:%M:%fZ','now')
+		WHERE id = ? AND session_id = ?`,
+		body, id, sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("updating note %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+	notes, err := queryNotes(
+		context.Background(), db.writer, "id = ?", id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return nil, nil
+	}
+	return &notes[0], nil
+}
+
+// DeleteNote removes a session's note. It reports whether the
+// note existed.
+func (db *DB) DeleteNote(sessionID string, id int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	res, err := db.writer.Exec(
+		"DELETE FROM session_notes WHERE id = ? AND session_id = ?",
+		id, sessionID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("deleting note %d: %w", id, err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// setAnnotations fills in the session-level tags and star for
+// each session.
+func (db *DB) setAnnotations(
+	ctx context.Context, sessions []Session,
+) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	idx := make(map[string]int, len(sessions))
+	args := make([]any, len(sessions))
+	for i, s := range sessions {
+		idx[s.ID] = i
+		args[i] = s.ID
+	}
+	in := "session_id IN (" +
+		strings.Repeat(",?", len(sessions))[1:] +
+		") AND ordinal IS NULL"
+
+	tags, err := queryTags(ctx, db.reader, in, args...)
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		s := &sessions[idx[t.SessionID]]
+		s.Tags = append(s.Tags, t.Tag)
+	}
+
+	stars, err := queryStars(ctx, db.reader, in, args...)
+	if err != nil {
+		return err
+	}
+	for _, st := range stars {
+		sessions[idx[st.SessionID]].Starred = true
+	}
+	return nil
+}
+
+// deleteAnnotations removes all annotations for the given
+// sessions. placeholders is a "?,?,..." list matching ids.
+func deleteAnnotations(
+	tx *sql.Tx, placeholders string, ids []any,
+) error {
+	for _, table := range []string{
+		"session_tags", "session_stars", "session_notes",
+	} {
+		if _, err := tx.Exec(
+			"DELETE FROM "+table+
+				" WHERE session_id IN ("+placeholders+")",
+			ids...,
+		); err != nil {
+			return fmt.Errorf("deleting %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// annotations is the contents of the annotation tables, carried
+// across a schema rebuild.
+type annotations struct {
+	tags  []Tag
+	stars []Star
+	notes []Note
+}
+
+// readAnnotations reads the annotation tables from the database
+// at path. Tables that predate the schema are skipped.
+func readAnnotations(path string) (annotations, error) {
+	var a annotations
+	conn, err := sql.Open("sqlite3", makeDSN(path, true))
+	if err != nil {
+		return a, err
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	exists := func(table string) (bool, error) {
+		var n int
+		err := conn.QueryRow(
+			`SELECT count(*) FROM sqlite_master
+			 WHERE type = 'table' AND name = ?`, table,
+		).Scan(&n)
+		return n > 0, err
+	}
+
+	if ok, err := exists("session_tags"); err != nil {
+		return a, err
+	} else if ok {
+		if a.tags, err = queryTags(ctx, conn, "1=1"); err != nil {
+			return a, err
+		}
+	}
+	if ok, err := exists("session_stars"); err != nil {
+		return a, err
+	} else if ok {
+		if a.stars, err = queryStars(ctx, conn, "1=1"); err != nil {
+			return a, err
+		}
+	}
+	if ok, err := exists("session_notes"); err != nil {
+		return a, err
+	} else if ok {
+		if a.notes, err = queryNotes(ctx, conn, "1=1"); err != nil {
+			return a, err
+		}
+	}
+	return a, nil
+}
+
+// restoreAnnotations writes annotations saved by readAnnotations
+// into a freshly created database.
+func (db *DB) restoreAnnotations(a annotations) error {
+	return db.Update(func(tx *sql.Tx) error {
+		for _, t := range a.tags {
+			if _, err := tx.Exec(`
+				INSERT OR IGNORE INTO session_tags
+					(session_id, ordinal, tag, created_at)
+				VALUES (?, ?, ?, ?)`,
+				t.SessionID, t.Ordinal, t.Tag, t.CreatedAt,
+			); err != nil {
+				return fmt.Errorf("restoring tag: %w", err)
+			}
+		}
+		for _, s := range a.stars {
+			if _, err := tx.Exec(`
+				INSERT OR IGNORE INTO session_stars
+					(session_id, ordinal, created_at)
+				VALUES (?, ?, ?)`,
+				s.SessionID, s.Ordinal, s.CreatedAt,
+			); err != nil {
+				return fmt.Errorf("restoring star: %w", err)
+			}
+		}
+		for _, n := range a.notes {
+			if _, err := tx.Exec(`
+				INSERT INTO session_notes
+					(id, session_id, ordinal, body,
+					 created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?)`,
+				n.ID, n.SessionID, n.Ordinal, n.Body,
+				n.CreatedAt, n.UpdatedAt,
+			); err != nil {
+				return fmt.Errorf("restoring note: %w", err)
+			}
+		}
+		return nil
+	})
+}
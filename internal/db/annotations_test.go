@@ -0,0 +1,278 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+
+	tag, err := d.AddTag("s1", nil, "  Keeper ")
+	requireNoError(t, err, "AddTag")
+	if tag.Tag != "keeper" || tag.Ordinal != nil || tag.CreatedAt == "" {
+		t.Errorf("AddTag = %+v", tag)
+	}
+	_, err = d.AddTag("s1", nil, "keeper")
+	requireNoError(t, err, "AddTag duplicate")
+	_, err = d.AddTag("s1", Ptr(3), "keeper")
+	requireNoError(t, err, "AddTag message")
+	_, err = d.AddTag("s1", Ptr(3), "bug")
+	requireNoError(t, err, "AddTag message")
+
+	for _, bad := range []string{"", "  ", "two words"} {
+		if _, err := d.AddTag("s1", nil, bad); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("AddTag(%q) err = %v, want ErrInvalidTag", bad, err)
+		}
+	}
+
+	tags, err := d.ListTags(ctx, "s1")
+	requireNoError(t, err, "ListTags")
+	if len(tags) != 3 {
+		t.Fatalf("ListTags = %+v, want 3 tags", tags)
+	}
+	if tags[0].Ordinal != nil || tags[1].Tag != "bug" ||
+		*tags[1].Ordinal != 3 {
+		t.Errorf("ListTags order = %+v", tags)
+	}
+
+	removed, err := d.RemoveTag("s1", nil, "KEEPER")
+	requireNoError(t, err, "RemoveTag")
+	if !removed {
+		t.Error("RemoveTag = false, want true")
+	}
+	removed, err = d.RemoveTag("s1", nil, "keeper")
+	requireNoError(t, err, "RemoveTag again")
+	if removed {
+		t.Error("second RemoveTag = true, want false")
+	}
+	tags, err = d.ListTags(ctx, "s1")
+	requireNoError(t, err, "ListTags")
+	if len(tags) != 2 {
+		t.Errorf("message tags removed with session tag: %+v", tags)
+	}
+}
+
+func TestStars(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+
+	_, err := d.AddStar("s1", nil)
+	requireNoError(t, err, "AddStar")
+	_, err = d.AddStar("s1", nil)
+	requireNoError(t, err, "AddStar duplicate")
+	_, err = d.AddStar("s1", Ptr(0))
+	requireNoError(t, err, "AddStar message")
+
+	stars, err := d.ListStars(ctx, "s1")
+	requireNoError(t, err, "ListStars")
+	if len(stars) != 2 || stars[0].Ordinal != nil ||
+		stars[1].Ordinal == nil || *stars[1].Ordinal != 0 {
+		t.Fatalf("ListStars = %+v", stars)
+	}
+
+	removed, err := d.RemoveStar("s1", Ptr(0))
+	requireNoError(t, err, "RemoveStar")
+	if !removed {
+		t.Error("RemoveStar = false, want true")
+	}
+	stars, err = d.ListStars(ctx, "s1")
+	requireNoError(t, err, "ListStars")
+	if len(stars) != 1 || stars[0].Ordinal != nil {
+		t.Errorf("ListStars after remove = %+v", stars)
+	}
+}
+
+func TestNotes(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+	insertSession(t, d, "s2", "proj")
+
+	n1, err := d.AddNote("s1", Ptr(2), "check this diff")
+	requireNoError(t, err, "AddNote")
+	if n1.ID == 0 || n1.Body != "check this diff" ||
+		n1.Ordinal == nil || *n1.Ordinal != 2 {
+		t.Errorf("AddNote = %+v", n1)
+	}
+	_, err = d.AddNote("s1", nil, "session summary")
+	requireNoError(t, err, "AddNote")
+
+	notes, err := d.ListNotes(ctx, "s1")
+	requireNoError(t, err, "ListNotes")
+	if len(notes) != 2 || notes[0].Body != "session summary" {
+		t.Fatalf("ListNotes = %+v", notes)
+	}
+
+	updated, err := d.UpdateNote("s1", n1.ID, "edited")
+	requireNoError(t, err, "UpdateNote")
+	if updated == nil || updated.Body != "edited" {
+		t.Errorf("UpdateNote = %+v", updated)
+	}
+	wrong, err := d.UpdateNote("s2", n1.ID, "hijack")
+	requireNoError(t, err, "UpdateNote other session")
+	if wrong != nil {
+		t.Errorf("UpdateNote via other session = %+v, want nil", wrong)
+	}
+
+	removed, err := d.DeleteNote("s2", n1.ID)
+	requireNoError(t, err, "DeleteNote other session")
+	if removed {
+		t.Error("DeleteNote via other session = true")
+	}
+	removed, err = d.DeleteNote("s1", n1.ID)
+	requireNoError(t, err, "DeleteNote")
+	if !removed {
+		t.Error("DeleteNote = false, want true")
+	}
+}
+
+func TestAnnotationFilters(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	requireFTS(t, d)
+	for _, id := range []string{"a", "b"} {
+		insertSession(t, d, id, "proj")
+		insertMessages(t, d,
+			userMsg(id, 0, "deploy the service"),
+			asstMsg(id, 1, "deploy finished"),
+		)
+	}
+
+	_, err := d.AddTag("a", nil, "release")
+	requireNoError(t, err, "AddTag")
+	_, err = d.AddTag("b", Ptr(1), "release")
+	requireNoError(t, err, "AddTag")
+	_, err = d.AddStar("b", nil)
+	requireNoError(t, err, "AddStar")
+
+	page, err := d.ListSessions(ctx, SessionFilter{Tag: "Release"})
+	requireNoError(t, err, "ListSessions tag")
+	if len(page.Sessions) != 1 || page.Sessions[0].ID != "a" ||
+		page.Total != 1 {
+		t.Errorf("tag filter = %v, want [a]", collectIDs(page.Sessions))
+	}
+	if got := page.Sessions[0].Tags; len(got) != 1 || got[0] != "release" {
+		t.Errorf("listed Tags = %v, want [release]", got)
+	}
+
+	page, err = d.ListSessions(ctx, SessionFilter{Starred: true})
+	requireNoError(t, err, "ListSessions starred")
+	if len(page.Sessions) != 1 || page.Sessions[0].ID != "b" ||
+		!page.Sessions[0].Starred {
+		t.Errorf("starred filter = %+v, want [b]", page.Sessions)
+	}
+
+	s, err := d.GetSession(ctx, "b")
+	requireNoError(t, err, "GetSession")
+	if !s.Starred || len(s.Tags) != 0 {
+		t.Errorf("GetSession annotations = %v %v", s.Starred, s.Tags)
+	}
+
+	res, err := d.Search(ctx, SearchFilter{
+		Query: "deploy", Tag: "release", Limit: 10,
+	})
+	requireNoError(t, err, "Search tag")
+	// Both messages of "a" via the session tag, and message 1
+	// of "b" via its own tag.
+	if len(res.Results) != 3 {
+		t.Errorf("search tag results = %+v, want 3", res.Results)
+	}
+	for _, r := range res.Results {
+		if r.SessionID == "b" && r.Ordinal != 1 {
+			t.Errorf("untagged message matched: %+v", r)
+		}
+	}
+
+	res, err = d.Search(ctx, SearchFilter{
+		Query: "deploy", Starred: true, Limit: 10,
+	})
+	requireNoError(t, err, "Search starred")
+	if len(res.Results) != 2 {
+		t.Errorf("search starred results = %+v, want 2", res.Results)
+	}
+}
+
+func TestDeleteSessionsRemovesAnnotations(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+	_, err := d.AddTag("s1", nil, "gone")
+	requireNoError(t, err, "AddTag")
+	_, err = d.AddNote("s1", nil, "gone")
+	requireNoError(t, err, "AddNote")
+
+	_, err = d.DeleteSessions([]string{"s1"})
+	requireNoError(t, err, "DeleteSessions")
+
+	tags, err := d.ListTags(ctx, "s1")
+	requireNoError(t, err, "ListTags")
+	notes, err := d.ListNotes(ctx, "s1")
+	requireNoError(t, err, "ListNotes")
+	if len(tags) != 0 || len(notes) != 0 {
+		t.Errorf("annotations left behind: %+v %+v", tags, notes)
+	}
+}
+
+func TestAnnotationsSurviveRebuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rebuild.db")
+	d, err := Open(path)
+	requireNoError(t, err, "Open")
+	insertSession(t, d, "s1", "proj")
+	insertMessages(t, d, userMsg("s1", 0, "hello"))
+
+	_, err = d.AddTag("s1", nil, "keeper")
+	requireNoError(t, err, "AddTag")
+	_, err = d.AddStar("s1", Ptr(0))
+	requireNoError(t, err, "AddStar")
+	note, err := d.AddNote("s1", Ptr(0), "remember")
+	requireNoError(t, err, "AddNote")
+
+	// Force needsRebuild with an outdated schema version.
+	if _, err := d.writer.Exec(
+		"UPDATE stats SET value = 1 WHERE key = 'schema_version'",
+	); err != nil {
+		t.Fatal(err)
+	}
+	requireNoError(t, d.Close(), "Close")
+
+	d, err = Open(path)
+	requireNoError(t, err, "reopen")
+	defer d.Close()
+	ctx := context.Background()
+
+	if s, err := d.GetSession(ctx, "s1"); err != nil || s != nil {
+		t.Fatalf("session survived rebuild: %v %v", s, err)
+	}
+	tags, err := d.ListTags(ctx, "s1")
+	requireNoError(t, err, "ListTags")
+	if len(tags) != 1 || tags[0].Tag != "keeper" {
+		t.Errorf("tags after rebuild = %+v", tags)
+	}
+	stars, err := d.ListStars(ctx, "s1")
+	requireNoError(t, err, "ListStars")
+	if len(stars) != 1 || stars[0].Ordinal == nil {
+		t.Errorf("stars after rebuild = %+v", stars)
+	}
+	notes, err := d.ListNotes(ctx, "s1")
+	requireNoError(t, err, "ListNotes")
+	if len(notes) != 1 || notes[0].ID != note.ID ||
+		notes[0].Body != note.Body ||
+		notes[0].CreatedAt != note.CreatedAt ||
+		*notes[0].Ordinal != 0 {
+		t.Errorf("notes after rebuild = %+v, want %+v", notes, note)
+	}
+
+	// Re-synced sessions pick their annotations back up.
+	insertSession(t, d, "s1", "proj")
+	s, err := d.GetSession(ctx, "s1")
+	requireNoError(t, err, "GetSession")
+	if len(s.Tags) != 1 || s.Tags[0] != "keeper" {
+		t.Errorf("re-synced session Tags = %v", s.Tags)
+	}
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// --- Branches ---
+
+// BranchAnalytics holds analytics for the sessions on one git
+// branch of a repository.
+type BranchAnalytics struct {
+	Project      string         `json:"project"`
+	RepoRoot     string         `json:"repo_root"`
+	Branch       string         `json:"branch"`
+	Sessions     int            `json:"sessions"`
+	Messages     int            `json:"messages"`
+	UserMessages int            `json:"user_messages"`
+	InputTokens  int64          `json:"input_tokens"`
+	OutputTokens int64          `json:"output_tokens"`
+	CostUSD      float64        `json:"cost_usd"`
+	FirstSession string         `json:"first_session"`
+	LastSession  string         `json:"last_session"`
+	Agents       map[string]int `json:"agents"`
+}
+
+// BranchesAnalyticsResponse wraps the branches list.
+type BranchesAnalyticsResponse struct {
+	Branches []BranchAnalytics `json:"branches"`
+}
+
+// GetAnalyticsBranches returns per-branch analytics for the
+// sessions that recorded a git branch, most messages first.
+// Branches are kept apart per repository, or per project for
+// sessions whose repository root is unknown.
+func (db *DB) GetAnalyticsBranches(
+	ctx context.Context, f AnalyticsFilter,
+) (BranchesAnalyticsResponse, error) {
+	loc := f.location()
+	dateCol := "COALESCE(started_at, created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return BranchesAnalyticsResponse{}, err
+		}
+	}
+
+	query := `SELECT id, project, repo_root, git_branch, agent,
+		` + dateCol + `, message_count, user_message_count,
+		input_tokens, output_tokens, token_usage_by_model
+		FROM sessions WHERE ` + where + ` AND git_branch <> ''`
+
+	rows, err := db.reader.QueryContext(ctx, query, args...)
+	if err != nil {
+		return BranchesAnalyticsResponse{},
+			fmt.Errorf("querying analytics branches: %w", err)
+	}
+	defer rows.Close()
+
+	type branchKey struct{ project, repoRoot, branch string }
+	table := db.priceTable()
+	branches := make(map[branchKey]*BranchAnalytics)
+
+	for rows.Next() {
+		var (
+			id, project, repoRoot, branch, agent, ts string
+			messages, userMessages                   int
+			input, output                            int64
+			byModel                                  RawJSON
+		)
+		if err := rows.Scan(
+			&id, &project, &repoRoot, &branch, &agent, &ts,
+			&messages, &userMessages, &input, &output, &byModel,
+		); err != nil {
+			return BranchesAnalyticsResponse{},
+				fmt.Errorf("scanning branch row: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+
+		key := branchKey{repoRoot: repoRoot, branch: branch}
+		if repoRoot == "" {
+			key.project = project
+		}
+		b, ok := branches[key]
+		if !ok {
+			b = &BranchAnalytics{
+				Project:  project,
+				RepoRoot: repoRoot,
+				Branch:   branch,
+				Agents:   make(map[string]int),
+			}
+			branches[key] = b
+		}
+		b.Sessions++
+		b.Messages += messages
+		b.UserMessages += userMessages
+		b.InputTokens += input
+		b.OutputTokens += output
+		if e := estimateCost(table, byModel, ts); e.Priced() {
+			b.CostUSD += e.Total
+		}
+		b.Agents[agent]++
+		if b.FirstSession == "" || date < b.FirstSession {
+			b.FirstSession = date
+		}
+		if date > b.LastSession {
+			b.LastSession = date
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return BranchesAnalyticsResponse{},
+			fmt.Errorf("iterating branch rows: %w", err)
+	}
+
+	out := make([]BranchAnalytics, 0, len(branches))
+	for _, b := range branches {
+		b.CostUSD = roundUSD(b.CostUSD)
+		out = append(out, *b)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Messages != out[j].Messages {
+			return out[i].Messages > out[j].Messages
+		}
+		if out[i].Project != out[j].Project {
+			return out[i].Project < out[j].Project
+		}
+		return out[i].Branch < out[j].Branch
+	})
+	return BranchesAnalyticsResponse{Branches: out}, nil
+}
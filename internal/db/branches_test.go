@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+// seedBranches inserts sessions on two repositories sharing a
+// branch name, plus one session without a branch.
+func seedBranches(t *testing.T, d *DB) {
+	t.Helper()
+	type entry struct {
+		id, project, repo, branch, started string
+		msgs                               int
+	}
+	for _, e := range []entry{
+		{"s1", "app", "/src/app", "main", "2024-06-01T09:00:00Z", 10},
+		{"s2", "app", "/src/app", "feature", "2024-06-01T12:00:00Z", 4},
+		{"s3", "app", "/src/app", "feature", "2024-06-02T10:00:00Z", 6},
+		{"s4", "lib", "/work/lib", "main", "2024-06-02T11:00:00Z", 3},
+		{"s5", "app", "/src/app", "", "2024-06-02T12:00:00Z", 20},
+	} {
+		insertSession(t, d, e.id, e.project, func(s *Session) {
+			s.RepoRoot = e.repo
+			s.GitBranch = e.branch
+			s.StartedAt = Ptr(e.started)
+			s.MessageCount = e.msgs
+		})
+	}
+}
+
+func TestListSessionsBranchAndRepoFilter(t *testing.T) {
+	d := testDB(t)
+	seedBranches(t, d)
+
+	requireCount(t, d, filterWith(func(f *SessionFilter) {
+		f.Branch = "main"
+	}), 2)
+	requireCount(t, d, filterWith(func(f *SessionFilter) {
+		f.Repo = "/src/app"
+	}), 4)
+	// A repository can be named by its directory.
+	requireCount(t, d, filterWith(func(f *SessionFilter) {
+		f.Repo = "lib"
+		f.Branch = "main"
+	}), 1)
+	requireCount(t, d, filterWith(func(f *SessionFilter) {
+		f.Repo = "src/app_"
+	}), 0)
+
+	page, err := d.ListSessions(context.Background(),
+		filterWith(func(f *SessionFilter) { f.Branch = "feature" }))
+	requireNoError(t, err, "ListSessions")
+	for _, s := range page.Sessions {
+		if s.GitBranch != "feature" || s.RepoRoot != "/src/app" {
+			t.Errorf("session %s = (%q, %q)", s.ID, s.RepoRoot, s.GitBranch)
+		}
+	}
+}
+
+func TestGetAnalyticsBranches(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	seedBranches(t, d)
+
+	resp, err := d.GetAnalyticsBranches(ctx, baseFilter())
+	requireNoError(t, err, "GetAnalyticsBranches")
+	if len(resp.Branches) != 3 {
+		t.Fatalf("branches = %+v, want 3", resp.Branches)
+	}
+	// app/feature and app/main tie on 10 messages and are ordered
+	// by branch name; lib/main has 3.
+	feature := resp.Branches[0]
+	if feature.Branch != "feature" || feature.RepoRoot != "/src/app" ||
+		feature.Sessions != 2 || feature.Messages != 10 ||
+		feature.FirstSession != "2024-06-01" ||
+		feature.LastSession != "2024-06-02" {
+		t.Errorf("feature = %+v", feature)
+	}
+	if b := resp.Branches[1]; b.Branch != "main" || b.RepoRoot != "/src/app" {
+		t.Errorf("second = %+v, want app main", b)
+	}
+	if b := resp.Branches[2]; b.Branch != "main" || b.RepoRoot != "/work/lib" ||
+		b.Agents[defaultAgent] != 1 {
+		t.Errorf("third = %+v, want lib main", b)
+	}
+
+	f := baseFilter()
+	f.Branch = "main"
+	resp, err = d.GetAnalyticsBranches(ctx, f)
+	requireNoError(t, err, "GetAnalyticsBranches branch")
+	if len(resp.Branches) != 2 {
+		t.Errorf("main branches = %+v, want 2", resp.Branches)
+	}
+
+	f = baseFilter()
+	f.Repo = "lib"
+	resp, err = d.GetAnalyticsBranches(ctx, f)
+	requireNoError(t, err, "GetAnalyticsBranches repo")
+	if len(resp.Branches) != 1 || resp.Branches[0].Project != "lib" {
+		t.Errorf("lib branches = %+v", resp.Branches)
+	}
+}
@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SessionCommit is a git commit linked to a session because it
+// was authored during or shortly after the session and changed
+// files the session edited. Files lists those shared files,
+// relative to RepoRoot.
+type SessionCommit struct {
+	SessionID  string   `json:"-"`
+	SHA        string   `json:"sha"`
+	RepoRoot   string   `json:"repo_root"`
+	Author     string   `json:"author"`
+	Email      string   `json:"email"`
+	AuthoredAt string   `json:"authored_at"`
+	Subject    string   `json:"subject"`
+	Files      []string `json:"files"`
+}
+
+// CommitSession is a session linked to a commit.
+type CommitSession struct {
+	SHA          string   `json:"sha"`
+	RepoRoot     string   `json:"repo_root"`
+	Subject      string   `json:"subject"`
+	AuthoredAt   string   `json:"authored_at"`
+	Files        []string `json:"files"`
+	SessionID    string   `json:"session_id"`
+	Project      string   `json:"project"`
+	Machine      string   `json:"machine"`
+	Agent        string   `json:"agent"`
+	FirstMessage *string  `json:"first_message"`
+	StartedAt    *string  `json:"started_at"`
+	EndedAt      *string  `json:"ended_at"`
+}
+
+// CommitCandidate is a session whose commits need to be found:
+// one that changed files inside git repositories and has not
+// been scanned since it last changed, or whose commit window was
+// still open at the last scan. Files maps each repository root
+// to the paths the session changed in it.
+type CommitCandidate struct {
+	SessionID string
+	StartedAt string
+	EndedAt   *string
+	Files     map[string][]string
+}
+
+// CommitCandidates returns the sessions the commit correlator
+// should scan.
+func (db *DB) CommitCandidates() ([]CommitCandidate, error) {
+	rows, err := db.reader.Query(`
+		SELECT DISTINCT s.id, s.started_at, s.ended_at,
+			f.repo_root, f.path
+		FROM tool_call_files f
+		JOIN sessions s ON s.id = f.session_id
+		LEFT JOIN commit_scans c ON c.session_id = s.id
+		WHERE f.action IN ('edit', 'write')
+		  AND f.repo_root <> ''
+		  AND s.started_at IS NOT NULL AND s.started_at <> ''
+		  AND (c.session_id IS NULL OR c.complete = 0
+			OR c.ended_at IS NOT s.ended_at)
+		ORDER BY s.id, f.repo_root, f.path`)
+	if err != nil {
+		return nil, fmt.Errorf("querying commit candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var out []CommitCandidate
+	for rows.Next() {
+		var (
+			id, startedAt, root, path string
+			endedAt                   *string
+		)
+		if err := rows.Scan(
+			&id, &startedAt, &endedAt, &root, &path,
+		); err != nil {
+			return nil, fmt.Errorf("scanning commit candidate: %w", err)
+		}
+		if len(out) == 0 || out[len(out)-1].SessionID != id {
+			out = append(out, CommitCandidate{
+				SessionID: id,
+				StartedAt: startedAt,
+				EndedAt:   endedAt,
+				Files:     map[string][]string{},
+			})
+		}
+		c := &out[len(out)-1]
+		c.Files[root] = append(c.Files[root], path)
+	}
+	return out, rows.Err()
+}
+
+// SaveSessionCommits replaces the commits linked to a session
+// and records the scan. endedAt is the end of the session the
+// scan used, and complete reports whether the scan covered the
+// whole commit window.
+func (db *DB) SaveSessionCommits(
+	sessionID string, endedAt *string, complete bool,
+	commits []SessionCommit,
+) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(
+		"DELETE FROM session_commits WHERE session_id = ?", sessionID,
+	); err != nil {
+		return fmt.Errorf("clearing session commits: %w", err)
+	}
+	for _, c := range commits {
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO session_commits
+				(session_id, sha, repo_root, author, email,
+				 authored_at, subject, files)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			sessionID, c.SHA, c.RepoRoot, c.Author, c.Email,
+			c.AuthoredAt, c.Subject, strings.Join(c.Files, "\n"),
+		); err != nil {
+			return fmt.Errorf("inserting commit %s: %w", c.SHA, err)
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT OR REPLACE INTO commit_scans
+			(session_id, ended_at, complete)
+		VALUES (?, ?, ?)`, sessionID, endedAt, complete,
+	); err != nil {
+		return fmt.Errorf("recording commit scan: %w", err)
+	}
+	return tx.Commit()
+}
+
+// SessionCommits returns the commits linked to a session,
+// oldest first.
+func (db *DB) SessionCommits(
+	ctx context.Context, sessionID string,
+) ([]SessionCommit, error) {
+	rows, err := db.reader.QueryContext(ctx, `
+		SELECT sha, repo_root, author, email, authored_at,
+			subject, files
+		FROM session_commits
+		WHERE session_id = ?
+		ORDER BY authored_at, sha`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("querying session commits: %w", err)
+	}
+	defer rows.Close()
+
+	out := []SessionCommit{}
+	for rows.Next() {
+		c := SessionCommit{SessionID: sessionID}
+		var files string
+		if err := rows.Scan(
+			&c.SHA, &c.RepoRoot, &c.Author, &c.Email,
+			&c.AuthoredAt, &c.Subject, &files,
+		); err != nil {
+			return nil, fmt.Errorf("scanning session commit: %w", err)
+		}
+		c.Files = splitFiles(files)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// CommitSessions returns the sessions linked to the commits
+// whose SHA starts with sha, most recent session first.
+func (db *DB) CommitSessions(
+	ctx context.Context, sha string,
+) ([]CommitSession, error) {
+	rows, err := db.reader.QueryContext(ctx, `
+		SELECT c.sha, c.repo_root, c.subject, c.authored_at,
+			c.files, s.id, s.project, s.machine, s.agent,
+			s.first_message, s.started_at, s.ended_at
+		FROM session_commits c
+		JOIN sessions s ON s.id = c.session_id
+		WHERE c.sha LIKE ? ESCAPE '\'
+		ORDER BY COALESCE(s.ended_at, s.started_at, '') DESC,
+			s.id, c.sha`,
+		escapeLike(strings.ToLower(sha))+"%",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying commit sessions: %w", err)
+	}
+	defer rows.Close()
+
+	out := []CommitSession{}
+	for rows.Next() {
+		var cs CommitSession
+		var files string
+		if err := rows.Scan(
+			&cs.SHA, &cs.RepoRoot, &cs.Subject, &cs.AuthoredAt,
+			&files, &cs.SessionID, &cs.Project, &cs.Machine,
+			&cs.Agent, &cs.FirstMessage, &cs.StartedAt, &cs.EndedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning commit session: %w", err)
+		}
+		cs.Files = splitFiles(files)
+		out = append(out, cs)
+	}
+	return out, rows.Err()
+}
+
+func splitFiles(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
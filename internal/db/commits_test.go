@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCommitCandidates(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "s1", "app", func(s *Session) {
+		s.StartedAt = Ptr("2024-01-01T10:00:00Z")
+		s.EndedAt = Ptr("2024-01-01T11:00:00Z")
+	})
+	insertSession(t, d, "s2", "app", func(s *Session) {
+		s.StartedAt = Ptr("2024-01-02T10:00:00Z")
+	})
+	insertMessages(t, d,
+		fileMsg("s1", 0, "Read", ToolFile{
+			Path: "README.md", RepoRoot: "/src/app", Action: "read",
+		}),
+		fileMsg("s1", 1, "Edit", ToolFile{
+			Path: "main.go", RepoRoot: "/src/app", Action: "edit",
+		}),
+		fileMsg("s1", 2, "Write", ToolFile{
+			Path: "/tmp/scratch.txt", Action: "write",
+		}),
+		fileMsg("s1", 3, "Edit", ToolFile{
+			Path: "lib.go", RepoRoot: "/src/lib", Action: "edit",
+		}),
+	)
+	insertMessages(t, d, fileMsg("s2", 0, "Read", ToolFile{
+		Path: "main.go", RepoRoot: "/src/app", Action: "read",
+	}))
+
+	got, err := d.CommitCandidates()
+	requireNoError(t, err, "CommitCandidates")
+	if len(got) != 1 {
+		t.Fatalf("candidates = %+v, want s1 only", got)
+	}
+	c := got[0]
+	if c.SessionID != "s1" || c.StartedAt != "2024-01-01T10:00:00Z" ||
+		c.EndedAt == nil || len(c.Files) != 2 ||
+		len(c.Files["/src/app"]) != 1 || c.Files["/src/app"][0] != "main.go" ||
+		len(c.Files["/src/lib"]) != 1 {
+		t.Errorf("candidate = %+v", c)
+	}
+
+	// An open window is scanned again, a closed one is not.
+	err = d.SaveSessionCommits("s1", c.EndedAt, false, nil)
+	requireNoError(t, err, "SaveSessionCommits open")
+	got, err = d.CommitCandidates()
+	requireNoError(t, err, "CommitCandidates open")
+	if len(got) != 1 {
+		t.Errorf("open window candidates = %+v", got)
+	}
+	err = d.SaveSessionCommits("s1", c.EndedAt, true, nil)
+	requireNoError(t, err, "SaveSessionCommits complete")
+	got, err = d.CommitCandidates()
+	requireNoError(t, err, "CommitCandidates complete")
+	if len(got) != 0 {
+		t.Errorf("complete candidates = %+v", got)
+	}
+
+	// A session that grows is scanned again.
+	err = d.SaveSessionCommits("s1", Ptr("2024-01-01T10:30:00Z"), true, nil)
+	requireNoError(t, err, "SaveSessionCommits stale")
+	got, err = d.CommitCandidates()
+	requireNoError(t, err, "CommitCandidates stale")
+	if len(got) != 1 {
+		t.Errorf("grown session candidates = %+v", got)
+	}
+}
+
+func TestSessionCommits(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "app")
+	insertSession(t, d, "s2", "app")
+
+	first := SessionCommit{
+		SHA: "aaaa1111", RepoRoot: "/src/app", Author: "Dev",
+		Email: "dev@example.com", AuthoredAt: "2024-01-01T10:30:00Z",
+		Subject: "First", Files: []string{"a.go", "b.go"},
+	}
+	second := first
+	second.SHA = "bbbb2222"
+	second.AuthoredAt = "2024-01-01T10:10:00Z"
+	second.Subject = "Second"
+	second.Files = []string{"a.go"}
+
+	err := d.SaveSessionCommits("s1", nil, true, []SessionCommit{first, second})
+	requireNoError(t, err, "SaveSessionCommits s1")
+	err = d.SaveSessionCommits("s2", nil, true, []SessionCommit{first})
+	requireNoError(t, err, "SaveSessionCommits s2")
+
+	got, err := d.SessionCommits(ctx, "s1")
+	requireNoError(t, err, "SessionCommits")
+	if len(got) != 2 || got[0].SHA != "bbbb2222" || got[1].SHA != "aaaa1111" {
+		t.Fatalf("SessionCommits = %+v, want oldest first", got)
+	}
+	if len(got[1].Files) != 2 || got[1].Files[1] != "b.go" {
+		t.Errorf("files = %v", got[1].Files)
+	}
+
+	sessions, err := d.CommitSessions(ctx, "AAAA")
+	requireNoError(t, err, "CommitSessions")
+	if len(sessions) != 2 || sessions[0].Subject != "First" {
+		t.Errorf("CommitSessions = %+v, want s1 and s2", sessions)
+	}
+	sessions, err = d.CommitSessions(ctx, "bbbb2222")
+	requireNoError(t, err, "CommitSessions full")
+	if len(sessions) != 1 || sessions[0].SessionID != "s1" {
+		t.Errorf("CommitSessions full = %+v", sessions)
+	}
+
+	// Rescanning replaces the links.
+	err = d.SaveSessionCommits("s1", nil, true, nil)
+	requireNoError(t, err, "SaveSessionCommits clear")
+	got, err = d.SessionCommits(ctx, "s1")
+	requireNoError(t, err, "SessionCommits cleared")
+	if len(got) != 0 {
+		t.Errorf("cleared commits = %+v", got)
+	}
+}
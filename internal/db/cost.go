@@ -0,0 +1,212 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/wesm/agentsview/internal/pricing"
+)
+
+// estimateCost prices a token_usage_by_model value at the given
+// session start time.
+func estimateCost(
+	table *pricing.Table, byModel RawJSON, startedAt string,
+) pricing.Estimate {
+	if len(byModel) == 0 {
+		return pricing.Estimate{}
+	}
+	var usage map[string]pricing.Usage
+	if err := json.Unmarshal(byModel, &usage); err != nil {
+		return pricing.Estimate{}
+	}
+	at, _ := localTime(startedAt, time.UTC)
+	return table.Estimate(usage, at)
+}
+
+// setSessionCost fills in the session's estimated cost. Sessions
+// whose token usage cannot be priced are left without one.
+func (db *DB) setSessionCost(s *Session) {
+	ts := s.CreatedAt
+	if s.StartedAt != nil {
+		ts = *s.StartedAt
+	}
+	e := estimateCost(db.priceTable(), s.TokenUsageByModel, ts)
+	if !e.Priced() {
+		return
+	}
+	total := roundUSD(e.Total)
+	s.CostUSD = &total
+	s.CostByModel = make(map[string]float64, len(e.ByModel))
+	for model, c := range e.ByModel {
+		s.CostByModel[model] = roundUSD(c)
+	}
+}
+
+// roundUSD rounds a cost to a hundredth of a cent.
+func roundUSD(v float64) float64 {
+	return math.Round(v*1e4) / 1e4
+}
+
+// --- Cost ---
+
+// DailyCost is the estimated spend on one day.
+type DailyCost struct {
+	Date    string  `json:"date"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// CostBreakdown is the estimated spend for one project, agent
+// or model.
+type CostBreakdown struct {
+	Name     string  `json:"name"`
+	CostUSD  float64 `json:"cost_usd"`
+	Sessions int     `json:"sessions"`
+}
+
+// CostAnalyticsResponse holds estimated spend for the filtered
+// sessions. Each session's cost is attributed to the day it
+// started.
+type CostAnalyticsResponse struct {
+	TotalUSD  float64         `json:"total_usd"`
+	Daily     []DailyCost     `json:"daily"`
+	ByProject []CostBreakdown `json:"by_project"`
+	ByAgent   []CostBreakdown `json:"by_agent"`
+	ByModel   []CostBreakdown `json:"by_model"`
+	// UnpricedModels lists models with token usage but no
+	// entry in the price table; their usage is not counted.
+	UnpricedModels []string `json:"unpriced_models"`
+}
+
+// costAccumulator sums spend per bucket key.
+type costAccumulator map[string]*CostBreakdown
+
+func (a costAccumulator) add(name string, cost float64) {
+	b, ok := a[name]
+	if !ok {
+		b = &CostBreakdown{Name: name}
+		a[name] = b
+	}
+	b.CostUSD += cost
+	b.Sessions++
+}
+
+// sorted returns the buckets by cost descending, rounded.
+func (a costAccumulator) sorted() []CostBreakdown {
+	out := make([]CostBreakdown, 0, len(a))
+	for _, b := range a {
+		out = append(out, CostBreakdown{
+			Name:     b.Name,
+			CostUSD:  roundUSD(b.CostUSD),
+			Sessions: b.Sessions,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CostUSD != out[j].CostUSD {
+			return out[i].CostUSD > out[j].CostUSD
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// GetAnalyticsCost returns estimated spend by day, project,
+// agent and model.
+func (db *DB) GetAnalyticsCost(
+	ctx context.Context, f AnalyticsFilter,
+) (CostAnalyticsResponse, error) {
+	loc := f.location()
+	dateCol := "COALESCE(started_at, created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return CostAnalyticsResponse{}, err
+		}
+	}
+
+	query := `SELECT id, project, agent, ` + dateCol + `,
+		token_usage_by_model
+		FROM sessions WHERE ` + where + `
+		AND token_usage_by_model IS NOT NULL`
+
+	rows, err := db.reader.QueryContext(ctx, query, args...)
+	if err != nil {
+		return CostAnalyticsResponse{},
+			fmt.Errorf("querying analytics cost: %w", err)
+	}
+	defer rows.Close()
+
+	table := db.priceTable()
+	var total float64
+	daily := make(map[string]float64)
+	projects := make(costAccumulator)
+	agents := make(costAccumulator)
+	models := make(costAccumulator)
+	unpriced := make(map[string]bool)
+
+	for rows.Next() {
+		var id, project, agent, ts string
+		var byModel RawJSON
+		if err := rows.Scan(
+			&id, &project, &agent, &ts, &byModel,
+		); err != nil {
+			return CostAnalyticsResponse{},
+				fmt.Errorf("scanning cost row: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+
+		e := estimateCost(table, byModel, ts)
+		for _, m := range e.Unpriced {
+			unpriced[m] = true
+		}
+		if !e.Priced() {
+			continue
+		}
+		total += e.Total
+		daily[date] += e.Total
+		projects.add(project, e.Total)
+		agents.add(agent, e.Total)
+		for model, c := range e.ByModel {
+			models.add(model, c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return CostAnalyticsResponse{},
+			fmt.Errorf("iterating cost rows: %w", err)
+	}
+
+	resp := CostAnalyticsResponse{
+		TotalUSD:       roundUSD(total),
+		Daily:          make([]DailyCost, 0, len(daily)),
+		ByProject:      projects.sorted(),
+		ByAgent:        agents.sorted(),
+		ByModel:        models.sorted(),
+		UnpricedModels: make([]string, 0, len(unpriced)),
+	}
+	for date, c := range daily {
+		resp.Daily = append(resp.Daily, DailyCost{
+			Date: date, CostUSD: roundUSD(c),
+		})
+	}
+	sort.Slice(resp.Daily, func(i, j int) bool {
+		return resp.Daily[i].Date < resp.Daily[j].Date
+	})
+	for m := range unpriced {
+		resp.UnpricedModels = append(resp.UnpricedModels, m)
+	}
+	sort.Strings(resp.UnpricedModels)
+	return resp, nil
+}
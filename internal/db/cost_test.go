@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/pricing"
+)
+
+func seedCostData(t *testing.T, d *DB) {
+	t.Helper()
+	sessions := []struct {
+		id, project, agent, start, usage string
+	}{
+		// 3 + 15 = 18 USD
+		{"c1", "alpha", "claude", "2024-06-01T09:00:00Z",
+			`{"claude-sonnet-4-5-20250929":{"input_tokens":1000000,"output_tokens":1000000}}`},
+		// 1 + 0.1 = 1.1 USD, plus an unpriced model
+		{"c2", "beta", "claude", "2024-06-02T09:00:00Z",
+			`{"claude-haiku-4-5":{"input_tokens":1000000,"cache_read_input_tokens":1000000},` +
+				`"mystery":{"input_tokens":5}}`},
+		// 10 USD
+		{"c3", "alpha", "codex", "2024-06-02T12:00:00Z",
+			`{"gpt-5":{"output_tokens":1000000}}`},
+		// No per-model usage: not priced.
+		{"c4", "beta", "codex", "2024-06-03T12:00:00Z", ""},
+	}
+	for _, s := range sessions {
+		insertSession(t, d, s.id, s.project, func(sess *Session) {
+			sess.Agent = s.agent
+			sess.StartedAt = Ptr(s.start)
+			sess.MessageCount = 2
+			if s.usage != "" {
+				sess.TokenUsageByModel = RawJSON(s.usage)
+			}
+		})
+	}
+}
+
+func TestSessionCost(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	seedCostData(t, d)
+
+	s, err := d.GetSession(ctx, "c1")
+	requireNoError(t, err, "GetSession")
+	if s.CostUSD == nil || *s.CostUSD != 18 {
+		t.Fatalf("CostUSD = %v, want 18", s.CostUSD)
+	}
+	if c := s.CostByModel["claude-sonnet-4-5-20250929"]; c != 18 {
+		t.Errorf("CostByModel = %v", s.CostByModel)
+	}
+
+	s, err = d.GetSession(ctx, "c4")
+	requireNoError(t, err, "GetSession")
+	if s.CostUSD != nil {
+		t.Errorf("unpriced session CostUSD = %v, want nil", *s.CostUSD)
+	}
+
+	page, err := d.ListSessions(ctx, SessionFilter{Limit: 10})
+	requireNoError(t, err, "ListSessions")
+	for _, s := range page.Sessions {
+		if s.ID == "c2" && (s.CostUSD == nil || *s.CostUSD != 1.1) {
+			t.Errorf("listed c2 CostUSD = %v, want 1.1", s.CostUSD)
+		}
+	}
+
+	table, err := pricing.Default().With(map[string][]pricing.Price{
+		"claude-sonnet-4-5": {{Input: 1, Output: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetPriceTable(table)
+	s, err = d.GetSession(ctx, "c1")
+	requireNoError(t, err, "GetSession")
+	if s.CostUSD == nil || *s.CostUSD != 2 {
+		t.Errorf("overridden CostUSD = %v, want 2", s.CostUSD)
+	}
+}
+
+func TestGetAnalyticsCost(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	seedCostData(t, d)
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-03", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsCost(ctx, f)
+	requireNoError(t, err, "GetAnalyticsCost")
+
+	if resp.TotalUSD != 29.1 {
+		t.Errorf("TotalUSD = %v, want 29.1", resp.TotalUSD)
+	}
+	wantDaily := []DailyCost{
+		{Date: "2024-06-01", CostUSD: 18},
+		{Date: "2024-06-02", CostUSD: 11.1},
+	}
+	if len(resp.Daily) != len(wantDaily) {
+		t.Fatalf("Daily = %+v, want %+v", resp.Daily, wantDaily)
+	}
+	for i, want := range wantDaily {
+		if resp.Daily[i] != want {
+			t.Errorf("Daily[%d] = %+v, want %+v",
+				i, resp.Daily[i], want)
+		}
+	}
+	wantProjects := []CostBreakdown{
+		{Name: "alpha", CostUSD: 28, Sessions: 2},
+		{Name: "beta", CostUSD: 1.1, Sessions: 1},
+	}
+	for i, want := range wantProjects {
+		if i >= len(resp.ByProject) || resp.ByProject[i] != want {
+			t.Errorf("ByProject = %+v, want %+v",
+				resp.ByProject, wantProjects)
+			break
+		}
+	}
+	wantAgents := []CostBreakdown{
+		{Name: "claude", CostUSD: 19.1, Sessions: 2},
+		{Name: "codex", CostUSD: 10, Sessions: 1},
+	}
+	for i, want := range wantAgents {
+		if i >= len(resp.ByAgent) || resp.ByAgent[i] != want {
+			t.Errorf("ByAgent = %+v, want %+v",
+				resp.ByAgent, wantAgents)
+			break
+		}
+	}
+	if len(resp.ByModel) != 3 {
+		t.Errorf("ByModel = %+v, want 3 models", resp.ByModel)
+	}
+	if len(resp.UnpricedModels) != 1 ||
+		resp.UnpricedModels[0] != "mystery" {
+		t.Errorf("UnpricedModels = %v, want [mystery]",
+			resp.UnpricedModels)
+	}
+
+	t.Run("AgentFilter", func(t *testing.T) {
+		f := f
+		f.Agent = "codex"
+		resp, err := d.GetAnalyticsCost(ctx, f)
+		requireNoError(t, err, "GetAnalyticsCost")
+		if resp.TotalUSD != 10 || len(resp.ByProject) != 1 {
+			t.Errorf("codex cost = %+v", resp)
+		}
+	})
+
+	t.Run("EmptyRange", func(t *testing.T) {
+		resp, err := d.GetAnalyticsCost(ctx, emptyFilter())
+		requireNoError(t, err, "GetAnalyticsCost")
+		if resp.TotalUSD != 0 || resp.Daily == nil ||
+			len(resp.Daily) != 0 {
+			t.Errorf("empty range = %+v", resp)
+		}
+	})
+}
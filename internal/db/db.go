@@ -13,6 +13,8 @@ import (
 	"sync"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/wesm/agentsview/internal/pricing"
 )
 
 //go:embed schema.sql
@@ -50,6 +52,9 @@ type DB struct {
 
 	cursorMu     sync.RWMutex
 	cursorSecret []byte
+
+	pricesMu sync.RWMutex
+	prices   *pricing.Table
 }
 
 // SetCursorSecret updates the secret key used for cursor signing.
@@ -59,6 +64,21 @@ func (db *DB) SetCursorSecret(secret []byte) {
 	db.cursorSecret = append([]byte(nil), secret...)
 }
 
+// SetPriceTable replaces the price table used to estimate
+// session costs. The built-in table is used until this is
+// called.
+func (db *DB) SetPriceTable(t *pricing.Table) {
+	db.pricesMu.Lock()
+	defer db.pricesMu.Unlock()
+	db.prices = t
+}
+
+func (db *DB) priceTable() *pricing.Table {
+	db.pricesMu.RLock()
+	defer db.pricesMu.RUnlock()
+	return db.prices
+}
+
 // makeDSN builds a SQLite connection string with shared pragmas.
 func makeDSN(path string, readOnly bool) string {
 	params := url.Values{}
@@ -79,33 +99,113 @@ func makeDSN(path string, readOnly bool) string {
 // It configures WAL mode, mmap, and returns a DB with separate
 // writer and reader connections.
 //
-// If an existing database has an outdated schema, it is deleted
-// and recreated from scratch. Session data is re-synced from
-// the source files on the next sync cycle.
+// An existing database with an older schema is backed up (see
+// MigrationPlan.Backup) and migrated in place. Databases that
+// predate versioned migrations are instead deleted and recreated
+// from scratch; session data is re-synced from the source files
+// on the next sync cycle, and tags, stars and notes are copied
+// over to the new database.
 func Open(path string) (*DB, error) {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, fmt.Errorf("creating db directory: %w", err)
 	}
 
-	rebuild, err := needsRebuild(path)
+	plan, err := PlanMigrations(path)
 	if err != nil {
 		return nil, fmt.Errorf("checking schema: %w", err)
 	}
-	if rebuild {
-		if err := dropDatabase(path); err != nil {
-			return nil, fmt.Errorf(
-				"rebuilding database: %w", err,
-			)
+	if plan.Pending() {
+		if err := backupDatabase(path, plan.Backup); err != nil {
+			return nil, fmt.Errorf("backing up database: %w", err)
 		}
 	}
+	if !plan.Rebuild {
+		return openAndInit(path)
+	}
 
-	return openAndInit(path)
+	saved, err := readAnnotations(path)
+	if err != nil {
+		return nil, fmt.Errorf("saving annotations: %w", err)
+	}
+	if err := dropDatabase(path); err != nil {
+		return nil, fmt.Errorf(
+			"rebuilding database: %w", err,
+		)
+	}
+	db, err := openAndInit(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.restoreAnnotations(saved); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("restoring annotations: %w", err)
+	}
+	return db, nil
 }
 
-// needsRebuild checks whether an existing database has an
-// outdated schema that requires a full rebuild. Returns an
-// error on probe failures so callers can surface them.
+// OpenReadOnly opens an existing database without creating,
+// migrating, or rebuilding it, and fails if the database has
+// migrations pending. Every connection sets
+// PRAGMA query_only so the database can be queried safely while
+// another process (e.g. serve) owns writes. Write methods return
+// errors.
+func OpenReadOnly(path string) (*DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	// go-sqlite3 ignores mode=ro for non-URI paths, so enforce
+	// read-only access with query_only instead.
+	dsn := makeDSN(path, true) + "&_query_only=true"
+	writer, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening writer: %w", err)
+	}
+	writer.SetMaxOpenConns(1)
+
+	reader, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("opening reader: %w", err)
+	}
+	reader.SetMaxOpenConns(4)
+
+	db := &DB{
+		writer: writer, reader: reader, prices: pricing.Default(),
+	}
+	db.cursorSecret = make([]byte, 32)
+	if _, err := rand.Read(db.cursorSecret); err != nil {
+		db.Close()
+		return nil, fmt.Errorf(
+			"generating cursor secret: %w", err,
+		)
+	}
+
+	if err := reader.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	// Queries assume the latest schema, and this handle cannot
+	// migrate the database itself.
+	v, err := schemaVersion(reader)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if v < latestVersion() {
+		db.Close()
+		return nil, fmt.Errorf(
+			"database is at schema v%d; run `agentsview serve` "+
+				"or `agentsview db migrate` first", v,
+		)
+	}
+	return db, nil
+}
+
+// needsRebuild checks whether an existing database predates
+// versioned migrations and so requires a full rebuild. Returns
+// an error on probe failures so callers can surface them.
 func needsRebuild(path string) (bool, error) {
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
@@ -263,21 +363,14 @@ func needsRebuild(path string) (bool, error) {
 		return true, nil
 	}
 
-	// Check schema_version to trigger re-parse when new
-	// columns or parsing changes are introduced (e.g.
-	// system messages that were previously dropped).
-	var schemaVersion int
-	err = conn.QueryRow(
-		`SELECT COALESCE(
-			(SELECT value FROM stats WHERE key = 'schema_version'),
-			0)`,
-	).Scan(&schemaVersion)
+	// Versions before baseVersion changed parsing without a
+	// migration (e.g. system messages that were previously
+	// dropped), so their data must be re-parsed from scratch.
+	v, err := schemaVersion(conn)
 	if err != nil {
-		return false, fmt.Errorf(
-			"probing schema version: %w", err,
-		)
+		return false, err
 	}
-	return schemaVersion < 3, nil
+	return v < baseVersion, nil
 }
 
 func dropDatabase(path string) error {
@@ -306,7 +399,9 @@ func openAndInit(path string) (*DB, error) {
 	}
 	reader.SetMaxOpenConns(4)
 
-	db := &DB{writer: writer, reader: reader}
+	db := &DB{
+		writer: writer, reader: reader, prices: pricing.Default(),
+	}
 
 	db.cursorSecret = make([]byte, 32)
 	if _, err := rand.Read(db.cursorSecret); err != nil {
@@ -336,8 +431,24 @@ func (db *DB) HasFTS() bool {
 func (db *DB) init() error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	if _, err := db.writer.Exec(schemaSQL); err != nil {
-		return err
+
+	var sessionsCount int
+	if err := db.writer.QueryRow(
+		"SELECT count(*) FROM sqlite_master WHERE type='table' AND name='sessions'",
+	).Scan(&sessionsCount); err != nil {
+		return fmt.Errorf("checking sessions table: %w", err)
+	}
+	if sessionsCount == 0 {
+		if err := db.createSchema(); err != nil {
+			return err
+		}
+	} else {
+		if err := db.migrate(); err != nil {
+			return fmt.Errorf("migrating schema: %w", err)
+		}
+		if _, err := db.writer.Exec(schemaSQL); err != nil {
+			return err
+		}
 	}
 
 	// Check if FTS table exists before trying to create it
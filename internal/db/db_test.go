@@ -282,6 +282,49 @@ func TestOpenCreatesFile(t *testing.T) {
 	}
 }
 
+func TestOpenReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.db")
+
+	_, err := OpenReadOnly(path)
+	requireErrContains(t, err, "opening database")
+
+	d, err := Open(path)
+	requireNoError(t, err, "Open")
+	insertSession(t, d, "s1", "proj")
+
+	ro, err := OpenReadOnly(path)
+	requireNoError(t, err, "OpenReadOnly")
+	defer ro.Close()
+
+	s, err := ro.GetSession(context.Background(), "s1")
+	requireNoError(t, err, "GetSession")
+	if s == nil || s.Project != "proj" {
+		t.Fatalf("GetSession = %+v, want project proj", s)
+	}
+
+	// Writes through the read-only handle must fail while the
+	// primary handle keeps working.
+	err = ro.UpsertSession(Session{ID: "s2", Project: "p"})
+	requireErrContains(t, err, "readonly")
+	insertSession(t, d, "s3", "proj")
+	requireNoError(t, d.Close(), "Close")
+}
+
+func TestOpenReadOnlyPendingMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	d, err := Open(path)
+	requireNoError(t, err, "Open")
+	_, err = d.writer.Exec(
+		"UPDATE stats SET value = 3 WHERE key = 'schema_version'",
+	)
+	requireNoError(t, err, "downgrade")
+	requireNoError(t, d.Close(), "Close")
+
+	_, err = OpenReadOnly(path)
+	requireErrContains(t, err, "database is at schema v3; run")
+}
+
 func TestOpenProbeErrorPropagates(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("skipping: chmod semantics differ on Windows")
@@ -1977,6 +2020,108 @@ func TestToolCallNewColumns(t *testing.T) {
 	}
 }
 
+func TestAppendMessagesPairsEarlierToolCalls(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "s1", "proj")
+	insertMessages(t, d, Message{
+		SessionID: "s1",
+		Ordinal:   0,
+		Role:      "assistant",
+		Content:   "[Read: main.go]",
+		Timestamp: tsZero,
+		ToolCalls: []ToolCall{{
+			SessionID: "s1",
+			ToolName:  "Read",
+			Category:  "Read",
+			ToolUseID: "toolu_1",
+		}},
+	})
+
+	err := d.AppendMessages("s1",
+		[]Message{asstMsg("s1", 2, "done reading")},
+		[]ToolResult{{
+			ToolUseID: "toolu_1", ContentLength: 7, Content: "package",
+			IsError: true, ExitCode: Ptr(1),
+		}},
+	)
+	requireNoError(t, err, "AppendMessages")
+
+	got, err := d.GetAllMessages(context.Background(), "s1")
+	requireNoError(t, err, "GetAllMessages")
+	if len(got) != 2 || got[1].Ordinal != 2 {
+		t.Fatalf("messages = %+v, want ordinals 0 and 2", got)
+	}
+	tc := got[0].ToolCalls[0]
+	if tc.ResultContentLength != 7 || tc.ResultContent != "package" {
+		t.Errorf("tool call result = %d %q, want 7 %q",
+			tc.ResultContentLength, tc.ResultContent, "package")
+	}
+	if tc.IsError == nil || !*tc.IsError ||
+		tc.ExitCode == nil || *tc.ExitCode != 1 {
+		t.Errorf("tool call status = %v %v, want error, exit 1",
+			tc.IsError, tc.ExitCode)
+	}
+}
+
+func TestToolCallStatus(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "s1", "proj")
+	m := asstMsg("s1", 0, "[Bash: make]")
+	m.ToolCalls = []ToolCall{
+		{SessionID: "s1", ToolName: "Bash", Category: "Bash",
+			IsError: Ptr(true), ExitCode: Ptr(2)},
+		{SessionID: "s1", ToolName: "Read", Category: "Read",
+			IsError: Ptr(false)},
+		{SessionID: "s1", ToolName: "Grep", Category: "Grep"},
+	}
+	insertMessages(t, d, m)
+
+	got, err := d.GetAllMessages(context.Background(), "s1")
+	requireNoError(t, err, "GetAllMessages")
+	tcs := got[0].ToolCalls
+	if len(tcs) != 3 {
+		t.Fatalf("tool calls = %+v, want 3", tcs)
+	}
+	if tcs[0].IsError == nil || !*tcs[0].IsError ||
+		tcs[0].ExitCode == nil || *tcs[0].ExitCode != 2 {
+		t.Errorf("failed call = %v %v", tcs[0].IsError, tcs[0].ExitCode)
+	}
+	if tcs[1].IsError == nil || *tcs[1].IsError || tcs[1].ExitCode != nil {
+		t.Errorf("successful call = %v %v", tcs[1].IsError, tcs[1].ExitCode)
+	}
+	if tcs[2].IsError != nil || tcs[2].ExitCode != nil {
+		t.Errorf("call without result = %v %v", tcs[2].IsError, tcs[2].ExitCode)
+	}
+}
+
+func TestMessageTokenUsage(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "s1", "proj")
+	a := asstMsg("s1", 1, "hi")
+	a.Model = "gpt-5-codex"
+	a.InputTokens = 120
+	a.OutputTokens = 30
+	a.CacheCreationInputTokens = 5
+	a.CacheReadInputTokens = 900
+	insertMessages(t, d, userMsg("s1", 0, "hello"), a)
+
+	got, err := d.GetMessages(context.Background(), "s1", 0, 10, true)
+	requireNoError(t, err, "GetMessages")
+	if len(got) != 2 {
+		t.Fatalf("messages = %d, want 2", len(got))
+	}
+	if got[0].Model != "" || got[0].InputTokens != 0 {
+		t.Errorf("user message usage = %q %d",
+			got[0].Model, got[0].InputTokens)
+	}
+	m := got[1]
+	if m.Model != "gpt-5-codex" || m.InputTokens != 120 ||
+		m.OutputTokens != 30 || m.CacheCreationInputTokens != 5 ||
+		m.CacheReadInputTokens != 900 {
+		t.Errorf("assistant message usage = %+v", m)
+	}
+}
+
 func TestToolCallSkillName(t *testing.T) {
 	d := testDB(t)
 	insertSession(t, d, "s1", "proj")
@@ -0,0 +1,276 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// toolFilesVersion is bumped when the extraction of files from
+// tool calls changes, so that BackfillToolFiles reindexes.
+const toolFilesVersion = 2
+
+// Default and maximum result sizes for the file queries.
+const (
+	DefaultFileLimit = 50
+	MaxFileLimit     = 500
+)
+
+// ToolFile is a file read or changed by a tool call. Path is
+// relative to RepoRoot when RepoRoot is set.
+type ToolFile struct {
+	ToolCallID int64  `json:"-"`
+	SessionID  string `json:"-"`
+	Path       string `json:"path"`
+	RepoRoot   string `json:"repo_root,omitempty"`
+	Action     string `json:"action"`
+}
+
+// FileSession is a session that touched a file.
+type FileSession struct {
+	SessionID    string  `json:"session_id"`
+	Project      string  `json:"project"`
+	Machine      string  `json:"machine"`
+	Agent        string  `json:"agent"`
+	FirstMessage *string `json:"first_message"`
+	StartedAt    *string `json:"started_at"`
+	EndedAt      *string `json:"ended_at"`
+	Path         string  `json:"path"`
+	RepoRoot     string  `json:"repo_root,omitempty"`
+	Reads        int     `json:"reads"`
+	Edits        int     `json:"edits"`
+	Writes       int     `json:"writes"`
+}
+
+// FileHotspot is a frequently changed file in a project.
+type FileHotspot struct {
+	Project       string  `json:"project"`
+	Path          string  `json:"path"`
+	RepoRoot      string  `json:"repo_root,omitempty"`
+	Changes       int     `json:"changes"`
+	Sessions      int     `json:"sessions"`
+	LastSessionID string  `json:"last_session_id"`
+	LastActive    *string `json:"last_active"`
+}
+
+func insertToolFilesTx(tx *sql.Tx, files []ToolFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO tool_call_files
+			(tool_call_id, session_id, path, repo_root, action)
+		VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("preparing tool_call_files insert: %w", err)
+	}
+	defer stmt.Close()
+	for _, f := range files {
+		if _, err := stmt.Exec(
+			f.ToolCallID, f.SessionID, f.Path, f.RepoRoot, f.Action,
+		); err != nil {
+			return fmt.Errorf("inserting tool file %q: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// BackfillToolFiles rebuilds the file index of every stored tool
+// call with extract, unless it was already built by this
+// version. extract is given the working directory and
+// repository root of the call's session. Tool calls inserted
+// later are indexed from their Files. It returns the number of
+// tool calls indexed.
+func (db *DB) BackfillToolFiles(
+	extract func(tc ToolCall, cwd, repoRoot string) []ToolFile,
+) (int, error) {
+	var version int
+	err := db.writer.QueryRow(
+		`SELECT COALESCE((SELECT value FROM stats
+			WHERE key = 'tool_files_version'), 0)`,
+	).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("reading tool files version: %w", err)
+	}
+	if version >= toolFilesVersion {
+		return 0, nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("DELETE FROM tool_call_files"); err != nil {
+		return 0, fmt.Errorf("clearing tool files: %w", err)
+	}
+	rows, err := tx.Query(`
+		SELECT t.id, t.session_id, t.tool_name, t.category,
+			COALESCE(t.input_json, ''), s.cwd, s.repo_root
+		FROM tool_calls t
+		JOIN sessions s ON s.id = t.session_id
+		WHERE t.input_json IS NOT NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("querying tool calls: %w", err)
+	}
+	var files []ToolFile
+	n := 0
+	for rows.Next() {
+		var id int64
+		var tc ToolCall
+		var cwd, repoRoot string
+		if err := rows.Scan(
+			&id, &tc.SessionID, &tc.ToolName, &tc.Category,
+			&tc.InputJSON, &cwd, &repoRoot,
+		); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning tool call: %w", err)
+		}
+		extracted := extract(tc, cwd, repoRoot)
+		if len(extracted) > 0 {
+			n++
+		}
+		for _, f := range extracted {
+			f.ToolCallID = id
+			f.SessionID = tc.SessionID
+			files = append(files, f)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("iterating tool calls: %w", err)
+	}
+
+	if err := insertToolFilesTx(tx, files); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(
+		`INSERT OR REPLACE INTO stats (key, value)
+		 VALUES ('tool_files_version', ?)`, toolFilesVersion,
+	); err != nil {
+		return 0, fmt.Errorf("setting tool files version: %w", err)
+	}
+	return n, tx.Commit()
+}
+
+// FileSessions returns the sessions that read or changed path,
+// most recent first. Path matches stored paths exactly or by
+// suffix, and absolute paths also match by repository root, so
+// both "internal/db/sessions.go" and its absolute path find the
+// file.
+func (db *DB) FileSessions(
+	ctx context.Context, path, project string, limit int,
+) ([]FileSession, error) {
+	where := `(f.path = ? OR f.path LIKE ? ESCAPE '\'
+		OR (f.repo_root <> '' AND f.repo_root || '/' || f.path = ?))`
+	args := []any{path, "%/" + escapeLike(path), path}
+	if project != "" {
+		where += " AND s.project = ?"
+		args = append(args, project)
+	}
+	args = append(args, limit)
+
+	rows, err := db.reader.QueryContext(ctx, `
+		SELECT s.id, s.project, s.machine, s.agent,
+			s.first_message, s.started_at, s.ended_at,
+			MIN(f.path), MIN(f.repo_root),
+			SUM(f.action = 'read'), SUM(f.action = 'edit'),
+			SUM(f.action = 'write')
+		FROM tool_call_files f
+		JOIN sessions s ON s.id = f.session_id
+		WHERE `+where+`
+		GROUP BY s.id
+		ORDER BY COALESCE(s.ended_at, s.started_at, '') DESC, s.id
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying file sessions: %w", err)
+	}
+	defer rows.Close()
+
+	out := []FileSession{}
+	for rows.Next() {
+		var fs FileSession
+		if err := rows.Scan(
+			&fs.SessionID, &fs.Project, &fs.Machine, &fs.Agent,
+			&fs.FirstMessage, &fs.StartedAt, &fs.EndedAt,
+			&fs.Path, &fs.RepoRoot,
+			&fs.Reads, &fs.Edits, &fs.Writes,
+		); err != nil {
+			return nil, fmt.Errorf("scanning file session: %w", err)
+		}
+		out = append(out, fs)
+	}
+	return out, rows.Err()
+}
+
+// FileHotspots returns the most changed files of each project,
+// up to limit per project, with the number of sessions that
+// changed them and the most recent of those sessions.
+func (db *DB) FileHotspots(
+	ctx context.Context, project string, limit int,
+) ([]FileHotspot, error) {
+	where := "f.action IN ('edit', 'write')"
+	var args []any
+	if project != "" {
+		where += " AND s.project = ?"
+		args = append(args, project)
+	}
+	args = append(args, limit)
+
+	rows, err := db.reader.QueryContext(ctx, `
+		WITH changes AS (
+			SELECT s.project, f.repo_root, f.path, s.id AS session_id,
+				COALESCE(s.ended_at, s.started_at) AS active,
+				COUNT(*) AS n
+			FROM tool_call_files f
+			JOIN sessions s ON s.id = f.session_id
+			WHERE `+where+`
+			GROUP BY s.project, f.repo_root, f.path, s.id
+		),
+		files AS (
+			SELECT project, repo_root, path,
+				SUM(n) AS changes,
+				COUNT(*) AS sessions,
+				MAX(COALESCE(active, '')) AS last_active
+			FROM changes
+			GROUP BY project, repo_root, path
+		),
+		ranked AS (
+			SELECT *, ROW_NUMBER() OVER (
+				PARTITION BY project
+				ORDER BY changes DESC, sessions DESC, path
+			) AS pos
+			FROM files
+		)
+		SELECT r.project, r.path, r.repo_root, r.changes,
+			r.sessions, NULLIF(r.last_active, ''),
+			(SELECT c.session_id FROM changes c
+			 WHERE c.project = r.project
+			   AND c.repo_root = r.repo_root AND c.path = r.path
+			 ORDER BY COALESCE(c.active, '') DESC, c.session_id
+			 LIMIT 1)
+		FROM ranked r
+		WHERE r.pos <= ?
+		ORDER BY r.project, r.pos`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying file hotspots: %w", err)
+	}
+	defer rows.Close()
+
+	out := []FileHotspot{}
+	for rows.Next() {
+		var h FileHotspot
+		if err := rows.Scan(
+			&h.Project, &h.Path, &h.RepoRoot, &h.Changes,
+			&h.Sessions, &h.LastActive, &h.LastSessionID,
+		); err != nil {
+			return nil, fmt.Errorf("scanning file hotspot: %w", err)
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
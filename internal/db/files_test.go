@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+// fileMsg returns an assistant message with one tool call that
+// touched the given files.
+func fileMsg(
+	sid string, ordinal int, tool string, files ...ToolFile,
+) Message {
+	m := asstMsg(sid, ordinal, "["+tool+"]")
+	m.HasToolUse = true
+	m.ToolCalls = []ToolCall{{
+		SessionID: sid,
+		ToolName:  tool,
+		Category:  tool,
+		InputJSON: `{}`,
+		Files:     files,
+	}}
+	return m
+}
+
+func seedFileSessions(t *testing.T, d *DB) {
+	t.Helper()
+	insertSession(t, d, "s1", "app", func(s *Session) {
+		s.EndedAt = Ptr("2024-01-01T10:00:00Z")
+	})
+	insertSession(t, d, "s2", "app", func(s *Session) {
+		s.EndedAt = Ptr("2024-01-02T10:00:00Z")
+	})
+	insertSession(t, d, "s3", "other", func(s *Session) {
+		s.EndedAt = Ptr("2024-01-03T10:00:00Z")
+	})
+	sessions := ToolFile{Path: "internal/db/sessions.go", RepoRoot: "/src/app"}
+	insertMessages(t, d,
+		fileMsg("s1", 0, "Read", withAction(sessions, "read")),
+		fileMsg("s1", 1, "Edit", withAction(sessions, "edit")),
+		fileMsg("s1", 2, "Edit", withAction(sessions, "edit")),
+	)
+	insertMessages(t, d,
+		fileMsg("s2", 0, "Edit", withAction(sessions, "edit")),
+		fileMsg("s2", 1, "Write",
+			ToolFile{Path: "README.md", RepoRoot: "/src/app", Action: "write"}),
+	)
+	insertMessages(t, d,
+		fileMsg("s3", 0, "Read", ToolFile{
+			Path: "/tmp/copy/internal/db/sessions.go", Action: "read",
+		}),
+	)
+}
+
+func withAction(f ToolFile, action string) ToolFile {
+	f.Action = action
+	return f
+}
+
+func TestFileSessions(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	seedFileSessions(t, d)
+
+	got, err := d.FileSessions(ctx, "internal/db/sessions.go", "", 10)
+	requireNoError(t, err, "FileSessions")
+	if len(got) != 3 {
+		t.Fatalf("FileSessions = %+v, want 3 sessions", got)
+	}
+	if got[0].SessionID != "s3" || got[1].SessionID != "s2" ||
+		got[2].SessionID != "s1" {
+		t.Errorf("order = %s, %s, %s; want s3, s2, s1",
+			got[0].SessionID, got[1].SessionID, got[2].SessionID)
+	}
+	s1 := got[2]
+	if s1.Reads != 1 || s1.Edits != 2 || s1.Writes != 0 ||
+		s1.Project != "app" || s1.RepoRoot != "/src/app" {
+		t.Errorf("s1 = %+v", s1)
+	}
+
+	got, err = d.FileSessions(ctx, "/src/app/internal/db/sessions.go", "", 10)
+	requireNoError(t, err, "FileSessions absolute")
+	if len(got) != 2 {
+		t.Errorf("absolute path sessions = %+v, want s1 and s2", got)
+	}
+
+	got, err = d.FileSessions(ctx, "sessions.go", "app", 1)
+	requireNoError(t, err, "FileSessions project")
+	if len(got) != 1 || got[0].SessionID != "s2" {
+		t.Errorf("project filter = %+v, want s2", got)
+	}
+
+	got, err = d.FileSessions(ctx, "db/%", "", 10)
+	requireNoError(t, err, "FileSessions wildcard")
+	if len(got) != 0 {
+		t.Errorf("LIKE wildcard matched: %+v", got)
+	}
+}
+
+func TestFileHotspots(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	seedFileSessions(t, d)
+
+	got, err := d.FileHotspots(ctx, "", 10)
+	requireNoError(t, err, "FileHotspots")
+	if len(got) != 2 {
+		t.Fatalf("FileHotspots = %+v, want 2", got)
+	}
+	h := got[0]
+	if h.Project != "app" || h.Path != "internal/db/sessions.go" ||
+		h.Changes != 3 || h.Sessions != 2 || h.LastSessionID != "s2" ||
+		h.LastActive == nil || *h.LastActive != "2024-01-02T10:00:00Z" {
+		t.Errorf("top hotspot = %+v", h)
+	}
+	if got[1].Path != "README.md" || got[1].Changes != 1 {
+		t.Errorf("second hotspot = %+v", got[1])
+	}
+
+	got, err = d.FileHotspots(ctx, "app", 1)
+	requireNoError(t, err, "FileHotspots limit")
+	if len(got) != 1 || got[0].Path != "internal/db/sessions.go" {
+		t.Errorf("limited hotspots = %+v", got)
+	}
+}
+
+func TestToolFilesFollowMessages(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	seedFileSessions(t, d)
+
+	err := d.ReplaceSessionMessages("s2", []Message{asstMsg("s2", 0, "hi")})
+	requireNoError(t, err, "ReplaceSessionMessages")
+	got, err := d.FileSessions(ctx, "README.md", "", 10)
+	requireNoError(t, err, "FileSessions")
+	if len(got) != 0 {
+		t.Errorf("files of replaced messages remain: %+v", got)
+	}
+}
+
+func TestBackfillToolFiles(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "app", func(s *Session) {
+		s.Cwd = "/home/u/app"
+	})
+	m := asstMsg("s1", 0, "[Read]")
+	m.ToolCalls = []ToolCall{
+		{SessionID: "s1", ToolName: "Read", Category: "Read",
+			InputJSON: `{"file_path":"main.go"}`},
+		{SessionID: "s1", ToolName: "Bash", Category: "Bash",
+			InputJSON: `{"command":"ls"}`},
+	}
+	insertMessages(t, d, m)
+
+	calls := 0
+	extract := func(tc ToolCall, cwd, repoRoot string) []ToolFile {
+		calls++
+		if cwd != "/home/u/app" {
+			t.Errorf("extract got cwd %q, want the session's", cwd)
+		}
+		if tc.ToolName != "Read" {
+			return nil
+		}
+		return []ToolFile{{Path: "main.go", Action: "read"}}
+	}
+	n, err := d.BackfillToolFiles(extract)
+	requireNoError(t, err, "BackfillToolFiles")
+	if n != 1 || calls != 2 {
+		t.Errorf("indexed %d of %d calls, want 1 of 2", n, calls)
+	}
+	got, err := d.FileSessions(ctx, "main.go", "", 10)
+	requireNoError(t, err, "FileSessions")
+	if len(got) != 1 || got[0].Reads != 1 {
+		t.Errorf("backfilled sessions = %+v", got)
+	}
+
+	// The index is only rebuilt once.
+	n, err = d.BackfillToolFiles(extract)
+	requireNoError(t, err, "BackfillToolFiles again")
+	if n != 0 || calls != 2 {
+		t.Errorf("second backfill indexed %d, extract calls %d", n, calls)
+	}
+}
@@ -9,10 +9,14 @@ import (
 
 const (
 	selectMessageCols = `id, session_id, ordinal, role, content,
-		timestamp, has_thinking, has_tool_use, content_length`
+		timestamp, has_thinking, has_tool_use, content_length,
+		model, input_tokens, output_tokens,
+		cache_creation_input_tokens, cache_read_input_tokens`
 
 	insertMessageCols = `session_id, ordinal, role, content,
-		timestamp, has_thinking, has_tool_use, content_length`
+		timestamp, has_thinking, has_tool_use, content_length,
+		model, input_tokens, output_tokens,
+		cache_creation_input_tokens, cache_read_input_tokens`
 
 	// DefaultMessageLimit is the default number of messages returned.
 	DefaultMessageLimit = 100
@@ -37,6 +41,12 @@ type ToolCall struct {
 	ResultContentLength int    `json:"result_content_length,omitempty"`
 	ResultContent       string `json:"result_content,omitempty"`
 	SubagentSessionID   string `json:"subagent_session_id,omitempty"`
+	// IsError and ExitCode are nil until a result is recorded.
+	IsError  *bool `json:"is_error,omitempty"`
+	ExitCode *int  `json:"exit_code,omitempty"`
+	// Files are the files the call read or changed, indexed in
+	// tool_call_files when the call is inserted.
+	Files []ToolFile `json:"-"`
 }
 
 // ToolResult holds a tool_result content and length for pairing.
@@ -44,6 +54,8 @@ type ToolResult struct {
 	ToolUseID     string
 	ContentLength int
 	Content       string
+	IsError       bool
+	ExitCode      *int
 }
 
 // Message represents a row in the messages table.
@@ -59,6 +71,14 @@ type Message struct {
 	ContentLength int          `json:"content_length"`
 	ToolCalls     []ToolCall   `json:"tool_calls,omitempty"`
 	ToolResults   []ToolResult `json:"-"` // transient, for pairing
+
+	// Model and token usage of an assistant turn, where the
+	// agent records them.
+	Model                    string `json:"model,omitempty"`
+	InputTokens              int64  `json:"input_tokens,omitempty"`
+	OutputTokens             int64  `json:"output_tokens,omitempty"`
+	CacheCreationInputTokens int64  `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int64  `json:"cache_read_input_tokens,omitempty"`
 }
 
 // MinimapEntry is a lightweight message summary for minimap rendering.
@@ -202,7 +222,8 @@ func (db *DB) insertMessagesTx(
 ) ([]int64, error) {
 	stmt, err := tx.Prepare(fmt.Sprintf(`
 		INSERT INTO messages (%s)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, insertMessageCols))
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		insertMessageCols))
 	if err != nil {
 		return nil, fmt.Errorf("preparing insert: %w", err)
 	}
@@ -213,7 +234,9 @@ func (db *DB) insertMessagesTx(
 		res, err := stmt.Exec(
 			m.SessionID, m.Ordinal, m.Role, m.Content,
 			m.Timestamp, m.HasThinking, m.HasToolUse,
-			m.ContentLength,
+			m.ContentLength, m.Model, m.InputTokens,
+			m.OutputTokens, m.CacheCreationInputTokens,
+			m.CacheReadInputTokens,
 		)
 		if err != nil {
 			return nil, fmt.Errorf(
@@ -258,15 +281,16 @@ func insertToolCallsTx(
 			(message_id, session_id, tool_name, category,
 			 tool_use_id, input_json, skill_name,
 			 result_content_length, result_content,
-			 subagent_session_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+			 subagent_session_id, is_error, exit_code)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return fmt.Errorf("preparing tool_calls insert: %w", err)
 	}
 	defer stmt.Close()
 
+	var files []ToolFile
 	for _, tc := range calls {
-		if _, err := stmt.Exec(
+		res, err := stmt.Exec(
 			tc.MessageID, tc.SessionID,
 			tc.ToolName, tc.Category,
 			nilIfEmpty(tc.ToolUseID),
@@ -275,13 +299,27 @@ func insertToolCallsTx(
 			nilIfZero(tc.ResultContentLength),
 			nilIfEmpty(tc.ResultContent),
 			nilIfEmpty(tc.SubagentSessionID),
-		); err != nil {
+			tc.IsError, tc.ExitCode,
+		)
+		if err != nil {
 			return fmt.Errorf(
 				"inserting tool_call %q: %w", tc.ToolName, err,
 			)
 		}
+		if len(tc.Files) == 0 {
+			continue
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("reading tool_call id: %w", err)
+		}
+		for _, f := range tc.Files {
+			f.ToolCallID = id
+			f.SessionID = tc.SessionID
+			files = append(files, f)
+		}
 	}
-	return nil
+	return insertToolFilesTx(tx, files)
 }
 
 // InsertMessages batch-inserts messages for a session.
@@ -311,6 +349,63 @@ func (db *DB) InsertMessages(msgs []Message) error {
 	return tx.Commit()
 }
 
+// AppendMessages inserts messages appended to a session and,
+// in the same transaction, records results for tool calls that
+// were stored by an earlier write, matched by tool_use_id.
+func (db *DB) AppendMessages(
+	sessionID string, msgs []Message, results []ToolResult,
+) error {
+	if len(msgs) == 0 && len(results) == 0 {
+		return nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if len(msgs) > 0 {
+		ids, err := db.insertMessagesTx(tx, msgs)
+		if err != nil {
+			return err
+		}
+		toolCalls := resolveToolCalls(msgs, ids)
+		if err := insertToolCallsTx(tx, toolCalls); err != nil {
+			return err
+		}
+	}
+
+	if len(results) > 0 {
+		stmt, err := tx.Prepare(`
+			UPDATE tool_calls
+			SET result_content_length = ?, result_content = ?,
+				is_error = ?, exit_code = ?
+			WHERE session_id = ? AND tool_use_id = ?`)
+		if err != nil {
+			return fmt.Errorf("preparing tool result update: %w", err)
+		}
+		defer stmt.Close()
+		for _, tr := range results {
+			if _, err := stmt.Exec(
+				nilIfZero(tr.ContentLength),
+				nilIfEmpty(tr.Content),
+				tr.IsError, tr.ExitCode,
+				sessionID, tr.ToolUseID,
+			); err != nil {
+				return fmt.Errorf(
+					"updating tool result %q: %w",
+					tr.ToolUseID, err,
+				)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
 // MaxOrdinal returns the highest ordinal for a session,
 // or -1 if the session has no messages.
 func (db *DB) MaxOrdinal(sessionID string) int {
@@ -326,6 +421,18 @@ func (db *DB) MaxOrdinal(sessionID string) int {
 	return int(n.Int64)
 }
 
+// NeedsReparse reports whether a migration asked for the
+// session's messages to be replaced from a fresh parse, which
+// ReplaceSessionMessages does.
+func (db *DB) NeedsReparse(sessionID string) bool {
+	var v bool
+	err := db.reader.QueryRow(
+		"SELECT needs_reparse FROM sessions WHERE id = ?",
+		sessionID,
+	).Scan(&v)
+	return err == nil && v
+}
+
 // DeleteSessionMessages removes all messages for a session.
 func (db *DB) DeleteSessionMessages(sessionID string) error {
 	db.mu.Lock()
@@ -374,6 +481,12 @@ func (db *DB) ReplaceSessionMessages(
 		}
 	}
 
+	if _, err := tx.Exec(
+		"UPDATE sessions SET needs_reparse = 0 WHERE id = ?",
+		sessionID,
+	); err != nil {
+		return fmt.Errorf("clearing reparse flag: %w", err)
+	}
 	return tx.Commit()
 }
 
@@ -425,7 +538,7 @@ func (db *DB) attachToolCallsBatch(
 		SELECT message_id, session_id, tool_name, category,
 			tool_use_id, input_json, skill_name,
 			result_content_length, result_content,
-			subagent_session_id
+			subagent_session_id, is_error, exit_code
 		FROM tool_calls
 		WHERE message_id IN (%s)
 		ORDER BY id`,
@@ -441,13 +554,14 @@ func (db *DB) attachToolCallsBatch(
 		var tc ToolCall
 		var toolUseID, inputJSON, skillName sql.NullString
 		var resultContent, subagentSessionID sql.NullString
-		var resultLen sql.NullInt64
+		var resultLen, exitCode sql.NullInt64
+		var isError sql.NullBool
 		if err := rows.Scan(
 			&tc.MessageID, &tc.SessionID,
 			&tc.ToolName, &tc.Category,
 			&toolUseID, &inputJSON, &skillName,
 			&resultLen, &resultContent,
-			&subagentSessionID,
+			&subagentSessionID, &isError, &exitCode,
 		); err != nil {
 			return fmt.Errorf("scanning tool_call: %w", err)
 		}
@@ -469,6 +583,13 @@ func (db *DB) attachToolCallsBatch(
 		if subagentSessionID.Valid {
 			tc.SubagentSessionID = subagentSessionID.String
 		}
+		if isError.Valid {
+			tc.IsError = &isError.Bool
+		}
+		if exitCode.Valid {
+			n := int(exitCode.Int64)
+			tc.ExitCode = &n
+		}
 
 		if idx, ok := idToIdx[tc.MessageID]; ok {
 			msgs[idx].ToolCalls = append(
@@ -479,15 +600,23 @@ func (db *DB) attachToolCallsBatch(
 	return rows.Err()
 }
 
+// scanMessage reads the selectMessageCols of one row.
+func scanMessage(row interface{ Scan(...any) error }) (Message, error) {
+	var m Message
+	err := row.Scan(
+		&m.ID, &m.SessionID, &m.Ordinal, &m.Role,
+		&m.Content, &m.Timestamp,
+		&m.HasThinking, &m.HasToolUse, &m.ContentLength,
+		&m.Model, &m.InputTokens, &m.OutputTokens,
+		&m.CacheCreationInputTokens, &m.CacheReadInputTokens,
+	)
+	return m, err
+}
+
 func scanMessages(rows *sql.Rows) ([]Message, error) {
 	var msgs []Message
 	for rows.Next() {
-		var m Message
-		err := rows.Scan(
-			&m.ID, &m.SessionID, &m.Ordinal, &m.Role,
-			&m.Content, &m.Timestamp,
-			&m.HasThinking, &m.HasToolUse, &m.ContentLength,
-		)
+		m, err := scanMessage(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scanning message: %w", err)
 		}
@@ -516,12 +645,7 @@ func (db *DB) GetMessageByOrdinal(
 		WHERE session_id = ? AND ordinal = ?`, selectMessageCols),
 		sessionID, ordinal)
 
-	var m Message
-	err := row.Scan(
-		&m.ID, &m.SessionID, &m.Ordinal, &m.Role,
-		&m.Content, &m.Timestamp,
-		&m.HasThinking, &m.HasToolUse, &m.ContentLength,
-	)
+	m, err := scanMessage(row)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -557,6 +681,9 @@ func resolveToolCalls(
 				ResultContentLength: tc.ResultContentLength,
 				ResultContent:       tc.ResultContent,
 				SubagentSessionID:   tc.SubagentSessionID,
+				IsError:             tc.IsError,
+				ExitCode:            tc.ExitCode,
+				Files:               tc.Files,
 			})
 		}
 	}
@@ -0,0 +1,328 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// baseVersion is the schema version of databases created before
+// versioned migrations. Older databases cannot be migrated in
+// place and are rebuilt from the source files.
+const baseVersion = 3
+
+// migration upgrades the schema from version-1 to version.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+	// reparse makes the next sync parse every session file
+	// again and replace the session's stored messages, for
+	// migrations that add data the stored rows lack. Existing
+	// rows are kept until they are replaced.
+	reparse bool
+}
+
+// migrations lists the schema changes since baseVersion in
+// order. A migration only needs to alter existing tables: new
+// tables and indexes come from schema.sql, which runs after the
+// migrations.
+var migrations = []migration{
+	{
+		version: 4,
+		name:    "tool call status and exit code",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "tool_calls",
+				"is_error INTEGER",
+				"exit_code INTEGER",
+			)
+		},
+		reparse: true,
+	},
+	{
+		version: 5,
+		name:    "message model and token usage",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "messages",
+				"model TEXT NOT NULL DEFAULT ''",
+				"input_tokens INTEGER NOT NULL DEFAULT 0",
+				"output_tokens INTEGER NOT NULL DEFAULT 0",
+				"cache_creation_input_tokens INTEGER NOT NULL DEFAULT 0",
+				"cache_read_input_tokens INTEGER NOT NULL DEFAULT 0",
+			)
+		},
+		reparse: true,
+	},
+	{
+		version: 6,
+		name:    "session working directory and git branch",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "sessions",
+				"cwd TEXT NOT NULL DEFAULT ''",
+				"git_branch TEXT NOT NULL DEFAULT ''",
+				"repo_root TEXT NOT NULL DEFAULT ''",
+			)
+		},
+		reparse: true,
+	},
+	{
+		// Databases migrated to 4-6 were reparsed by appending
+		// messages only, which left the new columns empty.
+		version: 7,
+		name:    "session reparse flag",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "sessions",
+				"needs_reparse INTEGER NOT NULL DEFAULT 0",
+			)
+		},
+		reparse: true,
+	},
+}
+
+func addColumns(tx *sql.Tx, table string, cols ...string) error {
+	for _, col := range cols {
+		if _, err := tx.Exec(
+			"ALTER TABLE " + table + " ADD COLUMN " + col,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latestVersion is the schema version this build creates.
+func latestVersion() int {
+	if len(migrations) == 0 {
+		return baseVersion
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// MigrationStep is one pending migration.
+type MigrationStep struct {
+	Version int
+	Name    string
+	Reparse bool
+}
+
+// MigrationPlan describes what Open will do to bring a database
+// up to date.
+type MigrationPlan struct {
+	From int // current schema version, 0 if unknown
+	To   int
+	// New is set when there is no database yet.
+	New bool
+	// Rebuild is set for databases that predate versioned
+	// migrations. They are dropped and re-synced; tags, stars
+	// and notes are carried over.
+	Rebuild bool
+	Steps   []MigrationStep
+	// Backup is where the database is copied before it is
+	// changed.
+	Backup string
+}
+
+// Pending reports whether opening the database will change its
+// schema.
+func (p MigrationPlan) Pending() bool {
+	return p.Rebuild || len(p.Steps) > 0
+}
+
+// Reparse reports whether the migration makes the next sync
+// parse every session file again.
+func (p MigrationPlan) Reparse() bool {
+	if p.Rebuild {
+		return true
+	}
+	for _, s := range p.Steps {
+		if s.Reparse {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanMigrations inspects the database at path, without changing
+// it, and returns the migrations Open would apply.
+func PlanMigrations(path string) (MigrationPlan, error) {
+	plan := MigrationPlan{To: latestVersion()}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			plan.New = true
+			return plan, nil
+		}
+		return plan, fmt.Errorf("checking database file: %w", err)
+	}
+
+	rebuild, err := needsRebuild(path)
+	if err != nil {
+		return plan, err
+	}
+
+	conn, err := sql.Open("sqlite3", makeDSN(path, true))
+	if err != nil {
+		return plan, fmt.Errorf("probing schema: %w", err)
+	}
+	defer conn.Close()
+
+	if rebuild {
+		// Old enough databases have no stats table; their
+		// version is reported as 0.
+		plan.From, _ = schemaVersion(conn)
+		plan.Rebuild = true
+		plan.Backup = backupPath(path, plan.From)
+		return plan, nil
+	}
+	if plan.From, err = schemaVersion(conn); err != nil {
+		return plan, err
+	}
+
+	switch {
+	case plan.From > plan.To:
+		return plan, fmt.Errorf(
+			"database schema version %d is newer than "+
+				"this build supports (%d)", plan.From, plan.To,
+		)
+	default:
+		for _, m := range migrations {
+			if m.version > plan.From {
+				plan.Steps = append(plan.Steps, MigrationStep{
+					Version: m.version,
+					Name:    m.name,
+					Reparse: m.reparse,
+				})
+			}
+		}
+	}
+	if plan.Pending() {
+		plan.Backup = backupPath(path, plan.From)
+	}
+	return plan, nil
+}
+
+// queryRower is satisfied by *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// schemaVersion returns the stored schema version, or 0 for
+// databases without one.
+func schemaVersion(q queryRower) (int, error) {
+	var v int
+	err := q.QueryRow(
+		`SELECT COALESCE(
+			(SELECT value FROM stats WHERE key = 'schema_version'),
+			0)`,
+	).Scan(&v)
+	if err != nil {
+		return 0, fmt.Errorf("probing schema version: %w", err)
+	}
+	return v, nil
+}
+
+func setSchemaVersion(tx *sql.Tx, v int) error {
+	_, err := tx.Exec(
+		`INSERT OR REPLACE INTO stats (key, value)
+		 VALUES ('schema_version', ?)`, v,
+	)
+	if err != nil {
+		return fmt.Errorf("setting schema version: %w", err)
+	}
+	return nil
+}
+
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// backupDatabase writes a consistent copy of the database at
+// path to dst, replacing any earlier copy.
+func backupDatabase(path, dst string) error {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing old backup: %w", err)
+	}
+	conn, err := sql.Open("sqlite3", makeDSN(path, true))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.Exec("VACUUM INTO ?", dst); err != nil {
+		return fmt.Errorf("writing %s: %w", dst, err)
+	}
+	return nil
+}
+
+// migrate applies pending migrations in a single transaction.
+// The caller must hold db.mu.
+func (db *DB) migrate() error {
+	from, err := schemaVersion(db.writer)
+	if err != nil {
+		return err
+	}
+	to := latestVersion()
+	if from >= to {
+		return nil
+	}
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Take the write lock before re-reading the version so a
+	// concurrent Open applies each migration only once.
+	if _, err := tx.Exec(
+		`UPDATE stats SET value = value
+		 WHERE key = 'schema_version'`,
+	); err != nil {
+		return fmt.Errorf("locking schema: %w", err)
+	}
+	if from, err = schemaVersion(tx); err != nil {
+		return err
+	}
+
+	reparse := false
+	for _, m := range migrations {
+		if m.version <= from {
+			continue
+		}
+		if err := m.up(tx); err != nil {
+			return fmt.Errorf(
+				"migration %d (%s): %w", m.version, m.name, err,
+			)
+		}
+		reparse = reparse || m.reparse
+	}
+	if reparse {
+		for _, stmt := range []string{
+			"UPDATE sessions SET file_mtime = 0, needs_reparse = 1",
+			"DELETE FROM skipped_files",
+		} {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("scheduling reparse: %w", err)
+			}
+		}
+	}
+	if err := setSchemaVersion(tx, to); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// createSchema creates a new database at the current schema
+// version. The caller must hold db.mu.
+func (db *DB) createSchema() error {
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(schemaSQL); err != nil {
+		return err
+	}
+	if err := setSchemaVersion(tx, latestVersion()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
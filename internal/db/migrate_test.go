@@ -0,0 +1,280 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withMigrations replaces the migration list for one test.
+func withMigrations(t *testing.T, ms ...migration) {
+	t.Helper()
+	saved := migrations
+	migrations = ms
+	t.Cleanup(func() { migrations = saved })
+}
+
+func addColumn(table, col string) func(*sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			"ALTER TABLE " + table + " ADD COLUMN " + col + " TEXT",
+		)
+		return err
+	}
+}
+
+// openAtBase creates a database at baseVersion with one synced
+// session, one insight and one skipped file.
+func openAtBase(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "migrate.db")
+	d, err := Open(path)
+	requireNoError(t, err, "Open")
+	insertSession(t, d, "s1", "proj", func(s *Session) {
+		s.FilePath = Ptr("/tmp/s1.jsonl")
+		s.FileMtime = Ptr(int64(1234))
+	})
+	_, err = d.InsertInsight(Insight{
+		Type: "daily_activity", DateFrom: "2024-01-01",
+		DateTo: "2024-01-01", Agent: "claude", Content: "hi",
+	})
+	requireNoError(t, err, "InsertInsight")
+	requireNoError(t, d.ReplaceSkippedFiles(
+		map[string]int64{"/tmp/bad.jsonl": 1},
+	), "ReplaceSkippedFiles")
+	requireNoError(t, d.Close(), "Close")
+	return path
+}
+
+func TestPlanMigrations(t *testing.T) {
+	withMigrations(t)
+	dir := t.TempDir()
+
+	t.Run("New", func(t *testing.T) {
+		plan, err := PlanMigrations(filepath.Join(dir, "none.db"))
+		requireNoError(t, err, "PlanMigrations")
+		if !plan.New || plan.Pending() || plan.To != baseVersion {
+			t.Errorf("plan = %+v", plan)
+		}
+	})
+
+	path := openAtBase(t)
+
+	t.Run("UpToDate", func(t *testing.T) {
+		plan, err := PlanMigrations(path)
+		requireNoError(t, err, "PlanMigrations")
+		if plan.Pending() || plan.From != baseVersion ||
+			plan.Backup != "" {
+			t.Errorf("plan = %+v", plan)
+		}
+	})
+
+	t.Run("Pending", func(t *testing.T) {
+		withMigrations(t,
+			migration{version: 4, name: "four", up: addColumn("sessions", "a")},
+			migration{version: 5, name: "five", up: addColumn("sessions", "b"), reparse: true},
+		)
+		plan, err := PlanMigrations(path)
+		requireNoError(t, err, "PlanMigrations")
+		if len(plan.Steps) != 2 || plan.To != 5 || !plan.Reparse() ||
+			plan.Backup != path+".v3.bak" {
+			t.Errorf("plan = %+v", plan)
+		}
+	})
+
+	t.Run("NewerThanBuild", func(t *testing.T) {
+		withMigrations(t)
+		newer := openAtBase(t)
+		conn, err := sql.Open("sqlite3", newer)
+		requireNoError(t, err, "sql.Open")
+		_, err = conn.Exec(
+			"UPDATE stats SET value = 99 WHERE key = 'schema_version'",
+		)
+		conn.Close()
+		requireNoError(t, err, "bump version")
+
+		_, err = PlanMigrations(newer)
+		requireErrContains(t, err, "newer than this build")
+		_, err = Open(newer)
+		requireErrContains(t, err, "newer than this build")
+	})
+}
+
+func TestMigrateInPlace(t *testing.T) {
+	withMigrations(t)
+	path := openAtBase(t)
+
+	withMigrations(t,
+		migration{version: 4, name: "add a", up: addColumn("sessions", "mig_a")},
+		migration{version: 5, name: "add b", up: addColumn("messages", "mig_b"), reparse: true},
+	)
+	d, err := Open(path)
+	requireNoError(t, err, "Open")
+	defer d.Close()
+	ctx := context.Background()
+
+	v, err := schemaVersion(d.reader)
+	requireNoError(t, err, "schemaVersion")
+	if v != 5 {
+		t.Errorf("schema version = %d, want 5", v)
+	}
+	for _, q := range []string{
+		"SELECT mig_a FROM sessions", "SELECT mig_b FROM messages",
+	} {
+		if _, err := d.reader.Exec(q); err != nil {
+			t.Errorf("%s: %v", q, err)
+		}
+	}
+
+	// Data survives; the reparse migration forces a resync.
+	s, err := d.GetSessionFull(ctx, "s1")
+	requireNoError(t, err, "GetSessionFull")
+	if s == nil || s.FileMtime == nil || *s.FileMtime != 0 {
+		t.Errorf("session after migration = %+v", s)
+	}
+	insights, err := d.ListInsights(ctx, InsightFilter{})
+	requireNoError(t, err, "ListInsights")
+	if len(insights) != 1 {
+		t.Errorf("insights after migration = %d, want 1", len(insights))
+	}
+	skipped, err := d.LoadSkippedFiles()
+	requireNoError(t, err, "LoadSkippedFiles")
+	if len(skipped) != 0 {
+		t.Errorf("skipped files after reparse = %v", skipped)
+	}
+
+	// The backup holds the pre-migration database.
+	backup, err := sql.Open("sqlite3", path+".v3.bak")
+	requireNoError(t, err, "open backup")
+	defer backup.Close()
+	bv, err := schemaVersion(backup)
+	requireNoError(t, err, "backup version")
+	if bv != 3 {
+		t.Errorf("backup version = %d, want 3", bv)
+	}
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	withMigrations(t)
+	path := openAtBase(t)
+
+	boom := errors.New("boom")
+	withMigrations(t,
+		migration{version: 4, name: "add a", up: addColumn("sessions", "mig_a")},
+		migration{version: 5, name: "fails", up: func(*sql.Tx) error {
+			return boom
+		}},
+	)
+	_, err := Open(path)
+	if !errors.Is(err, boom) {
+		t.Fatalf("Open err = %v, want boom", err)
+	}
+	if !strings.Contains(err.Error(), "migration 5 (fails)") {
+		t.Errorf("error does not name the migration: %v", err)
+	}
+
+	withMigrations(t)
+	d, err := Open(path)
+	requireNoError(t, err, "reopen")
+	defer d.Close()
+	v, err := schemaVersion(d.reader)
+	requireNoError(t, err, "schemaVersion")
+	if v != baseVersion {
+		t.Errorf("schema version = %d, want %d", v, baseVersion)
+	}
+	if _, err := d.reader.Exec("SELECT mig_a FROM sessions"); err == nil {
+		t.Error("column from rolled-back migration exists")
+	}
+	s, err := d.GetSessionFull(context.Background(), "s1")
+	requireNoError(t, err, "GetSessionFull")
+	if s == nil || *s.FileMtime != 1234 {
+		t.Errorf("session changed by failed migration: %+v", s)
+	}
+}
+
+func TestRebuildTakesBackup(t *testing.T) {
+	withMigrations(t)
+	path := openAtBase(t)
+	conn, err := sql.Open("sqlite3", path)
+	requireNoError(t, err, "sql.Open")
+	_, err = conn.Exec(
+		"UPDATE stats SET value = 1 WHERE key = 'schema_version'",
+	)
+	conn.Close()
+	requireNoError(t, err, "downgrade version")
+
+	plan, err := PlanMigrations(path)
+	requireNoError(t, err, "PlanMigrations")
+	if !plan.Rebuild || !plan.Reparse() || plan.From != 1 {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	d, err := Open(path)
+	requireNoError(t, err, "Open")
+	defer d.Close()
+	requireSessionGone(t, d, "s1")
+	if _, err := os.Stat(path + ".v1.bak"); err != nil {
+		t.Errorf("backup missing: %v", err)
+	}
+}
+
+// baseVersionDrops lists the columns added since baseVersion,
+// which the migrations must restore.
+var baseVersionDrops = []struct{ table, col string }{
+	{"tool_calls", "is_error"},
+	{"tool_calls", "exit_code"},
+	{"messages", "model"},
+	{"messages", "input_tokens"},
+	{"messages", "output_tokens"},
+	{"messages", "cache_creation_input_tokens"},
+	{"messages", "cache_read_input_tokens"},
+	{"sessions", "cwd"},
+	{"sessions", "git_branch"},
+	{"sessions", "repo_root"},
+	{"sessions", "needs_reparse"},
+}
+
+func TestMigrateFromBaseVersion(t *testing.T) {
+	real := migrations
+	withMigrations(t)
+	path := openAtBase(t)
+
+	// Strip the newer columns to get the tables as they were at
+	// baseVersion.
+	conn, err := sql.Open("sqlite3", path)
+	requireNoError(t, err, "sql.Open")
+	for _, d := range baseVersionDrops {
+		_, err = conn.Exec(
+			"ALTER TABLE " + d.table + " DROP COLUMN " + d.col,
+		)
+		requireNoError(t, err, "drop "+d.col)
+	}
+	conn.Close()
+
+	withMigrations(t, real...)
+	d, err := Open(path)
+	requireNoError(t, err, "Open")
+	defer d.Close()
+
+	v, err := schemaVersion(d.reader)
+	requireNoError(t, err, "schemaVersion")
+	if v != latestVersion() {
+		t.Errorf("schema version = %d, want %d", v, latestVersion())
+	}
+	for _, c := range baseVersionDrops {
+		if _, err := d.reader.Exec(
+			"SELECT " + c.col + " FROM " + c.table,
+		); err != nil {
+			t.Errorf("%s.%s missing: %v", c.table, c.col, err)
+		}
+	}
+	s, err := d.GetSessionFull(context.Background(), "s1")
+	requireNoError(t, err, "GetSessionFull")
+	if s == nil || *s.FileMtime != 0 {
+		t.Errorf("session not scheduled for reparse: %+v", s)
+	}
+}
@@ -21,6 +21,10 @@ CREATE TABLE IF NOT EXISTS sessions (
     file_hash   TEXT,
     parent_session_id TEXT,
     relationship_type TEXT NOT NULL DEFAULT '',
+    cwd         TEXT NOT NULL DEFAULT '',
+    git_branch  TEXT NOT NULL DEFAULT '',
+    repo_root   TEXT NOT NULL DEFAULT '',
+    needs_reparse INTEGER NOT NULL DEFAULT 0,
     created_at  TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
 );
 
@@ -35,6 +39,11 @@ CREATE TABLE IF NOT EXISTS messages (
     has_thinking   INTEGER NOT NULL DEFAULT 0,
     has_tool_use   INTEGER NOT NULL DEFAULT 0,
     content_length INTEGER NOT NULL DEFAULT 0,
+    model          TEXT NOT NULL DEFAULT '',
+    input_tokens   INTEGER NOT NULL DEFAULT 0,
+    output_tokens  INTEGER NOT NULL DEFAULT 0,
+    cache_creation_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_read_input_tokens     INTEGER NOT NULL DEFAULT 0,
     UNIQUE(session_id, ordinal)
 );
 
@@ -46,7 +55,6 @@ CREATE TABLE IF NOT EXISTS stats (
 
 INSERT OR IGNORE INTO stats (key, value) VALUES ('session_count', 0);
 INSERT OR IGNORE INTO stats (key, value) VALUES ('message_count', 0);
-INSERT OR IGNORE INTO stats (key, value) VALUES ('schema_version', 3);
 
 -- Triggers for stats maintenance
 CREATE TRIGGER IF NOT EXISTS sessions_insert_stats AFTER INSERT ON sessions BEGIN
@@ -109,7 +117,9 @@ CREATE TABLE IF NOT EXISTS tool_calls (
     skill_name  TEXT,
     result_content_length INTEGER,
     result_content TEXT,
-    subagent_session_id TEXT
+    subagent_session_id TEXT,
+    is_error    INTEGER,
+    exit_code   INTEGER
 );
 
 CREATE INDEX IF NOT EXISTS idx_tool_calls_session
@@ -120,6 +130,58 @@ CREATE INDEX IF NOT EXISTS idx_tool_calls_skill
     ON tool_calls(skill_name)
     WHERE skill_name IS NOT NULL;
 
+-- Files read or changed by tool calls. Relative paths are
+-- resolved against the session's cwd. Path is relative to
+-- repo_root when the file is inside the session's git
+-- repository, and as the agent gave it otherwise.
+CREATE TABLE IF NOT EXISTS tool_call_files (
+    tool_call_id INTEGER NOT NULL
+        REFERENCES tool_calls(id) ON DELETE CASCADE,
+    session_id   TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    path         TEXT NOT NULL,
+    repo_root    TEXT NOT NULL DEFAULT '',
+    action       TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_call_files_path
+    ON tool_call_files(path);
+CREATE INDEX IF NOT EXISTS idx_tool_call_files_session
+    ON tool_call_files(session_id);
+CREATE INDEX IF NOT EXISTS idx_tool_call_files_call
+    ON tool_call_files(tool_call_id);
+
+-- Commits of the local git repository linked to the sessions
+-- that edited their files, found by the optional correlator.
+-- files lists the paths, one per line, that both the commit and
+-- the session changed.
+CREATE TABLE IF NOT EXISTS session_commits (
+    session_id  TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    sha         TEXT NOT NULL,
+    repo_root   TEXT NOT NULL,
+    author      TEXT NOT NULL,
+    email       TEXT NOT NULL,
+    authored_at TEXT NOT NULL,
+    subject     TEXT NOT NULL,
+    files       TEXT NOT NULL,
+    PRIMARY KEY (session_id, sha)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_commits_sha
+    ON session_commits(sha);
+
+-- Sessions the correlator has looked at. ended_at is the end of
+-- the session when it was scanned and complete is set once the
+-- scan covered the whole commit window, so a session is scanned
+-- again when it grows or while its window is still open.
+CREATE TABLE IF NOT EXISTS commit_scans (
+    session_id TEXT PRIMARY KEY
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    ended_at   TEXT,
+    complete   INTEGER NOT NULL DEFAULT 0
+);
+
 -- Insights table for AI-generated activity insights
 CREATE TABLE IF NOT EXISTS insights (
     id          INTEGER PRIMARY KEY,
@@ -148,3 +210,91 @@ CREATE TABLE IF NOT EXISTS skipped_files (
     file_path  TEXT PRIMARY KEY,
     file_mtime INTEGER NOT NULL
 );
+
+-- User-owned annotations. A NULL ordinal annotates the session
+-- itself; otherwise the message with that ordinal. These tables
+-- have no foreign keys so they outlive the session and message
+-- rows, which are replaced on resync, and they are carried over
+-- when the database is rebuilt.
+CREATE TABLE IF NOT EXISTS session_tags (
+    session_id TEXT NOT NULL,
+    ordinal    INTEGER,
+    tag        TEXT NOT NULL,
+    created_at TEXT NOT NULL
+        DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_tags_unique
+    ON session_tags(session_id, ifnull(ordinal, -1), tag);
+CREATE INDEX IF NOT EXISTS idx_session_tags_tag
+    ON session_tags(tag);
+
+CREATE TABLE IF NOT EXISTS session_stars (
+    session_id TEXT NOT NULL,
+    ordinal    INTEGER,
+    created_at TEXT NOT NULL
+        DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_stars_unique
+    ON session_stars(session_id, ifnull(ordinal, -1));
+
+CREATE TABLE IF NOT EXISTS session_notes (
+    id         INTEGER PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    ordinal    INTEGER,
+    body       TEXT NOT NULL,
+    created_at TEXT NOT NULL
+        DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+    updated_at TEXT NOT NULL
+        DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_notes_session
+    ON session_notes(session_id, ordinal);
+
+-- Secrets found by `agentsview audit secrets`. Findings go with
+-- their message; ids are never reused, so a run can report just
+-- the findings it added. The audit scans messages above the
+-- watermark in stats; the triggers lower it when a scanned
+-- message's tool result changes, or when deleting the newest
+-- messages frees their ids for reuse, so those messages are
+-- scanned again.
+CREATE TABLE IF NOT EXISTS secret_findings (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id INTEGER NOT NULL
+        REFERENCES messages(id) ON DELETE CASCADE,
+    session_id TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    ordinal    INTEGER NOT NULL,
+    field      TEXT NOT NULL,
+    tool_call  INTEGER,
+    rule       TEXT NOT NULL,
+    start_pos  INTEGER NOT NULL,
+    end_pos    INTEGER NOT NULL,
+    preview    TEXT NOT NULL,
+    found_at   TEXT NOT NULL
+        DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_secret_findings_message
+    ON secret_findings(message_id);
+CREATE INDEX IF NOT EXISTS idx_secret_findings_session
+    ON secret_findings(session_id);
+
+INSERT OR IGNORE INTO stats (key, value)
+    VALUES ('secret_audit_watermark', 0);
+
+CREATE TRIGGER IF NOT EXISTS messages_delete_secret_audit
+AFTER DELETE ON messages
+WHEN OLD.id > (SELECT COALESCE(MAX(id), 0) FROM messages) BEGIN
+    UPDATE stats
+    SET value = MIN(value, (SELECT COALESCE(MAX(id), 0) FROM messages))
+    WHERE key = 'secret_audit_watermark';
+END;
+
+CREATE TRIGGER IF NOT EXISTS tool_calls_result_secret_audit
+AFTER UPDATE OF result_content ON tool_calls BEGIN
+    UPDATE stats SET value = MIN(value, NEW.message_id - 1)
+    WHERE key = 'secret_audit_watermark';
+END;
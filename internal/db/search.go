@@ -10,6 +10,9 @@ const (
 	DefaultSearchLimit = 50
 	MaxSearchLimit     = 500
 	snippetTokenLength = 32
+	// filterSnippetLength is the snippet length in characters
+	// for filter-only queries, which have no FTS snippet.
+	filterSnippetLength = 200
 )
 
 // SearchResult holds a message match with session context.
@@ -25,8 +28,12 @@ type SearchResult struct {
 
 // SearchFilter specifies search parameters.
 type SearchFilter struct {
-	Query   string
+	Query   string // structured query, see ParseSearchQuery
 	Project string
+	// Tag and Starred match messages that carry the tag or star
+	// themselves or belong to a session that does.
+	Tag     string
+	Starred bool
 	Cursor  int // offset for pagination
 	Limit   int
 }
@@ -37,7 +44,10 @@ type SearchPage struct {
 	NextCursor int            `json:"next_cursor,omitempty"`
 }
 
-// Search performs FTS5 full-text search across messages.
+// Search finds messages matching a structured query (see
+// ParseSearchQuery). Queries with free-text terms are ranked by
+// FTS5; filter-only queries return the newest messages first.
+// A malformed query returns a *QueryError.
 func (db *DB) Search(
 	ctx context.Context, f SearchFilter,
 ) (SearchPage, error) {
@@ -45,29 +55,64 @@ func (db *DB) Search(
 		f.Limit = DefaultSearchLimit
 	}
 
-	whereClauses := []string{"messages_fts MATCH ?"}
-	args := []any{f.Query}
+	q, err := ParseSearchQuery(f.Query)
+	if err != nil {
+		return SearchPage{}, err
+	}
 
+	whereClauses, args := q.predicates()
 	if f.Project != "" {
 		whereClauses = append(whereClauses, "s.project = ?")
 		args = append(args, f.Project)
 	}
+	if f.Tag != "" {
+		whereClauses = append(whereClauses, `EXISTS (
+			SELECT 1 FROM session_tags st
+			WHERE st.session_id = m.session_id AND st.tag = ?
+			AND (st.ordinal IS NULL OR st.ordinal = m.ordinal))`)
+		args = append(args, tagKey(f.Tag))
+	}
+	if f.Starred {
+		whereClauses = append(whereClauses, `EXISTS (
+			SELECT 1 FROM session_stars ss
+			WHERE ss.session_id = m.session_id
+			AND (ss.ordinal IS NULL OR ss.ordinal = m.ordinal))`)
+	}
 
-	query := fmt.Sprintf(`
-		SELECT m.session_id, s.project, m.ordinal, m.role,
-			m.timestamp,
-			snippet(messages_fts, 0, '<mark>', '</mark>',
-				'...', %d) as snippet,
-			rank
-		FROM messages_fts
-		JOIN messages m ON messages_fts.rowid = m.id
-		JOIN sessions s ON m.session_id = s.id
-		WHERE %s
-		ORDER BY rank
-		LIMIT ? OFFSET ?`,
-		snippetTokenLength,
-		strings.Join(whereClauses, " AND "),
-	)
+	var query string
+	if match := q.matchExpr(); match != "" {
+		whereClauses = append(
+			[]string{"messages_fts MATCH ?"}, whereClauses...,
+		)
+		args = append([]any{match}, args...)
+		query = fmt.Sprintf(`
+			SELECT m.session_id, s.project, m.ordinal, m.role,
+				m.timestamp,
+				snippet(messages_fts, 0, '<mark>', '</mark>',
+					'...', %d) as snippet,
+				rank
+			FROM messages_fts
+			JOIN messages m ON messages_fts.rowid = m.id
+			JOIN sessions s ON m.session_id = s.id
+			WHERE %s
+			ORDER BY rank
+			LIMIT ? OFFSET ?`,
+			snippetTokenLength,
+			strings.Join(whereClauses, " AND "),
+		)
+	} else {
+		query = fmt.Sprintf(`
+			SELECT m.session_id, s.project, m.ordinal, m.role,
+				m.timestamp, substr(m.content, 1, %d), 0
+			FROM messages m
+			JOIN sessions s ON m.session_id = s.id
+			WHERE %s
+			ORDER BY m.timestamp DESC, m.id DESC
+			LIMIT ? OFFSET ?`,
+			filterSnippetLength,
+			strings.Join(whereClauses, " AND "),
+		)
+	}
 	args = append(args, f.Limit+1, f.Cursor)
 
 	rows, err := db.reader.QueryContext(ctx, query, args...)
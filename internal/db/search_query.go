@@ -0,0 +1,425 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Search query syntax
+//
+// A query is a sequence of whitespace-separated items:
+//
+//	word           free text; adjacent words match as a phrase
+//	word*          prefix match
+//	"a phrase"     exact phrase
+//	-word          exclude messages matching a word or phrase
+//	field:value    filter; the value may be quoted
+//	-field:value   exclude messages matching a filter
+//
+// Fields are agent, project, machine, role, tool, after and
+// before. Repeating a field matches any of its values. after and
+// before take a date (YYYY-MM-DD) or RFC 3339 time and bound the
+// message timestamp: after is inclusive, before exclusive. tool
+// matches a tool name or category, ignoring case. Text that
+// contains a colon must be quoted to be searched for.
+
+// QueryError reports a malformed search query. Pos is the byte
+// offset in the query where the problem was found.
+type QueryError struct {
+	Pos int    `json:"position"`
+	Msg string `json:"message"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf(
+		"invalid query at position %d: %s", e.Pos, e.Msg,
+	)
+}
+
+// SearchTerm is a free-text part of a search query.
+type SearchTerm struct {
+	Text   string
+	Phrase bool // quoted, or several adjacent words
+	Prefix bool // trailing '*'
+	Negate bool
+}
+
+// FieldFilter is a field:value part of a search query.
+type FieldFilter struct {
+	Field  string
+	Value  string
+	Negate bool
+}
+
+// SearchQuery is a parsed search query.
+type SearchQuery struct {
+	Terms   []SearchTerm
+	Filters []FieldFilter
+}
+
+var searchFields = map[string]bool{
+	"agent":   true,
+	"project": true,
+	"machine": true,
+	"role":    true,
+	"tool":    true,
+	"after":   true,
+	"before":  true,
+}
+
+// ParseSearchQuery parses a structured search query. Errors are
+// returned as *QueryError.
+func ParseSearchQuery(s string) (SearchQuery, error) {
+	p := queryParser{s: s}
+	return p.parse()
+}
+
+type queryParser struct {
+	s   string
+	pos int
+	q   SearchQuery
+	run []string // adjacent bare words not yet added as a term
+}
+
+func (p *queryParser) errorf(
+	pos int, format string, args ...any,
+) error {
+	return &QueryError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) parse() (SearchQuery, error) {
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			break
+		}
+		if err := p.item(); err != nil {
+			return SearchQuery{}, err
+		}
+	}
+	p.flushRun()
+	if len(p.q.Terms) == 0 && len(p.q.Filters) == 0 {
+		return SearchQuery{}, p.errorf(len(p.s), "empty query")
+	}
+	return p.q, nil
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.s) && isQuerySpace(p.s[p.pos]) {
+		p.pos++
+	}
+}
+
+func isQuerySpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// item parses one whitespace-separated query item.
+func (p *queryParser) item() error {
+	start := p.pos
+	negate := false
+	if p.s[p.pos] == '-' {
+		negate = true
+		p.pos++
+		if p.pos >= len(p.s) || isQuerySpace(p.s[p.pos]) {
+			return p.errorf(start, "expected a term after '-'")
+		}
+	}
+
+	if p.s[p.pos] == '"' {
+		text, err := p.quoted()
+		if err != nil {
+			return err
+		}
+		p.flushRun()
+		p.q.Terms = append(p.q.Terms, SearchTerm{
+			Text: text, Phrase: true, Negate: negate,
+		})
+		return nil
+	}
+
+	wordStart := p.pos
+	word := p.bare()
+	if name, value, ok := strings.Cut(word, ":"); ok &&
+		isFieldName(name) {
+		return p.filter(start, wordStart, name, value, negate)
+	}
+	if p.pos < len(p.s) && p.s[p.pos] == '"' {
+		return p.errorf(p.pos, "unexpected '\"'")
+	}
+
+	prefix := strings.HasSuffix(word, "*")
+	if strings.TrimRight(word, "*") == "" {
+		return p.errorf(wordStart, "empty search term")
+	}
+	if negate || prefix {
+		p.flushRun()
+		p.q.Terms = append(p.q.Terms, SearchTerm{
+			Text:   strings.TrimRight(word, "*"),
+			Prefix: prefix,
+			Negate: negate,
+		})
+		return nil
+	}
+	p.run = append(p.run, word)
+	return nil
+}
+
+// bare reads up to the next space or quote.
+func (p *queryParser) bare() string {
+	start := p.pos
+	for p.pos < len(p.s) &&
+		!isQuerySpace(p.s[p.pos]) && p.s[p.pos] != '"' {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// quoted reads a double-quoted string starting at p.pos. A
+// doubled quote inside the string stands for one quote.
+func (p *queryParser) quoted() (string, error) {
+	open := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		if c != '"' {
+			b.WriteByte(c)
+			continue
+		}
+		if p.pos < len(p.s) && p.s[p.pos] == '"' {
+			b.WriteByte('"')
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.s) && !isQuerySpace(p.s[p.pos]) {
+			return "", p.errorf(
+				p.pos, "expected space after closing quote",
+			)
+		}
+		if strings.TrimSpace(b.String()) == "" {
+			return "", p.errorf(open, "empty phrase")
+		}
+		return b.String(), nil
+	}
+	return "", p.errorf(open, "unterminated quote")
+}
+
+func isFieldName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// filter parses the value of a field:value item. start is the
+// offset of the item (including any '-') and nameStart the
+// offset of the field name.
+func (p *queryParser) filter(
+	start, nameStart int, name, value string, negate bool,
+) error {
+	field := strings.ToLower(name)
+	if !searchFields[field] {
+		return p.errorf(nameStart,
+			"unknown field %q (quote the term to search for it)",
+			name)
+	}
+	valuePos := nameStart + len(name) + 1
+	if value == "" && p.pos < len(p.s) && p.s[p.pos] == '"' {
+		var err error
+		if value, err = p.quoted(); err != nil {
+			return err
+		}
+	} else if p.pos < len(p.s) && p.s[p.pos] == '"' {
+		return p.errorf(p.pos, "unexpected '\"'")
+	}
+	if value == "" {
+		return p.errorf(valuePos, "missing value for %s:", field)
+	}
+
+	switch field {
+	case "agent":
+		value = strings.ToLower(value)
+	case "role":
+		value = strings.ToLower(value)
+		if value != "user" && value != "assistant" {
+			return p.errorf(valuePos,
+				"role must be user or assistant, got %q", value)
+		}
+	case "after", "before":
+		if negate {
+			return p.errorf(start, "%s: cannot be negated", field)
+		}
+		ts, ok := parseQueryTime(value)
+		if !ok {
+			return p.errorf(valuePos,
+				"invalid date %q (want YYYY-MM-DD or RFC 3339)",
+				value)
+		}
+		value = ts
+	}
+
+	p.flushRun()
+	p.q.Filters = append(p.q.Filters, FieldFilter{
+		Field: field, Value: value, Negate: negate,
+	})
+	return nil
+}
+
+// queryTimeExpr renders a message timestamp in the layout
+// parseQueryTime produces. Stored timestamps drop a zero
+// fraction, and "10:00:00Z" would otherwise sort after
+// "10:00:00.250Z".
+const queryTimeExpr = "strftime('%Y-%m-%dT%H:%M:%fZ', m.timestamp)"
+
+// parseQueryTime normalizes a date or RFC 3339 time to UTC
+// with millisecond precision, so it compares as a string with
+// queryTimeExpr.
+func parseQueryTime(s string) (string, bool) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.Format("2006-01-02"), true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC().Format("2006-01-02T15:04:05.000Z"), true
+	}
+	return "", false
+}
+
+func (p *queryParser) flushRun() {
+	if len(p.run) == 0 {
+		return
+	}
+	p.q.Terms = append(p.q.Terms, SearchTerm{
+		Text:   strings.Join(p.run, " "),
+		Phrase: len(p.run) > 1,
+	})
+	p.run = nil
+}
+
+// fts renders the term as an FTS5 query string.
+func (t SearchTerm) fts() string {
+	s := t.Text
+	if t.Phrase || !isFTSBareword(s) {
+		s = `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	if t.Prefix {
+		s += "*"
+	}
+	return s
+}
+
+// isFTSBareword reports whether s can appear unquoted in an FTS5
+// query without being read as syntax.
+func isFTSBareword(s string) bool {
+	switch s {
+	case "", "AND", "OR", "NOT", "NEAR":
+		return false
+	}
+	for _, r := range s {
+		if r < 0x80 && r != '_' &&
+			!unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchExpr returns the FTS5 MATCH expression for the query's
+// positive terms, or "" if it has none.
+func (q SearchQuery) matchExpr() string {
+	var parts []string
+	for _, t := range q.Terms {
+		if !t.Negate {
+			parts = append(parts, t.fts())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// predicates returns SQL conditions over sessions s and messages
+// m for the query's filters and negated terms.
+func (q SearchQuery) predicates() ([]string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+
+	var excluded []string
+	for _, t := range q.Terms {
+		if t.Negate {
+			excluded = append(excluded, t.fts())
+		}
+	}
+	if len(excluded) > 0 {
+		where = append(where, `m.id NOT IN (
+			SELECT rowid FROM messages_fts
+			WHERE messages_fts MATCH ?)`)
+		args = append(args, strings.Join(excluded, " OR "))
+	}
+
+	for _, field := range []string{
+		"agent", "project", "machine", "role", "tool",
+	} {
+		for _, negate := range []bool{false, true} {
+			var values []any
+			for _, f := range q.Filters {
+				if f.Field == field && f.Negate == negate {
+					values = append(values, f.Value)
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			where = append(where, filterClause(field, negate, len(values)))
+			args = append(args, values...)
+			if field == "tool" {
+				// Each value is matched against both columns.
+				args = append(args, values...)
+			}
+		}
+	}
+
+	for _, f := range q.Filters {
+		switch f.Field {
+		case "after":
+			where = append(where, queryTimeExpr+" >= ?")
+			args = append(args, f.Value)
+		case "before":
+			where = append(where,
+				"m.timestamp != '' AND "+queryTimeExpr+" < ?")
+			args = append(args, f.Value)
+		}
+	}
+	return where, args
+}
+
+func filterClause(field string, negate bool, n int) string {
+	in := "IN"
+	if negate {
+		in = "NOT IN"
+	}
+	list := "(" + strings.TrimSuffix(strings.Repeat("?,", n), ",") + ")"
+	switch field {
+	case "tool":
+		exists := "EXISTS"
+		if negate {
+			exists = "NOT EXISTS"
+		}
+		return exists + ` (SELECT 1 FROM tool_calls tc
+			WHERE tc.message_id = m.id
+			AND (tc.tool_name COLLATE NOCASE IN ` + list + `
+				OR tc.category COLLATE NOCASE IN ` + list + `))`
+	case "role":
+		return "m.role " + in + " " + list
+	default:
+		return "s." + field + " " + in + " " + list
+	}
+}
@@ -0,0 +1,253 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSearchQueryMatchExpr(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "single word unchanged", raw: "login", want: "login"},
+		{name: "multi-word gets quoted", raw: "fix bug", want: `"fix bug"`},
+		{name: "already quoted unchanged", raw: `"fix bug"`, want: `"fix bug"`},
+		{name: "three words quoted", raw: "a b c", want: `"a b c"`},
+		{name: "punctuation quoted", raw: "auth.go", want: `"auth.go"`},
+		{name: "operator quoted", raw: "OR", want: `"OR"`},
+		{name: "prefix", raw: "auth*", want: "auth*"},
+		{name: "prefix ends phrase", raw: "fix bu*", want: "fix bu*"},
+		{name: "embedded quote", raw: `"say ""hi"""`, want: `"say ""hi"""`},
+		{
+			name: "filters split phrases",
+			raw:  "fix agent:codex the bug",
+			want: `fix "the bug"`,
+		},
+		{
+			name: "negated terms excluded",
+			raw:  `"race condition" -flaky`,
+			want: `"race condition"`,
+		},
+		{name: "filters only", raw: "role:user", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			q, err := ParseSearchQuery(tt.raw)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery(%q): %v", tt.raw, err)
+			}
+			if got := q.matchExpr(); got != tt.want {
+				t.Errorf("matchExpr(%q) = %q, want %q",
+					tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchQueryFilters(t *testing.T) {
+	q, err := ParseSearchQuery(
+		`agent:Codex role:user after:2026-01-01 tool:Bash ` +
+			`project:"my app" -machine:ci "race condition" -flaky`,
+	)
+	if err != nil {
+		t.Fatalf("ParseSearchQuery: %v", err)
+	}
+	wantFilters := []FieldFilter{
+		{Field: "agent", Value: "codex"},
+		{Field: "role", Value: "user"},
+		{Field: "after", Value: "2026-01-01"},
+		{Field: "tool", Value: "Bash"},
+		{Field: "project", Value: "my app"},
+		{Field: "machine", Value: "ci", Negate: true},
+	}
+	if len(q.Filters) != len(wantFilters) {
+		t.Fatalf("filters = %+v, want %+v", q.Filters, wantFilters)
+	}
+	for i, want := range wantFilters {
+		if q.Filters[i] != want {
+			t.Errorf("filters[%d] = %+v, want %+v",
+				i, q.Filters[i], want)
+		}
+	}
+	wantTerms := []SearchTerm{
+		{Text: "race condition", Phrase: true},
+		{Text: "flaky", Negate: true},
+	}
+	if len(q.Terms) != len(wantTerms) {
+		t.Fatalf("terms = %+v, want %+v", q.Terms, wantTerms)
+	}
+	for i, want := range wantTerms {
+		if q.Terms[i] != want {
+			t.Errorf("terms[%d] = %+v, want %+v", i, q.Terms[i], want)
+		}
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		raw string
+		pos int
+	}{
+		{"", 0},
+		{"   ", 3},
+		{`foo "bar`, 4},
+		{`"bar"baz`, 5},
+		{"bug colour:red", 4},
+		{"agent:", 6},
+		{"role:system", 5},
+		{"after:yesterday", 6},
+		{"x -after:2026-01-01", 2},
+		{"fix -", 4},
+		{`ab"c"`, 2},
+		{"**", 0},
+		{`""`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseSearchQuery(tt.raw)
+			var qerr *QueryError
+			if !errors.As(err, &qerr) {
+				t.Fatalf("ParseSearchQuery(%q) err = %v, want *QueryError",
+					tt.raw, err)
+			}
+			if qerr.Pos != tt.pos {
+				t.Errorf("ParseSearchQuery(%q) pos = %d, want %d (%s)",
+					tt.raw, qerr.Pos, tt.pos, qerr.Msg)
+			}
+		})
+	}
+}
+
+func TestSearchStructuredQuery(t *testing.T) {
+	d := testDB(t)
+	requireFTS(t, d)
+
+	insertSession(t, d, "s1", "api")
+	insertSession(t, d, "s2", "web", func(s *Session) {
+		s.Agent = "codex"
+	})
+	bash := asstMsgAt("s1", 1, "running the race condition test",
+		"2026-02-01T10:00:00Z")
+	bash.HasToolUse = true
+	bash.ToolCalls = []ToolCall{{
+		SessionID: "s1", ToolName: "Bash", Category: "Bash",
+	}}
+	insertMessages(t, d,
+		userMsgAt("s1", 0, "race condition in the cache",
+			"2025-12-01T10:00:00Z"),
+		bash,
+		userMsgAt("s2", 0, "flaky race condition",
+			"2026-02-02T10:00:00Z"),
+		asstMsgAt("s2", 1, "no race condition here",
+			"2026-02-03T10:00:00Z"),
+	)
+
+	type hit struct {
+		session string
+		ordinal int
+	}
+	tests := []struct {
+		query   string
+		want    []hit
+		ordered bool
+	}{
+		{`"race condition"`, []hit{{"s1", 0}, {"s1", 1}, {"s2", 0}, {"s2", 1}}, false},
+		{`"race condition" agent:codex`, []hit{{"s2", 0}, {"s2", 1}}, false},
+		{`"race condition" -flaky role:user`, []hit{{"s1", 0}}, false},
+		{`race tool:bash`, []hit{{"s1", 1}}, false},
+		{`race -tool:Bash project:api`, []hit{{"s1", 0}}, false},
+		{`race after:2026-01-01 before:2026-02-03`, []hit{{"s1", 1}, {"s2", 0}}, false},
+		{`race project:api project:web role:assistant`, []hit{{"s1", 1}, {"s2", 1}}, false},
+		// Filter-only queries list newest messages first.
+		{`after:2026-01-01`, []hit{{"s2", 1}, {"s2", 0}, {"s1", 1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			page, err := d.Search(context.Background(), SearchFilter{
+				Query: tt.query, Limit: 10,
+			})
+			requireNoError(t, err, "Search")
+			got := make(map[hit]bool)
+			for _, r := range page.Results {
+				got[hit{r.SessionID, r.Ordinal}] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", page.Results, tt.want)
+			}
+			for i, want := range tt.want {
+				if !got[want] {
+					t.Errorf("missing %+v in %+v", want, page.Results)
+				}
+				r := page.Results[i]
+				if tt.ordered && (r.SessionID != want.session ||
+					r.Ordinal != want.ordinal) {
+					t.Errorf("results[%d] = %s/%d, want %+v",
+						i, r.SessionID, r.Ordinal, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchTimeFilterBoundary(t *testing.T) {
+	d := testDB(t)
+	requireFTS(t, d)
+
+	insertSession(t, d, "s1", "api")
+	insertMessages(t, d,
+		userMsgAt("s1", 0, "deploy before", "2026-02-01T09:59:59.999Z"),
+		userMsgAt("s1", 1, "deploy on time", "2026-02-01T10:00:00Z"),
+		userMsgAt("s1", 2, "deploy after", "2026-02-01T10:00:00.250Z"),
+	)
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"deploy after:2026-02-01T10:00:00Z", []int{1, 2}},
+		{"deploy after:2026-02-01T11:00:00+01:00", []int{1, 2}},
+		{"deploy before:2026-02-01T10:00:00Z", []int{0}},
+		{"deploy after:2026-02-01T10:00:00.1Z", []int{2}},
+		{"deploy before:2026-02-01T10:00:00.250Z", []int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			page, err := d.Search(context.Background(), SearchFilter{
+				Query: tt.query, Limit: 10,
+			})
+			requireNoError(t, err, "Search")
+			var got []int
+			for _, r := range page.Results {
+				got = append(got, r.Ordinal)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ordinals = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchMalformedQuery(t *testing.T) {
+	d := testDB(t)
+	requireFTS(t, d)
+
+	_, err := d.Search(context.Background(), SearchFilter{
+		Query: "role:bot", Limit: 10,
+	})
+	var qerr *QueryError
+	if !errors.As(err, &qerr) || qerr.Pos != 5 {
+		t.Fatalf("err = %v, want *QueryError at position 5", err)
+	}
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/wesm/agentsview/internal/secrets"
+)
+
+// Fields of a message that are scanned for secrets.
+const (
+	SecretFieldContent    = "content"
+	SecretFieldToolInput  = "tool_input"
+	SecretFieldToolResult = "tool_result"
+)
+
+// SecretFinding locates a detected secret in a message. Start
+// and End are byte offsets into Field; ToolCall indexes the
+// message's tool calls for the tool fields. The session fields
+// are filled in by ListSecretFindings.
+type SecretFinding struct {
+	ID        int64  `json:"-"`
+	MessageID int64  `json:"-"`
+	SessionID string `json:"session_id"`
+	Ordinal   int    `json:"ordinal"`
+	Field     string `json:"field"`
+	ToolCall  *int   `json:"tool_call,omitempty"`
+	Rule      string `json:"rule"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	Preview   string `json:"preview"`
+	FoundAt   string `json:"found_at,omitempty"`
+
+	Project  string  `json:"project,omitempty"`
+	Machine  string  `json:"machine,omitempty"`
+	Agent    string  `json:"agent,omitempty"`
+	FilePath *string `json:"file_path,omitempty"`
+}
+
+// ScanMessages returns the secrets sc finds in the content and
+// tool calls of msgs, in message order.
+func ScanMessages(
+	sc *secrets.Scanner, msgs []Message,
+) []SecretFinding {
+	findings := []SecretFinding{}
+	add := func(m Message, field string, tc *int, text string) {
+		for _, f := range sc.Scan(text) {
+			findings = append(findings, SecretFinding{
+				MessageID: m.ID,
+				SessionID: m.SessionID,
+				Ordinal:   m.Ordinal,
+				Field:     field,
+				ToolCall:  tc,
+				Rule:      f.Rule,
+				Start:     f.Start,
+				End:       f.End,
+				Preview:   secrets.Mask(text[f.Start:f.End]),
+			})
+		}
+	}
+	for _, m := range msgs {
+		add(m, SecretFieldContent, nil, m.Content)
+		for i, tc := range m.ToolCalls {
+			add(m, SecretFieldToolInput, &i, tc.InputJSON)
+			add(m, SecretFieldToolResult, &i, tc.ResultContent)
+		}
+	}
+	return findings
+}
+
+// SecretAuditWatermark returns the highest message ID the
+// secret audit has scanned.
+func (db *DB) SecretAuditWatermark() (int64, error) {
+	var v int64
+	err := db.writer.QueryRow(
+		`SELECT COALESCE((SELECT value FROM stats
+			WHERE key = 'secret_audit_watermark'), 0)`,
+	).Scan(&v)
+	if err != nil {
+		return 0, fmt.Errorf("reading audit watermark: %w", err)
+	}
+	return v, nil
+}
+
+// MessagesAfter returns up to limit messages with IDs above
+// afterID, in ID order, with their tool calls.
+func (db *DB) MessagesAfter(
+	ctx context.Context, afterID int64, limit int,
+) ([]Message, error) {
+	rows, err := db.reader.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM messages
+		WHERE id > ?
+		ORDER BY id ASC
+		LIMIT ?`, selectMessageCols), afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying messages: %w", err)
+	}
+	defer rows.Close()
+	msgs, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.attachToolCalls(ctx, msgs); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// SaveSecretAudit records the findings for the messages with IDs
+// in (from, to], replacing earlier findings for them, and moves
+// the watermark from from to to. It returns false, saving
+// nothing, if the watermark is no longer from because a sync
+// changed scanned messages in the meantime.
+func (db *DB) SaveSecretAudit(
+	from, to int64, findings []SecretFinding,
+) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return false, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(
+		`UPDATE stats SET value = ?
+		 WHERE key = 'secret_audit_watermark' AND value = ?`,
+		to, from,
+	)
+	if err != nil {
+		return false, fmt.Errorf("moving audit watermark: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(
+		`DELETE FROM secret_findings
+		 WHERE message_id > ? AND message_id <= ?`, from, to,
+	); err != nil {
+		return false, fmt.Errorf("clearing findings: %w", err)
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO secret_findings (message_id, session_id,
+			ordinal, field, tool_call, rule, start_pos, end_pos,
+			preview)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return false, fmt.Errorf("prepare: %w", err)
+	}
+	defer stmt.Close()
+	for _, f := range findings {
+		if _, err := stmt.Exec(
+			f.MessageID, f.SessionID, f.Ordinal, f.Field,
+			f.ToolCall, f.Rule, f.Start, f.End, f.Preview,
+		); err != nil {
+			return false, fmt.Errorf("inserting finding: %w", err)
+		}
+	}
+	return true, tx.Commit()
+}
+
+// ResetSecretAudit deletes all findings and the watermark, so
+// the next audit scans every message.
+func (db *DB) ResetSecretAudit() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, stmt := range []string{
+		"DELETE FROM secret_findings",
+		`UPDATE stats SET value = 0
+		 WHERE key = 'secret_audit_watermark'`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("resetting secret audit: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// LastSecretFindingID returns the highest finding ID assigned so
+// far, or 0 if there are none.
+func (db *DB) LastSecretFindingID() (int64, error) {
+	var id int64
+	err := db.writer.QueryRow(
+		`SELECT COALESCE((SELECT seq FROM sqlite_sequence
+			WHERE name = 'secret_findings'), 0)`,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("reading last finding id: %w", err)
+	}
+	return id, nil
+}
+
+// ListSecretFindings returns the stored findings with IDs above
+// afterID, with their session's project and machine, ordered by
+// session and message.
+func (db *DB) ListSecretFindings(
+	ctx context.Context, afterID int64,
+) ([]SecretFinding, error) {
+	rows, err := db.reader.QueryContext(ctx, `
+		SELECT f.id, f.message_id, f.session_id, f.ordinal,
+			f.field, f.tool_call, f.rule, f.start_pos, f.end_pos,
+			f.preview, f.found_at,
+			s.project, s.machine, s.agent, s.file_path
+		FROM secret_findings f
+		JOIN sessions s ON s.id = f.session_id
+		WHERE f.id > ?
+		ORDER BY f.session_id, f.ordinal, f.id`, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("querying secret findings: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SecretFinding
+	for rows.Next() {
+		var f SecretFinding
+		var toolCall sql.NullInt64
+		if err := rows.Scan(
+			&f.ID, &f.MessageID, &f.SessionID, &f.Ordinal,
+			&f.Field, &toolCall, &f.Rule, &f.Start, &f.End,
+			&f.Preview, &f.FoundAt,
+			&f.Project, &f.Machine, &f.Agent, &f.FilePath,
+		); err != nil {
+			return nil, fmt.Errorf("scanning secret finding: %w", err)
+		}
+		if toolCall.Valid {
+			i := int(toolCall.Int64)
+			f.ToolCall = &i
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/secrets"
+)
+
+// fakeAWSKey is split so this file does not trip secret scanners.
+const fakeAWSKey = "AKIA" + "IOSFODNN7EXAMPLE"
+
+// auditAll scans every message above the watermark in one batch
+// and saves the result.
+func auditAll(t *testing.T, d *DB) []SecretFinding {
+	t.Helper()
+	from, err := d.SecretAuditWatermark()
+	requireNoError(t, err, "SecretAuditWatermark")
+	msgs, err := d.MessagesAfter(context.Background(), from, 1000)
+	requireNoError(t, err, "MessagesAfter")
+	if len(msgs) == 0 {
+		return nil
+	}
+	findings := ScanMessages(secrets.Default(), msgs)
+	saved, err := d.SaveSecretAudit(from, msgs[len(msgs)-1].ID, findings)
+	requireNoError(t, err, "SaveSecretAudit")
+	if !saved {
+		t.Fatal("SaveSecretAudit = false, want true")
+	}
+	return findings
+}
+
+func requireWatermark(t *testing.T, d *DB, want int64) {
+	t.Helper()
+	got, err := d.SecretAuditWatermark()
+	requireNoError(t, err, "SecretAuditWatermark")
+	if got != want {
+		t.Errorf("watermark = %d, want %d", got, want)
+	}
+}
+
+func TestScanMessages(t *testing.T) {
+	msg := asstMsg("s1", 2, "using "+fakeAWSKey)
+	msg.ToolCalls = []ToolCall{
+		{ToolName: "Read", InputJSON: `{"file":"a.go"}`},
+		{
+			ToolName:      "Bash",
+			InputJSON:     `{"command":"env"}`,
+			ResultContent: "AWS_KEY=" + fakeAWSKey,
+		},
+	}
+	got := ScanMessages(secrets.Default(), []Message{
+		userMsg("s1", 1, "nothing to see"), msg,
+	})
+	if len(got) != 2 {
+		t.Fatalf("findings = %+v, want 2", got)
+	}
+	if got[0].Field != SecretFieldContent || got[0].ToolCall != nil ||
+		got[0].Ordinal != 2 || got[0].Start != len("using ") {
+		t.Errorf("content finding = %+v", got[0])
+	}
+	if got[1].Field != SecretFieldToolResult ||
+		got[1].ToolCall == nil || *got[1].ToolCall != 1 {
+		t.Errorf("tool result finding = %+v", got[1])
+	}
+}
+
+func TestSecretAudit(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "my-app")
+	insertMessages(t, d,
+		userMsg("s1", 0, "deploy with "+fakeAWSKey),
+		asstMsg("s1", 1, "done"),
+	)
+
+	requireWatermark(t, d, 0)
+	if got := auditAll(t, d); len(got) != 1 {
+		t.Fatalf("first audit = %+v, want 1 finding", got)
+	}
+	msgs, err := d.MessagesAfter(ctx, 0, 10)
+	requireNoError(t, err, "MessagesAfter")
+	last := msgs[len(msgs)-1].ID
+	requireWatermark(t, d, last)
+
+	// A second audit has nothing new to scan.
+	if got := auditAll(t, d); got != nil {
+		t.Errorf("second audit = %+v, want nothing", got)
+	}
+
+	// A stale watermark is rejected.
+	saved, err := d.SaveSecretAudit(0, last, nil)
+	requireNoError(t, err, "SaveSecretAudit")
+	if saved {
+		t.Error("SaveSecretAudit with stale watermark = true")
+	}
+
+	findings, err := d.ListSecretFindings(ctx, 0)
+	requireNoError(t, err, "ListSecretFindings")
+	if len(findings) != 1 {
+		t.Fatalf("stored findings = %+v, want 1", findings)
+	}
+	f := findings[0]
+	if f.SessionID != "s1" || f.Project != "my-app" ||
+		f.Machine != defaultMachine || f.Rule != "aws_access_key_id" ||
+		f.FoundAt == "" {
+		t.Errorf("stored finding = %+v", f)
+	}
+	lastID, err := d.LastSecretFindingID()
+	requireNoError(t, err, "LastSecretFindingID")
+	if lastID != f.ID {
+		t.Errorf("LastSecretFindingID = %d, want %d", lastID, f.ID)
+	}
+	later, err := d.ListSecretFindings(ctx, lastID)
+	requireNoError(t, err, "ListSecretFindings after")
+	if len(later) != 0 {
+		t.Errorf("findings after %d = %+v", lastID, later)
+	}
+
+	requireNoError(t, d.ResetSecretAudit(), "ResetSecretAudit")
+	requireWatermark(t, d, 0)
+	findings, err = d.ListSecretFindings(ctx, 0)
+	requireNoError(t, err, "ListSecretFindings")
+	if len(findings) != 0 {
+		t.Errorf("findings after reset = %+v", findings)
+	}
+}
+
+func TestSecretAuditWatermarkTriggers(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+	insertSession(t, d, "s2", "proj")
+	first := asstMsg("s1", 0, "running")
+	first.ToolCalls = []ToolCall{{
+		ToolName: "Bash", Category: "Bash", ToolUseID: "t1",
+	}}
+	insertMessages(t, d, first, userMsg("s1", 1, "ok"))
+	insertMessages(t, d, userMsg("s2", 0, "key "+fakeAWSKey))
+	auditAll(t, d)
+
+	msgs, err := d.MessagesAfter(ctx, 0, 10)
+	requireNoError(t, err, "MessagesAfter")
+	if len(msgs) != 3 {
+		t.Fatalf("messages = %d, want 3", len(msgs))
+	}
+
+	// Deleting the newest messages lowers the watermark so that
+	// messages reusing their IDs are scanned.
+	requireNoError(t, d.DeleteSessionMessages("s2"), "DeleteSessionMessages")
+	requireWatermark(t, d, msgs[1].ID)
+	findings, err := d.ListSecretFindings(ctx, 0)
+	requireNoError(t, err, "ListSecretFindings")
+	if len(findings) != 0 {
+		t.Errorf("findings of deleted messages = %+v", findings)
+	}
+
+	// A tool result arriving for a scanned message rescans it.
+	requireNoError(t, d.AppendMessages("s1", nil, []ToolResult{{
+		ToolUseID: "t1", Content: "TOKEN=" + fakeAWSKey,
+	}}), "AppendMessages")
+	requireWatermark(t, d, msgs[0].ID-1)
+	if got := auditAll(t, d); len(got) != 1 ||
+		got[0].Field != SecretFieldToolResult {
+		t.Errorf("rescan findings = %+v", got)
+	}
+}
@@ -59,7 +59,8 @@ const sessionBaseCols = `id, project, machine, agent,
 	input_tokens, output_tokens,
 	cache_creation_input_tokens, cache_read_input_tokens,
 	token_usage_by_model, mcp_servers,
-	parent_session_id, relationship_type, created_at`
+	parent_session_id, relationship_type,
+	cwd, git_branch, repo_root, created_at`
 
 // sessionPruneCols extends sessionBaseCols with file metadata
 // needed by FindPruneCandidates.
@@ -70,6 +71,7 @@ const sessionPruneCols = `id, project, machine, agent,
 	cache_creation_input_tokens, cache_read_input_tokens,
 	token_usage_by_model, mcp_servers,
 	parent_session_id, relationship_type,
+	cwd, git_branch, repo_root,
 	file_path, file_size, created_at`
 
 // sessionFullCols includes all columns for a complete session record.
@@ -80,6 +82,7 @@ const sessionFullCols = `id, project, machine, agent,
 	cache_creation_input_tokens, cache_read_input_tokens,
 	token_usage_by_model, mcp_servers,
 	parent_session_id, relationship_type,
+	cwd, git_branch, repo_root,
 	file_path, file_size, file_mtime,
 	file_hash, created_at`
 
@@ -107,6 +110,7 @@ func scanSessionRow(rs rowScanner) (Session, error) {
 		&s.CacheCreationInputTokens, &s.CacheReadInputTokens,
 		&s.TokenUsageByModel, &s.MCPServers,
 		&s.ParentSessionID, &s.RelationshipType,
+		&s.Cwd, &s.GitBranch, &s.RepoRoot,
 		&s.CreatedAt,
 	)
 	return s, err
@@ -131,11 +135,24 @@ type Session struct {
 	MCPServers               RawJSON `json:"mcp_servers,omitempty"`
 	ParentSessionID          *string `json:"parent_session_id,omitempty"`
 	RelationshipType         string  `json:"relationship_type,omitempty"`
+	Cwd                      string  `json:"cwd,omitempty"`
+	GitBranch                string  `json:"git_branch,omitempty"`
+	RepoRoot                 string  `json:"repo_root,omitempty"`
 	FilePath                 *string `json:"file_path,omitempty"`
 	FileSize                 *int64  `json:"file_size,omitempty"`
 	FileMtime                *int64  `json:"file_mtime,omitempty"`
 	FileHash                 *string `json:"file_hash,omitempty"`
 	CreatedAt                string  `json:"created_at"`
+
+	// CostUSD and CostByModel are estimated from the token
+	// usage when the session is read; they are not stored.
+	CostUSD     *float64           `json:"cost_usd,omitempty"`
+	CostByModel map[string]float64 `json:"cost_by_model,omitempty"`
+
+	// Tags and Starred are the session-level annotations, filled
+	// in by ListSessions and GetSession.
+	Tags    []string `json:"tags,omitempty"`
+	Starred bool     `json:"starred,omitempty"`
 }
 
 // SessionCursor is the opaque pagination token.
@@ -221,6 +238,8 @@ type SessionFilter struct {
 	ExcludeProject  string // exclude sessions with this project name
 	Machine         string
 	Agent           string
+	Branch          string // git branch
+	Repo            string // repository root path or directory name
 	Date            string // exact date YYYY-MM-DD
 	DateFrom        string // range start (inclusive)
 	DateTo          string // range end (inclusive)
@@ -228,6 +247,8 @@ type SessionFilter struct {
 	MinMessages     int    // message_count >= N (0 = no filter)
 	MaxMessages     int    // message_count <= N (0 = no filter)
 	MinUserMessages int    // user_message_count >= N (0 = no filter)
+	Tag             string // session-level tag
+	Starred         bool   // only starred sessions
 	Cursor          string // opaque cursor from previous page
 	Limit           int
 }
@@ -264,6 +285,15 @@ func buildSessionFilter(f SessionFilter) (string, []any) {
 		preds = append(preds, "agent = ?")
 		args = append(args, f.Agent)
 	}
+	if f.Branch != "" {
+		preds = append(preds, "git_branch = ?")
+		args = append(args, f.Branch)
+	}
+	if f.Repo != "" {
+		pred, repoArgs := repoPredicate(f.Repo)
+		preds = append(preds, pred)
+		args = append(args, repoArgs...)
+	}
 	if f.Date != "" {
 		preds = append(preds,
 			"date(COALESCE(started_at, created_at)) = ?")
@@ -296,6 +326,17 @@ func buildSessionFilter(f SessionFilter) (string, []any) {
 		preds = append(preds, "user_message_count >= ?")
 		args = append(args, f.MinUserMessages)
 	}
+	if f.Tag != "" {
+		preds = append(preds, `id IN (
+			SELECT session_id FROM session_tags
+			WHERE tag = ? AND ordinal IS NULL)`)
+		args = append(args, tagKey(f.Tag))
+	}
+	if f.Starred {
+		preds = append(preds, `id IN (
+			SELECT session_id FROM session_stars
+			WHERE ordinal IS NULL)`)
+	}
 
 	return strings.Join(preds, " AND "), args
 }
@@ -358,7 +399,7 @@ func (db *DB) ListSessions(
 	}
 	defer rows.Close()
 
-	sessions, err := scanSessionRows(rows)
+	sessions, err := db.scanSessionRows(rows)
 	if err != nil {
 		return SessionPage{}, err
 	}
@@ -377,6 +418,9 @@ func (db *DB) ListSessions(
 		}
 		page.NextCursor = db.EncodeCursor(ea, last.ID, total)
 	}
+	if err := db.setAnnotations(ctx, page.Sessions); err != nil {
+		return SessionPage{}, err
+	}
 
 	return page, nil
 }
@@ -398,7 +442,12 @@ func (db *DB) GetSession(
 	if err != nil {
 		return nil, fmt.Errorf("getting session %s: %w", id, err)
 	}
-	return &s, nil
+	db.setSessionCost(&s)
+	one := []Session{s}
+	if err := db.setAnnotations(ctx, one); err != nil {
+		return nil, err
+	}
+	return &one[0], nil
 }
 
 // GetSessionFull returns a single session by ID with all file metadata.
@@ -420,6 +469,7 @@ func (db *DB) GetSessionFull(
 		&s.CacheCreationInputTokens, &s.CacheReadInputTokens,
 		&s.TokenUsageByModel, &s.MCPServers,
 		&s.ParentSessionID, &s.RelationshipType,
+		&s.Cwd, &s.GitBranch, &s.RepoRoot,
 		&s.FilePath, &s.FileSize,
 		&s.FileMtime, &s.FileHash, &s.CreatedAt,
 	)
@@ -429,6 +479,7 @@ func (db *DB) GetSessionFull(
 	if err != nil {
 		return nil, fmt.Errorf("getting session full %s: %w", id, err)
 	}
+	db.setSessionCost(&s)
 	return &s, nil
 }
 
@@ -446,8 +497,9 @@ func (db *DB) UpsertSession(s Session) error {
 			cache_creation_input_tokens, cache_read_input_tokens,
 			token_usage_by_model, mcp_servers,
 			parent_session_id, relationship_type,
+			cwd, git_branch, repo_root,
 			file_path, file_size, file_mtime, file_hash
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			project = excluded.project,
 			machine = excluded.machine,
@@ -465,6 +517,9 @@ func (db *DB) UpsertSession(s Session) error {
 			mcp_servers = excluded.mcp_servers,
 			parent_session_id = excluded.parent_session_id,
 			relationship_type = excluded.relationship_type,
+			cwd = excluded.cwd,
+			git_branch = excluded.git_branch,
+			repo_root = excluded.repo_root,
 			file_path = excluded.file_path,
 			file_size = excluded.file_size,
 			file_mtime = excluded.file_mtime,
@@ -476,6 +531,7 @@ func (db *DB) UpsertSession(s Session) error {
 		s.CacheCreationInputTokens, s.CacheReadInputTokens,
 		s.TokenUsageByModel, s.MCPServers,
 		s.ParentSessionID, s.RelationshipType,
+		s.Cwd, s.GitBranch, s.RepoRoot,
 		s.FilePath, s.FileSize, s.FileMtime, s.FileHash)
 	if err != nil {
 		return fmt.Errorf("upserting session %s: %w", s.ID, err)
@@ -499,7 +555,7 @@ func (db *DB) GetChildSessions(
 	}
 	defer rows.Close()
 
-	return scanSessionRows(rows)
+	return db.scanSessionRows(rows)
 }
 
 // GetSessionFileInfo returns file_size and file_mtime for a
@@ -617,14 +673,15 @@ func (db *DB) GetMachines(
 }
 
 // scanSessionRows iterates rows and scans each using
-// scanSessionRow.
-func scanSessionRows(rows *sql.Rows) ([]Session, error) {
+// scanSessionRow, filling in the estimated cost.
+func (db *DB) scanSessionRows(rows *sql.Rows) ([]Session, error) {
 	var sessions []Session
 	for rows.Next() {
 		s, err := scanSessionRow(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scanning session: %w", err)
 		}
+		db.setSessionCost(&s)
 		sessions = append(sessions, s)
 	}
 	return sessions, rows.Err()
@@ -656,6 +713,14 @@ func escapeLike(s string) string {
 	return r.Replace(s)
 }
 
+// repoPredicate matches sessions in a repository given by its
+// root path or by the name of its root directory.
+func repoPredicate(repo string) (string, []any) {
+	repo = strings.TrimRight(repo, "/")
+	return `(repo_root = ? OR repo_root LIKE ? ESCAPE '\')`,
+		[]any{repo, "%/" + escapeLike(repo)}
+}
+
 // FindPruneCandidates returns sessions matching all filter
 // criteria. Returns full Session rows including file metadata.
 func (db *DB) FindPruneCandidates(
@@ -715,6 +780,7 @@ func (db *DB) FindPruneCandidates(
 			&s.CacheCreationInputTokens, &s.CacheReadInputTokens,
 			&s.TokenUsageByModel, &s.MCPServers,
 			&s.ParentSessionID, &s.RelationshipType,
+			&s.Cwd, &s.GitBranch, &s.RepoRoot,
 			&s.FilePath, &s.FileSize, &s.CreatedAt,
 		)
 		if err != nil {
@@ -725,9 +791,10 @@ func (db *DB) FindPruneCandidates(
 	return sessions, rows.Err()
 }
 
-// DeleteSessions removes multiple sessions by ID in a single
-// transaction. Batches DELETEs in groups of 500 to stay under
-// SQLite variable limits. Returns count of deleted rows.
+// DeleteSessions removes multiple sessions by ID, along with
+// their tags, stars and notes, in a single transaction. Batches
+// DELETEs in groups of 500 to stay under SQLite variable limits.
+// Returns count of deleted rows.
 func (db *DB) DeleteSessions(ids []string) (int, error) {
 	if len(ids) == 0 {
 		return 0, nil
@@ -763,6 +830,9 @@ func (db *DB) DeleteSessions(ids []string) (int, error) {
 		}
 		n, _ := res.RowsAffected()
 		total += int(n)
+		if err := deleteAnnotations(tx, placeholders, args); err != nil {
+			return 0, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -0,0 +1,117 @@
+// Package gitlog reads commits from local git repositories by
+// running the git command line tool. It never fetches.
+package gitlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Commit is a commit and the files it changed, relative to the
+// repository root.
+type Commit struct {
+	SHA        string
+	Author     string
+	Email      string
+	AuthoredAt time.Time
+	Subject    string
+	Files      []string
+}
+
+// Field and record separators of the log format. The record
+// separator starts each commit so that the file names printed
+// by --name-only after it belong to that commit.
+const (
+	fieldSep  = "\x1f"
+	recordSep = "\x1e"
+)
+
+const logFormat = recordSep + "%H" + fieldSep + "%an" + fieldSep +
+	"%ae" + fieldSep + "%aI" + fieldSep + "%s"
+
+// Available reports whether a git executable is on the PATH.
+func Available() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// Log returns the commits of the repository at root, reachable
+// from any local branch, that were authored in [since, until],
+// most recently committed first. Merge commits are skipped.
+func Log(
+	ctx context.Context, root string, since, until time.Time,
+) ([]Commit, error) {
+	// --since filters by commit date, which is never earlier
+	// than the author date except for clock skew, so a day of
+	// slack keeps every candidate; the author date is checked
+	// below.
+	cmd := exec.CommandContext(ctx, "git",
+		"-c", "core.quotePath=false",
+		"-C", root, "log", "--branches", "--no-merges",
+		"--no-renames", "--name-only",
+		"--format="+logFormat,
+		"--since="+since.Add(-24*time.Hour).UTC().Format(time.RFC3339),
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git log in %s: %s", root, msg)
+	}
+
+	commits, err := parseLog(out)
+	if err != nil {
+		return nil, fmt.Errorf("git log in %s: %w", root, err)
+	}
+	kept := commits[:0]
+	for _, c := range commits {
+		if !c.AuthoredAt.Before(since) && !c.AuthoredAt.After(until) {
+			kept = append(kept, c)
+		}
+	}
+	return kept, nil
+}
+
+func parseLog(out []byte) ([]Commit, error) {
+	var commits []Commit
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if rest, ok := strings.CutPrefix(line, recordSep); ok {
+			fields := strings.SplitN(rest, fieldSep, 5)
+			if len(fields) != 5 {
+				return nil, fmt.Errorf("malformed commit line %q", line)
+			}
+			at, err := time.Parse(time.RFC3339, fields[3])
+			if err != nil {
+				return nil, fmt.Errorf(
+					"commit %s: parsing date: %w", fields[0], err,
+				)
+			}
+			commits = append(commits, Commit{
+				SHA:        fields[0],
+				Author:     fields[1],
+				Email:      fields[2],
+				AuthoredAt: at,
+				Subject:    fields[4],
+			})
+			continue
+		}
+		if line == "" || len(commits) == 0 {
+			continue
+		}
+		c := &commits[len(commits)-1]
+		c.Files = append(c.Files, line)
+	}
+	return commits, sc.Err()
+}
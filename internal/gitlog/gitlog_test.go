@@ -0,0 +1,110 @@
+package gitlog
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// commitAt writes content to name in the repository at dir and
+// commits it with the given author date.
+func commitAt(t *testing.T, dir, name, content, msg, date string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, nil, "add", name)
+	git(t, dir, []string{
+		"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date,
+	}, "commit", "-q", "-m", msg)
+}
+
+func git(t *testing.T, dir string, env []string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Dev", "GIT_AUTHOR_EMAIL=dev@example.com",
+		"GIT_COMMITTER_NAME=Dev", "GIT_COMMITTER_EMAIL=dev@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+	)
+	cmd.Env = append(cmd.Env, env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestLog(t *testing.T) {
+	if !Available() {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, nil, "init", "-q")
+	commitAt(t, dir, "old.txt", "old\n", "Old", "2024-01-01T08:00:00Z")
+	commitAt(t, dir, "src/main.go", "package main\n", "Add main",
+		"2024-01-01T10:30:00Z")
+	commitAt(t, dir, "README.md", "# app\n", "Add readme\n\nBody.",
+		"2024-01-01T11:00:00+02:00")
+	commitAt(t, dir, "late.txt", "late\n", "Late", "2024-01-02T10:00:00Z")
+
+	since := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	got, err := Log(context.Background(), dir, since, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Log = %+v, want 2 commits", got)
+	}
+	// git lists the commits in commit order, newest first.
+	c := got[1]
+	if c.Subject != "Add main" || c.Author != "Dev" ||
+		c.Email != "dev@example.com" || len(c.SHA) != 40 ||
+		len(c.Files) != 1 || c.Files[0] != "src/main.go" ||
+		!c.AuthoredAt.Equal(time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("main commit = %+v", c)
+	}
+	if got[0].Subject != "Add readme" || got[0].Files[0] != "README.md" ||
+		got[0].AuthoredAt.UTC().Hour() != 9 {
+		t.Errorf("readme commit = %+v", got[0])
+	}
+
+	if _, err := Log(
+		context.Background(), t.TempDir(), since, until,
+	); err == nil {
+		t.Error("Log outside a repository succeeded")
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	out := recordSep + "abc" + fieldSep + "A" + fieldSep + "a@x" +
+		fieldSep + "2024-01-01T10:00:00Z" + fieldSep + "Subject" +
+		fieldSep + "with separator\n\nx.go\ndir/y.go\n" +
+		recordSep + "def" + fieldSep + "B" + fieldSep + "b@x" +
+		fieldSep + "2024-01-01T09:00:00-05:00" + fieldSep + "Empty\n"
+	got, err := parseLog([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("commits = %+v", got)
+	}
+	if got[0].Subject != "Subject"+fieldSep+"with separator" ||
+		strings.Join(got[0].Files, ",") != "x.go,dir/y.go" {
+		t.Errorf("first = %+v", got[0])
+	}
+	if got[1].Files != nil || got[1].AuthoredAt.UTC().Hour() != 14 {
+		t.Errorf("second = %+v", got[1])
+	}
+
+	if _, err := parseLog([]byte(recordSep + "abc\n")); err == nil {
+		t.Error("malformed line accepted")
+	}
+}
@@ -0,0 +1,193 @@
+// Package mcp implements a Model Context Protocol server that
+// exposes the session archive to MCP clients over stdio.
+//
+// Messages are newline-delimited JSON-RPC 2.0 objects. Only the
+// subset of the protocol needed for tools is implemented:
+// initialize, ping, tools/list, and tools/call.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// ProtocolVersion is the MCP protocol revision this server
+// implements.
+const ProtocolVersion = "2024-11-05"
+
+// maxRequestSize bounds a single JSON-RPC line.
+const maxRequestSize = 4 * 1024 * 1024
+
+// JSON-RPC 2.0 error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// Server answers MCP requests using a session database.
+type Server struct {
+	db      *db.DB
+	name    string
+	version string
+}
+
+// New creates a Server backed by database. name and version are
+// reported to clients during initialization.
+func New(database *db.DB, name, version string) *Server {
+	return &Server{db: database, name: name, version: version}
+}
+
+// Serve reads requests from in and writes responses to out until
+// in reaches EOF or ctx is canceled.
+func (s *Server) Serve(
+	ctx context.Context, in io.Reader, out io.Writer,
+) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxRequestSize)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		resp := s.handleLine(ctx, line)
+		if resp == nil {
+			continue
+		}
+		if err := s.write(out, resp); err != nil {
+			return fmt.Errorf("writing response: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading request: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) write(out io.Writer, resp *response) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = out.Write(data)
+	return err
+}
+
+// handleLine dispatches one JSON-RPC message. It returns nil for
+// notifications, which never receive a response.
+func (s *Server) handleLine(
+	ctx context.Context, line []byte,
+) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(nil, &rpcError{
+			Code:    codeParseError,
+			Message: "parse error: " + err.Error(),
+		})
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &rpcError{
+			Code:    codeInvalidRequest,
+			Message: "invalid request",
+		})
+	}
+
+	isNotification := len(req.ID) == 0
+	result, err := s.dispatch(ctx, req.Method, req.Params)
+	if isNotification {
+		return nil
+	}
+	if err != nil {
+		var re *rpcError
+		if !errors.As(err, &re) {
+			re = &rpcError{
+				Code:    codeInternalError,
+				Message: err.Error(),
+			}
+		}
+		return errorResponse(req.ID, re)
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func errorResponse(id json.RawMessage, err *rpcError) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+func (s *Server) dispatch(
+	ctx context.Context, method string, params json.RawMessage,
+) (any, error) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities": map[string]any{
+				"tools": map[string]any{},
+			},
+			"serverInfo": map[string]any{
+				"name":    s.name,
+				"version": s.version,
+			},
+		}, nil
+	case "notifications/initialized",
+		"notifications/cancelled":
+		return nil, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": toolDefs}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{
+				Code:    codeInvalidParams,
+				Message: "invalid params: " + err.Error(),
+			}
+		}
+		return s.callTool(ctx, p.Name, p.Arguments)
+	default:
+		return nil, &rpcError{
+			Code:    codeMethodNotFound,
+			Message: "method not found: " + method,
+		}
+	}
+}
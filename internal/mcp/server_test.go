@@ -0,0 +1,260 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+)
+
+type rpcReply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// roundTrip sends each request line through Serve and returns
+// the decoded replies in order.
+func roundTrip(t *testing.T, s *Server, lines ...string) []rpcReply {
+	t.Helper()
+	in := strings.NewReader(strings.Join(lines, "\n") + "\n")
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	var replies []rpcReply
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r rpcReply
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decoding reply: %v", err)
+		}
+		replies = append(replies, r)
+	}
+	return replies
+}
+
+// callTool invokes a tool and returns the decoded tool result.
+func callTool(
+	t *testing.T, s *Server, name string, args any,
+) toolResult {
+	t.Helper()
+	params, err := json.Marshal(map[string]any{
+		"name": name, "arguments": args,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":` +
+		string(params) + `}`
+	replies := roundTrip(t, s, line)
+	if len(replies) != 1 {
+		t.Fatalf("got %d replies, want 1", len(replies))
+	}
+	if replies[0].Error != nil {
+		t.Fatalf("tools/call %s: %v", name, replies[0].Error)
+	}
+	var res toolResult
+	if err := json.Unmarshal(replies[0].Result, &res); err != nil {
+		t.Fatalf("decoding tool result: %v", err)
+	}
+	if len(res.Content) != 1 {
+		t.Fatalf("got %d content blocks, want 1", len(res.Content))
+	}
+	return res
+}
+
+func seededServer(t *testing.T) *Server {
+	t.Helper()
+	d := dbtest.OpenTestDB(t)
+	dbtest.SeedSession(t, d, "s1", "alpha", func(s *db.Session) {
+		s.MessageCount = 2
+		s.StartedAt = dbtest.Ptr("2024-06-01T10:00:00Z")
+	})
+	dbtest.SeedSession(t, d, "s2", "beta", func(s *db.Session) {
+		s.Agent = "codex"
+		s.StartedAt = dbtest.Ptr("2024-06-02T10:00:00Z")
+	})
+	dbtest.SeedMessages(t, d,
+		dbtest.UserMsg("s1", 0, "fix the race condition"),
+		dbtest.AsstMsg("s1", 1, "added a mutex around the map"),
+		dbtest.UserMsg("s2", 0, "write release notes"),
+	)
+	return New(d, "agentsview", "test")
+}
+
+func TestInitializeAndList(t *testing.T) {
+	s := seededServer(t)
+	replies := roundTrip(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+	)
+	if len(replies) != 3 {
+		t.Fatalf("got %d replies, want 3 (notification must not reply)",
+			len(replies))
+	}
+
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name string `json:"name"`
+		} `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(replies[0].Result, &init); err != nil {
+		t.Fatal(err)
+	}
+	if init.ProtocolVersion != ProtocolVersion ||
+		init.ServerInfo.Name != "agentsview" {
+		t.Errorf("initialize = %+v", init)
+	}
+
+	var list struct {
+		Tools []tool `json:"tools"`
+	}
+	if err := json.Unmarshal(replies[1].Result, &list); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tl := range list.Tools {
+		names = append(names, tl.Name)
+	}
+	want := "search_sessions,get_session,get_messages,list_projects"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("tools = %s, want %s", got, want)
+	}
+
+	if string(replies[2].ID) != "3" || replies[2].Error != nil {
+		t.Errorf("ping reply = %+v", replies[2])
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	s := seededServer(t)
+	replies := roundTrip(t, s,
+		`not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"nope"}}`,
+	)
+	wantCodes := []int{
+		codeParseError, codeMethodNotFound, codeInvalidParams,
+	}
+	if len(replies) != len(wantCodes) {
+		t.Fatalf("got %d replies, want %d",
+			len(replies), len(wantCodes))
+	}
+	for i, code := range wantCodes {
+		if replies[i].Error == nil || replies[i].Error.Code != code {
+			t.Errorf("reply %d error = %+v, want code %d",
+				i, replies[i].Error, code)
+		}
+	}
+}
+
+func TestSearchSessionsTool(t *testing.T) {
+	s := seededServer(t)
+
+	t.Run("FullText", func(t *testing.T) {
+		if !s.db.HasFTS() {
+			t.Skip("skipping search test: no FTS support")
+		}
+		res := callTool(t, s, "search_sessions",
+			map[string]any{"query": "race condition"})
+		var page searchResult
+		if err := json.Unmarshal(
+			[]byte(res.Content[0].Text), &page,
+		); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Results) != 1 ||
+			page.Results[0].SessionID != "s1" {
+			t.Errorf("results = %+v, want s1", page.Results)
+		}
+	})
+
+	t.Run("ListByAgent", func(t *testing.T) {
+		res := callTool(t, s, "search_sessions",
+			map[string]any{"agent": "codex"})
+		var page db.SessionPage
+		if err := json.Unmarshal(
+			[]byte(res.Content[0].Text), &page,
+		); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Sessions) != 1 || page.Sessions[0].ID != "s2" {
+			t.Errorf("sessions = %+v, want s2", page.Sessions)
+		}
+	})
+}
+
+func TestGetSessionTool(t *testing.T) {
+	s := seededServer(t)
+
+	res := callTool(t, s, "get_session",
+		map[string]any{"session_id": "s1"})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.Content[0].Text)
+	}
+	if !strings.Contains(res.Content[0].Text, `"project": "alpha"`) {
+		t.Errorf("session = %s", res.Content[0].Text)
+	}
+
+	res = callTool(t, s, "get_session",
+		map[string]any{"session_id": "missing"})
+	if !res.IsError {
+		t.Error("expected isError for missing session")
+	}
+	var e toolError
+	if err := json.Unmarshal(
+		[]byte(res.Content[0].Text), &e,
+	); err != nil || e.Error == "" {
+		t.Errorf("error content = %q, want JSON error",
+			res.Content[0].Text)
+	}
+}
+
+func TestGetMessagesTool(t *testing.T) {
+	s := seededServer(t)
+
+	res := callTool(t, s, "get_messages", map[string]any{
+		"session_id": "s1", "direction": "desc", "limit": 1,
+	})
+	var out struct {
+		Messages []db.Message `json:"messages"`
+		Count    int          `json:"count"`
+	}
+	if err := json.Unmarshal(
+		[]byte(res.Content[0].Text), &out,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if out.Count != 1 || out.Messages[0].Ordinal != 1 {
+		t.Errorf("messages = %+v, want newest ordinal 1", out.Messages)
+	}
+
+	res = callTool(t, s, "get_messages", map[string]any{
+		"session_id": "s1", "direction": "sideways",
+	})
+	if !res.IsError {
+		t.Error("expected isError for invalid direction")
+	}
+}
+
+func TestListProjectsTool(t *testing.T) {
+	s := seededServer(t)
+	res := callTool(t, s, "list_projects", nil)
+	var projects []db.ProjectInfo
+	if err := json.Unmarshal(
+		[]byte(res.Content[0].Text), &projects,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 2 || projects[0].Name != "alpha" {
+		t.Errorf("projects = %+v", projects)
+	}
+}
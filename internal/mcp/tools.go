@@ -0,0 +1,308 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// tool describes one MCP tool advertised by tools/list.
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// toolResult is the tools/call result payload. Tool failures
+// are reported in-band with IsError so the model can see them.
+type toolResult struct {
+	Content []toolContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+type toolContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func stringProp(desc string) map[string]any {
+	return map[string]any{"type": "string", "description": desc}
+}
+
+func intProp(desc string) map[string]any {
+	return map[string]any{"type": "integer", "description": desc}
+}
+
+func objectSchema(
+	props map[string]any, required ...string,
+) map[string]any {
+	s := map[string]any{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+const queryHelp = "Search query: words, \"phrases\", -excluded " +
+	"terms and filters such as agent:codex role:user tool:Bash " +
+	"after:2026-01-01"
+
+var toolDefs = []tool{
+	{
+		Name: "search_sessions",
+		Description: "Search agent sessions. With a query, runs a " +
+			"full-text search over message content and returns " +
+			"matching messages with snippets. Without a query, " +
+			"lists sessions newest first.",
+		InputSchema: objectSchema(map[string]any{
+			"query":     stringProp(queryHelp),
+			"project":   stringProp("Only sessions in this project"),
+			"agent":     stringProp("Only sessions from this agent (claude, codex, ...)"),
+			"date_from": stringProp("Sessions started on or after YYYY-MM-DD"),
+			"date_to":   stringProp("Sessions started on or before YYYY-MM-DD"),
+			"limit":     intProp("Maximum results to return"),
+			"cursor":    stringProp("Pagination cursor from a previous call"),
+		}),
+	},
+	{
+		Name:        "get_session",
+		Description: "Get metadata for a single session by ID.",
+		InputSchema: objectSchema(map[string]any{
+			"session_id": stringProp("Session ID"),
+		}, "session_id"),
+	},
+	{
+		Name: "get_messages",
+		Description: "Get messages for a session, including tool " +
+			"calls, starting at an ordinal.",
+		InputSchema: objectSchema(map[string]any{
+			"session_id": stringProp("Session ID"),
+			"from":       intProp("Starting ordinal (inclusive)"),
+			"limit":      intProp("Maximum messages to return"),
+			"direction":  stringProp("asc (default) or desc"),
+		}, "session_id"),
+	},
+	{
+		Name:        "list_projects",
+		Description: "List projects with their session counts.",
+		InputSchema: objectSchema(map[string]any{}),
+	},
+}
+
+type searchArgs struct {
+	Query    string `json:"query"`
+	Project  string `json:"project"`
+	Agent    string `json:"agent"`
+	DateFrom string `json:"date_from"`
+	DateTo   string `json:"date_to"`
+	Limit    int    `json:"limit"`
+	Cursor   string `json:"cursor"`
+}
+
+type sessionArgs struct {
+	SessionID string `json:"session_id"`
+}
+
+type messagesArgs struct {
+	SessionID string `json:"session_id"`
+	From      *int   `json:"from"`
+	Limit     int    `json:"limit"`
+	Direction string `json:"direction"`
+}
+
+func (s *Server) callTool(
+	ctx context.Context, name string, rawArgs json.RawMessage,
+) (*toolResult, error) {
+	if len(rawArgs) == 0 {
+		rawArgs = json.RawMessage("{}")
+	}
+
+	var (
+		out any
+		err error
+	)
+	switch name {
+	case "search_sessions":
+		var a searchArgs
+		if err := decodeArgs(rawArgs, &a); err != nil {
+			return nil, err
+		}
+		out, err = s.searchSessions(ctx, a)
+	case "get_session":
+		var a sessionArgs
+		if err := decodeArgs(rawArgs, &a); err != nil {
+			return nil, err
+		}
+		out, err = s.getSession(ctx, a)
+	case "get_messages":
+		var a messagesArgs
+		if err := decodeArgs(rawArgs, &a); err != nil {
+			return nil, err
+		}
+		out, err = s.getMessages(ctx, a)
+	case "list_projects":
+		out, err = s.db.GetProjects(ctx)
+	default:
+		return nil, &rpcError{
+			Code:    codeInvalidParams,
+			Message: "unknown tool: " + name,
+		}
+	}
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding result: %w", err)
+	}
+	return &toolResult{
+		Content: []toolContent{{Type: "text", Text: string(data)}},
+	}, nil
+}
+
+func decodeArgs(raw json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{
+			Code:    codeInvalidParams,
+			Message: "invalid arguments: " + err.Error(),
+		}
+	}
+	return nil
+}
+
+// toolError is the JSON body of a failed tool call, shaped like
+// the HTTP API's error responses.
+type toolError struct {
+	Error string `json:"error"`
+}
+
+func errorResult(msg string) *toolResult {
+	data, _ := json.Marshal(toolError{Error: msg})
+	return &toolResult{
+		Content: []toolContent{{Type: "text", Text: string(data)}},
+		IsError: true,
+	}
+}
+
+// searchResult wraps db.SearchPage with the cursor as a string
+// so both search modes share one pagination argument.
+type searchResult struct {
+	Results    []db.SearchResult `json:"results"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func (s *Server) searchSessions(
+	ctx context.Context, a searchArgs,
+) (any, error) {
+	query := strings.TrimSpace(a.Query)
+	if query == "" {
+		page, err := s.db.ListSessions(ctx, db.SessionFilter{
+			Project:  a.Project,
+			Agent:    a.Agent,
+			DateFrom: a.DateFrom,
+			DateTo:   a.DateTo,
+			Cursor:   a.Cursor,
+			Limit:    a.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if page.Sessions == nil {
+			page.Sessions = []db.Session{}
+		}
+		return page, nil
+	}
+
+	if !s.db.HasFTS() {
+		return nil, fmt.Errorf("search not available")
+	}
+
+	var offset int
+	if a.Cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(a.Cursor)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid cursor %q", a.Cursor)
+		}
+	}
+	page, err := s.db.Search(ctx, db.SearchFilter{
+		Query:   query,
+		Project: a.Project,
+		Cursor:  offset,
+		Limit:   a.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := searchResult{Results: page.Results}
+	if res.Results == nil {
+		res.Results = []db.SearchResult{}
+	}
+	if page.NextCursor > 0 {
+		res.NextCursor = strconv.Itoa(page.NextCursor)
+	}
+	return res, nil
+}
+
+func (s *Server) getSession(
+	ctx context.Context, a sessionArgs,
+) (any, error) {
+	if a.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	sess, err := s.db.GetSession(ctx, a.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, fmt.Errorf("session %s not found", a.SessionID)
+	}
+	return sess, nil
+}
+
+func (s *Server) getMessages(
+	ctx context.Context, a messagesArgs,
+) (any, error) {
+	if a.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+
+	asc := true
+	switch a.Direction {
+	case "", "asc":
+	case "desc":
+		asc = false
+	default:
+		return nil, fmt.Errorf(
+			"invalid direction %q: use asc or desc", a.Direction,
+		)
+	}
+
+	from := 0
+	if a.From != nil {
+		from = *a.From
+	} else if !asc {
+		from = math.MaxInt32
+	}
+
+	msgs, err := s.db.GetMessages(ctx, a.SessionID, from, a.Limit, asc)
+	if err != nil {
+		return nil, err
+	}
+	if msgs == nil {
+		msgs = []db.Message{}
+	}
+	return map[string]any{
+		"messages": msgs,
+		"count":    len(msgs),
+	}, nil
+}
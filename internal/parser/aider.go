@@ -0,0 +1,548 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Aider writes its history files into the root of the repository
+// it runs in.
+const (
+	AiderHistoryFile      = ".aider.chat.history.md"
+	aiderInputHistoryFile = ".aider.input.history"
+)
+
+var (
+	aiderChatStartRe = regexp.MustCompile(
+		`^# aider chat started at (\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\s*$`,
+	)
+	aiderModelRe   = regexp.MustCompile(`^> (?:Main )?[Mm]odel: (\S+)`)
+	aiderTokensRe  = regexp.MustCompile(`^> Tokens: ([\d.,]+[kM]?) sent,.* ([\d.,]+[kM]?) received`)
+	aiderSearchRe  = regexp.MustCompile(`^<{5,9} SEARCH\s*$`)
+	aiderDividerRe = regexp.MustCompile(`^={5,9}\s*$`)
+	aiderReplaceRe = regexp.MustCompile(`^>{5,9} REPLACE\s*$`)
+	aiderAppliedRe = regexp.MustCompile(`^> Applied edit to (.+)$`)
+	aiderFailedRe  = regexp.MustCompile(
+		`failed to exactly match lines in (.+)$`,
+	)
+)
+
+// aiderTimeLayout is the local time format of the chat and
+// input history headers.
+const aiderTimeLayout = "2006-01-02 15:04:05.999999"
+
+// AiderSessionPrefix returns the prefix shared by the IDs of the
+// sessions stored in the Aider history of repoDir.
+func AiderSessionPrefix(repoDir string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(repoDir)))
+	return "aider:" + hex.EncodeToString(sum[:])[:12] + "-"
+}
+
+// aiderChat is one "# aider chat started at" section of the
+// chat history.
+type aiderChat struct {
+	start time.Time
+	lines []string
+}
+
+// aiderInput is one entry of the input history.
+type aiderInput struct {
+	ts   time.Time
+	text string
+}
+
+// ParseAiderHistory parses the Aider chat history at path into
+// one session per chat. repoDir is the repository the history
+// belongs to; it keys the session IDs and holds the input
+// history, whose entries give user messages their timestamps.
+// Chats without user messages are omitted.
+func ParseAiderHistory(
+	path, repoDir, machine string,
+) ([]ParseResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	chats, err := readAiderChats(path)
+	if err != nil {
+		return nil, err
+	}
+	inputs := readAiderInputs(
+		filepath.Join(repoDir, aiderInputHistoryFile),
+	)
+
+	project := ExtractProjectFromCwd(repoDir)
+	if project == "" {
+		project = "unknown"
+	}
+	prefix := AiderSessionPrefix(repoDir)
+	seen := make(map[string]int)
+
+	var results []ParseResult
+	for i, chat := range chats {
+		var end time.Time
+		if i+1 < len(chats) {
+			end = chats[i+1].start
+		}
+		b := aiderSessionBuilder{repoDir: repoDir}
+		b.build(chat)
+		if b.userCount == 0 {
+			continue
+		}
+		b.timestampUsers(inputs, chat.start, end)
+
+		id := prefix + chat.start.Format("20060102T150405")
+		if n := seen[id]; n > 0 {
+			seen[id]++
+			id = fmt.Sprintf("%s-%d", id, n+1)
+		} else {
+			seen[id] = 1
+		}
+
+		sess := ParsedSession{
+			ID:               id,
+			Project:          project,
+			Machine:          machine,
+			Agent:            AgentAider,
+			FirstMessage:     b.firstMessage(),
+			StartedAt:        chat.start.UTC(),
+			EndedAt:          b.endedAt(chat.start),
+			MessageCount:     len(b.messages),
+			UserMessageCount: b.userCount,
+			File: FileInfo{
+				Path:  path,
+				Size:  info.Size(),
+				Mtime: info.ModTime().UnixNano(),
+			},
+		}
+		sess.SetWorkspace(repoDir, "")
+		addMessageTokens(&sess, b.messages)
+		results = append(results, ParseResult{
+			Session: sess, Messages: b.messages,
+		})
+	}
+	return results, nil
+}
+
+// readAiderChats splits the chat history into chats. Lines
+// before the first chat header are ignored.
+func readAiderChats(path string) ([]aiderChat, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var chats []aiderChat
+	lr := newLineReader(f, maxLineSize)
+	for {
+		line, ok := lr.next()
+		if !ok {
+			break
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if m := aiderChatStartRe.FindStringSubmatch(line); m != nil {
+			start, err := time.ParseInLocation(
+				aiderTimeLayout, m[1], time.Local,
+			)
+			if err == nil {
+				chats = append(chats, aiderChat{start: start})
+				continue
+			}
+		}
+		if len(chats) > 0 {
+			c := &chats[len(chats)-1]
+			c.lines = append(c.lines, line)
+		}
+	}
+	if err := lr.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return chats, nil
+}
+
+// readAiderInputs reads the input history: entries headed by a
+// "# <timestamp>" line with one "+"-prefixed line per input
+// line. A missing or unreadable file yields no entries.
+func readAiderInputs(path string) []aiderInput {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var (
+		inputs []aiderInput
+		lines  []string
+		ts     time.Time
+	)
+	flush := func() {
+		if !ts.IsZero() && len(lines) > 0 {
+			inputs = append(inputs, aiderInput{
+				ts:   ts,
+				text: strings.TrimSpace(strings.Join(lines, "\n")),
+			})
+		}
+		lines = nil
+	}
+	lr := newLineReader(f, maxLineSize)
+	for {
+		line, ok := lr.next()
+		if !ok {
+			break
+		}
+		if rest, ok := strings.CutPrefix(line, "# "); ok {
+			flush()
+			ts, err = time.ParseInLocation(
+				aiderTimeLayout, strings.TrimSpace(rest), time.Local,
+			)
+			if err != nil {
+				ts = time.Time{}
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "+"); ok {
+			lines = append(lines, rest)
+		}
+	}
+	flush()
+	return inputs
+}
+
+// aiderSessionBuilder turns the lines of one chat into messages.
+// Lines prefixed "#### " are user input, lines prefixed "> " are
+// Aider's own output, and the rest is the model's reply.
+type aiderSessionBuilder struct {
+	repoDir   string
+	model     string
+	messages  []ParsedMessage
+	userCount int
+	edits     int
+
+	// The message being collected.
+	role      RoleType
+	lines     []string
+	sawOutput bool
+}
+
+func (b *aiderSessionBuilder) build(chat aiderChat) {
+	intro := true
+	inFence := false
+	for _, line := range chat.lines {
+		if !inFence {
+			if text, ok := aiderUserLine(line); ok {
+				if b.role != RoleUser {
+					b.flush()
+					b.role = RoleUser
+				}
+				b.lines = append(b.lines, text)
+				intro = false
+				continue
+			}
+		}
+		if intro {
+			// The startup banner names the model.
+			if m := aiderModelRe.FindStringSubmatch(line); m != nil {
+				b.model = m[1]
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+		}
+		output := !inFence && isAiderOutput(line)
+		switch {
+		case b.role == RoleUser:
+			if line == "" {
+				continue
+			}
+			b.flush()
+			b.role = RoleAssistant
+		case b.role == "":
+			b.role = RoleAssistant
+		case !output && b.sawOutput && line != "":
+			// A reply after Aider's output (such as a retry
+			// after a failed edit) starts a new message.
+			b.flush()
+			b.role = RoleAssistant
+		}
+		if output {
+			b.sawOutput = true
+		}
+		b.lines = append(b.lines, line)
+	}
+	b.flush()
+}
+
+// aiderUserLine returns the text of a user input line.
+func aiderUserLine(line string) (string, bool) {
+	if line == "####" {
+		return "", true
+	}
+	return strings.CutPrefix(line, "#### ")
+}
+
+func isAiderOutput(line string) bool {
+	return line == ">" || strings.HasPrefix(line, "> ")
+}
+
+// flush appends the message being collected.
+func (b *aiderSessionBuilder) flush() {
+	role, lines := b.role, b.lines
+	b.role, b.lines, b.sawOutput = "", nil, false
+
+	content := strings.TrimSpace(strings.Join(lines, "\n"))
+	if content == "" {
+		return
+	}
+	if role == RoleUser {
+		b.messages = append(b.messages, ParsedMessage{
+			Ordinal:       len(b.messages),
+			Role:          RoleUser,
+			Content:       content,
+			ContentLength: len(content),
+		})
+		b.userCount++
+		return
+	}
+
+	m := ParsedMessage{
+		Ordinal:       len(b.messages),
+		Role:          RoleAssistant,
+		Content:       content,
+		ContentLength: len(content),
+		Model:         b.model,
+	}
+	edits := b.extractEdits(lines)
+	for _, e := range edits {
+		m.ToolCalls = append(m.ToolCalls, e.call)
+	}
+	m.HasToolUse = len(m.ToolCalls) > 0
+	m.InputTokens, m.OutputTokens = aiderTokens(lines)
+	b.messages = append(b.messages, m)
+
+	// Aider reports applied and failed edits in its output;
+	// record them as results of the edit calls.
+	applied, failed := aiderEditOutcomes(lines)
+	var results []ParsedToolResult
+	for _, e := range edits {
+		switch {
+		case failed[e.name]:
+			results = append(results, ParsedToolResult{
+				ToolUseID: e.call.ToolUseID, IsError: true,
+			})
+		case applied[e.name]:
+			results = append(results, ParsedToolResult{
+				ToolUseID: e.call.ToolUseID,
+			})
+		}
+	}
+	if len(results) > 0 {
+		b.messages = append(b.messages, ParsedMessage{
+			Ordinal:     len(b.messages),
+			Role:        RoleUser,
+			ToolResults: results,
+		})
+	}
+}
+
+// aiderEdit is a SEARCH/REPLACE block as an Edit tool call.
+type aiderEdit struct {
+	name string // file name as written in the reply
+	call ParsedToolCall
+}
+
+// extractEdits returns the SEARCH/REPLACE blocks in a reply. The
+// file name is the line before the block's opening fence; blocks
+// that follow another in the same fence edit the same file.
+func (b *aiderSessionBuilder) extractEdits(
+	lines []string,
+) []aiderEdit {
+	var (
+		edits []aiderEdit
+		name  string
+	)
+	for i := 0; i < len(lines); i++ {
+		if !aiderSearchRe.MatchString(lines[i]) {
+			continue
+		}
+		if n, ok := aiderEditFileName(lines[:i]); ok {
+			name = n
+		}
+		var search, replace []string
+		j := i + 1
+		for ; j < len(lines) && !aiderDividerRe.MatchString(lines[j]); j++ {
+			search = append(search, lines[j])
+		}
+		k := j + 1
+		for ; k < len(lines) && !aiderReplaceRe.MatchString(lines[k]); k++ {
+			replace = append(replace, lines[k])
+		}
+		if k >= len(lines) || name == "" {
+			continue
+		}
+		i = k
+
+		path := filepath.FromSlash(name)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(b.repoDir, path)
+		}
+		input, _ := json.Marshal(struct {
+			FilePath  string `json:"file_path"`
+			OldString string `json:"old_string"`
+			NewString string `json:"new_string"`
+		}{path, joinAiderBlock(search), joinAiderBlock(replace)})
+
+		b.edits++
+		edits = append(edits, aiderEdit{
+			name: name,
+			call: ParsedToolCall{
+				ToolUseID: fmt.Sprintf("aider-edit-%d", b.edits),
+				ToolName:  "Edit",
+				Category:  NormalizeToolCategory("Edit"),
+				InputJSON: string(input),
+			},
+		})
+	}
+	return edits
+}
+
+// aiderEditFileName finds the file name before a SEARCH marker.
+// It reports false when the block continues the previous one.
+func aiderEditFileName(before []string) (string, bool) {
+	for i := len(before) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(before[i])
+		switch {
+		case strings.HasPrefix(line, "```"):
+			continue
+		case aiderReplaceRe.MatchString(line):
+			return "", false
+		}
+		name := strings.Trim(line, "`*#: ")
+		return name, name != ""
+	}
+	return "", false
+}
+
+func joinAiderBlock(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// aiderEditOutcomes returns the files Aider reported applying and
+// failing to apply edits to.
+func aiderEditOutcomes(lines []string) (applied, failed map[string]bool) {
+	applied = make(map[string]bool)
+	failed = make(map[string]bool)
+	for _, line := range lines {
+		if !isAiderOutput(line) {
+			continue
+		}
+		if m := aiderAppliedRe.FindStringSubmatch(line); m != nil {
+			applied[strings.TrimSpace(m[1])] = true
+		}
+		if m := aiderFailedRe.FindStringSubmatch(line); m != nil {
+			failed[strings.TrimSpace(m[1])] = true
+		}
+	}
+	return applied, failed
+}
+
+// aiderTokens sums the "Tokens: N sent, M received" reports in
+// Aider's output.
+func aiderTokens(lines []string) (sent, received int64) {
+	for _, line := range lines {
+		if m := aiderTokensRe.FindStringSubmatch(line); m != nil {
+			sent += parseAiderCount(m[1])
+			received += parseAiderCount(m[2])
+		}
+	}
+	return sent, received
+}
+
+// parseAiderCount parses token counts such as "840", "2.1k",
+// "12k" and "1.2M".
+func parseAiderCount(s string) int64 {
+	s = strings.ReplaceAll(s, ",", "")
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		mult, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "M"):
+		mult, s = 1e6, strings.TrimSuffix(s, "M")
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(f*mult + 0.5)
+}
+
+// timestampUsers gives user messages the time of the matching
+// input history entry, matching in order within the chat's time
+// window [start, end). A zero end leaves the window open.
+func (b *aiderSessionBuilder) timestampUsers(
+	inputs []aiderInput, start, end time.Time,
+) {
+	next := 0
+	for i := range b.messages {
+		m := &b.messages[i]
+		if m.Role != RoleUser || m.Content == "" {
+			continue
+		}
+		for j := next; j < len(inputs); j++ {
+			in := inputs[j]
+			if in.ts.Before(start) {
+				continue
+			}
+			if !end.IsZero() && !in.ts.Before(end) {
+				break
+			}
+			if in.text == m.Content {
+				m.Timestamp = in.ts.UTC()
+				next = j + 1
+				break
+			}
+		}
+	}
+}
+
+// firstMessage returns the first user message that is not an
+// Aider command, or the first command if there is nothing else.
+func (b *aiderSessionBuilder) firstMessage() string {
+	first := ""
+	for _, m := range b.messages {
+		if m.Role != RoleUser || m.Content == "" {
+			continue
+		}
+		if !strings.HasPrefix(m.Content, "/") {
+			first = m.Content
+			break
+		}
+		if first == "" {
+			first = m.Content
+		}
+	}
+	return truncate(strings.ReplaceAll(first, "\n", " "), 300)
+}
+
+// endedAt returns the time of the last timestamped message, or
+// the chat start when none has one.
+func (b *aiderSessionBuilder) endedAt(start time.Time) time.Time {
+	for i := len(b.messages) - 1; i >= 0; i-- {
+		if ts := b.messages[i].Timestamp; !ts.IsZero() {
+			return ts
+		}
+	}
+	return start.UTC()
+}
@@ -0,0 +1,266 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const aiderHistory = `
+# aider chat started at 2024-06-01 10:00:00
+
+> /usr/local/bin/aider --model sonnet
+> Aider v0.50.0
+> Main model: claude-3-5-sonnet-20240620 with diff edit format
+> Git repo: .git with 3 files
+
+#### add a greet function
+#### that says hello
+
+I'll add it to ` + "`app.py`" + `.
+
+app.py
+` + "```python" + `
+<<<<<<< SEARCH
+def main():
+=======
+def greet():
+    print("hello")
+
+
+def main():
+>>>>>>> REPLACE
+` + "```" + `
+
+README.md
+` + "```markdown" + `
+<<<<<<< SEARCH
+# app
+=======
+# app
+
+#### Usage
+>>>>>>> REPLACE
+` + "```" + `
+
+> Tokens: 2.1k sent, 120 received. Cost: $0.01 message, $0.01 session.
+> Applied edit to app.py
+> Applied edit to README.md
+> Commit 1a2b3c4 feat: Add greet function
+
+#### /run pytest
+
+> 1 passed
+
+# aider chat started at 2024-06-02 09:30:00
+
+> Aider v0.50.0
+> Main model: gpt-4o with diff edit format
+
+#### rename main
+
+app.py
+` + "```python" + `
+<<<<<<< SEARCH
+def mian():
+=======
+def run():
+>>>>>>> REPLACE
+` + "```" + `
+
+> Tokens: 900 sent, 40 received.
+> The SEARCH section must exactly match an existing block of lines including all white space, comments, indentation, docstrings, etc
+> # 1 SEARCH/REPLACE block failed to exactly match lines in app.py
+
+Sorry, here is the fixed block.
+
+app.py
+` + "```python" + `
+<<<<<<< SEARCH
+def main():
+=======
+def run():
+>>>>>>> REPLACE
+` + "```" + `
+
+> Applied edit to app.py
+
+# aider chat started at 2024-06-03 08:00:00
+
+> Aider v0.50.0
+`
+
+const aiderInputs = `
+# 2024-06-01 10:00:05.123456
++add a greet function
++that says hello
+
+# 2024-06-01 10:02:00.000000
++/run pytest
+
+# 2024-06-02 09:31:00.000000
++rename main
+`
+
+// writeAiderRepo writes the chat and input histories into a
+// temporary repository and returns its directory.
+func writeAiderRepo(t *testing.T, history, inputs string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		AiderHistoryFile:      history,
+		aiderInputHistoryFile: inputs,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(
+			filepath.Join(dir, name), []byte(content), 0o644,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestParseAiderHistory(t *testing.T) {
+	dir := writeAiderRepo(t, aiderHistory, aiderInputs)
+	path := filepath.Join(dir, AiderHistoryFile)
+
+	results, err := ParseAiderHistory(path, dir, "laptop")
+	if err != nil {
+		t.Fatalf("ParseAiderHistory: %v", err)
+	}
+	// The third chat has no user messages.
+	if len(results) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(results))
+	}
+
+	sess, msgs := results[0].Session, results[0].Messages
+	prefix := AiderSessionPrefix(dir)
+	assertEqual(t, prefix+"20240601T100000", sess.ID, "ID")
+	assertEqual(t, AgentAider, sess.Agent, "agent")
+	assertEqual(t, "laptop", sess.Machine, "machine")
+	assertEqual(t, NormalizeName(filepath.Base(dir)), sess.Project, "project")
+	assertEqual(t, dir, sess.RepoRoot, "repo root")
+	assertEqual(t,
+		"add a greet function that says hello", sess.FirstMessage,
+		"first message")
+	assertEqual(t, 2, sess.UserMessageCount, "user messages")
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.Local)
+	assertEqual(t, true, sess.StartedAt.Equal(start), "started at")
+	end := time.Date(2024, 6, 1, 10, 2, 0, 0, time.Local)
+	assertEqual(t, true, sess.EndedAt.Equal(end), "ended at")
+	assertEqual(t, int64(2100), sess.InputTokens, "input tokens")
+	assertEqual(t, int64(120), sess.OutputTokens, "output tokens")
+	assertEqual(t, int64(2100),
+		sess.TokensByModel["claude-3-5-sonnet-20240620"].InputTokens,
+		"model input tokens")
+
+	// user, assistant, edit results, user, assistant
+	if len(msgs) != 5 {
+		for _, m := range msgs {
+			t.Logf("%s: %q", m.Role, m.Content)
+		}
+		t.Fatalf("got %d messages, want 5", len(msgs))
+	}
+	assertEqual(t, RoleUser, msgs[0].Role, "msgs[0].Role")
+	assertEqual(t,
+		"add a greet function\nthat says hello", msgs[0].Content,
+		"msgs[0].Content")
+	assertEqual(t, true,
+		msgs[0].Timestamp.Equal(
+			time.Date(2024, 6, 1, 10, 0, 5, 123456000, time.Local),
+		), "msgs[0].Timestamp")
+
+	reply := msgs[1]
+	assertEqual(t, RoleAssistant, reply.Role, "msgs[1].Role")
+	assertEqual(t, "claude-3-5-sonnet-20240620", reply.Model, "model")
+	assertEqual(t, true, strings.Contains(reply.Content, "#### Usage"),
+		"fenced heading kept in reply")
+	if len(reply.ToolCalls) != 2 {
+		t.Fatalf("got %d tool calls, want 2", len(reply.ToolCalls))
+	}
+	call := reply.ToolCalls[0]
+	assertEqual(t, "Edit", call.ToolName, "tool name")
+	assertEqual(t, "Edit", call.Category, "category")
+	in := gjson.Parse(call.InputJSON)
+	assertEqual(t, filepath.Join(dir, "app.py"),
+		in.Get("file_path").Str, "file_path")
+	assertEqual(t, "def main():\n", in.Get("old_string").Str, "old_string")
+	assertEqual(t, true,
+		strings.HasPrefix(in.Get("new_string").Str, "def greet():\n"),
+		"new_string")
+	assertEqual(t, filepath.Join(dir, "README.md"),
+		gjson.Get(reply.ToolCalls[1].InputJSON, "file_path").Str,
+		"second file_path")
+
+	results2 := msgs[2].ToolResults
+	if len(results2) != 2 || results2[0].IsError || results2[1].IsError ||
+		results2[0].ToolUseID != call.ToolUseID {
+		t.Errorf("edit results = %+v", results2)
+	}
+	assertEqual(t, "/run pytest", msgs[3].Content, "command")
+	assertEqual(t, "> 1 passed", msgs[4].Content, "command output")
+
+	// The retry after a failed edit is a separate reply.
+	sess, msgs = results[1].Session, results[1].Messages
+	assertEqual(t, prefix+"20240602T093000", sess.ID, "second ID")
+	if len(msgs) != 5 {
+		t.Fatalf("got %d messages, want 5", len(msgs))
+	}
+	assertEqual(t, "gpt-4o", msgs[1].Model, "second model")
+	if r := msgs[2].ToolResults; len(r) != 1 || !r[0].IsError {
+		t.Errorf("failed edit results = %+v", r)
+	}
+	assertEqual(t, true,
+		strings.HasPrefix(msgs[3].Content, "Sorry"), "retry reply")
+	if r := msgs[4].ToolResults; len(r) != 1 || r[0].IsError ||
+		r[0].ToolUseID == msgs[2].ToolResults[0].ToolUseID {
+		t.Errorf("retry edit results = %+v", r)
+	}
+}
+
+func TestParseAiderHistoryWithoutInputs(t *testing.T) {
+	dir := writeAiderRepo(t, aiderHistory, "")
+	results, err := ParseAiderHistory(
+		filepath.Join(dir, AiderHistoryFile), dir, "m",
+	)
+	if err != nil {
+		t.Fatalf("ParseAiderHistory: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(results))
+	}
+	sess := results[0].Session
+	if !sess.EndedAt.Equal(sess.StartedAt) {
+		t.Errorf("EndedAt = %v, want start %v",
+			sess.EndedAt, sess.StartedAt)
+	}
+	for _, m := range results[0].Messages {
+		if !m.Timestamp.IsZero() {
+			t.Errorf("message %d has timestamp %v", m.Ordinal, m.Timestamp)
+		}
+	}
+}
+
+func TestParseAiderCount(t *testing.T) {
+	tests := map[string]int64{
+		"840":   840,
+		"2.1k":  2100,
+		"12k":   12000,
+		"1.2M":  1200000,
+		"1,024": 1024,
+		"bad":   0,
+	}
+	for in, want := range tests {
+		if got := parseAiderCount(in); got != want {
+			t.Errorf("parseAiderCount(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// ampThreadBuilder accumulates messages while walking the
+// messages of an Amp thread.
+type ampThreadBuilder struct {
+	messages     []ParsedMessage
+	firstMessage string
+	userCount    int
+	ordinal      int
+	// last is the time of the latest message seen, used for
+	// messages that record none.
+	last time.Time
+}
+
+// ParseAmpThread parses an Amp thread JSON file. Returns
+// (nil, nil, nil) for threads without user messages.
+func ParseAmpThread(
+	path, machine string,
+) (*ParsedSession, []ParsedMessage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if !gjson.ValidBytes(data) {
+		return nil, nil, fmt.Errorf("invalid JSON in %s", path)
+	}
+	root := gjson.ParseBytes(data)
+
+	threadID := root.Get("id").Str
+	if threadID == "" {
+		threadID = AmpThreadID(path)
+	}
+
+	created := millisToTime(root.Get("created").Int())
+	b := ampThreadBuilder{last: created}
+	root.Get("messages").ForEach(func(_, msg gjson.Result) bool {
+		switch msg.Get("role").Str {
+		case "user":
+			b.addUser(msg)
+		case "assistant":
+			b.addAssistant(msg)
+		}
+		return true
+	})
+	if b.userCount == 0 {
+		return nil, nil, nil
+	}
+
+	cwd := fileURLPath(root.Get("env.initial.trees.0.uri").Str)
+	project := ExtractProjectFromCwd(cwd)
+	if project == "" {
+		project = "unknown"
+	}
+
+	startedAt, endedAt := created, created
+	for _, m := range b.messages {
+		if m.Timestamp.IsZero() {
+			continue
+		}
+		if startedAt.IsZero() || m.Timestamp.Before(startedAt) {
+			startedAt = m.Timestamp
+		}
+		if m.Timestamp.After(endedAt) {
+			endedAt = m.Timestamp
+		}
+	}
+
+	sess := &ParsedSession{
+		ID:               "amp:" + threadID,
+		Project:          project,
+		Machine:          machine,
+		Agent:            AgentAmp,
+		FirstMessage:     b.firstMessage,
+		StartedAt:        startedAt,
+		EndedAt:          endedAt,
+		MessageCount:     len(b.messages),
+		UserMessageCount: b.userCount,
+		File: FileInfo{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: info.ModTime().UnixNano(),
+		},
+	}
+	sess.SetWorkspace(cwd, "")
+	// Amp records usage on every assistant message, and a turn
+	// served from the cache may report cache tokens alone.
+	for _, m := range b.messages {
+		if m.Role == RoleAssistant {
+			sess.addTokens(m)
+		}
+	}
+	return sess, b.messages, nil
+}
+
+// AmpThreadID returns the thread ID of an Amp thread file, which
+// is its name without the extension.
+func AmpThreadID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".json")
+}
+
+// addUser adds a user message. Tool results travel in user
+// messages, so a message may carry only tool results.
+func (b *ampThreadBuilder) addUser(msg gjson.Result) {
+	ts := b.stamp(millisToTime(msg.Get("meta.sentAt").Int()))
+
+	var (
+		parts   []string
+		results []ParsedToolResult
+	)
+	msg.Get("content").ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").Str {
+		case "text":
+			if text := strings.TrimSpace(block.Get("text").Str); text != "" {
+				parts = append(parts, text)
+			}
+		case "tool_result":
+			results = append(results, ampToolResult(block))
+		}
+		return true
+	})
+
+	text := strings.Join(parts, "\n")
+	if text == "" && len(results) == 0 {
+		return
+	}
+	if text != "" {
+		if b.firstMessage == "" {
+			b.firstMessage = truncate(
+				strings.ReplaceAll(text, "\n", " "), 300,
+			)
+		}
+		b.userCount++
+	}
+
+	length := len(text)
+	for _, r := range results {
+		length += r.ContentLength
+	}
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:       b.ordinal,
+		Role:          RoleUser,
+		Content:       text,
+		Timestamp:     ts,
+		ContentLength: length,
+		ToolResults:   results,
+	})
+	b.ordinal++
+}
+
+func (b *ampThreadBuilder) addAssistant(msg gjson.Result) {
+	usage := msg.Get("usage")
+	ts := b.stamp(parseTimestamp(usage.Get("timestamp").Str))
+
+	var (
+		parts       []string
+		calls       []ParsedToolCall
+		hasThinking bool
+	)
+	msg.Get("content").ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").Str {
+		case "text":
+			if text := strings.TrimSpace(block.Get("text").Str); text != "" {
+				parts = append(parts, text)
+			}
+		case "thinking":
+			if text := strings.TrimSpace(block.Get("thinking").Str); text != "" {
+				hasThinking = true
+				parts = append(parts, "[Thinking]\n"+text)
+			}
+		case "tool_use":
+			name := block.Get("name").Str
+			if name == "" {
+				return true
+			}
+			input := block.Get("input").Raw
+			if input == "" {
+				input = "{}"
+			}
+			call := ParsedToolCall{
+				ToolUseID: block.Get("id").Str,
+				ToolName:  name,
+				Category:  NormalizeToolCategory(name),
+				InputJSON: input,
+			}
+			calls = append(calls, call)
+			parts = append(parts, formatToolHeader(
+				call.Category, ampToolDetail(block.Get("input")),
+			))
+		}
+		return true
+	})
+
+	text := strings.Join(parts, "\n\n")
+	if text == "" {
+		return
+	}
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:                  b.ordinal,
+		Role:                     RoleAssistant,
+		Content:                  text,
+		Timestamp:                ts,
+		HasThinking:              hasThinking,
+		HasToolUse:               len(calls) > 0,
+		ContentLength:            len(text),
+		ToolCalls:                calls,
+		Model:                    usage.Get("model").Str,
+		InputTokens:              usage.Get("inputTokens").Int(),
+		OutputTokens:             usage.Get("outputTokens").Int(),
+		CacheCreationInputTokens: usage.Get("cacheCreationInputTokens").Int(),
+		CacheReadInputTokens:     usage.Get("cacheReadInputTokens").Int(),
+	})
+	b.ordinal++
+}
+
+// stamp returns ts, remembering it as the latest message time.
+// Messages without a time of their own get the latest one, so
+// tool results line up with the calls they answer.
+func (b *ampThreadBuilder) stamp(ts time.Time) time.Time {
+	if ts.IsZero() {
+		return b.last
+	}
+	b.last = ts
+	return ts
+}
+
+// ampToolDetail returns the part of a tool's input shown next to
+// its category.
+func ampToolDetail(in gjson.Result) string {
+	for _, key := range []string{
+		"path", "cmd", "command", "pattern", "filePattern",
+		"query", "url",
+	} {
+		if v := in.Get(key).Str; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ampToolResult converts a tool_result block. The run's result
+// is a string, a command's output and exit code, or other JSON.
+func ampToolResult(block gjson.Result) ParsedToolResult {
+	run := block.Get("run")
+	result := run.Get("result")
+
+	r := ParsedToolResult{ToolUseID: block.Get("toolUseID").Str}
+	switch {
+	case result.Type == gjson.String:
+		r.Content = result.Str
+	case result.Get("output").Exists():
+		r.Content = result.Get("output").Str
+		if code := result.Get("exitCode"); code.Type == gjson.Number {
+			n := int(code.Int())
+			r.ExitCode = &n
+		}
+	case result.Exists():
+		r.Content = result.Raw
+	default:
+		r.Content = run.Get("error.message").Str
+	}
+	r.ContentLength = len(r.Content)
+
+	switch run.Get("status").Str {
+	case "error", "cancelled", "rejected-by-user":
+		r.IsError = true
+	}
+	return r
+}
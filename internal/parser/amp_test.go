@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+// ampCreated is the creation time, in Unix milliseconds, of the
+// Amp test threads.
+const ampCreated int64 = 1718000000000
+
+func TestParseAmpThread(t *testing.T) {
+	content := testjsonl.AmpThreadJSON(
+		"T-1234", ampCreated, "Greeting", "file:///src/greeter",
+		[]map[string]any{
+			testjsonl.AmpUserMsg(0, ampCreated+1000, "Add a greet function"),
+			testjsonl.AmpAssistantMsg(1, testjsonl.AmpMsgOpts{
+				Thinking: "Read it first.",
+				ToolUses: []testjsonl.AmpToolUse{{
+					ID: "toolu_1", Name: "Read",
+					Input: map[string]any{"path": "/src/greeter/app.py"},
+				}},
+				Model:        "claude-sonnet-4-20250514",
+				InputTokens:  10,
+				OutputTokens: 40,
+				CacheWrite:   2000,
+				Timestamp:    "2024-06-10T06:13:25Z",
+			}),
+			testjsonl.AmpToolResultMsg(2, "toolu_1", "done", "def main(): pass"),
+			testjsonl.AmpAssistantMsg(3, testjsonl.AmpMsgOpts{
+				ToolUses: []testjsonl.AmpToolUse{{
+					ID: "toolu_2", Name: "Bash",
+					Input: map[string]any{"cmd": "pytest"},
+				}},
+				Model:        "claude-sonnet-4-20250514",
+				InputTokens:  5,
+				OutputTokens: 20,
+				CacheRead:    2000,
+				Timestamp:    "2024-06-10T06:13:30Z",
+			}),
+			testjsonl.AmpToolResultMsg(4, "toolu_2", "done",
+				map[string]any{"output": "1 failed", "exitCode": 1}),
+			testjsonl.AmpToolResultMsg(5, "toolu_3", "rejected-by-user", nil),
+			testjsonl.AmpAssistantMsg(6, testjsonl.AmpMsgOpts{
+				Text:      "One test fails.",
+				Model:     "claude-sonnet-4-20250514",
+				CacheRead: 500,
+				Timestamp: "2024-06-10T06:13:40Z",
+			}),
+		},
+	)
+	path := createTestFile(t, "T-1234.json", content)
+
+	sess, msgs, err := ParseAmpThread(path, "laptop")
+	require.NoError(t, err)
+	require.NotNil(t, sess)
+
+	assertSessionMeta(t, sess, "amp:T-1234", "greeter", AgentAmp)
+	assert.Equal(t, "/src/greeter", sess.Cwd)
+	assert.Equal(t, "Add a greet function", sess.FirstMessage)
+	assert.Equal(t, 1, sess.UserMessageCount)
+	assertTimestamp(t, sess.StartedAt, time.UnixMilli(ampCreated))
+	assertTimestamp(t, sess.EndedAt,
+		time.Date(2024, 6, 10, 6, 13, 40, 0, time.UTC))
+	assert.Equal(t, int64(15), sess.InputTokens)
+	assert.Equal(t, int64(60), sess.OutputTokens)
+	assert.Equal(t, int64(2000), sess.CacheCreationInputTokens)
+	// The last reply was served from the cache alone.
+	assert.Equal(t, int64(2500), sess.CacheReadInputTokens)
+	assert.Equal(t, int64(60),
+		sess.TokensByModel["claude-sonnet-4-20250514"].OutputTokens)
+	assert.Equal(t, int64(2500),
+		sess.TokensByModel["claude-sonnet-4-20250514"].CacheReadInputTokens)
+
+	assertMessageCount(t, len(msgs), 7)
+	assertMessage(t, msgs[0], RoleUser, "Add a greet function")
+	assertTimestamp(t, msgs[0].Timestamp, time.UnixMilli(ampCreated+1000))
+
+	read := msgs[1]
+	assert.Equal(t,
+		"[Thinking]\nRead it first.\n\n[Read: /src/greeter/app.py]",
+		read.Content)
+	assert.Equal(t, "claude-sonnet-4-20250514", read.Model)
+	assertToolCalls(t, read.ToolCalls, []ParsedToolCall{{
+		ToolUseID: "toolu_1", ToolName: "Read", Category: "Read",
+	}})
+
+	// Tool results take the time of the call they answer.
+	require.Len(t, msgs[2].ToolResults, 1)
+	assert.Equal(t, "def main(): pass", msgs[2].ToolResults[0].Content)
+	assertTimestamp(t, msgs[2].Timestamp, read.Timestamp)
+
+	assertMessage(t, msgs[3], RoleAssistant, "[Bash: pytest]")
+	cmd := msgs[4].ToolResults
+	require.Len(t, cmd, 1)
+	assert.Equal(t, "1 failed", cmd[0].Content)
+	assertExitCode(t, 0, cmd[0].ExitCode, intPtr(1))
+	assert.False(t, cmd[0].IsError)
+
+	require.Len(t, msgs[5].ToolResults, 1)
+	assert.True(t, msgs[5].ToolResults[0].IsError)
+	assertMessage(t, msgs[6], RoleAssistant, "One test fails.")
+}
+
+func TestParseAmpThreadWithoutUserMessages(t *testing.T) {
+	content := testjsonl.AmpThreadJSON(
+		"T-empty", ampCreated, "", "", []map[string]any{},
+	)
+	path := createTestFile(t, "T-empty.json", content)
+
+	sess, msgs, err := ParseAmpThread(path, "m")
+	require.NoError(t, err)
+	assert.Nil(t, sess)
+	assert.Nil(t, msgs)
+
+	path = createTestFile(t, "T-bad.json", "{")
+	_, _, err = ParseAmpThread(path, "m")
+	assert.Error(t, err)
+}
+
+func TestParseAmpThreadWithoutWorkspace(t *testing.T) {
+	content := testjsonl.AmpThreadJSON(
+		"", ampCreated, "", "", []map[string]any{
+			testjsonl.AmpUserMsg(0, 0, "hi"),
+		},
+	)
+	path := createTestFile(t, "T-5678.json", content)
+
+	sess, msgs, err := ParseAmpThread(path, "m")
+	require.NoError(t, err)
+	require.NotNil(t, sess)
+	// The file name gives the ID when the thread has none.
+	assertSessionMeta(t, sess, "amp:T-5678", "unknown", AgentAmp)
+	assertTimestamp(t, msgs[0].Timestamp, time.UnixMilli(ampCreated))
+}
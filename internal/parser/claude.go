@@ -5,6 +5,7 @@ package parser
 import (
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -43,16 +44,30 @@ type dagEntry struct {
 func ParseClaudeSession(
 	path, project, machine string,
 ) ([]ParseResult, error) {
+	results, _, err := ParseClaudeSessionCheckpoint(
+		path, project, machine,
+	)
+	return results, err
+}
+
+// ParseClaudeSessionCheckpoint is ParseClaudeSession that also
+// returns a Checkpoint for resuming the parse once more lines
+// are appended. The checkpoint is nil when the file cannot be
+// continued incrementally: it split into fork sessions, its
+// DAG is malformed, or it has no user entry yet.
+func ParseClaudeSessionCheckpoint(
+	path, project, machine string,
+) ([]ParseResult, *Checkpoint, error) {
 	info, err := os.Stat(path)
 	if err != nil {
-		return nil, fmt.Errorf("stat %s: %w", path, err)
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
 	}
 
 	sessionID := strings.TrimSuffix(filepath.Base(path), ".jsonl")
 
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("open %s: %w", path, err)
+		return nil, nil, fmt.Errorf("open %s: %w", path, err)
 	}
 	defer f.Close()
 
@@ -64,10 +79,13 @@ func ParseClaudeSession(
 		parentSessionID       string
 		foundParentSID        bool
 		foundTranscriptParent bool
+		sawUser               bool
 		lineIndex             int
 		subagentMap           = map[string]string{}
 		globalStart           time.Time
 		globalEnd             time.Time
+		offset                int64
+		cwd, gitBranch        string
 	)
 	allHaveUUID = true
 
@@ -78,8 +96,14 @@ func ParseClaudeSession(
 			break
 		}
 		if !gjson.Valid(line) {
+			// A partial line at EOF is still being written;
+			// leave it for the next parse.
+			if lr.terminated {
+				offset = lr.pos
+			}
 			continue
 		}
+		offset = lr.pos
 
 		entryType := gjson.Get(line, "type").Str
 
@@ -96,24 +120,8 @@ func ParseClaudeSession(
 
 		// Collect queue-operation enqueue entries for subagent mapping.
 		if entryType == "queue-operation" {
-			if gjson.Get(line, "operation").Str == "enqueue" {
-				contentStr := gjson.Get(line, "content").Str
-				if contentStr != "" {
-					tuid := gjson.Get(contentStr, "tool_use_id").Str
-					taskID := gjson.Get(contentStr, "task_id").Str
-					if tuid == "" || taskID == "" {
-						// Fallback: extract from XML <task-id> and <tool-use-id> tags.
-						if m := xmlTaskIDRe.FindStringSubmatch(contentStr); m != nil {
-							taskID = m[1]
-						}
-						if m := xmlToolUseRe.FindStringSubmatch(contentStr); m != nil {
-							tuid = m[1]
-						}
-					}
-					if tuid != "" && taskID != "" {
-						subagentMap[tuid] = "agent-" + taskID
-					}
-				}
+			if tuid, sid, ok := parseSubagentEnqueue(line); ok {
+				subagentMap[tuid] = sid
 			}
 			continue
 		}
@@ -121,6 +129,10 @@ func ParseClaudeSession(
 		if entryType != "user" && entryType != "assistant" {
 			continue
 		}
+		if entryType == "user" {
+			sawUser = true
+			cwd, gitBranch = claudeWorkspace(line, cwd, gitBranch)
+		}
 
 		// Check parentSessionID from first user/assistant entry.
 		if !foundParentSID {
@@ -163,7 +175,7 @@ func ParseClaudeSession(
 	}
 
 	if err := lr.Err(); err != nil {
-		return nil, fmt.Errorf("reading %s: %w", path, err)
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
 	}
 
 	fileInfo := FileInfo{
@@ -172,21 +184,96 @@ func ParseClaudeSession(
 		Mtime: info.ModTime().UnixNano(),
 	}
 
-	// If all user/assistant entries have uuids, use DAG-aware processing.
-	if hasAnyUUID && allHaveUUID {
-		return parseDAG(
+	// If all user/assistant entries have uuids and form a
+	// well-formed DAG, use DAG-aware processing. Otherwise
+	// fall back to linear processing to avoid dropping messages.
+	useDAG := hasAnyUUID && allHaveUUID
+	var (
+		results  []ParseResult
+		mainPath []dagEntry
+	)
+	if useDAG && isWellFormedDAG(entries) {
+		results, mainPath = parseDAG(
+			entries, sessionID, project, machine,
+			parentSessionID, fileInfo, subagentMap,
+			globalStart, globalEnd,
+		)
+	} else {
+		results = parseLinear(
 			entries, sessionID, project, machine,
 			parentSessionID, fileInfo, subagentMap,
 			globalStart, globalEnd,
 		)
+		// A malformed DAG may become well-formed as lines
+		// are appended, so it cannot be resumed linearly.
+		if !useDAG {
+			mainPath = entries
+		}
 	}
 
-	// Fall back to linear processing.
-	return parseLinear(
-		entries, sessionID, project, machine,
-		parentSessionID, fileInfo, subagentMap,
-		globalStart, globalEnd,
-	)
+	if cwd != "" || gitBranch != "" {
+		for i := range results {
+			results[i].Session.SetWorkspace(cwd, gitBranch)
+		}
+	}
+
+	if len(results) != 1 || mainPath == nil || !sawUser {
+		return results, nil, nil
+	}
+	cp := &Checkpoint{
+		Offset:      offset,
+		agent:       AgentClaude,
+		session:     results[0].Session,
+		nextOrdinal: len(results[0].Messages),
+		dag:         useDAG,
+		subagents:   subagentMap,
+	}
+	if useDAG {
+		cp.leafUUID = mainPath[len(mainPath)-1].uuid
+	}
+	cp.lastMsgID, cp.lastUsage = lastClaudeUsage(mainPath)
+	return results, cp, nil
+}
+
+// claudeWorkspace updates the working directory and git branch
+// of a session from a user entry. The first cwd is kept, as it
+// names the project, while the branch follows the session, so
+// work that moves to a new feature branch is attributed to it.
+func claudeWorkspace(line, cwd, gitBranch string) (string, string) {
+	if cwd == "" {
+		cwd = gjson.Get(line, "cwd").Str
+	}
+	if b := gjson.Get(line, "gitBranch").Str; b != "" {
+		gitBranch = b
+	}
+	return cwd, gitBranch
+}
+
+// parseSubagentEnqueue extracts the tool_use_id and subagent
+// session ID from a queue-operation enqueue line.
+func parseSubagentEnqueue(line string) (string, string, bool) {
+	if gjson.Get(line, "operation").Str != "enqueue" {
+		return "", "", false
+	}
+	contentStr := gjson.Get(line, "content").Str
+	if contentStr == "" {
+		return "", "", false
+	}
+	tuid := gjson.Get(contentStr, "tool_use_id").Str
+	taskID := gjson.Get(contentStr, "task_id").Str
+	if tuid == "" || taskID == "" {
+		// Fallback: extract from XML <task-id> and <tool-use-id> tags.
+		if m := xmlTaskIDRe.FindStringSubmatch(contentStr); m != nil {
+			taskID = m[1]
+		}
+		if m := xmlToolUseRe.FindStringSubmatch(contentStr); m != nil {
+			tuid = m[1]
+		}
+	}
+	if tuid == "" || taskID == "" {
+		return "", "", false
+	}
+	return tuid, "agent-" + taskID, true
 }
 
 // parseLinear processes entries sequentially without DAG awareness.
@@ -196,7 +283,7 @@ func parseLinear(
 	fileInfo FileInfo,
 	subagentMap map[string]string,
 	globalStart, globalEnd time.Time,
-) ([]ParseResult, error) {
+) []ParseResult {
 	messages, startedAt, endedAt, tokens := extractMessages(entries)
 	startedAt = earlierTime(globalStart, startedAt)
 	endedAt = laterTime(globalEnd, endedAt)
@@ -234,28 +321,52 @@ func parseLinear(
 		File:                     fileInfo,
 	}
 
-	return []ParseResult{{Session: sess, Messages: messages}}, nil
+	return []ParseResult{{Session: sess, Messages: messages}}
+}
+
+// isWellFormedDAG reports whether entries have exactly one root
+// and every parentUuid reference resolves to an existing entry's
+// uuid.
+func isWellFormedDAG(entries []dagEntry) bool {
+	uuidSet := make(map[string]struct{}, len(entries))
+	roots := 0
+	for _, e := range entries {
+		if e.uuid != "" {
+			uuidSet[e.uuid] = struct{}{}
+		}
+		if e.parentUuid == "" {
+			roots++
+		}
+	}
+	if roots != 1 {
+		return false
+	}
+	for _, e := range entries {
+		if e.parentUuid != "" {
+			if _, ok := uuidSet[e.parentUuid]; !ok {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 // parseDAG builds a parent->children adjacency map and walks the
 // tree to detect fork points. Large-gap forks produce separate
 // ParseResults; small-gap retries follow the latest branch.
+// Entries must form a well-formed DAG (see isWellFormedDAG).
+// Also returns the entries on the main session's path.
 func parseDAG(
 	entries []dagEntry,
 	sessionID, project, machine, parentSessionID string,
 	fileInfo FileInfo,
 	subagentMap map[string]string,
 	globalStart, globalEnd time.Time,
-) ([]ParseResult, error) {
-	// Build parent -> children ordered by line position and
-	// collect the set of all uuids for connectivity checks.
+) ([]ParseResult, []dagEntry) {
+	// Build parent -> children ordered by line position.
 	children := make(map[string][]int, len(entries))
-	uuidSet := make(map[string]struct{}, len(entries))
 	var roots []int
 	for i, e := range entries {
-		if e.uuid != "" {
-			uuidSet[e.uuid] = struct{}{}
-		}
 		if e.parentUuid == "" {
 			roots = append(roots, i)
 		} else {
@@ -263,28 +374,6 @@ func parseDAG(
 		}
 	}
 
-	// A well-formed DAG has exactly one root and all parentUuid
-	// references resolve to an existing entry's uuid. If not,
-	// fall back to linear parsing to avoid dropping messages.
-	if len(roots) != 1 {
-		return parseLinear(
-			entries, sessionID, project, machine,
-			parentSessionID, fileInfo, subagentMap,
-			globalStart, globalEnd,
-		)
-	}
-	for _, e := range entries {
-		if e.parentUuid != "" {
-			if _, ok := uuidSet[e.parentUuid]; !ok {
-				return parseLinear(
-					entries, sessionID, project, machine,
-					parentSessionID, fileInfo, subagentMap,
-					globalStart, globalEnd,
-				)
-			}
-		}
-	}
-
 	// Walk from the root, collecting branches.
 	// branches[0] is the main branch; subsequent entries are forks.
 	type branch struct {
@@ -352,13 +441,19 @@ func parseDAG(
 	branches = append(branches, forkBranches...)
 
 	// Build results for each branch.
-	var results []ParseResult
+	var (
+		results  []ParseResult
+		mainEnts []dagEntry
+	)
 
 	for i, b := range branches {
 		branchEntries := make([]dagEntry, len(b.indices))
 		for j, idx := range b.indices {
 			branchEntries[j] = entries[idx]
 		}
+		if i == 0 {
+			mainEnts = branchEntries
+		}
 
 		messages, startedAt, endedAt, tokens := extractMessages(branchEntries)
 		// Main session uses global bounds to capture timestamps
@@ -420,7 +515,7 @@ func parseDAG(
 		})
 	}
 
-	return results, nil
+	return results, mainEnts
 }
 
 // countUserTurns counts the number of user entries reachable from
@@ -455,130 +550,205 @@ type tokenUsage struct {
 	ByModel                  map[string]ModelTokenUsage
 }
 
-// extractMessages converts dagEntries into ParsedMessages, applying
-// the same filtering and content extraction as the original linear
-// parser. Also returns accumulated token usage from assistant messages.
-func extractMessages(entries []dagEntry) (
-	[]ParsedMessage, time.Time, time.Time, tokenUsage,
-) {
-	var (
-		messages  []ParsedMessage
-		startedAt time.Time
-		endedAt   time.Time
-		ordinal   int
-		tokens    tokenUsage
-	)
+// claudeUsage is the token usage reported on an assistant line.
+type claudeUsage struct {
+	model         string
+	input         int64
+	output        int64
+	cacheCreation int64
+	cacheRead     int64
+}
 
-	// Track the last usage seen per messageId to avoid
-	// double-counting from streaming duplicate lines.
-	type usageEntry struct {
-		model         string
-		input         int64
-		output        int64
-		cacheCreation int64
-		cacheRead     int64
-	}
-	lastUsage := make(map[string]usageEntry)
+// claudeUsageOf returns the usage on an assistant entry keyed by
+// its message ID (falling back to the entry uuid).
+func claudeUsageOf(e dagEntry) (string, claudeUsage, bool) {
+	if e.entryType != "assistant" {
+		return "", claudeUsage{}, false
+	}
+	usage := gjson.Get(e.line, "message.usage")
+	if !usage.Exists() {
+		return "", claudeUsage{}, false
+	}
+	msgID := gjson.Get(e.line, "message.id").Str
+	if msgID == "" {
+		msgID = e.uuid
+	}
+	return msgID, claudeUsage{
+		model:         gjson.Get(e.line, "message.model").Str,
+		input:         usage.Get("input_tokens").Int(),
+		output:        usage.Get("output_tokens").Int(),
+		cacheCreation: usage.Get("cache_creation_input_tokens").Int(),
+		cacheRead:     usage.Get("cache_read_input_tokens").Int(),
+	}, true
+}
 
-	for _, e := range entries {
-		if !e.timestamp.IsZero() {
-			if startedAt.IsZero() {
-				startedAt = e.timestamp
-			}
-			endedAt = e.timestamp
-		}
-
-		// Accumulate token usage from assistant entries.
-		// The JSONL contains multiple streaming lines per message;
-		// we keep only the last entry per messageId.
-		if e.entryType == "assistant" {
-			usage := gjson.Get(e.line, "message.usage")
-			if usage.Exists() {
-				msgID := gjson.Get(e.line, "message.id").Str
-				if msgID == "" {
-					msgID = e.uuid
-				}
-				ue := usageEntry{
-					model:         gjson.Get(e.line, "message.model").Str,
-					input:         usage.Get("input_tokens").Int(),
-					output:        usage.Get("output_tokens").Int(),
-					cacheCreation: usage.Get("cache_creation_input_tokens").Int(),
-					cacheRead:     usage.Get("cache_read_input_tokens").Int(),
-				}
-				if msgID != "" {
-					lastUsage[msgID] = ue
-				} else {
-					// No message ID; accumulate directly.
-					tokens.InputTokens += ue.input
-					tokens.OutputTokens += ue.output
-					tokens.CacheCreationInputTokens += ue.cacheCreation
-					tokens.CacheReadInputTokens += ue.cacheRead
-				}
-			}
-		}
+// sub returns the usage u adds to prev.
+func (u claudeUsage) sub(prev claudeUsage) claudeUsage {
+	u.input -= prev.input
+	u.output -= prev.output
+	u.cacheCreation -= prev.cacheCreation
+	u.cacheRead -= prev.cacheRead
+	return u
+}
 
-		// Detect system-injected user entries.
-		isSystem := false
-		if e.entryType == "user" {
-			if gjson.Get(e.line, "isMeta").Bool() ||
-				gjson.Get(e.line, "isCompactSummary").Bool() {
-				isSystem = true
-			}
+// lastClaudeUsage returns the message ID and usage of the last
+// assistant entry carrying usage.
+func lastClaudeUsage(entries []dagEntry) (string, claudeUsage) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if msgID, u, ok := claudeUsageOf(entries[i]); ok {
+			return msgID, u
 		}
+	}
+	return "", claudeUsage{}
+}
 
-		content := gjson.Get(e.line, "message.content")
-		text, hasThinking, hasToolUse, tcs, trs :=
-			ExtractTextContent(content)
-		if strings.TrimSpace(text) == "" && len(trs) == 0 {
-			continue
+// add accumulates u into the session and per-model totals.
+func (t *tokenUsage) add(u claudeUsage, sign int64) {
+	t.InputTokens += sign * u.input
+	t.OutputTokens += sign * u.output
+	t.CacheCreationInputTokens += sign * u.cacheCreation
+	t.CacheReadInputTokens += sign * u.cacheRead
+
+	if u.model != "" {
+		if t.ByModel == nil {
+			t.ByModel = make(map[string]ModelTokenUsage)
 		}
+		m := t.ByModel[u.model]
+		m.InputTokens += sign * u.input
+		m.OutputTokens += sign * u.output
+		m.CacheCreationInputTokens += sign * u.cacheCreation
+		m.CacheReadInputTokens += sign * u.cacheRead
+		t.ByModel[u.model] = m
+	}
+}
 
-		// Detect known system-injected patterns.
-		if e.entryType == "user" && !isSystem &&
-			isClaudeSystemMessage(text) {
-			isSystem = true
+// claudeExtractor converts dagEntries into ParsedMessages one at
+// a time, so a resumed parse can continue where a previous one
+// stopped.
+type claudeExtractor struct {
+	messages  []ParsedMessage
+	startedAt time.Time
+	endedAt   time.Time
+	ordinal   int
+	// base holds usage already settled: lines without a message
+	// ID plus, when resuming, all but the last message.
+	base tokenUsage
+	// lastUsage tracks the last usage seen per messageId to
+	// avoid double-counting from streaming duplicate lines.
+	lastUsage map[string]claudeUsage
+	lastMsgID string
+}
+
+func newClaudeExtractor() *claudeExtractor {
+	return &claudeExtractor{
+		lastUsage: make(map[string]claudeUsage),
+	}
+}
+
+func (x *claudeExtractor) add(e dagEntry) {
+	if !e.timestamp.IsZero() {
+		if x.startedAt.IsZero() {
+			x.startedAt = e.timestamp
+		}
+		x.endedAt = e.timestamp
+	}
+
+	// Accumulate token usage from assistant entries.
+	// The JSONL contains multiple streaming lines per message;
+	// we keep only the last entry per messageId. Each line's
+	// message records what the line adds to the usage reported
+	// before it, so the messages of a turn sum to its usage.
+	msgID, u, hasUsage := claudeUsageOf(e)
+	var lineUsage claudeUsage
+	if hasUsage {
+		lineUsage = u.sub(x.lastUsage[msgID])
+		if msgID != "" {
+			x.lastUsage[msgID] = u
+			x.lastMsgID = msgID
+		} else {
+			// No message ID; accumulate directly.
+			x.base.InputTokens += u.input
+			x.base.OutputTokens += u.output
+			x.base.CacheCreationInputTokens += u.cacheCreation
+			x.base.CacheReadInputTokens += u.cacheRead
 		}
+	}
 
-		role := RoleType(e.entryType)
-		if isSystem {
-			role = RoleSystem
+	// Detect system-injected user entries.
+	isSystem := false
+	if e.entryType == "user" {
+		if gjson.Get(e.line, "isMeta").Bool() ||
+			gjson.Get(e.line, "isCompactSummary").Bool() {
+			isSystem = true
 		}
+	}
 
-		messages = append(messages, ParsedMessage{
-			Ordinal:       ordinal,
-			Role:          role,
-			Content:       text,
-			Timestamp:     e.timestamp,
-			HasThinking:   hasThinking,
-			HasToolUse:    hasToolUse,
-			ContentLength: len(text),
-			ToolCalls:     tcs,
-			ToolResults:   trs,
-		})
-		ordinal++
+	content := gjson.Get(e.line, "message.content")
+	text, hasThinking, hasToolUse, tcs, trs :=
+		ExtractTextContent(content)
+	if strings.TrimSpace(text) == "" && len(trs) == 0 {
+		return
 	}
 
-	// Sum the final usage per unique message, both as
-	// session-level aggregates and per-model breakdowns.
-	byModel := make(map[string]ModelTokenUsage)
-	for _, ue := range lastUsage {
-		tokens.InputTokens += ue.input
-		tokens.OutputTokens += ue.output
-		tokens.CacheCreationInputTokens += ue.cacheCreation
-		tokens.CacheReadInputTokens += ue.cacheRead
+	// Detect known system-injected patterns.
+	if e.entryType == "user" && !isSystem &&
+		isClaudeSystemMessage(text) {
+		isSystem = true
+	}
+
+	role := RoleType(e.entryType)
+	if isSystem {
+		role = RoleSystem
+	}
+	var model string
+	if role == RoleAssistant {
+		model = gjson.Get(e.line, "message.model").Str
+	}
+
+	x.messages = append(x.messages, ParsedMessage{
+		Ordinal:                  x.ordinal,
+		Role:                     role,
+		Content:                  text,
+		Timestamp:                e.timestamp,
+		HasThinking:              hasThinking,
+		HasToolUse:               hasToolUse,
+		ContentLength:            len(text),
+		ToolCalls:                tcs,
+		ToolResults:              trs,
+		Model:                    model,
+		InputTokens:              lineUsage.input,
+		OutputTokens:             lineUsage.output,
+		CacheCreationInputTokens: lineUsage.cacheCreation,
+		CacheReadInputTokens:     lineUsage.cacheRead,
+	})
+	x.ordinal++
+}
 
-		if ue.model != "" {
-			m := byModel[ue.model]
-			m.InputTokens += ue.input
-			m.OutputTokens += ue.output
-			m.CacheCreationInputTokens += ue.cacheCreation
-			m.CacheReadInputTokens += ue.cacheRead
-			byModel[ue.model] = m
-		}
+// tokens sums the final usage per unique message, both as
+// session-level aggregates and per-model breakdowns.
+func (x *claudeExtractor) tokens() tokenUsage {
+	tokens := x.base
+	tokens.ByModel = make(
+		map[string]ModelTokenUsage, len(x.base.ByModel),
+	)
+	maps.Copy(tokens.ByModel, x.base.ByModel)
+	for _, u := range x.lastUsage {
+		tokens.add(u, 1)
 	}
-	tokens.ByModel = byModel
+	return tokens
+}
 
-	return messages, startedAt, endedAt, tokens
+// extractMessages converts dagEntries into ParsedMessages, applying
+// the same filtering and content extraction as the original linear
+// parser. Also returns accumulated token usage from assistant messages.
+func extractMessages(entries []dagEntry) (
+	[]ParsedMessage, time.Time, time.Time, tokenUsage,
+) {
+	x := newClaudeExtractor()
+	for _, e := range entries {
+		x.add(e)
+	}
+	return x.messages, x.startedAt, x.endedAt, x.tokens()
 }
 
 // annotateSubagentSessions sets SubagentSessionID on Task tool calls
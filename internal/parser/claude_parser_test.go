@@ -305,6 +305,60 @@ func TestParseClaudeSession_TokenUsage(t *testing.T) {
 		assert.Equal(t, int64(200), sess.CacheReadInputTokens)
 	})
 
+	t.Run("per-message usage sums to session totals", func(t *testing.T) {
+		content := testjsonl.JoinJSONL(
+			testjsonl.ClaudeUserJSON("hello", tsZero),
+			testjsonl.ClaudeAssistantWithUsageJSON(
+				[]map[string]string{{"type": "text", "text": "partial"}},
+				tsZeroS1, "msg-1",
+				50, 0, 5, 100,
+			),
+			testjsonl.ClaudeAssistantWithUsageJSON(
+				[]map[string]string{{"type": "text", "text": "complete"}},
+				tsZeroS2, "msg-1",
+				100, 50, 10, 200,
+			),
+		)
+		sess, msgs := runClaudeParserTest(t, "test.jsonl", content)
+		require.Len(t, msgs, 3)
+		assert.Equal(t, int64(0), msgs[0].InputTokens)
+
+		// Each streaming line carries the increase over the
+		// previous line for the same message ID.
+		assert.Equal(t, int64(50), msgs[1].InputTokens)
+		assert.Equal(t, int64(0), msgs[1].OutputTokens)
+		assert.Equal(t, int64(50), msgs[2].InputTokens)
+		assert.Equal(t, int64(50), msgs[2].OutputTokens)
+
+		var in, out, cc, cr int64
+		for _, m := range msgs {
+			in += m.InputTokens
+			out += m.OutputTokens
+			cc += m.CacheCreationInputTokens
+			cr += m.CacheReadInputTokens
+		}
+		assert.Equal(t, sess.InputTokens, in)
+		assert.Equal(t, sess.OutputTokens, out)
+		assert.Equal(t, sess.CacheCreationInputTokens, cc)
+		assert.Equal(t, sess.CacheReadInputTokens, cr)
+	})
+
+	t.Run("records assistant model", func(t *testing.T) {
+		content := testjsonl.JoinJSONL(
+			testjsonl.ClaudeUserJSON("hello", tsZero),
+			`{"type":"assistant","timestamp":"`+tsZeroS1+`",`+
+				`"message":{"id":"msg-1","model":"claude-sonnet-4-5",`+
+				`"content":[{"type":"text","text":"hi"}],`+
+				`"usage":{"input_tokens":7,"output_tokens":3}}}`,
+		)
+		_, msgs := runClaudeParserTest(t, "test.jsonl", content)
+		require.Len(t, msgs, 2)
+		assert.Equal(t, "", msgs[0].Model)
+		assert.Equal(t, "claude-sonnet-4-5", msgs[1].Model)
+		assert.Equal(t, int64(7), msgs[1].InputTokens)
+		assert.Equal(t, int64(3), msgs[1].OutputTokens)
+	})
+
 	t.Run("zero tokens when no usage data", func(t *testing.T) {
 		content := testjsonl.JoinJSONL(
 			testjsonl.ClaudeUserJSON("hello", tsZero),
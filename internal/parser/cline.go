@@ -0,0 +1,671 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Cline and Roo Code keep each task in its own directory under
+// the extension's VS Code global storage:
+// <storage>/tasks/<taskId>/. The API conversation holds the
+// messages; the UI messages hold their timestamps and the token
+// usage of each API request.
+const (
+	ClineHistoryFile    = "api_conversation_history.json"
+	ClineUIMessagesFile = "ui_messages.json"
+)
+
+// clineTools are the tools Cline and Roo Code call with XML tags
+// in the text of their replies, e.g. <read_file><path>a.go</path>
+// </read_file>.
+var clineTools = map[string]bool{
+	"read_file":                  true,
+	"write_to_file":              true,
+	"replace_in_file":            true,
+	"apply_diff":                 true,
+	"insert_content":             true,
+	"search_and_replace":         true,
+	"execute_command":            true,
+	"search_files":               true,
+	"list_files":                 true,
+	"list_code_definition_names": true,
+	"codebase_search":            true,
+	"browser_action":             true,
+	"web_fetch":                  true,
+	"use_mcp_tool":               true,
+	"access_mcp_resource":        true,
+	"ask_followup_question":      true,
+	"attempt_completion":         true,
+	"plan_mode_respond":          true,
+	"new_task":                   true,
+	"switch_mode":                true,
+	"update_todo_list":           true,
+}
+
+// clineUserTags wrap what the user typed: the task, feedback on
+// a tool call, the answer to a question, or new instructions
+// when a task is resumed.
+var clineUserTags = []string{"task", "feedback", "answer", "user_message"}
+
+var (
+	clineTagRe      = regexp.MustCompile(`<([a-z_]+)>`)
+	clineResultRe   = regexp.MustCompile(`^\[([a-z_]+)(?: for [^\]]*)?\] Result:`)
+	clineCwdRe      = regexp.MustCompile(`# Current (?:Working|Workspace) Directory \(([^)\n]+)\) Files`)
+	clineThinkingRe = regexp.MustCompile(`(?s)<thinking>\s*(.*?)\s*</thinking>`)
+	// Notices the extension adds to the conversation, such as
+	// "[TASK RESUMPTION]" or "[ERROR] You did not use a tool".
+	clineNoticeRe = regexp.MustCompile(`^\[[A-Z][A-Z ]*\]`)
+)
+
+// clineRequest is an api_req_started entry of the UI messages.
+type clineRequest struct {
+	ts          time.Time
+	replyTS     time.Time // last UI message before the next request
+	model       string    // model of the reply, when known
+	tokensIn    int64
+	tokensOut   int64
+	cacheWrites int64
+	cacheReads  int64
+	cost        float64
+}
+
+// clineUI is what the parser takes from the UI messages.
+type clineUI struct {
+	start, end time.Time
+	requests   []clineRequest
+}
+
+// clineTaskBuilder accumulates state while reading the API
+// conversation of a task.
+type clineTaskBuilder struct {
+	messages     []ParsedMessage
+	firstMessage string
+	cwd          string
+	model        string
+	ordinal      int
+	// pending are the XML tool calls of the last reply, which
+	// the next user message answers.
+	pending []ParsedToolCall
+}
+
+// ParseClineTask parses the Cline or Roo Code task whose API
+// conversation is at path. The UI messages next to it, when
+// present, give the messages their timestamps and token usage.
+// The file info carries the later mtime of the two files, so a
+// task is synced again when either changes. Returns (nil, nil,
+// nil) if the task has no messages.
+func ParseClineTask(
+	path, machine string,
+) (*ParsedSession, []ParsedMessage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if !gjson.ValidBytes(data) {
+		return nil, nil, fmt.Errorf("invalid JSON in %s", path)
+	}
+
+	dir := filepath.Dir(path)
+	uiPath := filepath.Join(dir, ClineUIMessagesFile)
+	ui := readClineUI(uiPath)
+
+	b := &clineTaskBuilder{}
+	user, reply := 0, 0
+	gjson.ParseBytes(data).ForEach(func(_, entry gjson.Result) bool {
+		switch entry.Get("role").Str {
+		case "user":
+			var ts time.Time
+			if user < len(ui.requests) {
+				ts = ui.requests[user].ts
+			}
+			b.addUser(entry.Get("content"), clineEntryTime(entry, ts))
+			user++
+		case "assistant":
+			var req *clineRequest
+			if reply < len(ui.requests) {
+				req = &ui.requests[reply]
+			}
+			b.addReply(entry, req)
+			reply++
+		}
+		return true
+	})
+
+	hasContent := false
+	userCount := 0
+	for _, m := range b.messages {
+		if m.Content != "" {
+			hasContent = true
+			if m.Role == RoleUser {
+				userCount++
+			}
+		}
+	}
+	if !hasContent {
+		return nil, nil, nil
+	}
+
+	start, end := ui.start, ui.end
+	if start.IsZero() {
+		start = clineTaskTime(filepath.Base(dir))
+	}
+	for _, m := range b.messages {
+		if m.Timestamp.IsZero() {
+			continue
+		}
+		if start.IsZero() || m.Timestamp.Before(start) {
+			start = m.Timestamp
+		}
+		if m.Timestamp.After(end) {
+			end = m.Timestamp
+		}
+	}
+
+	project := ExtractProjectFromCwd(b.cwd)
+	if project == "" {
+		project = "unknown"
+	}
+
+	sess := &ParsedSession{
+		ID:               "cline:" + filepath.Base(dir),
+		Project:          project,
+		Machine:          machine,
+		Agent:            AgentCline,
+		FirstMessage:     b.firstMessage,
+		StartedAt:        start,
+		EndedAt:          end,
+		MessageCount:     len(b.messages),
+		UserMessageCount: userCount,
+		File: FileInfo{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: ClineTaskMtime(path, info),
+		},
+	}
+	sess.SetWorkspace(b.cwd, "")
+	b.addTokens(sess, ui.requests)
+	return sess, b.messages, nil
+}
+
+// ClineTaskMtime returns the later modification time of the API
+// conversation at path, whose info is given, and the UI messages
+// next to it.
+func ClineTaskMtime(path string, info os.FileInfo) int64 {
+	mtime := info.ModTime().UnixNano()
+	ui, err := os.Stat(
+		filepath.Join(filepath.Dir(path), ClineUIMessagesFile),
+	)
+	if err == nil && ui.ModTime().UnixNano() > mtime {
+		mtime = ui.ModTime().UnixNano()
+	}
+	return mtime
+}
+
+// readClineUI reads the timestamps and API requests of the UI
+// messages at path. A missing or unreadable file yields none.
+func readClineUI(path string) clineUI {
+	var ui clineUI
+	data, err := os.ReadFile(path)
+	if err != nil || !gjson.ValidBytes(data) {
+		return ui
+	}
+	gjson.ParseBytes(data).ForEach(func(_, m gjson.Result) bool {
+		ts := clineMillis(m.Get("ts").Int())
+		if ts.IsZero() {
+			return true
+		}
+		if ui.start.IsZero() {
+			ui.start = ts
+		}
+		ui.end = ts
+		if m.Get("say").Str == "api_req_started" {
+			// The request details are a JSON document in the
+			// message text.
+			info := gjson.Parse(m.Get("text").Str)
+			ui.requests = append(ui.requests, clineRequest{
+				ts:          ts,
+				tokensIn:    info.Get("tokensIn").Int(),
+				tokensOut:   info.Get("tokensOut").Int(),
+				cacheWrites: info.Get("cacheWrites").Int(),
+				cacheReads:  info.Get("cacheReads").Int(),
+				cost:        info.Get("cost").Float(),
+			})
+		}
+		if n := len(ui.requests); n > 0 {
+			ui.requests[n-1].replyTS = ts
+		}
+		return true
+	})
+	return ui
+}
+
+// clineMillis converts a Unix millisecond timestamp.
+func clineMillis(ms int64) time.Time {
+	if ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
+
+// clineTaskTime returns the creation time encoded in a Cline
+// task ID, which is a Unix millisecond timestamp. Roo Code task
+// IDs are UUIDs and yield the zero time.
+func clineTaskTime(taskID string) time.Time {
+	ms, err := strconv.ParseInt(taskID, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return clineMillis(ms)
+}
+
+// clineEntryTime returns the timestamp recorded on an API
+// conversation entry by newer versions, or fallback.
+func clineEntryTime(entry gjson.Result, fallback time.Time) time.Time {
+	if ts := clineMillis(entry.Get("ts").Int()); !ts.IsZero() {
+		return ts
+	}
+	return fallback
+}
+
+// addUser adds a user entry of the API conversation. Besides
+// what the user typed, user entries carry the results of the
+// previous reply's tool calls and the editor's environment
+// details, which are dropped.
+func (b *clineTaskBuilder) addUser(content gjson.Result, ts time.Time) {
+	var (
+		texts   []string
+		results []ParsedToolResult
+		// inResult is the index of the result that text
+		// blocks belong to, or -1 before the first.
+		inResult = -1
+	)
+	addText := func(text string) {
+		if b.cwd == "" {
+			if m := clineCwdRe.FindStringSubmatch(text); m != nil {
+				b.cwd = strings.TrimSpace(m[1])
+			}
+		}
+		text = strings.TrimSpace(removeTagBlocks(text, "environment_details"))
+		if text == "" {
+			return
+		}
+		if m := clineResultRe.FindStringSubmatch(text); m != nil {
+			results = append(results, b.resultFor(m[1]))
+			inResult = len(results) - 1
+			text = strings.TrimSpace(text[len(m[0]):])
+		}
+		typed := clineUserText(text)
+		if inResult >= 0 {
+			addClineResultText(&results[inResult], text)
+		}
+		switch {
+		case typed != "":
+			texts = append(texts, typed)
+		case inResult < 0 && !clineNoticeRe.MatchString(text):
+			texts = append(texts, text)
+		}
+	}
+
+	if content.Type == gjson.String {
+		addText(content.Str)
+	}
+	content.ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").Str {
+		case "text":
+			addText(block.Get("text").Str)
+		case "tool_result":
+			out := toolResultContentText(block.Get("content"))
+			r := ParsedToolResult{
+				ToolUseID: block.Get("tool_use_id").Str,
+				IsError:   block.Get("is_error").Bool(),
+			}
+			addClineResultText(&r, out)
+			results = append(results, r)
+			if typed := clineUserText(out); typed != "" {
+				texts = append(texts, typed)
+			}
+		}
+		return true
+	})
+	b.pending = nil
+
+	text := strings.Join(texts, "\n\n")
+	if text == "" && len(results) == 0 {
+		return
+	}
+	if b.firstMessage == "" && text != "" {
+		b.firstMessage = truncate(strings.ReplaceAll(text, "\n", " "), 300)
+	}
+	contentLen := len(text)
+	for _, r := range results {
+		contentLen += r.ContentLength
+	}
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:       b.ordinal,
+		Role:          RoleUser,
+		Content:       text,
+		Timestamp:     ts,
+		ContentLength: contentLen,
+		ToolResults:   results,
+	})
+	b.ordinal++
+}
+
+// resultFor returns a result for the first pending call of the
+// named tool. Cline runs one tool per reply, so calls without a
+// match get a result with no tool use ID, which pairs with
+// nothing.
+func (b *clineTaskBuilder) resultFor(tool string) ParsedToolResult {
+	for i, tc := range b.pending {
+		if tc.ToolName == tool {
+			b.pending = append(b.pending[:i:i], b.pending[i+1:]...)
+			return ParsedToolResult{ToolUseID: tc.ToolUseID}
+		}
+	}
+	return ParsedToolResult{}
+}
+
+// addClineResultText adds text to the output of a tool result
+// and marks results that report a failure or a denial.
+func addClineResultText(r *ParsedToolResult, text string) {
+	if r.Content != "" {
+		r.Content += "\n"
+	}
+	r.Content += text
+	r.ContentLength = len(r.Content)
+	r.ExitCode = exitCodeFromOutput(r.Content)
+	if strings.HasPrefix(text, "The tool execution failed") ||
+		strings.HasPrefix(text, "<error>") ||
+		strings.Contains(text, "The user denied this operation") {
+		r.IsError = true
+	}
+}
+
+// clineUserText returns what the user typed in text, taken from
+// the tags that wrap it, or "" if text has none.
+func clineUserText(text string) string {
+	var parts []string
+	for _, tag := range clineUserTags {
+		for _, body := range tagBodies(text, tag) {
+			if body = strings.TrimSpace(body); body != "" {
+				parts = append(parts, body)
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// addReply adds an assistant entry of the API conversation with
+// the token usage of the API request that produced it.
+func (b *clineTaskBuilder) addReply(entry gjson.Result, req *clineRequest) {
+	if m := entry.Get("modelInfo.modelId").Str; m != "" {
+		b.model = m
+	}
+
+	var (
+		parts       []string
+		calls       []ParsedToolCall
+		hasThinking bool
+	)
+	addCall := func(tc ParsedToolCall, detail string) {
+		calls = append(calls, tc)
+		parts = append(parts, formatToolHeader(tc.Category, detail))
+	}
+	addText := func(text string) {
+		text, thinking := clineThinking(text)
+		if thinking != "" {
+			hasThinking = true
+			parts = append(parts, "[Thinking]\n"+thinking)
+		}
+		text, xmlCalls := b.extractXMLTools(text)
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+		for _, tc := range xmlCalls {
+			addCall(tc, clineToolDetail(gjson.Parse(tc.InputJSON)))
+		}
+	}
+
+	content := entry.Get("content")
+	if content.Type == gjson.String {
+		addText(content.Str)
+	}
+	content.ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").Str {
+		case "text":
+			addText(block.Get("text").Str)
+		case "thinking", "reasoning":
+			if t := block.Get("thinking").Str; t != "" {
+				hasThinking = true
+				parts = append(parts, "[Thinking]\n"+t)
+			}
+		case "tool_use":
+			name := block.Get("name").Str
+			if name == "" {
+				return true
+			}
+			addCall(ParsedToolCall{
+				ToolUseID: block.Get("id").Str,
+				ToolName:  name,
+				Category:  NormalizeToolCategory(name),
+				InputJSON: block.Get("input").Raw,
+			}, clineToolDetail(block.Get("input")))
+		}
+		return true
+	})
+
+	text := strings.Join(parts, "\n\n")
+	if text == "" {
+		return
+	}
+	msg := ParsedMessage{
+		Ordinal:       b.ordinal,
+		Role:          RoleAssistant,
+		Content:       text,
+		HasThinking:   hasThinking,
+		HasToolUse:    len(calls) > 0,
+		ContentLength: len(text),
+		ToolCalls:     calls,
+		Model:         b.model,
+	}
+	if req != nil {
+		req.model = b.model
+		msg.Timestamp = req.replyTS
+		msg.InputTokens = req.tokensIn
+		msg.OutputTokens = req.tokensOut
+		msg.CacheCreationInputTokens = req.cacheWrites
+		msg.CacheReadInputTokens = req.cacheReads
+	}
+	msg.Timestamp = clineEntryTime(entry, msg.Timestamp)
+	b.messages = append(b.messages, msg)
+	b.ordinal++
+	b.pending = calls
+}
+
+// extractXMLTools removes the XML tool calls from the text of a
+// reply and returns them with their parameters as input JSON.
+func (b *clineTaskBuilder) extractXMLTools(
+	text string,
+) (string, []ParsedToolCall) {
+	var (
+		rest  strings.Builder
+		calls []ParsedToolCall
+	)
+	for {
+		loc, name := nextTag(text, func(name string) bool {
+			return clineTools[name]
+		})
+		if loc == nil {
+			break
+		}
+		end := strings.Index(text[loc[1]:], "</"+name+">")
+		if end < 0 {
+			// Replies cut off mid-call never ran the tool.
+			break
+		}
+		body := text[loc[1] : loc[1]+end]
+		rest.WriteString(text[:loc[0]])
+		text = text[loc[1]+end+len("</"+name+">"):]
+
+		input, _ := json.Marshal(clineToolParams(body))
+		calls = append(calls, ParsedToolCall{
+			ToolUseID: fmt.Sprintf("cline-%d-%d", b.ordinal, len(calls)),
+			ToolName:  name,
+			Category:  NormalizeToolCategory(name),
+			InputJSON: string(input),
+		})
+	}
+	rest.WriteString(text)
+	return rest.String(), calls
+}
+
+// clineToolParams parses the parameter tags of an XML tool call.
+// File contents and diffs keep their whitespace apart from the
+// newlines that open and close the tag.
+func clineToolParams(body string) map[string]string {
+	params := make(map[string]string)
+	for {
+		loc, name := nextTag(body, nil)
+		if loc == nil {
+			break
+		}
+		end := strings.Index(body[loc[1]:], "</"+name+">")
+		if end < 0 {
+			break
+		}
+		v := body[loc[1] : loc[1]+end]
+		body = body[loc[1]+end+len("</"+name+">"):]
+		switch name {
+		case "content", "diff":
+			v = strings.TrimSuffix(strings.TrimPrefix(v, "\n"), "\n")
+		default:
+			v = strings.TrimSpace(v)
+		}
+		params[name] = v
+	}
+	return params
+}
+
+// clineToolDetail returns the part of a tool's input shown next
+// to its name.
+func clineToolDetail(in gjson.Result) string {
+	for _, key := range []string{"path", "command", "regex", "query", "url"} {
+		if v := in.Get(key).Str; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clineThinking removes the <thinking> blocks from text and
+// returns them joined.
+func clineThinking(text string) (string, string) {
+	var thoughts []string
+	for _, m := range clineThinkingRe.FindAllStringSubmatch(text, -1) {
+		if m[1] != "" {
+			thoughts = append(thoughts, m[1])
+		}
+	}
+	return clineThinkingRe.ReplaceAllString(text, ""),
+		strings.Join(thoughts, "\n")
+}
+
+// nextTag finds the first opening tag in s accepted by keep, or
+// any tag when keep is nil. It returns the tag's location and
+// name, or a nil location when there is none.
+func nextTag(s string, keep func(string) bool) ([]int, string) {
+	for _, m := range clineTagRe.FindAllStringSubmatchIndex(s, -1) {
+		name := s[m[2]:m[3]]
+		if keep == nil || keep(name) {
+			return m[:2], name
+		}
+	}
+	return nil, ""
+}
+
+// tagBodies returns the contents of each <tag>...</tag> block in
+// s.
+func tagBodies(s, tag string) []string {
+	openTag, closeTag := "<"+tag+">", "</"+tag+">"
+	var out []string
+	for {
+		i := strings.Index(s, openTag)
+		if i < 0 {
+			return out
+		}
+		s = s[i+len(openTag):]
+		j := strings.Index(s, closeTag)
+		if j < 0 {
+			return append(out, s)
+		}
+		out = append(out, s[:j])
+		s = s[j+len(closeTag):]
+	}
+}
+
+// removeTagBlocks removes each <tag>...</tag> block from s.
+func removeTagBlocks(s, tag string) string {
+	openTag, closeTag := "<"+tag+">", "</"+tag+">"
+	for {
+		i := strings.Index(s, openTag)
+		if i < 0 {
+			return s
+		}
+		j := strings.Index(s[i:], closeTag)
+		if j < 0 {
+			return s[:i]
+		}
+		s = s[:i] + s[i+j+len(closeTag):]
+	}
+}
+
+// addTokens totals the token usage of the task's API requests
+// into sess, with the cost the extension reported for them. Each
+// request counts toward the model of its reply, or the task's
+// last model when the reply did not record one.
+func (b *clineTaskBuilder) addTokens(
+	sess *ParsedSession, requests []clineRequest,
+) {
+	for _, r := range requests {
+		if r.tokensIn == 0 && r.tokensOut == 0 &&
+			r.cacheWrites == 0 && r.cacheReads == 0 && r.cost == 0 {
+			continue
+		}
+		sess.InputTokens += r.tokensIn
+		sess.OutputTokens += r.tokensOut
+		sess.CacheCreationInputTokens += r.cacheWrites
+		sess.CacheReadInputTokens += r.cacheReads
+		model := r.model
+		if model == "" {
+			model = b.model
+		}
+		if model == "" {
+			model = "unknown"
+		}
+		if sess.TokensByModel == nil {
+			sess.TokensByModel = make(map[string]ModelTokenUsage)
+		}
+		u := sess.TokensByModel[model]
+		u.InputTokens += r.tokensIn
+		u.OutputTokens += r.tokensOut
+		u.CacheCreationInputTokens += r.cacheWrites
+		u.CacheReadInputTokens += r.cacheReads
+		u.CostUSD += r.cost
+		sess.TokensByModel[model] = u
+	}
+}
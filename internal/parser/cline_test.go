@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const clineHistory = `[
+  {"role": "user", "content": [
+    {"type": "text", "text": "<task>\nAdd a greet function\n</task>"},
+    {"type": "text", "text": "<environment_details>\n# VSCode Visible Files\napp.py\n\n# Current Working Directory (/src/app) Files\napp.py\n</environment_details>"}
+  ]},
+  {"role": "assistant", "modelInfo": {"modelId": "claude-sonnet-4-5", "providerId": "anthropic"}, "content": [
+    {"type": "text", "text": "<thinking>\nI should look at the file first.\n</thinking>\n\n<read_file>\n<path>app.py</path>\n</read_file>"}
+  ]},
+  {"role": "user", "content": [
+    {"type": "text", "text": "[read_file for 'app.py'] Result:"},
+    {"type": "text", "text": "def main():\n    pass\n"},
+    {"type": "text", "text": "<environment_details>\n# VSCode Visible Files\napp.py\n</environment_details>"}
+  ]},
+  {"role": "assistant", "content": [
+    {"type": "text", "text": "Adding it now.\n\n<replace_in_file>\n<path>app.py</path>\n<diff>\n------- SEARCH\ndef main():\n=======\ndef greet():\n    print(\"hi\")\n\n\ndef main():\n+++++++ REPLACE\n</diff>\n</replace_in_file>"}
+  ]},
+  {"role": "user", "content": [
+    {"type": "text", "text": "[replace_in_file for 'app.py'] Result:"},
+    {"type": "text", "text": "The user denied this operation.\nThe user provided the following feedback:\n<feedback>\nCall it hello instead\n</feedback>"},
+    {"type": "text", "text": "<environment_details>\nnone\n</environment_details>"}
+  ]},
+  {"role": "assistant", "content": [
+    {"type": "tool_use", "id": "toolu_1", "name": "execute_command", "input": {"command": "pytest", "requires_approval": false}}
+  ]},
+  {"role": "user", "content": [
+    {"type": "tool_result", "tool_use_id": "toolu_1", "content": "Command executed.\nOutput:\n1 passed"}
+  ]}
+]`
+
+const clineUIMessages = `[
+  {"ts": 1718000000000, "type": "say", "say": "task", "text": "Add a greet function"},
+  {"ts": 1718000001000, "type": "say", "say": "api_req_started", "text": "{\"request\":\"...\",\"tokensIn\":1000,\"tokensOut\":50,\"cacheWrites\":200,\"cacheReads\":0,\"cost\":0.012}"},
+  {"ts": 1718000004000, "type": "say", "say": "text", "text": "I should look at the file first."},
+  {"ts": 1718000005000, "type": "say", "say": "tool", "text": "{\"tool\":\"readFile\",\"path\":\"app.py\"}"},
+  {"ts": 1718000010000, "type": "say", "say": "api_req_started", "text": "{\"request\":\"...\",\"tokensIn\":10,\"tokensOut\":80,\"cacheWrites\":0,\"cacheReads\":1200,\"cost\":0.003}"},
+  {"ts": 1718000015000, "type": "ask", "ask": "tool", "text": "{\"tool\":\"editedExistingFile\",\"path\":\"app.py\"}"},
+  {"ts": 1718000030000, "type": "say", "say": "api_req_started", "text": "{\"request\":\"...\",\"tokensIn\":20,\"tokensOut\":30}"},
+  {"ts": 1718000040000, "type": "ask", "ask": "command", "text": "pytest"}
+]`
+
+// writeClineTask writes the API conversation and UI messages of
+// a task into a temporary task directory and returns the path
+// of the API conversation.
+func writeClineTask(t *testing.T, taskID, history, ui string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "tasks", taskID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{ClineHistoryFile: history}
+	if ui != "" {
+		files[ClineUIMessagesFile] = ui
+	}
+	for name, content := range files {
+		if err := os.WriteFile(
+			filepath.Join(dir, name), []byte(content), 0o644,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, ClineHistoryFile)
+}
+
+func TestParseClineTask(t *testing.T) {
+	path := writeClineTask(t, "1718000000000", clineHistory, clineUIMessages)
+
+	sess, msgs, err := ParseClineTask(path, "laptop")
+	if err != nil {
+		t.Fatalf("ParseClineTask: %v", err)
+	}
+	if sess == nil {
+		t.Fatal("expected session")
+	}
+	assertEqual(t, "cline:1718000000000", sess.ID, "ID")
+	assertEqual(t, AgentCline, sess.Agent, "agent")
+	assertEqual(t, "laptop", sess.Machine, "machine")
+	assertEqual(t, "app", sess.Project, "project")
+	assertEqual(t, "/src/app", sess.Cwd, "cwd")
+	assertEqual(t, "Add a greet function", sess.FirstMessage, "first message")
+	assertEqual(t, 2, sess.UserMessageCount, "user messages")
+	assertEqual(t, true,
+		sess.StartedAt.Equal(time.UnixMilli(1718000000000)), "started at")
+	assertEqual(t, true,
+		sess.EndedAt.Equal(time.UnixMilli(1718000040000)), "ended at")
+
+	assertEqual(t, int64(1030), sess.InputTokens, "input tokens")
+	assertEqual(t, int64(160), sess.OutputTokens, "output tokens")
+	assertEqual(t, int64(200), sess.CacheCreationInputTokens, "cache writes")
+	assertEqual(t, int64(1200), sess.CacheReadInputTokens, "cache reads")
+	usage := sess.TokensByModel["claude-sonnet-4-5"]
+	assertEqual(t, int64(1030), usage.InputTokens, "model input tokens")
+	assertEqual(t, 0.015, usage.CostUSD, "model cost")
+
+	// task, read, result, edit, result + feedback, command, result
+	if len(msgs) != 7 {
+		for _, m := range msgs {
+			t.Logf("%s: %q", m.Role, m.Content)
+		}
+		t.Fatalf("got %d messages, want 7", len(msgs))
+	}
+	assertEqual(t, RoleUser, msgs[0].Role, "msgs[0].Role")
+	assertEqual(t, "Add a greet function", msgs[0].Content, "task")
+	assertEqual(t, true,
+		msgs[0].Timestamp.Equal(time.UnixMilli(1718000001000)),
+		"task timestamp")
+
+	read := msgs[1]
+	assertEqual(t, RoleAssistant, read.Role, "msgs[1].Role")
+	assertEqual(t, true, read.HasThinking, "thinking")
+	assertEqual(t,
+		"[Thinking]\nI should look at the file first.\n\n[Read: app.py]",
+		read.Content, "read content")
+	assertEqual(t, "claude-sonnet-4-5", read.Model, "model")
+	assertEqual(t, int64(1000), read.InputTokens, "reply input tokens")
+	assertEqual(t, true,
+		read.Timestamp.Equal(time.UnixMilli(1718000005000)),
+		"reply timestamp")
+	if len(read.ToolCalls) != 1 {
+		t.Fatalf("got %d tool calls, want 1", len(read.ToolCalls))
+	}
+	assertEqual(t, "Read", read.ToolCalls[0].Category, "read category")
+	assertEqual(t, "app.py",
+		gjson.Get(read.ToolCalls[0].InputJSON, "path").Str, "read path")
+
+	result := msgs[2]
+	assertEqual(t, "", result.Content, "result content")
+	if len(result.ToolResults) != 1 ||
+		result.ToolResults[0].ToolUseID != read.ToolCalls[0].ToolUseID ||
+		result.ToolResults[0].IsError ||
+		!strings.Contains(result.ToolResults[0].Content, "def main") {
+		t.Errorf("read result = %+v", result.ToolResults)
+	}
+
+	edit := msgs[3].ToolCalls[0]
+	assertEqual(t, "replace_in_file", edit.ToolName, "edit tool")
+	assertEqual(t, "Edit", edit.Category, "edit category")
+	assertEqual(t, true,
+		strings.HasPrefix(
+			gjson.Get(edit.InputJSON, "diff").Str, "------- SEARCH\n",
+		), "diff")
+	assertEqual(t, "Adding it now.\n\n[Edit: app.py]",
+		msgs[3].Content, "edit content")
+
+	denied := msgs[4]
+	assertEqual(t, "Call it hello instead", denied.Content, "feedback")
+	if len(denied.ToolResults) != 1 || !denied.ToolResults[0].IsError ||
+		denied.ToolResults[0].ToolUseID != edit.ToolUseID {
+		t.Errorf("denied result = %+v", denied.ToolResults)
+	}
+
+	cmd := msgs[5].ToolCalls[0]
+	assertEqual(t, "toolu_1", cmd.ToolUseID, "native tool id")
+	assertEqual(t, "Bash", cmd.Category, "command category")
+	assertEqual(t, "[Bash: pytest]", msgs[5].Content, "command content")
+	if r := msgs[6].ToolResults; len(r) != 1 || r[0].ToolUseID != "toolu_1" ||
+		!strings.HasSuffix(r[0].Content, "1 passed") {
+		t.Errorf("command result = %+v", r)
+	}
+}
+
+func TestParseClineTaskWithoutUIMessages(t *testing.T) {
+	path := writeClineTask(t, "1718000000000", clineHistory, "")
+
+	sess, msgs, err := ParseClineTask(path, "m")
+	if err != nil {
+		t.Fatalf("ParseClineTask: %v", err)
+	}
+	if sess == nil {
+		t.Fatal("expected session")
+	}
+	// The task ID gives the start time.
+	assertEqual(t, true,
+		sess.StartedAt.Equal(time.UnixMilli(1718000000000)), "started at")
+	assertEqual(t, int64(0), sess.InputTokens, "input tokens")
+	assertEqual(t, 0, len(sess.TokensByModel), "tokens by model")
+	for _, m := range msgs {
+		if !m.Timestamp.IsZero() {
+			t.Errorf("message %d has timestamp %v", m.Ordinal, m.Timestamp)
+		}
+	}
+}
+
+func TestParseClineTaskEmpty(t *testing.T) {
+	path := writeClineTask(t, "1718000000000", "[]", "[]")
+	sess, msgs, err := ParseClineTask(path, "m")
+	if err != nil || sess != nil || msgs != nil {
+		t.Errorf("ParseClineTask = %v, %v, %v; want nil", sess, msgs, err)
+	}
+
+	path = writeClineTask(t, "1718000000000", "{", "")
+	if _, _, err := ParseClineTask(path, "m"); err == nil {
+		t.Error("invalid JSON accepted")
+	}
+}
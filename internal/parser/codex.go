@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,6 +16,8 @@ import (
 const (
 	codexTypeSessionMeta  = "session_meta"
 	codexTypeResponseItem = "response_item"
+	codexTypeTurnContext  = "turn_context"
+	codexTypeEventMsg     = "event_msg"
 	codexOriginatorExec   = "codex_exec"
 )
 
@@ -27,8 +30,19 @@ type codexSessionBuilder struct {
 	endedAt      time.Time
 	sessionID    string
 	project      string
+	cwd          string
+	gitBranch    string
 	ordinal      int
+	userCount    int
 	includeExec  bool
+	// calls maps a function call's call_id to the index of its
+	// message, so the output can be attached to the call.
+	calls map[string]int
+	// model is the model of the current turn.
+	model string
+	// unbilled is the index of the last assistant message not
+	// yet given the usage of a token_count event, or -1.
+	unbilled int
 }
 
 func newCodexSessionBuilder(
@@ -37,6 +51,7 @@ func newCodexSessionBuilder(
 	return &codexSessionBuilder{
 		project:     "unknown",
 		includeExec: includeExec,
+		unbilled:    -1,
 	}
 }
 
@@ -66,10 +81,44 @@ func (b *codexSessionBuilder) processLine(
 		return b.handleSessionMeta(payload)
 	case codexTypeResponseItem:
 		b.handleResponseItem(payload, ts)
+	case codexTypeTurnContext:
+		if m := payload.Get("model").Str; m != "" {
+			b.model = m
+		}
+	case codexTypeEventMsg:
+		if payload.Get("type").Str == "token_count" {
+			b.handleTokenCount(payload)
+		}
 	}
 	return false
 }
 
+// handleTokenCount gives the usage of the last model response
+// to the assistant message it produced. Cached input is part of
+// input_tokens in Codex and is split out as cache reads.
+func (b *codexSessionBuilder) handleTokenCount(
+	payload gjson.Result,
+) {
+	usage := payload.Get("info.last_token_usage")
+	if !usage.Exists() || b.unbilled < 0 {
+		return
+	}
+	m := &b.messages[b.unbilled]
+	cached := usage.Get("cached_input_tokens").Int()
+	m.InputTokens = usage.Get("input_tokens").Int() - cached
+	m.CacheReadInputTokens = cached
+	m.OutputTokens = usage.Get("output_tokens").Int()
+	b.unbilled = -1
+}
+
+// appendAssistant adds an assistant message for the current
+// turn's model.
+func (b *codexSessionBuilder) appendAssistant(m ParsedMessage) {
+	m.Model = b.model
+	b.unbilled = len(b.messages)
+	b.messages = append(b.messages, m)
+}
+
 func (b *codexSessionBuilder) handleSessionMeta(
 	payload gjson.Result,
 ) (skip bool) {
@@ -77,6 +126,7 @@ func (b *codexSessionBuilder) handleSessionMeta(
 
 	if cwd := payload.Get("cwd").Str; cwd != "" {
 		branch := payload.Get("git.branch").Str
+		b.cwd, b.gitBranch = cwd, branch
 		if proj := ExtractProjectFromCwdWithBranch(cwd, branch); proj != "" {
 			b.project = proj
 		} else {
@@ -94,9 +144,13 @@ func (b *codexSessionBuilder) handleSessionMeta(
 func (b *codexSessionBuilder) handleResponseItem(
 	payload gjson.Result, ts time.Time,
 ) {
-	if payload.Get("type").Str == "function_call" {
+	switch payload.Get("type").Str {
+	case "function_call":
 		b.handleFunctionCall(payload, ts)
 		return
+	case "function_call_output":
+		b.handleFunctionCallOutput(payload, ts)
+		return
 	}
 
 	role := payload.Get("role").Str
@@ -113,19 +167,27 @@ func (b *codexSessionBuilder) handleResponseItem(
 		return
 	}
 
-	if role == "user" && b.firstMessage == "" {
-		b.firstMessage = truncate(
-			strings.ReplaceAll(content, "\n", " "), 300,
-		)
+	if role == "user" {
+		b.userCount++
+		if b.firstMessage == "" {
+			b.firstMessage = truncate(
+				strings.ReplaceAll(content, "\n", " "), 300,
+			)
+		}
 	}
 
-	b.messages = append(b.messages, ParsedMessage{
+	m := ParsedMessage{
 		Ordinal:       b.ordinal,
 		Role:          RoleType(role),
 		Content:       content,
 		Timestamp:     ts,
 		ContentLength: len(content),
-	})
+	}
+	if m.Role == RoleAssistant {
+		b.appendAssistant(m)
+	} else {
+		b.messages = append(b.messages, m)
+	}
 	b.ordinal++
 }
 
@@ -138,8 +200,15 @@ func (b *codexSessionBuilder) handleFunctionCall(
 	}
 
 	content := formatCodexFunctionCall(name, payload)
+	callID := payload.Get("call_id").Str
+	if callID != "" {
+		if b.calls == nil {
+			b.calls = make(map[string]int)
+		}
+		b.calls[callID] = len(b.messages)
+	}
 
-	b.messages = append(b.messages, ParsedMessage{
+	b.appendAssistant(ParsedMessage{
 		Ordinal:       b.ordinal,
 		Role:          RoleAssistant,
 		Content:       content,
@@ -147,13 +216,77 @@ func (b *codexSessionBuilder) handleFunctionCall(
 		HasToolUse:    true,
 		ContentLength: len(content),
 		ToolCalls: []ParsedToolCall{{
-			ToolName: name,
-			Category: NormalizeToolCategory(name),
+			ToolName:  name,
+			Category:  NormalizeToolCategory(name),
+			ToolUseID: callID,
+			InputJSON: codexInputJSON(payload),
 		}},
 	})
 	b.ordinal++
 }
 
+func (b *codexSessionBuilder) handleFunctionCallOutput(
+	payload gjson.Result, ts time.Time,
+) {
+	callID := payload.Get("call_id").Str
+	if callID == "" {
+		return
+	}
+	tr := parseCodexToolOutput(callID, payload.Get("output"))
+
+	if i, ok := b.calls[callID]; ok {
+		b.messages[i].ToolResults = append(
+			b.messages[i].ToolResults, tr,
+		)
+		return
+	}
+
+	// The call was read before the last checkpoint. Emit a
+	// tool-result-only user message so the sync can pair it
+	// with the stored call; it is dropped before storage and
+	// takes no ordinal.
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:     b.ordinal,
+		Role:        RoleUser,
+		Timestamp:   ts,
+		ToolResults: []ParsedToolResult{tr},
+	})
+}
+
+// parseCodexToolOutput reads the output of a function call.
+// Older Codex versions wrap it in JSON with the exit code in
+// metadata; newer ones write plain text that starts with
+// "Exit code: N".
+func parseCodexToolOutput(
+	callID string, output gjson.Result,
+) ParsedToolResult {
+	text := output.Str
+	if output.Type != gjson.String {
+		text = output.Raw
+	}
+
+	tr := ParsedToolResult{ToolUseID: callID}
+	wrapped := gjson.Parse(text)
+	if wrapped.IsObject() && wrapped.Get("output").Exists() {
+		text = wrapped.Get("output").Str
+		if ec := wrapped.Get("metadata.exit_code"); ec.Exists() {
+			n := int(ec.Int())
+			tr.ExitCode = &n
+		}
+		if ok := wrapped.Get("success"); ok.Exists() && !ok.Bool() {
+			tr.IsError = true
+		}
+	} else {
+		tr.ExitCode = exitCodeFromOutput(text)
+	}
+	if tr.ExitCode != nil && *tr.ExitCode != 0 {
+		tr.IsError = true
+	}
+	tr.Content = text
+	tr.ContentLength = len(text)
+	return tr
+}
+
 func formatCodexFunctionCall(
 	name string, payload gjson.Result,
 ) string {
@@ -235,6 +368,24 @@ func parseCodexFunctionArgs(
 	return gjson.Result{}, ""
 }
 
+// codexInputJSON returns a function call's arguments as JSON.
+// Arguments that are not JSON, such as a bare apply_patch body,
+// are wrapped as {"input": ...}.
+func codexInputJSON(payload gjson.Result) string {
+	args, rawArgs := parseCodexFunctionArgs(payload)
+	if args.Exists() {
+		return args.Raw
+	}
+	if rawArgs == "" {
+		return ""
+	}
+	b, err := json.Marshal(map[string]string{"input": rawArgs})
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
 func formatCodexBashCall(
 	summary string, args gjson.Result, rawArgs string,
 ) string {
@@ -492,38 +643,74 @@ func extractCodexContent(payload gjson.Result) string {
 func ParseCodexSession(
 	path, machine string, includeExec bool,
 ) (*ParsedSession, []ParsedMessage, error) {
+	sess, msgs, _, err := ParseCodexSessionCheckpoint(
+		path, machine, includeExec,
+	)
+	return sess, msgs, err
+}
+
+// ParseCodexSessionCheckpoint is ParseCodexSession that also
+// returns a Checkpoint for resuming the parse once more lines
+// are appended. The checkpoint is nil when the session is.
+func ParseCodexSessionCheckpoint(
+	path, machine string, includeExec bool,
+) (*ParsedSession, []ParsedMessage, *Checkpoint, error) {
 	info, err := os.Stat(path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+		return nil, nil, nil, fmt.Errorf("stat %s: %w", path, err)
 	}
 
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("open %s: %w", path, err)
+		return nil, nil, nil, fmt.Errorf("open %s: %w", path, err)
 	}
 	defer f.Close()
 
 	lr := newLineReader(f, maxLineSize)
 	b := newCodexSessionBuilder(includeExec)
 
+	var offset int64
 	for {
 		line, ok := lr.next()
 		if !ok {
 			break
 		}
 		if !gjson.Valid(line) {
+			if lr.terminated {
+				offset = lr.pos
+			}
 			continue
 		}
+		offset = lr.pos
 		if b.processLine(line) {
-			return nil, nil, nil
+			return nil, nil, nil, nil
 		}
 	}
 
 	if err := lr.Err(); err != nil {
-		return nil, nil,
+		return nil, nil, nil,
 			fmt.Errorf("reading codex %s: %w", path, err)
 	}
 
+	sess := b.session(path, machine, info)
+	saved := *b
+	saved.messages = nil
+	saved.calls = nil
+	saved.unbilled = -1
+	cp := &Checkpoint{
+		Offset:  offset,
+		agent:   AgentCodex,
+		session: *sess,
+		codex:   &saved,
+	}
+	return sess, b.messages, cp, nil
+}
+
+// session builds the ParsedSession for the lines processed so
+// far.
+func (b *codexSessionBuilder) session(
+	path, machine string, info os.FileInfo,
+) *ParsedSession {
 	sessionID := b.sessionID
 	if sessionID == "" {
 		sessionID = strings.TrimSuffix(
@@ -532,13 +719,6 @@ func ParseCodexSession(
 	}
 	sessionID = "codex:" + sessionID
 
-	userCount := 0
-	for _, m := range b.messages {
-		if m.Role == RoleUser && m.Content != "" {
-			userCount++
-		}
-	}
-
 	sess := &ParsedSession{
 		ID:               sessionID,
 		Project:          b.project,
@@ -547,16 +727,16 @@ func ParseCodexSession(
 		FirstMessage:     b.firstMessage,
 		StartedAt:        b.startedAt,
 		EndedAt:          b.endedAt,
-		MessageCount:     len(b.messages),
-		UserMessageCount: userCount,
+		MessageCount:     b.ordinal,
+		UserMessageCount: b.userCount,
 		File: FileInfo{
 			Path:  path,
 			Size:  info.Size(),
 			Mtime: info.ModTime().UnixNano(),
 		},
 	}
-
-	return sess, b.messages, nil
+	sess.SetWorkspace(b.cwd, b.gitBranch)
+	return sess
 }
 
 func isCodexSystemMessage(content string) bool {
@@ -86,6 +86,24 @@ func TestParseCodexSession_FunctionCalls(t *testing.T) {
 		assert.Equal(t, want, msgs[1].Content)
 	})
 
+	t.Run("records function call arguments", func(t *testing.T) {
+		patch := "*** Begin Patch\n*** Add File: a.txt\n+hi\n*** End Patch"
+		content := testjsonl.JoinJSONL(
+			testjsonl.CodexSessionMetaJSON("fc-args", "/tmp", "user", tsEarly),
+			testjsonl.CodexMsgJSON("user", "edit", tsEarlyS1),
+			testjsonl.CodexFunctionCallArgsJSON("exec_command",
+				`{"cmd":"ls"}`, tsEarlyS5),
+			testjsonl.CodexFunctionCallFieldsJSON("apply_patch",
+				nil, patch, tsLate),
+		)
+		_, msgs := runCodexParserTest(t, "test.jsonl", content, false)
+		require.Equal(t, 3, len(msgs))
+		assert.JSONEq(t, `{"cmd":"ls"}`, msgs[1].ToolCalls[0].InputJSON)
+		assert.JSONEq(t,
+			`{"input":"*** Begin Patch\n*** Add File: a.txt\n+hi\n*** End Patch"}`,
+			msgs[2].ToolCalls[0].InputJSON)
+	})
+
 	t.Run("write_stdin formats with session and chars", func(t *testing.T) {
 		content := loadFixture(t, "codex/fc_stdin.jsonl")
 		_, msgs := runCodexParserTest(t, "test.jsonl", content, false)
@@ -159,6 +177,92 @@ func TestParseCodexSession_FunctionCalls(t *testing.T) {
 	})
 }
 
+func TestParseCodexSession_FunctionCallOutput(t *testing.T) {
+	parse := func(t *testing.T, output string) []ParsedMessage {
+		t.Helper()
+		content := testjsonl.JoinJSONL(
+			testjsonl.CodexSessionMetaJSON("out", "/tmp", "user", tsEarly),
+			testjsonl.CodexMsgJSON("user", "run the tests", tsEarlyS1),
+			testjsonl.CodexFunctionCallArgsJSON("exec_command", `{"cmd":"go test"}`, tsEarlyS5),
+			testjsonl.CodexFunctionCallOutputJSON("call_test", output, tsLate),
+		)
+		_, msgs := runCodexParserTest(t, "test.jsonl", content, false)
+		require.Len(t, msgs, 2)
+		assertToolCalls(t, msgs[1].ToolCalls, []ParsedToolCall{{
+			ToolName: "exec_command", Category: "Bash", ToolUseID: "call_test",
+		}})
+		require.Len(t, msgs[1].ToolResults, 1)
+		assert.Equal(t, "call_test", msgs[1].ToolResults[0].ToolUseID)
+		return msgs
+	}
+
+	t.Run("plain text with exit code", func(t *testing.T) {
+		output := "Exit code: 1\nWall time: 0.4 seconds\nOutput:\nFAIL"
+		tr := parse(t, output)[1].ToolResults[0]
+		assert.True(t, tr.IsError)
+		require.NotNil(t, tr.ExitCode)
+		assert.Equal(t, 1, *tr.ExitCode)
+		assert.Equal(t, output, tr.Content)
+	})
+
+	t.Run("json wrapped output", func(t *testing.T) {
+		tr := parse(t, `{"output":"ok\n","metadata":{"exit_code":0,"duration_seconds":0.1}}`)[1].ToolResults[0]
+		assert.False(t, tr.IsError)
+		require.NotNil(t, tr.ExitCode)
+		assert.Equal(t, 0, *tr.ExitCode)
+		assert.Equal(t, "ok\n", tr.Content)
+		assert.Equal(t, 3, tr.ContentLength)
+	})
+
+	t.Run("output without exit code", func(t *testing.T) {
+		tr := parse(t, "patch applied")[1].ToolResults[0]
+		assert.False(t, tr.IsError)
+		assert.Nil(t, tr.ExitCode)
+	})
+
+	t.Run("output for an earlier call", func(t *testing.T) {
+		content := testjsonl.JoinJSONL(
+			testjsonl.CodexSessionMetaJSON("out", "/tmp", "user", tsEarly),
+			testjsonl.CodexFunctionCallOutputJSON("call_old", "Exit code: 2", tsEarlyS1),
+			testjsonl.CodexMsgJSON("user", "what happened?", tsEarlyS5),
+		)
+		sess, msgs := runCodexParserTest(t, "test.jsonl", content, false)
+		require.Len(t, msgs, 2)
+		assert.Equal(t, RoleUser, msgs[0].Role)
+		assert.Empty(t, msgs[0].Content)
+		require.Len(t, msgs[0].ToolResults, 1)
+		assert.True(t, msgs[0].ToolResults[0].IsError)
+		// The result-only message takes no ordinal.
+		assert.Equal(t, 0, msgs[1].Ordinal)
+		assert.Equal(t, 1, sess.MessageCount)
+	})
+}
+
+func TestParseCodexSession_TokenUsage(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("tok", "/tmp", "user", tsEarly),
+		testjsonl.CodexTurnContextJSON("gpt-5-codex", tsEarly),
+		testjsonl.CodexMsgJSON("user", "hi", tsEarlyS1),
+		testjsonl.CodexMsgJSON("assistant", "hello", tsEarlyS5),
+		testjsonl.CodexTokenCountJSON(1200, 1000, 40, tsEarlyS5),
+		// Usage with no new response is not given to the user
+		// message.
+		testjsonl.CodexMsgJSON("user", "thanks", tsLate),
+		testjsonl.CodexTokenCountJSON(900, 0, 10, tsLate),
+	)
+	_, msgs := runCodexParserTest(t, "test.jsonl", content, false)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "", msgs[0].Model)
+	assert.Equal(t, int64(0), msgs[0].InputTokens)
+	assert.Equal(t, int64(0), msgs[2].InputTokens)
+
+	m := msgs[1]
+	assert.Equal(t, "gpt-5-codex", m.Model)
+	assert.Equal(t, int64(200), m.InputTokens)
+	assert.Equal(t, int64(1000), m.CacheReadInputTokens)
+	assert.Equal(t, int64(40), m.OutputTokens)
+}
+
 func TestParseCodexSession_EdgeCases(t *testing.T) {
 	t.Run("skips system messages", func(t *testing.T) {
 		content := testjsonl.JoinJSONL(
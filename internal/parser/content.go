@@ -2,6 +2,8 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/tidwall/gjson"
@@ -67,6 +69,8 @@ func ExtractTextContent(
 					ToolUseID:     tuid,
 					ContentLength: cl,
 					Content:       ct,
+					IsError:       block.Get("is_error").Bool(),
+					ExitCode:      exitCodeFromOutput(ct),
 				})
 			}
 		}
@@ -110,6 +114,33 @@ func toolResultContentText(content gjson.Result) string {
 	return ""
 }
 
+var (
+	// "Exit code 1" (Claude) or "Exit code: 1" (Codex) leading
+	// the output of a shell command.
+	exitCodePrefixRe = regexp.MustCompile(`^Exit code:? (-?\d+)`)
+	// "<exited with exit code 1>" (Copilot) ending the output.
+	exitedWithCodeRe = regexp.MustCompile(
+		`<exited with exit code (-?\d+)>\s*$`,
+	)
+)
+
+// exitCodeFromOutput returns the exit code an agent reported in
+// a shell tool's output, or nil if there is none.
+func exitCodeFromOutput(s string) *int {
+	m := exitCodePrefixRe.FindStringSubmatch(s)
+	if m == nil {
+		m = exitedWithCodeRe.FindStringSubmatch(s)
+	}
+	if m == nil {
+		return nil
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
 var todoIcons = map[string]string{
 	"completed":   "✓",
 	"in_progress": "→",
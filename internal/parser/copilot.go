@@ -28,6 +28,8 @@ type copilotSessionBuilder struct {
 	endedAt      time.Time
 	sessionID    string
 	project      string
+	cwd          string
+	gitBranch    string
 	ordinal      int
 }
 
@@ -72,6 +74,7 @@ func (b *copilotSessionBuilder) handleSessionStart(
 
 	cwd := data.Get("context.cwd").Str
 	branch := data.Get("context.branch").Str
+	b.cwd, b.gitBranch = cwd, branch
 	if cwd != "" {
 		if p := ExtractProjectFromCwdWithBranch(
 			cwd, branch,
@@ -172,6 +175,16 @@ func (b *copilotSessionBuilder) handleToolComplete(
 		content = r.Raw
 	}
 	contentLen := len(content)
+	output := content
+	if r.IsObject() {
+		output = r.Get("content").Str
+	}
+
+	// Failed calls carry "success": false, an "error", or both.
+	success := data.Get("success")
+	isError := success.Exists() && !success.Bool() ||
+		data.Get("error").Exists() &&
+			data.Get("error").Type != gjson.Null
 
 	// Emit a tool-result-only user message for pairing.
 	b.messages = append(b.messages, ParsedMessage{
@@ -182,6 +195,8 @@ func (b *copilotSessionBuilder) handleToolComplete(
 		ToolResults: []ParsedToolResult{{
 			ToolUseID:     toolCallID,
 			ContentLength: contentLen,
+			IsError:       isError,
+			ExitCode:      exitCodeFromOutput(output),
 		}},
 	})
 	b.ordinal++
@@ -289,6 +304,7 @@ func ParseCopilotSession(
 			Mtime: info.ModTime().UnixNano(),
 		},
 	}
+	sess.SetWorkspace(b.cwd, b.gitBranch)
 
 	return sess, b.messages, nil
 }
@@ -96,6 +96,7 @@ func TestParseCopilotSession_ToolCalls(t *testing.T) {
 	assertEqual(t, 1, len(trMsg.ToolResults), "len(trMsg.ToolResults)")
 	assertEqual(t, "tc-1", trMsg.ToolResults[0].ToolUseID, "tool result ID")
 	assertEqual(t, 15, trMsg.ToolResults[0].ContentLength, "tool result ContentLength")
+	assertEqual(t, false, trMsg.ToolResults[0].IsError, "tool result IsError")
 
 	wantTS := parseTimestamp("2025-01-15T10:00:03Z")
 	assertEqual(t, wantTS, trMsg.Timestamp, "tool result timestamp")
@@ -239,6 +240,25 @@ func TestParseCopilotSession_NonexistentFile(t *testing.T) {
 	}
 }
 
+func TestParseCopilotSession_ToolFailure(t *testing.T) {
+	path := writeCopilotJSONL(t,
+		`{"type":"session.start","data":{"sessionId":"fail-test"},"timestamp":"2025-01-15T10:00:00Z"}`,
+		`{"type":"user.message","data":{"content":"Build it"},"timestamp":"2025-01-15T10:00:01Z"}`,
+		`{"type":"assistant.message","data":{"content":"","toolRequests":[{"toolCallId":"tc-1","name":"bash","arguments":"{\"command\":\"make\"}"},{"toolCallId":"tc-2","name":"view","arguments":"{}"}]},"timestamp":"2025-01-15T10:00:02Z"}`,
+		`{"type":"tool.execution_complete","data":{"toolCallId":"tc-1","success":false,"result":{"content":"make: *** No targets.\n<exited with exit code 2>"}},"timestamp":"2025-01-15T10:00:03Z"}`,
+		`{"type":"tool.execution_complete","data":{"toolCallId":"tc-2","error":{"message":"path not found"}},"timestamp":"2025-01-15T10:00:04Z"}`,
+	)
+
+	_, msgs := parseAndValidateHelper(t, path, "m", 4)
+
+	bash := msgs[2].ToolResults[0]
+	assertEqual(t, true, bash.IsError, "bash IsError")
+	assertExitCode(t, 0, bash.ExitCode, intPtr(2))
+	view := msgs[3].ToolResults[0]
+	assertEqual(t, true, view.IsError, "view IsError")
+	assertExitCode(t, 1, view.ExitCode, nil)
+}
+
 func TestParseCopilotSession_ObjectArguments(t *testing.T) {
 	// arguments is a native JSON object, not a string.
 	path := writeCopilotJSONL(t,
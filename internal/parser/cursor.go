@@ -0,0 +1,506 @@
+package parser
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Cursor keeps its composer and agent chats in the global state
+// database of its VS Code user directory, in the cursorDiskKV
+// table: a composerData:<composerId> entry per chat and a
+// bubbleId:<composerId>:<bubbleId> entry per message. Each
+// workspace's own state database lists the chats started in it
+// under the composer.composerData key of its ItemTable.
+const (
+	cursorStateDB         = "state.vscdb"
+	cursorWorkspaceFile   = "workspace.json"
+	cursorWorkspaceKey    = "composer.composerData"
+	cursorComposerPrefix  = "composerData:"
+	cursorBubblePrefix    = "bubbleId:"
+	cursorBubbleUser      = 1
+	cursorBubbleAssistant = 2
+)
+
+// CursorGlobalDB returns the path of the global state database
+// under a Cursor user directory.
+func CursorGlobalDB(userDir string) string {
+	return filepath.Join(userDir, "globalStorage", cursorStateDB)
+}
+
+// CursorComposerMeta is lightweight metadata for a chat, used to
+// detect changes without reading its messages.
+type CursorComposerMeta struct {
+	ComposerID  string
+	VirtualPath string
+	FileMtime   int64
+}
+
+// ListCursorComposerMeta returns lightweight metadata for all
+// chats in the global state database at dbPath. A chat's mtime
+// is its last update time, or the database's mtime for chats
+// that do not record one.
+func ListCursorComposerMeta(
+	dbPath string,
+) ([]CursorComposerMeta, error) {
+	info, err := os.Stat(dbPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", dbPath, err)
+	}
+
+	db, err := openCursorDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT substr(key, ?),
+		       COALESCE(json_extract(CAST(value AS TEXT), '$.lastUpdatedAt'),
+		                json_extract(CAST(value AS TEXT), '$.createdAt'), 0)
+		FROM cursorDiskKV
+		WHERE key LIKE ? AND json_valid(CAST(value AS TEXT))
+	`, len(cursorComposerPrefix)+1, cursorComposerPrefix+"%")
+	if err != nil {
+		return nil, fmt.Errorf("listing cursor composers: %w", err)
+	}
+	defer rows.Close()
+
+	var metas []CursorComposerMeta
+	for rows.Next() {
+		var (
+			id      string
+			updated int64
+		)
+		if err := rows.Scan(&id, &updated); err != nil {
+			return nil, fmt.Errorf(
+				"scanning cursor composer meta: %w", err,
+			)
+		}
+		metas = append(metas, CursorComposerMeta{
+			ComposerID:  id,
+			VirtualPath: dbPath + "#" + id,
+			FileMtime:   cursorComposerMtime(updated, info),
+		})
+	}
+	return metas, rows.Err()
+}
+
+// cursorComposerMtime returns the mtime stored for a chat last
+// updated at the given Unix milliseconds, falling back to the
+// mtime of its database.
+func cursorComposerMtime(updatedMillis int64, db os.FileInfo) int64 {
+	if updatedMillis > 0 {
+		return updatedMillis * 1_000_000
+	}
+	return db.ModTime().UnixNano()
+}
+
+// LoadCursorWorkspaces maps the IDs of the chats listed in the
+// workspace state databases under a Cursor user directory to the
+// folder of their workspace.
+func LoadCursorWorkspaces(userDir string) map[string]string {
+	folders := make(map[string]string)
+	dirs, err := os.ReadDir(filepath.Join(userDir, "workspaceStorage"))
+	if err != nil {
+		return folders
+	}
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(userDir, "workspaceStorage", d.Name())
+		folder := readCursorWorkspaceFolder(
+			filepath.Join(dir, cursorWorkspaceFile),
+		)
+		if folder == "" {
+			continue
+		}
+		for _, id := range readCursorWorkspaceComposers(
+			filepath.Join(dir, cursorStateDB),
+		) {
+			folders[id] = folder
+		}
+	}
+	return folders
+}
+
+// readCursorWorkspaceFolder returns the local folder a
+// workspace.json file points at, or "" if it has none.
+func readCursorWorkspaceFolder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return fileURLPath(gjson.GetBytes(data, "folder").Str)
+}
+
+// fileURLPath returns the local path of a file:// URL, or "" for
+// other URLs.
+func fileURLPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// readCursorWorkspaceComposers returns the IDs of the chats a
+// workspace state database lists.
+func readCursorWorkspaceComposers(dbPath string) []string {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil
+	}
+	db, err := openCursorDB(dbPath)
+	if err != nil {
+		return nil
+	}
+	defer db.Close()
+
+	var value string
+	err = db.QueryRow(
+		"SELECT CAST(value AS TEXT) FROM ItemTable WHERE key = ?",
+		cursorWorkspaceKey,
+	).Scan(&value)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	gjson.Get(value, "allComposers.#.composerId").ForEach(
+		func(_, id gjson.Result) bool {
+			if id.Str != "" {
+				ids = append(ids, id.Str)
+			}
+			return true
+		},
+	)
+	return ids
+}
+
+// ParseCursorComposer parses one chat from the global state
+// database at dbPath. folder is the chat's workspace folder, if
+// known. Returns (nil, nil, nil) for chats without user
+// messages.
+func ParseCursorComposer(
+	dbPath, composerID, folder, machine string,
+) (*ParsedSession, []ParsedMessage, error) {
+	info, err := os.Stat(dbPath)
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf(
+			"cursor db not found: %s", dbPath,
+		)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat %s: %w", dbPath, err)
+	}
+
+	db, err := openCursorDB(dbPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+
+	var data string
+	err = db.QueryRow(
+		"SELECT CAST(value AS TEXT) FROM cursorDiskKV WHERE key = ?",
+		cursorComposerPrefix+composerID,
+	).Scan(&data)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"loading cursor composer %s: %w", composerID, err,
+		)
+	}
+	composer := gjson.Parse(data)
+
+	bubbles, err := loadCursorBubbles(db, composerID, composer)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"loading bubbles for %s: %w", composerID, err,
+		)
+	}
+
+	b := cursorComposerBuilder{
+		model: composer.Get("modelConfig.modelName").Str,
+	}
+	for _, bubble := range bubbles {
+		b.addBubble(bubble)
+	}
+	if b.userCount == 0 {
+		return nil, nil, nil
+	}
+
+	project := ExtractProjectFromCwd(folder)
+	if project == "" {
+		project = "unknown"
+	}
+
+	startedAt := millisToTime(composer.Get("createdAt").Int())
+	endedAt := millisToTime(composer.Get("lastUpdatedAt").Int())
+	updated := composer.Get("lastUpdatedAt").Int()
+	if updated <= 0 {
+		updated = composer.Get("createdAt").Int()
+	}
+	for _, m := range b.messages {
+		if m.Timestamp.IsZero() {
+			continue
+		}
+		if startedAt.IsZero() || m.Timestamp.Before(startedAt) {
+			startedAt = m.Timestamp
+		}
+		if m.Timestamp.After(endedAt) {
+			endedAt = m.Timestamp
+		}
+	}
+
+	sess := &ParsedSession{
+		ID:               "cursor:" + composerID,
+		Project:          project,
+		Machine:          machine,
+		Agent:            AgentCursor,
+		FirstMessage:     b.firstMessage,
+		StartedAt:        startedAt,
+		EndedAt:          endedAt,
+		MessageCount:     len(b.messages),
+		UserMessageCount: b.userCount,
+		File: FileInfo{
+			Path:  dbPath + "#" + composerID,
+			Mtime: cursorComposerMtime(updated, info),
+		},
+	}
+	sess.SetWorkspace(folder, "")
+	addMessageTokens(sess, b.messages)
+	return sess, b.messages, nil
+}
+
+func openCursorDB(dbPath string) (*sql.DB, error) {
+	dsn := dbPath + "?mode=ro&_busy_timeout=3000"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"opening cursor db %s: %w", dbPath, err,
+		)
+	}
+	return db, nil
+}
+
+// loadCursorBubbles returns the messages of a chat in order.
+// Current versions list bubble IDs in the composer and store
+// each bubble under its own key; older ones keep the bubbles in
+// the composer's conversation array.
+func loadCursorBubbles(
+	db *sql.DB, composerID string, composer gjson.Result,
+) ([]gjson.Result, error) {
+	headers := composer.Get("fullConversationHeadersOnly").Array()
+	if len(headers) == 0 {
+		return composer.Get("conversation").Array(), nil
+	}
+
+	prefix := cursorBubblePrefix + composerID + ":"
+	rows, err := db.Query(`
+		SELECT substr(key, ?), CAST(value AS TEXT)
+		FROM cursorDiskKV
+		WHERE key LIKE ?
+	`, len(prefix)+1, prefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[string]string)
+	for rows.Next() {
+		var id string
+		var value sql.NullString
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, err
+		}
+		byID[id] = value.String
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	bubbles := make([]gjson.Result, 0, len(headers))
+	for _, h := range headers {
+		if v, ok := byID[h.Get("bubbleId").Str]; ok && gjson.Valid(v) {
+			bubbles = append(bubbles, gjson.Parse(v))
+		}
+	}
+	return bubbles, nil
+}
+
+// cursorComposerBuilder accumulates the messages of a chat.
+type cursorComposerBuilder struct {
+	messages     []ParsedMessage
+	firstMessage string
+	userCount    int
+	model        string
+	ordinal      int
+}
+
+// addBubble adds a chat bubble. An assistant bubble with a tool
+// call also yields a tool-result-only user message carrying the
+// call's result.
+func (b *cursorComposerBuilder) addBubble(bubble gjson.Result) {
+	ts := cursorBubbleTime(bubble.Get("createdAt"))
+	text := strings.TrimSpace(bubble.Get("text").Str)
+
+	switch bubble.Get("type").Int() {
+	case cursorBubbleUser:
+		if text == "" {
+			return
+		}
+		if b.firstMessage == "" {
+			b.firstMessage = truncate(
+				strings.ReplaceAll(text, "\n", " "), 300,
+			)
+		}
+		b.userCount++
+		b.messages = append(b.messages, ParsedMessage{
+			Ordinal:       b.ordinal,
+			Role:          RoleUser,
+			Content:       text,
+			Timestamp:     ts,
+			ContentLength: len(text),
+		})
+		b.ordinal++
+
+	case cursorBubbleAssistant:
+		var parts []string
+		hasThinking := false
+		if thinking := strings.TrimSpace(
+			bubble.Get("thinking.text").Str,
+		); thinking != "" {
+			hasThinking = true
+			parts = append(parts, "[Thinking]\n"+thinking)
+		}
+		if text != "" {
+			parts = append(parts, text)
+		}
+
+		var calls []ParsedToolCall
+		tool := bubble.Get("toolFormerData")
+		call, ok := cursorToolCall(tool)
+		if ok {
+			calls = append(calls, call)
+			parts = append(parts, formatToolHeader(
+				call.Category, cursorToolDetail(gjson.Parse(call.InputJSON)),
+			))
+		}
+
+		content := strings.Join(parts, "\n\n")
+		if content == "" {
+			return
+		}
+		model := bubble.Get("modelInfo.modelName").Str
+		if model == "" {
+			model = b.model
+		}
+		b.messages = append(b.messages, ParsedMessage{
+			Ordinal:       b.ordinal,
+			Role:          RoleAssistant,
+			Content:       content,
+			Timestamp:     ts,
+			HasThinking:   hasThinking,
+			HasToolUse:    len(calls) > 0,
+			ContentLength: len(content),
+			ToolCalls:     calls,
+			Model:         model,
+			InputTokens:   bubble.Get("tokenCount.inputTokens").Int(),
+			OutputTokens:  bubble.Get("tokenCount.outputTokens").Int(),
+		})
+		b.ordinal++
+
+		if ok && tool.Get("result").Exists() {
+			b.addToolResult(call.ToolUseID, tool, ts)
+		}
+	}
+}
+
+// addToolResult emits a tool-result-only user message for
+// pairing with the call that produced it.
+func (b *cursorComposerBuilder) addToolResult(
+	toolUseID string, tool gjson.Result, ts time.Time,
+) {
+	result := tool.Get("result").Str
+	status := tool.Get("status").Str
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:       b.ordinal,
+		Role:          RoleUser,
+		Timestamp:     ts,
+		ContentLength: len(result),
+		ToolResults: []ParsedToolResult{{
+			ToolUseID:     toolUseID,
+			ContentLength: len(result),
+			Content:       result,
+			IsError:       status == "error" || status == "cancelled",
+			ExitCode:      cursorExitCode(result),
+		}},
+	})
+	b.ordinal++
+}
+
+// cursorToolCall converts a bubble's toolFormerData. Arguments
+// are in rawArgs, or params in older versions, as a JSON string.
+func cursorToolCall(tool gjson.Result) (ParsedToolCall, bool) {
+	name := tool.Get("name").Str
+	if name == "" {
+		return ParsedToolCall{}, false
+	}
+	input := tool.Get("rawArgs").Str
+	if !gjson.Valid(input) {
+		input = tool.Get("params").Str
+	}
+	if !gjson.Valid(input) {
+		input = "{}"
+	}
+	return ParsedToolCall{
+		ToolUseID: tool.Get("toolCallId").Str,
+		ToolName:  name,
+		Category:  NormalizeToolCategory(name),
+		InputJSON: input,
+	}, true
+}
+
+// cursorToolDetail returns the part of a tool's input shown next
+// to its name.
+func cursorToolDetail(in gjson.Result) string {
+	for _, key := range []string{
+		"target_file", "file_path", "command", "query",
+		"relative_workspace_path",
+	} {
+		if v := in.Get(key).Str; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// cursorExitCode returns the exit code of a terminal command's
+// JSON result, or nil if it has none.
+func cursorExitCode(result string) *int {
+	code := gjson.Get(result, "exitCode")
+	if code.Type != gjson.Number {
+		return exitCodeFromOutput(result)
+	}
+	n := int(code.Int())
+	return &n
+}
+
+// cursorBubbleTime parses a bubble's createdAt, which is an ISO
+// timestamp in current versions and Unix milliseconds in older
+// ones.
+func cursorBubbleTime(v gjson.Result) time.Time {
+	if v.Type == gjson.Number {
+		return millisToTime(v.Int())
+	}
+	return parseTimestamp(v.Str)
+}
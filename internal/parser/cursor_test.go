@@ -0,0 +1,244 @@
+package parser
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// cursorKVSchema matches the tables of Cursor's state.vscdb
+// databases.
+const cursorKVSchema = `
+CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);
+CREATE TABLE cursorDiskKV (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);
+`
+
+// writeCursorDB creates a state database at path holding the
+// given cursorDiskKV and ItemTable entries.
+func writeCursorDB(
+	t *testing.T, path string, kv, items map[string]string,
+) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(cursorKVSchema); err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+	for table, entries := range map[string]map[string]string{
+		"cursorDiskKV": kv, "ItemTable": items,
+	} {
+		for k, v := range entries {
+			// Cursor stores values as blobs.
+			if _, err := db.Exec(
+				"INSERT INTO "+table+" (key, value) VALUES (?, ?)",
+				k, []byte(v),
+			); err != nil {
+				t.Fatalf("inserting %s: %v", k, err)
+			}
+		}
+	}
+}
+
+const cursorComposer = `{
+  "composerId": "c1",
+  "name": "Greeting",
+  "createdAt": 1718000000000,
+  "lastUpdatedAt": 1718000060000,
+  "modelConfig": {"modelName": "claude-4-sonnet"},
+  "fullConversationHeadersOnly": [
+    {"bubbleId": "b1", "type": 1},
+    {"bubbleId": "b2", "type": 2},
+    {"bubbleId": "b3", "type": 2},
+    {"bubbleId": "missing", "type": 2},
+    {"bubbleId": "b4", "type": 2}
+  ]
+}`
+
+var cursorBubbles = map[string]string{
+	"bubbleId:c1:b1": `{"bubbleId": "b1", "type": 1, "text": "Add a greet function",
+		"createdAt": "2024-06-10T06:13:21.000Z"}`,
+	"bubbleId:c1:b2": `{"bubbleId": "b2", "type": 2, "text": "",
+		"thinking": {"text": "Read the file first."},
+		"createdAt": "2024-06-10T06:13:25.000Z",
+		"tokenCount": {"inputTokens": 1200, "outputTokens": 40},
+		"toolFormerData": {"tool": 5, "name": "read_file", "toolCallId": "call_1",
+			"status": "completed",
+			"rawArgs": "{\"target_file\":\"app.py\",\"should_read_entire_file\":true}",
+			"result": "{\"contents\":\"def main(): pass\"}"}}`,
+	"bubbleId:c1:b3": `{"bubbleId": "b3", "type": 2,
+		"createdAt": "2024-06-10T06:13:30.000Z",
+		"tokenCount": {"inputTokens": 1300, "outputTokens": 60},
+		"toolFormerData": {"name": "run_terminal_cmd", "toolCallId": "call_2",
+			"status": "error",
+			"rawArgs": "{\"command\":\"pytest\",\"is_background\":false}",
+			"result": "{\"output\":\"1 failed\",\"exitCode\":1}"}}`,
+	"bubbleId:c1:b4": `{"bubbleId": "b4", "type": 2, "text": "Done.",
+		"createdAt": "2024-06-10T06:13:40.000Z",
+		"modelInfo": {"modelName": "gpt-5"},
+		"tokenCount": {"inputTokens": 0, "outputTokens": 0}}`,
+}
+
+// writeCursorUserDir creates a Cursor user directory with the
+// test chat, an empty chat, and a workspace listing the test
+// chat. It returns the user directory and the workspace folder.
+func writeCursorUserDir(t *testing.T) (string, string) {
+	t.Helper()
+	userDir := t.TempDir()
+	kv := map[string]string{
+		"composerData:c1":    cursorComposer,
+		"composerData:empty": `{"composerId": "empty", "createdAt": 1718000100000, "conversation": []}`,
+	}
+	for k, v := range cursorBubbles {
+		kv[k] = v
+	}
+	writeCursorDB(t, CursorGlobalDB(userDir), kv, nil)
+
+	folder := filepath.Join(t.TempDir(), "greeter")
+	ws := filepath.Join(userDir, "workspaceStorage", "abc123")
+	writeCursorDB(t, filepath.Join(ws, "state.vscdb"), nil, map[string]string{
+		"composer.composerData": `{"allComposers": [{"composerId": "c1"}]}`,
+	})
+	if err := os.WriteFile(
+		filepath.Join(ws, "workspace.json"),
+		[]byte(`{"folder": "file://`+filepath.ToSlash(folder)+`"}`), 0o644,
+	); err != nil {
+		t.Fatal(err)
+	}
+	return userDir, folder
+}
+
+func TestListCursorComposerMeta(t *testing.T) {
+	userDir, _ := writeCursorUserDir(t)
+	dbPath := CursorGlobalDB(userDir)
+
+	metas, err := ListCursorComposerMeta(dbPath)
+	if err != nil {
+		t.Fatalf("ListCursorComposerMeta: %v", err)
+	}
+	got := make(map[string]CursorComposerMeta)
+	for _, m := range metas {
+		got[m.ComposerID] = m
+	}
+	if len(got) != 2 {
+		t.Fatalf("metas = %+v, want 2", metas)
+	}
+	assertEq(t, "virtual path", got["c1"].VirtualPath, dbPath+"#c1")
+	assertEq(t, "updated mtime", got["c1"].FileMtime,
+		int64(1718000060000)*1_000_000)
+	assertEq(t, "created mtime", got["empty"].FileMtime,
+		int64(1718000100000)*1_000_000)
+
+	metas, err = ListCursorComposerMeta(filepath.Join(userDir, "none.vscdb"))
+	if err != nil || metas != nil {
+		t.Errorf("missing db = %v, %v; want nil", metas, err)
+	}
+}
+
+func TestParseCursorComposer(t *testing.T) {
+	userDir, folder := writeCursorUserDir(t)
+	dbPath := CursorGlobalDB(userDir)
+
+	folders := LoadCursorWorkspaces(userDir)
+	assertEq(t, "workspace folder", folders["c1"], folder)
+
+	sess, msgs, err := ParseCursorComposer(dbPath, "c1", folders["c1"], "laptop")
+	if err != nil {
+		t.Fatalf("ParseCursorComposer: %v", err)
+	}
+	if sess == nil {
+		t.Fatal("expected session")
+	}
+	assertEq(t, "ID", sess.ID, "cursor:c1")
+	assertEq(t, "agent", sess.Agent, AgentCursor)
+	assertEq(t, "project", sess.Project, "greeter")
+	assertEq(t, "cwd", sess.Cwd, folder)
+	assertEq(t, "first message", sess.FirstMessage, "Add a greet function")
+	assertEq(t, "user messages", sess.UserMessageCount, 1)
+	assertEq(t, "file path", sess.File.Path, dbPath+"#c1")
+	assertEq(t, "mtime", sess.File.Mtime, int64(1718000060000)*1_000_000)
+	assertEq(t, "started at",
+		sess.StartedAt.Equal(time.UnixMilli(1718000000000)), true)
+	assertEq(t, "input tokens", sess.InputTokens, int64(2500))
+	assertEq(t, "model tokens",
+		sess.TokensByModel["claude-4-sonnet"].OutputTokens, int64(100))
+	// The reply reports no tokens, so its model is left out.
+	assertEq(t, "models", len(sess.TokensByModel), 1)
+
+	// user, read, read result, command, command result, reply
+	if len(msgs) != 6 {
+		for _, m := range msgs {
+			t.Logf("%s: %q", m.Role, m.Content)
+		}
+		t.Fatalf("got %d messages, want 6", len(msgs))
+	}
+	assertEq(t, "msgs[0].Role", msgs[0].Role, RoleUser)
+	assertEq(t, "msgs[0].Timestamp",
+		msgs[0].Timestamp.Equal(time.Date(2024, 6, 10, 6, 13, 21, 0, time.UTC)),
+		true)
+
+	read := msgs[1]
+	assertEq(t, "read content", read.Content,
+		"[Thinking]\nRead the file first.\n\n[Read: app.py]")
+	assertEq(t, "thinking", read.HasThinking, true)
+	assertEq(t, "model", read.Model, "claude-4-sonnet")
+	if len(read.ToolCalls) != 1 || read.ToolCalls[0].ToolUseID != "call_1" ||
+		gjson.Get(read.ToolCalls[0].InputJSON, "target_file").Str != "app.py" {
+		t.Fatalf("read tool calls = %+v", read.ToolCalls)
+	}
+	if r := msgs[2].ToolResults; len(r) != 1 || r[0].ToolUseID != "call_1" ||
+		r[0].IsError || msgs[2].Content != "" {
+		t.Errorf("read result = %+v", msgs[2])
+	}
+
+	cmd := msgs[3].ToolCalls[0]
+	assertEq(t, "command category", cmd.Category, "Bash")
+	assertEq(t, "command content", msgs[3].Content, "[Bash: pytest]")
+	r := msgs[4].ToolResults
+	if len(r) != 1 || !r[0].IsError || r[0].ExitCode == nil || *r[0].ExitCode != 1 {
+		t.Errorf("command result = %+v", r)
+	}
+	assertEq(t, "reply", msgs[5].Content, "Done.")
+	assertEq(t, "reply model", msgs[5].Model, "gpt-5")
+
+	// Chats without user messages are skipped.
+	sess, msgs, err = ParseCursorComposer(dbPath, "empty", "", "m")
+	if err != nil || sess != nil || msgs != nil {
+		t.Errorf("empty chat = %v, %v, %v; want nil", sess, msgs, err)
+	}
+	if _, _, err := ParseCursorComposer(dbPath, "nope", "", "m"); err == nil {
+		t.Error("unknown composer parsed")
+	}
+}
+
+func TestParseCursorComposerInlineConversation(t *testing.T) {
+	dbPath := CursorGlobalDB(t.TempDir())
+	writeCursorDB(t, dbPath, map[string]string{
+		"composerData:old": `{"composerId": "old", "createdAt": 1718000000000,
+			"conversation": [
+				{"type": 1, "bubbleId": "u", "text": "hi"},
+				{"type": 2, "bubbleId": "a", "text": "hello"}
+			]}`,
+	}, nil)
+
+	sess, msgs, err := ParseCursorComposer(dbPath, "old", "", "m")
+	if err != nil {
+		t.Fatalf("ParseCursorComposer: %v", err)
+	}
+	if sess == nil || len(msgs) != 2 {
+		t.Fatalf("got %v with %d messages, want 2", sess, len(msgs))
+	}
+	assertEq(t, "project", sess.Project, "unknown")
+	assertEq(t, "reply", msgs[1].Content, "hello")
+	// Without a last update time, the chat's start is used.
+	assertEq(t, "mtime", sess.File.Mtime, int64(1718000000000)*1_000_000)
+}
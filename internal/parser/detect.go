@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// detectMaxLines bounds the JSONL lines DetectAgent inspects.
+const detectMaxLines = 50
+
+// DetectAgent guesses which agent wrote a session file from its
+// content: a Gemini chat, OpenCode export or Amp thread JSON
+// document, or a Codex rollout, Copilot events, Goose session or
+// Claude session JSONL file. Returns "" when the content matches
+// none of them.
+func DetectAgent(data []byte) AgentType {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' &&
+		gjson.ValidBytes(trimmed) {
+		root := gjson.ParseBytes(trimmed)
+		if root.Get("messages").IsArray() {
+			switch {
+			case root.Get("sessionId").Str != "":
+				return AgentGemini
+			case root.Get("info.id").Str != "":
+				return AgentOpenCode
+			case strings.HasPrefix(root.Get("id").Str, "T-"):
+				return AgentAmp
+			}
+		}
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for n := 0; n < detectMaxLines && sc.Scan(); n++ {
+		line := sc.Bytes()
+		if !gjson.ValidBytes(line) {
+			continue
+		}
+		entry := gjson.ParseBytes(line)
+		if agent := detectLineAgent(entry.Get("type").Str); agent != "" {
+			return agent
+		}
+		if isGooseLine(entry) {
+			return AgentGoose
+		}
+	}
+	return ""
+}
+
+// isGooseLine reports whether a JSONL entry is the metadata line
+// or a message of a Goose session. Neither has a type.
+func isGooseLine(entry gjson.Result) bool {
+	if !entry.IsObject() || entry.Get("type").Exists() {
+		return false
+	}
+	if entry.Get("working_dir").Exists() {
+		return true
+	}
+	return entry.Get("role").Exists() &&
+		entry.Get("created").Type == gjson.Number &&
+		entry.Get("content").IsArray()
+}
+
+// detectLineAgent maps the type of a JSONL entry to the agent
+// whose files contain it.
+func detectLineAgent(typ string) AgentType {
+	switch typ {
+	case codexTypeSessionMeta, codexTypeResponseItem,
+		codexTypeTurnContext, codexTypeEventMsg:
+		return AgentCodex
+	case copilotEventSessionStart, copilotEventUserMessage,
+		copilotEventAssistantMsg, copilotEventToolComplete,
+		copilotEventAssistantReason:
+		return AgentCopilot
+	case "user", "assistant", "summary", "system",
+		"file-history-snapshot":
+		return AgentClaude
+	}
+	if strings.HasPrefix(typ, "session.") {
+		return AgentCopilot
+	}
+	return ""
+}
+
+// IsClaudeEntry reports whether the first line of data looks
+// like a Claude session entry: a JSON object with a type and the
+// sessionId Claude writes on each entry. It lets callers accept
+// Claude sessions that start with entry types DetectAgent does
+// not know.
+func IsClaudeEntry(data []byte) bool {
+	first, _, _ := bytes.Cut(bytes.TrimSpace(data), []byte("\n"))
+	if !gjson.ValidBytes(first) {
+		return false
+	}
+	entry := gjson.ParseBytes(first)
+	return entry.Get("type").Str != "" &&
+		entry.Get("sessionId").Str != ""
+}
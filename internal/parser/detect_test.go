@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+func TestDetectAgent(t *testing.T) {
+	const ts = "2024-01-01T10:00:00Z"
+	tests := []struct {
+		name    string
+		content string
+		want    AgentType
+	}{
+		{
+			"Claude",
+			testjsonl.NewSessionBuilder().
+				AddClaudeUser(ts, "hi").
+				AddClaudeAssistant(ts, "hello").
+				String(),
+			AgentClaude,
+		},
+		{
+			"ClaudeAfterUnknownLines",
+			testjsonl.JoinJSONL(
+				`{"type":"queue-operation"}`,
+				`not json`,
+				testjsonl.ClaudeSnapshotJSON(ts),
+			),
+			AgentClaude,
+		},
+		{
+			"Codex",
+			testjsonl.NewSessionBuilder().
+				AddCodexMeta(ts, "abc", "/tmp", "codex_cli_rs").
+				AddCodexMessage(ts, "user", "hi").
+				String(),
+			AgentCodex,
+		},
+		{
+			"Copilot",
+			testjsonl.JoinJSONL(
+				`{"type":"session.start","data":{"sessionId":"abc"}}`,
+				`{"type":"user.message","data":{"content":"hi"}}`,
+			),
+			AgentCopilot,
+		},
+		{
+			"Gemini",
+			testjsonl.GeminiSessionJSON(
+				"sess-1", "hash", ts, ts,
+				[]map[string]any{
+					testjsonl.GeminiUserMsg("m1", ts, "hi"),
+				},
+			),
+			AgentGemini,
+		},
+		{
+			"OpenCodeExport",
+			testjsonl.OpenCodeExportJSON(
+				"ses_1", "/tmp", 0, 0,
+				[]map[string]any{
+					testjsonl.OpenCodeExportMsg("msg_1", "user", 0),
+				},
+			),
+			AgentOpenCode,
+		},
+		{
+			"Amp",
+			testjsonl.AmpThreadJSON(
+				"T-1", 0, "", "", []map[string]any{},
+			),
+			AgentAmp,
+		},
+		{
+			"Goose",
+			testjsonl.JoinJSONL(
+				testjsonl.GooseMetaJSON("/tmp", "greet", 10, 20),
+				testjsonl.GooseMessageJSON(
+					"m1", "user", 1704103200,
+					testjsonl.GooseText("hi"),
+				),
+			),
+			AgentGoose,
+		},
+		{
+			"GooseWithoutMetadata",
+			testjsonl.GooseMessageJSON(
+				"m1", "user", 1704103200, testjsonl.GooseText("hi"),
+			),
+			AgentGoose,
+		},
+		{"Empty", "", ""},
+		{"EmptyObject", "{}", ""},
+		{"Text", "hello world\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectAgent([]byte(tt.content))
+			if got != tt.want {
+				t.Errorf("DetectAgent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsClaudeEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{
+			"QueueOperation",
+			`{"type":"queue-operation","sessionId":"abc"}` + "\n",
+			true,
+		},
+		{"NoSessionID", `{"type":"queue-operation"}`, false},
+		{"NoType", `{"sessionId":"abc"}`, false},
+		{
+			"SessionIDOnLaterLine",
+			testjsonl.JoinJSONL(
+				`{"hello":"world"}`,
+				`{"type":"user","sessionId":"abc"}`,
+			),
+			false,
+		},
+		{"Text", "hello world\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsClaudeEntry([]byte(tt.content)); got != tt.want {
+				t.Errorf("IsClaudeEntry = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
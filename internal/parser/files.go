@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"github.com/tidwall/gjson"
+)
+
+// File access kinds recorded for tool calls.
+const (
+	FileRead  = "read"
+	FileEdit  = "edit"
+	FileWrite = "write"
+)
+
+// ToolFile is a file a tool call read or changed, with the path
+// as the agent gave it.
+type ToolFile struct {
+	Path   string
+	Action string
+}
+
+// toolFileActions maps the file tools of each agent to the
+// access they make.
+var toolFileActions = map[string]string{
+	// Claude Code
+	"Read":         FileRead,
+	"Edit":         FileEdit,
+	"MultiEdit":    FileEdit,
+	"NotebookEdit": FileEdit,
+	"Write":        FileWrite,
+	// Gemini CLI
+	"read_file":  FileRead,
+	"replace":    FileEdit,
+	"edit_file":  FileEdit,
+	"write_file": FileWrite,
+	// OpenCode
+	"read":  FileRead,
+	"edit":  FileEdit,
+	"write": FileWrite,
+	// Copilot CLI
+	"view":   FileRead,
+	"create": FileWrite,
+	// Cline and Roo Code
+	"write_to_file":      FileWrite,
+	"replace_in_file":    FileEdit,
+	"apply_diff":         FileEdit,
+	"insert_content":     FileEdit,
+	"search_and_replace": FileEdit,
+	// Cursor
+	"search_replace": FileEdit,
+	// Amp
+	"create_file": FileWrite,
+	"undo_edit":   FileEdit,
+}
+
+// ExtractToolFiles returns the files a tool call read or changed,
+// from its input JSON. Codex apply_patch calls yield every file
+// in the patch.
+func ExtractToolFiles(toolName, inputJSON string) []ToolFile {
+	if inputJSON == "" {
+		return nil
+	}
+	in := gjson.Parse(inputJSON)
+
+	var patch string
+	switch toolName {
+	case "apply_patch":
+		patch = codexArgValue(in, "patch", "input")
+	case "shell", "exec_command", "shell_command":
+		// Older Codex versions run apply_patch as a command.
+		cmd := in.Get("command").Array()
+		if len(cmd) != 2 || cmd[0].Str != "apply_patch" {
+			return nil
+		}
+		patch = cmd[1].Str
+	}
+	if patch != "" {
+		var files []ToolFile
+		for _, p := range extractPatchedFiles(patch) {
+			files = append(files, ToolFile{Path: p, Action: FileEdit})
+		}
+		return files
+	}
+
+	action, ok := toolFileActions[toolName]
+	if toolName == gooseTextEditor {
+		action, ok = gooseEditorAction(in.Get("command").Str)
+	}
+	if !ok {
+		return nil
+	}
+	path := codexArgValue(in,
+		"file_path", "filePath", "absolute_path",
+		"notebook_path", "path", "target_file",
+	)
+	if path == "" {
+		return nil
+	}
+	return []ToolFile{{Path: path, Action: action}}
+}
+
+// gooseEditorAction maps a Goose text editor command to the
+// access it makes.
+func gooseEditorAction(command string) (string, bool) {
+	switch command {
+	case "view":
+		return FileRead, true
+	case "write":
+		return FileWrite, true
+	case "str_replace", "insert", "undo_edit":
+		return FileEdit, true
+	}
+	return "", false
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractToolFiles(t *testing.T) {
+	tests := []struct {
+		name, tool, input string
+		want              []ToolFile
+	}{
+		{"ClaudeRead", "Read", `{"file_path":"/r/a.go"}`,
+			[]ToolFile{{"/r/a.go", FileRead}}},
+		{"ClaudeMultiEdit", "MultiEdit", `{"file_path":"/r/a.go","edits":[]}`,
+			[]ToolFile{{"/r/a.go", FileEdit}}},
+		{"ClaudeWrite", "Write", `{"file_path":"/r/b.go","content":"x"}`,
+			[]ToolFile{{"/r/b.go", FileWrite}}},
+		{"GeminiRead", "read_file", `{"absolute_path":"/r/c.go"}`,
+			[]ToolFile{{"/r/c.go", FileRead}}},
+		{"OpenCodeEdit", "edit", `{"filePath":"/r/d.go"}`,
+			[]ToolFile{{"/r/d.go", FileEdit}}},
+		{"CopilotView", "view", `{"path":"config.json"}`,
+			[]ToolFile{{"config.json", FileRead}}},
+		{"ClineReplace", "replace_in_file", `{"path":"src/e.ts","diff":"x"}`,
+			[]ToolFile{{"src/e.ts", FileEdit}}},
+		{"CursorRead", "read_file", `{"target_file":"src/f.ts"}`,
+			[]ToolFile{{"src/f.ts", FileRead}}},
+		{"GooseView", "developer__text_editor",
+			`{"command":"view","path":"/src/g.rs"}`,
+			[]ToolFile{{"/src/g.rs", FileRead}}},
+		{"GooseReplace", "developer__text_editor",
+			`{"command":"str_replace","path":"/src/g.rs","old_str":"a","new_str":"b"}`,
+			[]ToolFile{{"/src/g.rs", FileEdit}}},
+		{"AmpCreate", "create_file", `{"path":"/src/h.go","content":"x"}`,
+			[]ToolFile{{"/src/h.go", FileWrite}}},
+		{"CodexPatch", "apply_patch",
+			`{"input":"*** Begin Patch\n*** Update File: a.go\n` +
+				`*** Add File: b.go\n+x\n*** End Patch"}`,
+			[]ToolFile{{"a.go", FileEdit}, {"b.go", FileEdit}}},
+		{"CodexShellPatch", "shell",
+			`{"command":["apply_patch","*** Begin Patch\n` +
+				`*** Delete File: c.go\n*** End Patch"]}`,
+			[]ToolFile{{"c.go", FileEdit}}},
+		{"Shell", "shell", `{"command":["ls"]}`, nil},
+		{"Bash", "Bash", `{"command":"cat a.go"}`, nil},
+		{"NoPath", "Read", `{}`, nil},
+		{"NoInput", "Read", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExtractToolFiles(tt.tool, tt.input))
+		})
+	}
+}
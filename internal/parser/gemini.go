@@ -174,8 +174,9 @@ func extractGeminiContent(
 			name := tc.Get("name").Str
 			if name != "" {
 				parsed = append(parsed, ParsedToolCall{
-					ToolName: name,
-					Category: NormalizeToolCategory(name),
+					ToolName:  name,
+					Category:  NormalizeToolCategory(name),
+					InputJSON: tc.Get("args").Raw,
 				})
 			}
 			parts = append(parts, formatGeminiToolCall(tc))
@@ -0,0 +1,308 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// gooseTextEditor is Goose's developer extension file tool. Its
+// command argument selects what it does to the file.
+const gooseTextEditor = "developer__text_editor"
+
+// gooseSessionBuilder accumulates state while scanning a Goose
+// JSONL session file line by line.
+type gooseSessionBuilder struct {
+	messages     []ParsedMessage
+	firstMessage string
+	startedAt    time.Time
+	endedAt      time.Time
+	cwd          string
+	inputTokens  int64
+	outputTokens int64
+	userCount    int
+	ordinal      int
+}
+
+// processLine handles a single valid JSON line. The first line
+// of a session file is its metadata; the rest are messages.
+func (b *gooseSessionBuilder) processLine(line string) {
+	msg := gjson.Parse(line)
+	if !msg.Get("role").Exists() {
+		if msg.Get("working_dir").Exists() {
+			b.handleMetadata(msg)
+		}
+		return
+	}
+
+	ts := time.Time{}
+	if created := msg.Get("created").Int(); created > 0 {
+		ts = time.Unix(created, 0)
+		if b.startedAt.IsZero() {
+			b.startedAt = ts
+		}
+		b.endedAt = ts
+	}
+
+	switch msg.Get("role").Str {
+	case "user":
+		b.handleUser(msg.Get("content"), ts)
+	case "assistant":
+		b.handleAssistant(msg.Get("content"), ts)
+	}
+}
+
+// handleMetadata reads the working directory and token totals.
+// Sessions written by older versions only have the counts of
+// the last request, so the accumulated counts are preferred.
+func (b *gooseSessionBuilder) handleMetadata(meta gjson.Result) {
+	b.cwd = meta.Get("working_dir").Str
+	b.inputTokens = meta.Get("accumulated_input_tokens").Int()
+	if b.inputTokens == 0 {
+		b.inputTokens = meta.Get("input_tokens").Int()
+	}
+	b.outputTokens = meta.Get("accumulated_output_tokens").Int()
+	if b.outputTokens == 0 {
+		b.outputTokens = meta.Get("output_tokens").Int()
+	}
+}
+
+// handleUser adds a user message. Tool responses travel in user
+// messages, so a message may carry only tool results.
+func (b *gooseSessionBuilder) handleUser(
+	content gjson.Result, ts time.Time,
+) {
+	var (
+		parts   []string
+		results []ParsedToolResult
+	)
+	content.ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").Str {
+		case "text":
+			if text := strings.TrimSpace(block.Get("text").Str); text != "" {
+				parts = append(parts, text)
+			}
+		case "toolResponse":
+			results = append(results, gooseToolResult(block))
+		}
+		return true
+	})
+
+	text := strings.Join(parts, "\n")
+	if text == "" && len(results) == 0 {
+		return
+	}
+	if text != "" {
+		if b.firstMessage == "" {
+			b.firstMessage = truncate(
+				strings.ReplaceAll(text, "\n", " "), 300,
+			)
+		}
+		b.userCount++
+	}
+
+	length := len(text)
+	for _, r := range results {
+		length += r.ContentLength
+	}
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:       b.ordinal,
+		Role:          RoleUser,
+		Content:       text,
+		Timestamp:     ts,
+		ContentLength: length,
+		ToolResults:   results,
+	})
+	b.ordinal++
+}
+
+func (b *gooseSessionBuilder) handleAssistant(
+	content gjson.Result, ts time.Time,
+) {
+	var (
+		parts       []string
+		calls       []ParsedToolCall
+		hasThinking bool
+	)
+	content.ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").Str {
+		case "text":
+			if text := strings.TrimSpace(block.Get("text").Str); text != "" {
+				parts = append(parts, text)
+			}
+		case "thinking":
+			if text := strings.TrimSpace(block.Get("thinking").Str); text != "" {
+				hasThinking = true
+				parts = append(parts, "[Thinking]\n"+text)
+			}
+		case "redactedThinking":
+			hasThinking = true
+		case "toolRequest":
+			call, ok := gooseToolCall(block)
+			if !ok {
+				return true
+			}
+			calls = append(calls, call)
+			parts = append(parts, formatToolHeader(
+				call.Category,
+				gooseToolDetail(gjson.Parse(call.InputJSON)),
+			))
+		}
+		return true
+	})
+
+	text := strings.Join(parts, "\n\n")
+	if text == "" {
+		return
+	}
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:       b.ordinal,
+		Role:          RoleAssistant,
+		Content:       text,
+		Timestamp:     ts,
+		HasThinking:   hasThinking,
+		HasToolUse:    len(calls) > 0,
+		ContentLength: len(text),
+		ToolCalls:     calls,
+	})
+	b.ordinal++
+}
+
+// gooseToolCall converts a toolRequest block. Requests the model
+// made malformed have no tool call value and are skipped.
+func gooseToolCall(block gjson.Result) (ParsedToolCall, bool) {
+	value := block.Get("toolCall.value")
+	name := value.Get("name").Str
+	if name == "" {
+		return ParsedToolCall{}, false
+	}
+	input := value.Get("arguments").Raw
+	if input == "" {
+		input = "{}"
+	}
+	return ParsedToolCall{
+		ToolUseID: block.Get("id").Str,
+		ToolName:  name,
+		Category:  gooseToolCategory(name, gjson.Parse(input)),
+		InputJSON: input,
+	}, true
+}
+
+// gooseToolCategory refines the category of the text editor,
+// which views and writes files as well as editing them.
+func gooseToolCategory(name string, in gjson.Result) string {
+	if name == gooseTextEditor {
+		switch in.Get("command").Str {
+		case "view":
+			return "Read"
+		case "write":
+			return "Write"
+		}
+	}
+	return NormalizeToolCategory(name)
+}
+
+// gooseToolDetail returns the part of a tool's input shown next
+// to its category.
+func gooseToolDetail(in gjson.Result) string {
+	for _, key := range []string{"path", "command", "query"} {
+		if v := in.Get(key).Str; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// gooseToolResult converts a toolResponse block. Successful
+// results hold a list of content items; failed ones an error.
+func gooseToolResult(block gjson.Result) ParsedToolResult {
+	result := block.Get("toolResult")
+	r := ParsedToolResult{ToolUseID: block.Get("id").Str}
+	if result.Get("status").Str == "error" {
+		r.IsError = true
+		r.Content = result.Get("error").Str
+	} else {
+		r.Content = toolResultContentText(result.Get("value"))
+	}
+	r.ContentLength = len(r.Content)
+	r.ExitCode = exitCodeFromOutput(r.Content)
+	return r
+}
+
+// ParseGooseSession parses a Goose JSONL session file. Returns
+// (nil, nil, nil) if the file doesn't exist or has no user
+// messages.
+func ParseGooseSession(
+	path, machine string,
+) (*ParsedSession, []ParsedMessage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	lr := newLineReader(f, maxLineSize)
+	var b gooseSessionBuilder
+	for {
+		line, ok := lr.next()
+		if !ok {
+			break
+		}
+		if !gjson.Valid(line) {
+			continue
+		}
+		b.processLine(line)
+	}
+	if err := lr.Err(); err != nil {
+		return nil, nil,
+			fmt.Errorf("reading goose %s: %w", path, err)
+	}
+
+	if b.userCount == 0 {
+		return nil, nil, nil
+	}
+
+	project := ExtractProjectFromCwd(b.cwd)
+	if project == "" {
+		project = "unknown"
+	}
+
+	sess := &ParsedSession{
+		ID:               "goose:" + GooseSessionID(path),
+		Project:          project,
+		Machine:          machine,
+		Agent:            AgentGoose,
+		FirstMessage:     b.firstMessage,
+		StartedAt:        b.startedAt,
+		EndedAt:          b.endedAt,
+		MessageCount:     len(b.messages),
+		UserMessageCount: b.userCount,
+		InputTokens:      b.inputTokens,
+		OutputTokens:     b.outputTokens,
+		File: FileInfo{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: info.ModTime().UnixNano(),
+		},
+	}
+	sess.SetWorkspace(b.cwd, "")
+	return sess, b.messages, nil
+}
+
+// GooseSessionID returns the session ID of a Goose session
+// file, which is its name without the extension.
+func GooseSessionID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".jsonl")
+}
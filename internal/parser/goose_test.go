@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+// gooseStart is the creation time, in Unix seconds, of the first
+// message of the Goose test sessions.
+const gooseStart int64 = 1718000000
+
+func TestParseGooseSession(t *testing.T) {
+	content := testjsonl.NewSessionBuilder().
+		AddGooseMeta("/src/greeter", "Add a greet function", 5200, 340).
+		AddGooseMessage("m1", "user", gooseStart,
+			testjsonl.GooseText("Add a greet function")).
+		AddGooseMessage("m2", "assistant", gooseStart+4,
+			testjsonl.GooseThinking("Look at the file first."),
+			testjsonl.GooseToolRequest("call_1", "developer__text_editor",
+				map[string]any{"command": "view", "path": "/src/greeter/app.py"})).
+		AddGooseMessage("m3", "user", gooseStart+5,
+			testjsonl.GooseToolResponse("call_1", "def main(): pass", false)).
+		AddGooseMessage("m4", "assistant", gooseStart+9,
+			testjsonl.GooseText("Running the tests."),
+			testjsonl.GooseToolRequest("call_2", "developer__shell",
+				map[string]any{"command": "pytest"})).
+		AddGooseMessage("m5", "user", gooseStart+12,
+			testjsonl.GooseToolResponse("call_2", "command failed", true)).
+		AddGooseMessage("m6", "assistant", gooseStart+15,
+			testjsonl.GooseText("The tests fail before the change.")).
+		String()
+	path := createTestFile(t, "20240610_061320.jsonl", content)
+
+	sess, msgs, err := ParseGooseSession(path, "laptop")
+	require.NoError(t, err)
+	require.NotNil(t, sess)
+
+	assertSessionMeta(t, sess, "goose:20240610_061320", "greeter", AgentGoose)
+	assert.Equal(t, "/src/greeter", sess.Cwd)
+	assert.Equal(t, "Add a greet function", sess.FirstMessage)
+	assert.Equal(t, 1, sess.UserMessageCount)
+	assert.Equal(t, int64(5200), sess.InputTokens)
+	assert.Equal(t, int64(340), sess.OutputTokens)
+	assertTimestamp(t, sess.StartedAt, time.Unix(gooseStart, 0))
+	assertTimestamp(t, sess.EndedAt, time.Unix(gooseStart+15, 0))
+
+	assertMessageCount(t, len(msgs), 6)
+	assertMessage(t, msgs[0], RoleUser, "Add a greet function")
+
+	view := msgs[1]
+	assert.Equal(t,
+		"[Thinking]\nLook at the file first.\n\n[Read: /src/greeter/app.py]",
+		view.Content)
+	assert.True(t, view.HasThinking)
+	assertToolCalls(t, view.ToolCalls, []ParsedToolCall{{
+		ToolUseID: "call_1",
+		ToolName:  "developer__text_editor",
+		Category:  "Read",
+	}})
+
+	require.Len(t, msgs[2].ToolResults, 1)
+	assert.Equal(t, "", msgs[2].Content)
+	assert.Equal(t, "call_1", msgs[2].ToolResults[0].ToolUseID)
+	assert.Equal(t, "def main(): pass", msgs[2].ToolResults[0].Content)
+	assert.False(t, msgs[2].ToolResults[0].IsError)
+
+	assertMessage(t, msgs[3], RoleAssistant, "[Bash: pytest]")
+	assert.Equal(t, "Bash", msgs[3].ToolCalls[0].Category)
+	require.Len(t, msgs[4].ToolResults, 1)
+	assert.True(t, msgs[4].ToolResults[0].IsError)
+	assertMessage(t, msgs[5], RoleAssistant, "fail before the change")
+}
+
+func TestParseGooseSessionWithoutUserMessages(t *testing.T) {
+	content := testjsonl.NewSessionBuilder().
+		AddGooseMeta("/src/greeter", "", 0, 0).
+		AddGooseMessage("m1", "assistant", gooseStart,
+			testjsonl.GooseText("Hello.")).
+		String()
+	path := createTestFile(t, "empty.jsonl", content)
+
+	sess, msgs, err := ParseGooseSession(path, "m")
+	require.NoError(t, err)
+	assert.Nil(t, sess)
+	assert.Nil(t, msgs)
+}
+
+func TestParseGooseSessionLegacyTokens(t *testing.T) {
+	// Older versions record only the last request's counts.
+	content := testjsonl.NewSessionBuilder().
+		AddRaw(`{"working_dir":"/src/app","description":"","input_tokens":90,"output_tokens":12}`).
+		AddGooseMessage("m1", "user", gooseStart,
+			testjsonl.GooseText("hi")).
+		String()
+	path := createTestFile(t, "legacy.jsonl", content)
+
+	sess, _, err := ParseGooseSession(path, "m")
+	require.NoError(t, err)
+	require.NotNil(t, sess)
+	assert.Equal(t, int64(90), sess.InputTokens)
+	assert.Equal(t, int64(12), sess.OutputTokens)
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
 	"io"
 )
 
@@ -14,6 +15,13 @@ type lineReader struct {
 	maxLen int
 	buf    []byte
 	err    error
+	// pos is the byte offset just past the last line read,
+	// relative to where the underlying reader started.
+	pos int64
+	// terminated reports whether the last line returned by
+	// next ended with a newline (false for a trailing partial
+	// line at EOF).
+	terminated bool
 }
 
 func newLineReader(r io.Reader, maxLen int) *lineReader {
@@ -55,36 +63,43 @@ func (lr *lineReader) readLine() (string, error) {
 	oversized := false
 
 	for {
-		chunk, isPrefix, err := lr.r.ReadLine()
-		if err != nil {
-			if len(lr.buf) > 0 && err == io.EOF {
-				break
+		chunk, err := lr.r.ReadSlice('\n')
+		lr.pos += int64(len(chunk))
+		switch err {
+		case nil:
+			lr.terminated = true
+		case bufio.ErrBufferFull:
+		case io.EOF:
+			if len(chunk) == 0 && len(lr.buf) == 0 && !oversized {
+				return "", io.EOF
 			}
+			lr.terminated = false
+		default:
 			return "", err
 		}
 
-		if oversized {
-			if !isPrefix {
-				return "", nil // done skipping
+		// Allow for the "\r\n" terminator before enforcing
+		// the limit, which applies to the line content.
+		if !oversized {
+			lr.buf = append(lr.buf, chunk...)
+			if len(lr.buf) > lr.maxLen+2 {
+				oversized = true
+				lr.buf = lr.buf[:0]
 			}
-			continue
 		}
 
-		lr.buf = append(lr.buf, chunk...)
-
-		if len(lr.buf) > lr.maxLen {
-			oversized = true
-			lr.buf = lr.buf[:0]
-			if !isPrefix {
-				return "", nil
-			}
-			continue
-		}
-
-		if !isPrefix {
+		if err != bufio.ErrBufferFull {
 			break
 		}
 	}
 
-	return string(lr.buf), nil
+	if oversized {
+		return "", nil
+	}
+	line := bytes.TrimSuffix(lr.buf, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) > lr.maxLen {
+		return "", nil
+	}
+	return string(line), nil
 }
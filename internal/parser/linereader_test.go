@@ -117,3 +117,30 @@ func TestLineReaderIOError(t *testing.T) {
 		t.Fatalf("Err() = %v, want %v", lr.Err(), ioErr)
 	}
 }
+
+func TestLineReaderPosition(t *testing.T) {
+	lr := newLineReader(strings.NewReader("aa\r\n\nbbb\ncc"), 100)
+	type step struct {
+		line       string
+		pos        int64
+		terminated bool
+	}
+	want := []step{
+		{"aa", 4, true},
+		{"bbb", 9, true},
+		{"cc", 11, false},
+	}
+	for i, w := range want {
+		line, ok := lr.next()
+		if !ok {
+			t.Fatalf("line %d: unexpected EOF", i)
+		}
+		got := step{line, lr.pos, lr.terminated}
+		if got != w {
+			t.Errorf("line %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if _, ok := lr.next(); ok {
+		t.Error("expected EOF")
+	}
+}
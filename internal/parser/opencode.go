@@ -161,6 +161,104 @@ func ParseOpenCodeSession(
 	)
 }
 
+// openCodeExport is the JSON document written by
+// "opencode export": the session row and each message with its
+// parts, in the same shapes as the database's data columns.
+type openCodeExport struct {
+	Info struct {
+		ID        string `json:"id"`
+		ParentID  string `json:"parentID"`
+		Title     string `json:"title"`
+		Directory string `json:"directory"`
+		Time      struct {
+			Created int64 `json:"created"`
+			Updated int64 `json:"updated"`
+		} `json:"time"`
+	} `json:"info"`
+	Messages []struct {
+		Info  json.RawMessage   `json:"info"`
+		Parts []json.RawMessage `json:"parts"`
+	} `json:"messages"`
+}
+
+// ParseOpenCodeExport parses a session exported with
+// "opencode export". Returns (nil, nil, nil) for sessions
+// without user or assistant content.
+func ParseOpenCodeExport(
+	path, machine string,
+) (*ParsedSession, []ParsedMessage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var exp openCodeExport
+	if err := json.Unmarshal(data, &exp); err != nil {
+		return nil, nil, fmt.Errorf(
+			"invalid opencode export %s: %w", path, err,
+		)
+	}
+	if exp.Info.ID == "" {
+		return nil, nil, fmt.Errorf(
+			"missing session id in %s", path,
+		)
+	}
+
+	s := openCodeSessionRow{
+		id:          exp.Info.ID,
+		parentID:    exp.Info.ParentID,
+		title:       exp.Info.Title,
+		timeCreated: exp.Info.Time.Created,
+		timeUpdated: exp.Info.Time.Updated,
+	}
+	msgs := make([]openCodeMessageRow, 0, len(exp.Messages))
+	parts := make(map[string][]openCodePartRow)
+	for i, m := range exp.Messages {
+		var mi struct {
+			ID   string `json:"id"`
+			Time struct {
+				Created int64 `json:"created"`
+			} `json:"time"`
+		}
+		if json.Unmarshal(m.Info, &mi) != nil {
+			continue
+		}
+		if mi.ID == "" {
+			mi.ID = fmt.Sprintf("#%d", i)
+		}
+		msgs = append(msgs, openCodeMessageRow{
+			id:          mi.ID,
+			data:        string(m.Info),
+			timeCreated: mi.Time.Created,
+		})
+		// Parts are exported in order; leaving their times
+		// zero keeps that order through the stable sort.
+		for _, p := range m.Parts {
+			parts[mi.ID] = append(parts[mi.ID], openCodePartRow{
+				messageID: mi.ID,
+				data:      string(p),
+			})
+		}
+	}
+
+	sess, parsed := assembleOpenCodeSession(
+		s, msgs, parts, exp.Info.Directory, machine,
+	)
+	if sess == nil {
+		return nil, nil, nil
+	}
+	sess.File = FileInfo{
+		Path:  path,
+		Size:  info.Size(),
+		Mtime: info.ModTime().UnixNano(),
+	}
+	return sess, parsed, nil
+}
+
 func openOpenCodeDB(dbPath string) (*sql.DB, error) {
 	dsn := dbPath +
 		"?mode=ro&_journal_mode=WAL&_busy_timeout=3000"
@@ -272,7 +370,8 @@ type openCodeMessageRow struct {
 // openCodeMessageData holds the fields we extract from the
 // message data JSON blob.
 type openCodeMessageData struct {
-	Role string `json:"role"`
+	Role    string `json:"role"`
+	ModelID string `json:"modelID"`
 }
 
 // openCodePartRow is a row from the opencode part table.
@@ -362,6 +461,29 @@ func buildOpenCodeSession(
 		)
 	}
 
+	sess, parsed := assembleOpenCodeSession(
+		s, msgs, parts, worktree, machine,
+	)
+	if sess == nil {
+		return nil, nil, nil
+	}
+	sess.File = FileInfo{
+		Path:  dbPath + "#" + s.id,
+		Mtime: s.timeUpdated * 1_000_000,
+	}
+	return sess, parsed, nil
+}
+
+// assembleOpenCodeSession builds a session from its messages
+// and their parts, however they were loaded. Returns nil for
+// sessions without user or assistant content. The caller sets
+// the session's file info.
+func assembleOpenCodeSession(
+	s openCodeSessionRow,
+	msgs []openCodeMessageRow,
+	parts map[string][]openCodePartRow,
+	worktree, machine string,
+) (*ParsedSession, []ParsedMessage) {
 	var (
 		parsed       []ParsedMessage
 		firstMsg     string
@@ -381,7 +503,7 @@ func buildOpenCodeSession(
 		hasUserOrAst = true
 
 		msgParts := parts[m.id]
-		sort.Slice(msgParts, func(a, b int) bool {
+		sort.SliceStable(msgParts, func(a, b int) bool {
 			return msgParts[a].timeCreated <
 				msgParts[b].timeCreated
 		})
@@ -389,6 +511,9 @@ func buildOpenCodeSession(
 		pm := buildOpenCodeMessage(
 			ordinal, role, m.timeCreated, msgParts,
 		)
+		if role == RoleAssistant {
+			pm.Model = md.ModelID
+		}
 		if strings.TrimSpace(pm.Content) == "" &&
 			!pm.HasToolUse {
 			continue
@@ -406,7 +531,7 @@ func buildOpenCodeSession(
 	}
 
 	if !hasUserOrAst || len(parsed) == 0 {
-		return nil, nil, nil
+		return nil, nil
 	}
 
 	project := ExtractProjectFromCwd(worktree)
@@ -440,13 +565,10 @@ func buildOpenCodeSession(
 		EndedAt:          endedAt,
 		MessageCount:     len(parsed),
 		UserMessageCount: userCount,
-		File: FileInfo{
-			Path:  dbPath + "#" + s.id,
-			Mtime: s.timeUpdated * 1_000_000,
-		},
 	}
+	sess.SetWorkspace(worktree, "")
 
-	return sess, parsed, nil
+	return sess, parsed
 }
 
 func normalizeOpenCodeRole(role string) RoleType {
@@ -471,6 +593,7 @@ func buildOpenCodeMessage(
 		toolCalls   []ParsedToolCall
 		hasThinking bool
 		hasToolUse  bool
+		tokens      openCodeTokens
 	)
 
 	for _, p := range parts {
@@ -493,21 +616,57 @@ func buildOpenCodeMessage(
 				hasThinking = true
 				texts = append(texts, "[Thinking]\n"+text)
 			}
+		case "step-finish":
+			tokens.add(extractOpenCodeStepTokens(p.data))
 		}
-		// skip step-start, step-finish, patch, etc.
+		// skip step-start, patch, etc.
 	}
 
 	content := strings.Join(texts, "\n")
 	return ParsedMessage{
-		Ordinal:       ordinal,
-		Role:          role,
-		Content:       content,
-		Timestamp:     millisToTime(timeCreatedMs),
-		HasThinking:   hasThinking,
-		HasToolUse:    hasToolUse,
-		ContentLength: len(content),
-		ToolCalls:     toolCalls,
+		Ordinal:                  ordinal,
+		Role:                     role,
+		Content:                  content,
+		Timestamp:                millisToTime(timeCreatedMs),
+		HasThinking:              hasThinking,
+		HasToolUse:               hasToolUse,
+		ContentLength:            len(content),
+		ToolCalls:                toolCalls,
+		InputTokens:              tokens.Input,
+		OutputTokens:             tokens.Output + tokens.Reasoning,
+		CacheCreationInputTokens: tokens.Cache.Write,
+		CacheReadInputTokens:     tokens.Cache.Read,
+	}
+}
+
+// openCodeTokens is the token usage a step-finish part reports
+// for one model call. Reasoning tokens are billed as output.
+type openCodeTokens struct {
+	Input     int64 `json:"input"`
+	Output    int64 `json:"output"`
+	Reasoning int64 `json:"reasoning"`
+	Cache     struct {
+		Read  int64 `json:"read"`
+		Write int64 `json:"write"`
+	} `json:"cache"`
+}
+
+func (t *openCodeTokens) add(o openCodeTokens) {
+	t.Input += o.Input
+	t.Output += o.Output
+	t.Reasoning += o.Reasoning
+	t.Cache.Read += o.Cache.Read
+	t.Cache.Write += o.Cache.Write
+}
+
+func extractOpenCodeStepTokens(data string) openCodeTokens {
+	var d struct {
+		Tokens openCodeTokens `json:"tokens"`
+	}
+	if json.Unmarshal([]byte(data), &d) != nil {
+		return openCodeTokens{}
 	}
+	return d.Tokens
 }
 
 // openCodePartTypeData extracts just the type from a part's
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/wesm/agentsview/internal/testjsonl"
 )
 
 // openCodeSchema matches the real OpenCode database schema.
@@ -377,3 +378,81 @@ func TestListOpenCodeSessionMeta_NonexistentDB(t *testing.T) {
 	}
 	assertEq(t, "metas len", len(metas), 0)
 }
+
+func TestParseOpenCodeDB_StepTokens(t *testing.T) {
+	dbPath, seeder, db := newTestDB(t)
+	defer db.Close()
+
+	seeder.AddProject("prj_1", "/tmp/proj")
+	seeder.AddSession("ses_tok", "prj_1", "", "", 1700000000000, 1700000020000)
+	seeder.AddMessage("msg_u", "ses_tok", 1700000000000, 1700000000000, `{"role":"user"}`)
+	seeder.AddPart("prt_u", "msg_u", "ses_tok", 1700000000000, 1700000000000, `{"type":"text","text":"hi"}`)
+	seeder.AddMessage("msg_a", "ses_tok", 1700000010000, 1700000010000, `{"role":"assistant","modelID":"claude-sonnet-4"}`)
+	seeder.AddPart("prt_a", "msg_a", "ses_tok", 1700000010000, 1700000010000, `{"type":"text","text":"hello"}`)
+	seeder.AddPart("prt_s1", "msg_a", "ses_tok", 1700000011000, 1700000011000, `{"type":"step-finish","tokens":{"input":10,"output":5,"reasoning":2,"cache":{"read":100,"write":20}}}`)
+	seeder.AddPart("prt_s2", "msg_a", "ses_tok", 1700000012000, 1700000012000, `{"type":"step-finish","tokens":{"input":3,"output":1,"reasoning":0,"cache":{"read":50,"write":0}}}`)
+
+	sessions, err := ParseOpenCodeDB(dbPath, "m")
+	if err != nil {
+		t.Fatalf("ParseOpenCodeDB: %v", err)
+	}
+	assertEq(t, "sessions len", len(sessions), 1)
+
+	msgs := sessions[0].Messages
+	assertEq(t, "messages len", len(msgs), 2)
+	assertEq(t, "user Model", msgs[0].Model, "")
+
+	ast := msgs[1]
+	assertEq(t, "Model", ast.Model, "claude-sonnet-4")
+	assertEq(t, "InputTokens", ast.InputTokens, int64(13))
+	assertEq(t, "OutputTokens", ast.OutputTokens, int64(8))
+	assertEq(t, "CacheCreationInputTokens", ast.CacheCreationInputTokens, int64(20))
+	assertEq(t, "CacheReadInputTokens", ast.CacheReadInputTokens, int64(150))
+}
+
+func TestParseOpenCodeExport(t *testing.T) {
+	content := testjsonl.OpenCodeExportJSON(
+		"ses_exp", "/home/user/code/myapp",
+		1700000000000, 1700000020000,
+		[]map[string]any{
+			testjsonl.OpenCodeExportMsg("msg_1", "user", 1700000000000,
+				map[string]any{"type": "text", "text": "List the files"},
+			),
+			testjsonl.OpenCodeExportMsg("msg_2", "assistant", 1700000010000,
+				map[string]any{"type": "reasoning", "text": "Use ls."},
+				map[string]any{
+					"type": "tool", "tool": "bash", "callID": "call_1",
+					"state": map[string]any{
+						"input": map[string]any{"command": "ls"},
+					},
+				},
+				map[string]any{"type": "text", "text": "Done."},
+			),
+		},
+	)
+	path := createTestFile(t, "export.json", content)
+
+	sess, msgs, err := ParseOpenCodeExport(path, "m")
+	if err != nil {
+		t.Fatalf("ParseOpenCodeExport: %v", err)
+	}
+	if sess == nil {
+		t.Fatal("expected session")
+	}
+	assertEq(t, "ID", sess.ID, "opencode:ses_exp")
+	assertEq(t, "Project", sess.Project, "myapp")
+	assertEq(t, "File.Path", sess.File.Path, path)
+	assertEq(t, "FirstMessage", sess.FirstMessage, "List the files")
+	assertEq(t, "messages len", len(msgs), 2)
+	assertEq(t, "msg[1].Content", msgs[1].Content, "[Thinking]\nUse ls.\nDone.")
+	assertEq(t, "msg[1].HasThinking", msgs[1].HasThinking, true)
+	assertEq(t, "tool calls", len(msgs[1].ToolCalls), 1)
+	assertEq(t, "tool name", msgs[1].ToolCalls[0].ToolName, "bash")
+}
+
+func TestParseOpenCodeExport_Invalid(t *testing.T) {
+	path := createTestFile(t, "bad.json", `{"messages":[]}`)
+	if _, _, err := ParseOpenCodeExport(path, "m"); err == nil {
+		t.Error("expected error for export without session id")
+	}
+}
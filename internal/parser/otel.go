@@ -0,0 +1,950 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// OTLP/JSON exports spans as resourceSpans, each holding the
+// attributes of the process that emitted them and a list of
+// scopeSpans. Agents following the OpenTelemetry GenAI semantic
+// conventions emit a span per model request (operation "chat")
+// and per tool execution (operation "execute_tool"); the prompt
+// and completion travel in gen_ai.* attributes.
+
+// otelAttrs maps attribute keys to their OTLP AnyValue objects.
+type otelAttrs map[string]gjson.Result
+
+// newOTelAttrs indexes an OTLP attribute list. Later entries
+// win over earlier ones with the same key.
+func newOTelAttrs(list gjson.Result) otelAttrs {
+	a := make(otelAttrs)
+	list.ForEach(func(_, kv gjson.Result) bool {
+		if key := kv.Get("key").Str; key != "" {
+			a[key] = kv.Get("value")
+		}
+		return true
+	})
+	return a
+}
+
+// str returns the attribute's value as a string. Numbers and
+// booleans are formatted; arrays and maps are returned as
+// their OTLP JSON.
+func (a otelAttrs) str(key string) string {
+	v, ok := a[key]
+	if !ok {
+		return ""
+	}
+	for _, field := range []string{
+		"stringValue", "intValue", "doubleValue", "boolValue",
+	} {
+		if f := v.Get(field); f.Exists() {
+			return f.String()
+		}
+	}
+	if f := v.Get("arrayValue"); f.Exists() {
+		return f.Raw
+	}
+	return v.Get("kvlistValue").Raw
+}
+
+// int returns the first of the attributes that holds a number.
+// OTLP/JSON writes 64-bit integers as strings.
+func (a otelAttrs) int(keys ...string) int64 {
+	for _, key := range keys {
+		v, ok := a[key]
+		if !ok {
+			continue
+		}
+		if f := v.Get("intValue"); f.Exists() {
+			return f.Int()
+		}
+		if f := v.Get("doubleValue"); f.Exists() {
+			return int64(f.Float())
+		}
+	}
+	return 0
+}
+
+// first returns the first of the attributes that is set.
+func (a otelAttrs) first(keys ...string) string {
+	for _, key := range keys {
+		if v := a.str(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// hasGenAI reports whether any attribute belongs to the GenAI
+// conventions.
+func (a otelAttrs) hasGenAI() bool {
+	for key := range a {
+		if strings.HasPrefix(key, "gen_ai.") {
+			return true
+		}
+	}
+	return false
+}
+
+// otelConversationKeys are the attributes naming the
+// conversation a span belongs to, in order of preference.
+var otelConversationKeys = []string{
+	"gen_ai.conversation.id", "session.id",
+}
+
+// otelSpan is a span of an OTLP/JSON export together with the
+// attributes of the resource that emitted it.
+type otelSpan struct {
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    otelAttrs
+	resource otelAttrs
+	failed   bool
+	status   string
+}
+
+// newOTelSpan converts an OTLP/JSON span. The attributes of
+// the events older instrumentations use to carry the prompt
+// and completion are folded into the span's own.
+func newOTelSpan(resource otelAttrs, span gjson.Result) *otelSpan {
+	s := &otelSpan{
+		traceID:  span.Get("traceId").Str,
+		spanID:   span.Get("spanId").Str,
+		parentID: span.Get("parentSpanId").Str,
+		name:     span.Get("name").Str,
+		start:    otelTime(span.Get("startTimeUnixNano")),
+		end:      otelTime(span.Get("endTimeUnixNano")),
+		attrs:    newOTelAttrs(span.Get("attributes")),
+		resource: resource,
+		status:   span.Get("status.message").Str,
+	}
+	switch code := span.Get("status.code"); code.Type {
+	case gjson.Number:
+		s.failed = code.Int() == 2
+	case gjson.String:
+		s.failed = code.Str == "STATUS_CODE_ERROR"
+	}
+	if s.end.IsZero() {
+		s.end = s.start
+	}
+	span.Get("events").ForEach(func(_, ev gjson.Result) bool {
+		if !strings.HasPrefix(ev.Get("name").Str, "gen_ai.") {
+			return true
+		}
+		for key, v := range newOTelAttrs(ev.Get("attributes")) {
+			if _, ok := s.attrs[key]; !ok {
+				s.attrs[key] = v
+			}
+		}
+		return true
+	})
+	return s
+}
+
+// otelTime converts a Unix nanosecond timestamp, which
+// OTLP/JSON writes as a string.
+func otelTime(v gjson.Result) time.Time {
+	n := v.Int()
+	if n <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// forEachOTLPRequest calls fn for every export request in data,
+// which holds either one JSON document or one request per line,
+// as the collector's file exporter writes them.
+func forEachOTLPRequest(
+	data []byte, fn func(req gjson.Result),
+) error {
+	if gjson.ValidBytes(data) {
+		fn(gjson.ParseBytes(data))
+		return nil
+	}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !gjson.ValidBytes(line) {
+			return fmt.Errorf("invalid JSON on line %d", i+1)
+		}
+		fn(gjson.ParseBytes(line))
+	}
+	return nil
+}
+
+// OTelBatch holds the GenAI spans of one session taken from an
+// OTLP/JSON export.
+type OTelBatch struct {
+	// Key names the session: the conversation ID of its spans,
+	// or their trace ID if they carry none. Keys that are not
+	// safe file names are replaced by a hash.
+	Key string
+	// Conversation reports whether Key came from a
+	// conversation ID rather than a trace ID.
+	Conversation bool
+	// TraceIDs lists the traces the spans belong to.
+	TraceIDs []string
+	// Request is a single-line OTLP/JSON export request holding
+	// the spans, with their resources and scopes.
+	Request []byte
+}
+
+// SplitOTelTraces groups the GenAI spans of an OTLP/JSON export
+// into sessions. All spans of a trace belong to the session of
+// the first conversation ID found on any of them, so that agents
+// tracing every turn as its own trace form one session. Spans
+// without gen_ai.* attributes are dropped.
+func SplitOTelTraces(data []byte) ([]OTelBatch, error) {
+	type group struct {
+		resource, scope string
+		spans           []string
+	}
+	type traceSpans struct {
+		conversation string
+		groups       []*group
+	}
+	traces := make(map[string]*traceSpans)
+	var order []string
+
+	err := forEachOTLPRequest(data, func(req gjson.Result) {
+		req.Get("resourceSpans").ForEach(func(_, rs gjson.Result) bool {
+			resource := rs.Get("resource")
+			rs.Get("scopeSpans").ForEach(func(_, ss gjson.Result) bool {
+				scope := ss.Get("scope")
+				byTrace := make(map[string]*group)
+				ss.Get("spans").ForEach(func(_, span gjson.Result) bool {
+					attrs := newOTelAttrs(span.Get("attributes"))
+					if !attrs.hasGenAI() && !otelHasGenAIEvent(span) {
+						return true
+					}
+					traceID := span.Get("traceId").Str
+					t, ok := traces[traceID]
+					if !ok {
+						t = &traceSpans{}
+						traces[traceID] = t
+						order = append(order, traceID)
+					}
+					if t.conversation == "" {
+						t.conversation = attrs.first(otelConversationKeys...)
+					}
+					g, ok := byTrace[traceID]
+					if !ok {
+						g = &group{resource: resource.Raw, scope: scope.Raw}
+						byTrace[traceID] = g
+						t.groups = append(t.groups, g)
+					}
+					g.spans = append(g.spans, span.Raw)
+					return true
+				})
+				return true
+			})
+			return true
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var batches []OTelBatch
+	byKey := make(map[string]int)
+	groups := make(map[string][]*group)
+	for _, traceID := range order {
+		t := traces[traceID]
+		key, conv := traceID, false
+		if t.conversation != "" {
+			key, conv = t.conversation, true
+		}
+		key = otelSafeKey(key)
+		i, ok := byKey[key]
+		if !ok {
+			i = len(batches)
+			byKey[key] = i
+			batches = append(batches, OTelBatch{
+				Key: key, Conversation: conv,
+			})
+		}
+		batches[i].TraceIDs = append(batches[i].TraceIDs, traceID)
+		groups[key] = append(groups[key], t.groups...)
+	}
+
+	for i := range batches {
+		var buf bytes.Buffer
+		buf.WriteString(`{"resourceSpans":[`)
+		for j, g := range groups[batches[i].Key] {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`{"resource":`)
+			buf.WriteString(otelRawOrEmpty(g.resource))
+			buf.WriteString(`,"scopeSpans":[{"scope":`)
+			buf.WriteString(otelRawOrEmpty(g.scope))
+			buf.WriteString(`,"spans":[`)
+			buf.WriteString(strings.Join(g.spans, ","))
+			buf.WriteString(`]}]}`)
+		}
+		buf.WriteString(`]}`)
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, buf.Bytes()); err != nil {
+			return nil, fmt.Errorf("encoding spans: %w", err)
+		}
+		batches[i].Request = compact.Bytes()
+	}
+	return batches, nil
+}
+
+// otelHasGenAIEvent reports whether a span has an event of the
+// GenAI conventions.
+func otelHasGenAIEvent(span gjson.Result) bool {
+	found := false
+	span.Get("events").ForEach(func(_, ev gjson.Result) bool {
+		found = strings.HasPrefix(ev.Get("name").Str, "gen_ai.")
+		return !found
+	})
+	return found
+}
+
+func otelRawOrEmpty(raw string) string {
+	if raw == "" {
+		return "{}"
+	}
+	return raw
+}
+
+// otelSafeKey returns key if it is usable as a file name and
+// session ID, or a hash of it otherwise.
+func otelSafeKey(key string) string {
+	safe := key != "" && len(key) <= 128
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			c >= '0' && c <= '9' || c == '-' || c == '_') {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "h" + hex.EncodeToString(sum[:16])
+}
+
+// otelMessage is a prompt or completion message in any of the
+// encodings the GenAI conventions have used.
+type otelMessage struct {
+	role       string
+	text       string
+	thinking   string
+	calls      []otelToolCall
+	toolCallID string
+}
+
+type otelToolCall struct {
+	id   string
+	name string
+	args string
+}
+
+// otelMessages returns the prompt (base "gen_ai.prompt") or
+// completion (base "gen_ai.completion") messages of a span.
+// Current instrumentations write a JSON list of messages with
+// typed parts to gen_ai.input.messages/gen_ai.output.messages;
+// older ones a JSON list under the base key, or one attribute
+// per field under <base>.<i>.<field>.
+func otelMessages(a otelAttrs, base, current, role string) []otelMessage {
+	if raw := a.str(current); raw != "" {
+		return otelJSONMessages(raw, role)
+	}
+	if raw := a.str(base); raw != "" {
+		if gjson.Valid(raw) && gjson.Parse(raw).IsArray() {
+			return otelJSONMessages(raw, role)
+		}
+		return []otelMessage{{role: role, text: raw}}
+	}
+	return otelIndexedMessages(a, base, role)
+}
+
+// otelJSONMessages parses a JSON list of messages. Entries may
+// be semantic-convention messages with parts, OpenAI-style chat
+// messages, or choices wrapping a message.
+func otelJSONMessages(raw, role string) []otelMessage {
+	var msgs []otelMessage
+	gjson.Parse(raw).ForEach(func(_, el gjson.Result) bool {
+		if m := el.Get("message"); m.IsObject() {
+			el = m
+		}
+		m := otelMessage{
+			role:       el.Get("role").Str,
+			toolCallID: el.Get("tool_call_id").Str,
+		}
+		if m.role == "" {
+			m.role = role
+		}
+		var texts []string
+		if parts := el.Get("parts"); parts.IsArray() {
+			parts.ForEach(func(_, p gjson.Result) bool {
+				switch p.Get("type").Str {
+				case "text":
+					texts = append(texts, p.Get("content").Str)
+				case "reasoning":
+					m.thinking = p.Get("content").Str
+				case "tool_call":
+					m.calls = append(m.calls, otelToolCall{
+						id:   p.Get("id").Str,
+						name: p.Get("name").Str,
+						args: otelArgs(p.Get("arguments")),
+					})
+				case "tool_call_response":
+					m.toolCallID = p.Get("id").Str
+					texts = append(texts, otelResultText(p.Get("response")))
+				}
+				return true
+			})
+		} else {
+			texts = append(texts, toolResultContentText(el.Get("content")))
+		}
+		el.Get("tool_calls").ForEach(func(_, tc gjson.Result) bool {
+			fn := tc.Get("function")
+			if !fn.Exists() {
+				fn = tc
+			}
+			m.calls = append(m.calls, otelToolCall{
+				id:   tc.Get("id").Str,
+				name: fn.Get("name").Str,
+				args: otelArgs(fn.Get("arguments")),
+			})
+			return true
+		})
+		m.text = strings.TrimSpace(strings.Join(texts, "\n"))
+		msgs = append(msgs, m)
+		return true
+	})
+	return msgs
+}
+
+// otelIndexedMessages reads messages flattened into one
+// attribute per field, such as gen_ai.prompt.0.role.
+func otelIndexedMessages(a otelAttrs, base, role string) []otelMessage {
+	var msgs []otelMessage
+	for i := 0; ; i++ {
+		prefix := fmt.Sprintf("%s.%d.", base, i)
+		m := otelMessage{
+			role:       a.str(prefix + "role"),
+			text:       strings.TrimSpace(a.str(prefix + "content")),
+			toolCallID: a.str(prefix + "tool_call_id"),
+		}
+		for j := 0; ; j++ {
+			tc := fmt.Sprintf("%stool_calls.%d.", prefix, j)
+			name := a.str(tc + "name")
+			if name == "" {
+				break
+			}
+			m.calls = append(m.calls, otelToolCall{
+				id:   a.str(tc + "id"),
+				name: name,
+				args: otelArgText(a.str(tc + "arguments")),
+			})
+		}
+		if m.role == "" && m.text == "" && len(m.calls) == 0 {
+			return msgs
+		}
+		if m.role == "" {
+			m.role = role
+		}
+		msgs = append(msgs, m)
+	}
+}
+
+// otelArgs returns tool arguments as JSON. Arguments are often
+// a JSON object serialized into a string.
+func otelArgs(v gjson.Result) string {
+	switch {
+	case v.Type == gjson.String:
+		return otelArgText(v.Str)
+	case v.Exists() && v.Type != gjson.Null:
+		return v.Raw
+	}
+	return "{}"
+}
+
+// otelArgText returns arguments recorded as text as JSON,
+// quoting text that is not JSON itself.
+func otelArgText(s string) string {
+	switch {
+	case s == "":
+		return "{}"
+	case gjson.Valid(s):
+		return s
+	}
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// otelResultText returns a tool result as text.
+func otelResultText(v gjson.Result) string {
+	if v.Type == gjson.String {
+		return v.Str
+	}
+	return v.Raw
+}
+
+// otelToolDetail returns the part of a tool's input shown next
+// to its category.
+func otelToolDetail(in gjson.Result) string {
+	for _, key := range []string{
+		"file_path", "path", "command", "cmd", "pattern",
+		"query", "url",
+	} {
+		if v := in.Get(key).Str; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// otelSessionBuilder rebuilds a conversation from the GenAI
+// spans of a session, visited in tree order.
+type otelSessionBuilder struct {
+	messages     []ParsedMessage
+	firstMessage string
+	userCount    int
+	ordinal      int
+	// history is the number of messages the next prompt
+	// repeats when the agent resends the whole conversation:
+	// the previous prompt plus its completion.
+	history int
+	// called and answered record tool call IDs with a call and
+	// a result, so results reported both by tool spans and in
+	// later prompts are kept once.
+	called   map[string]bool
+	answered map[string]bool
+	tokens   map[string]ModelTokenUsage
+	usage    ModelTokenUsage
+}
+
+// addChat handles a model request span: the messages its
+// prompt adds to the conversation, then its completion.
+func (b *otelSessionBuilder) addChat(s *otelSpan) {
+	prompt := otelMessages(
+		s.attrs, "gen_ai.prompt", "gen_ai.input.messages", "user",
+	)
+	start := 0
+	if b.history > 0 && b.history <= len(prompt) &&
+		prompt[b.history-1].role == "assistant" {
+		start = b.history
+	}
+	for _, m := range prompt[start:] {
+		switch m.role {
+		case "user":
+			b.addUser(m.text, s.start)
+		case "tool", "function":
+			b.addResult(m.toolCallID, m.text, false, s.start)
+		}
+	}
+
+	completion := otelMessages(
+		s.attrs, "gen_ai.completion", "gen_ai.output.messages",
+		"assistant",
+	)
+	b.history = len(prompt) + len(completion)
+
+	model := s.attrs.first(
+		"gen_ai.response.model", "gen_ai.request.model",
+	)
+	u := ModelTokenUsage{
+		InputTokens: s.attrs.int(
+			"gen_ai.usage.input_tokens", "gen_ai.usage.prompt_tokens",
+		),
+		OutputTokens: s.attrs.int(
+			"gen_ai.usage.output_tokens",
+			"gen_ai.usage.completion_tokens",
+		),
+		CacheCreationInputTokens: s.attrs.int(
+			"gen_ai.usage.cache_creation.input_tokens",
+			"gen_ai.usage.cache_creation_input_tokens",
+		),
+		CacheReadInputTokens: s.attrs.int(
+			"gen_ai.usage.cache_read.input_tokens",
+			"gen_ai.usage.cache_read_input_tokens",
+		),
+	}
+	b.addUsage(model, u)
+
+	var (
+		parts       []string
+		calls       []ParsedToolCall
+		hasThinking bool
+	)
+	for _, m := range completion {
+		if m.thinking != "" {
+			hasThinking = true
+			parts = append(parts, "[Thinking]\n"+m.thinking)
+		}
+		if m.text != "" {
+			parts = append(parts, m.text)
+		}
+		for _, tc := range m.calls {
+			if tc.name == "" {
+				continue
+			}
+			call := ParsedToolCall{
+				ToolUseID: tc.id,
+				ToolName:  tc.name,
+				Category:  NormalizeToolCategory(tc.name),
+				InputJSON: tc.args,
+			}
+			calls = append(calls, call)
+			parts = append(parts, formatToolHeader(
+				call.Category, otelToolDetail(gjson.Parse(tc.args)),
+			))
+		}
+	}
+	text := strings.Join(parts, "\n\n")
+	if text == "" {
+		return
+	}
+	for _, c := range calls {
+		if c.ToolUseID != "" {
+			b.called[c.ToolUseID] = true
+		}
+	}
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:                  b.ordinal,
+		Role:                     RoleAssistant,
+		Content:                  text,
+		Timestamp:                s.end,
+		HasThinking:              hasThinking,
+		HasToolUse:               len(calls) > 0,
+		ContentLength:            len(text),
+		ToolCalls:                calls,
+		Model:                    model,
+		InputTokens:              u.InputTokens,
+		OutputTokens:             u.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens,
+	})
+	b.ordinal++
+}
+
+// addTool handles a tool execution span. Tools whose call was
+// not recorded by a model request span, as when message content
+// capture is off, get a call of their own.
+func (b *otelSessionBuilder) addTool(s *otelSpan) {
+	name := s.attrs.str("gen_ai.tool.name")
+	if name == "" {
+		name = strings.TrimSpace(
+			strings.TrimPrefix(s.name, "execute_tool"),
+		)
+	}
+	id := s.attrs.str("gen_ai.tool.call.id")
+	if id == "" {
+		id = s.spanID
+	}
+
+	if !b.called[id] {
+		args := otelArgText(s.attrs.str("gen_ai.tool.call.arguments"))
+		call := ParsedToolCall{
+			ToolUseID: id,
+			ToolName:  name,
+			Category:  NormalizeToolCategory(name),
+			InputJSON: args,
+		}
+		text := formatToolHeader(
+			call.Category, otelToolDetail(gjson.Parse(args)),
+		)
+		b.called[id] = true
+		b.messages = append(b.messages, ParsedMessage{
+			Ordinal:       b.ordinal,
+			Role:          RoleAssistant,
+			Content:       text,
+			Timestamp:     s.start,
+			HasToolUse:    true,
+			ContentLength: len(text),
+			ToolCalls:     []ParsedToolCall{call},
+		})
+		b.ordinal++
+	}
+
+	result := s.attrs.str("gen_ai.tool.call.result")
+	failed := s.failed || s.attrs.str("error.type") != ""
+	if result == "" && failed {
+		result = s.status
+	}
+	b.addResult(id, result, failed, s.end)
+}
+
+func (b *otelSessionBuilder) addUser(text string, ts time.Time) {
+	if text == "" {
+		return
+	}
+	if b.firstMessage == "" {
+		b.firstMessage = truncate(strings.ReplaceAll(text, "\n", " "), 300)
+	}
+	b.userCount++
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:       b.ordinal,
+		Role:          RoleUser,
+		Content:       text,
+		Timestamp:     ts,
+		ContentLength: len(text),
+	})
+	b.ordinal++
+}
+
+// addResult adds a message carrying a tool result, unless the
+// call already has one.
+func (b *otelSessionBuilder) addResult(
+	id, content string, failed bool, ts time.Time,
+) {
+	if id != "" {
+		if b.answered[id] {
+			return
+		}
+		b.answered[id] = true
+	}
+	r := ParsedToolResult{
+		ToolUseID:     id,
+		Content:       content,
+		ContentLength: len(content),
+		IsError:       failed,
+		ExitCode:      exitCodeFromOutput(content),
+	}
+	b.messages = append(b.messages, ParsedMessage{
+		Ordinal:       b.ordinal,
+		Role:          RoleUser,
+		Timestamp:     ts,
+		ContentLength: r.ContentLength,
+		ToolResults:   []ParsedToolResult{r},
+	})
+	b.ordinal++
+}
+
+// addUsage records the token counts of a model request. Counts
+// are kept per span rather than per message, so requests traced
+// without their content still count.
+func (b *otelSessionBuilder) addUsage(model string, u ModelTokenUsage) {
+	b.usage.InputTokens += u.InputTokens
+	b.usage.OutputTokens += u.OutputTokens
+	b.usage.CacheCreationInputTokens += u.CacheCreationInputTokens
+	b.usage.CacheReadInputTokens += u.CacheReadInputTokens
+	if model == "" {
+		return
+	}
+	t := b.tokens[model]
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+	t.CacheCreationInputTokens += u.CacheCreationInputTokens
+	t.CacheReadInputTokens += u.CacheReadInputTokens
+	b.tokens[model] = t
+}
+
+// otelSpanKind classifies a span as a model request ("chat"),
+// a tool execution ("tool"), or neither ("").
+func otelSpanKind(s *otelSpan) string {
+	switch op := s.attrs.str("gen_ai.operation.name"); op {
+	case "execute_tool":
+		return "tool"
+	case "chat", "text_completion", "generate_content":
+		return "chat"
+	case "":
+		if s.attrs.str("gen_ai.tool.name") != "" {
+			return "tool"
+		}
+		for _, key := range []string{
+			"gen_ai.request.model", "gen_ai.input.messages",
+			"gen_ai.prompt", "gen_ai.prompt.0.role",
+			"gen_ai.completion", "gen_ai.completion.0.role",
+		} {
+			if _, ok := s.attrs[key]; ok {
+				return "chat"
+			}
+		}
+	}
+	return ""
+}
+
+// otelTreeOrder returns spans in depth-first order of the span
+// trees, with siblings ordered by start time. Spans whose
+// parent is missing are treated as roots. Ordering by tree
+// rather than by time alone keeps a tool's work together when
+// the clocks of the processes in a trace disagree.
+func otelTreeOrder(spans []*otelSpan) []*otelSpan {
+	byID := make(map[string]bool, len(spans))
+	for _, s := range spans {
+		byID[s.traceID+"/"+s.spanID] = true
+	}
+	children := make(map[string][]*otelSpan)
+	var roots []*otelSpan
+	for _, s := range spans {
+		parent := s.traceID + "/" + s.parentID
+		if s.parentID != "" && byID[parent] {
+			children[parent] = append(children[parent], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+	byStart := func(list []*otelSpan) {
+		sort.SliceStable(list, func(i, j int) bool {
+			if !list[i].start.Equal(list[j].start) {
+				return list[i].start.Before(list[j].start)
+			}
+			return list[i].end.Before(list[j].end)
+		})
+	}
+
+	ordered := make([]*otelSpan, 0, len(spans))
+	var visit func(list []*otelSpan)
+	visit = func(list []*otelSpan) {
+		byStart(list)
+		for _, s := range list {
+			ordered = append(ordered, s)
+			visit(children[s.traceID+"/"+s.spanID])
+		}
+	}
+	visit(roots)
+	return ordered
+}
+
+// ParseOTelSession parses a file of OTLP/JSON export requests,
+// one per line, holding the GenAI spans of one session, as
+// written by the sync engine's OTel import. The session is
+// named after the file. The project is the service.name of the
+// emitting resource, and the machine its host.name when set.
+// Returns (nil, nil, nil) if the file doesn't exist or yields
+// no messages.
+func ParseOTelSession(
+	path, machine string,
+) (*ParsedSession, []ParsedMessage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	// Spans sent again by a retrying exporter replace the
+	// earlier copy.
+	seen := make(map[string]int)
+	var spans []*otelSpan
+	err = forEachOTLPRequest(data, func(req gjson.Result) {
+		req.Get("resourceSpans").ForEach(func(_, rs gjson.Result) bool {
+			resource := newOTelAttrs(rs.Get("resource.attributes"))
+			rs.Get("scopeSpans").ForEach(func(_, ss gjson.Result) bool {
+				ss.Get("spans").ForEach(func(_, span gjson.Result) bool {
+					s := newOTelSpan(resource, span)
+					key := s.traceID + "/" + s.spanID
+					if i, ok := seen[key]; ok && s.spanID != "" {
+						spans[i] = s
+						return true
+					}
+					seen[key] = len(spans)
+					spans = append(spans, s)
+					return true
+				})
+				return true
+			})
+			return true
+		})
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing otel %s: %w", path, err)
+	}
+
+	b := otelSessionBuilder{
+		called:   make(map[string]bool),
+		answered: make(map[string]bool),
+		tokens:   make(map[string]ModelTokenUsage),
+	}
+	var (
+		startedAt, endedAt time.Time
+		project, host      string
+	)
+	for _, s := range otelTreeOrder(spans) {
+		switch otelSpanKind(s) {
+		case "chat":
+			b.addChat(s)
+		case "tool":
+			b.addTool(s)
+		default:
+			continue
+		}
+		if startedAt.IsZero() || s.start.Before(startedAt) {
+			startedAt = s.start
+		}
+		if s.end.After(endedAt) {
+			endedAt = s.end
+		}
+		if project == "" {
+			project = s.resource.str("service.name")
+			if strings.HasPrefix(project, "unknown_service") {
+				project = ""
+			}
+		}
+		if host == "" {
+			host = s.resource.str("host.name")
+		}
+	}
+	if len(b.messages) == 0 {
+		return nil, nil, nil
+	}
+
+	if project == "" {
+		project = "unknown"
+	}
+	if host != "" {
+		machine = host
+	}
+
+	sess := &ParsedSession{
+		ID:                       "otel:" + OTelSessionID(path),
+		Project:                  project,
+		Machine:                  machine,
+		Agent:                    AgentOTel,
+		FirstMessage:             b.firstMessage,
+		StartedAt:                startedAt,
+		EndedAt:                  endedAt,
+		MessageCount:             len(b.messages),
+		UserMessageCount:         b.userCount,
+		InputTokens:              b.usage.InputTokens,
+		OutputTokens:             b.usage.OutputTokens,
+		CacheCreationInputTokens: b.usage.CacheCreationInputTokens,
+		CacheReadInputTokens:     b.usage.CacheReadInputTokens,
+		File: FileInfo{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: info.ModTime().UnixNano(),
+		},
+	}
+	if len(b.tokens) > 0 {
+		sess.TokensByModel = b.tokens
+	}
+	return sess, b.messages, nil
+}
+
+// OTelSessionID returns the session ID of an OTel session
+// file, which is its name without the extension.
+func OTelSessionID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".jsonl")
+}
@@ -0,0 +1,279 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+// otelStart is the start time, in Unix nanoseconds, of the OTel
+// test traces.
+const otelStart int64 = 1718000000 * int64(time.Second)
+
+// otelAt returns the time sec seconds into the OTel test traces.
+func otelAt(sec int64) int64 {
+	return otelStart + sec*int64(time.Second)
+}
+
+func TestParseOTelSessionIndexedAttributes(t *testing.T) {
+	root := testjsonl.OTelSpan{
+		TraceID: "t1", SpanID: "root", Name: "invoke_agent support",
+		Start: otelAt(0), End: otelAt(10),
+		Attrs: map[string]any{
+			"gen_ai.operation.name": "invoke_agent",
+			"gen_ai.agent.name":     "support",
+		},
+	}
+	chat1 := testjsonl.OTelSpan{
+		TraceID: "t1", SpanID: "c1", ParentID: "root", Name: "chat gpt-4o",
+		Start: otelAt(1), End: otelAt(3),
+		Attrs: map[string]any{
+			"gen_ai.operation.name":                      "chat",
+			"gen_ai.request.model":                       "gpt-4o",
+			"gen_ai.response.model":                      "gpt-4o-2024-08-06",
+			"gen_ai.prompt.0.role":                       "system",
+			"gen_ai.prompt.0.content":                    "You are helpful.",
+			"gen_ai.prompt.1.role":                       "user",
+			"gen_ai.prompt.1.content":                    "Fix the failing test",
+			"gen_ai.completion.0.role":                   "assistant",
+			"gen_ai.completion.0.tool_calls.0.id":        "call_1",
+			"gen_ai.completion.0.tool_calls.0.name":      "bash",
+			"gen_ai.completion.0.tool_calls.0.arguments": `{"command":"pytest"}`,
+			"gen_ai.usage.input_tokens":                  100,
+			"gen_ai.usage.output_tokens":                 20,
+			"gen_ai.usage.cache_read_input_tokens":       50,
+		},
+	}
+	tool := testjsonl.OTelSpan{
+		TraceID: "t1", SpanID: "tool1", ParentID: "root",
+		Name:  "execute_tool bash",
+		Start: otelAt(3), End: otelAt(4), Error: true,
+		Attrs: map[string]any{
+			"gen_ai.operation.name":   "execute_tool",
+			"gen_ai.tool.name":        "bash",
+			"gen_ai.tool.call.id":     "call_1",
+			"gen_ai.tool.call.result": "1 failed",
+		},
+	}
+	chat2 := testjsonl.OTelSpan{
+		TraceID: "t1", SpanID: "c2", ParentID: "root", Name: "chat gpt-4o",
+		Start: otelAt(5), End: otelAt(6),
+		Attrs: map[string]any{
+			"gen_ai.operation.name":             "chat",
+			"gen_ai.response.model":             "gpt-4o-2024-08-06",
+			"gen_ai.prompt.0.role":              "system",
+			"gen_ai.prompt.0.content":           "You are helpful.",
+			"gen_ai.prompt.1.role":              "user",
+			"gen_ai.prompt.1.content":           "Fix the failing test",
+			"gen_ai.prompt.2.role":              "assistant",
+			"gen_ai.prompt.2.tool_calls.0.id":   "call_1",
+			"gen_ai.prompt.2.tool_calls.0.name": "bash",
+			"gen_ai.prompt.3.role":              "tool",
+			"gen_ai.prompt.3.tool_call_id":      "call_1",
+			"gen_ai.prompt.3.content":           "1 failed",
+			"gen_ai.completion.0.role":          "assistant",
+			"gen_ai.completion.0.content":       "The test fails.",
+			"gen_ai.usage.input_tokens":         150,
+			"gen_ai.usage.output_tokens":        10,
+		},
+	}
+	http := testjsonl.OTelSpan{
+		TraceID: "t1", SpanID: "h1", ParentID: "c2", Name: "POST",
+		Start: otelAt(5), End: otelAt(6),
+		Attrs: map[string]any{"http.request.method": "POST"},
+	}
+
+	// The tool span arrives first and the first request is
+	// exported twice, as by a retrying exporter.
+	content := strings.Join([]string{
+		testjsonl.OTLPRequestJSON("support-bot", "build-7", tool, chat1),
+		testjsonl.OTLPRequestJSON("support-bot", "build-7", root, chat1, chat2, http),
+	}, "\n")
+	path := createTestFile(t, "t1.jsonl", content)
+
+	sess, msgs, err := ParseOTelSession(path, "local")
+	require.NoError(t, err)
+	require.NotNil(t, sess)
+
+	assertSessionMeta(t, sess, "otel:t1", "support-bot", AgentOTel)
+	assert.Equal(t, "build-7", sess.Machine)
+	assert.Equal(t, "Fix the failing test", sess.FirstMessage)
+	assert.Equal(t, 1, sess.UserMessageCount)
+	assertTimestamp(t, sess.StartedAt, time.Unix(0, otelAt(1)))
+	assertTimestamp(t, sess.EndedAt, time.Unix(0, otelAt(6)))
+	assert.Equal(t, int64(250), sess.InputTokens)
+	assert.Equal(t, int64(30), sess.OutputTokens)
+	assert.Equal(t, int64(50), sess.CacheReadInputTokens)
+	assert.Equal(t, ModelTokenUsage{
+		InputTokens: 250, OutputTokens: 30, CacheReadInputTokens: 50,
+	}, sess.TokensByModel["gpt-4o-2024-08-06"])
+
+	assertMessageCount(t, len(msgs), 4)
+	assertMessage(t, msgs[0], RoleUser, "Fix the failing test")
+	assertTimestamp(t, msgs[0].Timestamp, time.Unix(0, otelAt(1)))
+
+	assertMessage(t, msgs[1], RoleAssistant, "[Bash: pytest]")
+	assert.Equal(t, "gpt-4o-2024-08-06", msgs[1].Model)
+	assert.Equal(t, int64(100), msgs[1].InputTokens)
+	assertToolCalls(t, msgs[1].ToolCalls, []ParsedToolCall{{
+		ToolUseID: "call_1", ToolName: "bash", Category: "Bash",
+	}})
+
+	// The tool span's result is kept; the copy in the next
+	// prompt is dropped.
+	require.Len(t, msgs[2].ToolResults, 1)
+	assert.Equal(t, "1 failed", msgs[2].ToolResults[0].Content)
+	assert.True(t, msgs[2].ToolResults[0].IsError)
+	assertTimestamp(t, msgs[2].Timestamp, time.Unix(0, otelAt(4)))
+
+	assertMessage(t, msgs[3], RoleAssistant, "The test fails.")
+}
+
+func TestParseOTelSessionMessageParts(t *testing.T) {
+	chat1 := testjsonl.OTelSpan{
+		TraceID: "t2", SpanID: "c1", Name: "chat claude",
+		Start: otelAt(0), End: otelAt(2),
+		Attrs: map[string]any{
+			"gen_ai.operation.name": "chat",
+			"gen_ai.request.model":  "claude-sonnet-4",
+			"gen_ai.input.messages": `[{"role":"user","parts":[
+				{"type":"text","content":"Summarise README.md"}]}]`,
+			"gen_ai.output.messages": `[{"role":"assistant","parts":[
+				{"type":"reasoning","content":"Need the file."},
+				{"type":"tool_call","id":"r1","name":"read_file",
+				 "arguments":{"path":"README.md"}}],
+				"finish_reason":"tool_call"}]`,
+			"gen_ai.usage.input_tokens":  40,
+			"gen_ai.usage.output_tokens": 8,
+		},
+	}
+	// The agent sends only the new messages with each request.
+	chat2 := testjsonl.OTelSpan{
+		TraceID: "t2", SpanID: "c2", Name: "chat claude",
+		Start: otelAt(3), End: otelAt(4),
+		Attrs: map[string]any{
+			"gen_ai.operation.name": "chat",
+			"gen_ai.request.model":  "claude-sonnet-4",
+			"gen_ai.input.messages": `[{"role":"tool","parts":[
+				{"type":"tool_call_response","id":"r1","response":"# Demo"}]}]`,
+			"gen_ai.output.messages": `[{"role":"assistant","parts":[
+				{"type":"text","content":"It is a demo."}]}]`,
+		},
+	}
+	// A tool traced without a model request recording its call.
+	search := testjsonl.OTelSpan{
+		TraceID: "t2", SpanID: "s1", Name: "execute_tool web_search",
+		Start: otelAt(5), End: otelAt(6),
+		Attrs: map[string]any{
+			"gen_ai.tool.name":           "web_search",
+			"gen_ai.tool.call.arguments": `{"query":"demo"}`,
+		},
+	}
+	content := testjsonl.OTLPRequestJSON(
+		"unknown_service:python", "", chat1, chat2, search,
+	)
+	path := createTestFile(t, "t2.jsonl", content)
+
+	sess, msgs, err := ParseOTelSession(path, "laptop")
+	require.NoError(t, err)
+	require.NotNil(t, sess)
+
+	assertSessionMeta(t, sess, "otel:t2", "unknown", AgentOTel)
+	assert.Equal(t, "laptop", sess.Machine)
+	assert.Equal(t, int64(40), sess.TokensByModel["claude-sonnet-4"].InputTokens)
+
+	assertMessageCount(t, len(msgs), 6)
+	assertMessage(t, msgs[0], RoleUser, "Summarise README.md")
+
+	read := msgs[1]
+	assert.Equal(t,
+		"[Thinking]\nNeed the file.\n\n[Read: README.md]", read.Content)
+	assert.True(t, read.HasThinking)
+	require.Len(t, read.ToolCalls, 1)
+	assert.Equal(t, "README.md",
+		gjson.Get(read.ToolCalls[0].InputJSON, "path").Str)
+
+	require.Len(t, msgs[2].ToolResults, 1)
+	assert.Equal(t, "r1", msgs[2].ToolResults[0].ToolUseID)
+	assert.Equal(t, "# Demo", msgs[2].ToolResults[0].Content)
+	assertMessage(t, msgs[3], RoleAssistant, "It is a demo.")
+
+	assertToolCalls(t, msgs[4].ToolCalls, []ParsedToolCall{{
+		ToolUseID: "s1", ToolName: "web_search",
+		Category: NormalizeToolCategory("web_search"),
+	}})
+	require.Len(t, msgs[5].ToolResults, 1)
+	assert.Equal(t, "s1", msgs[5].ToolResults[0].ToolUseID)
+}
+
+func TestParseOTelSessionWithoutMessages(t *testing.T) {
+	content := testjsonl.OTLPRequestJSON("svc", "", testjsonl.OTelSpan{
+		TraceID: "t3", SpanID: "a", Name: "invoke_agent",
+		Start: otelAt(0), End: otelAt(1),
+		Attrs: map[string]any{"gen_ai.operation.name": "invoke_agent"},
+	})
+	path := createTestFile(t, "t3.jsonl", content)
+
+	sess, msgs, err := ParseOTelSession(path, "m")
+	require.NoError(t, err)
+	assert.Nil(t, sess)
+	assert.Nil(t, msgs)
+
+	path = createTestFile(t, "bad.jsonl", "{\n")
+	_, _, err = ParseOTelSession(path, "m")
+	assert.Error(t, err)
+}
+
+func TestSplitOTelTraces(t *testing.T) {
+	chat := func(trace, span, conv string) testjsonl.OTelSpan {
+		attrs := map[string]any{
+			"gen_ai.operation.name": "chat",
+			"gen_ai.request.model":  "m",
+		}
+		if conv != "" {
+			attrs["gen_ai.conversation.id"] = conv
+		}
+		return testjsonl.OTelSpan{
+			TraceID: trace, SpanID: span, Name: "chat",
+			Start: otelAt(0), End: otelAt(1), Attrs: attrs,
+		}
+	}
+	http := testjsonl.OTelSpan{
+		TraceID: "c", SpanID: "h", Name: "GET",
+		Attrs: map[string]any{"http.request.method": "GET"},
+	}
+	data := strings.Join([]string{
+		testjsonl.OTLPRequestJSON("svc", "",
+			chat("a", "a1", "conv/1"), chat("a", "a2", ""),
+			chat("b", "b1", ""), http),
+		testjsonl.OTLPRequestJSON("svc", "", chat("d", "d1", "conv/1")),
+	}, "\n")
+
+	batches, err := SplitOTelTraces([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+
+	conv := batches[0]
+	assert.True(t, conv.Conversation)
+	assert.True(t, strings.HasPrefix(conv.Key, "h"))
+	assert.Equal(t, conv.Key, otelSafeKey("conv/1"))
+	assert.Equal(t, []string{"a", "d"}, conv.TraceIDs)
+	assert.NotContains(t, string(conv.Request), "\n")
+	req := gjson.ParseBytes(conv.Request)
+	assert.Equal(t, `[["a1","a2"],["d1"]]`,
+		req.Get("resourceSpans.#.scopeSpans.0.spans.#.spanId").Raw)
+	assert.Equal(t, "svc",
+		req.Get("resourceSpans.0.resource.attributes.0.value.stringValue").Str)
+
+	assert.Equal(t, OTelBatch{
+		Key: "b", TraceIDs: []string{"b"}, Request: batches[1].Request,
+	}, batches[1])
+
+	_, err = SplitOTelTraces([]byte("{\nnope"))
+	assert.Error(t, err)
+}
@@ -240,6 +240,16 @@ func TestExtractToolResults(t *testing.T) {
 				{ToolUseID: "toolu_2", ContentLength: 5, Content: "defgh"},
 			},
 		},
+		{
+			"failed command",
+			`[{"type":"tool_result","tool_use_id":"toolu_7","is_error":true,"content":"Exit code 2\nno such file"}]`,
+			[]ParsedToolResult{{ToolUseID: "toolu_7", ContentLength: 24, Content: "Exit code 2\nno such file", IsError: true, ExitCode: intPtr(2)}},
+		},
+		{
+			"failed tool without exit code",
+			`[{"type":"tool_result","tool_use_id":"toolu_8","is_error":true,"content":"File does not exist."}]`,
+			[]ParsedToolResult{{ToolUseID: "toolu_8", ContentLength: 20, Content: "File does not exist.", IsError: true}},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -262,6 +272,11 @@ func TestExtractToolResults(t *testing.T) {
 					t.Errorf("[%d].Content = %q, want %q",
 						i, trs[i].Content, tt.wantResults[i].Content)
 				}
+				if trs[i].IsError != tt.wantResults[i].IsError {
+					t.Errorf("[%d].IsError = %v, want %v",
+						i, trs[i].IsError, tt.wantResults[i].IsError)
+				}
+				assertExitCode(t, i, trs[i].ExitCode, tt.wantResults[i].ExitCode)
 			}
 		})
 	}
@@ -1139,3 +1154,76 @@ func TestParseClaudeToolResults(t *testing.T) {
 			userMsg.ToolResults[0].Content, wantContent)
 	}
 }
+
+func TestParseSessionWorkspace(t *testing.T) {
+	t.Run("claude keeps first cwd and last branch", func(t *testing.T) {
+		content := `{"type":"user","timestamp":"2024-01-01T00:00:00Z","cwd":"/src/app","gitBranch":"main","message":{"content":"hi"}}` + "\n" +
+			`{"type":"assistant","timestamp":"2024-01-01T00:00:01Z","cwd":"/src/app","gitBranch":"main","message":{"content":[{"type":"text","text":"ok"}]}}` + "\n" +
+			`{"type":"user","timestamp":"2024-01-01T00:00:02Z","cwd":"/src/app/sub","gitBranch":"feature","message":{"content":"now on a branch"}}` + "\n" +
+			`{"type":"user","timestamp":"2024-01-01T00:00:03Z","cwd":"/src/app","message":{"content":"thanks"}}` + "\n"
+		path := createTestFile(t, "workspace.jsonl", content)
+
+		results, err := ParseClaudeSession(path, "app", "local")
+		if err != nil {
+			t.Fatalf("ParseClaudeSession: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("results = %d, want 1", len(results))
+		}
+		sess := results[0].Session
+		if sess.Cwd != "/src/app" {
+			t.Errorf("Cwd = %q, want %q", sess.Cwd, "/src/app")
+		}
+		if sess.GitBranch != "feature" {
+			t.Errorf("GitBranch = %q, want %q", sess.GitBranch, "feature")
+		}
+	})
+
+	t.Run("codex session_meta", func(t *testing.T) {
+		content := `{"type":"session_meta","timestamp":"2024-01-01T00:00:00Z","payload":{"id":"ws-uuid","cwd":"/src/app","originator":"user","git":{"branch":"fix-login"}}}` + "\n" +
+			`{"type":"response_item","timestamp":"2024-01-01T00:00:01Z","payload":{"role":"user","content":[{"type":"input_text","text":"hello"}]}}` + "\n"
+		path := createTestFile(t, "codex-workspace.jsonl", content)
+
+		sess, _, err := ParseCodexSession(path, "local", false)
+		if err != nil {
+			t.Fatalf("ParseCodexSession: %v", err)
+		}
+		if sess.Cwd != "/src/app" || sess.GitBranch != "fix-login" {
+			t.Errorf("workspace = (%q, %q), want (%q, %q)",
+				sess.Cwd, sess.GitBranch, "/src/app", "fix-login")
+		}
+	})
+
+	t.Run("no workspace", func(t *testing.T) {
+		content := `{"type":"user","timestamp":"2024-01-01T00:00:00Z","message":{"content":"hi"}}` + "\n"
+		path := createTestFile(t, "no-workspace.jsonl", content)
+
+		results, err := ParseClaudeSession(path, "app", "local")
+		if err != nil {
+			t.Fatalf("ParseClaudeSession: %v", err)
+		}
+		sess := results[0].Session
+		if sess.Cwd != "" || sess.GitBranch != "" || sess.RepoRoot != "" {
+			t.Errorf("workspace = (%q, %q, %q), want empty",
+				sess.Cwd, sess.GitBranch, sess.RepoRoot)
+		}
+	})
+}
+
+func TestSetWorkspaceRepoRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "pkg")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var sess ParsedSession
+	sess.SetWorkspace(sub, "main")
+	if sess.Cwd != sub || sess.GitBranch != "main" || sess.RepoRoot != root {
+		t.Errorf("workspace = (%q, %q, %q), want (%q, %q, %q)",
+			sess.Cwd, sess.GitBranch, sess.RepoRoot, sub, "main", root)
+	}
+}
@@ -106,6 +106,15 @@ func isInvalidPathBase(name string) bool {
 	return false
 }
 
+// FindGitRepoRoot returns the root of the git repository that
+// contains path, or "" if there is none. Path need not exist.
+func FindGitRepoRoot(path string) string {
+	if path == "" {
+		return ""
+	}
+	return findGitRepoRoot(filepath.Clean(path))
+}
+
 // findGitRepoRoot walks upward from cwd to find the enclosing git
 // repository root. Supports both standard repos (.git directory)
 // and linked worktrees/submodules (.git file).
@@ -0,0 +1,285 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ErrFullParseRequired is returned by ResumeSession when the
+// appended lines cannot be applied on top of the checkpoint,
+// e.g. because they fork the Claude conversation DAG. The
+// caller should parse the whole file again.
+var ErrFullParseRequired = errors.New("full reparse required")
+
+// Checkpoint records where a parse of an append-only JSONL
+// session file stopped, along with the parser state needed to
+// continue from there: the next ordinal, running totals and, for
+// Claude, the leaf of the conversation DAG. Checkpoints are
+// produced by ParseClaudeSessionCheckpoint and
+// ParseCodexSessionCheckpoint and consumed by ResumeSession.
+type Checkpoint struct {
+	// Offset is the byte offset just past the last line
+	// consumed. A trailing partial line is not consumed.
+	Offset int64
+
+	agent AgentType
+	// session holds the session as of Offset. Message counts
+	// are cumulative and unfiltered.
+	session ParsedSession
+
+	// Claude state.
+	nextOrdinal int
+	dag         bool   // entries carry uuids and form a DAG
+	leafUUID    string // last entry on the main path
+	lastMsgID   string
+	lastUsage   claudeUsage
+	subagents   map[string]string
+
+	// Codex state, with messages cleared.
+	codex *codexSessionBuilder
+}
+
+// ResumeSession parses the lines appended to path since cp was
+// taken. The returned result carries the updated session totals
+// and only the new messages; the returned checkpoint replaces
+// cp. The caller must ensure the first cp.Offset bytes of the
+// file are unchanged. Returns ErrFullParseRequired when the new
+// lines cannot be applied incrementally.
+func ResumeSession(
+	path string, cp *Checkpoint,
+) (ParseResult, *Checkpoint, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ParseResult{}, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	if info.Size() < cp.Offset {
+		return ParseResult{}, nil, ErrFullParseRequired
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ParseResult{}, nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(cp.Offset, io.SeekStart); err != nil {
+		return ParseResult{}, nil, fmt.Errorf("seek %s: %w", path, err)
+	}
+
+	lr := newLineReader(f, maxLineSize)
+	lr.pos = cp.Offset
+
+	file := FileInfo{
+		Path:  path,
+		Size:  info.Size(),
+		Mtime: info.ModTime().UnixNano(),
+	}
+
+	var res ParseResult
+	var next *Checkpoint
+	switch cp.agent {
+	case AgentClaude:
+		res, next, err = resumeClaude(lr, cp)
+	case AgentCodex:
+		res, next, err = resumeCodex(lr, cp)
+	default:
+		return ParseResult{}, nil, fmt.Errorf(
+			"cannot resume %s session", cp.agent,
+		)
+	}
+	if err != nil {
+		return ParseResult{}, nil, err
+	}
+	if err := lr.Err(); err != nil {
+		return ParseResult{}, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	res.Session.File = file
+	next.session.File = file
+	return res, next, nil
+}
+
+// resumeClaude continues a Claude parse. New entries must extend
+// the main path one after another; anything else (a new root, a
+// retry of an earlier turn, an entry without a uuid) could change
+// how the whole DAG is split and requires a full parse.
+func resumeClaude(
+	lr *lineReader, cp *Checkpoint,
+) (ParseResult, *Checkpoint, error) {
+	next := *cp
+	next.subagents = maps.Clone(cp.subagents)
+	if next.subagents == nil {
+		next.subagents = map[string]string{}
+	}
+	sess := cp.session
+
+	var (
+		entries  []dagEntry
+		enqueued []string
+	)
+	for {
+		line, ok := lr.next()
+		if !ok {
+			break
+		}
+		if !gjson.Valid(line) {
+			if lr.terminated {
+				next.Offset = lr.pos
+			}
+			continue
+		}
+		next.Offset = lr.pos
+
+		ts := extractTimestamp(line)
+		if !ts.IsZero() {
+			sess.StartedAt = earlierTime(sess.StartedAt, ts)
+			sess.EndedAt = laterTime(sess.EndedAt, ts)
+		}
+
+		entryType := gjson.Get(line, "type").Str
+		if entryType == "queue-operation" {
+			if tuid, sid, ok := parseSubagentEnqueue(line); ok {
+				next.subagents[tuid] = sid
+				enqueued = append(enqueued, tuid)
+			}
+			continue
+		}
+		if entryType != "user" && entryType != "assistant" {
+			continue
+		}
+		if entryType == "user" {
+			cwd, branch := claudeWorkspace(line, sess.Cwd, sess.GitBranch)
+			if cwd != sess.Cwd || branch != sess.GitBranch {
+				sess.SetWorkspace(cwd, branch)
+			}
+		}
+
+		uuid := gjson.Get(line, "uuid").Str
+		parentUuid := gjson.Get(line, "parentUuid").Str
+		if cp.dag {
+			if uuid == "" || parentUuid != next.leafUUID {
+				return ParseResult{}, nil, ErrFullParseRequired
+			}
+			next.leafUUID = uuid
+		}
+
+		entries = append(entries, dagEntry{
+			uuid:       uuid,
+			parentUuid: parentUuid,
+			entryType:  entryType,
+			line:       line,
+			timestamp:  ts,
+		})
+	}
+
+	x := newClaudeExtractor()
+	x.ordinal = cp.nextOrdinal
+	x.base = tokenUsage{
+		InputTokens:              sess.InputTokens,
+		OutputTokens:             sess.OutputTokens,
+		CacheCreationInputTokens: sess.CacheCreationInputTokens,
+		CacheReadInputTokens:     sess.CacheReadInputTokens,
+		ByModel:                  maps.Clone(sess.TokensByModel),
+	}
+	// Streaming lines of one message are written back to back,
+	// so only the last message seen can still be updated.
+	if cp.lastMsgID != "" {
+		x.base.add(cp.lastUsage, -1)
+		x.lastUsage[cp.lastMsgID] = cp.lastUsage
+		x.lastMsgID = cp.lastMsgID
+	}
+	for _, e := range entries {
+		x.add(e)
+	}
+	msgs := x.messages
+	annotateSubagentSessions(msgs, next.subagents)
+
+	// An enqueue for a Task call stored by an earlier parse
+	// would have to update that message.
+	for _, tuid := range enqueued {
+		if !hasToolUse(msgs, tuid) {
+			return ParseResult{}, nil, ErrFullParseRequired
+		}
+	}
+
+	for _, m := range msgs {
+		if m.Role == RoleUser && m.Content != "" {
+			sess.UserMessageCount++
+			if sess.FirstMessage == "" {
+				sess.FirstMessage = truncate(
+					strings.ReplaceAll(m.Content, "\n", " "), 300,
+				)
+			}
+		}
+	}
+	sess.MessageCount += len(msgs)
+	tokens := x.tokens()
+	sess.InputTokens = tokens.InputTokens
+	sess.OutputTokens = tokens.OutputTokens
+	sess.CacheCreationInputTokens = tokens.CacheCreationInputTokens
+	sess.CacheReadInputTokens = tokens.CacheReadInputTokens
+	sess.TokensByModel = tokens.ByModel
+
+	next.session = sess
+	next.nextOrdinal = x.ordinal
+	next.lastMsgID = x.lastMsgID
+	next.lastUsage = x.lastUsage[x.lastMsgID]
+	return ParseResult{Session: sess, Messages: msgs}, &next, nil
+}
+
+func hasToolUse(msgs []ParsedMessage, toolUseID string) bool {
+	for _, m := range msgs {
+		for _, tc := range m.ToolCalls {
+			if tc.ToolUseID == toolUseID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resumeCodex continues a Codex parse. A new session_meta line
+// can change the session ID or project, so it requires a full
+// parse.
+func resumeCodex(
+	lr *lineReader, cp *Checkpoint,
+) (ParseResult, *Checkpoint, error) {
+	next := *cp
+	b := *cp.codex
+	for {
+		line, ok := lr.next()
+		if !ok {
+			break
+		}
+		if !gjson.Valid(line) {
+			if lr.terminated {
+				next.Offset = lr.pos
+			}
+			continue
+		}
+		next.Offset = lr.pos
+		if gjson.Get(line, "type").Str == codexTypeSessionMeta {
+			return ParseResult{}, nil, ErrFullParseRequired
+		}
+		b.processLine(line)
+	}
+
+	msgs := b.messages
+	saved := b
+	saved.messages = nil
+	next.codex = &saved
+
+	sess := cp.session
+	sess.FirstMessage = b.firstMessage
+	sess.StartedAt = b.startedAt
+	sess.EndedAt = b.endedAt
+	sess.MessageCount = b.ordinal
+	sess.UserMessageCount = b.userCount
+	next.session = sess
+	return ParseResult{Session: sess, Messages: msgs}, &next, nil
+}
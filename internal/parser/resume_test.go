@@ -0,0 +1,291 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+func appendToFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// resumeClaudeTest parses prefix with a checkpoint, appends
+// suffix and resumes.
+func resumeClaudeTest(
+	t *testing.T, name, prefix, suffix string,
+) (string, []ParseResult, ParseResult, error) {
+	t.Helper()
+	path := createTestFile(t, name, prefix)
+	first, cp, err := ParseClaudeSessionCheckpoint(
+		path, "proj", "local",
+	)
+	if err != nil {
+		t.Fatalf("ParseClaudeSessionCheckpoint: %v", err)
+	}
+	if cp == nil {
+		t.Fatal("expected checkpoint")
+	}
+	appendToFile(t, path, suffix)
+	res, _, err := ResumeSession(path, cp)
+	return path, first, res, err
+}
+
+// assertResumeMatchesFull checks that the resumed result equals
+// the tail of a full parse of the same file.
+func assertResumeMatchesFull(
+	t *testing.T, full ParseResult, prevCount int, res ParseResult,
+) {
+	t.Helper()
+	if !reflect.DeepEqual(res.Session, full.Session) {
+		t.Errorf("session mismatch:\n got %+v\nwant %+v",
+			res.Session, full.Session)
+	}
+	want := full.Messages[prevCount:]
+	if !reflect.DeepEqual(res.Messages, want) {
+		t.Errorf("messages mismatch:\n got %+v\nwant %+v",
+			res.Messages, want)
+	}
+}
+
+func TestResumeClaudeDAGAppend(t *testing.T) {
+	prefix := testjsonl.NewSessionBuilder().
+		AddClaudeUserWithUUID(tsEarly, "hello", "a", "", "/home/u/proj").
+		AddClaudeAssistantWithUUID(tsEarlyS1, "hi there", "b", "a").
+		String()
+	suffix := testjsonl.NewSessionBuilder().
+		AddClaudeUserWithUUID(tsEarlyS5, "next question", "c", "b").
+		AddClaudeAssistantWithUUID(tsLate, "answer", "d", "c").
+		String()
+
+	path, first, res, err := resumeClaudeTest(
+		t, "dag.jsonl", prefix, suffix,
+	)
+	if err != nil {
+		t.Fatalf("ResumeSession: %v", err)
+	}
+	full, err := ParseClaudeSession(path, "proj", "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Messages) != 2 || res.Messages[0].Ordinal != 2 {
+		t.Fatalf("resumed messages = %+v, want ordinals 2-3",
+			res.Messages)
+	}
+	assertResumeMatchesFull(t, full[0], len(first[0].Messages), res)
+}
+
+func TestResumeClaudeForkNeedsFullParse(t *testing.T) {
+	prefix := testjsonl.NewSessionBuilder().
+		AddClaudeUserWithUUID(tsEarly, "hello", "a", "").
+		AddClaudeAssistantWithUUID(tsEarlyS1, "hi there", "b", "a").
+		String()
+
+	tests := []struct {
+		name   string
+		suffix string
+	}{
+		{
+			"retry of earlier turn",
+			testjsonl.NewSessionBuilder().
+				AddClaudeAssistantWithUUID(tsEarlyS5, "retry", "c", "a").
+				String(),
+		},
+		{
+			"new root",
+			testjsonl.NewSessionBuilder().
+				AddClaudeUserWithUUID(tsEarlyS5, "again", "c", "").
+				String(),
+		},
+		{
+			"entry without uuid",
+			testjsonl.NewSessionBuilder().
+				AddClaudeUser(tsEarlyS5, "no uuid").
+				String(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := resumeClaudeTest(
+				t, "fork.jsonl", prefix, tt.suffix,
+			)
+			if !errors.Is(err, ErrFullParseRequired) {
+				t.Errorf("err = %v, want ErrFullParseRequired", err)
+			}
+		})
+	}
+}
+
+func TestResumeClaudeStreamingUsage(t *testing.T) {
+	// The second streaming line of msg_1 lands after the
+	// checkpoint and must replace, not add to, its usage.
+	text := []map[string]string{{"type": "text", "text": "part"}}
+	prefix := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsEarly, "hello").
+		AddRaw(testjsonl.ClaudeAssistantWithUsageJSON(
+			text, tsEarlyS1, "msg_0", 10, 5, 0, 0)).
+		AddRaw(testjsonl.ClaudeAssistantWithUsageJSON(
+			text, tsEarlyS1, "msg_1", 100, 1, 0, 0)).
+		String()
+	suffix := testjsonl.NewSessionBuilder().
+		AddRaw(testjsonl.ClaudeAssistantWithUsageJSON(
+			text, tsEarlyS5, "msg_1", 100, 50, 0, 0)).
+		AddRaw(testjsonl.ClaudeAssistantWithUsageJSON(
+			text, tsLate, "msg_2", 7, 3, 0, 0)).
+		String()
+
+	path, first, res, err := resumeClaudeTest(
+		t, "usage.jsonl", prefix, suffix,
+	)
+	if err != nil {
+		t.Fatalf("ResumeSession: %v", err)
+	}
+	full, err := ParseClaudeSession(path, "proj", "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Session.OutputTokens != 58 {
+		t.Errorf("output tokens = %d, want 58",
+			res.Session.OutputTokens)
+	}
+	assertResumeMatchesFull(t, full[0], len(first[0].Messages), res)
+}
+
+func TestResumeSkipsPartialLine(t *testing.T) {
+	line := testjsonl.ClaudeEntryJSON(
+		"user", "second", tsEarlyS5, "c", "b",
+	)
+	prefix := testjsonl.NewSessionBuilder().
+		AddClaudeUserWithUUID(tsEarly, "hello", "a", "").
+		AddClaudeAssistantWithUUID(tsEarlyS1, "hi there", "b", "a").
+		String() + line[:len(line)/2]
+
+	path := createTestFile(t, "partial.jsonl", prefix)
+	first, cp, err := ParseClaudeSessionCheckpoint(
+		path, "proj", "local",
+	)
+	if err != nil || cp == nil {
+		t.Fatalf("ParseClaudeSessionCheckpoint: cp=%v err=%v", cp, err)
+	}
+	if want := int64(len(prefix) - len(line)/2); cp.Offset != want {
+		t.Fatalf("offset = %d, want %d (before partial line)",
+			cp.Offset, want)
+	}
+
+	appendToFile(t, path, line[len(line)/2:]+"\n")
+	res, next, err := ResumeSession(path, cp)
+	if err != nil {
+		t.Fatalf("ResumeSession: %v", err)
+	}
+	full, err := ParseClaudeSession(path, "proj", "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResumeMatchesFull(t, full[0], len(first[0].Messages), res)
+	if info, _ := os.Stat(path); next.Offset != info.Size() {
+		t.Errorf("next offset = %d, want %d", next.Offset, info.Size())
+	}
+}
+
+func TestParseClaudeCheckpointNilForForks(t *testing.T) {
+	// Large-gap fork from b: the file splits into two sessions.
+	b := testjsonl.NewSessionBuilder().
+		AddClaudeUserWithUUID(tsEarly, "start", "a", "").
+		AddClaudeAssistantWithUUID(tsEarlyS1, "ok", "b", "a")
+	parent := "b"
+	for _, id := range []string{"c", "e", "g", "k"} {
+		b.AddClaudeUserWithUUID(tsEarlyS5, "turn", id, parent)
+		reply := id + "r"
+		b.AddClaudeAssistantWithUUID(tsEarlyS5, "reply", reply, id)
+		parent = reply
+	}
+	b.AddClaudeUserWithUUID(tsLate, "fork", "i", "b")
+
+	path := createTestFile(t, "forked.jsonl", b.String())
+	results, cp, err := ParseClaudeSessionCheckpoint(
+		path, "proj", "local",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if cp != nil {
+		t.Error("forked file should not produce a checkpoint")
+	}
+}
+
+func TestResumeCodexAppend(t *testing.T) {
+	prefix := testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsEarly, "abc", "/home/u/proj", "user").
+		AddCodexMessage(tsEarlyS1, "user", "hello").
+		String()
+	suffix := testjsonl.NewSessionBuilder().
+		AddCodexMessage(tsEarlyS5, "assistant", "hi").
+		AddCodexFunctionCall(tsLate, "shell", "list files").
+		AddCodexMessage(tsLateS5, "user", "thanks").
+		String()
+
+	path := createTestFile(t, "codex.jsonl", prefix)
+	_, firstMsgs, cp, err := ParseCodexSessionCheckpoint(
+		path, "local", false,
+	)
+	if err != nil || cp == nil {
+		t.Fatalf("ParseCodexSessionCheckpoint: cp=%v err=%v", cp, err)
+	}
+	appendToFile(t, path, suffix)
+
+	res, _, err := ResumeSession(path, cp)
+	if err != nil {
+		t.Fatalf("ResumeSession: %v", err)
+	}
+	sess, msgs, err := ParseCodexSession(path, "local", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResumeMatchesFull(t,
+		ParseResult{Session: *sess, Messages: msgs},
+		len(firstMsgs), res)
+
+	// A second session_meta may change the session identity.
+	appendToFile(t, path, testjsonl.CodexSessionMetaJSON(
+		"other", "/tmp", "user", tsLateS5,
+	)+"\n")
+	if _, _, err := ResumeSession(path, cp); !errors.Is(
+		err, ErrFullParseRequired,
+	) {
+		t.Errorf("err = %v, want ErrFullParseRequired", err)
+	}
+}
+
+func TestResumeTruncatedFile(t *testing.T) {
+	content := testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsEarly, "abc", "/home/u/proj", "user").
+		AddCodexMessage(tsEarlyS1, "user", "hello").
+		String()
+	path := createTestFile(t, "trunc.jsonl", content)
+	_, _, cp, err := ParseCodexSessionCheckpoint(path, "local", false)
+	if err != nil || cp == nil {
+		t.Fatalf("ParseCodexSessionCheckpoint: cp=%v err=%v", cp, err)
+	}
+	if err := os.Truncate(path, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ResumeSession(path, cp); !errors.Is(
+		err, ErrFullParseRequired,
+	) {
+		t.Errorf("err = %v, want ErrFullParseRequired", err)
+	}
+}
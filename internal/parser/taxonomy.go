@@ -63,6 +63,52 @@ func NormalizeToolCategory(rawName string) string {
 	case "report_intent":
 		return "Tool"
 
+	// Cline and Roo Code tools
+	// Note: "read_file" (Read), "execute_command" (Bash) and
+	// "search_files" (Grep) are handled in the Gemini section.
+	case "write_to_file":
+		return "Write"
+	case "replace_in_file", "apply_diff", "insert_content",
+		"search_and_replace":
+		return "Edit"
+	case "list_files":
+		return "Glob"
+
+	// Cursor tools
+	// Note: "read_file" (Read), "edit_file" (Write) and
+	// "grep" (Grep) are handled in earlier sections.
+	case "run_terminal_cmd":
+		return "Bash"
+	case "search_replace":
+		return "Edit"
+	case "grep_search", "codebase_search":
+		return "Grep"
+	case "file_search", "list_dir":
+		return "Glob"
+
+	// Goose tools, named <extension>__<tool>. The text editor
+	// also views and writes files; the Goose parser refines its
+	// category from the command.
+	case "developer__shell":
+		return "Bash"
+	case "developer__text_editor":
+		return "Edit"
+	case "dynamic_task__create_task", "subagent__execute_task":
+		return "Task"
+
+	// Amp tools
+	// Note: "Read", "Bash", "Grep" and "Task" are handled in the
+	// Claude Code section, "glob" in the OpenCode section and
+	// "edit_file" (Write) in the Gemini section.
+	case "create_file":
+		return "Write"
+	case "undo_edit":
+		return "Edit"
+	case "list_directory":
+		return "Glob"
+	case "codebase_search_agent", "oracle":
+		return "Task"
+
 	default:
 		return "Other"
 	}
@@ -47,6 +47,33 @@ func TestNormalizeToolCategory(t *testing.T) {
 		{"view", "Read"},
 		{"report_intent", "Tool"},
 
+		// Cline and Roo Code tools
+		{"write_to_file", "Write"},
+		{"replace_in_file", "Edit"},
+		{"apply_diff", "Edit"},
+		{"insert_content", "Edit"},
+		{"search_and_replace", "Edit"},
+		{"list_files", "Glob"},
+
+		// Cursor tools
+		{"run_terminal_cmd", "Bash"},
+		{"search_replace", "Edit"},
+		{"grep_search", "Grep"},
+		{"codebase_search", "Grep"},
+		{"file_search", "Glob"},
+		{"list_dir", "Glob"},
+
+		// Goose tools
+		{"developer__shell", "Bash"},
+		{"developer__text_editor", "Edit"},
+		{"subagent__execute_task", "Task"},
+
+		// Amp tools
+		{"create_file", "Write"},
+		{"undo_edit", "Edit"},
+		{"list_directory", "Glob"},
+		{"codebase_search_agent", "Task"},
+
 		// Unknown
 		{"view_image", "Other"},
 		{"update_plan", "Other"},
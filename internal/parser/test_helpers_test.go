@@ -3,6 +3,7 @@ package parser
 import (
 	"bytes"
 	"log"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -189,3 +190,21 @@ func parseClaudeTestFile(
 	}
 	return results[0].Session, results[0].Messages
 }
+
+func intPtr(n int) *int { return &n }
+
+func assertExitCode(t *testing.T, i int, got, want *int) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil || *got != *want:
+		t.Errorf("[%d].ExitCode = %v, want %v", i, fmtIntPtr(got), fmtIntPtr(want))
+	}
+}
+
+func fmtIntPtr(p *int) string {
+	if p == nil {
+		return "nil"
+	}
+	return strconv.Itoa(*p)
+}
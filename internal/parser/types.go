@@ -14,6 +14,12 @@ const (
 	AgentCopilot  AgentType = "copilot"
 	AgentGemini   AgentType = "gemini"
 	AgentOpenCode AgentType = "opencode"
+	AgentAider    AgentType = "aider"
+	AgentCline    AgentType = "cline"
+	AgentCursor   AgentType = "cursor"
+	AgentGoose    AgentType = "goose"
+	AgentAmp      AgentType = "amp"
+	AgentOTel     AgentType = "otel"
 )
 
 // RelationshipType describes how a session relates to its parent.
@@ -62,14 +68,65 @@ type ParsedSession struct {
 	CacheReadInputTokens     int64
 	TokensByModel            map[string]ModelTokenUsage
 	File                     FileInfo
+
+	// Cwd and GitBranch are the working directory and git branch
+	// the agent recorded, and RepoRoot is the root of the git
+	// repository containing Cwd. Each is empty when unknown.
+	Cwd       string
+	GitBranch string
+	RepoRoot  string
+}
+
+// SetWorkspace records the working directory and git branch of
+// the session, and finds the repository containing cwd.
+func (s *ParsedSession) SetWorkspace(cwd, gitBranch string) {
+	s.Cwd = cwd
+	s.GitBranch = gitBranch
+	s.RepoRoot = FindGitRepoRoot(cwd)
 }
 
 // ModelTokenUsage holds token counts for a single model within a session.
+// CostUSD is the cost the agent reported for them, when it does.
 type ModelTokenUsage struct {
-	InputTokens              int64 `json:"input_tokens"`
-	OutputTokens             int64 `json:"output_tokens"`
-	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
-	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	InputTokens              int64   `json:"input_tokens"`
+	OutputTokens             int64   `json:"output_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
+	CostUSD                  float64 `json:"cost_usd,omitempty"`
+}
+
+// addMessageTokens totals the token counts of msgs into sess,
+// overall and per model, for agents that record usage on each
+// message rather than for the whole session. Messages without
+// input or output tokens are skipped.
+func addMessageTokens(sess *ParsedSession, msgs []ParsedMessage) {
+	for _, m := range msgs {
+		if m.InputTokens == 0 && m.OutputTokens == 0 {
+			continue
+		}
+		sess.addTokens(m)
+	}
+}
+
+// addTokens adds the token counts of m to the session totals
+// and, when m names its model, to that model's.
+func (s *ParsedSession) addTokens(m ParsedMessage) {
+	s.InputTokens += m.InputTokens
+	s.OutputTokens += m.OutputTokens
+	s.CacheCreationInputTokens += m.CacheCreationInputTokens
+	s.CacheReadInputTokens += m.CacheReadInputTokens
+	if m.Model == "" {
+		return
+	}
+	if s.TokensByModel == nil {
+		s.TokensByModel = make(map[string]ModelTokenUsage)
+	}
+	u := s.TokensByModel[m.Model]
+	u.InputTokens += m.InputTokens
+	u.OutputTokens += m.OutputTokens
+	u.CacheCreationInputTokens += m.CacheCreationInputTokens
+	u.CacheReadInputTokens += m.CacheReadInputTokens
+	s.TokensByModel[m.Model] = u
 }
 
 // ParsedToolCall holds a single tool invocation extracted from
@@ -89,6 +146,11 @@ type ParsedToolResult struct {
 	ToolUseID     string
 	ContentLength int
 	Content       string
+	// IsError is set when the tool reported a failure.
+	IsError bool
+	// ExitCode is the command's exit status, when the agent
+	// records one.
+	ExitCode *int
 }
 
 // ParsedMessage holds a single extracted message.
@@ -102,6 +164,13 @@ type ParsedMessage struct {
 	ContentLength int
 	ToolCalls     []ParsedToolCall
 	ToolResults   []ParsedToolResult
+	// Model and token counts are set on assistant messages when
+	// the format records them.
+	Model                    string
+	InputTokens              int64
+	OutputTokens             int64
+	CacheCreationInputTokens int64
+	CacheReadInputTokens     int64
 }
 
 // ParseResult pairs a parsed session with its messages.
@@ -86,3 +86,30 @@ func TestInferRelationshipTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestAddMessageTokens(t *testing.T) {
+	var sess ParsedSession
+	addMessageTokens(&sess, []ParsedMessage{
+		{Role: RoleUser},
+		{
+			Role: RoleAssistant, Model: "a",
+			InputTokens: 100, OutputTokens: 10,
+			CacheReadInputTokens: 5,
+		},
+		{Role: RoleAssistant, Model: "b", CacheReadInputTokens: 50},
+		{Role: RoleAssistant, Model: "c"},
+		{Role: RoleAssistant, Model: "a", OutputTokens: 20},
+	})
+	if sess.InputTokens != 100 || sess.OutputTokens != 30 ||
+		sess.CacheReadInputTokens != 5 {
+		t.Errorf("totals = %d in, %d out, %d cache read",
+			sess.InputTokens, sess.OutputTokens,
+			sess.CacheReadInputTokens)
+	}
+	want := ModelTokenUsage{
+		InputTokens: 100, OutputTokens: 30, CacheReadInputTokens: 5,
+	}
+	if len(sess.TokensByModel) != 1 || sess.TokensByModel["a"] != want {
+		t.Errorf("by model = %+v", sess.TokensByModel)
+	}
+}
@@ -0,0 +1,46 @@
+package pricing
+
+// defaultPrices is the built-in price table, in USD per million
+// tokens. Entries can be overridden or extended with the
+// "pricing" object in config.json.
+var defaultPrices = map[string][]Price{
+	// Anthropic
+	"claude-opus-4-5":   {{Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5}},
+	"claude-opus-4-1":   {{Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5}},
+	"claude-opus-4":     {{Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5}},
+	"claude-sonnet-4-5": {{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3}},
+	"claude-sonnet-4":   {{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3}},
+	"claude-haiku-4-5":  {{Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.1}},
+	"claude-3-7-sonnet": {{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3}},
+	"claude-3-5-sonnet": {{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3}},
+	"claude-3-5-haiku":  {{Input: 0.8, Output: 4, CacheWrite: 1, CacheRead: 0.08}},
+	"claude-3-opus":     {{Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5}},
+	"claude-3-haiku":    {{Input: 0.25, Output: 1.25, CacheWrite: 0.3, CacheRead: 0.03}},
+
+	// OpenAI
+	"gpt-5":        {{Input: 1.25, Output: 10, CacheRead: 0.125}},
+	"gpt-5-codex":  {{Input: 1.25, Output: 10, CacheRead: 0.125}},
+	"gpt-5-mini":   {{Input: 0.25, Output: 2, CacheRead: 0.025}},
+	"gpt-5-nano":   {{Input: 0.05, Output: 0.4, CacheRead: 0.005}},
+	"gpt-4.1":      {{Input: 2, Output: 8, CacheRead: 0.5}},
+	"gpt-4.1-mini": {{Input: 0.4, Output: 1.6, CacheRead: 0.1}},
+	"gpt-4o":       {{Input: 2.5, Output: 10, CacheRead: 1.25}},
+	"o4-mini":      {{Input: 1.1, Output: 4.4, CacheRead: 0.275}},
+	"o3": {
+		{Input: 10, Output: 40, CacheRead: 2.5},
+		{Since: "2025-06-10", Input: 2, Output: 8, CacheRead: 0.5},
+	},
+
+	// Google
+	"gemini-2.5-pro":   {{Input: 1.25, Output: 10, CacheRead: 0.31}},
+	"gemini-2.5-flash": {{Input: 0.3, Output: 2.5, CacheRead: 0.075}},
+}
+
+// Default returns the built-in price table.
+func Default() *Table {
+	t, err := NewTable(defaultPrices)
+	if err != nil {
+		panic("pricing: invalid built-in table: " + err.Error())
+	}
+	return t
+}
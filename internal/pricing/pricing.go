@@ -0,0 +1,211 @@
+// Package pricing estimates the cost of agent sessions from
+// their token usage and a per-model price table.
+package pricing
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Price is a model's price in USD per million tokens, in effect
+// from Since (YYYY-MM-DD, UTC) until the next price for the same
+// model. An empty Since means the price has no start date.
+type Price struct {
+	Since      string  `json:"since,omitempty"`
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+// Usage is a token count breakdown. The JSON tags match the
+// sessions.token_usage_by_model column. Reported is the cost in
+// USD the agent itself reported for the usage, if any.
+type Usage struct {
+	Input      int64   `json:"input_tokens"`
+	Output     int64   `json:"output_tokens"`
+	CacheWrite int64   `json:"cache_creation_input_tokens"`
+	CacheRead  int64   `json:"cache_read_input_tokens"`
+	Reported   float64 `json:"cost_usd,omitempty"`
+}
+
+// Cost returns the cost of u in USD at price p.
+func (p Price) Cost(u Usage) float64 {
+	return (float64(u.Input)*p.Input +
+		float64(u.Output)*p.Output +
+		float64(u.CacheWrite)*p.CacheWrite +
+		float64(u.CacheRead)*p.CacheRead) / 1e6
+}
+
+// Table maps model names to their price history.
+type Table struct {
+	models map[string][]Price // sorted by Since
+	keys   []string           // longest first, for prefix matching
+}
+
+// NewTable builds a table from per-model price histories.
+func NewTable(models map[string][]Price) (*Table, error) {
+	t := &Table{models: make(map[string][]Price, len(models))}
+	for name, prices := range models {
+		if err := t.set(name, prices); err != nil {
+			return nil, err
+		}
+	}
+	t.index()
+	return t, nil
+}
+
+// With returns a copy of t where each model in overrides has its
+// price history replaced. Models not in t are added.
+func (t *Table) With(overrides map[string][]Price) (*Table, error) {
+	out := &Table{models: maps.Clone(t.models)}
+	for name, prices := range overrides {
+		if err := out.set(name, prices); err != nil {
+			return nil, err
+		}
+	}
+	out.index()
+	return out, nil
+}
+
+func (t *Table) set(name string, prices []Price) error {
+	key := normalizeModel(name)
+	if key == "" {
+		return fmt.Errorf("empty model name")
+	}
+	if len(prices) == 0 {
+		return fmt.Errorf("model %s: no prices", name)
+	}
+	sorted := make([]Price, len(prices))
+	copy(sorted, prices)
+	for _, p := range sorted {
+		if p.Since != "" {
+			if _, err := time.Parse("2006-01-02", p.Since); err != nil {
+				return fmt.Errorf(
+					"model %s: invalid since %q: want YYYY-MM-DD",
+					name, p.Since,
+				)
+			}
+		}
+		if p.Input < 0 || p.Output < 0 ||
+			p.CacheWrite < 0 || p.CacheRead < 0 {
+			return fmt.Errorf("model %s: negative price", name)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Since < sorted[j].Since
+	})
+	t.models[key] = sorted
+	return nil
+}
+
+func (t *Table) index() {
+	t.keys = make([]string, 0, len(t.models))
+	for k := range t.models {
+		t.keys = append(t.keys, k)
+	}
+	sort.Slice(t.keys, func(i, j int) bool {
+		if len(t.keys[i]) != len(t.keys[j]) {
+			return len(t.keys[i]) > len(t.keys[j])
+		}
+		return t.keys[i] < t.keys[j]
+	})
+}
+
+// Lookup returns the price of model in effect at the given time.
+// A model matches a table entry with the same name or, failing
+// that, the longest entry it extends with a version or date
+// suffix, so "claude-sonnet-4-5-20250929" is priced as
+// "claude-sonnet-4-5" but "o3-mini" is not priced as "o3". A
+// zero time uses the latest price; a time before the first dated
+// price uses the earliest one.
+func (t *Table) Lookup(model string, at time.Time) (Price, bool) {
+	prices, ok := t.match(normalizeModel(model))
+	if !ok {
+		return Price{}, false
+	}
+	day := ""
+	if !at.IsZero() {
+		day = at.UTC().Format("2006-01-02")
+	}
+	p := prices[0]
+	for _, cand := range prices[1:] {
+		if day != "" && cand.Since > day {
+			break
+		}
+		p = cand
+	}
+	return p, true
+}
+
+func (t *Table) match(model string) ([]Price, bool) {
+	if prices, ok := t.models[model]; ok {
+		return prices, true
+	}
+	for _, k := range t.keys {
+		suffix, ok := strings.CutPrefix(model, k+"-")
+		if ok && isVersionSuffix(suffix) {
+			return t.models[k], true
+		}
+	}
+	return nil, false
+}
+
+func isVersionSuffix(s string) bool {
+	return s == "latest" || (s != "" && s[0] >= '0' && s[0] <= '9')
+}
+
+// normalizeModel lowercases a model name and drops a provider
+// prefix such as "anthropic/".
+func normalizeModel(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndexByte(model, '/'); i >= 0 {
+		model = model[i+1:]
+	}
+	return model
+}
+
+// Estimate is the cost of a session's token usage.
+type Estimate struct {
+	Total   float64
+	ByModel map[string]float64
+	// Unpriced lists models with token usage but no price.
+	Unpriced []string
+}
+
+// Priced reports whether any of the usage could be priced.
+func (e Estimate) Priced() bool {
+	return len(e.ByModel) > 0
+}
+
+// Estimate prices per-model token usage at the given time. Usage
+// with a reported cost is charged that cost instead of its price.
+func (t *Table) Estimate(
+	byModel map[string]Usage, at time.Time,
+) Estimate {
+	var e Estimate
+	for model, u := range byModel {
+		if u == (Usage{}) {
+			continue
+		}
+		c := u.Reported
+		if c <= 0 {
+			p, ok := t.Lookup(model, at)
+			if !ok {
+				e.Unpriced = append(e.Unpriced, model)
+				continue
+			}
+			c = p.Cost(u)
+		}
+		if e.ByModel == nil {
+			e.ByModel = make(map[string]float64)
+		}
+		e.ByModel[model] = c
+		e.Total += c
+	}
+	sort.Strings(e.Unpriced)
+	return e
+}
@@ -0,0 +1,131 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestLookup(t *testing.T) {
+	table := Default()
+	tests := []struct {
+		model string
+		at    time.Time
+		want  float64 // input price
+		ok    bool
+	}{
+		{"claude-sonnet-4-5", time.Time{}, 3, true},
+		{"claude-sonnet-4-5-20250929", time.Time{}, 3, true},
+		{"claude-opus-4-1-20250805", time.Time{}, 15, true},
+		{"claude-opus-4-5-20251101", time.Time{}, 5, true},
+		{"Anthropic/Claude-Haiku-4-5", time.Time{}, 1, true},
+		{"o3", day("2025-06-09"), 10, true},
+		{"o3", day("2025-06-10"), 2, true},
+		{"o3", time.Time{}, 2, true},
+		{"o3-mini", time.Time{}, 0, false},
+		{"claude-sonnet", time.Time{}, 0, false},
+		{"<synthetic>", time.Time{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			p, ok := table.Lookup(tt.model, tt.at)
+			if ok != tt.ok || p.Input != tt.want {
+				t.Errorf("Lookup(%q) = %v, %v; want input %v, %v",
+					tt.model, p, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	p := Price{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3}
+	got := p.Cost(Usage{
+		Input: 1_000_000, Output: 100_000,
+		CacheWrite: 200_000, CacheRead: 2_000_000,
+	})
+	// 3 + 1.5 + 0.75 + 0.6
+	if math.Abs(got-5.85) > 1e-9 {
+		t.Errorf("Cost = %v, want 5.85", got)
+	}
+}
+
+func TestWithOverrides(t *testing.T) {
+	base := Default()
+	table, err := base.With(map[string][]Price{
+		"claude-sonnet-4-5": {
+			{Since: "2026-01-01", Input: 2, Output: 10},
+			{Input: 4, Output: 20},
+		},
+		"local-llama": {{Input: 0, Output: 0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p, _ := table.Lookup("claude-sonnet-4-5", day("2025-12-31")); p.Input != 4 {
+		t.Errorf("before override date: input = %v, want 4", p.Input)
+	}
+	if p, _ := table.Lookup("claude-sonnet-4-5", day("2026-01-02")); p.Input != 2 {
+		t.Errorf("after override date: input = %v, want 2", p.Input)
+	}
+	if _, ok := table.Lookup("local-llama-3", time.Time{}); !ok {
+		t.Error("added model not found")
+	}
+	if p, _ := base.Lookup("claude-sonnet-4-5", time.Time{}); p.Input != 3 {
+		t.Errorf("base table modified: input = %v", p.Input)
+	}
+
+	for name, bad := range map[string][]Price{
+		"bad-date": {{Since: "June 2025"}},
+		"negative": {{Input: -1}},
+		"empty":    {},
+	} {
+		if _, err := base.With(map[string][]Price{name: bad}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	e := Default().Estimate(map[string]Usage{
+		"claude-sonnet-4-5-20250929": {Input: 1_000_000},
+		"claude-haiku-4-5":           {Output: 1_000_000},
+		"mystery-model":              {Input: 10},
+		"<synthetic>":                {},
+	}, time.Time{})
+
+	if math.Abs(e.Total-8) > 1e-9 {
+		t.Errorf("Total = %v, want 8", e.Total)
+	}
+	if len(e.ByModel) != 2 {
+		t.Errorf("ByModel = %v, want 2 models", e.ByModel)
+	}
+	if len(e.Unpriced) != 1 || e.Unpriced[0] != "mystery-model" {
+		t.Errorf("Unpriced = %v, want [mystery-model]", e.Unpriced)
+	}
+	if !e.Priced() {
+		t.Error("Priced() = false")
+	}
+}
+
+func TestEstimateReportedCost(t *testing.T) {
+	e := Default().Estimate(map[string]Usage{
+		"claude-haiku-4-5": {Output: 1_000_000, Reported: 0.5},
+		"mystery-model":    {Input: 10, Reported: 0.25},
+	}, time.Time{})
+
+	if math.Abs(e.Total-0.75) > 1e-9 {
+		t.Errorf("Total = %v, want 0.75", e.Total)
+	}
+	if e.ByModel["mystery-model"] != 0.25 || len(e.Unpriced) != 0 {
+		t.Errorf("estimate = %+v, want reported costs", e)
+	}
+}
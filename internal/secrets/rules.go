@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// minEntropy is the Shannon entropy, in bits per character, above
+// which a long mixed-case alphanumeric token is treated as a
+// secret. Random base64 and base62 keys score well above it;
+// identifiers and hex hashes score below or lack a character
+// class.
+const minEntropy = 4.5
+
+// pemBody matches one character of a PEM key body.
+const pemBody = `(?:[A-Za-z0-9+/=:,.\s-]|\\[nrt])`
+
+// defaultRules are the built-in detection rules. Specific rules
+// come first so they name a match that the generic ones also
+// cover. Extra rules can be added with the "secret_patterns"
+// list in config.json.
+var defaultRules = []rule{
+	// The key body may only hold base64, PEM headers and newlines,
+	// raw or JSON-escaped, so a key cut off before its END line
+	// stops at the first other character rather than running on
+	// through the closing quotes of a JSON document.
+	{name: "private_key", re: regexp.MustCompile(
+		`-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----` +
+			`(?:` + pemBody + `*?-----END [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----` +
+			`|` + pemBody + `*)`)},
+	{name: "aws_access_key_id", re: regexp.MustCompile(
+		`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{name: "aws_secret_access_key", re: regexp.MustCompile(
+		`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?` +
+			`([A-Za-z0-9/+=]{40})\b`)},
+	{name: "github_token", re: regexp.MustCompile(
+		`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|` +
+			`github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{name: "anthropic_api_key", re: regexp.MustCompile(
+		`\bsk-ant-[A-Za-z0-9_\-]{20,}`)},
+	{name: "openai_api_key", re: regexp.MustCompile(
+		`\bsk-(?:proj-|svcacct-|admin-)?[A-Za-z0-9_\-]{20,}`)},
+	{name: "slack_token", re: regexp.MustCompile(
+		`\bxox[abposr]-[A-Za-z0-9\-]{10,}`)},
+	{name: "slack_webhook", re: regexp.MustCompile(
+		`https://hooks\.slack\.com/services/[A-Za-z0-9_/]+`)},
+	// KEY=value lines from .env files and shell exports.
+	{
+		name: "secret_assignment",
+		re: regexp.MustCompile(
+			`(?i)\b[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|` +
+				`API_?KEY|ACCESS_?KEY)[A-Z0-9_]*["']?\s*[:=]\s*` +
+				`["']?([^\s"'` + "`" + `]{8,})`),
+		check: isSecretValue,
+	},
+	{
+		name:  "high_entropy",
+		re:    regexp.MustCompile(`[A-Za-z0-9+_\-]{32,}={0,2}`),
+		check: isHighEntropy,
+	},
+}
+
+// Default returns a scanner with the built-in rules.
+func Default() *Scanner {
+	return &Scanner{rules: defaultRules}
+}
+
+// isSecretValue rejects assignment values that are plainly not
+// credentials: numbers and references to other variables.
+func isSecretValue(v string) bool {
+	if strings.HasPrefix(v, "$") || strings.HasPrefix(v, "<") {
+		return false
+	}
+	return strings.IndexFunc(v, unicode.IsLetter) >= 0
+}
+
+func isHighEntropy(s string) bool {
+	var upper, lower, digit bool
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit && entropy(s) >= minEntropy
+}
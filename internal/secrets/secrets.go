@@ -0,0 +1,151 @@
+// Package secrets finds credentials in session text, such as
+// API keys and tokens printed by tools, and redacts them.
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Pattern is a user-defined detection rule. If Regex has a
+// capture group, only the first group is reported, so a pattern
+// can match surrounding context without redacting it.
+type Pattern struct {
+	Name  string `json:"name"`
+	Regex string `json:"regex"`
+}
+
+// Finding is one detected secret. Start and End are byte offsets
+// into the scanned text.
+type Finding struct {
+	Rule  string `json:"rule"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+	// check, if set, filters candidate matches.
+	check func(string) bool
+}
+
+// Scanner detects secrets with an ordered list of rules. When
+// matches overlap, the longest wins, then the earliest rule.
+type Scanner struct {
+	rules []rule
+}
+
+// With returns a copy of s with patterns added after its rules.
+func (s *Scanner) With(patterns []Pattern) (*Scanner, error) {
+	out := &Scanner{rules: slices.Clone(s.rules)}
+	for _, p := range patterns {
+		if p.Name == "" {
+			return nil, fmt.Errorf("secret pattern without a name")
+		}
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("secret pattern %s: %w", p.Name, err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf(
+				"secret pattern %s matches empty text", p.Name,
+			)
+		}
+		out.rules = append(out.rules, rule{name: p.Name, re: re})
+	}
+	return out, nil
+}
+
+// Scan returns the secrets in text, ordered by position and not
+// overlapping.
+func (s *Scanner) Scan(text string) []Finding {
+	type candidate struct {
+		Finding
+		rank int
+	}
+	var cands []candidate
+	for i, r := range s.rules {
+		for _, loc := range r.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			if start == end {
+				continue
+			}
+			if r.check != nil && !r.check(text[start:end]) {
+				continue
+			}
+			cands = append(cands, candidate{
+				Finding{Rule: r.name, Start: start, End: end}, i,
+			})
+		}
+	}
+	slices.SortFunc(cands, func(a, b candidate) int {
+		if a.Start != b.Start {
+			return a.Start - b.Start
+		}
+		if a.End != b.End {
+			return b.End - a.End
+		}
+		return a.rank - b.rank
+	})
+
+	var out []Finding
+	for _, c := range cands {
+		if n := len(out); n > 0 && c.Start < out[n-1].End {
+			continue
+		}
+		out = append(out, c.Finding)
+	}
+	return out
+}
+
+// Redact replaces each secret in text with a [REDACTED:<rule>]
+// marker and returns the result with the number replaced.
+func (s *Scanner) Redact(text string) (string, int) {
+	findings := s.Scan(text)
+	if len(findings) == 0 {
+		return text, 0
+	}
+	var b strings.Builder
+	pos := 0
+	for _, f := range findings {
+		b.WriteString(text[pos:f.Start])
+		b.WriteString("[REDACTED:" + f.Rule + "]")
+		pos = f.End
+	}
+	b.WriteString(text[pos:])
+	return b.String(), len(findings)
+}
+
+// Mask returns a preview of secret that is safe to display: its
+// first few characters and its length.
+func Mask(secret string) string {
+	const keep = 4
+	if len(secret) <= 2*keep {
+		return fmt.Sprintf("… (%d chars)", len(secret))
+	}
+	return fmt.Sprintf("%s… (%d chars)", secret[:keep], len(secret))
+}
+
+// entropy returns the Shannon entropy of s in bits per byte.
+func entropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
@@ -113,6 +113,8 @@ func parseAnalyticsFilter(
 		Machine:         q.Get("machine"),
 		Project:         q.Get("project"),
 		Agent:           q.Get("agent"),
+		Branch:          q.Get("branch"),
+		Repo:            q.Get("repo"),
 		Timezone:        tz,
 		DayOfWeek:       dow,
 		Hour:            hour,
@@ -217,6 +219,28 @@ func (s *Server) handleAnalyticsHeatmap(
 	writeJSON(w, http.StatusOK, result)
 }
 
+func (s *Server) handleAnalyticsBranches(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsBranches(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		log.Printf("analytics error: %v", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
 func (s *Server) handleAnalyticsProjects(
 	w http.ResponseWriter, r *http.Request,
 ) {
@@ -239,6 +263,28 @@ func (s *Server) handleAnalyticsProjects(
 	writeJSON(w, http.StatusOK, result)
 }
 
+func (s *Server) handleAnalyticsCost(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsCost(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		log.Printf("analytics error: %v", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
 func (s *Server) handleAnalyticsHourOfWeek(
 	w http.ResponseWriter, r *http.Request,
 ) {
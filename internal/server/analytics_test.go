@@ -201,6 +201,7 @@ func TestAnalyticsErrorRedaction(t *testing.T) {
 		"activity",
 		"heatmap",
 		"projects",
+		"cost",
 		"hour-of-week",
 		"sessions",
 		"velocity",
@@ -228,11 +229,13 @@ func TestAnalyticsEndpoints_DefaultParams(t *testing.T) {
 		"activity",
 		"heatmap",
 		"projects",
+		"cost",
 		"hour-of-week",
 		"sessions",
 		"velocity",
 		"tools",
 		"top-sessions",
+		"branches",
 	}
 
 	for _, ep := range endpoints {
@@ -468,6 +471,66 @@ func TestAnalyticsProjects(t *testing.T) {
 	})
 }
 
+func TestAnalyticsBranches(t *testing.T) {
+	te := setup(t)
+	for _, s := range []struct{ id, branch string }{
+		{"b1", "main"}, {"b2", "feature"}, {"b3", "feature"}, {"b4", ""},
+	} {
+		branch := s.branch
+		te.seedSession(t, s.id, "app", 2, func(sess *db.Session) {
+			sess.StartedAt = dbtest.Ptr("2024-06-01T09:00:00Z")
+			sess.RepoRoot = "/src/app"
+			sess.GitBranch = branch
+		})
+	}
+
+	w := te.get(t, buildURLWithRange("branches", nil))
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.BranchesAnalyticsResponse](t, w)
+	if len(resp.Branches) != 2 || resp.Branches[0].Branch != "feature" ||
+		resp.Branches[0].Sessions != 2 {
+		t.Errorf("branches = %+v", resp.Branches)
+	}
+
+	w = te.get(t, buildURLWithRange("branches",
+		map[string]string{"branch": "main", "repo": "app"}))
+	assertStatus(t, w, http.StatusOK)
+	resp = decode[db.BranchesAnalyticsResponse](t, w)
+	if len(resp.Branches) != 1 || resp.Branches[0].Branch != "main" {
+		t.Errorf("filtered branches = %+v", resp.Branches)
+	}
+}
+
+func TestAnalyticsCost(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "c1", "alpha", 2, func(s *db.Session) {
+		s.StartedAt = dbtest.Ptr("2024-06-01T09:00:00Z")
+		s.TokenUsageByModel = db.RawJSON(
+			`{"claude-sonnet-4-5":{"input_tokens":1000000}}`,
+		)
+	})
+
+	w := te.get(t, buildURLWithRange("cost", nil))
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[db.CostAnalyticsResponse](t, w)
+	if resp.TotalUSD != 3 {
+		t.Errorf("total_usd = %v, want 3", resp.TotalUSD)
+	}
+	if len(resp.ByProject) != 1 || resp.ByProject[0].Name != "alpha" {
+		t.Errorf("by_project = %+v, want alpha", resp.ByProject)
+	}
+
+	w = te.get(t, "/api/v1/sessions/c1")
+	assertStatus(t, w, http.StatusOK)
+	sess := decode[struct {
+		CostUSD *float64 `json:"cost_usd"`
+	}](t, w)
+	if sess.CostUSD == nil || *sess.CostUSD != 3 {
+		t.Errorf("session cost_usd = %v, want 3", sess.CostUSD)
+	}
+}
+
 func TestAnalyticsHourOfWeek(t *testing.T) {
 	te := setup(t)
 	seedAnalyticsEnv(t, te)
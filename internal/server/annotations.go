@@ -0,0 +1,302 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// maxNoteLength caps note bodies, in bytes.
+const maxNoteLength = 64 * 1024
+
+// requireSession writes a 404 and returns false if the session
+// in the request path does not exist.
+func (s *Server) requireSession(
+	w http.ResponseWriter, r *http.Request,
+) (string, bool) {
+	id := r.PathValue("id")
+	session, err := s.db.GetSession(r.Context(), id)
+	if err != nil {
+		if !handleContextError(w, err) {
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return "", false
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return "", false
+	}
+	return id, true
+}
+
+// parseOrdinalParam reads the optional "ordinal" query
+// parameter that selects a message within the session. Returns
+// nil when it is absent.
+func parseOrdinalParam(
+	w http.ResponseWriter, r *http.Request,
+) (*int, bool) {
+	if r.URL.Query().Get("ordinal") == "" {
+		return nil, true
+	}
+	v, ok := parseIntParam(w, r, "ordinal")
+	if !ok {
+		return nil, false
+	}
+	if v < 0 {
+		writeError(w, http.StatusBadRequest, "invalid ordinal parameter")
+		return nil, false
+	}
+	return &v, true
+}
+
+func validOrdinal(w http.ResponseWriter, ordinal *int) bool {
+	if ordinal != nil && *ordinal < 0 {
+		writeError(w, http.StatusBadRequest, "invalid ordinal")
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleListTags(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id, ok := s.requireSession(w, r)
+	if !ok {
+		return
+	}
+	tags, err := s.db.ListTags(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+func (s *Server) handleAddTag(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var req struct {
+		Tag     string `json:"tag"`
+		Ordinal *int   `json:"ordinal"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if !validOrdinal(w, req.Ordinal) {
+		return
+	}
+	id, ok := s.requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	tag, err := s.db.AddTag(id, req.Ordinal, req.Tag)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidTag) {
+			writeError(w, http.StatusBadRequest,
+				"invalid tag: must be 1-64 characters without spaces")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, tag)
+}
+
+func (s *Server) handleRemoveTag(
+	w http.ResponseWriter, r *http.Request,
+) {
+	ordinal, ok := parseOrdinalParam(w, r)
+	if !ok {
+		return
+	}
+	removed, err := s.db.RemoveTag(
+		r.PathValue("id"), ordinal, r.PathValue("tag"),
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "tag not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListStars(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id, ok := s.requireSession(w, r)
+	if !ok {
+		return
+	}
+	stars, err := s.db.ListStars(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"stars": stars})
+}
+
+func (s *Server) handleAddStar(
+	w http.ResponseWriter, r *http.Request,
+) {
+	ordinal, ok := parseOrdinalParam(w, r)
+	if !ok {
+		return
+	}
+	id, ok := s.requireSession(w, r)
+	if !ok {
+		return
+	}
+	star, err := s.db.AddStar(id, ordinal)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, star)
+}
+
+func (s *Server) handleRemoveStar(
+	w http.ResponseWriter, r *http.Request,
+) {
+	ordinal, ok := parseOrdinalParam(w, r)
+	if !ok {
+		return
+	}
+	removed, err := s.db.RemoveStar(r.PathValue("id"), ordinal)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "star not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListNotes(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id, ok := s.requireSession(w, r)
+	if !ok {
+		return
+	}
+	notes, err := s.db.ListNotes(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"notes": notes})
+}
+
+type noteRequest struct {
+	Body    string `json:"body"`
+	Ordinal *int   `json:"ordinal"`
+}
+
+// decodeNote reads and validates a note request body.
+func decodeNote(
+	w http.ResponseWriter, r *http.Request,
+) (noteRequest, bool) {
+	var req noteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return req, false
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		writeError(w, http.StatusBadRequest, "body required")
+		return req, false
+	}
+	if len(req.Body) > maxNoteLength {
+		writeError(w, http.StatusBadRequest, "body too long")
+		return req, false
+	}
+	return req, validOrdinal(w, req.Ordinal)
+}
+
+func (s *Server) handleAddNote(
+	w http.ResponseWriter, r *http.Request,
+) {
+	req, ok := decodeNote(w, r)
+	if !ok {
+		return
+	}
+	id, ok := s.requireSession(w, r)
+	if !ok {
+		return
+	}
+	note, err := s.db.AddNote(id, req.Ordinal, req.Body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, note)
+}
+
+func parseNoteID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("noteId"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid note id")
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) handleUpdateNote(
+	w http.ResponseWriter, r *http.Request,
+) {
+	noteID, ok := parseNoteID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := decodeNote(w, r)
+	if !ok {
+		return
+	}
+	note, err := s.db.UpdateNote(r.PathValue("id"), noteID, req.Body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if note == nil {
+		writeError(w, http.StatusNotFound, "note not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (s *Server) handleDeleteNote(
+	w http.ResponseWriter, r *http.Request,
+) {
+	noteID, ok := parseNoteID(w, r)
+	if !ok {
+		return
+	}
+	removed, err := s.db.DeleteNote(r.PathValue("id"), noteID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "note not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+func TestSessionTags(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "proj", 4)
+	te.seedSession(t, "s2", "proj", 4)
+
+	w := te.post(t, "/api/v1/sessions/s1/tags", `{"tag":"Keeper"}`)
+	assertStatus(t, w, http.StatusCreated)
+	if tag := decode[db.Tag](t, w); tag.Tag != "keeper" || tag.Ordinal != nil {
+		t.Errorf("created tag = %+v", tag)
+	}
+	w = te.post(t, "/api/v1/sessions/s1/tags", `{"tag":"bug","ordinal":2}`)
+	assertStatus(t, w, http.StatusCreated)
+
+	w = te.get(t, "/api/v1/sessions/s1/tags")
+	assertStatus(t, w, http.StatusOK)
+	list := decode[struct {
+		Tags []db.Tag `json:"tags"`
+	}](t, w)
+	if len(list.Tags) != 2 {
+		t.Fatalf("tags = %+v, want 2", list.Tags)
+	}
+
+	w = te.get(t, "/api/v1/sessions?tag=keeper")
+	assertStatus(t, w, http.StatusOK)
+	page := decode[struct {
+		Sessions []struct {
+			ID   string   `json:"id"`
+			Tags []string `json:"tags"`
+		} `json:"sessions"`
+	}](t, w)
+	if len(page.Sessions) != 1 || page.Sessions[0].ID != "s1" ||
+		len(page.Sessions[0].Tags) != 1 {
+		t.Errorf("tag filter = %+v, want s1 tagged keeper", page.Sessions)
+	}
+
+	w = te.del(t, "/api/v1/sessions/s1/tags/bug")
+	assertStatus(t, w, http.StatusNotFound)
+	w = te.del(t, "/api/v1/sessions/s1/tags/bug?ordinal=2")
+	assertStatus(t, w, http.StatusNoContent)
+
+	for _, tc := range []struct {
+		name, path, body string
+		want             int
+	}{
+		{"UnknownSession", "/api/v1/sessions/nope/tags", `{"tag":"x"}`, http.StatusNotFound},
+		{"EmptyTag", "/api/v1/sessions/s1/tags", `{"tag":" "}`, http.StatusBadRequest},
+		{"Whitespace", "/api/v1/sessions/s1/tags", `{"tag":"a b"}`, http.StatusBadRequest},
+		{"NegativeOrdinal", "/api/v1/sessions/s1/tags", `{"tag":"x","ordinal":-1}`, http.StatusBadRequest},
+		{"BadJSON", "/api/v1/sessions/s1/tags", `{`, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assertStatus(t, te.post(t, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestSessionStars(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "proj", 4)
+	te.seedSession(t, "s2", "proj", 4)
+
+	assertStatus(t, te.put(t, "/api/v1/sessions/s1/star", ""), http.StatusOK)
+	assertStatus(t, te.put(t, "/api/v1/sessions/s2/star?ordinal=1", ""), http.StatusOK)
+	assertStatus(t, te.put(t, "/api/v1/sessions/s2/star?ordinal=x", ""), http.StatusBadRequest)
+	assertStatus(t, te.put(t, "/api/v1/sessions/nope/star", ""), http.StatusNotFound)
+
+	w := te.get(t, "/api/v1/sessions?starred=true")
+	assertStatus(t, w, http.StatusOK)
+	page := decode[struct {
+		Sessions []struct {
+			ID      string `json:"id"`
+			Starred bool   `json:"starred"`
+		} `json:"sessions"`
+	}](t, w)
+	if len(page.Sessions) != 1 || page.Sessions[0].ID != "s1" ||
+		!page.Sessions[0].Starred {
+		t.Errorf("starred filter = %+v, want [s1]", page.Sessions)
+	}
+	assertStatus(t, te.get(t, "/api/v1/sessions?starred=maybe"), http.StatusBadRequest)
+
+	w = te.get(t, "/api/v1/sessions/s2/stars")
+	assertStatus(t, w, http.StatusOK)
+	stars := decode[struct {
+		Stars []db.Star `json:"stars"`
+	}](t, w)
+	if len(stars.Stars) != 1 || stars.Stars[0].Ordinal == nil ||
+		*stars.Stars[0].Ordinal != 1 {
+		t.Errorf("stars = %+v", stars.Stars)
+	}
+
+	assertStatus(t, te.del(t, "/api/v1/sessions/s1/star"), http.StatusNoContent)
+	assertStatus(t, te.del(t, "/api/v1/sessions/s1/star"), http.StatusNotFound)
+}
+
+func TestSessionNotes(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "proj", 4)
+	te.seedSession(t, "s2", "proj", 4)
+
+	w := te.post(t, "/api/v1/sessions/s1/notes", `{"body":"look here","ordinal":3}`)
+	assertStatus(t, w, http.StatusCreated)
+	note := decode[db.Note](t, w)
+	if note.ID == 0 || note.Ordinal == nil || *note.Ordinal != 3 {
+		t.Fatalf("created note = %+v", note)
+	}
+	notePath := fmt.Sprintf("/api/v1/sessions/s1/notes/%d", note.ID)
+
+	w = te.put(t, notePath, `{"body":"edited"}`)
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[db.Note](t, w); got.Body != "edited" {
+		t.Errorf("updated note = %+v", got)
+	}
+
+	w = te.get(t, "/api/v1/sessions/s1/notes")
+	assertStatus(t, w, http.StatusOK)
+	list := decode[struct {
+		Notes []db.Note `json:"notes"`
+	}](t, w)
+	if len(list.Notes) != 1 || list.Notes[0].Body != "edited" {
+		t.Errorf("notes = %+v", list.Notes)
+	}
+
+	otherPath := fmt.Sprintf("/api/v1/sessions/s2/notes/%d", note.ID)
+	assertStatus(t, te.put(t, otherPath, `{"body":"x"}`), http.StatusNotFound)
+	assertStatus(t, te.del(t, otherPath), http.StatusNotFound)
+	assertStatus(t, te.put(t, notePath, `{"body":""}`), http.StatusBadRequest)
+	assertStatus(t, te.put(t, "/api/v1/sessions/s1/notes/abc", `{"body":"x"}`), http.StatusBadRequest)
+	assertStatus(t, te.post(t, "/api/v1/sessions/nope/notes", `{"body":"x"}`), http.StatusNotFound)
+
+	assertStatus(t, te.del(t, notePath), http.StatusNoContent)
+	assertStatus(t, te.del(t, notePath), http.StatusNotFound)
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/wesm/agentsview/internal/config"
+)
+
+// Principal is an authenticated caller and the scopes it holds.
+type Principal struct {
+	Name   string
+	Scopes []string
+}
+
+// Authenticator identifies the caller of a request. It returns
+// nil and no error when the request carries no credentials it
+// understands, so the next authenticator can try, and an error
+// when it carries credentials that are invalid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// WithAuthenticator adds an authenticator, turning on
+// authentication of every request.
+func WithAuthenticator(a Authenticator) Option {
+	return func(s *Server) { s.auth = append(s.auth, a) }
+}
+
+var errInvalidToken = errors.New("invalid token")
+
+// tokenCheckInterval bounds how often the config file is
+// checked for token changes.
+const tokenCheckInterval = 5 * time.Second
+
+// tokenAuth accepts the configured static tokens, either as a
+// bearer token or as the password of HTTP basic auth, which
+// lets browsers log in through their own prompt. The basic auth
+// user name is ignored. Tokens are re-read when the config file
+// changes, so created and revoked tokens take effect without a
+// restart; a file that fails to load keeps the previous tokens.
+type tokenAuth struct {
+	cfg        *config.Config
+	checkEvery time.Duration
+
+	mu        gosync.Mutex
+	tokens    []config.AuthToken
+	stamp     string
+	lastCheck time.Time
+}
+
+func newTokenAuth(cfg *config.Config) *tokenAuth {
+	a := &tokenAuth{
+		cfg:        cfg,
+		checkEvery: tokenCheckInterval,
+		tokens:     cfg.Auth.Tokens,
+	}
+	a.stamp = a.fileStamp()
+	return a
+}
+
+// fileStamp identifies the current version of the config file,
+// or is empty if it can't be read.
+func (a *tokenAuth) fileStamp() string {
+	info, err := os.Stat(a.cfg.ConfigPath())
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// currentTokens returns the tokens, reloading them if the config
+// file changed since the last check.
+func (a *tokenAuth) currentTokens() []config.AuthToken {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if time.Since(a.lastCheck) < a.checkEvery {
+		return a.tokens
+	}
+	a.lastCheck = time.Now()
+	stamp := a.fileStamp()
+	if stamp == "" || stamp == a.stamp {
+		return a.tokens
+	}
+	tokens, err := a.cfg.ReadAuthTokens()
+	if err != nil {
+		log.Printf("keeping previous API tokens: %v", err)
+		return a.tokens
+	}
+	a.tokens = tokens
+	a.stamp = stamp
+	return a.tokens
+}
+
+func (a *tokenAuth) Authenticate(r *http.Request) (*Principal, error) {
+	var secret string
+	if _, pass, ok := r.BasicAuth(); ok {
+		secret = pass
+	} else if bearer, ok := strings.CutPrefix(
+		r.Header.Get("Authorization"), "Bearer ",
+	); ok {
+		secret = strings.TrimSpace(bearer)
+	} else {
+		return nil, nil
+	}
+
+	hash := []byte(config.HashToken(secret))
+	for _, t := range a.currentTokens() {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+			return &Principal{Name: t.Name, Scopes: t.Scopes}, nil
+		}
+	}
+	return nil, errInvalidToken
+}
+
+// proxyAuth trusts a user header set by a reverse proxy that
+// has authenticated the user, when the request comes from one
+// of the trusted networks or over the server's Unix domain
+// socket, whose file permissions decide who can connect.
+type proxyAuth struct {
+	header  string
+	trusted []*net.IPNet
+	scopes  []string
+}
+
+// newProxyAuth builds a proxyAuth from the config, which was
+// validated when loaded.
+func newProxyAuth(c *config.ProxyAuth) (*proxyAuth, error) {
+	cidrs := c.Trusted
+	if len(cidrs) == 0 {
+		cidrs = []string{"127.0.0.0/8", "::1/128"}
+	}
+	a := &proxyAuth{header: c.Header, scopes: c.Scopes}
+	if len(a.scopes) == 0 {
+		a.scopes = []string{config.ScopeRead}
+	}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", cidr, err)
+		}
+		a.trusted = append(a.trusted, n)
+	}
+	return a, nil
+}
+
+func (a *proxyAuth) Authenticate(r *http.Request) (*Principal, error) {
+	user := strings.TrimSpace(r.Header.Get(a.header))
+	if user == "" || !a.fromTrusted(r) {
+		return nil, nil
+	}
+	return &Principal{Name: user, Scopes: a.scopes}, nil
+}
+
+func (a *proxyAuth) fromTrusted(r *http.Request) bool {
+	// Unix socket peers have no IP address to check.
+	if local, ok := r.Context().Value(
+		http.LocalAddrContextKey,
+	).(net.Addr); ok && local.Network() == "unix" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// configAuthenticators builds the authenticators the config
+// asks for. It returns none when authentication is off.
+func configAuthenticators(cfg *config.Config) ([]Authenticator, error) {
+	if !cfg.Auth.Enabled() {
+		return nil, nil
+	}
+	auths := []Authenticator{newTokenAuth(cfg)}
+	if cfg.Auth.Proxy != nil {
+		p, err := newProxyAuth(cfg.Auth.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, p)
+	}
+	return auths, nil
+}
+
+// requiredScope returns the scope a request needs: upload for
+// the upload and trace ingestion endpoints, read for any other
+// GET, write for changes to a session's tags, star and notes,
+// and admin for everything else, such as resyncs, deletes and
+// insight generation.
+func requiredScope(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodPost &&
+		(r.URL.Path == "/api/v1/sessions/upload" ||
+			r.URL.Path == "/v1/traces"):
+		return config.ScopeUpload
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return config.ScopeRead
+	case isAnnotationPath(r.URL.Path):
+		return config.ScopeWrite
+	default:
+		return config.ScopeAdmin
+	}
+}
+
+// isAnnotationPath reports whether path is under a session's
+// tags, star or notes.
+func isAnnotationPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/api/v1/sessions/")
+	if !ok {
+		return false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) < 2 || parts[0] == "" {
+		return false
+	}
+	switch parts[1] {
+	case "tags", "star", "notes":
+		return true
+	}
+	return false
+}
+
+// authenticate returns the first principal an authenticator
+// finds, or an error if any rejects the request's credentials.
+func (s *Server) authenticate(r *http.Request) (*Principal, error) {
+	for _, a := range s.auth {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// authMiddleware rejects requests that are unauthenticated or
+// lack the scope their endpoint needs. It passes everything
+// through when no authenticators are configured.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	if len(s.auth) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := s.authenticate(r)
+		if err != nil || p == nil {
+			msg := "authentication required"
+			if err != nil {
+				msg = err.Error()
+			}
+			w.Header().Set(
+				"WWW-Authenticate", `Basic realm="agentsview"`,
+			)
+			writeError(w, http.StatusUnauthorized, msg)
+			return
+		}
+		scope := requiredScope(r)
+		if !config.HasScope(p.Scopes, scope) {
+			writeError(w, http.StatusForbidden,
+				fmt.Sprintf("%s scope required", scope))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/config"
+)
+
+func TestTokenAuth_ReloadsOnChange(t *testing.T) {
+	cfg := &config.Config{DataDir: t.TempDir()}
+	first, firstSecret, err := config.NewAuthToken("a", []string{"read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SaveAuthTokens([]config.AuthToken{first}); err != nil {
+		t.Fatal(err)
+	}
+
+	a := newTokenAuth(cfg)
+	a.checkEvery = 0
+	authenticate := func(secret string) error {
+		t.Helper()
+		r := httptest.NewRequest("GET", "/api/v1/stats", nil)
+		r.Header.Set("Authorization", "Bearer "+secret)
+		_, err := a.Authenticate(r)
+		return err
+	}
+	if err := authenticate(firstSecret); err != nil {
+		t.Fatalf("configured token: %v", err)
+	}
+
+	// A token created after startup is accepted, and a revoked
+	// one is refused.
+	second, secondSecret, err := config.NewAuthToken("b", []string{"read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SaveAuthTokens([]config.AuthToken{second}); err != nil {
+		t.Fatal(err)
+	}
+	if err := authenticate(secondSecret); err != nil {
+		t.Errorf("created token: %v", err)
+	}
+	if err := authenticate(firstSecret); err == nil {
+		t.Error("revoked token still accepted")
+	}
+}
@@ -0,0 +1,180 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/server"
+)
+
+// withTokens turns on auth with a token per scope list, and
+// returns the tokens in the same order.
+func withTokens(
+	t *testing.T, scopes ...[]string,
+) (setupOption, []string) {
+	t.Helper()
+	var (
+		records []config.AuthToken
+		secrets []string
+	)
+	for _, s := range scopes {
+		rec, secret, err := config.NewAuthToken("t", s)
+		if err != nil {
+			t.Fatalf("NewAuthToken: %v", err)
+		}
+		records = append(records, rec)
+		secrets = append(secrets, secret)
+	}
+	return func(c *config.Config) {
+		c.Auth.Required = true
+		c.Auth.Tokens = records
+	}, secrets
+}
+
+func (te *testEnv) do(
+	t *testing.T, method, path string, mod func(*http.Request),
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	if mod != nil {
+		mod(req)
+	}
+	w := httptest.NewRecorder()
+	te.handler.ServeHTTP(w, req)
+	return w
+}
+
+func bearer(token string) func(*http.Request) {
+	return func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func TestAuth_Token(t *testing.T) {
+	opt, tokens := withTokens(t, []string{config.ScopeRead})
+	te := setup(t, opt)
+
+	w := te.get(t, "/api/v1/stats")
+	assertStatus(t, w, http.StatusUnauthorized)
+	assertErrorResponse(t, w, "authentication required")
+	if got := w.Header().Get("WWW-Authenticate"); got == "" {
+		t.Error("missing WWW-Authenticate header")
+	}
+
+	w = te.do(t, http.MethodGet, "/api/v1/stats", bearer("avt_wrong"))
+	assertStatus(t, w, http.StatusUnauthorized)
+	assertErrorResponse(t, w, "invalid token")
+
+	w = te.do(t, http.MethodGet, "/api/v1/stats", bearer(tokens[0]))
+	assertStatus(t, w, http.StatusOK)
+
+	// Basic auth takes the token as its password.
+	w = te.do(t, http.MethodGet, "/api/v1/stats",
+		func(r *http.Request) { r.SetBasicAuth("me", tokens[0]) })
+	assertStatus(t, w, http.StatusOK)
+
+	// Preflights are answered without credentials.
+	w = te.do(t, http.MethodOptions, "/api/v1/stats", nil)
+	assertStatus(t, w, http.StatusNoContent)
+}
+
+func TestAuth_Scopes(t *testing.T) {
+	opt, tokens := withTokens(t,
+		[]string{config.ScopeRead},
+		[]string{config.ScopeUpload},
+		[]string{config.ScopeAdmin},
+		[]string{config.ScopeWrite},
+	)
+	te := setup(t, opt)
+	read, upload, admin, write := tokens[0], tokens[1], tokens[2], tokens[3]
+	te.seedSession(t, "s1", "my-app", 1)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"ReadGet", http.MethodGet, "/api/v1/sessions", read, http.StatusOK},
+		{"ReadUpload", http.MethodPost, "/api/v1/sessions/upload", read, http.StatusForbidden},
+		{"ReadResync", http.MethodPost, "/api/v1/resync", read, http.StatusForbidden},
+		{"UploadGet", http.MethodGet, "/api/v1/sessions", upload, http.StatusForbidden},
+		{"UploadUpload", http.MethodPost, "/api/v1/sessions/upload", upload, http.StatusBadRequest},
+		{"UploadDelete", http.MethodDelete, "/api/v1/insights/1", upload, http.StatusForbidden},
+		{"AdminGet", http.MethodGet, "/api/v1/sessions", admin, http.StatusOK},
+		{"AdminUpload", http.MethodPost, "/api/v1/sessions/upload", admin, http.StatusBadRequest},
+		{"AdminDelete", http.MethodDelete, "/api/v1/insights/1", admin, http.StatusNotFound},
+		{"ReadStar", http.MethodPut, "/api/v1/sessions/s1/star", read, http.StatusForbidden},
+		{"WriteStar", http.MethodPut, "/api/v1/sessions/s1/star", write, http.StatusOK},
+		{"WriteTagDelete", http.MethodDelete, "/api/v1/sessions/s1/tags/x", write, http.StatusNotFound},
+		{"WriteNoteDelete", http.MethodDelete, "/api/v1/sessions/s1/notes/1", write, http.StatusNotFound},
+		{"WriteSessionDelete", http.MethodDelete, "/api/v1/sessions/s1", write, http.StatusForbidden},
+		{"WriteResync", http.MethodPost, "/api/v1/resync", write, http.StatusForbidden},
+		{"UploadStar", http.MethodPut, "/api/v1/sessions/s1/star", upload, http.StatusForbidden},
+		{"AdminStar", http.MethodPut, "/api/v1/sessions/s1/star", admin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := te.do(t, tt.method, tt.path, bearer(tt.token))
+			assertStatus(t, w, tt.want)
+		})
+	}
+}
+
+func TestAuth_Proxy(t *testing.T) {
+	te := setup(t, func(c *config.Config) {
+		c.Auth.Proxy = &config.ProxyAuth{
+			Header:  "X-Forwarded-User",
+			Trusted: []string{"10.0.0.0/8"},
+		}
+	})
+
+	proxied := func(addr string) func(*http.Request) {
+		return func(r *http.Request) {
+			r.RemoteAddr = addr
+			r.Header.Set("X-Forwarded-User", "alice")
+		}
+	}
+	w := te.do(t, http.MethodGet, "/api/v1/stats", proxied("10.1.2.3:5000"))
+	assertStatus(t, w, http.StatusOK)
+
+	w = te.do(t, http.MethodGet, "/api/v1/stats", proxied("192.0.2.1:5000"))
+	assertStatus(t, w, http.StatusUnauthorized)
+
+	// Proxy users get read access unless configured otherwise.
+	w = te.do(t, http.MethodPost, "/api/v1/resync", proxied("10.1.2.3:5000"))
+	assertStatus(t, w, http.StatusForbidden)
+}
+
+func TestNew_InvalidAuthConfig(t *testing.T) {
+	cfg := config.Config{DataDir: t.TempDir()}
+	cfg.Auth.Proxy = &config.ProxyAuth{
+		Header:  "X-Forwarded-User",
+		Trusted: []string{"10.0.0.0"},
+	}
+	if _, err := server.New(cfg, nil, nil); err == nil {
+		t.Fatal("New with a bad trusted network succeeded")
+	}
+}
+
+// staticAuth authenticates every request as one principal.
+type staticAuth struct{ p server.Principal }
+
+func (a staticAuth) Authenticate(*http.Request) (*server.Principal, error) {
+	return &a.p, nil
+}
+
+func TestAuth_CustomAuthenticator(t *testing.T) {
+	te := setupWithServerOpts(t, []server.Option{
+		server.WithAuthenticator(staticAuth{server.Principal{
+			Name: "ci", Scopes: []string{config.ScopeUpload},
+		}}),
+	})
+
+	assertStatus(t, te.get(t, "/api/v1/stats"), http.StatusForbidden)
+	w := te.do(t, http.MethodPost, "/api/v1/sessions/upload", nil)
+	assertStatus(t, w, http.StatusBadRequest)
+}
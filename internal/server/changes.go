@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/wesm/agentsview/internal/changes"
+	"github.com/wesm/agentsview/internal/db"
+)
+
+type changesResponse struct {
+	Files   []changes.File `json:"files"`
+	Added   int            `json:"added"`
+	Removed int            `json:"removed"`
+}
+
+// changeCalls returns the tool calls of msgs in session order.
+func changeCalls(msgs []db.Message) []changes.Call {
+	var calls []changes.Call
+	for _, m := range msgs {
+		for _, tc := range m.ToolCalls {
+			calls = append(calls, changes.Call{
+				Ordinal:   m.Ordinal,
+				ToolName:  tc.ToolName,
+				InputJSON: tc.InputJSON,
+				Failed:    tc.IsError != nil && *tc.IsError,
+			})
+		}
+	}
+	return calls
+}
+
+func (s *Server) handleListChanges(
+	w http.ResponseWriter, r *http.Request,
+) {
+	withDiff, ok := parseBoolParamDefault(w, r, "diff", true)
+	if !ok {
+		return
+	}
+	_, msgs, ok := s.getSessionWithMessages(w, r)
+	if !ok {
+		return
+	}
+
+	resp := changesResponse{Files: changes.Compute(changeCalls(msgs))}
+	for i := range resp.Files {
+		resp.Added += resp.Files[i].Added
+		resp.Removed += resp.Files[i].Removed
+		if !withDiff {
+			resp.Files[i].Diff = ""
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,71 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+func TestListChanges(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 3)
+	failed := true
+	te.seedMessages(t, "s1", 3, func(i int, m *db.Message) {
+		tc := db.ToolCall{SessionID: "s1", ToolName: "Edit", Category: "Edit"}
+		switch i {
+		case 0:
+			tc.InputJSON = `{"file_path":"main.go",` +
+				`"old_string":"a\nb","new_string":"a\nc\nd"}`
+		case 1:
+			tc.ToolName, tc.Category = "Write", "Write"
+			tc.InputJSON = `{"file_path":"notes.md","content":"hi\n"}`
+		case 2:
+			tc.InputJSON = `{"file_path":"broken.go",` +
+				`"old_string":"x","new_string":"y"}`
+			tc.IsError = &failed
+		}
+		m.ToolCalls = []db.ToolCall{tc}
+	})
+
+	w := te.get(t, "/api/v1/sessions/s1/changes")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		Files []struct {
+			Path     string `json:"path"`
+			Status   string `json:"status"`
+			Added    int    `json:"added"`
+			Removed  int    `json:"removed"`
+			Ordinals []int  `json:"ordinals"`
+			Diff     string `json:"diff"`
+		} `json:"files"`
+		Added   int `json:"added"`
+		Removed int `json:"removed"`
+	}](t, w)
+	if len(resp.Files) != 2 || resp.Added != 3 || resp.Removed != 1 {
+		t.Fatalf("changes = %+v", resp)
+	}
+	f := resp.Files[0]
+	want := "--- a/main.go\n+++ b/main.go\n" +
+		"@@ -1,2 +1,3 @@\n a\n-b\n+c\n+d\n"
+	if f.Path != "main.go" || f.Status != "modified" ||
+		f.Added != 2 || f.Removed != 1 || f.Diff != want {
+		t.Errorf("main.go = %+v", f)
+	}
+	if f := resp.Files[1]; f.Path != "notes.md" || f.Status != "added" ||
+		len(f.Ordinals) != 1 || f.Ordinals[0] != 1 {
+		t.Errorf("notes.md = %+v", f)
+	}
+
+	w = te.get(t, "/api/v1/sessions/s1/changes?diff=false")
+	assertStatus(t, w, http.StatusOK)
+	if strings.Contains(w.Body.String(), "@@") {
+		t.Errorf("diff=false response has diffs: %s", w.Body.String())
+	}
+
+	assertStatus(t, te.get(t, "/api/v1/sessions/s1/changes?diff=x"),
+		http.StatusBadRequest)
+	assertStatus(t, te.get(t, "/api/v1/sessions/nope/changes"),
+		http.StatusNotFound)
+}
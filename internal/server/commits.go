@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// sessionResponse is a session with the git commits linked to
+// it.
+type sessionResponse struct {
+	*db.Session
+	Commits []db.SessionCommit `json:"commits"`
+}
+
+// commitSHA matches full and abbreviated commit SHAs.
+var commitSHA = regexp.MustCompile(`^[0-9a-fA-F]{4,64}$`)
+
+func (s *Server) handleCommitSessions(
+	w http.ResponseWriter, r *http.Request,
+) {
+	sha := r.PathValue("sha")
+	if !commitSHA.MatchString(sha) {
+		writeError(w, http.StatusBadRequest,
+			"sha must be 4 to 64 hex digits")
+		return
+	}
+
+	sessions, err := s.db.CommitSessions(r.Context(), sha)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sha":      sha,
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
@@ -0,0 +1,66 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+func TestSessionCommitsEndpoints(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "app", 2)
+	te.seedSession(t, "s2", "app", 2)
+	commit := db.SessionCommit{
+		SHA:        "abc123def4567890abc123def4567890abc123de",
+		RepoRoot:   "/src/app",
+		Author:     "Dev",
+		Email:      "dev@example.com",
+		AuthoredAt: "2024-01-01T11:00:00Z",
+		Subject:    "Fix sessions",
+		Files:      []string{"internal/db/sessions.go"},
+	}
+	if err := te.db.SaveSessionCommits(
+		"s1", nil, true, []db.SessionCommit{commit},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	w := te.get(t, "/api/v1/sessions/s1")
+	assertStatus(t, w, http.StatusOK)
+	sess := decode[struct {
+		ID      string             `json:"id"`
+		Project string             `json:"project"`
+		Commits []db.SessionCommit `json:"commits"`
+	}](t, w)
+	if sess.ID != "s1" || sess.Project != "app" {
+		t.Errorf("session = %+v", sess)
+	}
+	if len(sess.Commits) != 1 || sess.Commits[0].SHA != commit.SHA ||
+		sess.Commits[0].Files[0] != "internal/db/sessions.go" {
+		t.Errorf("commits = %+v", sess.Commits)
+	}
+
+	w = te.get(t, "/api/v1/sessions/s2")
+	assertStatus(t, w, http.StatusOK)
+	assertBodyContains(t, w, `"commits":[]`)
+
+	w = te.get(t, "/api/v1/commits/ABC123D")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		SHA      string             `json:"sha"`
+		Sessions []db.CommitSession `json:"sessions"`
+		Count    int                `json:"count"`
+	}](t, w)
+	if resp.Count != 1 || resp.Sessions[0].SessionID != "s1" ||
+		resp.Sessions[0].SHA != commit.SHA {
+		t.Errorf("commit sessions = %+v", resp)
+	}
+
+	w = te.get(t, "/api/v1/commits/fff0")
+	assertStatus(t, w, http.StatusOK)
+	assertBodyContains(t, w, `"count":0`)
+
+	assertStatus(t, te.get(t, "/api/v1/commits/abc"), http.StatusBadRequest)
+	assertStatus(t, te.get(t, "/api/v1/commits/xyz123"), http.StatusBadRequest)
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"html/template"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/secrets"
 )
 
 // getSessionWithMessages fetches a session and its messages by ID,
@@ -22,18 +24,13 @@ import (
 func (s *Server) getSessionWithMessages(
 	w http.ResponseWriter, r *http.Request,
 ) (*db.Session, []db.Message, bool) {
-	id := r.PathValue("id")
-	session, err := s.db.GetSession(r.Context(), id)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return nil, nil, false
-	}
-	if session == nil {
+	session, msgs, err := loadSessionWithMessages(
+		r.Context(), s.db, r.PathValue("id"),
+	)
+	if errors.Is(err, ErrSessionNotFound) {
 		writeError(w, http.StatusNotFound, "session not found")
 		return nil, nil, false
 	}
-
-	msgs, err := s.db.GetAllMessages(r.Context(), id)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return nil, nil, false
@@ -41,25 +38,103 @@ func (s *Server) getSessionWithMessages(
 	return session, msgs, true
 }
 
+// ErrSessionNotFound is returned by ExportSession for an
+// unknown session ID.
+var ErrSessionNotFound = errors.New("session not found")
+
+func loadSessionWithMessages(
+	ctx context.Context, database *db.DB, id string,
+) (*db.Session, []db.Message, error) {
+	session, err := database.GetSession(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session == nil {
+		return nil, nil, ErrSessionNotFound
+	}
+	msgs, err := database.GetAllMessages(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, msgs, nil
+}
+
+// ExportSession renders session id in the named format (see
+// ExportFormats) and returns the content with a suggested
+// filename. Secrets found by redact are replaced with redaction
+// markers; a nil scanner exports the session as stored. The CLI
+// export command uses it to produce the same output as the
+// export endpoint.
+func ExportSession(
+	ctx context.Context, database *db.DB, id, format string,
+	redact *secrets.Scanner,
+) (content []byte, filename string, err error) {
+	f, ok := lookupExportFormat(format)
+	if !ok {
+		return nil, "", fmt.Errorf(
+			"unknown export format %q (want %s)",
+			format, strings.Join(ExportFormats(), ", "),
+		)
+	}
+	session, msgs, err := loadSessionWithMessages(ctx, database, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if redact != nil {
+		session = redactSession(redact, session)
+		msgs = redactMessages(redact, msgs)
+	}
+	content, err = f.render(session, msgs)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, exportFilename(session, f.ext), nil
+}
+
 func (s *Server) handleExportSession(
 	w http.ResponseWriter, r *http.Request,
 ) {
+	f, ok := lookupExportFormat(r.URL.Query().Get("format"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf(
+			"invalid format: want %s",
+			strings.Join(ExportFormats(), ", "),
+		))
+		return
+	}
+	redact, ok := parseRedactParam(w, r)
+	if !ok {
+		return
+	}
 	session, msgs, ok := s.getSessionWithMessages(w, r)
 	if !ok {
 		return
 	}
+	if redact {
+		session = redactSession(s.secrets, session)
+		msgs = redactMessages(s.secrets, msgs)
+	}
 
-	htmlContent := generateExportHTML(session, msgs)
-	filename := sanitizeFilename(
-		session.Project + "-" + formatDateShort(session.StartedAt) + ".html",
-	)
+	content, err := f.render(session, msgs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	filename := exportFilename(session, f.ext)
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Type", f.contentType)
 	w.Header().Set(
 		"Content-Disposition",
 		fmt.Sprintf(`attachment; filename="%s"`, filename),
 	)
-	_, _ = io.WriteString(w, htmlContent)
+	_, _ = w.Write(content)
+}
+
+func exportFilename(session *db.Session, ext string) string {
+	return sanitizeFilename(
+		session.Project + "-" + formatDateShort(session.StartedAt) +
+			"." + ext,
+	)
 }
 
 func (s *Server) handlePublishSession(
@@ -72,10 +147,18 @@ func (s *Server) handlePublishSession(
 		return
 	}
 
+	redact, ok := parseRedactParam(w, r)
+	if !ok {
+		return
+	}
 	session, msgs, ok := s.getSessionWithMessages(w, r)
 	if !ok {
 		return
 	}
+	if redact {
+		session = redactSession(s.secrets, session)
+		msgs = redactMessages(s.secrets, msgs)
+	}
 
 	htmlContent := generateExportHTML(session, msgs)
 	filename := session.Project + "-" +
@@ -528,11 +611,6 @@ footer a:hover { text-decoration: underline; }
 func generateExportHTML(
 	session *db.Session, msgs []db.Message,
 ) string {
-	agentDisplay := "Claude"
-	if session.Agent == "codex" {
-		agentDisplay = "Codex"
-	}
-
 	startedAt := ""
 	if session.StartedAt != nil {
 		startedAt = formatTimestamp(*session.StartedAt)
@@ -540,7 +618,7 @@ func generateExportHTML(
 
 	data := exportData{
 		Project:      session.Project,
-		Agent:        agentDisplay,
+		Agent:        exportAgentName(session.Agent),
 		MessageCount: session.MessageCount,
 		StartedAt:    startedAt,
 		Messages:     make([]exportMessage, len(msgs)),
@@ -572,6 +650,13 @@ func generateExportHTML(
 	return b.String()
 }
 
+func exportAgentName(agent string) string {
+	if agent == "codex" {
+		return "Codex"
+	}
+	return "Claude"
+}
+
 var (
 	codeBlockRe  = regexp.MustCompile("(?s)```(\\w*)\\n(.*?)```")
 	inlineCodeRe = regexp.MustCompile("`([^`]+)`")
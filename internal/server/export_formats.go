@@ -0,0 +1,219 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// exportFormat is one output format of the session export.
+type exportFormat struct {
+	ext         string
+	contentType string
+	render      func(*db.Session, []db.Message) ([]byte, error)
+}
+
+var exportFormats = map[string]exportFormat{
+	"html": {
+		ext:         "html",
+		contentType: "text/html; charset=utf-8",
+		render:      renderExportHTML,
+	},
+	"markdown": {
+		ext:         "md",
+		contentType: "text/markdown; charset=utf-8",
+		render:      renderExportMarkdown,
+	},
+	"json": {
+		ext:         "json",
+		contentType: "application/json",
+		render:      renderExportJSON,
+	},
+}
+
+// ExportFormats returns the names of the supported export
+// formats. "md" is accepted as an alias for "markdown", and an
+// empty name means "html".
+func ExportFormats() []string {
+	return []string{"html", "markdown", "json"}
+}
+
+func lookupExportFormat(name string) (exportFormat, bool) {
+	switch name {
+	case "":
+		name = "html"
+	case "md":
+		name = "markdown"
+	}
+	f, ok := exportFormats[name]
+	return f, ok
+}
+
+func renderExportHTML(
+	session *db.Session, msgs []db.Message,
+) ([]byte, error) {
+	return []byte(generateExportHTML(session, msgs)), nil
+}
+
+// exportJSON is the document written by the JSON export: the
+// session and its messages as the API returns them, with tool
+// call inputs and results inline.
+type exportJSON struct {
+	Session  *db.Session  `json:"session"`
+	Messages []db.Message `json:"messages"`
+}
+
+func renderExportJSON(
+	session *db.Session, msgs []db.Message,
+) ([]byte, error) {
+	if msgs == nil {
+		msgs = []db.Message{}
+	}
+	b, err := json.MarshalIndent(
+		exportJSON{Session: session, Messages: msgs}, "", "  ",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("encoding export: %w", err)
+	}
+	return append(b, '\n'), nil
+}
+
+func renderExportMarkdown(
+	session *db.Session, msgs []db.Message,
+) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", session.Project)
+	fmt.Fprintf(&b, "- **Agent:** %s\n", exportAgentName(session.Agent))
+	if session.StartedAt != nil {
+		fmt.Fprintf(&b, "- **Started:** %s\n",
+			formatTimestamp(*session.StartedAt))
+	}
+	fmt.Fprintf(&b, "- **Messages:** %d\n", session.MessageCount)
+	fmt.Fprintf(&b, "- **Session:** `%s`\n", session.ID)
+
+	for _, m := range msgs {
+		b.WriteString("\n---\n\n")
+		writeMarkdownMessage(&b, m)
+	}
+	return []byte(b.String()), nil
+}
+
+func writeMarkdownMessage(b *strings.Builder, m db.Message) {
+	role := m.Role
+	if role != "" {
+		role = strings.ToUpper(role[:1]) + role[1:]
+	}
+	fmt.Fprintf(b, "### %s", role)
+	if ts := formatTimestamp(m.Timestamp); ts != "" {
+		fmt.Fprintf(b, " · %s", ts)
+	}
+	b.WriteString("\n")
+
+	for _, seg := range splitThinking(m.Content) {
+		b.WriteString("\n")
+		if !seg.thinking {
+			b.WriteString(seg.text)
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString("> **Thinking**\n>\n")
+		for line := range strings.SplitSeq(seg.text, "\n") {
+			if line == "" {
+				b.WriteString(">\n")
+			} else {
+				b.WriteString("> " + line + "\n")
+			}
+		}
+	}
+
+	for _, tc := range m.ToolCalls {
+		b.WriteString("\n")
+		writeMarkdownToolCall(b, tc)
+	}
+}
+
+func writeMarkdownToolCall(b *strings.Builder, tc db.ToolCall) {
+	fmt.Fprintf(b, "**Tool: %s**", tc.ToolName)
+	if tc.IsError != nil {
+		if *tc.IsError {
+			b.WriteString(" · failed")
+		} else {
+			b.WriteString(" · ok")
+		}
+	}
+	if tc.ExitCode != nil {
+		fmt.Fprintf(b, " · exit %d", *tc.ExitCode)
+	}
+	b.WriteString("\n")
+	if tc.SubagentSessionID != "" {
+		fmt.Fprintf(b, "\nSubagent session: `%s`\n",
+			tc.SubagentSessionID)
+	}
+	if tc.InputJSON != "" {
+		input := tc.InputJSON
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, []byte(input), "", "  ") == nil {
+			input = pretty.String()
+		}
+		b.WriteString("\nInput:\n\n")
+		writeMarkdownFence(b, "json", input)
+	}
+	if tc.ResultContent != "" {
+		b.WriteString("\nResult:\n\n")
+		writeMarkdownFence(b, "", tc.ResultContent)
+	}
+}
+
+// writeMarkdownFence writes s as a fenced code block, using a
+// fence longer than any backtick run in s.
+func writeMarkdownFence(b *strings.Builder, lang, s string) {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	b.WriteString(fence + lang + "\n")
+	b.WriteString(strings.TrimSuffix(s, "\n"))
+	b.WriteString("\n" + fence + "\n")
+}
+
+// contentSegment is a run of message content that is either
+// thinking or ordinary text.
+type contentSegment struct {
+	thinking bool
+	text     string
+}
+
+// splitThinking separates the [Thinking] blocks in content from
+// the text around them, keeping their order.
+func splitThinking(content string) []contentSegment {
+	var segs []contentSegment
+	add := func(thinking bool, text string) {
+		text = strings.Trim(text, "\n")
+		if strings.TrimSpace(text) != "" {
+			segs = append(segs, contentSegment{thinking, text})
+		}
+	}
+	rest := content
+	for {
+		loc := thinkingRe.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			break
+		}
+		add(false, rest[:loc[0]])
+		add(true, rest[loc[2]:loc[3]])
+		// Resume after the thinking text, not the whole match:
+		// the match ends inside the next block's marker.
+		rest = rest[loc[3]:]
+	}
+	add(false, rest)
+	return segs
+}
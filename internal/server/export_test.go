@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"html/template"
 	"net/http"
@@ -494,6 +495,121 @@ func TestSanitizeFilename(t *testing.T) {
 	}
 }
 
+func TestRenderExportMarkdown(t *testing.T) {
+	t.Parallel()
+	session := testSession(func(s *db.Session) {
+		s.Project = "my-project"
+		s.MessageCount = 2
+	})
+	msgs := []db.Message{
+		{
+			Role: "user", Content: "Run the tests",
+			Timestamp: "2025-01-15T10:00:00Z",
+		},
+		{
+			Role:      "assistant",
+			Content:   "[Thinking]\nCheck the suite\n\nsecond line\n[Bash: go test]\n$ go test ./...",
+			Timestamp: "2025-01-15T10:00:05Z",
+			ToolCalls: []db.ToolCall{{
+				ToolName:      "Bash",
+				InputJSON:     `{"command":"go test ./..."}`,
+				ResultContent: "FAIL ```x```",
+				IsError:       dbtest.Ptr(true),
+				ExitCode:      dbtest.Ptr(1),
+			}},
+		},
+	}
+
+	out, err := renderExportMarkdown(session, msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContainsAll(t, string(out), []string{
+		"# my-project\n",
+		"- **Agent:** Claude\n",
+		"### User · 2025-01-15 10:00:00\n\nRun the tests\n",
+		"> **Thinking**\n>\n> Check the suite\n>\n> second line\n",
+		"\n[Bash: go test]\n$ go test ./...\n",
+		"**Tool: Bash** · failed · exit 1\n",
+		"```json\n{\n  \"command\": \"go test ./...\"\n}\n```\n",
+		"````\nFAIL ```x```\n````\n",
+	})
+}
+
+func TestSplitThinking(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		content string
+		want    []contentSegment
+	}{
+		{"Plain", "just text", []contentSegment{{false, "just text"}}},
+		{"Empty", "", nil},
+		{
+			"ThinkingThenText",
+			"[Thinking]\nhmm\n[Read: a.go]",
+			[]contentSegment{{true, "hmm"}, {false, "[Read: a.go]"}},
+		},
+		{
+			"AdjacentThinking",
+			"intro\n[Thinking]\none\n[Thinking]\ntwo",
+			[]contentSegment{
+				{false, "intro"}, {true, "one"}, {true, "two"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := splitThinking(tt.content)
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitThinking = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("segment %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRenderExportJSON(t *testing.T) {
+	t.Parallel()
+	out, err := renderExportJSON(testSession(), []db.Message{{
+		Role: "assistant", Content: "done",
+		ToolCalls: []db.ToolCall{{
+			ToolName: "Bash", ResultContent: "ok",
+			ExitCode: dbtest.Ptr(0),
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Session  db.Session   `json:"session"`
+		Messages []db.Message `json:"messages"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc.Session.ID != "test-id" || len(doc.Messages) != 1 {
+		t.Fatalf("decoded = %+v", doc)
+	}
+	tc := doc.Messages[0].ToolCalls[0]
+	if tc.ResultContent != "ok" || tc.ExitCode == nil || *tc.ExitCode != 0 {
+		t.Errorf("tool call = %+v", tc)
+	}
+
+	empty, err := renderExportJSON(testSession(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(empty), `"messages": []`) {
+		t.Errorf("empty export = %s", empty)
+	}
+}
+
 func TestTruncateStr(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
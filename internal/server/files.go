@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+func (s *Server) handleFileSessions(
+	w http.ResponseWriter, r *http.Request,
+) {
+	q := r.URL.Query()
+	path := strings.TrimPrefix(strings.TrimSpace(q.Get("path")), "./")
+	if path == "" {
+		writeError(w, http.StatusBadRequest, "path required")
+		return
+	}
+	limit, ok := parseIntParam(w, r, "limit")
+	if !ok {
+		return
+	}
+	limit = clampLimit(limit, db.DefaultFileLimit, db.MaxFileLimit)
+
+	sessions, err := s.db.FileSessions(
+		r.Context(), path, q.Get("project"), limit,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"path":     path,
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+func (s *Server) handleFileHotspots(
+	w http.ResponseWriter, r *http.Request,
+) {
+	limit, ok := parseIntParam(w, r, "limit")
+	if !ok {
+		return
+	}
+	limit = clampLimit(limit, 10, db.MaxFileLimit)
+
+	hotspots, err := s.db.FileHotspots(
+		r.Context(), r.URL.Query().Get("project"), limit,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"hotspots": hotspots,
+	})
+}
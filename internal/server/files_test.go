@@ -0,0 +1,82 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+func seedFileSession(t *testing.T, te *testEnv, id, project string) {
+	t.Helper()
+	te.seedSession(t, id, project, 2)
+	te.seedMessages(t, id, 2, func(i int, m *db.Message) {
+		action, tool := "read", "Read"
+		if i == 1 {
+			action, tool = "edit", "Edit"
+		}
+		m.ToolCalls = []db.ToolCall{{
+			SessionID: id, ToolName: tool, Category: tool,
+			InputJSON: `{}`,
+			Files: []db.ToolFile{{
+				Path: "internal/db/sessions.go", RepoRoot: "/src/app",
+				Action: action,
+			}},
+		}}
+	})
+}
+
+func TestFileSessionsEndpoint(t *testing.T) {
+	te := setup(t)
+	seedFileSession(t, te, "s1", "app")
+	seedFileSession(t, te, "s2", "other")
+
+	w := te.get(t, "/api/v1/files?path=./internal/db/sessions.go")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		Path     string           `json:"path"`
+		Sessions []db.FileSession `json:"sessions"`
+		Count    int              `json:"count"`
+	}](t, w)
+	if resp.Path != "internal/db/sessions.go" || resp.Count != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if s := resp.Sessions[0]; s.Reads != 1 || s.Edits != 1 {
+		t.Errorf("session = %+v", s)
+	}
+
+	w = te.get(t, "/api/v1/files?path=sessions.go&project=app")
+	assertStatus(t, w, http.StatusOK)
+	resp = decode[struct {
+		Path     string           `json:"path"`
+		Sessions []db.FileSession `json:"sessions"`
+		Count    int              `json:"count"`
+	}](t, w)
+	if resp.Count != 1 || resp.Sessions[0].SessionID != "s1" {
+		t.Errorf("project filter = %+v", resp)
+	}
+
+	assertStatus(t, te.get(t, "/api/v1/files"), http.StatusBadRequest)
+	assertStatus(t, te.get(t, "/api/v1/files?path=a&limit=x"),
+		http.StatusBadRequest)
+}
+
+func TestFileHotspotsEndpoint(t *testing.T) {
+	te := setup(t)
+	seedFileSession(t, te, "s1", "app")
+	seedFileSession(t, te, "s2", "app")
+
+	w := te.get(t, "/api/v1/files/hotspots?project=app")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		Hotspots []db.FileHotspot `json:"hotspots"`
+	}](t, w)
+	if len(resp.Hotspots) != 1 {
+		t.Fatalf("hotspots = %+v", resp.Hotspots)
+	}
+	h := resp.Hotspots[0]
+	if h.Path != "internal/db/sessions.go" || h.Changes != 2 ||
+		h.Sessions != 2 || h.LastSessionID == "" {
+		t.Errorf("hotspot = %+v", h)
+	}
+}
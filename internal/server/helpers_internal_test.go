@@ -40,8 +40,12 @@ func testServer(
 		DBPath:       dbPath,
 		WriteTimeout: writeTimeout,
 	}
-	engine := sync.NewEngine(database, []string{dir}, nil, nil, nil, nil, "test")
-	return New(cfg, database, engine, opts...)
+	engine := sync.NewEngine(database, []string{dir}, nil, nil, nil, nil, nil, nil, nil, nil, nil, "test")
+	s, err := New(cfg, database, engine, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
 }
 
 // withHandlerDelay injects a sleep before each timeout-wrapped
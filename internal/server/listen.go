@@ -0,0 +1,151 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Listen opens the listener the config asks for: a Unix domain
+// socket when Socket is set, otherwise TCP on Host and Port,
+// wrapped in TLS when a certificate is configured or
+// self-signing is on.
+func (s *Server) Listen() (net.Listener, error) {
+	s.mu.RLock()
+	cfg := s.cfg
+	s.mu.RUnlock()
+
+	var tlsCfg *tls.Config
+	if cfg.TLSEnabled() {
+		certFile, keyFile := cfg.TLSCert, cfg.TLSKey
+		if certFile == "" {
+			var err error
+			certFile, keyFile, err = ensureSelfSignedCert(
+				cfg.TLSDir(), selfSignedHosts(cfg.Host),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("self-signed certificate: %w", err)
+			}
+		}
+		certs, err := newCertReloader(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg = &tls.Config{
+			GetCertificate: certs.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+	}
+
+	var (
+		ln  net.Listener
+		err error
+	)
+	if cfg.Socket != "" {
+		perm, perr := cfg.SocketPerm()
+		if perr != nil {
+			return nil, perr
+		}
+		ln, err = listenUnix(cfg.Socket, perm)
+	} else {
+		ln, err = net.Listen("tcp", net.JoinHostPort(
+			cfg.Host, strconv.Itoa(cfg.Port),
+		))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		ln = tls.NewListener(ln, tlsCfg)
+	}
+	return ln, nil
+}
+
+// URL returns the address a browser on this machine can reach
+// the server at, or "" when it listens on a Unix domain socket.
+func (s *Server) URL() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.Socket != "" {
+		return ""
+	}
+	scheme := "http"
+	if s.cfg.TLSEnabled() {
+		scheme = "https"
+	}
+	host := s.cfg.Host
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return scheme + "://" + net.JoinHostPort(
+		host, strconv.Itoa(s.cfg.Port),
+	)
+}
+
+// Addr describes where the server listens, for logging.
+func (s *Server) Addr() string {
+	if u := s.URL(); u != "" {
+		return u
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return "unix:" + s.cfg.Socket
+}
+
+// listenUnix listens on a Unix domain socket at path, replacing
+// a socket left behind by a server that is gone, and sets the
+// socket file's permissions.
+func listenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// removeStaleSocket removes the socket at path unless a server
+// still accepts connections on it. Other files are left alone.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	return os.Remove(path)
+}
+
+// selfSignedHosts returns the names a self-signed certificate
+// should cover: loopback, this machine's host name, and the
+// host the server binds to.
+func selfSignedHosts(bind string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hosts = append(hosts, name)
+	}
+	if bind != "" && bind != "localhost" &&
+		!net.ParseIP(bind).IsUnspecified() &&
+		!net.ParseIP(bind).IsLoopback() {
+		hosts = append(hosts, bind)
+	}
+	return hosts
+}
@@ -0,0 +1,266 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/server"
+)
+
+// serve starts te's server on the configured listener and stops
+// it when the test ends.
+func (te *testEnv) serve(t *testing.T) {
+	t.Helper()
+	ln, err := te.srv.Listen()
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- te.srv.Serve(ln) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(
+			context.Background(), 5*time.Second,
+		)
+		defer cancel()
+		te.srv.Shutdown(ctx)
+		ln.Close()
+		if err := <-done; err != nil && err != http.ErrServerClosed &&
+			!strings.Contains(err.Error(), "closed") {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+}
+
+// socketPath returns a socket path short enough for the OS
+// limit, which test temp dirs can exceed.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix socket permissions are not supported on Windows")
+	}
+	dir, err := os.MkdirTemp("", "av")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "av.sock")
+}
+
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+func TestListen_UnixSocket(t *testing.T) {
+	path := socketPath(t)
+
+	// A socket left behind by a server that is gone is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	te := setup(t, func(c *config.Config) {
+		c.Socket = path
+		c.SocketMode = "0660"
+	})
+	te.serve(t)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o660 {
+		t.Errorf("socket mode = %o, want 660", perm)
+	}
+	if u := te.srv.URL(); u != "" {
+		t.Errorf("URL = %q, want none for a socket", u)
+	}
+
+	resp, err := unixClient(path).Get("http://agentsview/api/v1/stats")
+	if err != nil {
+		t.Fatalf("GET over socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d", resp.StatusCode)
+	}
+
+	// A live socket is not taken over.
+	other := setup(t, func(c *config.Config) { c.Socket = path })
+	if _, err := other.srv.Listen(); err == nil ||
+		!strings.Contains(err.Error(), "in use") {
+		t.Errorf("Listen on live socket: %v", err)
+	}
+}
+
+func TestListen_UnixSocketProxyAuth(t *testing.T) {
+	path := socketPath(t)
+	te := setup(t, func(c *config.Config) {
+		c.Socket = path
+		c.Auth.Proxy = &config.ProxyAuth{Header: "X-Forwarded-User"}
+	})
+	te.serve(t)
+
+	client := unixClient(path)
+	get := func(user string) int {
+		t.Helper()
+		req, err := http.NewRequest(
+			http.MethodGet, "http://agentsview/api/v1/stats", nil,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user != "" {
+			req.Header.Set("X-Forwarded-User", user)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET over socket: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := get("alice"); got != http.StatusOK {
+		t.Errorf("proxied status = %d, want 200", got)
+	}
+	if got := get(""); got != http.StatusUnauthorized {
+		t.Errorf("status without user = %d, want 401", got)
+	}
+}
+
+func TestListen_UnixSocketRefusesOtherFiles(t *testing.T) {
+	path := socketPath(t)
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	te := setup(t, func(c *config.Config) { c.Socket = path })
+	if _, err := te.srv.Listen(); err == nil {
+		t.Fatal("expected error for a regular file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "data" {
+		t.Error("regular file was replaced")
+	}
+}
+
+func TestListen_SelfSignedTLS(t *testing.T) {
+	port := server.FindAvailablePort("127.0.0.1", 41000)
+	te := setup(t, func(c *config.Config) {
+		c.Port = port
+		c.TLSSelfSigned = true
+	})
+	te.serve(t)
+
+	certFile := filepath.Join(te.dataDir, "tls", "cert.pem")
+	pemData, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("self-signed certificate not stored: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		t.Fatal("cert.pem holds no certificate")
+	}
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}}
+
+	url := te.srv.URL()
+	if !strings.HasPrefix(url, "https://127.0.0.1:") {
+		t.Fatalf("URL = %q", url)
+	}
+	resp, err := client.Get(url + "/api/v1/stats")
+	if err != nil {
+		t.Fatalf("GET over TLS: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d", resp.StatusCode)
+	}
+}
+
+func TestServe_SlowUploadBody(t *testing.T) {
+	if testing.Short() {
+		t.Skip("streams a body for 11s")
+	}
+	port := server.FindAvailablePort("127.0.0.1", 42000)
+	te := setup(t, func(c *config.Config) { c.Port = port })
+	te.serve(t)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "rollout-slow.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(codexRollout("cx-slow")))
+	mw.Close()
+
+	// Send half the body, stall past the header timeout, then
+	// finish; the upload must still go through.
+	pr, pw := io.Pipe()
+	go func() {
+		data := body.Bytes()
+		pw.Write(data[:len(data)/2])
+		time.Sleep(11 * time.Second)
+		pw.Write(data[len(data)/2:])
+		pw.Close()
+	}()
+	resp, err := http.Post(
+		te.srv.URL()+"/api/v1/sessions/upload",
+		mw.FormDataContentType(), pr,
+	)
+	if err != nil {
+		t.Fatalf("slow upload: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		t.Errorf("status = %d: %s", resp.StatusCode, msg)
+	}
+}
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		name string
+		mod  func(*config.Config)
+		want string
+	}{
+		{"Default", func(c *config.Config) {}, "http://127.0.0.1:8080"},
+		{"AllInterfaces", func(c *config.Config) { c.Host = "0.0.0.0" }, "http://127.0.0.1:8080"},
+		{"IPv6", func(c *config.Config) { c.Host = "::1" }, "http://[::1]:8080"},
+		{"TLS", func(c *config.Config) {
+			c.Host = "localhost"
+			c.TLSCert, c.TLSKey = "c.pem", "k.pem"
+		}, "https://localhost:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := setup(t, func(c *config.Config) {
+				c.Port = 8080
+				tt.mod(c)
+			})
+			if got := te.srv.URL(); got != tt.want {
+				t.Errorf("URL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
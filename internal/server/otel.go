@@ -0,0 +1,70 @@
+package server
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// maxOTLPBodySize bounds the size of an OTLP/HTTP export
+// request, after decompression.
+const maxOTLPBodySize = 64 << 20
+
+// handleOTLPTraces is an OTLP/HTTP trace receiver. Exporters
+// configured with this server as their endpoint post their
+// spans here; the GenAI spans among them are imported as otel
+// sessions. Only the JSON encoding is accepted.
+func (s *Server) handleOTLPTraces(
+	w http.ResponseWriter, r *http.Request,
+) {
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if ct != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType,
+			"only OTLP/JSON (application/json) is supported")
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxOTLPBodySize)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid gzip body")
+			return
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, maxOTLPBodySize+1)
+	}
+	data, err := io.ReadAll(body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || len(data) > maxOTLPBodySize {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			"request body too large")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "reading request body")
+		return
+	}
+
+	batches, err := parser.SplitOTelTraces(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("parsing traces: %v", err))
+		return
+	}
+	if _, err := s.engine.ImportOTel(batches); err != nil {
+		log.Printf("Error importing traces: %v", err)
+		writeError(w, http.StatusInternalServerError,
+			"failed to import traces")
+		return
+	}
+
+	// An empty ExportTraceServiceResponse reports full success.
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
@@ -26,6 +26,37 @@ func parseIntParam(
 	return v, true
 }
 
+// parseBoolParam reads a boolean query parameter from r,
+// writing a 400 error and returning (false, false) if it is
+// present but not a valid boolean. When the parameter is absent,
+// returns (false, true).
+func parseBoolParam(
+	w http.ResponseWriter, r *http.Request, name string,
+) (bool, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return false, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("invalid %s parameter", name))
+		return false, false
+	}
+	return v, true
+}
+
+// parseBoolParamDefault is parseBoolParam for a parameter that
+// defaults to def when absent.
+func parseBoolParamDefault(
+	w http.ResponseWriter, r *http.Request, name string, def bool,
+) (bool, bool) {
+	if !r.URL.Query().Has(name) {
+		return def, true
+	}
+	return parseBoolParam(w, r, name)
+}
+
 // clampLimit applies a default and upper bound to a limit value.
 func clampLimit(limit, defaultLimit, maxLimit int) int {
 	if limit <= 0 {
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -14,15 +15,11 @@ type searchResponse struct {
 	Next    int               `json:"next"`
 }
 
-// prepareFTSQuery wraps multi-word queries in quotes so
-// SQLite FTS matches the exact phrase rather than individual
-// terms.
-func prepareFTSQuery(raw string) string {
-	if strings.Contains(raw, " ") &&
-		!strings.HasPrefix(raw, "\"") {
-		return "\"" + raw + "\""
-	}
-	return raw
+// queryErrorResponse reports a malformed search query along
+// with the byte offset of the problem.
+type queryErrorResponse struct {
+	Error    string `json:"error"`
+	Position int    `json:"position"`
 }
 
 func (s *Server) handleSearch(
@@ -47,14 +44,21 @@ func (s *Server) handleSearch(
 		return
 	}
 
+	starred, ok := parseBoolParam(w, r, "starred")
+	if !ok {
+		return
+	}
+
 	if !s.db.HasFTS() {
 		writeError(w, http.StatusNotImplemented, "search not available")
 		return
 	}
 
 	filter := db.SearchFilter{
-		Query:   prepareFTSQuery(query),
+		Query:   query,
 		Project: q.Get("project"),
+		Tag:     q.Get("tag"),
+		Starred: starred,
 		Cursor:  cursor,
 		Limit:   limit,
 	}
@@ -64,6 +68,14 @@ func (s *Server) handleSearch(
 		if handleContextError(w, err) {
 			return
 		}
+		var qerr *db.QueryError
+		if errors.As(err, &qerr) {
+			writeJSON(w, http.StatusBadRequest, queryErrorResponse{
+				Error:    qerr.Error(),
+				Position: qerr.Pos,
+			})
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/secrets"
+)
+
+// redactMessages returns a copy of msgs with every secret in
+// their content and tool calls replaced by a redaction marker.
+func redactMessages(
+	sc *secrets.Scanner, msgs []db.Message,
+) []db.Message {
+	out := make([]db.Message, len(msgs))
+	for i, m := range msgs {
+		m.Content, _ = sc.Redact(m.Content)
+		if m.ToolCalls != nil {
+			tcs := make([]db.ToolCall, len(m.ToolCalls))
+			for j, tc := range m.ToolCalls {
+				tc.InputJSON, _ = sc.Redact(tc.InputJSON)
+				tc.ResultContent, _ = sc.Redact(tc.ResultContent)
+				tcs[j] = tc
+			}
+			m.ToolCalls = tcs
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// redactSession returns a copy of session with secrets in its
+// first message redacted.
+func redactSession(sc *secrets.Scanner, session *db.Session) *db.Session {
+	out := *session
+	if out.FirstMessage != nil {
+		first, _ := sc.Redact(*out.FirstMessage)
+		out.FirstMessage = &first
+	}
+	return &out
+}
+
+// parseRedactParam reads the "redact" query parameter, which
+// defaults to true.
+func parseRedactParam(
+	w http.ResponseWriter, r *http.Request,
+) (bool, bool) {
+	return parseBoolParamDefault(w, r, "redact", true)
+}
+
+func (s *Server) handleListSecrets(
+	w http.ResponseWriter, r *http.Request,
+) {
+	_, msgs, ok := s.getSessionWithMessages(w, r)
+	if !ok {
+		return
+	}
+	findings := db.ScanMessages(s.secrets, msgs)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"findings": findings,
+		"count":    len(findings),
+	})
+}
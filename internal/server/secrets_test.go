@@ -0,0 +1,133 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+	"github.com/wesm/agentsview/internal/secrets"
+	"github.com/wesm/agentsview/internal/server"
+)
+
+// awsKeyID is a fake key, split so this file does not trip
+// secret scanners.
+const awsKeyID = "AKIA" + "IOSFODNN7EXAMPLE"
+
+func seedSecretSession(t *testing.T, te *testEnv) {
+	t.Helper()
+	te.seedSession(t, "s1", "my-app", 2)
+	te.seedMessages(t, "s1", 2, func(i int, m *db.Message) {
+		if i == 0 {
+			m.Content = "deploy with key " + awsKeyID
+			m.ContentLength = len(m.Content)
+			return
+		}
+		m.ToolCalls = []db.ToolCall{{
+			SessionID:     "s1",
+			ToolName:      "Bash",
+			Category:      "Bash",
+			InputJSON:     `{"command":"cat .env"}`,
+			ResultContent: "STRIPE_SECRET=sk_live_abcdefgh12345",
+		}}
+	})
+}
+
+func TestListSecrets(t *testing.T) {
+	te := setup(t)
+	seedSecretSession(t, te)
+
+	w := te.get(t, "/api/v1/sessions/s1/secrets")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		Findings []struct {
+			Ordinal  int    `json:"ordinal"`
+			Field    string `json:"field"`
+			ToolCall *int   `json:"tool_call"`
+			Rule     string `json:"rule"`
+			Start    int    `json:"start"`
+			End      int    `json:"end"`
+			Preview  string `json:"preview"`
+		} `json:"findings"`
+		Count int `json:"count"`
+	}](t, w)
+	if resp.Count != 2 || len(resp.Findings) != 2 {
+		t.Fatalf("findings = %+v, want 2", resp.Findings)
+	}
+
+	f := resp.Findings[0]
+	start := len("deploy with key ")
+	if f.Ordinal != 0 || f.Field != "content" || f.ToolCall != nil ||
+		f.Rule != "aws_access_key_id" ||
+		f.Start != start || f.End != start+len(awsKeyID) {
+		t.Errorf("content finding = %+v", f)
+	}
+	if strings.Contains(f.Preview, awsKeyID) {
+		t.Errorf("preview leaks the secret: %q", f.Preview)
+	}
+
+	f = resp.Findings[1]
+	if f.Ordinal != 1 || f.Field != "tool_result" ||
+		f.ToolCall == nil || *f.ToolCall != 0 ||
+		f.Rule != "secret_assignment" {
+		t.Errorf("tool result finding = %+v", f)
+	}
+
+	assertStatus(t, te.get(t, "/api/v1/sessions/nope/secrets"),
+		http.StatusNotFound)
+}
+
+func TestExportRedactsSecrets(t *testing.T) {
+	te := setup(t)
+	seedSecretSession(t, te)
+
+	for _, format := range []string{"html", "markdown", "json"} {
+		t.Run(format, func(t *testing.T) {
+			w := te.get(t, "/api/v1/sessions/s1/export?format="+format)
+			assertStatus(t, w, http.StatusOK)
+			body := w.Body.String()
+			if strings.Contains(body, awsKeyID) ||
+				strings.Contains(body, "sk_live_abcdefgh12345") {
+				t.Errorf("export leaks secrets:\n%s", body)
+			}
+			if !strings.Contains(body, "[REDACTED:aws_access_key_id]") {
+				t.Errorf("export has no redaction marker:\n%s", body)
+			}
+		})
+	}
+
+	w := te.get(t, "/api/v1/sessions/s1/export?format=markdown&redact=false")
+	assertStatus(t, w, http.StatusOK)
+	assertBodyContains(t, w, awsKeyID)
+
+	w = te.get(t, "/api/v1/sessions/s1/export?redact=maybe")
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
+func TestExportRedactsFirstMessage(t *testing.T) {
+	te := setup(t)
+	token := "ghp_" + "abcdefghijklmnopqrstuvwxyz0123456789"
+	te.seedSession(t, "s1", "my-app", 1, func(s *db.Session) {
+		s.FirstMessage = dbtest.Ptr("push with " + token)
+	})
+
+	w := te.get(t, "/api/v1/sessions/s1/export?format=json")
+	assertStatus(t, w, http.StatusOK)
+	if strings.Contains(w.Body.String(), token) {
+		t.Errorf("JSON export leaks the first message's secret:\n%s",
+			w.Body.String())
+	}
+
+	// The CLI export goes through ExportSession.
+	content, _, err := server.ExportSession(
+		context.Background(), te.db, "s1", "json", secrets.Default(),
+	)
+	if err != nil {
+		t.Fatalf("ExportSession: %v", err)
+	}
+	if strings.Contains(string(content), token) {
+		t.Errorf("ExportSession leaks the first message's secret")
+	}
+}
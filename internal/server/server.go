@@ -7,6 +7,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	gosync "sync"
@@ -15,6 +16,7 @@ import (
 	"github.com/wesm/agentsview/internal/config"
 	"github.com/wesm/agentsview/internal/db"
 	"github.com/wesm/agentsview/internal/insight"
+	"github.com/wesm/agentsview/internal/secrets"
 	"github.com/wesm/agentsview/internal/sync"
 	"github.com/wesm/agentsview/internal/web"
 )
@@ -35,10 +37,12 @@ type Server struct {
 	mux     *http.ServeMux
 	httpSrv *http.Server
 	version VersionInfo
+	secrets *secrets.Scanner
 
 	generateFunc insight.GenerateFunc
 	spaFS        fs.FS
 	spaHandler   http.Handler
+	auth         []Authenticator
 
 	// handlerDelay is injected before each timeout-wrapped
 	// handler, used only by tests to guarantee handlers
@@ -46,14 +50,15 @@ type Server struct {
 	handlerDelay time.Duration
 }
 
-// New creates a new Server.
+// New creates a new Server. It fails if the frontend is missing
+// from the build or the auth config is invalid.
 func New(
 	cfg config.Config, database *db.DB, engine *sync.Engine,
 	opts ...Option,
-) *Server {
+) (*Server, error) {
 	dist, err := web.Assets()
 	if err != nil {
-		log.Fatalf("embedded frontend not found: %v", err)
+		return nil, fmt.Errorf("embedded frontend not found: %w", err)
 	}
 
 	s := &Server{
@@ -65,11 +70,25 @@ func New(
 		spaFS:        dist,
 		spaHandler:   http.FileServerFS(dist),
 	}
+	// The config file's patterns were checked when it was
+	// loaded; fall back to the built-in rules if they are bad.
+	scanner, err := cfg.SecretScanner()
+	if err != nil {
+		log.Printf("invalid secret_patterns: %v", err)
+		scanner = secrets.Default()
+	}
+	s.secrets = scanner
+	// Fail closed: serving without the auth the config asks for
+	// would expose it.
+	s.auth, err = configAuthenticators(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
 	for _, opt := range opts {
 		opt(s)
 	}
 	s.routes()
-	return s
+	return s, nil
 }
 
 // Option configures a Server.
@@ -115,12 +134,39 @@ func (s *Server) routes() {
 		"POST /api/v1/sessions/{id}/publish", s.withTimeout(s.handlePublishSession),
 	)
 	s.mux.Handle(
-		"POST /api/v1/sessions/upload", s.withTimeout(s.handleUploadSession),
+		"GET /api/v1/sessions/{id}/secrets", s.withTimeout(s.handleListSecrets),
+	)
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/changes", s.withTimeout(s.handleListChanges),
+	)
+	// Upload: Do not use timeout handler; archives of many sessions
+	// take longer to import, and a timeout would drop the report
+	// while the import carried on.
+	s.mux.Handle(
+		"POST /api/v1/sessions/upload", http.HandlerFunc(s.handleUploadSession),
+	)
+	s.mux.Handle("GET /api/v1/sessions/{id}/tags", s.withTimeout(s.handleListTags))
+	s.mux.Handle("POST /api/v1/sessions/{id}/tags", s.withTimeout(s.handleAddTag))
+	s.mux.Handle(
+		"DELETE /api/v1/sessions/{id}/tags/{tag}", s.withTimeout(s.handleRemoveTag),
+	)
+	s.mux.Handle("GET /api/v1/sessions/{id}/stars", s.withTimeout(s.handleListStars))
+	s.mux.Handle("PUT /api/v1/sessions/{id}/star", s.withTimeout(s.handleAddStar))
+	s.mux.Handle("DELETE /api/v1/sessions/{id}/star", s.withTimeout(s.handleRemoveStar))
+	s.mux.Handle("GET /api/v1/sessions/{id}/notes", s.withTimeout(s.handleListNotes))
+	s.mux.Handle("POST /api/v1/sessions/{id}/notes", s.withTimeout(s.handleAddNote))
+	s.mux.Handle(
+		"PUT /api/v1/sessions/{id}/notes/{noteId}", s.withTimeout(s.handleUpdateNote),
+	)
+	s.mux.Handle(
+		"DELETE /api/v1/sessions/{id}/notes/{noteId}", s.withTimeout(s.handleDeleteNote),
 	)
 	s.mux.Handle("GET /api/v1/analytics/summary", s.withTimeout(s.handleAnalyticsSummary))
 	s.mux.Handle("GET /api/v1/analytics/activity", s.withTimeout(s.handleAnalyticsActivity))
 	s.mux.Handle("GET /api/v1/analytics/heatmap", s.withTimeout(s.handleAnalyticsHeatmap))
 	s.mux.Handle("GET /api/v1/analytics/projects", s.withTimeout(s.handleAnalyticsProjects))
+	s.mux.Handle("GET /api/v1/analytics/branches", s.withTimeout(s.handleAnalyticsBranches))
+	s.mux.Handle("GET /api/v1/analytics/cost", s.withTimeout(s.handleAnalyticsCost))
 	s.mux.Handle("GET /api/v1/analytics/hour-of-week", s.withTimeout(s.handleAnalyticsHourOfWeek))
 	s.mux.Handle("GET /api/v1/analytics/sessions", s.withTimeout(s.handleAnalyticsSessionShape))
 	s.mux.Handle("GET /api/v1/analytics/velocity", s.withTimeout(s.handleAnalyticsVelocity))
@@ -136,6 +182,9 @@ func (s *Server) routes() {
 	s.mux.Handle("GET /api/v1/search", s.withTimeout(s.handleSearch))
 	s.mux.Handle("GET /api/v1/projects", s.withTimeout(s.handleListProjects))
 	s.mux.Handle("GET /api/v1/machines", s.withTimeout(s.handleListMachines))
+	s.mux.Handle("GET /api/v1/files", s.withTimeout(s.handleFileSessions))
+	s.mux.Handle("GET /api/v1/files/hotspots", s.withTimeout(s.handleFileHotspots))
+	s.mux.Handle("GET /api/v1/commits/{sha}", s.withTimeout(s.handleCommitSessions))
 	s.mux.Handle("GET /api/v1/stats", s.withTimeout(s.handleGetStats))
 	s.mux.Handle("GET /api/v1/version", s.withTimeout(s.handleGetVersion))
 	s.mux.HandleFunc("POST /api/v1/sync", s.handleTriggerSync)
@@ -146,6 +195,10 @@ func (s *Server) routes() {
 		"POST /api/v1/config/github", s.withTimeout(s.handleSetGithubConfig),
 	)
 
+	// OTLP/HTTP receiver, at the path exporters append to
+	// their configured endpoint.
+	s.mux.Handle("POST /v1/traces", s.withTimeout(s.handleOTLPTraces))
+
 	// SPA fallback: serve embedded frontend
 	// Do not use timeout handler for static assets to avoid buffering.
 	s.mux.Handle("/", http.HandlerFunc(s.handleSPA))
@@ -199,23 +252,36 @@ func (s *Server) githubToken() string {
 
 // Handler returns the http.Handler with middleware applied.
 func (s *Server) Handler() http.Handler {
-	return corsMiddleware(logMiddleware(s.mux))
+	return s.corsMiddleware(logMiddleware(s.authMiddleware(s.mux)))
 }
 
-// ListenAndServe starts the HTTP server.
+// ListenAndServe opens the configured listener and serves on
+// it.
 func (s *Server) ListenAndServe() error {
-	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+	ln, err := s.Listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// readHeaderTimeout bounds how long a client may take to send
+// request headers. Bodies have no read deadline, so large
+// uploads over slow links aren't cut off.
+const readHeaderTimeout = 10 * time.Second
+
+// Serve serves HTTP requests on ln, which Listen opened.
+func (s *Server) Serve(ln net.Listener) error {
 	srv := &http.Server{
-		Addr:        addr,
-		Handler:     s.Handler(),
-		ReadTimeout: 10 * time.Second,
-		IdleTimeout: 120 * time.Second,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		IdleTimeout:       120 * time.Second,
 	}
 	s.mu.Lock()
 	s.httpSrv = srv
 	s.mu.Unlock()
-	log.Printf("Starting server at http://%s", addr)
-	return srv.ListenAndServe()
+	log.Printf("Starting server at %s", s.Addr())
+	return srv.Serve(ln)
 }
 
 // Shutdown gracefully shuts down the HTTP server.
@@ -230,7 +296,8 @@ func (s *Server) Shutdown(ctx context.Context) error {
 }
 
 // FindAvailablePort finds an available port starting from the
-// given port, binding to the specified host.
+// given port, binding to the specified host. It only applies to
+// TCP listeners; a Unix domain socket has no port.
 func FindAvailablePort(host string, start int) int {
 	for port := start; port < start+100; port++ {
 		addr := net.JoinHostPort(host, strconv.Itoa(port))
@@ -243,20 +310,29 @@ func FindAvailablePort(host string, start int) int {
 	return start
 }
 
-func corsMiddleware(next http.Handler) http.Handler {
+// corsMiddleware lets the origins in the cors_origins allowlist
+// call the API from other sites. Preflight requests are answered
+// before authentication, since browsers send them without
+// credentials.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	allowed := s.cfg.CORSOrigins
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasPrefix(r.URL.Path, "/api/") {
-			w.Header().Set(
-				"Access-Control-Allow-Origin", "*",
-			)
-			w.Header().Set(
-				"Access-Control-Allow-Methods",
-				"GET, POST, DELETE, OPTIONS",
-			)
-			w.Header().Set(
-				"Access-Control-Allow-Headers",
-				"Content-Type",
-			)
+			w.Header().Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			if origin != "" && slices.Contains(allowed, origin) {
+				w.Header().Set(
+					"Access-Control-Allow-Origin", origin,
+				)
+				w.Header().Set(
+					"Access-Control-Allow-Methods",
+					"GET, POST, PUT, DELETE, OPTIONS",
+				)
+				w.Header().Set(
+					"Access-Control-Allow-Headers",
+					"Content-Type, Authorization",
+				)
+			}
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(http.StatusNoContent)
 				return
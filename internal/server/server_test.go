@@ -3,6 +3,7 @@ package server_test
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -11,6 +12,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -96,9 +98,13 @@ func setupWithServerOpts(
 		opt(&cfg)
 	}
 	engine := sync.NewEngine(
-		database, []string{claudeDir}, []string{codexDir}, nil, nil, nil, "test",
+		database, []string{claudeDir}, []string{codexDir}, nil, nil, nil, nil, nil, nil, nil, nil, "test",
 	)
-	srv := server.New(cfg, database, engine, srvOpts...)
+	engine.SetOTelDir(cfg.OTelDir())
+	srv, err := server.New(cfg, database, engine, srvOpts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
 
 	return &testEnv{
 		srv:       srv,
@@ -272,6 +278,18 @@ func (te *testEnv) post(
 	return w
 }
 
+func (te *testEnv) put(
+	t *testing.T, path string, body string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPut, path,
+		strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	te.handler.ServeHTTP(w, req)
+	return w
+}
+
 func (te *testEnv) del(
 	t *testing.T, path string,
 ) *httptest.ResponseRecorder {
@@ -286,6 +304,16 @@ func (te *testEnv) del(
 func (te *testEnv) upload(
 	t *testing.T, filename, content, query string,
 ) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	te.handler.ServeHTTP(w, uploadRequest(t, filename, content, query))
+	return w
+}
+
+// uploadRequest builds a multipart upload request for one file.
+func uploadRequest(
+	t *testing.T, filename, content, query string,
+) *http.Request {
 	t.Helper()
 	var buf bytes.Buffer
 	mw := multipart.NewWriter(&buf)
@@ -303,9 +331,7 @@ func (te *testEnv) upload(
 	req := httptest.NewRequest(http.MethodPost,
 		"/api/v1/sessions/upload?"+query, &buf)
 	req.Header.Set("Content-Type", mw.FormDataContentType())
-	w := httptest.NewRecorder()
-	te.handler.ServeHTTP(w, req)
-	return w
+	return req
 }
 
 // decode unmarshals the response body into a typed struct.
@@ -486,6 +512,8 @@ type githubConfigResponse struct {
 
 type uploadResponse struct {
 	SessionID string `json:"session_id"`
+	Agent     string `json:"agent"`
+	Status    string `json:"status"`
 	Project   string `json:"project"`
 	Machine   string `json:"machine"`
 	Messages  int    `json:"messages"`
@@ -564,6 +592,34 @@ func TestListSessions_ExcludeProjectFilter(t *testing.T) {
 	}
 }
 
+func TestListSessions_BranchFilter(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 5, func(s *db.Session) {
+		s.RepoRoot = "/src/my-app"
+		s.GitBranch = "main"
+	})
+	te.seedSession(t, "s2", "my-app", 3, func(s *db.Session) {
+		s.RepoRoot = "/src/my-app"
+		s.GitBranch = "fix-login"
+	})
+
+	w := te.get(t,
+		"/api/v1/sessions?branch=fix-login&repo=my-app",
+	)
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[sessionListResponse](t, w)
+	if len(resp.Sessions) != 1 {
+		t.Fatalf("expected 1 session, got %d",
+			len(resp.Sessions))
+	}
+	if resp.Sessions[0].ID != "s2" ||
+		resp.Sessions[0].GitBranch != "fix-login" {
+		t.Errorf("expected session s2 on fix-login, got %+v",
+			resp.Sessions[0])
+	}
+}
+
 func TestGetSession_Found(t *testing.T) {
 	te := setup(t)
 	te.seedSession(t, "s1", "my-app", 5)
@@ -1024,6 +1080,49 @@ func TestSearch_ZeroResults(t *testing.T) {
 	}
 }
 
+func TestSearch_StructuredQuery(t *testing.T) {
+	te := setup(t)
+	if !te.db.HasFTS() {
+		t.Skip("skipping search test: no FTS support")
+	}
+	te.seedSession(t, "s1", "my-app", 2)
+	te.seedMessages(t, "s1", 2, func(_ int, m *db.Message) {
+		m.Content = "the login bug"
+		m.ContentLength = len(m.Content)
+	})
+
+	w := te.get(t, "/api/v1/search?q="+
+		url.QueryEscape("login role:assistant project:my-app"))
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[searchResponse](t, w)
+	if resp.Count != 1 || resp.Results[0].Ordinal != 1 {
+		t.Fatalf("results = %+v, want ordinal 1 only", resp.Results)
+	}
+}
+
+func TestSearch_MalformedQuery(t *testing.T) {
+	te := setup(t)
+	if !te.db.HasFTS() {
+		t.Skip("skipping search test: no FTS support")
+	}
+
+	w := te.get(t, "/api/v1/search?q="+
+		url.QueryEscape(`login "unterminated`))
+	assertStatus(t, w, http.StatusBadRequest)
+
+	resp := decode[struct {
+		Error    string `json:"error"`
+		Position int    `json:"position"`
+	}](t, w)
+	if resp.Position != 6 {
+		t.Errorf("position = %d, want 6", resp.Position)
+	}
+	if !strings.Contains(resp.Error, "unterminated quote") {
+		t.Errorf("error = %q, want unterminated quote", resp.Error)
+	}
+}
+
 func TestSearch_NotAvailable(t *testing.T) {
 	te := setup(t)
 	// Simulate missing FTS by dropping the virtual table.
@@ -1092,13 +1191,49 @@ func TestSyncStatus(t *testing.T) {
 	}
 }
 
+func withCORSOrigins(origins ...string) setupOption {
+	return func(c *config.Config) { c.CORSOrigins = origins }
+}
+
+// getFromOrigin sends a GET as a browser on origin would.
+func (te *testEnv) getFromOrigin(
+	t *testing.T, path, origin string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Origin", origin)
+	w := httptest.NewRecorder()
+	te.handler.ServeHTTP(w, req)
+	return w
+}
+
 func TestCORSHeaders(t *testing.T) {
-	te := setup(t)
+	te := setup(t, withCORSOrigins("https://dash.example.com"))
 
-	w := te.get(t, "/api/v1/stats")
+	w := te.getFromOrigin(t, "/api/v1/stats", "https://dash.example.com")
 	cors := w.Header().Get("Access-Control-Allow-Origin")
-	if cors != "*" {
-		t.Fatalf("expected CORS *, got %q", cors)
+	if cors != "https://dash.example.com" {
+		t.Fatalf("expected allowed origin, got %q", cors)
+	}
+	if vary := w.Header().Get("Vary"); vary != "Origin" {
+		t.Errorf("Vary = %q, want Origin", vary)
+	}
+}
+
+func TestCORSOriginNotAllowed(t *testing.T) {
+	te := setup(t, withCORSOrigins("https://dash.example.com"))
+
+	w := te.getFromOrigin(t, "/api/v1/stats", "https://evil.example.com")
+	assertStatus(t, w, http.StatusOK)
+	if cors := w.Header().Get("Access-Control-Allow-Origin"); cors != "" {
+		t.Fatalf("expected no CORS header, got %q", cors)
+	}
+
+	// Without an allowlist no origin is allowed.
+	te = setup(t)
+	w = te.getFromOrigin(t, "/api/v1/stats", "https://dash.example.com")
+	if cors := w.Header().Get("Access-Control-Allow-Origin"); cors != "" {
+		t.Fatalf("expected no CORS header, got %q", cors)
 	}
 }
 
@@ -1114,14 +1249,15 @@ func TestCORSPreflight(t *testing.T) {
 }
 
 func TestCORSAllowMethods(t *testing.T) {
-	te := setup(t)
+	te := setup(t, withCORSOrigins("https://dash.example.com"))
 
-	w := te.get(t, "/api/v1/stats")
+	w := te.getFromOrigin(t, "/api/v1/stats", "https://dash.example.com")
 	methods := w.Header().Get(
 		"Access-Control-Allow-Methods",
 	)
 	for _, want := range []string{
-		http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions,
+		http.MethodGet, http.MethodPost, http.MethodPut,
+		http.MethodDelete, http.MethodOptions,
 	} {
 		if !strings.Contains(methods, want) {
 			t.Errorf(
@@ -1130,6 +1266,10 @@ func TestCORSAllowMethods(t *testing.T) {
 			)
 		}
 	}
+	headers := w.Header().Get("Access-Control-Allow-Headers")
+	if !strings.Contains(headers, "Authorization") {
+		t.Errorf("Allow-Headers %q missing Authorization", headers)
+	}
 }
 
 func TestGetGithubConfig(t *testing.T) {
@@ -1170,6 +1310,39 @@ func TestExportSession_NotFound(t *testing.T) {
 	assertStatus(t, w, http.StatusNotFound)
 }
 
+func TestExportSession_Formats(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 3)
+	te.seedMessages(t, "s1", 3)
+
+	tests := []struct {
+		format, contentType, ext, body string
+	}{
+		{"markdown", "text/markdown", ".md", "# my-app"},
+		{"md", "text/markdown", ".md", "### User"},
+		{"json", "application/json", ".json", `"messages": [`},
+		{"html", "text/html", ".html", "<!DOCTYPE html>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			w := te.get(t,
+				"/api/v1/sessions/s1/export?format="+tt.format)
+			assertStatus(t, w, http.StatusOK)
+			if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+			cd := w.Header().Get("Content-Disposition")
+			if !strings.Contains(cd, tt.ext+`"`) {
+				t.Errorf("Content-Disposition = %q, want %s file", cd, tt.ext)
+			}
+			assertBodyContains(t, w, tt.body)
+		})
+	}
+
+	w := te.get(t, "/api/v1/sessions/s1/export?format=pdf")
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
 func TestPublishSession_NoToken(t *testing.T) {
 	te := setup(t)
 	te.seedSession(t, "s1", "my-app", 3)
@@ -1350,6 +1523,115 @@ func TestUploadSession_Errors(t *testing.T) {
 	}
 }
 
+// postTraces posts an OTLP/JSON export request to the OTLP/HTTP
+// receiver.
+func (te *testEnv) postTraces(
+	t *testing.T, contentType string, body []byte, gzipped bool,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	if gzipped {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(body); err != nil {
+			t.Fatalf("compressing body: %v", err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatalf("compressing body: %v", err)
+		}
+		body = buf.Bytes()
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces",
+		bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	w := httptest.NewRecorder()
+	te.handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestOTLPTraces(t *testing.T) {
+	te := setup(t)
+	start := time.Date(2024, 6, 10, 6, 0, 0, 0, time.UTC).UnixNano()
+	chat := testjsonl.OTelSpan{
+		TraceID: "trace1", SpanID: "c1", Name: "chat gpt-4o",
+		Start: start, End: start + int64(2*time.Second),
+		Attrs: map[string]any{
+			"gen_ai.operation.name":                 "chat",
+			"gen_ai.request.model":                  "gpt-4o",
+			"gen_ai.prompt.0.role":                  "user",
+			"gen_ai.prompt.0.content":               "List the files",
+			"gen_ai.completion.0.tool_calls.0.id":   "call_1",
+			"gen_ai.completion.0.tool_calls.0.name": "bash",
+			"gen_ai.usage.input_tokens":             12,
+			"gen_ai.usage.output_tokens":            3,
+		},
+	}
+	tool := testjsonl.OTelSpan{
+		TraceID: "trace1", SpanID: "t1", Name: "execute_tool bash",
+		Start: start + int64(3*time.Second),
+		End:   start + int64(4*time.Second),
+		Attrs: map[string]any{
+			"gen_ai.operation.name":   "execute_tool",
+			"gen_ai.tool.name":        "bash",
+			"gen_ai.tool.call.id":     "call_1",
+			"gen_ai.tool.call.result": "a.go b.go",
+		},
+	}
+
+	w := te.postTraces(t, "application/json",
+		[]byte(testjsonl.OTLPRequestJSON("support-bot", "", chat)), false)
+	assertStatus(t, w, http.StatusOK)
+	if got := strings.TrimSpace(w.Body.String()); got != "{}" {
+		t.Errorf("response = %s, want {}", got)
+	}
+
+	sess, err := te.db.GetSession(context.Background(), "otel:trace1")
+	if err != nil || sess == nil {
+		t.Fatalf("GetSession = %v, %v", sess, err)
+	}
+	if sess.Agent != "otel" || sess.Project != "support-bot" {
+		t.Errorf("agent, project = %q, %q", sess.Agent, sess.Project)
+	}
+	if sess.InputTokens != 12 || sess.OutputTokens != 3 {
+		t.Errorf("tokens = %d, %d", sess.InputTokens, sess.OutputTokens)
+	}
+
+	// Spans exported later join the session.
+	w = te.postTraces(t, "application/json; charset=utf-8",
+		[]byte(testjsonl.OTLPRequestJSON("support-bot", "", tool)), true)
+	assertStatus(t, w, http.StatusOK)
+
+	msgs, err := te.db.GetAllMessages(context.Background(), "otel:trace1")
+	if err != nil {
+		t.Fatalf("GetAllMessages: %v", err)
+	}
+	if len(msgs) != 2 || len(msgs[1].ToolCalls) != 1 {
+		t.Fatalf("messages = %+v, want user and tool call", msgs)
+	}
+	if got := msgs[1].ToolCalls[0].ResultContent; got != "a.go b.go" {
+		t.Errorf("tool result = %q", got)
+	}
+}
+
+func TestOTLPTraces_Errors(t *testing.T) {
+	te := setup(t)
+	w := te.postTraces(t, "application/x-protobuf", []byte{0x0a}, false)
+	assertStatus(t, w, http.StatusUnsupportedMediaType)
+
+	w = te.postTraces(t, "application/json", []byte("{\nnope"), false)
+	assertStatus(t, w, http.StatusBadRequest)
+
+	// Exports without GenAI spans are accepted and ignored.
+	w = te.postTraces(t, "application/json",
+		[]byte(testjsonl.OTLPRequestJSON("web", "", testjsonl.OTelSpan{
+			TraceID: "t", SpanID: "s", Name: "GET",
+			Attrs: map[string]any{"http.request.method": "GET"},
+		})), false)
+	assertStatus(t, w, http.StatusOK)
+}
+
 func TestUploadSession_EmptyFile(t *testing.T) {
 	te := setup(t)
 
@@ -1440,7 +1722,7 @@ func TestWatchSession_Events(t *testing.T) {
 
 	engine := sync.NewEngine(
 		te.db, []string{te.claudeDir},
-		[]string{filepath.Join(te.dataDir, "codex")}, nil, nil, nil, "test",
+		[]string{filepath.Join(te.dataDir, "codex")}, nil, nil, nil, nil, nil, nil, nil, nil, "test",
 	)
 	engine.SyncAll(nil)
 
@@ -1486,7 +1768,7 @@ func TestWatchSession_FileDisappearAndResolve(t *testing.T) {
 
 	engine := sync.NewEngine(
 		te.db, []string{te.claudeDir},
-		[]string{filepath.Join(te.dataDir, "codex")}, nil, nil, nil, "test",
+		[]string{filepath.Join(te.dataDir, "codex")}, nil, nil, nil, nil, nil, nil, nil, nil, "test",
 	)
 	engine.SyncAll(nil)
 
@@ -31,6 +31,11 @@ func (s *Server) handleListSessions(
 		return
 	}
 
+	starred, ok := parseBoolParam(w, r, "starred")
+	if !ok {
+		return
+	}
+
 	date := q.Get("date")
 	dateFrom := q.Get("date_from")
 	dateTo := q.Get("date_to")
@@ -60,6 +65,8 @@ func (s *Server) handleListSessions(
 		ExcludeProject:  q.Get("exclude_project"),
 		Machine:         q.Get("machine"),
 		Agent:           q.Get("agent"),
+		Branch:          q.Get("branch"),
+		Repo:            q.Get("repo"),
 		Date:            date,
 		DateFrom:        dateFrom,
 		DateTo:          dateTo,
@@ -67,6 +74,8 @@ func (s *Server) handleListSessions(
 		MinMessages:     minMsgs,
 		MaxMessages:     maxMsgs,
 		MinUserMessages: minUserMsgs,
+		Tag:             q.Get("tag"),
+		Starred:         starred,
 		Cursor:          q.Get("cursor"),
 		Limit:           limit,
 	}
@@ -103,7 +112,18 @@ func (s *Server) handleGetSession(
 		writeError(w, http.StatusNotFound, "session not found")
 		return
 	}
-	writeJSON(w, http.StatusOK, session)
+	commits, err := s.db.SessionCommits(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, sessionResponse{
+		Session: session,
+		Commits: commits,
+	})
 }
 
 func (s *Server) handleGetChildSessions(
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"time"
+)
+
+const (
+	// certCheckInterval bounds how often the certificate files
+	// are checked for changes during handshakes.
+	certCheckInterval = 5 * time.Second
+
+	selfSignedValidity = 365 * 24 * time.Hour
+	// selfSignedRenewBefore is how long before expiry a
+	// self-signed certificate is replaced on startup.
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+// certReloader serves a certificate and key from files,
+// reloading them when they change on disk. A failed reload
+// keeps the previous certificate.
+type certReloader struct {
+	certFile, keyFile string
+	checkEvery        time.Duration
+
+	mu        gosync.Mutex
+	cert      *tls.Certificate
+	stamp     string
+	lastCheck time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile:   certFile,
+		keyFile:    keyFile,
+		checkEvery: certCheckInterval,
+	}
+	stamp, err := r.fileStamp()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(stamp); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// fileStamp identifies the current versions of the files.
+func (r *certReloader) fileStamp() (string, error) {
+	var stamp string
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		stamp += fmt.Sprintf(
+			"%d:%d;", info.ModTime().UnixNano(), info.Size(),
+		)
+	}
+	return stamp, nil
+}
+
+func (r *certReloader) load(stamp string) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	r.cert = &cert
+	r.stamp = stamp
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(
+	*tls.ClientHelloInfo,
+) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.lastCheck) >= r.checkEvery {
+		r.lastCheck = time.Now()
+		stamp, err := r.fileStamp()
+		if err == nil && stamp != r.stamp {
+			if err := r.load(stamp); err != nil {
+				log.Printf("keeping previous certificate: %v", err)
+			} else {
+				log.Printf("reloaded TLS certificate %s", r.certFile)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// ensureSelfSignedCert returns the paths of a self-signed
+// certificate and key in dir, generating them unless a current
+// certificate covering hosts is already there.
+func ensureSelfSignedCert(
+	dir string, hosts []string,
+) (certFile, keyFile string, err error) {
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if selfSignedCertValid(certFile, keyFile, hosts) {
+		return certFile, keyFile, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("generating key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", fmt.Errorf("generating serial: %w", err)
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"agentsview"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(selfSignedValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+		},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(
+		rand.Reader, tmpl, tmpl, &key.PublicKey, key,
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("creating certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("encoding key: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", fmt.Errorf("creating TLS directory: %w", err)
+	}
+	if err := writePEM(keyFile, "PRIVATE KEY", keyDER, 0o600); err != nil {
+		return "", "", err
+	}
+	if err := writePEM(certFile, "CERTIFICATE", der, 0o644); err != nil {
+		return "", "", err
+	}
+	log.Printf("generated self-signed certificate %s", certFile)
+	return certFile, keyFile, nil
+}
+
+// selfSignedCertValid reports whether the certificate in
+// certFile loads with its key, is not about to expire, and
+// covers every host.
+func selfSignedCertValid(certFile, keyFile string, hosts []string) bool {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	if time.Until(leaf.NotAfter) < selfSignedRenewBefore {
+		return false
+	}
+	for _, h := range hosts {
+		if leaf.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
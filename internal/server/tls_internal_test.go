@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureSelfSignedCert_Reused(t *testing.T) {
+	dir := t.TempDir()
+	hosts := []string{"localhost", "127.0.0.1"}
+	certFile, _, err := ensureSelfSignedCert(dir, hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := os.ReadFile(certFile)
+
+	if _, _, err := ensureSelfSignedCert(dir, hosts); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(certFile); !bytes.Equal(first, again) {
+		t.Error("valid certificate was regenerated")
+	}
+
+	// A host the certificate doesn't cover forces a new one.
+	hosts = append(hosts, "viewer.internal")
+	if _, _, err := ensureSelfSignedCert(dir, hosts); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(certFile); bytes.Equal(first, again) {
+		t.Error("certificate not regenerated for new host")
+	}
+}
+
+func TestCertReloader_ReloadsOnChange(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	certA, keyA, err := ensureSelfSignedCert(a, []string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	certB, keyB, err := ensureSelfSignedCert(b, []string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := newCertReloader(certA, keyA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.checkEvery = 0
+	leaf := func() []byte {
+		t.Helper()
+		c, err := r.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.Certificate[0]
+	}
+	before := leaf()
+
+	copyFile(t, certB, certA)
+	copyFile(t, keyB, keyA)
+	after := leaf()
+	if bytes.Equal(before, after) {
+		t.Fatal("certificate not reloaded after change")
+	}
+
+	// A broken update keeps the last good certificate.
+	if err := os.WriteFile(certA, []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(leaf(), after) {
+		t.Error("broken certificate replaced the good one")
+	}
+}
+
+func TestNewCertReloader_MissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, err := newCertReloader(
+		filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"),
+	)
+	if err == nil {
+		t.Fatal("expected error for missing files")
+	}
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
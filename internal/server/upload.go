@@ -1,25 +1,49 @@
 package server
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
-	"github.com/wesm/agentsview/internal/db"
 	"github.com/wesm/agentsview/internal/parser"
-	"github.com/wesm/agentsview/internal/timeutil"
+	syncpkg "github.com/wesm/agentsview/internal/sync"
+)
+
+// maxUploadFileSize bounds each uploaded session file, whether
+// sent on its own or inside an archive.
+const maxUploadFileSize = 256 << 20
+
+var errUploadTooLarge = errors.New("file too large")
+
+// Statuses of the files in an upload report.
+const (
+	uploadImported  = "imported"
+	uploadUnchanged = "unchanged"
+	uploadDuplicate = "duplicate"
+	uploadSkipped   = "skipped"
+	uploadFailed    = "error"
 )
 
 type uploadRequest struct {
 	project  string
 	machine  string
+	agent    parser.AgentType
 	file     multipart.File
 	filename string
+	archive  bool
 }
 
 // parseUploadRequest extracts and validates query params and
@@ -31,10 +55,7 @@ func parseUploadRequest(
 	project := strings.TrimSpace(
 		r.URL.Query().Get("project"),
 	)
-	if project == "" {
-		return nil, "project required"
-	}
-	if !isSafeName(project) {
+	if project != "" && !isSafeName(project) {
 		return nil, "invalid project name"
 	}
 
@@ -43,19 +64,25 @@ func parseUploadRequest(
 		machine = "remote"
 	}
 
+	agent := parser.AgentType(r.URL.Query().Get("agent"))
+	if agent != "" && !syncpkg.CanParseSessionFile(agent) {
+		return nil, fmt.Sprintf("unsupported agent: %s", agent)
+	}
+
 	file, header, err := r.FormFile("file")
 	if err != nil {
 		return nil, "file field required"
 	}
 
-	if !strings.HasSuffix(header.Filename, ".jsonl") {
+	ext := uploadExt(header.Filename)
+	if ext == "" {
 		file.Close()
-		return nil, "file must be .jsonl"
+		return nil, "file must be .jsonl, .json or .tar.gz"
 	}
 
 	safeName := filepath.Base(header.Filename)
 	if safeName != header.Filename || !isSafeName(
-		strings.TrimSuffix(safeName, ".jsonl"),
+		strings.TrimSuffix(safeName, ext),
 	) {
 		file.Close()
 		return nil, "invalid filename"
@@ -64,97 +91,172 @@ func parseUploadRequest(
 	return &uploadRequest{
 		project:  project,
 		machine:  machine,
+		agent:    agent,
 		file:     file,
 		filename: safeName,
+		archive:  ext == ".tar.gz" || ext == ".tgz",
 	}, ""
 }
 
-// saveUpload writes the uploaded file to disk under
-// <dataDir>/uploads/<project>/<filename> and returns the
-// destination path.
-func (s *Server) saveUpload(
-	project string, filename string, src io.Reader,
-) (string, error) {
-	uploadDir := filepath.Join(
-		s.cfg.DataDir, "uploads", project,
-	)
-	if err := os.MkdirAll(uploadDir, 0o755); err != nil {
-		return "", fmt.Errorf(
-			"creating upload directory: %w", err,
-		)
+// uploadExt returns the extension of an accepted upload file
+// name, or "" if the name has none.
+func uploadExt(name string) string {
+	for _, ext := range []string{
+		".jsonl", ".json", ".tar.gz", ".tgz",
+	} {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
 	}
+	return ""
+}
+
+// uploadError is a failure to import an uploaded file, with
+// the HTTP status it maps to when the file was sent alone.
+type uploadError struct {
+	status int
+	msg    string
+}
 
-	destPath := filepath.Join(uploadDir, filename)
-	dest, err := os.Create(destPath)
+// uploadFileResult reports what became of one uploaded file.
+type uploadFileResult struct {
+	File        string   `json:"file"`
+	Agent       string   `json:"agent,omitempty"`
+	Status      string   `json:"status"`
+	SessionIDs  []string `json:"session_ids,omitempty"`
+	Messages    int      `json:"messages"`
+	DuplicateOf string   `json:"duplicate_of,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// importUpload detects the format of an uploaded session file,
+// saves it, parses it with the sync engine's parser for that
+// agent, and stores its sessions unless they are already stored
+// from identical content. name must be a safe file name.
+func (s *Server) importUpload(
+	ctx context.Context, req *uploadRequest, name string,
+	data []byte, hash string,
+) ([]parser.ParseResult, uploadFileResult, *uploadError) {
+	res := uploadFileResult{File: name}
+
+	agent := req.agent
+	if agent == "" {
+		agent = parser.DetectAgent(data)
+	}
+	// Other JSONL files are taken for Claude sessions, whose
+	// entry types DetectAgent may not know, if they start like
+	// one or are empty.
+	if agent == "" && strings.HasSuffix(name, ".jsonl") &&
+		(parser.IsClaudeEntry(data) ||
+			len(bytes.TrimSpace(data)) == 0) {
+		agent = parser.AgentClaude
+	}
+	if agent == "" {
+		return nil, res, &uploadError{
+			http.StatusBadRequest, "unrecognized session format",
+		}
+	}
+	res.Agent = string(agent)
+
+	// Claude sessions are named after their file and don't
+	// record their project, so they are kept per project.
+	dir := filepath.Join(s.cfg.DataDir, "uploads")
+	if agent == parser.AgentClaude {
+		if req.project == "" {
+			return nil, res, &uploadError{
+				http.StatusBadRequest, "project required",
+			}
+		}
+		dir = filepath.Join(dir, req.project)
+	} else {
+		dir = filepath.Join(dir, string(agent), hash[:16])
+	}
+	destPath, err := saveUpload(dir, name, data)
 	if err != nil {
+		log.Printf("Error saving upload: %v", err)
+		return nil, res, &uploadError{
+			http.StatusInternalServerError, "failed to save upload",
+		}
+	}
+
+	results, err := syncpkg.ParseSessionFile(
+		agent, destPath, req.project, req.machine,
+	)
+	if err != nil {
+		return nil, res, &uploadError{
+			http.StatusBadRequest,
+			fmt.Sprintf("parsing session: %v", err),
+		}
+	}
+	if len(results) == 0 {
+		return nil, res, &uploadError{
+			http.StatusBadRequest, "no sessions parsed from upload",
+		}
+	}
+	for _, pr := range results {
+		res.SessionIDs = append(res.SessionIDs, pr.Session.ID)
+	}
+	res.Messages = len(results[0].Messages)
+
+	if s.alreadyStored(ctx, results) {
+		res.Status = uploadUnchanged
+		return results, res, nil
+	}
+	if err := s.engine.StoreSessions(results); err != nil {
+		log.Printf("Error saving session to DB: %v", err)
+		return nil, res, &uploadError{
+			http.StatusInternalServerError,
+			"failed to save session to database",
+		}
+	}
+	res.Status = uploadImported
+	return results, res, nil
+}
+
+// alreadyStored reports whether every session was stored before
+// from a file with the same content hash.
+func (s *Server) alreadyStored(
+	ctx context.Context, results []parser.ParseResult,
+) bool {
+	for _, pr := range results {
+		stored, err := s.db.GetSessionFull(ctx, pr.Session.ID)
+		if err != nil || stored == nil || stored.FileHash == nil ||
+			*stored.FileHash != pr.Session.File.Hash {
+			return false
+		}
+	}
+	return true
+}
+
+// saveUpload writes an uploaded file to dir and returns its
+// path.
+func saveUpload(dir, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", fmt.Errorf(
-			"saving uploaded file: %w", err,
+			"creating upload directory: %w", err,
 		)
 	}
-	defer dest.Close()
-
-	if _, err := io.Copy(dest, src); err != nil {
+	destPath := filepath.Join(dir, name)
+	if err := os.WriteFile(destPath, data, 0o644); err != nil {
 		return "", fmt.Errorf(
-			"writing uploaded file: %w", err,
+			"saving uploaded file: %w", err,
 		)
 	}
 	return destPath, nil
 }
 
-// saveSessionToDB maps parsed session and messages to DB types
-// and persists them.
-func (s *Server) saveSessionToDB(
-	sess parser.ParsedSession,
-	msgs []parser.ParsedMessage,
-) error {
-	dbSess := db.Session{
-		ID:               sess.ID,
-		Project:          sess.Project,
-		Machine:          sess.Machine,
-		Agent:            string(sess.Agent),
-		MessageCount:     sess.MessageCount,
-		UserMessageCount: sess.UserMessageCount,
-		ParentSessionID:  strPtr(sess.ParentSessionID),
-		RelationshipType: string(sess.RelationshipType),
-		FilePath:         strPtr(sess.File.Path),
-		FileSize:         int64Ptr(sess.File.Size),
-		FileMtime:        int64Ptr(sess.File.Mtime),
-		FileHash:         strPtr(sess.File.Hash),
-	}
-	if sess.FirstMessage != "" {
-		dbSess.FirstMessage = &sess.FirstMessage
-	}
-	if !sess.StartedAt.IsZero() {
-		dbSess.StartedAt = timeutil.Ptr(sess.StartedAt)
-	}
-	if !sess.EndedAt.IsZero() {
-		dbSess.EndedAt = timeutil.Ptr(sess.EndedAt)
-	}
-
-	if err := s.db.UpsertSession(dbSess); err != nil {
-		return fmt.Errorf("storing session: %w", err)
-	}
-
-	dbMsgs := make([]db.Message, len(msgs))
-	for i, m := range msgs {
-		dbMsgs[i] = db.Message{
-			SessionID:     sess.ID,
-			Ordinal:       m.Ordinal,
-			Role:          string(m.Role),
-			Content:       m.Content,
-			Timestamp:     timeutil.Format(m.Timestamp),
-			HasThinking:   m.HasThinking,
-			HasToolUse:    m.HasToolUse,
-			ContentLength: m.ContentLength,
-		}
-	}
-
-	if err := s.db.ReplaceSessionMessages(
-		sess.ID, dbMsgs,
-	); err != nil {
-		return fmt.Errorf("storing messages: %w", err)
-	}
-	return nil
+// readUploadFile reads at most maxUploadFileSize bytes from r
+// and returns them with their SHA-256 hex digest.
+func readUploadFile(r io.Reader) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxUploadFileSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxUploadFileSize {
+		return nil, "", errUploadTooLarge
+	}
+	sum := sha256.Sum256(data)
+	return data, hex.EncodeToString(sum[:]), nil
 }
 
 func (s *Server) handleUploadSession(
@@ -167,51 +269,160 @@ func (s *Server) handleUploadSession(
 	}
 	defer req.file.Close()
 
-	destPath, err := s.saveUpload(
-		req.project, req.filename, req.file,
-	)
-	if err != nil {
-		log.Printf("Error saving upload: %v", err)
-		writeError(w, http.StatusInternalServerError,
-			"failed to save upload")
+	if req.archive {
+		s.handleUploadArchive(w, r, req)
 		return
 	}
 
-	results, err := parser.ParseClaudeSession(
-		destPath, req.project, req.machine,
-	)
-	if err != nil {
-		writeError(w, http.StatusBadRequest,
-			fmt.Sprintf("parsing session: %v", err))
+	data, hash, err := readUploadFile(req.file)
+	if errors.Is(err, errUploadTooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			"file too large")
 		return
 	}
-	if len(results) == 0 {
-		writeError(w, http.StatusBadRequest,
-			"no sessions parsed from upload")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "reading upload")
 		return
 	}
 
-	parser.InferRelationshipTypes(results)
-
-	for _, pr := range results {
-		if err := s.saveSessionToDB(pr.Session, pr.Messages); err != nil {
-			log.Printf("Error saving session to DB: %v", err)
-			writeError(w, http.StatusInternalServerError,
-				"failed to save session to database")
-			return
-		}
+	results, res, uerr := s.importUpload(
+		r.Context(), req, req.filename, data, hash,
+	)
+	if uerr != nil {
+		writeError(w, uerr.status, uerr.msg)
+		return
 	}
 
 	main := results[0]
 	writeJSON(w, http.StatusOK, map[string]any{
 		"session_id": main.Session.ID,
-		"project":    req.project,
+		"agent":      res.Agent,
+		"status":     res.Status,
+		"project":    main.Session.Project,
 		"machine":    req.machine,
 		"messages":   len(main.Messages),
 		"sessions":   len(results),
 	})
 }
 
+// handleUploadArchive imports every session file in an uploaded
+// .tar.gz and reports on each. Files whose content was already
+// seen earlier in the archive are not imported again. It stops
+// when the client goes away.
+func (s *Server) handleUploadArchive(
+	w http.ResponseWriter, r *http.Request, req *uploadRequest,
+) {
+	ctx := r.Context()
+	gz, err := gzip.NewReader(req.file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid gzip archive")
+		return
+	}
+	defer gz.Close()
+
+	var (
+		files  []uploadFileResult
+		counts = make(map[string]int)
+		seen   = make(map[string]string)
+	)
+	tr := tar.NewReader(gz)
+	for {
+		if err := ctx.Err(); err != nil {
+			log.Printf(
+				"upload archive: stopped after %d files: %v",
+				len(files), err,
+			)
+			return
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest,
+				fmt.Sprintf("reading archive: %v", err))
+			return
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		res := s.importArchiveEntry(ctx, req, hdr.Name, tr, seen)
+		counts[res.Status]++
+		files = append(files, res)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"machine":    req.machine,
+		"files":      files,
+		"imported":   counts[uploadImported],
+		"unchanged":  counts[uploadUnchanged],
+		"duplicates": counts[uploadDuplicate],
+		"skipped":    counts[uploadSkipped],
+		"failed":     counts[uploadFailed],
+	})
+}
+
+// importArchiveEntry imports one file of an uploaded archive.
+// seen maps the content hashes imported so far to the entries
+// they came from.
+func (s *Server) importArchiveEntry(
+	ctx context.Context, req *uploadRequest, entry string, r io.Reader,
+	seen map[string]string,
+) uploadFileResult {
+	name := archiveEntryName(entry)
+	ext := uploadExt(name)
+	if ext != ".jsonl" && ext != ".json" {
+		return uploadFileResult{
+			File: entry, Status: uploadSkipped,
+			Error: "not a session file",
+		}
+	}
+	if !isSafeName(strings.TrimSuffix(name, ext)) {
+		return uploadFileResult{
+			File: entry, Status: uploadFailed,
+			Error: "invalid filename",
+		}
+	}
+
+	data, hash, err := readUploadFile(r)
+	if err != nil {
+		return uploadFileResult{
+			File: entry, Status: uploadFailed, Error: err.Error(),
+		}
+	}
+	if first, ok := seen[hash]; ok {
+		return uploadFileResult{
+			File: entry, Status: uploadDuplicate, DuplicateOf: first,
+		}
+	}
+	seen[hash] = entry
+
+	_, res, uerr := s.importUpload(ctx, req, name, data, hash)
+	res.File = entry
+	if uerr != nil {
+		res.Status = uploadFailed
+		res.Error = uerr.msg
+	}
+	return res
+}
+
+// archiveEntryName returns the name an archive entry is saved
+// under: its base name, except that a Copilot events.jsonl is
+// named after its session directory, from which its session ID
+// is taken.
+func archiveEntryName(entry string) string {
+	name := path.Base(entry)
+	if name != "events.jsonl" {
+		return name
+	}
+	dir := path.Base(path.Dir(entry))
+	if dir == "." || dir == "/" || !isSafeName(dir) {
+		return name
+	}
+	return dir + ".jsonl"
+}
+
 // isSafeName rejects names containing path separators, "..",
 // or starting with "." to prevent directory traversal.
 func isSafeName(name string) bool {
@@ -226,17 +437,3 @@ func isSafeName(name string) bool {
 	}
 	return true
 }
-
-func strPtr(s string) *string {
-	if s == "" {
-		return nil
-	}
-	return &s
-}
-
-func int64Ptr(n int64) *int64 {
-	if n == 0 {
-		return nil
-	}
-	return &n
-}
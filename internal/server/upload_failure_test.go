@@ -21,7 +21,8 @@ func TestUploadSession_SaveFailure(t *testing.T) {
 		t.Fatalf("creating conflict file: %v", err)
 	}
 
-	w := te.upload(t, "test.jsonl", "{}", "project="+projectName)
+	w := te.upload(t, "test.jsonl", "{}",
+		"agent=claude&project="+projectName)
 	assertStatus(t, w, http.StatusInternalServerError)
 	assertErrorResponse(t, w, "failed to save upload")
 }
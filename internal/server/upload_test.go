@@ -0,0 +1,283 @@
+package server_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+type uploadFileReport struct {
+	File        string   `json:"file"`
+	Agent       string   `json:"agent"`
+	Status      string   `json:"status"`
+	SessionIDs  []string `json:"session_ids"`
+	DuplicateOf string   `json:"duplicate_of"`
+	Error       string   `json:"error"`
+}
+
+type uploadArchiveResponse struct {
+	Files      []uploadFileReport `json:"files"`
+	Imported   int                `json:"imported"`
+	Duplicates int                `json:"duplicates"`
+	Skipped    int                `json:"skipped"`
+	Failed     int                `json:"failed"`
+}
+
+// tarGz builds a .tar.gz archive holding the given name/content
+// pairs in order.
+func tarGz(t *testing.T, files ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i := 0; i+1 < len(files); i += 2 {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     files[i],
+			Mode:     0o644,
+			Size:     int64(len(files[i+1])),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatalf("writing tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(files[i+1])); err != nil {
+			t.Fatalf("writing tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("closing tar writer: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("closing gzip writer: %v", err)
+	}
+	return buf.String()
+}
+
+func codexRollout(id string) string {
+	return testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsEarly, id, "/home/user/code/api", "codex_cli_rs").
+		AddCodexMessage(tsEarly, "user", "Add a health check").
+		AddCodexMessage(tsEarlyS5, "assistant", "Added.").
+		String()
+}
+
+func TestUploadSession_DetectsFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		filename  string
+		content   string
+		wantID    string
+		wantAgent string
+	}{
+		{
+			"Codex", "rollout-1.jsonl", codexRollout("cx-1"),
+			"codex:cx-1", "codex",
+		},
+		{
+			"Copilot", "events.jsonl",
+			testjsonl.JoinJSONL(
+				`{"type":"session.start","data":{"sessionId":"cp-1"},"timestamp":"2024-01-01T10:00:00Z"}`,
+				`{"type":"user.message","data":{"content":"Fix it"},"timestamp":"2024-01-01T10:00:01Z"}`,
+			),
+			"copilot:cp-1", "copilot",
+		},
+		{
+			"Gemini", "session-1.json",
+			testjsonl.GeminiSessionJSON(
+				"gm-1", "hash", tsEarly, tsEarlyS5,
+				[]map[string]any{
+					testjsonl.GeminiUserMsg("m1", tsEarly, "Hello"),
+					testjsonl.GeminiAssistantMsg(
+						"m2", tsEarlyS5, "Hi", nil,
+					),
+				},
+			),
+			"gemini:gm-1", "gemini",
+		},
+		{
+			"OpenCode", "export.json",
+			testjsonl.OpenCodeExportJSON(
+				"ses_1", "/home/user/code/web",
+				1704103200000, 1704103205000,
+				[]map[string]any{
+					testjsonl.OpenCodeExportMsg(
+						"msg_1", "user", 1704103200000,
+						map[string]any{"type": "text", "text": "Hi"},
+					),
+				},
+			),
+			"opencode:ses_1", "opencode",
+		},
+		{
+			"Goose", "20240101_1.jsonl",
+			testjsonl.JoinJSONL(
+				testjsonl.GooseMetaJSON(
+					"/home/user/code/api", "Greeting", 10, 20,
+				),
+				testjsonl.GooseMessageJSON(
+					"m1", "user", 1704103200,
+					testjsonl.GooseText("Hello"),
+				),
+				testjsonl.GooseMessageJSON(
+					"m2", "assistant", 1704103205,
+					testjsonl.GooseText("Hi"),
+				),
+			),
+			"goose:20240101_1", "goose",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := setup(t)
+			w := te.upload(t, tt.filename, tt.content, "machine=laptop")
+			assertStatus(t, w, http.StatusOK)
+
+			resp := decode[uploadResponse](t, w)
+			if resp.SessionID != tt.wantID {
+				t.Errorf("session_id = %q, want %q",
+					resp.SessionID, tt.wantID)
+			}
+			if resp.Agent != tt.wantAgent {
+				t.Errorf("agent = %q, want %q",
+					resp.Agent, tt.wantAgent)
+			}
+			sess, err := te.db.GetSession(
+				context.Background(), tt.wantID,
+			)
+			if err != nil || sess == nil {
+				t.Fatalf("GetSession(%q) = %v, %v",
+					tt.wantID, sess, err)
+			}
+			if sess.Machine != "laptop" {
+				t.Errorf("machine = %q", sess.Machine)
+			}
+		})
+	}
+}
+
+func TestUploadSession_AgentParam(t *testing.T) {
+	te := setup(t)
+
+	// Without the parameter the rollout would be detected as
+	// Codex; naming Claude parses it as a (messageless) Claude
+	// session instead.
+	w := te.upload(t, "forced.jsonl", codexRollout("cx-2"),
+		"agent=claude&project=myproj")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[uploadResponse](t, w)
+	if resp.Agent != "claude" || resp.SessionID != "forced" {
+		t.Errorf("got agent %q session %q", resp.Agent, resp.SessionID)
+	}
+
+	w = te.upload(t, "x.jsonl", "{}", "agent=vim")
+	assertStatus(t, w, http.StatusBadRequest)
+	assertErrorResponse(t, w, "unsupported agent: vim")
+
+	w = te.upload(t, "x.json", `{"hello":"world"}`, "")
+	assertStatus(t, w, http.StatusBadRequest)
+	assertErrorResponse(t, w, "unrecognized session format")
+
+	// JSONL files are taken as Claude sessions only if they
+	// look like one.
+	w = te.upload(t, "x.jsonl", `{"hello":"world"}`, "project=myproj")
+	assertStatus(t, w, http.StatusBadRequest)
+	assertErrorResponse(t, w, "unrecognized session format")
+}
+
+func TestUploadSession_Unchanged(t *testing.T) {
+	te := setup(t)
+
+	content := codexRollout("cx-3")
+	w := te.upload(t, "rollout.jsonl", content, "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[uploadResponse](t, w).Status; got != "imported" {
+		t.Errorf("first status = %q, want imported", got)
+	}
+
+	w = te.upload(t, "rollout.jsonl", content, "")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[uploadResponse](t, w).Status; got != "unchanged" {
+		t.Errorf("second status = %q, want unchanged", got)
+	}
+}
+
+func TestUploadSession_Archive(t *testing.T) {
+	te := setup(t)
+
+	claude := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsEarly, "Hello").
+		AddClaudeAssistant(tsEarlyS5, "Hi!").
+		String()
+	archive := tarGz(t,
+		"claude/sess-a.jsonl", claude,
+		"codex/2024/rollout-a.jsonl", codexRollout("cx-a"),
+		"copy/rollout-a.jsonl", codexRollout("cx-a"),
+		"README.md", "# sessions",
+		"broken.json", "{",
+	)
+
+	w := te.upload(t, "sessions.tar.gz", archive, "project=myproj")
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[uploadArchiveResponse](t, w)
+	if resp.Imported != 2 || resp.Duplicates != 1 ||
+		resp.Skipped != 1 || resp.Failed != 1 {
+		t.Errorf("counts = %+v", resp)
+	}
+	if len(resp.Files) != 5 {
+		t.Fatalf("got %d file reports, want 5", len(resp.Files))
+	}
+	if dup := resp.Files[2]; dup.Status != "duplicate" ||
+		dup.DuplicateOf != "codex/2024/rollout-a.jsonl" {
+		t.Errorf("duplicate report = %+v", dup)
+	}
+	if bad := resp.Files[4]; bad.Status != "error" || bad.Error == "" {
+		t.Errorf("broken report = %+v", bad)
+	}
+
+	for _, id := range []string{"sess-a", "codex:cx-a"} {
+		sess, err := te.db.GetSession(context.Background(), id)
+		if err != nil || sess == nil {
+			t.Errorf("GetSession(%q) = %v, %v", id, sess, err)
+		}
+	}
+}
+
+func TestUploadSession_ArchiveStopsWhenCanceled(t *testing.T) {
+	te := setup(t)
+	archive := tarGz(t,
+		"codex/rollout-a.jsonl", codexRollout("cx-a"),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := uploadRequest(t, "sessions.tar.gz", archive, "").WithContext(ctx)
+	te.handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	sess, err := te.db.GetSession(context.Background(), "codex:cx-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess != nil {
+		t.Error("archive imported after the client went away")
+	}
+}
+
+func TestUploadSession_NoWriteTimeout(t *testing.T) {
+	// Imports can outlast the write timeout of other routes.
+	te := setup(t, withWriteTimeout(time.Nanosecond))
+	w := te.upload(t, "rollout-a.jsonl", codexRollout("cx-a"), "")
+	assertStatus(t, w, http.StatusOK)
+}
+
+func TestUploadSession_InvalidArchive(t *testing.T) {
+	te := setup(t)
+	w := te.upload(t, "sessions.tar.gz", "not gzip", "")
+	assertStatus(t, w, http.StatusBadRequest)
+	assertErrorResponse(t, w, "invalid gzip archive")
+}
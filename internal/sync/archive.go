@@ -0,0 +1,333 @@
+package sync
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	gosync "sync"
+
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// Archive keeps gzip-compressed copies of session source files
+// so sessions can be re-parsed after an agent deletes its own
+// transcripts. Blobs are content-addressed by the SHA-256 file
+// hash stored in sessions.file_hash:
+//
+//	<dir>/blobs/<hash[:2]>/<hash>.gz
+//	<dir>/index/<sha256(source path)>.json
+//
+// Each index entry records the most recently archived version
+// of one source path. Superseded blobs are removed by Prune.
+type Archive struct {
+	dir string
+	// mu lets concurrent Stores proceed while keeping Prune
+	// from deleting a blob whose index entry is being written.
+	mu gosync.RWMutex
+}
+
+// ArchiveEntry describes the archived copy of one source file.
+type ArchiveEntry struct {
+	SourcePath string           `json:"source_path"`
+	Agent      parser.AgentType `json:"agent"`
+	Project    string           `json:"project,omitempty"`
+	Hash       string           `json:"hash"`
+	Size       int64            `json:"size"`
+	Mtime      int64            `json:"mtime"`
+}
+
+// NewArchive returns an Archive rooted at dir. The directory
+// is created lazily on the first Store.
+func NewArchive(dir string) *Archive {
+	return &Archive{dir: dir}
+}
+
+// Dir returns the archive root directory.
+func (a *Archive) Dir() string {
+	return a.dir
+}
+
+func isValidHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// BlobPath returns the path of the compressed blob for hash.
+func (a *Archive) BlobPath(hash string) string {
+	return filepath.Join(a.dir, "blobs", hash[:2], hash+".gz")
+}
+
+func (a *Archive) indexPath(sourcePath string) string {
+	sum := sha256.Sum256([]byte(sourcePath))
+	return filepath.Join(
+		a.dir, "index", hex.EncodeToString(sum[:])+".json",
+	)
+}
+
+// Has reports whether sourcePath has an archived version.
+func (a *Archive) Has(sourcePath string) bool {
+	_, err := os.Stat(a.indexPath(sourcePath))
+	return err == nil
+}
+
+// Lookup returns the latest archived version of sourcePath.
+func (a *Archive) Lookup(sourcePath string) (ArchiveEntry, bool) {
+	entry, err := readArchiveEntry(a.indexPath(sourcePath))
+	if err != nil || entry.SourcePath != sourcePath {
+		return ArchiveEntry{}, false
+	}
+	return entry, true
+}
+
+// lookupFile returns the archive entry backing file when it
+// is an archived file. Safe to call on a nil Archive.
+func (a *Archive) lookupFile(
+	file DiscoveredFile,
+) (ArchiveEntry, bool) {
+	if a == nil || !file.Archived {
+		return ArchiveEntry{}, false
+	}
+	return a.Lookup(file.Path)
+}
+
+func readArchiveEntry(path string) (ArchiveEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ArchiveEntry{}, err
+	}
+	var entry ArchiveEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return ArchiveEntry{}, fmt.Errorf(
+			"parsing %s: %w", path, err,
+		)
+	}
+	if !isValidHash(entry.Hash) {
+		return ArchiveEntry{}, fmt.Errorf(
+			"%s: invalid hash %q", path, entry.Hash,
+		)
+	}
+	return entry, nil
+}
+
+// Store archives the file at entry.SourcePath. The blob is
+// written only if no blob with the same hash exists. If the
+// file no longer matches entry.Hash (it changed after being
+// parsed) nothing is recorded and an error is returned; the
+// next sync archives the new content.
+func (a *Archive) Store(entry ArchiveEntry) error {
+	if !isValidHash(entry.Hash) {
+		return fmt.Errorf("invalid hash %q", entry.Hash)
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	blob := a.BlobPath(entry.Hash)
+	if _, err := os.Stat(blob); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("checking blob: %w", err)
+		}
+		if err := writeBlob(entry.SourcePath, blob, entry.Hash); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding archive entry: %w", err)
+	}
+	return writeFileAtomic(a.indexPath(entry.SourcePath), data)
+}
+
+// writeBlob compresses src into dst, verifying that the
+// content hashes to wantHash.
+func writeBlob(src, dst, wantHash string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", src, err)
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("creating archive dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".blob-*")
+	if err != nil {
+		return fmt.Errorf("creating temp blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	zw := gzip.NewWriter(tmp)
+	if _, err := io.Copy(io.MultiWriter(zw, h), in); err != nil {
+		tmp.Close()
+		return fmt.Errorf("compressing %s: %w", src, err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("compressing %s: %w", src, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing blob: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != wantHash {
+		return fmt.Errorf(
+			"%s changed during archive (hash %s, want %s)",
+			src, got, wantHash,
+		)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("writing blob: %w", err)
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating archive dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// Entries returns every index entry in the archive. Unreadable
+// entries are skipped.
+func (a *Archive) Entries() ([]ArchiveEntry, error) {
+	dir := filepath.Join(a.dir, "index")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading archive index: %w", err)
+	}
+	var entries []ArchiveEntry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		entry, err := readArchiveEntry(filepath.Join(dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Extract decompresses the blob for entry into destDir and
+// returns the restored file path. The restored file keeps the
+// source file's name and parent directory name, which parsers
+// use to derive session IDs.
+func (a *Archive) Extract(
+	entry ArchiveEntry, destDir string,
+) (string, error) {
+	if !isValidHash(entry.Hash) {
+		return "", fmt.Errorf("invalid hash %q", entry.Hash)
+	}
+	in, err := os.Open(a.BlobPath(entry.Hash))
+	if err != nil {
+		return "", fmt.Errorf("opening blob: %w", err)
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return "", fmt.Errorf("reading blob %s: %w", entry.Hash, err)
+	}
+	defer zr.Close()
+
+	dst := filepath.Join(
+		destDir,
+		filepath.Base(filepath.Dir(entry.SourcePath)),
+		filepath.Base(entry.SourcePath),
+	)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", fmt.Errorf("creating restore dir: %w", err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", fmt.Errorf("creating %s: %w", dst, err)
+	}
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), zr)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("restoring blob %s: %w", entry.Hash, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != entry.Hash {
+		return "", fmt.Errorf(
+			"blob %s is corrupt (hash %s)", entry.Hash, got,
+		)
+	}
+	return dst, nil
+}
+
+// Prune removes blobs that no index entry references, i.e.
+// older versions of files that have since grown. Returns the
+// number of blobs removed.
+func (a *Archive) Prune() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries, err := a.Entries()
+	if err != nil {
+		return 0, err
+	}
+	live := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		live[e.Hash] = true
+	}
+
+	removed := 0
+	root := filepath.Join(a.dir, "blobs")
+	err = filepath.WalkDir(root, func(
+		path string, d fs.DirEntry, err error,
+	) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		hash, ok := strings.CutSuffix(d.Name(), ".gz")
+		if d.IsDir() || !ok || live[hash] {
+			return nil
+		}
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("pruning archive: %w", err)
+	}
+	return removed, nil
+}
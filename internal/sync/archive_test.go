@@ -0,0 +1,119 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+func archiveSource(
+	t *testing.T, a *Archive, path, content string,
+) ArchiveEntry {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := ComputeFileHash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := ArchiveEntry{
+		SourcePath: path,
+		Agent:      parser.AgentClaude,
+		Project:    "proj",
+		Hash:       hash,
+		Size:       int64(len(content)),
+		Mtime:      42,
+	}
+	if err := a.Store(entry); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	return entry
+}
+
+func TestArchiveStoreExtract(t *testing.T) {
+	a := NewArchive(t.TempDir())
+	srcDir := filepath.Join(t.TempDir(), "proj")
+	if err := os.MkdirAll(srcDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(srcDir, "sess.jsonl")
+	entry := archiveSource(t, a, src, "line one\nline two\n")
+
+	if _, err := os.Stat(a.BlobPath(entry.Hash)); err != nil {
+		t.Fatalf("blob not written: %v", err)
+	}
+	got, ok := a.Lookup(src)
+	if !ok || got != entry {
+		t.Fatalf("Lookup = %+v, %v; want %+v", got, ok, entry)
+	}
+
+	if err := os.Remove(src); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := a.Extract(entry, t.TempDir())
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if !strings.HasSuffix(restored, filepath.Join("proj", "sess.jsonl")) {
+		t.Errorf("restored path = %q, want .../proj/sess.jsonl", restored)
+	}
+	data, err := os.ReadFile(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "line one\nline two\n" {
+		t.Errorf("restored content = %q", data)
+	}
+}
+
+func TestArchiveStoreRejectsChangedFile(t *testing.T) {
+	a := NewArchive(t.TempDir())
+	src := filepath.Join(t.TempDir(), "sess.jsonl")
+	entry := archiveSource(t, a, src, "v1\n")
+
+	// Record a stale hash for new content: nothing is stored.
+	if err := os.WriteFile(src, []byte("v2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stale := entry
+	stale.Hash = strings.Repeat("a", 64)
+	if err := a.Store(stale); err == nil {
+		t.Fatal("Store with stale hash should fail")
+	}
+	if got, _ := a.Lookup(src); got != entry {
+		t.Errorf("index changed after failed store: %+v", got)
+	}
+}
+
+func TestArchivePrune(t *testing.T) {
+	a := NewArchive(t.TempDir())
+	src := filepath.Join(t.TempDir(), "sess.jsonl")
+	v1 := archiveSource(t, a, src, "v1\n")
+	v2 := archiveSource(t, a, src, "v1\nv2\n")
+
+	n, err := a.Prune()
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("pruned %d blobs, want 1", n)
+	}
+	if _, err := os.Stat(a.BlobPath(v1.Hash)); !os.IsNotExist(err) {
+		t.Error("superseded blob should be removed")
+	}
+	if _, err := os.Stat(a.BlobPath(v2.Hash)); err != nil {
+		t.Errorf("current blob missing: %v", err)
+	}
+
+	entries, err := a.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0] != v2 {
+		t.Errorf("Entries = %+v, want [%+v]", entries, v2)
+	}
+}
@@ -0,0 +1,197 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/gitlog"
+)
+
+// CommitWindow is how long after a session ends a commit that
+// changes the files it edited is still linked to it.
+const CommitWindow = 2 * time.Hour
+
+// gitLogTimeout bounds the git log of one repository.
+const gitLogTimeout = 30 * time.Second
+
+// SetCommitCorrelation enables linking sessions to the commits
+// of the local git repositories they edited. Call before the
+// first sync.
+func (e *Engine) SetCommitCorrelation(enabled bool) {
+	e.correlateCommits = enabled
+}
+
+// commitSpan is a time span in which commits are linked to
+// sessions.
+type commitSpan struct{ since, until time.Time }
+
+// repoLog is the git log of one repository over the windows of
+// every session being scanned.
+type repoLog struct {
+	commits []gitlog.Commit
+	err     error
+}
+
+// linkCommits links each session that edited files in a git
+// repository to the commits authored from its start until
+// CommitWindow after its end that changed one of those files.
+// Sessions are scanned again while their window is open at now.
+// It returns the number of sessions scanned.
+func (e *Engine) linkCommits(now time.Time) (int, error) {
+	candidates, err := e.db.CommitCandidates()
+	if err != nil {
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+	if !gitlog.Available() {
+		return 0, errors.New("git not found on PATH")
+	}
+
+	windows := make([]commitSpan, len(candidates))
+	spans := map[string]commitSpan{}
+	for i, c := range candidates {
+		w, ok := commitWindow(c)
+		if !ok {
+			continue
+		}
+		windows[i] = w
+		for root := range c.Files {
+			span, seen := spans[root]
+			if !seen || w.since.Before(span.since) {
+				span.since = w.since
+			}
+			if !seen || w.until.After(span.until) {
+				span.until = w.until
+			}
+			spans[root] = span
+		}
+	}
+
+	logs := make(map[string]repoLog, len(spans))
+	for root, span := range spans {
+		logs[root] = readRepoLog(root, span.since, span.until)
+	}
+
+	n := 0
+	for i, c := range candidates {
+		w := windows[i]
+		if w.since.IsZero() {
+			continue
+		}
+		commits, err := matchCommits(c, logs, w.since, w.until)
+		if err != nil {
+			log.Printf("commits: session %s: %v", c.SessionID, err)
+			continue
+		}
+		complete := now.After(w.until)
+		if err := e.db.SaveSessionCommits(
+			c.SessionID, c.EndedAt, complete, commits,
+		); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// commitWindow returns the time span in which commits are
+// linked to the session.
+func commitWindow(c db.CommitCandidate) (w commitSpan, ok bool) {
+	start, err := time.Parse(time.RFC3339Nano, c.StartedAt)
+	if err != nil {
+		return w, false
+	}
+	end := start
+	if c.EndedAt != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *c.EndedAt); err == nil &&
+			t.After(start) {
+			end = t
+		}
+	}
+	w.since = start
+	w.until = end.Add(CommitWindow)
+	return w, true
+}
+
+// readRepoLog reads the commits of the repository at root. A
+// repository that no longer exists has no commits.
+func readRepoLog(root string, since, until time.Time) repoLog {
+	if _, err := os.Stat(root); errors.Is(err, os.ErrNotExist) {
+		return repoLog{}
+	}
+	ctx, cancel := context.WithTimeout(
+		context.Background(), gitLogTimeout,
+	)
+	defer cancel()
+	commits, err := gitlog.Log(ctx, root, since, until)
+	return repoLog{commits: commits, err: err}
+}
+
+// matchCommits returns the commits in [since, until] that
+// changed a file the session edited.
+func matchCommits(
+	c db.CommitCandidate, logs map[string]repoLog,
+	since, until time.Time,
+) ([]db.SessionCommit, error) {
+	var out []db.SessionCommit
+	for root, paths := range c.Files {
+		rl := logs[root]
+		if rl.err != nil {
+			return nil, rl.err
+		}
+		edited := make(map[string]bool, len(paths))
+		for _, p := range paths {
+			edited[p] = true
+		}
+		for _, commit := range rl.commits {
+			if commit.AuthoredAt.Before(since) ||
+				commit.AuthoredAt.After(until) {
+				continue
+			}
+			var shared []string
+			for _, f := range commit.Files {
+				if edited[f] {
+					shared = append(shared, f)
+				}
+			}
+			if len(shared) == 0 {
+				continue
+			}
+			sort.Strings(shared)
+			out = append(out, db.SessionCommit{
+				SessionID:  c.SessionID,
+				SHA:        commit.SHA,
+				RepoRoot:   root,
+				Author:     commit.Author,
+				Email:      commit.Email,
+				AuthoredAt: commit.AuthoredAt.UTC().Format(time.RFC3339),
+				Subject:    commit.Subject,
+				Files:      shared,
+			})
+		}
+	}
+	return out, nil
+}
+
+// logCommitLinks runs linkCommits when commit correlation is
+// enabled.
+func (e *Engine) logCommitLinks(verbose bool) {
+	if !e.correlateCommits {
+		return
+	}
+	n, err := e.linkCommits(time.Now())
+	if err != nil {
+		log.Printf("commits: %v", err)
+		return
+	}
+	if verbose && n > 0 {
+		log.Printf("commits: scanned %d session(s)", n)
+	}
+}
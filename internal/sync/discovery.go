@@ -44,6 +44,13 @@ type DiscoveredFile struct {
 	Path    string
 	Project string           // pre-extracted project name
 	Agent   parser.AgentType // AgentClaude or AgentCodex
+	// Cwd is the working directory of the session when
+	// discovery knows it (Gemini project directories and Aider
+	// repositories).
+	Cwd string
+	// Archived is set when Path no longer exists and the file
+	// is restored from the session archive instead.
+	Archived bool
 }
 
 // DiscoverClaudeProjects finds all project directories under the
@@ -348,6 +355,7 @@ func DiscoverGeminiSessions(
 	}
 
 	projectMap := buildGeminiProjectMap(geminiDir)
+	projectDirs := buildGeminiProjectDirs(geminiDir)
 
 	var files []DiscoveredFile
 	for _, hd := range hashDirs {
@@ -376,6 +384,7 @@ func DiscoverGeminiSessions(
 				Path:    filepath.Join(chatsDir, name),
 				Project: project,
 				Agent:   parser.AgentGemini,
+				Cwd:     projectDirs[hash],
 			})
 		}
 	}
@@ -471,14 +480,47 @@ func buildGeminiProjectMap(
 	geminiDir string,
 ) map[string]string {
 	result := make(map[string]string)
+	for _, paths := range geminiProjectPaths(geminiDir) {
+		addProjectPaths(result, paths)
+	}
+	return result
+}
+
+// buildGeminiProjectDirs maps the same directory names as
+// buildGeminiProjectMap to the absolute project path, which is
+// the working directory of the sessions stored under them.
+func buildGeminiProjectDirs(
+	geminiDir string,
+) map[string]string {
+	result := make(map[string]string)
+	for _, paths := range geminiProjectPaths(geminiDir) {
+		for _, absPath := range sortedKeys(paths) {
+			keys := []string{geminiPathHash(absPath)}
+			if name := paths[absPath]; name != "" {
+				keys = append(keys, name)
+			}
+			for _, k := range keys {
+				if _, exists := result[k]; !exists {
+					result[k] = absPath
+				}
+			}
+		}
+	}
+	return result
+}
 
+// geminiProjectPaths returns the project paths of
+// ~/.gemini/projects.json and then ~/.gemini/trustedFolders.json,
+// each mapping an absolute path to its short project name.
+func geminiProjectPaths(geminiDir string) []map[string]string {
+	var out []map[string]string
 	data, err := os.ReadFile(
 		filepath.Join(geminiDir, "projects.json"),
 	)
 	if err == nil {
 		var pf geminiProjectsFile
 		if err := json.Unmarshal(data, &pf); err == nil {
-			addProjectPaths(result, pf.Projects)
+			out = append(out, pf.Projects)
 		}
 	}
 
@@ -498,11 +540,10 @@ func buildGeminiProjectMap(
 			for _, p := range tf.TrustedFolders {
 				paths[p] = ""
 			}
-			addProjectPaths(result, paths)
+			out = append(out, paths)
 		}
 	}
-
-	return result
+	return out
 }
 
 // addProjectPaths adds hash and name entries for the given
@@ -515,13 +556,7 @@ func addProjectPaths(
 ) {
 	// Sort keys for deterministic first-seen-wins on
 	// duplicate short names.
-	sorted := make([]string, 0, len(paths))
-	for absPath := range paths {
-		sorted = append(sorted, absPath)
-	}
-	sort.Strings(sorted)
-
-	for _, absPath := range sorted {
+	for _, absPath := range sortedKeys(paths) {
 		name := paths[absPath]
 		project := parser.ExtractProjectFromCwd(absPath)
 		if project == "" {
@@ -539,6 +574,15 @@ func addProjectPaths(
 	}
 }
 
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // geminiPathHash computes the SHA-256 hex hash of a path,
 // matching Gemini CLI's project hash algorithm.
 func geminiPathHash(path string) string {
@@ -649,6 +693,37 @@ func DiscoverCopilotSessions(
 	return files
 }
 
+// DiscoverAiderSessions returns the Aider chat history of the
+// repository at repoDir, if it has one.
+func DiscoverAiderSessions(repoDir string) []DiscoveredFile {
+	if repoDir == "" {
+		return nil
+	}
+	path := filepath.Join(repoDir, parser.AiderHistoryFile)
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return nil
+	}
+	return []DiscoveredFile{{
+		Path:  path,
+		Agent: parser.AgentAider,
+		Cwd:   repoDir,
+	}}
+}
+
+// FindAiderSourceFile returns the Aider chat history of repoDir
+// when it holds the session with the given ID.
+func FindAiderSourceFile(repoDir, sessionID string) string {
+	if repoDir == "" ||
+		!strings.HasPrefix(sessionID, parser.AiderSessionPrefix(repoDir)) {
+		return ""
+	}
+	path := filepath.Join(repoDir, parser.AiderHistoryFile)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
 // FindCopilotSourceFile locates a Copilot session file by
 // UUID. Checks both bare (<uuid>.jsonl) and directory
 // (<uuid>/events.jsonl) layouts.
@@ -676,3 +751,148 @@ func FindCopilotSourceFile(
 
 	return ""
 }
+
+// DiscoverClineTasks finds the tasks of a Cline or Roo Code
+// storage directory (<clineDir>/tasks/<taskId>/
+// api_conversation_history.json).
+func DiscoverClineTasks(clineDir string) []DiscoveredFile {
+	if clineDir == "" {
+		return nil
+	}
+
+	tasksDir := filepath.Join(clineDir, "tasks")
+	entries, err := os.ReadDir(tasksDir)
+	if err != nil {
+		return nil
+	}
+
+	var files []DiscoveredFile
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(
+			tasksDir, entry.Name(), parser.ClineHistoryFile,
+		)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, DiscoveredFile{
+				Path:  path,
+				Agent: parser.AgentCline,
+			})
+		}
+	}
+	return files
+}
+
+// FindClineSourceFile locates the API conversation of a Cline
+// or Roo Code task by task ID.
+func FindClineSourceFile(clineDir, taskID string) string {
+	if clineDir == "" || !isValidSessionID(taskID) {
+		return ""
+	}
+	path := filepath.Join(
+		clineDir, "tasks", taskID, parser.ClineHistoryFile,
+	)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// DiscoverGooseSessions finds the session files of a Goose data
+// directory (<gooseDir>/sessions/<id>.jsonl).
+func DiscoverGooseSessions(gooseDir string) []DiscoveredFile {
+	if gooseDir == "" {
+		return nil
+	}
+	return discoverFlatFiles(
+		filepath.Join(gooseDir, "sessions"), ".jsonl",
+		parser.AgentGoose,
+	)
+}
+
+// FindGooseSourceFile locates a Goose session file by ID.
+func FindGooseSourceFile(gooseDir, rawID string) string {
+	if gooseDir == "" {
+		return ""
+	}
+	return findFlatFile(
+		filepath.Join(gooseDir, "sessions"), rawID, ".jsonl",
+	)
+}
+
+// DiscoverAmpThreads finds the thread files of an Amp data
+// directory (<ampDir>/threads/<id>.json).
+func DiscoverAmpThreads(ampDir string) []DiscoveredFile {
+	if ampDir == "" {
+		return nil
+	}
+	return discoverFlatFiles(
+		filepath.Join(ampDir, "threads"), ".json",
+		parser.AgentAmp,
+	)
+}
+
+// FindAmpSourceFile locates an Amp thread file by thread ID.
+func FindAmpSourceFile(ampDir, threadID string) string {
+	if ampDir == "" {
+		return ""
+	}
+	return findFlatFile(
+		filepath.Join(ampDir, "threads"), threadID, ".json",
+	)
+}
+
+// DiscoverOTelSessions finds the session files the OTel import
+// wrote to otelDir (<otelDir>/<id>.jsonl).
+func DiscoverOTelSessions(otelDir string) []DiscoveredFile {
+	if otelDir == "" {
+		return nil
+	}
+	return discoverFlatFiles(otelDir, ".jsonl", parser.AgentOTel)
+}
+
+// FindOTelSourceFile locates an imported OTel session file by
+// ID.
+func FindOTelSourceFile(otelDir, rawID string) string {
+	if otelDir == "" {
+		return ""
+	}
+	return findFlatFile(otelDir, rawID, ".jsonl")
+}
+
+// discoverFlatFiles returns the files of dir with the given
+// extension, for agents that keep one file per session in a
+// single directory.
+func discoverFlatFiles(
+	dir, ext string, agent parser.AgentType,
+) []DiscoveredFile {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []DiscoveredFile
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+		files = append(files, DiscoveredFile{
+			Path:  filepath.Join(dir, entry.Name()),
+			Agent: agent,
+		})
+	}
+	return files
+}
+
+// findFlatFile returns the path of the file for id in dir, or
+// "" if there is none.
+func findFlatFile(dir, id, ext string) string {
+	if !isValidSessionID(id) {
+		return ""
+	}
+	path := filepath.Join(dir, id+ext)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
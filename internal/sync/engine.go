@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	gosync "sync"
 	"time"
@@ -33,6 +34,11 @@ type Engine struct {
 	copilotDirs   []string
 	geminiDirs    []string
 	opencodeDirs  []string
+	aiderDirs     []string
+	clineDirs     []string
+	cursorDirs    []string
+	gooseDirs     []string
+	ampDirs       []string
 	machine       string
 	syncMu        gosync.Mutex // serializes full sync runs
 	mu            gosync.RWMutex
@@ -45,6 +51,29 @@ type Engine struct {
 	// retried when its mtime changes.
 	skipMu    gosync.RWMutex
 	skipCache map[string]int64
+	// archive, when set, receives a compressed copy of every
+	// synced source file and backs files that have since been
+	// deleted from the agent directories.
+	archive *Archive
+	// correlateCommits links sessions to the commits of the
+	// git repositories they edited after each sync.
+	correlateCommits bool
+	// checkpoints records where the last parse of each Claude
+	// and Codex file stopped, keyed by path, so appended lines
+	// can be parsed without re-reading the whole file.
+	cpMu        gosync.Mutex
+	checkpoints map[string]fileCheckpoint
+	// cursorMtimes records the mtime of each Cursor state
+	// database at its last sync, so unchanged databases are not
+	// opened. Guarded by syncMu.
+	cursorMtimes map[string]int64
+	// otelDir holds the session files written by ImportOTel.
+	// otelTraces maps trace IDs to the conversation key of
+	// their session, so spans exported later without a
+	// conversation ID join it.
+	otelDir    string
+	otelMu     gosync.Mutex
+	otelTraces map[string]string
 }
 
 // NewEngine creates a sync engine. It pre-populates the
@@ -53,7 +82,9 @@ type Engine struct {
 func NewEngine(
 	database *db.DB,
 	claudeDirs, codexDirs, copilotDirs,
-	geminiDirs, opencodeDirs []string, machine string,
+	geminiDirs, opencodeDirs, aiderDirs, clineDirs,
+	cursorDirs, gooseDirs, ampDirs []string,
+	machine string,
 ) *Engine {
 	skipCache := make(map[string]int64)
 	if loaded, err := database.LoadSkippedFiles(); err == nil {
@@ -69,11 +100,24 @@ func NewEngine(
 		copilotDirs:  copilotDirs,
 		geminiDirs:   geminiDirs,
 		opencodeDirs: opencodeDirs,
+		aiderDirs:    aiderDirs,
+		clineDirs:    clineDirs,
+		cursorDirs:   cursorDirs,
+		gooseDirs:    gooseDirs,
+		ampDirs:      ampDirs,
 		machine:      machine,
 		skipCache:    skipCache,
+		checkpoints:  make(map[string]fileCheckpoint),
+		cursorMtimes: make(map[string]int64),
+		otelTraces:   make(map[string]string),
 	}
 }
 
+// SetArchive enables archive mode. Call before the first sync.
+func (e *Engine) SetArchive(a *Archive) {
+	e.archive = a
+}
+
 // LastSync returns the time of the last completed sync.
 func (e *Engine) LastSync() time.Time {
 	e.mu.RLock()
@@ -119,13 +163,20 @@ func (e *Engine) SyncPaths(paths []string) {
 	}
 }
 
+// geminiProjects caches the project names and directories of a
+// Gemini directory while paths are classified.
+type geminiProjects struct {
+	names map[string]string
+	dirs  map[string]string
+}
+
 // classifyPaths maps changed file system paths to
 // DiscoveredFile structs, filtering out paths that don't
 // match known session file patterns.
 func (e *Engine) classifyPaths(
 	paths []string,
 ) []DiscoveredFile {
-	geminiProjectsByDir := make(map[string]map[string]string)
+	geminiProjectsByDir := make(map[string]geminiProjects)
 	var files []DiscoveredFile
 	for _, p := range paths {
 		if df, ok := e.classifyOnePath(
@@ -156,7 +207,7 @@ func isUnder(dir, path string) (string, bool) {
 
 func (e *Engine) classifyOnePath(
 	path string,
-	geminiProjectsByDir map[string]map[string]string,
+	geminiProjectsByDir map[string]geminiProjects,
 ) (DiscoveredFile, bool) {
 	sep := string(filepath.Separator)
 
@@ -291,17 +342,105 @@ func (e *Engine) classifyOnePath(
 				continue
 			}
 			dirName := parts[1]
-			if _, ok := geminiProjectsByDir[geminiDir]; !ok {
-				geminiProjectsByDir[geminiDir] =
-					buildGeminiProjectMap(geminiDir)
+			projects, ok := geminiProjectsByDir[geminiDir]
+			if !ok {
+				projects = geminiProjects{
+					names: buildGeminiProjectMap(geminiDir),
+					dirs:  buildGeminiProjectDirs(geminiDir),
+				}
+				geminiProjectsByDir[geminiDir] = projects
 			}
-			project := resolveGeminiProject(
-				dirName, geminiProjectsByDir[geminiDir],
-			)
+			project := resolveGeminiProject(dirName, projects.names)
 			return DiscoveredFile{
 				Path:    path,
 				Project: project,
 				Agent:   parser.AgentGemini,
+				Cwd:     projects.dirs[dirName],
+			}, true
+		}
+	}
+
+	// Aider: <repoDir>/.aider.chat.history.md
+	for _, repoDir := range e.aiderDirs {
+		if repoDir == "" {
+			continue
+		}
+		if rel, ok := isUnder(repoDir, path); ok &&
+			rel == parser.AiderHistoryFile {
+			return DiscoveredFile{
+				Path:  path,
+				Agent: parser.AgentAider,
+				Cwd:   repoDir,
+			}, true
+		}
+	}
+
+	// Cline: <clineDir>/tasks/<taskId>/api_conversation_history.json
+	// A change to the task's ui_messages.json syncs the task too.
+	for _, clineDir := range e.clineDirs {
+		if clineDir == "" {
+			continue
+		}
+		tasksDir := filepath.Join(clineDir, "tasks")
+		if rel, ok := isUnder(tasksDir, path); ok {
+			parts := strings.Split(rel, sep)
+			if len(parts) != 2 {
+				continue
+			}
+			switch parts[1] {
+			case parser.ClineHistoryFile, parser.ClineUIMessagesFile:
+			default:
+				continue
+			}
+			return DiscoveredFile{
+				Path: filepath.Join(
+					tasksDir, parts[0], parser.ClineHistoryFile,
+				),
+				Agent: parser.AgentCline,
+			}, true
+		}
+	}
+
+	// Goose: <gooseDir>/sessions/<id>.jsonl
+	for _, gooseDir := range e.gooseDirs {
+		if gooseDir == "" {
+			continue
+		}
+		sessionsDir := filepath.Join(gooseDir, "sessions")
+		if rel, ok := isUnder(sessionsDir, path); ok &&
+			!strings.Contains(rel, sep) &&
+			strings.HasSuffix(rel, ".jsonl") {
+			return DiscoveredFile{
+				Path:  path,
+				Agent: parser.AgentGoose,
+			}, true
+		}
+	}
+
+	// Amp: <ampDir>/threads/<id>.json
+	for _, ampDir := range e.ampDirs {
+		if ampDir == "" {
+			continue
+		}
+		threadsDir := filepath.Join(ampDir, "threads")
+		if rel, ok := isUnder(threadsDir, path); ok &&
+			!strings.Contains(rel, sep) &&
+			strings.HasSuffix(rel, ".json") {
+			return DiscoveredFile{
+				Path:  path,
+				Agent: parser.AgentAmp,
+			}, true
+		}
+	}
+
+	// OTel: <otelDir>/<key>.jsonl
+	if e.otelDir != "" {
+		if rel, ok := isUnder(e.otelDir, path); ok &&
+			!strings.Contains(rel, sep) &&
+			strings.HasSuffix(rel, ".jsonl") {
+			return DiscoveredFile{
+				Path:  path,
+				Agent: parser.AgentOTel,
 			}, true
 		}
 	}
@@ -326,6 +465,13 @@ func (e *Engine) ResyncAll(
 	e.skipCache = make(map[string]int64)
 	e.skipMu.Unlock()
 
+	// Also forget parse checkpoints so every file is parsed
+	// from the start.
+	e.cpMu.Lock()
+	e.checkpoints = make(map[string]fileCheckpoint)
+	e.cpMu.Unlock()
+	e.cursorMtimes = make(map[string]int64)
+
 	// 2. Clear persisted skip cache.
 	if err := e.db.ReplaceSkippedFiles(
 		map[string]int64{},
@@ -352,8 +498,22 @@ func (e *Engine) syncAllLocked(
 	onProgress ProgressFunc,
 ) SyncStats {
 	t0 := time.Now()
+	verbose := onProgress == nil
 
-	var claude, codex, copilot, gemini []DiscoveredFile
+	// Index the files of tool calls stored before the file
+	// index existed; new tool calls are indexed on insert.
+	n, err := e.db.BackfillToolFiles(func(
+		tc db.ToolCall, cwd, repoRoot string,
+	) []db.ToolFile {
+		return toolFiles(tc.ToolName, tc.InputJSON, cwd, repoRoot)
+	})
+	if err != nil {
+		log.Printf("file index: %v", err)
+	} else if verbose && n > 0 {
+		log.Printf("file index: indexed %d tool calls", n)
+	}
+
+	var claude, codex, copilot, gemini, aider, cline, goose, amp []DiscoveredFile
 	for _, d := range e.claudeDirs {
 		claude = append(claude, DiscoverClaudeProjects(d)...)
 	}
@@ -366,22 +526,50 @@ func (e *Engine) syncAllLocked(
 	for _, d := range e.geminiDirs {
 		gemini = append(gemini, DiscoverGeminiSessions(d)...)
 	}
+	for _, d := range e.aiderDirs {
+		aider = append(aider, DiscoverAiderSessions(d)...)
+	}
+	for _, d := range e.clineDirs {
+		cline = append(cline, DiscoverClineTasks(d)...)
+	}
+	for _, d := range e.gooseDirs {
+		goose = append(goose, DiscoverGooseSessions(d)...)
+	}
+	for _, d := range e.ampDirs {
+		amp = append(amp, DiscoverAmpThreads(d)...)
+	}
+	otel := DiscoverOTelSessions(e.otelDir)
 
 	all := make(
 		[]DiscoveredFile, 0,
-		len(claude)+len(codex)+len(copilot)+len(gemini),
+		len(claude)+len(codex)+len(copilot)+len(gemini)+
+			len(aider)+len(cline)+len(goose)+len(amp)+len(otel),
 	)
 	all = append(all, claude...)
 	all = append(all, codex...)
 	all = append(all, copilot...)
 	all = append(all, gemini...)
-
-	verbose := onProgress == nil
+	all = append(all, aider...)
+	all = append(all, cline...)
+	all = append(all, goose...)
+	all = append(all, amp...)
+	all = append(all, otel...)
+
+	if e.archive != nil {
+		archived := e.discoverArchived(all)
+		all = append(all, archived...)
+		if verbose && len(archived) > 0 {
+			log.Printf(
+				"restoring %d deleted file(s) from archive",
+				len(archived),
+			)
+		}
+	}
 
 	if verbose {
 		log.Printf(
-			"discovered %d files (%d claude, %d codex, %d copilot, %d gemini) in %s",
-			len(all), len(claude), len(codex), len(copilot), len(gemini),
+			"discovered %d files (%d claude, %d codex, %d copilot, %d gemini, %d aider, %d cline, %d goose, %d amp, %d otel) in %s",
+			len(all), len(claude), len(codex), len(copilot), len(gemini), len(aider), len(cline), len(goose), len(amp), len(otel),
 			time.Since(t0).Round(time.Millisecond),
 		)
 	}
@@ -404,32 +592,22 @@ func (e *Engine) syncAllLocked(
 		)
 	}
 
-	// Sync OpenCode sessions (DB-backed, not file-based).
-	// Uses full replace because OpenCode messages can change
-	// in place (streaming updates, tool result pairing).
-	tOC := time.Now()
-	ocPending := e.syncOpenCode()
-	if len(ocPending) > 0 {
-		stats.TotalSessions += len(ocPending)
-		stats.RecordSynced(len(ocPending))
-		tWrite := time.Now()
-		for _, pw := range ocPending {
-			e.writeSessionFull(pw)
-		}
-		if verbose {
-			log.Printf(
-				"opencode write: %d sessions in %s",
-				len(ocPending),
-				time.Since(tWrite).Round(time.Millisecond),
-			)
+	// Sync OpenCode and Cursor sessions (DB-backed, not
+	// file-based). Uses full replace because their messages
+	// can change in place (streaming updates, tool result
+	// pairing).
+	e.syncDBSessions("opencode", e.syncOpenCode, &stats, verbose)
+	e.syncDBSessions("cursor", e.syncCursor, &stats, verbose)
+
+	e.logCommitLinks(verbose)
+
+	if e.archive != nil {
+		if n, err := e.archive.Prune(); err != nil {
+			log.Printf("archive: %v", err)
+		} else if verbose && n > 0 {
+			log.Printf("archive: pruned %d superseded file(s)", n)
 		}
 	}
-	if verbose {
-		log.Printf(
-			"opencode sync: %s",
-			time.Since(tOC).Round(time.Millisecond),
-		)
-	}
 
 	tPersist := time.Now()
 	skipCount := e.persistSkipCache()
@@ -448,6 +626,37 @@ func (e *Engine) syncAllLocked(
 	return stats
 }
 
+// syncDBSessions runs the sync of a database-backed agent and
+// writes the sessions it returns in full.
+func (e *Engine) syncDBSessions(
+	label string, run func() []pendingWrite,
+	stats *SyncStats, verbose bool,
+) {
+	t0 := time.Now()
+	pending := run()
+	if len(pending) > 0 {
+		stats.TotalSessions += len(pending)
+		stats.RecordSynced(len(pending))
+		tWrite := time.Now()
+		for _, pw := range pending {
+			e.writeSessionFull(pw)
+		}
+		if verbose {
+			log.Printf(
+				"%s write: %d sessions in %s",
+				label, len(pending),
+				time.Since(tWrite).Round(time.Millisecond),
+			)
+		}
+	}
+	if verbose {
+		log.Printf(
+			"%s sync: %s",
+			label, time.Since(t0).Round(time.Millisecond),
+		)
+	}
+}
+
 // syncOpenCode syncs sessions from OpenCode SQLite databases.
 // Uses per-session time_updated to detect changes, so only
 // modified sessions are fully parsed. Returns pending writes.
@@ -517,6 +726,91 @@ func (e *Engine) syncOneOpenCode(dir string) []pendingWrite {
 	return pending
 }
 
+// syncCursor syncs chats from Cursor state databases. A
+// database whose mtime is unchanged since the last sync is not
+// opened; otherwise only chats updated since they were stored
+// are parsed. Returns pending writes.
+func (e *Engine) syncCursor() []pendingWrite {
+	var allPending []pendingWrite
+	for _, dir := range e.cursorDirs {
+		if dir == "" {
+			continue
+		}
+		allPending = append(allPending, e.syncOneCursor(dir)...)
+	}
+	return allPending
+}
+
+// syncOneCursor handles a single Cursor user directory.
+func (e *Engine) syncOneCursor(dir string) []pendingWrite {
+	dbPath := parser.CursorGlobalDB(dir)
+	mtime := cursorDBMtime(dbPath)
+	if mtime == 0 || e.cursorMtimes[dbPath] == mtime {
+		return nil
+	}
+
+	metas, err := parser.ListCursorComposerMeta(dbPath)
+	if err != nil {
+		log.Printf("sync cursor: %v", err)
+		return nil
+	}
+
+	var changed []string
+	for _, m := range metas {
+		_, storedMtime, ok :=
+			e.db.GetFileInfoByPath(m.VirtualPath)
+		if ok && storedMtime == m.FileMtime {
+			continue
+		}
+		changed = append(changed, m.ComposerID)
+	}
+
+	var pending []pendingWrite
+	if len(changed) > 0 {
+		folders := parser.LoadCursorWorkspaces(dir)
+		for _, id := range changed {
+			sess, msgs, err := parser.ParseCursorComposer(
+				dbPath, id, folders[id], e.machine,
+			)
+			if err != nil {
+				log.Printf("cursor composer %s: %v", id, err)
+				continue
+			}
+			if sess == nil {
+				continue
+			}
+			pending = append(pending, pendingWrite{
+				sess: *sess,
+				msgs: msgs,
+			})
+		}
+	}
+	e.cursorMtimes[dbPath] = mtime
+
+	if len(pending) > 0 {
+		log.Printf(
+			"sync: %d cursor session(s) updated",
+			len(pending),
+		)
+	}
+	return pending
+}
+
+// cursorDBMtime returns the later mtime of a state database and
+// its write-ahead log, or 0 if the database does not exist.
+func cursorDBMtime(dbPath string) int64 {
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return 0
+	}
+	mtime := info.ModTime().UnixNano()
+	if wal, err := os.Stat(dbPath + "-wal"); err == nil &&
+		wal.ModTime().UnixNano() > mtime {
+		mtime = wal.ModTime().UnixNano()
+	}
+	return mtime
+}
+
 // startWorkers fans out file processing across a worker pool
 // and returns a channel of results.
 func (e *Engine) startWorkers(
@@ -530,8 +824,10 @@ func (e *Engine) startWorkers(
 	for range workers {
 		go func() {
 			for file := range jobs {
+				res := e.processFile(file)
+				e.archiveFile(file, res)
 				results <- syncJob{
-					processResult: e.processFile(file),
+					processResult: res,
 					path:          file.Path,
 				}
 			}
@@ -591,8 +887,9 @@ func (e *Engine) collectAndBatch(
 
 		for _, pr := range r.results {
 			pending = append(pending, pendingWrite{
-				sess: pr.Session,
-				msgs: pr.Messages,
+				sess:        pr.Session,
+				msgs:        pr.Messages,
+				incremental: r.incremental,
 			})
 		}
 
@@ -627,11 +924,17 @@ type processResult struct {
 	skip    bool
 	mtime   int64
 	err     error
+	// incremental marks results from a resumed parse, which
+	// carry only newly appended messages.
+	incremental bool
 }
 
 func (e *Engine) processFile(
 	file DiscoveredFile,
 ) processResult {
+	if file.Archived {
+		return e.processArchived(file)
+	}
 
 	info, err := os.Stat(file.Path)
 	if err != nil {
@@ -653,27 +956,234 @@ func (e *Engine) processFile(
 		return processResult{skip: true, mtime: mtime}
 	}
 
-	var res processResult
+	res := e.processByAgent(file, info)
+	res.mtime = mtime
+	return res
+}
+
+// processByAgent dispatches a stat'ed file to its agent parser.
+func (e *Engine) processByAgent(
+	file DiscoveredFile, info os.FileInfo,
+) processResult {
 	switch file.Agent {
 	case parser.AgentClaude:
-		res = e.processClaude(file, info)
+		return e.processClaude(file, info)
 	case parser.AgentCodex:
-		res = e.processCodex(file, info)
+		return e.processCodex(file, info)
 	case parser.AgentCopilot:
-		res = e.processCopilot(file, info)
+		return e.processSingle(file, info)
 	case parser.AgentGemini:
-		res = e.processGemini(file, info)
+		return e.processGemini(file, info)
+	case parser.AgentAider:
+		return e.processAider(file, info)
+	case parser.AgentCline:
+		return e.processCline(file, info)
+	case parser.AgentGoose:
+		return e.processSingle(file, info)
+	case parser.AgentAmp:
+		return e.processSingle(file, info)
+	case parser.AgentOTel:
+		return e.processSingle(file, info)
 	default:
-		res = processResult{
+		return processResult{
 			err: fmt.Errorf(
 				"unknown agent type: %s", file.Agent,
 			),
 		}
 	}
-	res.mtime = mtime
+}
+
+// processArchived parses a file whose original is gone from a
+// temporary copy restored out of the archive. Results carry the
+// original path and the archived size/mtime, so the stored file
+// info keeps matching and later syncs skip the file until a
+// resync zeroes the stored mtimes.
+func (e *Engine) processArchived(
+	file DiscoveredFile,
+) processResult {
+	if e.archive == nil {
+		return processResult{
+			err: fmt.Errorf("archive disabled: %s", file.Path),
+		}
+	}
+	entry, ok := e.archive.Lookup(file.Path)
+	if !ok {
+		return processResult{
+			err: fmt.Errorf("not in archive: %s", file.Path),
+		}
+	}
+
+	storedSize, storedMtime, ok := e.db.GetFileInfoByPath(
+		file.Path,
+	)
+	if ok && storedSize == entry.Size &&
+		storedMtime == entry.Mtime {
+		return processResult{skip: true, mtime: entry.Mtime}
+	}
+
+	res := e.parseRestored(entry, file, e.processByAgent)
+	res.mtime = entry.Mtime
+	return res
+}
+
+// parseRestored extracts entry to a temporary directory, runs
+// parse on the copy, and points the results back at the
+// original source path.
+func (e *Engine) parseRestored(
+	entry ArchiveEntry, file DiscoveredFile,
+	parse func(DiscoveredFile, os.FileInfo) processResult,
+) processResult {
+	tmp, err := os.MkdirTemp("", "agentsview-restore-*")
+	if err != nil {
+		return processResult{
+			err: fmt.Errorf("restoring %s: %w", file.Path, err),
+		}
+	}
+	defer os.RemoveAll(tmp)
+
+	path, err := e.archive.Extract(entry, tmp)
+	if err != nil {
+		return processResult{
+			err: fmt.Errorf("restoring %s: %w", file.Path, err),
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return processResult{
+			err: fmt.Errorf("stat %s: %w", path, err),
+		}
+	}
+
+	restored := file
+	restored.Path = path
+	restored.Archived = false
+	if restored.Project == "" {
+		restored.Project = entry.Project
+	}
+	res := parse(restored, info)
+	e.dropCheckpoint(path)
+	for i := range res.results {
+		f := &res.results[i].Session.File
+		f.Path = entry.SourcePath
+		f.Size = entry.Size
+		f.Mtime = entry.Mtime
+		f.Hash = entry.Hash
+	}
 	return res
 }
 
+// archiveFile stores a compressed copy of a processed source
+// file. Files skipped as unchanged are archived too when the
+// archive has no copy yet, so enabling archive mode also covers
+// sessions that were synced before it was turned on.
+func (e *Engine) archiveFile(
+	file DiscoveredFile, res processResult,
+) {
+	if e.archive == nil || file.Archived || res.err != nil {
+		return
+	}
+
+	entry := ArchiveEntry{
+		SourcePath: file.Path,
+		Agent:      file.Agent,
+		Project:    file.Project,
+	}
+	switch {
+	case len(res.results) > 0:
+		f := res.results[0].Session.File
+		entry.Hash, entry.Size, entry.Mtime = f.Hash, f.Size, f.Mtime
+	case res.skip && !e.archive.Has(file.Path):
+		e.skipMu.RLock()
+		_, cached := e.skipCache[file.Path]
+		e.skipMu.RUnlock()
+		if cached {
+			return // non-interactive or unparseable
+		}
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return
+		}
+		hash, err := ComputeFileHash(file.Path)
+		if err != nil {
+			return
+		}
+		entry.Hash = hash
+		entry.Size = info.Size()
+		entry.Mtime = info.ModTime().UnixNano()
+	default:
+		return
+	}
+	if entry.Hash == "" {
+		return
+	}
+	if prev, ok := e.archive.Lookup(file.Path); ok && prev == entry {
+		return
+	}
+	if err := e.archive.Store(entry); err != nil {
+		log.Printf("archive %s: %v", file.Path, err)
+	}
+}
+
+// discoverArchived returns archived files whose originals no
+// longer exist on disk.
+func (e *Engine) discoverArchived(
+	found []DiscoveredFile,
+) []DiscoveredFile {
+	entries, err := e.archive.Entries()
+	if err != nil {
+		log.Printf("archive: %v", err)
+		return nil
+	}
+
+	seen := make(map[string]bool, len(found))
+	for _, f := range found {
+		seen[f.Path] = true
+	}
+
+	var files []DiscoveredFile
+	for _, entry := range entries {
+		if seen[entry.SourcePath] {
+			continue
+		}
+		if _, err := os.Stat(entry.SourcePath); !os.IsNotExist(err) {
+			continue
+		}
+		f := DiscoveredFile{
+			Path:     entry.SourcePath,
+			Project:  entry.Project,
+			Agent:    entry.Agent,
+			Archived: true,
+		}
+		if entry.Agent == parser.AgentAider {
+			// Aider sessions are keyed by the repository the
+			// history was in, not the restored copy's location.
+			f.Cwd = filepath.Dir(entry.SourcePath)
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// archivedEntry finds the archived copy of a session's source
+// file using the file path recorded in the database.
+func (e *Engine) archivedEntry(
+	sessionID string,
+) (ArchiveEntry, bool) {
+	if e.archive == nil {
+		return ArchiveEntry{}, false
+	}
+	sess, err := e.db.GetSessionFull(
+		context.Background(), sessionID,
+	)
+	if err != nil || sess == nil || sess.FilePath == nil {
+		return ArchiveEntry{}, false
+	}
+	return e.archive.Lookup(*sess.FilePath)
+}
+
 // cacheSkip records a file so it won't be retried until
 // its mtime changes.
 func (e *Engine) cacheSkip(path string, mtime int64) {
@@ -741,6 +1251,25 @@ func (e *Engine) shouldSkipByPath(
 		storedMtime == info.ModTime().UnixNano()
 }
 
+// ClaudeProject returns the project name of a discovered Claude
+// session file: the project of the working directory the session
+// records if possible, else the name decoded from its project
+// directory.
+func ClaudeProject(file DiscoveredFile) string {
+	project := parser.GetProjectName(file.Project)
+	cwd, gitBranch := parser.ExtractClaudeProjectHints(
+		file.Path,
+	)
+	if cwd != "" {
+		if p := parser.ExtractProjectFromCwdWithBranch(
+			cwd, gitBranch,
+		); p != "" {
+			project = p
+		}
+	}
+	return project
+}
+
 func (e *Engine) processClaude(
 	file DiscoveredFile, info os.FileInfo,
 ) processResult {
@@ -758,32 +1287,19 @@ func (e *Engine) processClaude(
 		}
 	}
 
-	// Determine project name from cwd if possible
-	project := parser.GetProjectName(file.Project)
-	cwd, gitBranch := parser.ExtractClaudeProjectHints(
-		file.Path,
-	)
-	if cwd != "" {
-		if p := parser.ExtractProjectFromCwdWithBranch(
-			cwd, gitBranch,
-		); p != "" {
-			project = p
-		}
+	if res, ok := e.resumeFile(file, info); ok {
+		return res
 	}
 
-	results, err := parser.ParseClaudeSession(
-		file.Path, project, e.machine,
+	results, cp, err := parser.ParseClaudeSessionCheckpoint(
+		file.Path, ClaudeProject(file), e.machine,
 	)
 	if err != nil {
+		e.dropCheckpoint(file.Path)
 		return processResult{err: err}
 	}
 
-	hash, err := ComputeFileHash(file.Path)
-	if err == nil {
-		for i := range results {
-			results[i].Session.File.Hash = hash
-		}
-	}
+	e.hashAndCheckpoint(file.Path, results, cp)
 
 	parser.InferRelationshipTypes(results)
 
@@ -799,68 +1315,93 @@ func (e *Engine) processCodex(
 		return processResult{skip: true}
 	}
 
-	sess, msgs, err := parser.ParseCodexSession(
+	if res, ok := e.resumeFile(file, info); ok {
+		return res
+	}
+
+	sess, msgs, cp, err := parser.ParseCodexSessionCheckpoint(
 		file.Path, e.machine, false,
 	)
 	if err != nil {
+		e.dropCheckpoint(file.Path)
 		return processResult{err: err}
 	}
 	if sess == nil {
+		e.dropCheckpoint(file.Path)
 		return processResult{} // non-interactive
 	}
 
-	hash, err := ComputeFileHash(file.Path)
-	if err == nil {
-		sess.File.Hash = hash
+	results := []parser.ParseResult{
+		{Session: *sess, Messages: msgs},
 	}
+	e.hashAndCheckpoint(file.Path, results, cp)
 
-	return processResult{
-		results: []parser.ParseResult{
-			{Session: *sess, Messages: msgs},
-		},
-	}
+	return processResult{results: results}
 }
 
-func (e *Engine) processCopilot(
+func (e *Engine) processGemini(
 	file DiscoveredFile, info os.FileInfo,
 ) processResult {
+	// Fast path: skip by file_path + mtime before parsing.
 	if e.shouldSkipByPath(file.Path, info) {
 		return processResult{skip: true}
 	}
 
-	sess, msgs, err := parser.ParseCopilotSession(
-		file.Path, e.machine,
+	results, err := ParseSessionFile(
+		file.Agent, file.Path, file.Project, e.machine,
 	)
 	if err != nil {
 		return processResult{err: err}
 	}
-	if sess == nil {
-		return processResult{}
+	if file.Cwd != "" {
+		for i := range results {
+			results[i].Session.SetWorkspace(file.Cwd, "")
+		}
 	}
+	return processResult{results: results}
+}
 
-	hash, err := ComputeFileHash(file.Path)
-	if err == nil {
-		sess.File.Hash = hash
+func (e *Engine) processAider(
+	file DiscoveredFile, info os.FileInfo,
+) processResult {
+	if e.shouldSkipByPath(file.Path, info) {
+		return processResult{skip: true}
 	}
 
-	return processResult{
-		results: []parser.ParseResult{
-			{Session: *sess, Messages: msgs},
-		},
+	// The history is keyed by its repository, which is also
+	// where it lives unless it was restored from the archive.
+	repoDir := file.Cwd
+	if repoDir == "" {
+		repoDir = filepath.Dir(file.Path)
 	}
+	results, err := parser.ParseAiderHistory(
+		file.Path, repoDir, e.machine,
+	)
+	if err != nil {
+		return processResult{err: err}
+	}
+
+	if hash, err := ComputeFileHash(file.Path); err == nil {
+		for i := range results {
+			results[i].Session.File.Hash = hash
+		}
+	}
+	return processResult{results: results}
 }
 
-func (e *Engine) processGemini(
+func (e *Engine) processCline(
 	file DiscoveredFile, info os.FileInfo,
 ) processResult {
-	// Fast path: skip by file_path + mtime before parsing.
-	if e.shouldSkipByPath(file.Path, info) {
+	// The stored mtime is the later of the API conversation's
+	// and the UI messages', so the task is skipped only when
+	// neither changed.
+	storedSize, storedMtime, ok := e.db.GetFileInfoByPath(file.Path)
+	if ok && storedSize == info.Size() &&
+		storedMtime == parser.ClineTaskMtime(file.Path, info) {
 		return processResult{skip: true}
 	}
 
-	sess, msgs, err := parser.ParseGeminiSession(
-		file.Path, file.Project, e.machine,
-	)
+	sess, msgs, err := parser.ParseClineTask(file.Path, e.machine)
 	if err != nil {
 		return processResult{err: err}
 	}
@@ -868,11 +1409,9 @@ func (e *Engine) processGemini(
 		return processResult{}
 	}
 
-	hash, err := ComputeFileHash(file.Path)
-	if err == nil {
+	if hash, err := ComputeFileHash(file.Path); err == nil {
 		sess.File.Hash = hash
 	}
-
 	return processResult{
 		results: []parser.ParseResult{
 			{Session: *sess, Messages: msgs},
@@ -880,13 +1419,50 @@ func (e *Engine) processGemini(
 	}
 }
 
+// processSingle handles formats with one session per file and
+// no state to carry between syncs, parsing them through the
+// session file parsers and skipping files whose stored size and
+// mtime are unchanged.
+func (e *Engine) processSingle(
+	file DiscoveredFile, info os.FileInfo,
+) processResult {
+	if e.shouldSkipByPath(file.Path, info) {
+		return processResult{skip: true}
+	}
+
+	results, err := ParseSessionFile(
+		file.Agent, file.Path, file.Project, e.machine,
+	)
+	if err != nil {
+		return processResult{err: err}
+	}
+	return processResult{results: results}
+}
+
 type pendingWrite struct {
-	sess parser.ParsedSession
-	msgs []parser.ParsedMessage
+	sess        parser.ParsedSession
+	msgs        []parser.ParsedMessage
+	incremental bool
 }
 
 func (e *Engine) writeBatch(batch []pendingWrite) {
 	for _, pw := range batch {
+		if pw.incremental {
+			e.writeIncremental(pw)
+			continue
+		}
+		// Spans exported late can land anywhere in an OTel
+		// session, so its messages are replaced, not appended.
+		if pw.sess.Agent == parser.AgentOTel {
+			e.writeSessionFull(pw)
+			continue
+		}
+		// Sessions a migration flagged get their stored
+		// messages rewritten, so new columns are filled in.
+		if e.db.NeedsReparse(pw.sess.ID) {
+			e.writeSessionFull(pw)
+			continue
+		}
 		msgs := toDBMessages(pw)
 		s := toDBSession(pw)
 		s.MessageCount, s.UserMessageCount =
@@ -947,22 +1523,40 @@ func (e *Engine) writeMessages(
 // single-session re-syncs where existing content may have
 // changed (not just appended).
 func (e *Engine) writeSessionFull(pw pendingWrite) {
+	if err := e.storeSessionFull(pw); err != nil {
+		log.Print(err)
+	}
+}
+
+// StoreSessions writes parsed sessions that arrived outside of
+// discovery, such as uploads, replacing any stored messages.
+func (e *Engine) StoreSessions(results []parser.ParseResult) error {
+	for _, r := range results {
+		if err := e.storeSessionFull(pendingWrite{
+			sess: r.Session, msgs: r.Messages,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Engine) storeSessionFull(pw pendingWrite) error {
 	msgs := toDBMessages(pw)
 	s := toDBSession(pw)
 	s.MessageCount, s.UserMessageCount =
 		postFilterCounts(msgs)
 	if err := e.db.UpsertSession(s); err != nil {
-		log.Printf("upsert session %s: %v", s.ID, err)
-		return
+		return fmt.Errorf("upsert session %s: %w", s.ID, err)
 	}
 	if err := e.db.ReplaceSessionMessages(
 		pw.sess.ID, msgs,
 	); err != nil {
-		log.Printf(
-			"replace messages for %s: %v",
-			pw.sess.ID, err,
+		return fmt.Errorf(
+			"replace messages for %s: %w", pw.sess.ID, err,
 		)
 	}
+	return nil
 }
 
 // extractMCPServers collects distinct MCP server names from
@@ -1093,6 +1687,9 @@ func toDBSession(pw pendingWrite) db.Session {
 		FileSize:                 int64Ptr(pw.sess.File.Size),
 		FileMtime:                int64Ptr(pw.sess.File.Mtime),
 		FileHash:                 strPtr(pw.sess.File.Hash),
+		Cwd:                      pw.sess.Cwd,
+		GitBranch:                pw.sess.GitBranch,
+		RepoRoot:                 pw.sess.RepoRoot,
 	}
 	if pw.sess.FirstMessage != "" {
 		s.FirstMessage = &pw.sess.FirstMessage
@@ -1109,24 +1706,32 @@ func toDBSession(pw pendingWrite) db.Session {
 // toDBMessages converts parsed messages to db.Message rows
 // with tool-result pairing and filtering applied.
 func toDBMessages(pw pendingWrite) []db.Message {
+	return pairAndFilter(convertMessages(pw))
+}
+
+// convertMessages converts parsed messages to db.Message rows.
+func convertMessages(pw pendingWrite) []db.Message {
 	msgs := make([]db.Message, len(pw.msgs))
 	for i, m := range pw.msgs {
 		msgs[i] = db.Message{
-			SessionID:     pw.sess.ID,
-			Ordinal:       m.Ordinal,
-			Role:          string(m.Role),
-			Content:       m.Content,
-			Timestamp:     timeutil.Format(m.Timestamp),
-			HasThinking:   m.HasThinking,
-			HasToolUse:    m.HasToolUse,
-			ContentLength: m.ContentLength,
-			ToolCalls: convertToolCalls(
-				pw.sess.ID, m.ToolCalls,
-			),
-			ToolResults: convertToolResults(m.ToolResults),
+			SessionID:                pw.sess.ID,
+			Ordinal:                  m.Ordinal,
+			Role:                     string(m.Role),
+			Content:                  m.Content,
+			Timestamp:                timeutil.Format(m.Timestamp),
+			HasThinking:              m.HasThinking,
+			HasToolUse:               m.HasToolUse,
+			ContentLength:            m.ContentLength,
+			Model:                    m.Model,
+			InputTokens:              m.InputTokens,
+			OutputTokens:             m.OutputTokens,
+			CacheCreationInputTokens: m.CacheCreationInputTokens,
+			CacheReadInputTokens:     m.CacheReadInputTokens,
+			ToolCalls:                convertToolCalls(&pw.sess, m.ToolCalls),
+			ToolResults:              convertToolResults(m.ToolResults),
 		}
 	}
-	return pairAndFilter(msgs)
+	return msgs
 }
 
 // postFilterCounts returns the total and user message counts
@@ -1149,10 +1754,22 @@ func countMessages(batch []pendingWrite) int {
 }
 
 // FindSourceFile locates the original source file for a
-// session ID.
+// session ID. In archive mode, a session whose original has
+// been deleted resolves to its archived blob instead.
 func (e *Engine) FindSourceFile(sessionID string) string {
+	if f := e.findLiveSourceFile(sessionID); f != "" {
+		return f
+	}
+	if entry, ok := e.archivedEntry(sessionID); ok {
+		return e.archive.BlobPath(entry.Hash)
+	}
+	return ""
+}
+
+func (e *Engine) findLiveSourceFile(sessionID string) string {
 	switch {
-	case strings.HasPrefix(sessionID, "opencode:"):
+	case strings.HasPrefix(sessionID, "opencode:"),
+		strings.HasPrefix(sessionID, "cursor:"):
 		return ""
 	case strings.HasPrefix(sessionID, "codex:"):
 		for _, d := range e.codexDirs {
@@ -1175,6 +1792,36 @@ func (e *Engine) FindSourceFile(sessionID string) string {
 			}
 		}
 		return ""
+	case strings.HasPrefix(sessionID, "aider:"):
+		for _, d := range e.aiderDirs {
+			if f := FindAiderSourceFile(d, sessionID); f != "" {
+				return f
+			}
+		}
+		return ""
+	case strings.HasPrefix(sessionID, "cline:"):
+		for _, d := range e.clineDirs {
+			if f := FindClineSourceFile(d, sessionID[6:]); f != "" {
+				return f
+			}
+		}
+		return ""
+	case strings.HasPrefix(sessionID, "goose:"):
+		for _, d := range e.gooseDirs {
+			if f := FindGooseSourceFile(d, sessionID[6:]); f != "" {
+				return f
+			}
+		}
+		return ""
+	case strings.HasPrefix(sessionID, "amp:"):
+		for _, d := range e.ampDirs {
+			if f := FindAmpSourceFile(d, sessionID[4:]); f != "" {
+				return f
+			}
+		}
+		return ""
+	case strings.HasPrefix(sessionID, "otel:"):
+		return FindOTelSourceFile(e.otelDir, sessionID[5:])
 	default:
 		for _, d := range e.claudeDirs {
 			if f := FindClaudeSourceFile(d, sessionID); f != "" {
@@ -1192,12 +1839,21 @@ func (e *Engine) SyncSingleSession(sessionID string) error {
 	if strings.HasPrefix(sessionID, "opencode:") {
 		return e.syncSingleOpenCode(sessionID)
 	}
+	if strings.HasPrefix(sessionID, "cursor:") {
+		return e.syncSingleCursor(sessionID)
+	}
 
-	path := e.FindSourceFile(sessionID)
+	path := e.findLiveSourceFile(sessionID)
+	var archived bool
 	if path == "" {
-		return fmt.Errorf(
-			"source file not found for %s", sessionID,
-		)
+		entry, ok := e.archivedEntry(sessionID)
+		if !ok {
+			return fmt.Errorf(
+				"source file not found for %s", sessionID,
+			)
+		}
+		path = entry.SourcePath
+		archived = true
 	}
 
 	var agent parser.AgentType
@@ -1208,6 +1864,16 @@ func (e *Engine) SyncSingleSession(sessionID string) error {
 		agent = parser.AgentCopilot
 	case strings.HasPrefix(sessionID, "gemini:"):
 		agent = parser.AgentGemini
+	case strings.HasPrefix(sessionID, "aider:"):
+		agent = parser.AgentAider
+	case strings.HasPrefix(sessionID, "cline:"):
+		agent = parser.AgentCline
+	case strings.HasPrefix(sessionID, "goose:"):
+		agent = parser.AgentGoose
+	case strings.HasPrefix(sessionID, "amp:"):
+		agent = parser.AgentAmp
+	case strings.HasPrefix(sessionID, "otel:"):
+		agent = parser.AgentOTel
 	default:
 		agent = parser.AgentClaude
 	}
@@ -1221,8 +1887,12 @@ func (e *Engine) SyncSingleSession(sessionID string) error {
 	// Claude this is the full pipeline; for Codex we need
 	// includeExec=true so we call the parser directly.
 	file := DiscoveredFile{
-		Path:  path,
-		Agent: agent,
+		Path:     path,
+		Agent:    agent,
+		Archived: archived,
+	}
+	if agent == parser.AgentAider {
+		file.Cwd = filepath.Dir(path)
 	}
 	if agent == parser.AgentClaude {
 		// Try to preserve existing project from DB first
@@ -1250,7 +1920,16 @@ func (e *Engine) SyncSingleSession(sessionID string) error {
 	// return empty results for exec-originated sessions. Re-parse
 	// with includeExec=true when that happens.
 	if len(res.results) == 0 && agent == parser.AgentCodex {
-		execRes := e.processCodexIncludeExec(file)
+		var execRes processResult
+		if entry, ok := e.archive.lookupFile(file); ok {
+			execRes = e.parseRestored(entry, file, func(
+				f DiscoveredFile, _ os.FileInfo,
+			) processResult {
+				return e.processCodexIncludeExec(f)
+			})
+		} else {
+			execRes = e.processCodexIncludeExec(file)
+		}
 		if execRes.err != nil {
 			if res.mtime != 0 {
 				e.cacheSkip(path, res.mtime)
@@ -1268,9 +1947,12 @@ func (e *Engine) SyncSingleSession(sessionID string) error {
 	}
 
 	for _, pr := range res.results {
-		e.writeSessionFull(
-			pendingWrite{sess: pr.Session, msgs: pr.Messages},
-		)
+		pw := pendingWrite{sess: pr.Session, msgs: pr.Messages}
+		if res.incremental {
+			e.writeIncremental(pw)
+		} else {
+			e.writeSessionFull(pw)
+		}
 	}
 	return nil
 }
@@ -1338,6 +2020,46 @@ func (e *Engine) syncSingleOpenCode(
 	return fmt.Errorf("opencode session %s not found", sessionID)
 }
 
+// syncSingleCursor re-syncs a single Cursor chat.
+func (e *Engine) syncSingleCursor(
+	sessionID string,
+) error {
+	rawID := strings.TrimPrefix(sessionID, "cursor:")
+
+	var lastErr error
+	for _, dir := range e.cursorDirs {
+		if dir == "" {
+			continue
+		}
+		dbPath := parser.CursorGlobalDB(dir)
+		folder := parser.LoadCursorWorkspaces(dir)[rawID]
+		sess, msgs, err := parser.ParseCursorComposer(
+			dbPath, rawID, folder, e.machine,
+		)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if sess == nil {
+			continue
+		}
+		e.writeSessionFull(
+			pendingWrite{sess: *sess, msgs: msgs},
+		)
+		return nil
+	}
+
+	if len(e.cursorDirs) == 0 {
+		return fmt.Errorf("cursor dir not configured")
+	}
+	if lastErr != nil {
+		return fmt.Errorf(
+			"cursor session %s: %w", sessionID, lastErr,
+		)
+	}
+	return fmt.Errorf("cursor session %s not found", sessionID)
+}
+
 func strPtr(s string) *string {
 	if s == "" {
 		return nil
@@ -1352,10 +2074,10 @@ func int64Ptr(n int64) *int64 {
 	return &n
 }
 
-// convertToolCalls maps parsed tool calls to db.ToolCall
+// convertToolCalls maps parsed tool calls of sess to db.ToolCall
 // structs. MessageID is resolved later during insert.
 func convertToolCalls(
-	sessionID string, parsed []parser.ParsedToolCall,
+	sess *parser.ParsedSession, parsed []parser.ParsedToolCall,
 ) []db.ToolCall {
 	if len(parsed) == 0 {
 		return nil
@@ -1363,13 +2085,16 @@ func convertToolCalls(
 	calls := make([]db.ToolCall, len(parsed))
 	for i, tc := range parsed {
 		calls[i] = db.ToolCall{
-			SessionID:         sessionID,
+			SessionID:         sess.ID,
 			ToolName:          tc.ToolName,
 			Category:          tc.Category,
 			ToolUseID:         tc.ToolUseID,
 			InputJSON:         tc.InputJSON,
 			SkillName:         tc.SkillName,
 			SubagentSessionID: tc.SubagentSessionID,
+			Files: toolFiles(
+				tc.ToolName, tc.InputJSON, sess.Cwd, sess.RepoRoot,
+			),
 		}
 	}
 	return calls
@@ -1389,6 +2114,8 @@ func convertToolResults(
 			ToolUseID:     tr.ToolUseID,
 			ContentLength: tr.ContentLength,
 			Content:       tr.Content,
+			IsError:       tr.IsError,
+			ExitCode:      tr.ExitCode,
 		}
 	}
 	return results
@@ -1411,9 +2138,9 @@ func pairAndFilter(msgs []db.Message) []db.Message {
 	return filtered
 }
 
-// pairToolResults matches tool_result content lengths to their
-// corresponding tool_calls across message boundaries using
-// tool_use_id.
+// pairToolResults copies tool_result content, length and status
+// to their corresponding tool_calls across message boundaries
+// using tool_use_id.
 func pairToolResults(msgs []db.Message) {
 	idx := make(map[string]*db.ToolCall)
 	for i := range msgs {
@@ -1432,6 +2159,8 @@ func pairToolResults(msgs []db.Message) {
 			if tc, ok := idx[tr.ToolUseID]; ok {
 				tc.ResultContentLength = tr.ContentLength
 				tc.ResultContent = tr.Content
+				tc.IsError = &tr.IsError
+				tc.ExitCode = tr.ExitCode
 			}
 		}
 	}
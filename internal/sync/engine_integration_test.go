@@ -5,14 +5,19 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	gosync "sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
 	"github.com/wesm/agentsview/internal/db"
 	"github.com/wesm/agentsview/internal/dbtest"
+	"github.com/wesm/agentsview/internal/parser"
 	"github.com/wesm/agentsview/internal/sync"
 	"github.com/wesm/agentsview/internal/testjsonl"
 )
@@ -22,6 +27,11 @@ type testEnv struct {
 	codexDir    string
 	geminiDir   string
 	opencodeDir string
+	aiderDir    string
+	clineDir    string
+	cursorDir   string
+	gooseDir    string
+	ampDir      string
 	db          *db.DB
 	engine      *sync.Engine
 }
@@ -59,6 +69,11 @@ func setupTestEnv(t *testing.T, opts ...TestEnvOption) *testEnv {
 	env := &testEnv{
 		geminiDir:   t.TempDir(),
 		opencodeDir: t.TempDir(),
+		aiderDir:    t.TempDir(),
+		clineDir:    t.TempDir(),
+		cursorDir:   t.TempDir(),
+		gooseDir:    t.TempDir(),
+		ampDir:      t.TempDir(),
 		db:          dbtest.OpenTestDB(t),
 	}
 
@@ -80,7 +95,10 @@ func setupTestEnv(t *testing.T, opts ...TestEnvOption) *testEnv {
 
 	env.engine = sync.NewEngine(
 		env.db, claudeDirs, codexDirs, nil,
-		[]string{env.geminiDir}, []string{env.opencodeDir}, "local",
+		[]string{env.geminiDir}, []string{env.opencodeDir},
+		[]string{env.aiderDir}, []string{env.clineDir},
+		[]string{env.cursorDir}, []string{env.gooseDir},
+		[]string{env.ampDir}, "local",
 	)
 	return env
 }
@@ -283,6 +301,116 @@ func TestSyncEngineCodex(t *testing.T) {
 	})
 }
 
+func TestSyncEngineIndexesToolFiles(t *testing.T) {
+	env := setupTestEnv(t)
+
+	patch := "*** Begin Patch\n*** Update File: internal/db/x.go\n" +
+		"@@\n-a\n+b\n*** End Patch"
+	content := testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsEarly, "files-uuid", "/home/user/code/api", "user").
+		AddCodexMessage(tsEarlyS1, "user", "Fix x").
+		AddRaw(testjsonl.CodexFunctionCallFieldsJSON(
+			"apply_patch", nil, patch, tsEarlyS5,
+		)).
+		String()
+	env.writeCodexSession(
+		t, filepath.Join("2024", "01", "15"),
+		"rollout-20240115-files-uuid.jsonl", content,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	got, err := env.db.FileSessions(
+		context.Background(), "internal/db/x.go", "", 10,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].SessionID != "codex:files-uuid" ||
+		got[0].Edits != 1 {
+		t.Errorf("FileSessions = %+v", got)
+	}
+}
+
+func TestSyncEngineLinksCommits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	env := setupTestEnv(t)
+	env.engine.SetCommitCorrelation(true)
+
+	repo := t.TempDir()
+	git := func(date string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Dev", "GIT_AUTHOR_EMAIL=dev@example.com",
+			"GIT_COMMITTER_NAME=Dev", "GIT_COMMITTER_EMAIL=dev@example.com",
+			"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	commit := func(name, msg, date string) {
+		t.Helper()
+		path := filepath.Join(repo, name)
+		if err := os.WriteFile(path, []byte(msg+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git(date, "add", name)
+		git(date, "commit", "-q", "-m", msg)
+	}
+	git(tsEarly, "init", "-q")
+	commit("app.go", "Before the session", "2024-01-01T09:00:00Z")
+	commit("app.go", "Fix app", "2024-01-01T10:30:00Z")
+	commit("other.go", "Unrelated", "2024-01-01T10:40:00Z")
+	commit("app.go", "Next day", "2024-01-02T10:00:00Z")
+
+	patch := "*** Begin Patch\n*** Update File: " +
+		filepath.Join(repo, "app.go") + "\n@@\n-a\n+b\n*** End Patch"
+	content := testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsEarly, "commits-uuid", repo, "user").
+		AddCodexMessage(tsEarlyS1, "user", "Fix app").
+		AddRaw(testjsonl.CodexFunctionCallFieldsJSON(
+			"apply_patch", nil, patch, tsEarlyS5,
+		)).
+		String()
+	env.writeCodexSession(
+		t, filepath.Join("2024", "01", "01"),
+		"rollout-20240101-commits-uuid.jsonl", content,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	ctx := context.Background()
+	commits, err := env.db.SessionCommits(ctx, "codex:commits-uuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].Subject != "Fix app" ||
+		commits[0].AuthoredAt != "2024-01-01T10:30:00Z" ||
+		len(commits[0].Files) != 1 || commits[0].Files[0] != "app.go" {
+		t.Fatalf("SessionCommits = %+v", commits)
+	}
+
+	sessions, err := env.db.CommitSessions(ctx, commits[0].SHA[:8])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].SessionID != "codex:commits-uuid" {
+		t.Errorf("CommitSessions = %+v", sessions)
+	}
+
+	// The window has closed, so the session is not scanned again.
+	candidates, err := env.db.CommitCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 0 {
+		t.Errorf("candidates after sync = %+v", candidates)
+	}
+}
+
 func TestSyncEngineProgress(t *testing.T) {
 	env := setupTestEnv(t)
 
@@ -397,6 +525,128 @@ func TestSyncEngineFileAppend(t *testing.T) {
 	assertSessionMessageCount(t, env.db, "append-test", 2)
 }
 
+func TestResyncAllKeepsAnnotations(t *testing.T) {
+	env := setupTestEnv(t)
+
+	content := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsZero, "hello").
+		AddClaudeAssistant(tsZeroS5, "hi").
+		String()
+	env.writeClaudeSession(
+		t, "test-proj", "annotated.jsonl", content,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	if _, err := env.db.AddTag("annotated", nil, "keeper"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.db.AddStar("annotated", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.db.AddNote("annotated", dbtest.Ptr(1), "good answer"); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := env.engine.ResyncAll(nil); stats.Synced != 1 {
+		t.Fatalf("ResyncAll synced %d, want 1", stats.Synced)
+	}
+
+	ctx := context.Background()
+	sess, err := env.db.GetSession(ctx, "annotated")
+	if err != nil || sess == nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if !sess.Starred || len(sess.Tags) != 1 || sess.Tags[0] != "keeper" {
+		t.Errorf("annotations after resync: starred=%v tags=%v",
+			sess.Starred, sess.Tags)
+	}
+	notes, err := env.db.ListNotes(ctx, "annotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Body != "good answer" {
+		t.Errorf("notes after resync = %+v", notes)
+	}
+}
+
+func TestSyncEngineArchiveRestoresDeletedFiles(t *testing.T) {
+	env := setupTestEnv(t)
+	archive := sync.NewArchive(t.TempDir())
+	env.engine.SetArchive(archive)
+
+	content := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsZero, "keep me").
+		AddClaudeAssistant(tsZeroS5, "archived").
+		String()
+	path := env.writeClaudeSession(
+		t, "test-proj", "archived-sess.jsonl", content,
+	)
+
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+	entry, ok := archive.Lookup(path)
+	if !ok {
+		t.Fatal("synced file was not archived")
+	}
+
+	// The agent prunes its transcript.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged archived files are skipped like live ones.
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Skipped: 1})
+	if got := env.engine.FindSourceFile("archived-sess"); got != archive.BlobPath(entry.Hash) {
+		t.Errorf("FindSourceFile = %q, want archive blob", got)
+	}
+
+	// A full resync and a rebuilt database both re-parse from
+	// the archive while keeping the original file path.
+	if stats := env.engine.ResyncAll(nil); stats.Synced != 1 {
+		t.Errorf("ResyncAll synced %d, want 1", stats.Synced)
+	}
+	assertMessageContent(t, env.db, "archived-sess", "keep me", "archived")
+
+	if err := env.db.DeleteSession("archived-sess"); err != nil {
+		t.Fatal(err)
+	}
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+	assertMessageContent(t, env.db, "archived-sess", "keep me", "archived")
+
+	sess, err := env.db.GetSessionFull(context.Background(), "archived-sess")
+	if err != nil || sess == nil {
+		t.Fatalf("GetSessionFull: %v", err)
+	}
+	if sess.FilePath == nil || *sess.FilePath != path {
+		t.Errorf("file_path = %v, want %q", sess.FilePath, path)
+	}
+	if sess.FileHash == nil || *sess.FileHash != entry.Hash {
+		t.Errorf("file_hash = %v, want %q", sess.FileHash, entry.Hash)
+	}
+
+	if err := env.engine.SyncSingleSession("archived-sess"); err != nil {
+		t.Fatalf("SyncSingleSession: %v", err)
+	}
+}
+
+func TestSyncEngineArchiveBackfillsUnchangedFiles(t *testing.T) {
+	env := setupTestEnv(t)
+
+	content := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsZero, "synced before archive mode").
+		String()
+	path := env.writeClaudeSession(
+		t, "test-proj", "backfill.jsonl", content,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	archive := sync.NewArchive(t.TempDir())
+	env.engine.SetArchive(archive)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Skipped: 1})
+	if !archive.Has(path) {
+		t.Error("unchanged file was not archived after enabling archive mode")
+	}
+}
+
 // TestSyncSingleSessionReplacesContent verifies that an
 // explicit SyncSingleSession replaces existing message
 // content (same ordinals, different text).
@@ -831,6 +1081,328 @@ func TestSyncPathsGemini(t *testing.T) {
 	assertSessionMessageCount(t, env.db, "gemini:"+sessionID, 2)
 }
 
+func TestSyncEngineAider(t *testing.T) {
+	env := setupTestEnv(t)
+
+	history := "# aider chat started at 2024-01-01 10:00:00\n\n" +
+		"> Main model: gpt-4o with diff edit format\n\n" +
+		"#### add a readme\n\n" +
+		"README.md\n```\n<<<<<<< SEARCH\n=======\n# app\n>>>>>>> REPLACE\n```\n\n" +
+		"> Applied edit to README.md\n\n" +
+		"# aider chat started at 2024-01-02 10:00:00\n\n" +
+		"#### explain main.go\n\nIt prints hello.\n"
+	path := env.writeSession(
+		t, env.aiderDir, ".aider.chat.history.md", history,
+	)
+	env.writeSession(t, env.aiderDir, "notes.md", "not a history\n")
+
+	runSyncAndAssert(t, env.engine,
+		sync.SyncStats{TotalSessions: 1, Synced: 2, Skipped: 0})
+
+	page, err := env.db.ListSessions(
+		context.Background(), db.SessionFilter{Agent: "aider", Limit: 10},
+	)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(page.Sessions) != 2 {
+		t.Fatalf("got %d aider sessions, want 2", len(page.Sessions))
+	}
+	var first *db.Session
+	for i := range page.Sessions {
+		if page.Sessions[i].FirstMessage != nil &&
+			*page.Sessions[i].FirstMessage == "add a readme" {
+			first = &page.Sessions[i]
+		}
+	}
+	if first == nil {
+		t.Fatalf("session for first chat not found: %+v", page.Sessions)
+	}
+	if first.Cwd != env.aiderDir {
+		t.Errorf("cwd = %q, want %q", first.Cwd, env.aiderDir)
+	}
+	if got := env.engine.FindSourceFile(first.ID); got != path {
+		t.Errorf("FindSourceFile = %q, want %q", got, path)
+	}
+	assertMessageRoles(t, env.db, first.ID, "user", "assistant")
+	msgs := fetchMessages(t, env.db, first.ID)
+	if len(msgs[1].ToolCalls) != 1 ||
+		msgs[1].ToolCalls[0].ToolName != "Edit" ||
+		msgs[1].ToolCalls[0].IsError == nil ||
+		*msgs[1].ToolCalls[0].IsError {
+		t.Errorf("tool calls = %+v", msgs[1].ToolCalls)
+	}
+
+	// A new chat appended to the history is picked up by the
+	// watcher path.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString(
+		"\n# aider chat started at 2024-01-03 10:00:00\n\n#### hi\n",
+	)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.engine.SyncPaths([]string{
+		path, filepath.Join(env.aiderDir, "notes.md"),
+	})
+	page, err = env.db.ListSessions(
+		context.Background(), db.SessionFilter{Agent: "aider", Limit: 10},
+	)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(page.Sessions) != 3 {
+		t.Errorf("got %d aider sessions after append, want 3",
+			len(page.Sessions))
+	}
+
+	if err := env.engine.SyncSingleSession(first.ID); err != nil {
+		t.Errorf("SyncSingleSession: %v", err)
+	}
+}
+
+func TestSyncEngineCline(t *testing.T) {
+	env := setupTestEnv(t)
+
+	history := `[
+		{"role":"user","content":[{"type":"text","text":"<task>\nlist files\n</task>"}]},
+		{"role":"assistant","modelInfo":{"modelId":"mystery-model"},
+		 "content":[{"type":"text","text":"<list_files>\n<path>.</path>\n</list_files>"}]},
+		{"role":"user","content":[{"type":"text","text":"[list_files for '.'] Result:\na.go"}]}
+	]`
+	request := func(ts int64, cost string) string {
+		return fmt.Sprintf(
+			`{"ts":%d,"type":"say","say":"api_req_started",`+
+				`"text":"{\"tokensIn\":100,\"tokensOut\":10,\"cost\":%s}"}`,
+			ts, cost,
+		)
+	}
+	path := env.writeSession(t, env.clineDir,
+		"tasks/1704103200000/api_conversation_history.json", history)
+	uiPath := env.writeSession(t, env.clineDir,
+		"tasks/1704103200000/ui_messages.json",
+		"["+request(1704103201000, "0.5")+"]")
+
+	runSyncAndAssert(t, env.engine,
+		sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+
+	const id = "cline:1704103200000"
+	sess, err := env.db.GetSession(context.Background(), id)
+	if err != nil || sess == nil {
+		t.Fatalf("GetSession: %v, %v", sess, err)
+	}
+	if sess.Agent != "cline" || sess.CostUSD == nil || *sess.CostUSD != 0.5 {
+		t.Errorf("session = %+v, want cline with reported cost", sess)
+	}
+	if got := env.engine.FindSourceFile(id); got != path {
+		t.Errorf("FindSourceFile = %q, want %q", got, path)
+	}
+	assertMessageRoles(t, env.db, id, "user", "assistant")
+	msgs := fetchMessages(t, env.db, id)
+	if len(msgs[1].ToolCalls) != 1 ||
+		msgs[1].ToolCalls[0].Category != "Glob" ||
+		msgs[1].ToolCalls[0].ResultContent != "a.go" {
+		t.Errorf("tool calls = %+v", msgs[1].ToolCalls)
+	}
+
+	// A change to the UI messages alone syncs the task again.
+	env.writeSession(t, env.clineDir,
+		"tasks/1704103200000/ui_messages.json",
+		"["+request(1704103201000, "0.5")+","+
+			request(1704103202000, "0.25")+"]")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(uiPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	env.engine.SyncPaths([]string{uiPath})
+	sess, err = env.db.GetSession(context.Background(), id)
+	if err != nil || sess == nil {
+		t.Fatalf("GetSession after change: %v, %v", sess, err)
+	}
+	if sess.CostUSD == nil || *sess.CostUSD != 0.75 {
+		t.Errorf("cost after change = %v, want 0.75", sess.CostUSD)
+	}
+
+	if err := env.engine.SyncSingleSession(id); err != nil {
+		t.Errorf("SyncSingleSession: %v", err)
+	}
+}
+
+// TestSyncEngineGooseAndAmp verifies that Goose sessions and
+// Amp threads are discovered, skipped while unchanged, and
+// broken out by agent in the velocity analytics.
+func TestSyncEngineGooseAndAmp(t *testing.T) {
+	env := setupTestEnv(t)
+
+	const start int64 = 1718000000 // 2024-06-10T06:13:20Z
+	goosePath := env.writeSession(t, env.gooseDir,
+		"sessions/20240610_061320.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddGooseMeta("/src/app", "list files", 100, 10).
+			AddGooseMessage("m1", "user", start,
+				testjsonl.GooseText("list files")).
+			AddGooseMessage("m2", "assistant", start+3,
+				testjsonl.GooseToolRequest("c1", "developer__shell",
+					map[string]any{"command": "ls"})).
+			AddGooseMessage("m3", "user", start+4,
+				testjsonl.GooseToolResponse("c1", "a.go", false)).
+			AddGooseMessage("m4", "assistant", start+6,
+				testjsonl.GooseText("One file.")).
+			String(),
+	)
+	ampPath := env.writeSession(t, env.ampDir, "threads/T-1.json",
+		testjsonl.AmpThreadJSON("T-1", start*1000, "", "file:///src/app",
+			[]map[string]any{
+				testjsonl.AmpUserMsg(0, start*1000, "what changed?"),
+				testjsonl.AmpAssistantMsg(1, testjsonl.AmpMsgOpts{
+					Text:      "Nothing yet.",
+					Model:     "claude-sonnet-4-20250514",
+					Timestamp: "2024-06-10T06:13:30Z",
+				}),
+			}),
+	)
+
+	runSyncAndAssert(t, env.engine,
+		sync.SyncStats{TotalSessions: 2, Synced: 2, Skipped: 0})
+
+	for id, path := range map[string]string{
+		"goose:20240610_061320": goosePath,
+		"amp:T-1":               ampPath,
+	} {
+		if got := env.engine.FindSourceFile(id); got != path {
+			t.Errorf("FindSourceFile(%q) = %q, want %q", id, got, path)
+		}
+		if err := env.engine.SyncSingleSession(id); err != nil {
+			t.Errorf("SyncSingleSession(%q): %v", id, err)
+		}
+	}
+	assertMessageRoles(t, env.db, "goose:20240610_061320",
+		"user", "assistant", "assistant")
+	msgs := fetchMessages(t, env.db, "goose:20240610_061320")
+	if len(msgs[1].ToolCalls) != 1 ||
+		msgs[1].ToolCalls[0].Category != "Bash" ||
+		msgs[1].ToolCalls[0].ResultContent != "a.go" {
+		t.Errorf("goose tool calls = %+v", msgs[1].ToolCalls)
+	}
+
+	runSyncAndAssert(t, env.engine,
+		sync.SyncStats{TotalSessions: 2, Synced: 0, Skipped: 2})
+
+	resp, err := env.db.GetAnalyticsVelocity(
+		context.Background(),
+		db.AnalyticsFilter{From: "2024-06-01", To: "2024-06-30"},
+	)
+	if err != nil {
+		t.Fatalf("GetAnalyticsVelocity: %v", err)
+	}
+	var agents []string
+	for _, b := range resp.ByAgent {
+		agents = append(agents, b.Label)
+	}
+	if diff := cmp.Diff([]string{"amp", "goose"}, agents,
+		cmpopts.SortSlices(func(a, b string) bool { return a < b }),
+	); diff != "" {
+		t.Errorf("velocity agents mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSyncEngineOTelImport(t *testing.T) {
+	env := setupTestEnv(t)
+	if _, err := env.engine.ImportOTel(
+		[]parser.OTelBatch{{Key: "x"}},
+	); err == nil {
+		t.Error("import without an OTel directory succeeded")
+	}
+	otelDir := filepath.Join(t.TempDir(), "otel")
+	env.engine.SetOTelDir(otelDir)
+
+	const start int64 = 1718000000 * int64(time.Second)
+	sec := int64(time.Second)
+	chat := testjsonl.OTelSpan{
+		TraceID: "a", SpanID: "c1", Name: "chat",
+		Start: start, End: start + sec,
+		Attrs: map[string]any{
+			"gen_ai.operation.name":                 "chat",
+			"gen_ai.conversation.id":                "conv-1",
+			"gen_ai.request.model":                  "gpt-4o",
+			"gen_ai.prompt.0.role":                  "user",
+			"gen_ai.prompt.0.content":               "list files",
+			"gen_ai.completion.0.tool_calls.0.id":   "call_1",
+			"gen_ai.completion.0.tool_calls.0.name": "bash",
+		},
+	}
+	// The tool span carries no conversation ID; it joins the
+	// conversation through its trace.
+	tool := testjsonl.OTelSpan{
+		TraceID: "a", SpanID: "t1", Name: "execute_tool bash",
+		Start: start + 2*sec, End: start + 3*sec,
+		Attrs: map[string]any{
+			"gen_ai.tool.name":        "bash",
+			"gen_ai.tool.call.id":     "call_1",
+			"gen_ai.tool.call.result": "a.go",
+		},
+	}
+	other := testjsonl.OTelSpan{
+		TraceID: "b", SpanID: "c2", Name: "chat",
+		Start: start, End: start + sec,
+		Attrs: map[string]any{
+			"gen_ai.operation.name":   "chat",
+			"gen_ai.prompt.0.role":    "user",
+			"gen_ai.prompt.0.content": "hello",
+		},
+	}
+
+	importOTel := func(spans ...testjsonl.OTelSpan) []string {
+		t.Helper()
+		batches, err := parser.SplitOTelTraces([]byte(
+			testjsonl.OTLPRequestJSON("support-bot", "build-7", spans...),
+		))
+		if err != nil {
+			t.Fatalf("SplitOTelTraces: %v", err)
+		}
+		ids, err := env.engine.ImportOTel(batches)
+		if err != nil {
+			t.Fatalf("ImportOTel: %v", err)
+		}
+		return ids
+	}
+
+	if diff := cmp.Diff([]string{"otel:conv-1"}, importOTel(chat)); diff != "" {
+		t.Errorf("first import ids mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"otel:conv-1", "otel:b"},
+		importOTel(tool, other)); diff != "" {
+		t.Errorf("second import ids mismatch (-want +got):\n%s", diff)
+	}
+
+	assertSessionState(t, env.db, "otel:conv-1", func(sess *db.Session) {
+		if sess.Agent != "otel" || sess.Project != "support-bot" ||
+			sess.Machine != "build-7" {
+			t.Errorf("session = %s/%s/%s", sess.Agent, sess.Project, sess.Machine)
+		}
+	})
+	assertMessageRoles(t, env.db, "otel:conv-1", "user", "assistant")
+	msgs := fetchMessages(t, env.db, "otel:conv-1")
+	if len(msgs[1].ToolCalls) != 1 ||
+		msgs[1].ToolCalls[0].ResultContent != "a.go" {
+		t.Errorf("tool calls = %+v", msgs[1].ToolCalls)
+	}
+
+	path := filepath.Join(otelDir, "conv-1.jsonl")
+	if got := env.engine.FindSourceFile("otel:conv-1"); got != path {
+		t.Errorf("FindSourceFile = %q, want %q", got, path)
+	}
+	if err := env.engine.SyncSingleSession("otel:b"); err != nil {
+		t.Errorf("SyncSingleSession: %v", err)
+	}
+	runSyncAndAssert(t, env.engine,
+		sync.SyncStats{TotalSessions: 2, Synced: 0, Skipped: 2})
+}
+
 func TestSyncPathsCodexRejectsFlat(t *testing.T) {
 	env := setupTestEnv(t)
 
@@ -1306,6 +1878,65 @@ func TestSyncEngineOpenCodeToolCallReplace(t *testing.T) {
 // launches ResyncAll and signals when it enters its own
 // progress callback. If the mutex works, the second
 // signal only arrives after the barrier is released.
+// TestSyncEngineCursor verifies that Cursor chats are synced
+// from the state database, skipped while unchanged, and
+// replaced when a chat is updated.
+func TestSyncEngineCursor(t *testing.T) {
+	env := setupTestEnv(t)
+
+	composer := func(updated int64, bubbles ...string) string {
+		headers := make([]string, len(bubbles))
+		for i, b := range bubbles {
+			headers[i] = fmt.Sprintf(`{"bubbleId":%q}`, b)
+		}
+		return fmt.Sprintf(
+			`{"composerId":"c1","createdAt":1704067200000,`+
+				`"lastUpdatedAt":%d,"fullConversationHeadersOnly":[%s]}`,
+			updated, strings.Join(headers, ","),
+		)
+	}
+	writeCursorChats(t, env.cursorDir, map[string]string{
+		"composerData:c1": composer(1704067205000, "u1", "a1"),
+		"bubbleId:c1:u1":  `{"type":1,"text":"hello cursor"}`,
+		"bubbleId:c1:a1":  `{"type":2,"text":"hi there"}`,
+	})
+
+	runSyncAndAssert(t, env.engine,
+		sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+
+	const id = "cursor:c1"
+	assertSessionState(t, env.db, id, func(sess *db.Session) {
+		if sess.Agent != "cursor" {
+			t.Errorf("agent = %q, want cursor", sess.Agent)
+		}
+	})
+	assertMessageContent(t, env.db, id, "hello cursor", "hi there")
+
+	// An unchanged database is not read again.
+	runSyncAndAssert(t, env.engine, sync.SyncStats{})
+
+	writeCursorChats(t, env.cursorDir, map[string]string{
+		"composerData:c1": composer(1704067210000, "u1", "a1", "a2"),
+		"bubbleId:c1:a2":  `{"type":2,"text":"anything else?"}`,
+	})
+	later := time.Now().Add(time.Minute)
+	dbPath := filepath.Join(env.cursorDir, "globalStorage", "state.vscdb")
+	if err := os.Chtimes(dbPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	runSyncAndAssert(t, env.engine,
+		sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+	assertMessageContent(t, env.db, id,
+		"hello cursor", "hi there", "anything else?")
+
+	if err := env.engine.SyncSingleSession(id); err != nil {
+		t.Errorf("SyncSingleSession: %v", err)
+	}
+	if got := env.engine.FindSourceFile(id); got != "" {
+		t.Errorf("FindSourceFile = %q, want empty", got)
+	}
+}
+
 func TestSyncEngineConcurrentSerialization(t *testing.T) {
 	env := setupTestEnv(t)
 
@@ -1603,3 +2234,224 @@ func TestSyncSmallGapRetry(t *testing.T) {
 
 	assertSessionMessageCount(t, env.db, "retry-uuid", 4)
 }
+
+// appendToSession appends content to an existing session file.
+func appendToSession(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("append %s: %v", path, err)
+	}
+}
+
+func TestSyncIncrementalAppendMatchesFullParse(t *testing.T) {
+	env := setupTestEnv(t)
+
+	toolUse := `{"type":"assistant","timestamp":"2024-01-01T10:00:01Z",` +
+		`"uuid":"b","parentUuid":"a","message":{"id":"msg_1",` +
+		`"model":"claude-sonnet-4","usage":{"input_tokens":10,` +
+		`"output_tokens":5},"content":[{"type":"tool_use",` +
+		`"id":"toolu_1","name":"Read",` +
+		`"input":{"file_path":"main.go"}}]}}`
+	toolResult := `{"type":"user","timestamp":"2024-01-01T10:00:02Z",` +
+		`"uuid":"c","parentUuid":"b","message":{"content":[` +
+		`{"type":"tool_result","tool_use_id":"toolu_1",` +
+		`"content":"package main"}]}}`
+
+	initial := testjsonl.NewSessionBuilder().
+		AddClaudeUserWithUUID(tsEarly, "read main.go", "a", "").
+		AddRaw(toolUse).
+		String()
+	appended := testjsonl.NewSessionBuilder().
+		AddRaw(toolResult).
+		AddClaudeAssistantWithUUID(tsEarlyS5, "It is package main.", "d", "c").
+		AddClaudeUserWithUUID("2024-01-01T10:00:06Z", "thanks", "e", "d").
+		String()
+
+	path := env.writeClaudeSession(
+		t, "test-proj", "grow.jsonl", initial,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+
+	appendToSession(t, path, appended)
+	if err := env.engine.SyncSingleSession("grow"); err != nil {
+		t.Fatalf("SyncSingleSession: %v", err)
+	}
+
+	// A fresh engine parsing the whole file must store the same
+	// session and messages.
+	fresh := setupTestEnv(t)
+	fresh.writeClaudeSession(
+		t, "test-proj", "grow.jsonl", initial+appended,
+	)
+	runSyncAndAssert(t, fresh.engine, sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+
+	ctx := context.Background()
+	got, err := env.db.GetSession(ctx, "grow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := fresh.db.GetSession(ctx, "grow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignore := cmpopts.IgnoreFields(db.Session{},
+		"FilePath", "FileMtime", "CreatedAt")
+	if diff := cmp.Diff(want, got, ignore); diff != "" {
+		t.Errorf("session mismatch (-full +incremental):\n%s", diff)
+	}
+	if diff := cmp.Diff(
+		fetchMessages(t, fresh.db, "grow"),
+		fetchMessages(t, env.db, "grow"),
+	); diff != "" {
+		t.Errorf("messages mismatch (-full +incremental):\n%s", diff)
+	}
+}
+
+func TestSyncIncrementalKeepsStoredMessages(t *testing.T) {
+	env := setupTestEnv(t)
+
+	initial := testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsEarly, "grow-uuid", "/home/user/code/api", "user").
+		AddCodexMessage(tsEarlyS1, "user", "Add tests").
+		String()
+	path := env.writeCodexSession(
+		t, filepath.Join("2024", "01", "15"),
+		"rollout-20240115-grow-uuid.jsonl", initial,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+
+	// Mark the stored row: an incremental sync only appends, so
+	// the marker must survive.
+	err := env.db.Update(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`UPDATE messages SET content = 'marker'
+			 WHERE session_id = 'codex:grow-uuid'`,
+		)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appendToSession(t, path, testjsonl.NewSessionBuilder().
+		AddCodexMessage(tsEarlyS5, "assistant", "Adding coverage.").
+		String())
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+
+	assertMessageContent(t, env.db, "codex:grow-uuid",
+		"marker", "Adding coverage.")
+	assertSessionState(t, env.db, "codex:grow-uuid", func(sess *db.Session) {
+		if sess.MessageCount != 2 || sess.UserMessageCount != 1 {
+			t.Errorf("counts = %d/%d, want 2/1",
+				sess.MessageCount, sess.UserMessageCount)
+		}
+	})
+}
+
+func TestSyncIncrementalPairsCodexToolOutput(t *testing.T) {
+	env := setupTestEnv(t)
+
+	initial := testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsEarly, "out-uuid", "/home/user/code/api", "user").
+		AddCodexMessage(tsEarlyS1, "user", "Run the tests").
+		AddRaw(testjsonl.CodexFunctionCallArgsJSON(
+			"exec_command", `{"cmd":"go test ./..."}`, tsEarlyS5,
+		)).
+		String()
+	path := env.writeCodexSession(
+		t, filepath.Join("2024", "01", "15"),
+		"rollout-20240115-out-uuid.jsonl", initial,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+
+	appendToSession(t, path, testjsonl.NewSessionBuilder().
+		AddRaw(testjsonl.CodexFunctionCallOutputJSON(
+			"call_test", "Exit code: 1\nOutput:\nFAIL", "2024-01-01T10:00:06Z",
+		)).
+		AddCodexMessage("2024-01-01T10:00:07Z", "assistant", "A test fails.").
+		String())
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+
+	msgs := fetchMessages(t, env.db, "codex:out-uuid")
+	if len(msgs) != 3 {
+		t.Fatalf("messages = %d, want 3", len(msgs))
+	}
+	tc := msgs[1].ToolCalls[0]
+	if tc.IsError == nil || !*tc.IsError ||
+		tc.ExitCode == nil || *tc.ExitCode != 1 {
+		t.Errorf("tool call status = %v %v, want error, exit 1",
+			tc.IsError, tc.ExitCode)
+	}
+	assertSessionState(t, env.db, "codex:out-uuid", func(sess *db.Session) {
+		if sess.MessageCount != 3 {
+			t.Errorf("MessageCount = %d, want 3", sess.MessageCount)
+		}
+	})
+}
+
+func TestSyncIncrementalFallsBackToFullParse(t *testing.T) {
+	initial := testjsonl.NewSessionBuilder().
+		AddClaudeUserWithUUID(tsEarly, "start", "a", "").
+		AddClaudeAssistantWithUUID(tsEarlyS1, "ok", "b", "a").
+		AddClaudeUserWithUUID("2024-01-01T10:00:02Z", "try1", "c", "b").
+		AddClaudeAssistantWithUUID("2024-01-01T10:00:03Z", "resp1", "d", "c").
+		String()
+
+	tests := []struct {
+		name    string
+		rewrite func(t *testing.T, path string)
+		want    []string
+	}{
+		{
+			name: "retry appended",
+			rewrite: func(t *testing.T, path string) {
+				appendToSession(t, path, testjsonl.NewSessionBuilder().
+					AddClaudeUserWithUUID("2024-01-01T10:01:00Z", "try2", "e", "b").
+					AddClaudeAssistantWithUUID("2024-01-01T10:01:01Z", "resp2", "f", "e").
+					String())
+			},
+			want: []string{"start", "ok", "try2", "resp2"},
+		},
+		{
+			name: "prefix rewritten",
+			rewrite: func(t *testing.T, path string) {
+				content := strings.Replace(
+					initial, `"start"`, `"START"`, 1,
+				) + testjsonl.NewSessionBuilder().
+					AddClaudeUserWithUUID(tsEarlyS5, "more", "e", "d").
+					String()
+				if err := os.WriteFile(
+					path, []byte(content), 0o644,
+				); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"START", "ok", "try1", "resp1", "more"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupTestEnv(t)
+			path := env.writeClaudeSession(
+				t, "test-proj", "fallback.jsonl", initial,
+			)
+			runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1, Skipped: 0})
+
+			tt.rewrite(t, path)
+			if err := env.engine.SyncSingleSession(
+				"fallback",
+			); err != nil {
+				t.Fatalf("SyncSingleSession: %v", err)
+			}
+			assertMessageContent(t, env.db, "fallback", tt.want...)
+			assertSessionMessageCount(
+				t, env.db, "fallback", len(tt.want),
+			)
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
 	"github.com/wesm/agentsview/internal/parser"
 )
 
@@ -42,7 +43,7 @@ func TestFilterEmptyMessages(t *testing.T) {
 					Role:    "assistant",
 					Content: "Let me read the file.",
 					ToolCalls: []db.ToolCall{
-						{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 500, ResultContent: "file data"},
+						{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 500, ResultContent: "file data", IsError: dbtest.Ptr(false)},
 					},
 				},
 			},
@@ -61,7 +62,7 @@ func TestFilterEmptyMessages(t *testing.T) {
 					Role:    "user",
 					Content: "",
 					ToolResults: []db.ToolResult{
-						{ToolUseID: "t1", ContentLength: 100, Content: "bash output"},
+						{ToolUseID: "t1", ContentLength: 100, Content: "bash output", IsError: true, ExitCode: dbtest.Ptr(2)},
 					},
 				},
 				{
@@ -74,7 +75,7 @@ func TestFilterEmptyMessages(t *testing.T) {
 					Role:    "assistant",
 					Content: "Here is the result.",
 					ToolCalls: []db.ToolCall{
-						{ToolUseID: "t1", ToolName: "Bash", ResultContentLength: 100, ResultContent: "bash output"},
+						{ToolUseID: "t1", ToolName: "Bash", ResultContentLength: 100, ResultContent: "bash output", IsError: dbtest.Ptr(true), ExitCode: dbtest.Ptr(2)},
 					},
 				},
 				{
@@ -106,7 +107,7 @@ func TestFilterEmptyMessages(t *testing.T) {
 					Role:    "assistant",
 					Content: "Reading...",
 					ToolCalls: []db.ToolCall{
-						{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 300, ResultContent: "read output"},
+						{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 300, ResultContent: "read output", IsError: dbtest.Ptr(false)},
 					},
 				},
 			},
@@ -238,8 +239,8 @@ func TestPairToolResults(t *testing.T) {
 			},
 			want: []db.Message{
 				{ToolCalls: []db.ToolCall{
-					{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 100, ResultContent: "file contents"},
-					{ToolUseID: "t2", ToolName: "Grep", ResultContentLength: 200, ResultContent: "grep output"},
+					{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 100, ResultContent: "file contents", IsError: dbtest.Ptr(false)},
+					{ToolUseID: "t2", ToolName: "Grep", ResultContentLength: 200, ResultContent: "grep output", IsError: dbtest.Ptr(false)},
 				}},
 				{ToolResults: []db.ToolResult{
 					{ToolUseID: "t1", ContentLength: 100, Content: "file contents"},
@@ -260,7 +261,7 @@ func TestPairToolResults(t *testing.T) {
 			},
 			want: []db.Message{
 				{ToolCalls: []db.ToolCall{
-					{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 50, ResultContent: "data"},
+					{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 50, ResultContent: "data", IsError: dbtest.Ptr(false)},
 				}},
 				{ToolResults: []db.ToolResult{
 					{ToolUseID: "t1", ContentLength: 50, Content: "data"},
@@ -281,7 +282,7 @@ func TestPairToolResults(t *testing.T) {
 			},
 			want: []db.Message{
 				{ToolCalls: []db.ToolCall{
-					{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 42, ResultContent: "result text"},
+					{ToolUseID: "t1", ToolName: "Read", ResultContentLength: 42, ResultContent: "result text", IsError: dbtest.Ptr(false)},
 					{ToolUseID: "t2", ToolName: "Bash", ResultContentLength: 0},
 				}},
 				{ToolResults: []db.ToolResult{
@@ -0,0 +1,53 @@
+package sync
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// toolFiles returns the files a tool call read or changed.
+// Relative paths are resolved against the session's working
+// directory cwd, and files inside the session's repository
+// repoRoot are stored relative to it. Other paths are kept as
+// the agent gave them.
+func toolFiles(
+	toolName, inputJSON, cwd, repoRoot string,
+) []db.ToolFile {
+	extracted := parser.ExtractToolFiles(toolName, inputJSON)
+	if len(extracted) == 0 {
+		return nil
+	}
+	files := make([]db.ToolFile, 0, len(extracted))
+	for _, f := range extracted {
+		p := f.Path
+		if !filepath.IsAbs(p) && filepath.IsAbs(cwd) {
+			p = filepath.Join(cwd, p)
+		}
+		tf := db.ToolFile{Path: filepath.Clean(p), Action: f.Action}
+		if rel, ok := repoRelative(repoRoot, tf.Path); ok {
+			tf.RepoRoot = repoRoot
+			tf.Path = rel
+		} else if !filepath.IsAbs(tf.Path) {
+			tf.Path = filepath.ToSlash(tf.Path)
+		}
+		files = append(files, tf)
+	}
+	return files
+}
+
+// repoRelative returns the slash-separated path of the absolute
+// path p within the repository root, and whether p is inside it.
+func repoRelative(root, p string) (string, bool) {
+	if root == "" || !filepath.IsAbs(p) {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,100 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wesm/agentsview/internal/db"
+)
+
+func TestToolFiles(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "app")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(t.TempDir(), "lib")
+	if err := os.MkdirAll(filepath.Join(other, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	cwd := filepath.Join(repo, "cmd")
+	slash := func(p string) string { return filepath.ToSlash(p) }
+
+	tests := []struct {
+		name, tool, input string
+		cwd, repoRoot     string
+		want              []db.ToolFile
+	}{
+		{
+			name:     "inside repo",
+			tool:     "Edit",
+			input:    `{"file_path":"` + slash(filepath.Join(repo, "internal", "db", "x.go")) + `"}`,
+			cwd:      repo,
+			repoRoot: repo,
+			want: []db.ToolFile{{
+				Path: "internal/db/x.go", RepoRoot: repo, Action: "edit",
+			}},
+		},
+		{
+			name:     "outside repo",
+			tool:     "Read",
+			input:    `{"file_path":"` + slash(filepath.Join(outside, "notes.txt")) + `"}`,
+			cwd:      repo,
+			repoRoot: repo,
+			want: []db.ToolFile{{
+				Path: filepath.Join(outside, "notes.txt"), Action: "read",
+			}},
+		},
+		{
+			// A file in another repository is not keyed to it.
+			name:     "other repo",
+			tool:     "Read",
+			input:    `{"file_path":"` + slash(filepath.Join(other, "lib.go")) + `"}`,
+			cwd:      repo,
+			repoRoot: repo,
+			want: []db.ToolFile{{
+				Path: filepath.Join(other, "lib.go"), Action: "read",
+			}},
+		},
+		{
+			name:     "relative to cwd",
+			tool:     "view",
+			input:    `{"path":"./main.go"}`,
+			cwd:      cwd,
+			repoRoot: repo,
+			want: []db.ToolFile{{
+				Path: "cmd/main.go", RepoRoot: repo, Action: "read",
+			}},
+		},
+		{
+			name:  "relative without cwd",
+			tool:  "view",
+			input: `{"path":"./cmd/main.go"}`,
+			want:  []db.ToolFile{{Path: "cmd/main.go", Action: "read"}},
+		},
+		{
+			name:  "no session repo",
+			tool:  "Read",
+			input: `{"file_path":"` + slash(filepath.Join(repo, "go.mod")) + `"}`,
+			cwd:   outside,
+			want: []db.ToolFile{{
+				Path: filepath.Join(repo, "go.mod"), Action: "read",
+			}},
+		},
+		{
+			name:  "not a file tool",
+			tool:  "Bash",
+			input: `{"command":"ls"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toolFiles(tt.tool, tt.input, tt.cwd, tt.repoRoot)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("toolFiles() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -29,3 +29,32 @@ func ComputeFileHash(path string) (string, error) {
 	}
 	return hash, nil
 }
+
+// computeFilePrefixHashes returns the SHA-256 hex digests of the
+// first n bytes of the file at path for each of the ascending
+// offsets, followed by the digest of the whole file, reading
+// the file once.
+func computeFilePrefixHashes(
+	path string, offsets ...int64,
+) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	hashes := make([]string, 0, len(offsets)+1)
+	var pos int64
+	for _, off := range offsets {
+		if _, err := io.CopyN(h, f, off-pos); err != nil {
+			return nil, fmt.Errorf("hashing %s: %w", path, err)
+		}
+		pos = off
+		hashes = append(hashes, fmt.Sprintf("%x", h.Sum(nil)))
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("hashing %s: %w", path, err)
+	}
+	return append(hashes, fmt.Sprintf("%x", h.Sum(nil))), nil
+}
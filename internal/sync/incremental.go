@@ -0,0 +1,207 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// fileCheckpoint lets an append-only session file be parsed
+// incrementally. It pairs a parser checkpoint with the hash of
+// the bytes before the checkpoint offset and with the file
+// size/mtime written to the session row by the same parse.
+type fileCheckpoint struct {
+	cp         *parser.Checkpoint
+	prefixHash string
+	sessionID  string
+	size       int64
+	mtime      int64
+}
+
+func (e *Engine) setCheckpoint(path string, fc fileCheckpoint) {
+	e.cpMu.Lock()
+	e.checkpoints[path] = fc
+	e.cpMu.Unlock()
+}
+
+func (e *Engine) dropCheckpoint(path string) {
+	e.cpMu.Lock()
+	delete(e.checkpoints, path)
+	e.cpMu.Unlock()
+}
+
+// hashAndCheckpoint sets the file hash on freshly parsed results
+// and records cp (nil clears any previous checkpoint) so the
+// next change to the file can be parsed incrementally.
+func (e *Engine) hashAndCheckpoint(
+	path string, results []parser.ParseResult,
+	cp *parser.Checkpoint,
+) {
+	if cp == nil || len(results) != 1 {
+		e.dropCheckpoint(path)
+		if hash, err := ComputeFileHash(path); err == nil {
+			for i := range results {
+				results[i].Session.File.Hash = hash
+			}
+		}
+		return
+	}
+
+	hashes, err := computeFilePrefixHashes(path, cp.Offset)
+	if err != nil {
+		e.dropCheckpoint(path)
+		return
+	}
+	f := &results[0].Session.File
+	f.Hash = hashes[1]
+	e.setCheckpoint(path, fileCheckpoint{
+		cp:         cp,
+		prefixHash: hashes[0],
+		sessionID:  results[0].Session.ID,
+		size:       f.Size,
+		mtime:      f.Mtime,
+	})
+}
+
+// resumeFile parses only the lines appended to a file since its
+// last sync. It reports false, leaving the caller to do a full
+// parse, when there is no usable checkpoint: the file did not
+// grow, the stored session no longer matches the checkpoint, the
+// bytes before the checkpoint changed (truncation or rewrite),
+// or the appended lines fork the conversation.
+func (e *Engine) resumeFile(
+	file DiscoveredFile, info os.FileInfo,
+) (processResult, bool) {
+	e.cpMu.Lock()
+	fc, ok := e.checkpoints[file.Path]
+	e.cpMu.Unlock()
+	if !ok || info.Size() <= fc.size {
+		return processResult{}, false
+	}
+
+	size, mtime, ok := e.db.GetSessionFileInfo(fc.sessionID)
+	if !ok || size != fc.size || mtime != fc.mtime {
+		return processResult{}, false
+	}
+
+	res, next, err := parser.ResumeSession(file.Path, fc.cp)
+	if err != nil {
+		return processResult{}, false
+	}
+	hashes, err := computeFilePrefixHashes(
+		file.Path, fc.cp.Offset, next.Offset,
+	)
+	if err != nil || hashes[0] != fc.prefixHash {
+		return processResult{}, false
+	}
+	res.Session.File.Hash = hashes[2]
+
+	results := []parser.ParseResult{res}
+	parser.InferRelationshipTypes(results)
+
+	e.setCheckpoint(file.Path, fileCheckpoint{
+		cp:         next,
+		prefixHash: hashes[1],
+		sessionID:  fc.sessionID,
+		size:       res.Session.File.Size,
+		mtime:      res.Session.File.Mtime,
+	})
+	return processResult{results: results, incremental: true}, true
+}
+
+// writeIncremental stores a resumed parse: pw.msgs holds only
+// the messages appended since the last sync and pw.sess the
+// updated session. Messages are written before the session row
+// so a failure leaves the stored file size/mtime stale and the
+// next sync does a full parse.
+func (e *Engine) writeIncremental(pw pendingWrite) {
+	stored, err := e.db.GetSession(
+		context.Background(), pw.sess.ID,
+	)
+	if err != nil || stored == nil {
+		log.Printf(
+			"append session %s: stored session missing",
+			pw.sess.ID,
+		)
+		return
+	}
+
+	msgs := convertMessages(pw)
+	results := unpairedToolResults(msgs)
+	msgs = pairAndFilter(msgs)
+	if err := e.db.AppendMessages(
+		pw.sess.ID, msgs, results,
+	); err != nil {
+		log.Printf(
+			"append messages for %s: %v", pw.sess.ID, err,
+		)
+		return
+	}
+
+	s := toDBSession(pw)
+	total, user := postFilterCounts(msgs)
+	s.MessageCount = stored.MessageCount + total
+	s.UserMessageCount = stored.UserMessageCount + user
+	s.MCPServers = mergeMCPServers(stored.MCPServers, s.MCPServers)
+	if err := e.db.UpsertSession(s); err != nil {
+		log.Printf("upsert session %s: %v", s.ID, err)
+	}
+}
+
+// unpairedToolResults returns tool results whose tool call is
+// not among msgs, i.e. results for calls stored by an earlier
+// sync.
+func unpairedToolResults(msgs []db.Message) []db.ToolResult {
+	calls := make(map[string]bool)
+	for _, m := range msgs {
+		for _, tc := range m.ToolCalls {
+			if tc.ToolUseID != "" {
+				calls[tc.ToolUseID] = true
+			}
+		}
+	}
+	var results []db.ToolResult
+	for _, m := range msgs {
+		for _, tr := range m.ToolResults {
+			if tr.ToolUseID != "" && !calls[tr.ToolUseID] {
+				results = append(results, tr)
+			}
+		}
+	}
+	return results
+}
+
+// mergeMCPServers returns the union of two JSON arrays of MCP
+// server names.
+func mergeMCPServers(a, b db.RawJSON) db.RawJSON {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	var x, y []string
+	if json.Unmarshal(a, &x) != nil ||
+		json.Unmarshal(b, &y) != nil {
+		return b
+	}
+	seen := make(map[string]bool, len(x))
+	for _, s := range x {
+		seen[s] = true
+	}
+	for _, s := range y {
+		if !seen[s] {
+			seen[s] = true
+			x = append(x, s)
+		}
+	}
+	merged, err := json.Marshal(x)
+	if err != nil {
+		return b
+	}
+	return merged
+}
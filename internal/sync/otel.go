@@ -0,0 +1,107 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// maxOTelTraces bounds the trace IDs remembered for joining
+// spans to conversations. The map is cleared when it fills.
+const maxOTelTraces = 10000
+
+// SetOTelDir sets the directory ImportOTel keeps sessions in,
+// enabling the import. Call before the first sync.
+func (e *Engine) SetOTelDir(dir string) {
+	e.otelDir = dir
+}
+
+// ImportOTel stores GenAI spans split from an OTLP/JSON trace
+// export and syncs the sessions they belong to. Each session's
+// spans are appended to its own file in the OTel directory, so
+// a session exported in several batches is rebuilt from all of
+// them. Returns the IDs of the sessions the spans went to.
+func (e *Engine) ImportOTel(
+	batches []parser.OTelBatch,
+) ([]string, error) {
+	if e.otelDir == "" {
+		return nil, errors.New("otel import is not enabled")
+	}
+	if len(batches) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(e.otelDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating otel directory: %w", err)
+	}
+
+	paths, err := e.appendOTelBatches(batches)
+	if len(paths) > 0 {
+		e.SyncPaths(paths)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(paths))
+	for i, p := range paths {
+		ids[i] = "otel:" + parser.OTelSessionID(p)
+	}
+	return ids, nil
+}
+
+// appendOTelBatches appends each batch to the file of its
+// session and returns the files written. Spans without a
+// conversation ID join the conversation an earlier batch of
+// their trace belonged to.
+func (e *Engine) appendOTelBatches(
+	batches []parser.OTelBatch,
+) ([]string, error) {
+	e.otelMu.Lock()
+	defer e.otelMu.Unlock()
+
+	var paths []string
+	written := make(map[string]bool)
+	for _, b := range batches {
+		key := b.Key
+		if b.Conversation {
+			if len(e.otelTraces)+len(b.TraceIDs) > maxOTelTraces {
+				e.otelTraces = make(map[string]string)
+			}
+			for _, id := range b.TraceIDs {
+				e.otelTraces[id] = key
+			}
+		} else if len(b.TraceIDs) > 0 {
+			if conv, ok := e.otelTraces[b.TraceIDs[0]]; ok {
+				key = conv
+			}
+		}
+
+		path := filepath.Join(e.otelDir, key+".jsonl")
+		if err := appendLine(path, b.Request); err != nil {
+			return paths, err
+		}
+		if !written[path] {
+			written[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// appendLine appends data and a newline to the file at path,
+// creating it if needed.
+func appendLine(path string, data []byte) error {
+	f, err := os.OpenFile(
+		path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644,
+	)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,110 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// sessionFileParser parses a standalone session file into the
+// sessions it holds.
+type sessionFileParser func(
+	path, project, machine string,
+) ([]parser.ParseResult, error)
+
+// sessionFileParsers holds the parser of each agent whose
+// sessions can be read from a file on its own. The engine parses
+// one-session-per-file formats through it, and uploads use it
+// for every format they accept.
+var sessionFileParsers = map[parser.AgentType]sessionFileParser{
+	parser.AgentClaude:   parseClaudeFile,
+	parser.AgentCodex:    parseCodexFile,
+	parser.AgentCopilot:  singleSessionFile(parser.ParseCopilotSession),
+	parser.AgentGemini:   parseGeminiFile,
+	parser.AgentOpenCode: singleSessionFile(parser.ParseOpenCodeExport),
+	parser.AgentGoose:    singleSessionFile(parser.ParseGooseSession),
+	parser.AgentAmp:      singleSessionFile(parser.ParseAmpThread),
+	parser.AgentOTel:     singleSessionFile(parser.ParseOTelSession),
+}
+
+// CanParseSessionFile reports whether ParseSessionFile handles
+// files of agent.
+func CanParseSessionFile(agent parser.AgentType) bool {
+	_, ok := sessionFileParsers[agent]
+	return ok
+}
+
+// ParseSessionFile parses the session file at path with the
+// parser registered for agent and records the file's hash on
+// the sessions. project names the project for formats that
+// don't record their own. Returns no results for files without
+// a session worth keeping.
+func ParseSessionFile(
+	agent parser.AgentType, path, project, machine string,
+) ([]parser.ParseResult, error) {
+	parse, ok := sessionFileParsers[agent]
+	if !ok {
+		return nil, fmt.Errorf("unsupported agent: %q", agent)
+	}
+	results, err := parse(path, project, machine)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	if hash, err := ComputeFileHash(path); err == nil {
+		for i := range results {
+			results[i].Session.File.Hash = hash
+		}
+	}
+	return results, nil
+}
+
+// singleSessionFile adapts the parser of a format with one
+// session per file and no project of its own.
+func singleSessionFile(
+	parse func(path, machine string) (
+		*parser.ParsedSession, []parser.ParsedMessage, error,
+	),
+) sessionFileParser {
+	return func(path, _, machine string) ([]parser.ParseResult, error) {
+		return sessionResults(parse(path, machine))
+	}
+}
+
+// sessionResults wraps the output of a single-session parser.
+func sessionResults(
+	sess *parser.ParsedSession, msgs []parser.ParsedMessage, err error,
+) ([]parser.ParseResult, error) {
+	if err != nil || sess == nil {
+		return nil, err
+	}
+	return []parser.ParseResult{
+		{Session: *sess, Messages: msgs},
+	}, nil
+}
+
+func parseClaudeFile(
+	path, project, machine string,
+) ([]parser.ParseResult, error) {
+	results, err := parser.ParseClaudeSession(path, project, machine)
+	if err != nil {
+		return nil, err
+	}
+	parser.InferRelationshipTypes(results)
+	return results, nil
+}
+
+func parseCodexFile(
+	path, _, machine string,
+) ([]parser.ParseResult, error) {
+	return sessionResults(
+		parser.ParseCodexSession(path, machine, false),
+	)
+}
+
+func parseGeminiFile(
+	path, project, machine string,
+) ([]parser.ParseResult, error) {
+	return sessionResults(
+		parser.ParseGeminiSession(path, project, machine),
+	)
+}
@@ -561,6 +561,26 @@ func TestBuildGeminiProjectMap(t *testing.T) {
 	}
 }
 
+func TestBuildGeminiProjectDirs(t *testing.T) {
+	dir := t.TempDir()
+	projectsJSON := `{"projects":{"/Users/alice/code/my-app":"my-app"}}`
+	if err := os.WriteFile(
+		filepath.Join(dir, "projects.json"),
+		[]byte(projectsJSON), 0o644,
+	); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	m := buildGeminiProjectDirs(dir)
+	hash := geminiPathHash("/Users/alice/code/my-app")
+	for _, key := range []string{hash, "my-app"} {
+		if m[key] != "/Users/alice/code/my-app" {
+			t.Errorf("dir for %q = %q, want %q",
+				key, m[key], "/Users/alice/code/my-app")
+		}
+	}
+}
+
 func TestBuildGeminiProjectMapMissingFile(t *testing.T) {
 	m := buildGeminiProjectMap(t.TempDir())
 	if len(m) != 0 {
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -403,3 +404,34 @@ func (oc *openCodeTestDB) replaceTextContent(
 		assistantContent, timeCreated+1,
 	)
 }
+
+// writeCursorChats stores the given cursorDiskKV entries in the
+// global state database of a Cursor user directory, creating
+// the database if needed.
+func writeCursorChats(
+	t *testing.T, userDir string, kv map[string]string,
+) {
+	t.Helper()
+	path := filepath.Join(userDir, "globalStorage", "state.vscdb")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	d, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("opening cursor test db: %v", err)
+	}
+	defer d.Close()
+	if _, err := d.Exec(`CREATE TABLE IF NOT EXISTS cursorDiskKV (
+		key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB
+	)`); err != nil {
+		t.Fatalf("creating cursor schema: %v", err)
+	}
+	for k, v := range kv {
+		if _, err := d.Exec(
+			"INSERT INTO cursorDiskKV (key, value) VALUES (?, ?)",
+			k, []byte(v),
+		); err != nil {
+			t.Fatalf("inserting %s: %v", k, err)
+		}
+	}
+}
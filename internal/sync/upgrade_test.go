@@ -0,0 +1,183 @@
+package sync_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+	"github.com/wesm/agentsview/internal/sync"
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+// baseSchemaDrops lists what databases at schema version 3, the
+// oldest migrated in place, lack.
+var baseSchemaDrops = []string{
+	"DROP TABLE tool_call_files",
+	"DELETE FROM stats WHERE key = 'tool_files_version'",
+	"ALTER TABLE tool_calls DROP COLUMN is_error",
+	"ALTER TABLE tool_calls DROP COLUMN exit_code",
+	"ALTER TABLE messages DROP COLUMN model",
+	"ALTER TABLE messages DROP COLUMN input_tokens",
+	"ALTER TABLE messages DROP COLUMN output_tokens",
+	"ALTER TABLE messages DROP COLUMN cache_creation_input_tokens",
+	"ALTER TABLE messages DROP COLUMN cache_read_input_tokens",
+	"ALTER TABLE sessions DROP COLUMN cwd",
+	"ALTER TABLE sessions DROP COLUMN git_branch",
+	"ALTER TABLE sessions DROP COLUMN repo_root",
+	"ALTER TABLE sessions DROP COLUMN needs_reparse",
+	"UPDATE stats SET value = 3 WHERE key = 'schema_version'",
+}
+
+// upgradedSession is a Claude session with a model, token usage
+// and a failed edit of /src/app/main.go.
+var upgradedSession = testjsonl.JoinJSONL(
+	`{"type":"user","timestamp":"2024-01-01T10:00:00Z","cwd":"/src/app","message":{"content":"Fix main.go"}}`,
+	`{"type":"assistant","timestamp":"2024-01-01T10:00:01Z","message":{"id":"m1","model":"claude-sonnet-4","content":[{"type":"tool_use","id":"tu1","name":"Edit","input":{"file_path":"/src/app/main.go"}}],"usage":{"input_tokens":10,"output_tokens":20,"cache_creation_input_tokens":30,"cache_read_input_tokens":40}}}`,
+	`{"type":"user","timestamp":"2024-01-01T10:00:02Z","message":{"content":[{"type":"tool_result","tool_use_id":"tu1","content":"no match","is_error":true}]}}`,
+	`{"type":"assistant","timestamp":"2024-01-01T10:00:03Z","message":{"id":"m2","model":"claude-sonnet-4","content":"Done.","usage":{"input_tokens":1,"output_tokens":2}}}`,
+)
+
+// syncUpgraded syncs upgradedSession into a database, takes the
+// database back to schema version 3, then reopens it, which
+// migrates it, and syncs again as the first run of an upgraded
+// install would. removeSource deletes the session file before
+// that sync, as for sessions whose files are gone.
+func syncUpgraded(t *testing.T, removeSource bool) *db.DB {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	claudeDir := t.TempDir()
+	source := filepath.Join(claudeDir, "-src-app", "up-1.jsonl")
+	dbtest.WriteTestFile(t, source, []byte(upgradedSession))
+	path := filepath.Join(t.TempDir(), "sessions.db")
+	newEngine := func(d *db.DB) *sync.Engine {
+		return sync.NewEngine(
+			d, []string{claudeDir}, nil, nil, nil, nil,
+			nil, nil, nil, nil, nil, "local",
+		)
+	}
+
+	d, err := db.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	newEngine(d).SyncAll(nil)
+	d.Close()
+
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range baseSchemaDrops {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	conn.Close()
+	if removeSource {
+		if err := os.Remove(source); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err = db.Open(path)
+	if err != nil {
+		t.Fatalf("Open after downgrade: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	newEngine(d).SyncAll(nil)
+	return d
+}
+
+func TestSyncAll_UpgradedDBRewritesMessages(t *testing.T) {
+	d := syncUpgraded(t, false)
+	msgs := fetchMessages(t, d, "up-1")
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 3", len(msgs))
+	}
+	if msgs[1].Model != "claude-sonnet-4" {
+		t.Errorf("model = %q, want claude-sonnet-4", msgs[1].Model)
+	}
+	if d.NeedsReparse("up-1") {
+		t.Error("reparse flag not cleared")
+	}
+}
+
+func TestSyncAll_UpgradedDBRecordsToolErrors(t *testing.T) {
+	d := syncUpgraded(t, false)
+	tools, err := d.GetAnalyticsTools(
+		context.Background(), db.AnalyticsFilter{
+			From: "2024-01-01", To: "2024-01-01", Timezone: "UTC",
+		},
+	)
+	if err != nil {
+		t.Fatalf("GetAnalyticsTools: %v", err)
+	}
+	if tools.TotalCalls != 1 || tools.ErrorCalls != 1 {
+		t.Errorf("calls = %d, errors = %d, want 1 and 1",
+			tools.TotalCalls, tools.ErrorCalls)
+	}
+	// Calls without a recorded status would be left out.
+	if len(tools.ErrorsByTool) != 1 ||
+		tools.ErrorsByTool[0].Rate != 100 {
+		t.Errorf("errors by tool = %+v", tools.ErrorsByTool)
+	}
+}
+
+func TestSyncAll_UpgradedDBRecordsMessageTokens(t *testing.T) {
+	d := syncUpgraded(t, false)
+	msgs := fetchMessages(t, d, "up-1")
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 3", len(msgs))
+	}
+	if m := msgs[1]; m.InputTokens != 10 || m.OutputTokens != 20 ||
+		m.CacheCreationInputTokens != 30 || m.CacheReadInputTokens != 40 {
+		t.Errorf("message tokens = %+v", m)
+	}
+
+	activity, err := d.GetAnalyticsActivity(
+		context.Background(), db.AnalyticsFilter{
+			From: "2024-01-01", To: "2024-01-01", Timezone: "UTC",
+		}, "day",
+	)
+	if err != nil {
+		t.Fatalf("GetAnalyticsActivity: %v", err)
+	}
+	if len(activity.Series) != 1 {
+		t.Fatalf("series = %+v", activity.Series)
+	}
+	if e := activity.Series[0]; e.InputTokens != 11 ||
+		e.OutputTokens != 22 {
+		t.Errorf("activity tokens = %d in, %d out, want 11 and 22",
+			e.InputTokens, e.OutputTokens)
+	}
+}
+
+func TestSyncAll_UpgradedDBIndexesFiles(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		removeSource bool
+	}{
+		{"Reparsed", false},
+		{"SourceGone", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d := syncUpgraded(t, tt.removeSource)
+			sessions, err := d.FileSessions(
+				context.Background(), "/src/app/main.go", "", 10,
+			)
+			if err != nil {
+				t.Fatalf("FileSessions: %v", err)
+			}
+			if len(sessions) != 1 || sessions[0].SessionID != "up-1" ||
+				sessions[0].Edits != 1 {
+				t.Errorf("file sessions = %+v", sessions)
+			}
+		})
+	}
+}
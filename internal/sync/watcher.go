@@ -19,6 +19,9 @@ type Watcher struct {
 	watcher  *fsnotify.Watcher
 	debounce time.Duration
 	pending  map[string]time.Time
+	// shallow holds directories watched without their
+	// subdirectories.
+	shallow  map[string]bool
 	mu       sync.Mutex
 	stop     chan struct{}
 	done     chan struct{}
@@ -45,6 +48,7 @@ func NewWatcher(
 		watcher:  fsw,
 		debounce: debounce,
 		pending:  make(map[string]time.Time),
+		shallow:  make(map[string]bool),
 		stop:     make(chan struct{}),
 		done:     make(chan struct{}),
 		now:      time.Now,
@@ -73,6 +77,20 @@ func (w *Watcher) WatchRecursive(root string) (watched int, unwatched int, err e
 	return watched, unwatched, err
 }
 
+// WatchDir adds dir to the watch list without its
+// subdirectories, for directories such as repository roots whose
+// trees are too large to watch. Directories created in dir are
+// not watched either.
+func (w *Watcher) WatchDir(dir string) error {
+	if err := w.watcher.Add(dir); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.shallow[filepath.Clean(dir)] = true
+	w.mu.Unlock()
+	return nil
+}
+
 // Start begins processing file events in a goroutine.
 func (w *Watcher) Start() {
 	go w.loop()
@@ -122,7 +140,10 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 		return
 	}
 
-	if event.Op&fsnotify.Create != 0 {
+	w.mu.Lock()
+	shallow := w.shallow[filepath.Dir(event.Name)]
+	w.mu.Unlock()
+	if event.Op&fsnotify.Create != 0 && !shallow {
 		w.watchIfDir(event.Name)
 	}
 
@@ -118,6 +118,48 @@ func TestWatcherAutoWatchesNewDirs(t *testing.T) {
 	}
 }
 
+func TestWatcherWatchDirIsShallow(t *testing.T) {
+	pathsCh := make(chan []string, 10)
+	w, err := NewWatcher(50*time.Millisecond, func(paths []string) {
+		pathsCh <- paths
+	})
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	t.Cleanup(func() { w.Stop() })
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "existing"), 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := w.WatchDir(dir); err != nil {
+		t.Fatalf("WatchDir: %v", err)
+	}
+	w.Start()
+
+	subdir := filepath.Join(dir, "newdir")
+	if err := os.Mkdir(subdir, 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	path := filepath.Join(dir, "history.md")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for found := false; !found; {
+		select {
+		case paths := <-pathsCh:
+			found = slices.Contains(paths, path)
+		case <-deadline:
+			t.Fatal("timed out waiting for file change")
+		}
+	}
+	if got := w.watcher.WatchList(); len(got) != 1 || got[0] != dir {
+		t.Errorf("WatchList = %v, want only %s", got, dir)
+	}
+}
+
 func TestWatcherStopIsClean(t *testing.T) {
 	w, _ := startTestWatcherNoCleanup(t, func(_ []string) {}, 50*time.Millisecond)
 
@@ -5,6 +5,9 @@ package testjsonl
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -262,6 +265,59 @@ func CodexFunctionCallFieldsJSON(
 	return mustMarshal(m)
 }
 
+// CodexFunctionCallOutputJSON returns a Codex
+// function_call_output response_item for callID.
+func CodexFunctionCallOutputJSON(
+	callID, output, timestamp string,
+) string {
+	m := map[string]any{
+		"type":      "response_item",
+		"timestamp": timestamp,
+		"payload": map[string]any{
+			"type":    "function_call_output",
+			"call_id": callID,
+			"output":  output,
+		},
+	}
+	return mustMarshal(m)
+}
+
+// CodexTurnContextJSON returns a Codex turn_context entry
+// naming the model for the turn.
+func CodexTurnContextJSON(model, timestamp string) string {
+	m := map[string]any{
+		"type":      "turn_context",
+		"timestamp": timestamp,
+		"payload": map[string]any{
+			"cwd":   "/tmp",
+			"model": model,
+		},
+	}
+	return mustMarshal(m)
+}
+
+// CodexTokenCountJSON returns a Codex token_count event_msg
+// with the usage of the last model response.
+func CodexTokenCountJSON(
+	input, cachedInput, output int64, timestamp string,
+) string {
+	m := map[string]any{
+		"type":      "event_msg",
+		"timestamp": timestamp,
+		"payload": map[string]any{
+			"type": "token_count",
+			"info": map[string]any{
+				"last_token_usage": map[string]any{
+					"input_tokens":        input,
+					"cached_input_tokens": cachedInput,
+					"output_tokens":       output,
+				},
+			},
+		},
+	}
+	return mustMarshal(m)
+}
+
 // ClaudeEntryJSON returns a Claude JSONL entry with uuid and
 // parentUuid fields.
 func ClaudeEntryJSON(
@@ -540,6 +596,363 @@ func GeminiSessionJSON(
 	return string(b)
 }
 
+// GooseMetaJSON returns the metadata line that starts a Goose
+// session file, with the session's accumulated token counts.
+func GooseMetaJSON(
+	workingDir, description string, inputTokens, outputTokens int64,
+) string {
+	m := map[string]any{
+		"working_dir":               workingDir,
+		"description":               description,
+		"schedule_id":               nil,
+		"input_tokens":              inputTokens,
+		"output_tokens":             outputTokens,
+		"total_tokens":              inputTokens + outputTokens,
+		"accumulated_input_tokens":  inputTokens,
+		"accumulated_output_tokens": outputTokens,
+		"accumulated_total_tokens":  inputTokens + outputTokens,
+	}
+	return mustMarshal(m)
+}
+
+// GooseMessageJSON returns a Goose message line. created is in
+// Unix seconds and content holds blocks built by the Goose*
+// content helpers.
+func GooseMessageJSON(
+	id, role string, created int64, content ...map[string]any,
+) string {
+	m := map[string]any{
+		"id":      id,
+		"role":    role,
+		"created": created,
+		"content": content,
+	}
+	return mustMarshal(m)
+}
+
+// GooseText builds a Goose text content block.
+func GooseText(text string) map[string]any {
+	return map[string]any{"type": "text", "text": text}
+}
+
+// GooseThinking builds a Goose thinking content block.
+func GooseThinking(text string) map[string]any {
+	return map[string]any{
+		"type": "thinking", "thinking": text, "signature": "sig",
+	}
+}
+
+// GooseToolRequest builds a Goose toolRequest content block
+// calling the named tool with args.
+func GooseToolRequest(
+	id, name string, args map[string]any,
+) map[string]any {
+	return map[string]any{
+		"type": "toolRequest",
+		"id":   id,
+		"toolCall": map[string]any{
+			"status": "success",
+			"value": map[string]any{
+				"name":      name,
+				"arguments": args,
+			},
+		},
+	}
+}
+
+// GooseToolResponse builds a Goose toolResponse content block
+// answering the tool request id. A failed response carries text
+// as its error.
+func GooseToolResponse(id, text string, failed bool) map[string]any {
+	result := map[string]any{
+		"status": "success",
+		"value": []map[string]any{
+			{"type": "text", "text": text},
+		},
+	}
+	if failed {
+		result = map[string]any{"status": "error", "error": text}
+	}
+	return map[string]any{
+		"type":       "toolResponse",
+		"id":         id,
+		"toolResult": result,
+	}
+}
+
+// AddGooseMeta appends a Goose session metadata line.
+func (b *SessionBuilder) AddGooseMeta(
+	workingDir, description string, inputTokens, outputTokens int64,
+) *SessionBuilder {
+	b.lines = append(b.lines, GooseMetaJSON(
+		workingDir, description, inputTokens, outputTokens,
+	))
+	return b
+}
+
+// AddGooseMessage appends a Goose message line.
+func (b *SessionBuilder) AddGooseMessage(
+	id, role string, created int64, content ...map[string]any,
+) *SessionBuilder {
+	b.lines = append(
+		b.lines, GooseMessageJSON(id, role, created, content...),
+	)
+	return b
+}
+
+// AmpToolUse defines a tool call for Amp test fixtures.
+type AmpToolUse struct {
+	ID    string
+	Name  string
+	Input map[string]any
+}
+
+// AmpMsgOpts holds the content and usage of an Amp assistant
+// message.
+type AmpMsgOpts struct {
+	Thinking     string
+	Text         string
+	ToolUses     []AmpToolUse
+	Model        string
+	InputTokens  int64
+	OutputTokens int64
+	CacheRead    int64
+	CacheWrite   int64
+	Timestamp    string
+}
+
+// AmpUserMsg builds an Amp user message sent at sentAt Unix
+// milliseconds.
+func AmpUserMsg(id int, sentAt int64, text string) map[string]any {
+	return map[string]any{
+		"role":      "user",
+		"messageId": id,
+		"content": []map[string]any{
+			{"type": "text", "text": text},
+		},
+		"meta": map[string]any{"sentAt": sentAt},
+	}
+}
+
+// AmpAssistantMsg builds an Amp assistant message object.
+func AmpAssistantMsg(id int, opts AmpMsgOpts) map[string]any {
+	var content []map[string]any
+	if opts.Thinking != "" {
+		content = append(content, map[string]any{
+			"type": "thinking", "thinking": opts.Thinking,
+		})
+	}
+	if opts.Text != "" {
+		content = append(content, map[string]any{
+			"type": "text", "text": opts.Text,
+		})
+	}
+	stopReason := "end_turn"
+	for _, tu := range opts.ToolUses {
+		stopReason = "tool_use"
+		content = append(content, map[string]any{
+			"type":  "tool_use",
+			"id":    tu.ID,
+			"name":  tu.Name,
+			"input": tu.Input,
+		})
+	}
+	m := map[string]any{
+		"role":      "assistant",
+		"messageId": id,
+		"content":   content,
+		"state": map[string]any{
+			"type": "complete", "stopReason": stopReason,
+		},
+	}
+	if opts.Model != "" {
+		m["usage"] = map[string]any{
+			"model":                    opts.Model,
+			"inputTokens":              opts.InputTokens,
+			"outputTokens":             opts.OutputTokens,
+			"cacheReadInputTokens":     opts.CacheRead,
+			"cacheCreationInputTokens": opts.CacheWrite,
+			"timestamp":                opts.Timestamp,
+		}
+	}
+	return m
+}
+
+// AmpToolResultMsg builds an Amp user message carrying the run
+// of a tool call. status is "done", "error", "cancelled" or
+// "rejected-by-user".
+func AmpToolResultMsg(
+	id int, toolUseID, status string, result any,
+) map[string]any {
+	run := map[string]any{"status": status}
+	if result != nil {
+		run["result"] = result
+	}
+	return map[string]any{
+		"role":      "user",
+		"messageId": id,
+		"content": []map[string]any{{
+			"type":      "tool_result",
+			"toolUseID": toolUseID,
+			"run":       run,
+		}},
+	}
+}
+
+// AmpThreadJSON builds a complete Amp thread JSON string. tree
+// is the URI of the workspace the thread started in, if any.
+func AmpThreadJSON(
+	id string, created int64, title, tree string,
+	messages []map[string]any,
+) string {
+	thread := map[string]any{
+		"v":        1,
+		"id":       id,
+		"created":  created,
+		"title":    title,
+		"messages": messages,
+	}
+	if tree != "" {
+		thread["env"] = map[string]any{
+			"initial": map[string]any{
+				"trees": []map[string]any{{"uri": tree}},
+			},
+		}
+	}
+	b, err := json.MarshalIndent(thread, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// OpenCodeExportMsg builds a message of an "opencode export"
+// document with the given parts.
+func OpenCodeExportMsg(
+	id, role string, created int64, parts ...map[string]any,
+) map[string]any {
+	return map[string]any{
+		"info": map[string]any{
+			"id":   id,
+			"role": role,
+			"time": map[string]any{"created": created},
+		},
+		"parts": parts,
+	}
+}
+
+// OpenCodeExportJSON builds a complete "opencode export"
+// document for a session started in directory.
+func OpenCodeExportJSON(
+	id, directory string, created, updated int64,
+	messages []map[string]any,
+) string {
+	export := map[string]any{
+		"info": map[string]any{
+			"id":        id,
+			"directory": directory,
+			"time": map[string]any{
+				"created": created,
+				"updated": updated,
+			},
+		},
+		"messages": messages,
+	}
+	b, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// OTelSpan describes a span for OTLP/JSON test fixtures. Start
+// and End are Unix nanoseconds.
+type OTelSpan struct {
+	TraceID  string
+	SpanID   string
+	ParentID string
+	Name     string
+	Start    int64
+	End      int64
+	Attrs    map[string]any
+	Error    bool
+}
+
+// OTelSpanJSON builds an OTLP/JSON span object. Attribute
+// values are encoded by their Go type; integers are written as
+// strings, as OTLP/JSON does.
+func OTelSpanJSON(s OTelSpan) map[string]any {
+	keys := make([]string, 0, len(s.Attrs))
+	for k := range s.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]map[string]any, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, map[string]any{
+			"key": k, "value": otelValue(s.Attrs[k]),
+		})
+	}
+	span := map[string]any{
+		"traceId":           s.TraceID,
+		"spanId":            s.SpanID,
+		"name":              s.Name,
+		"kind":              3,
+		"startTimeUnixNano": strconv.FormatInt(s.Start, 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.End, 10),
+		"attributes":        attrs,
+		"status":            map[string]any{},
+	}
+	if s.ParentID != "" {
+		span["parentSpanId"] = s.ParentID
+	}
+	if s.Error {
+		span["status"] = map[string]any{"code": 2, "message": "failed"}
+	}
+	return span
+}
+
+func otelValue(v any) map[string]any {
+	switch v := v.(type) {
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
+
+// OTLPRequestJSON builds a single-line OTLP/JSON trace export
+// request holding spans emitted by service on host.
+func OTLPRequestJSON(service, host string, spans ...OTelSpan) string {
+	resource := []map[string]any{{
+		"key": "service.name", "value": otelValue(service),
+	}}
+	if host != "" {
+		resource = append(resource, map[string]any{
+			"key": "host.name", "value": otelValue(host),
+		})
+	}
+	list := make([]map[string]any, len(spans))
+	for i, s := range spans {
+		list[i] = OTelSpanJSON(s)
+	}
+	return mustMarshal(map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{"attributes": resource},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "agent"},
+				"spans": list,
+			}},
+		}},
+	})
+}
+
 func mustMarshal(v any) string {
 	b, err := json.Marshal(v)
 	if err != nil {